@@ -0,0 +1,114 @@
+package apikey
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/pkg/errors"
+)
+
+var scopeSegmentRegex = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// ValidateRestrictions verifies the restrictions of a new API key
+func ValidateRestrictions(restrictions portainer.APIKeyRestrictions) error {
+	if restrictions.ExpiresAt != 0 && restrictions.ExpiresAt <= time.Now().Unix() {
+		return errors.New("invalid expiry. must be in the future")
+	}
+
+	for _, scope := range restrictions.Scopes {
+		segments := strings.Split(strings.Trim(scope, "/"), "/")
+		for _, segment := range segments {
+			if !scopeSegmentRegex.MatchString(segment) {
+				return errors.Errorf("invalid scope %q. must be an API path prefix without identifiers, such as stacks or endpoints/docker", scope)
+			}
+		}
+	}
+
+	return nil
+}
+
+// IsExpired returns true when the API key cannot be used anymore
+func IsExpired(apiKey portainer.APIKey, now time.Time) bool {
+	return apiKey.ExpiresAt != 0 && now.Unix() >= apiKey.ExpiresAt
+}
+
+// AllowsMethod returns true when the API key can be used for requests with the HTTP method
+func AllowsMethod(apiKey portainer.APIKey, method string) bool {
+	if !apiKey.ReadOnly {
+		return true
+	}
+
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// AllowsEndpoint returns true when the API key can be used on the environment(endpoint)
+func AllowsEndpoint(apiKey portainer.APIKey, endpointID portainer.EndpointID) bool {
+	if len(apiKey.EndpointIDs) == 0 {
+		return true
+	}
+
+	for _, id := range apiKey.EndpointIDs {
+		if id == endpointID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllowsPath returns true when the API path matches one of the scopes of the API key.
+// The numeric identifiers of the path are ignored and the scopes match whole path segments,
+// the "endpoints/docker" scope matches /api/endpoints/1/docker/containers/json but not /api/endpoints/1.
+func AllowsPath(apiKey portainer.APIKey, path string) bool {
+	if len(apiKey.Scopes) == 0 {
+		return true
+	}
+
+	pathSegments := routeSegments(path)
+	for _, scope := range apiKey.Scopes {
+		if hasSegmentsPrefix(pathSegments, strings.Split(strings.Trim(scope, "/"), "/")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// routeSegments returns the segments of the API path without the api prefix and the numeric identifiers
+func routeSegments(path string) []string {
+	path = strings.TrimPrefix(strings.Trim(path, "/"), "api/")
+
+	segments := make([]string, 0)
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" {
+			continue
+		}
+
+		if _, err := strconv.Atoi(segment); err == nil {
+			continue
+		}
+
+		segments = append(segments, segment)
+	}
+
+	return segments
+}
+
+func hasSegmentsPrefix(segments, prefix []string) bool {
+	if len(prefix) > len(segments) {
+		return false
+	}
+
+	for i := range prefix {
+		if segments[i] != prefix[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,50 @@
+package apikey
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ValidateRestrictions(t *testing.T) {
+	is := assert.New(t)
+
+	is.NoError(ValidateRestrictions(portainer.APIKeyRestrictions{}))
+	is.NoError(ValidateRestrictions(portainer.APIKeyRestrictions{ExpiresAt: time.Now().Add(time.Hour).Unix(), Scopes: []string{"stacks", "endpoints/docker", "/edge_stacks/"}}))
+	is.Error(ValidateRestrictions(portainer.APIKeyRestrictions{ExpiresAt: time.Now().Add(-time.Hour).Unix()}), "expiry in the past")
+	is.Error(ValidateRestrictions(portainer.APIKeyRestrictions{Scopes: []string{"stacks/1"}}), "scope with identifier")
+	is.Error(ValidateRestrictions(portainer.APIKeyRestrictions{Scopes: []string{""}}), "empty scope")
+}
+
+func Test_restrictions(t *testing.T) {
+	is := assert.New(t)
+
+	unrestricted := portainer.APIKey{}
+	is.False(IsExpired(unrestricted, time.Now()))
+	is.True(AllowsMethod(unrestricted, http.MethodDelete))
+	is.True(AllowsEndpoint(unrestricted, 3))
+	is.True(AllowsPath(unrestricted, "/api/users/1"))
+
+	restricted := portainer.APIKey{APIKeyRestrictions: portainer.APIKeyRestrictions{
+		ExpiresAt:   time.Now().Unix(),
+		ReadOnly:    true,
+		EndpointIDs: []portainer.EndpointID{1, 2},
+		Scopes:      []string{"stacks", "endpoints/docker"},
+	}}
+	is.True(IsExpired(restricted, time.Now().Add(time.Second)))
+	is.True(AllowsMethod(restricted, http.MethodGet))
+	is.False(AllowsMethod(restricted, http.MethodPost))
+	is.True(AllowsEndpoint(restricted, 2))
+	is.False(AllowsEndpoint(restricted, 3))
+
+	is.True(AllowsPath(restricted, "/api/stacks"))
+	is.True(AllowsPath(restricted, "/stacks/5/git/redeploy"))
+	is.True(AllowsPath(restricted, "/api/endpoints/1/docker/containers/json"))
+	is.False(AllowsPath(restricted, "/api/endpoints/1"), "the scope must match whole segments")
+	is.False(AllowsPath(restricted, "/api/stacksx"))
+	is.False(AllowsPath(restricted, "/api/edge_stacks"))
+}
@@ -39,6 +39,12 @@ func (a *apiKeyService) HashRaw(rawKey string) []byte {
 // GenerateApiKey generates a raw API key for a user (for one-time display).
 // The generated API key is stored in the cache and database.
 func (a *apiKeyService) GenerateApiKey(user portainer.User, description string) (string, *portainer.APIKey, error) {
+	return a.GenerateRestrictedApiKey(user, description, portainer.APIKeyRestrictions{})
+}
+
+// GenerateRestrictedApiKey generates a raw API key for a user (for one-time display), limited by the restrictions.
+// The generated API key is stored in the cache and database.
+func (a *apiKeyService) GenerateRestrictedApiKey(user portainer.User, description string, restrictions portainer.APIKeyRestrictions) (string, *portainer.APIKey, error) {
 	randKey := generateRandomKey(32)
 	encodedRawAPIKey := base64.StdEncoding.EncodeToString(randKey)
 	prefixedAPIKey := portainerAPIKeyPrefix + encodedRawAPIKey
@@ -51,6 +57,8 @@ func (a *apiKeyService) GenerateApiKey(user portainer.User, description string)
 		Prefix:      prefixedAPIKey[:7],
 		DateCreated: time.Now().Unix(),
 		Digest:      hashDigest,
+
+		APIKeyRestrictions: restrictions,
 	}
 
 	err := a.apiKeyRepository.CreateAPIKey(apiKey)
@@ -74,6 +82,11 @@ func (a *apiKeyService) GetAPIKeys(userID portainer.UserID) ([]portainer.APIKey,
 	return a.apiKeyRepository.GetAPIKeysByUserID(userID)
 }
 
+// GetAllAPIKeys returns the API keys of all the users.
+func (a *apiKeyService) GetAllAPIKeys() ([]portainer.APIKey, error) {
+	return a.apiKeyRepository.GetAPIKeys()
+}
+
 // GetDigestUserAndKey returns the user and api-key associated to a specified hash digest.
 // A cache lookup is performed first; if the user/api-key is not found in the cache, respective database lookups are performed.
 func (a *apiKeyService) GetDigestUserAndKey(digest []byte) (portainer.User, portainer.APIKey, error) {
@@ -27,6 +27,7 @@ var filesToBackup = []string{
 	"edge_jobs",
 	"edge_stacks",
 	"extensions",
+	"mfa.key",
 	"portainer.key",
 	"portainer.pub",
 	"tls",
@@ -81,7 +82,8 @@ func CreateBackupArchive(password string, gate *offlinegate.OfflineGate, datasto
 }
 
 func backupDb(backupDirPath string, datastore dataservices.DataStore) error {
-	backupWriter, err := os.Create(filepath.Join(backupDirPath, "portainer.db"))
+	// keep the name of the database file so that the backend and the encryption are known on restore
+	backupWriter, err := os.Create(filepath.Join(backupDirPath, datastore.Connection().GetDatabaseFileName()))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,127 @@
+package backup
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/pkg/errors"
+)
+
+// ArchivePrefix is the prefix of the name of every backup archive
+const ArchivePrefix = "portainer-backup_"
+
+// Archive represents a backup archive stored in a destination
+type Archive struct {
+	Name    string
+	ModTime time.Time
+}
+
+// Destination represents a place where the backup archives are stored
+type Destination interface {
+	// Upload stores the archive located at archivePath under the given name
+	Upload(name, archivePath string) error
+	// List returns the backup archives stored in the destination
+	List() ([]Archive, error)
+	// Delete removes the backup archive with the given name
+	Delete(name string) error
+}
+
+// NewDestination returns the destination described by the backup settings
+func NewDestination(settings portainer.BackupSettings) (Destination, error) {
+	switch settings.Destination {
+	case portainer.BackupDestinationLocal:
+		return &localDestination{directory: settings.LocalDirectory}, nil
+	case portainer.BackupDestinationS3:
+		return newS3Destination(settings.S3Settings), nil
+	}
+
+	return nil, errors.Errorf("unsupported backup destination %q", settings.Destination)
+}
+
+type localDestination struct {
+	directory string
+}
+
+func (d *localDestination) Upload(name, archivePath string) error {
+	err := os.MkdirAll(d.directory, rwxr__r__)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the backup directory")
+	}
+
+	in, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(filepath.Join(d.directory, name))
+	if err != nil {
+		return errors.Wrap(err, "failed to create the backup file")
+	}
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return errors.Wrap(err, "failed to write the backup file")
+	}
+
+	return out.Close()
+}
+
+func (d *localDestination) List() ([]Archive, error) {
+	entries, err := os.ReadDir(d.directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrap(err, "failed to read the backup directory")
+	}
+
+	archives := make([]Archive, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), ArchivePrefix) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		archives = append(archives, Archive{Name: entry.Name(), ModTime: info.ModTime()})
+	}
+
+	return archives, nil
+}
+
+func (d *localDestination) Delete(name string) error {
+	return os.Remove(filepath.Join(d.directory, filepath.Base(name)))
+}
+
+// ExpiredArchives returns the archives that must be removed to honor the retention policy,
+// keepCount and keepDays are ignored when they are not positive
+func ExpiredArchives(archives []Archive, keepCount, keepDays int, now time.Time) []Archive {
+	sorted := make([]Archive, len(archives))
+	copy(sorted, archives)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ModTime.After(sorted[j].ModTime)
+	})
+
+	limit := now.AddDate(0, 0, -keepDays)
+
+	expired := make([]Archive, 0)
+	for i, archive := range sorted {
+		if (keepCount > 0 && i >= keepCount) || (keepDays > 0 && archive.ModTime.Before(limit)) {
+			expired = append(expired, archive)
+		}
+	}
+
+	return expired
+}
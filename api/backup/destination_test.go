@@ -0,0 +1,152 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExpiredArchives(t *testing.T) {
+	now := time.Now()
+	archives := []Archive{
+		{Name: "b", ModTime: now.AddDate(0, 0, -1)},
+		{Name: "a", ModTime: now},
+		{Name: "d", ModTime: now.AddDate(0, 0, -10)},
+		{Name: "c", ModTime: now.AddDate(0, 0, -2)},
+	}
+
+	names := func(archives []Archive) []string {
+		result := []string{}
+		for _, archive := range archives {
+			result = append(result, archive.Name)
+		}
+		return result
+	}
+
+	assert.Empty(t, ExpiredArchives(archives, 0, 0, now), "every archive should be kept without retention policy")
+	assert.Equal(t, []string{"c", "d"}, names(ExpiredArchives(archives, 2, 0, now)))
+	assert.Equal(t, []string{"d"}, names(ExpiredArchives(archives, 0, 5, now)))
+	assert.Equal(t, []string{"b", "c", "d"}, names(ExpiredArchives(archives, 1, 5, now)))
+}
+
+func Test_LocalDestination(t *testing.T) {
+	is := assert.New(t)
+
+	archivePath := filepath.Join(t.TempDir(), "archive.tar.gz")
+	is.NoError(os.WriteFile(archivePath, []byte("content"), 0644))
+
+	directory := filepath.Join(t.TempDir(), "backups")
+	destination, err := NewDestination(portainer.BackupSettings{Destination: portainer.BackupDestinationLocal, LocalDirectory: directory})
+	is.NoError(err)
+
+	archives, err := destination.List()
+	is.NoError(err)
+	is.Empty(archives, "a missing directory should not contain any archive")
+
+	name := ArchivePrefix + "archive.tar.gz"
+	is.NoError(destination.Upload(name, archivePath))
+	is.NoError(os.WriteFile(filepath.Join(directory, "unrelated.txt"), []byte{}, 0644))
+
+	archives, err = destination.List()
+	is.NoError(err)
+	is.Len(archives, 1)
+	is.Equal(name, archives[0].Name)
+
+	content, err := os.ReadFile(filepath.Join(directory, name))
+	is.NoError(err)
+	is.Equal("content", string(content))
+
+	is.NoError(destination.Delete(name))
+	archives, err = destination.List()
+	is.NoError(err)
+	is.Empty(archives)
+}
+
+// fakeS3 is a minimal in-memory stand-in of an S3 compatible API
+type fakeS3 struct {
+	mu      sync.Mutex
+	bucket  string
+	objects map[string][]byte
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") || r.Header.Get(contentHashHeader) == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/"+s.bucket)
+	key = strings.TrimPrefix(key, "/")
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		s.objects[key] = body
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		prefix := r.URL.Query().Get("prefix")
+		fmt.Fprint(w, "<ListBucketResult><IsTruncated>false</IsTruncated>")
+		for key := range s.objects {
+			if strings.HasPrefix(key, prefix) {
+				fmt.Fprintf(w, "<Contents><Key>%s</Key><LastModified>2023-01-02T15:04:05.000Z</LastModified></Contents>", key)
+			}
+		}
+		fmt.Fprint(w, "</ListBucketResult>")
+	}
+}
+
+func Test_S3Destination(t *testing.T) {
+	is := assert.New(t)
+
+	s3 := &fakeS3{bucket: "backups", objects: map[string][]byte{"other/file": {}}}
+	server := httptest.NewServer(s3)
+	defer server.Close()
+
+	archivePath := filepath.Join(t.TempDir(), "archive.tar.gz")
+	is.NoError(os.WriteFile(archivePath, []byte("content"), 0644))
+
+	destination, err := NewDestination(portainer.BackupSettings{
+		Destination: portainer.BackupDestinationS3,
+		S3Settings: portainer.S3BackupSettings{
+			Endpoint:        server.URL,
+			BucketName:      "backups",
+			Prefix:          "portainer/",
+			AccessKeyID:     "access",
+			SecretAccessKey: "secret",
+		},
+	})
+	is.NoError(err)
+
+	name := ArchivePrefix + "archive.tar.gz"
+	is.NoError(destination.Upload(name, archivePath))
+	is.Equal("content", string(s3.objects["portainer/"+name]))
+
+	archives, err := destination.List()
+	is.NoError(err)
+	is.Len(archives, 1)
+	is.Equal(name, archives[0].Name)
+	is.Equal(2023, archives[0].ModTime.Year())
+
+	is.NoError(destination.Delete(name))
+	is.NotContains(s3.objects, "portainer/"+name)
+
+	unauthorized := newS3Destination(portainer.S3BackupSettings{Endpoint: server.URL, BucketName: "backups", AccessKeyID: "wrong"})
+	_, err = unauthorized.List()
+	is.ErrorContains(err, "403")
+}
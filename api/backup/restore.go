@@ -13,12 +13,19 @@ import (
 	"github.com/portainer/portainer/api/archive"
 	"github.com/portainer/portainer/api/crypto"
 	"github.com/portainer/portainer/api/database/boltdb"
+	"github.com/portainer/portainer/api/database/sqlite"
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/filesystem"
 	"github.com/portainer/portainer/api/http/offlinegate"
 )
 
-var filesToRestore = append(filesToBackup, "portainer.db")
+var filesToRestore = filesToBackup
+
+// databaseFileNames are the names of the database files of each backend, unencrypted then encrypted
+var databaseFileNames = [][]string{
+	{boltdb.DatabaseFileName, boltdb.EncryptedDatabaseFileName},
+	{sqlite.DatabaseFileName, sqlite.EncryptedDatabaseFileName},
+}
 
 // Restores system state from backup archive, will trigger system shutdown, when finished.
 func RestoreArchive(archive io.Reader, password string, filestorePath string, gate *offlinegate.OfflineGate, datastore dataservices.DataStore, shutdownTrigger context.CancelFunc) error {
@@ -68,8 +75,8 @@ func extractArchive(r io.Reader, destinationDirPath string) error {
 }
 
 func getRestoreSourcePath(dir string) (string, error) {
-	// find portainer.db or portainer.edb file. Return the parent directory
-	var portainerdbRegex = regexp.MustCompile(`^portainer.e?db$`)
+	// find the portainer.db, portainer.edb, portainer.sqlite or portainer.esqlite file. Return the parent directory
+	var portainerdbRegex = regexp.MustCompile(`^portainer\.(e?db|e?sqlite)$`)
 
 	backupDirPath := dir
 	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
@@ -95,19 +102,46 @@ func restoreFiles(srcDir string, destinationDir string) error {
 		}
 	}
 
-	// TODO:  This is very boltdb module specific once again due to the filename.  Move to bolt module? Refactor for another day
+	for _, fileNames := range databaseFileNames {
+		if !containsAnyFile(srcDir, fileNames) {
+			continue
+		}
+
+		// Prevent the possibility of having both an encrypted and an unencrypted database, or the database of
+		// another backend that would be loaded instead of the restored one. Remove any default new instance
+		for _, otherFileNames := range databaseFileNames {
+			for _, fileName := range otherFileNames {
+				removeDatabaseFile(filepath.Join(destinationDir, fileName))
+			}
+		}
 
-	// Prevent the possibility of having both databases.  Remove any default new instance
-	os.Remove(filepath.Join(destinationDir, boltdb.DatabaseFileName))
-	os.Remove(filepath.Join(destinationDir, boltdb.EncryptedDatabaseFileName))
+		// Note: CopyPath does not return an error if the source file doesn't exist
+		for _, fileName := range fileNames {
+			err := filesystem.CopyPath(filepath.Join(srcDir, fileName), destinationDir)
+			if err != nil {
+				return err
+			}
+		}
 
-	// Now copy the database.  It'll be either portainer.db or portainer.edb
+		break
+	}
 
-	// Note: CopyPath does not return an error if the source file doesn't exist
-	err := filesystem.CopyPath(filepath.Join(srcDir, boltdb.EncryptedDatabaseFileName), destinationDir)
-	if err != nil {
-		return err
+	return nil
+}
+
+// removeDatabaseFile removes a database file along with the write-ahead log files of SQLite
+func removeDatabaseFile(path string) {
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		os.Remove(path + suffix)
+	}
+}
+
+func containsAnyFile(dir string, fileNames []string) bool {
+	for _, fileName := range fileNames {
+		if _, err := os.Stat(filepath.Join(dir, fileName)); err == nil {
+			return true
+		}
 	}
 
-	return filesystem.CopyPath(filepath.Join(srcDir, boltdb.DatabaseFileName), destinationDir)
+	return false
 }
@@ -0,0 +1,60 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/portainer/portainer/api/database/boltdb"
+	"github.com/portainer/portainer/api/database/sqlite"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_restoreFiles_SQLite(t *testing.T) {
+	is := assert.New(t)
+
+	backupDir := filepath.Join(t.TempDir(), "backup")
+	require.NoError(t, os.MkdirAll(backupDir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(backupDir, sqlite.DatabaseFileName), []byte("backup"), 0600))
+
+	restorePath, err := getRestoreSourcePath(filepath.Dir(backupDir))
+	require.NoError(t, err)
+	is.Equal(backupDir, restorePath)
+
+	destinationDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(destinationDir, sqlite.EncryptedDatabaseFileName), []byte("new"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(destinationDir, sqlite.EncryptedDatabaseFileName+"-wal"), []byte("new"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(destinationDir, boltdb.DatabaseFileName), []byte("bolt"), 0600))
+
+	require.NoError(t, restoreFiles(restorePath, destinationDir))
+
+	content, err := os.ReadFile(filepath.Join(destinationDir, sqlite.DatabaseFileName))
+	require.NoError(t, err)
+	is.Equal("backup", string(content))
+
+	is.NoFileExists(filepath.Join(destinationDir, sqlite.EncryptedDatabaseFileName), "the new instance should be removed")
+	is.NoFileExists(filepath.Join(destinationDir, sqlite.EncryptedDatabaseFileName+"-wal"), "the write-ahead log of the new instance should be removed")
+	is.NoFileExists(filepath.Join(destinationDir, boltdb.DatabaseFileName), "the database of the other backend should be removed")
+}
+
+func Test_restoreFiles_BoltDB(t *testing.T) {
+	is := assert.New(t)
+
+	backupDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(backupDir, boltdb.EncryptedDatabaseFileName), []byte("backup"), 0600))
+
+	destinationDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(destinationDir, sqlite.DatabaseFileName), []byte("sqlite"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(destinationDir, sqlite.DatabaseFileName+"-shm"), []byte("sqlite"), 0600))
+
+	require.NoError(t, restoreFiles(backupDir, destinationDir))
+
+	content, err := os.ReadFile(filepath.Join(destinationDir, boltdb.EncryptedDatabaseFileName))
+	require.NoError(t, err)
+	is.Equal("backup", string(content))
+
+	is.NoFileExists(filepath.Join(destinationDir, sqlite.DatabaseFileName), "the database of the other backend should be removed")
+	is.NoFileExists(filepath.Join(destinationDir, sqlite.DatabaseFileName+"-shm"))
+}
@@ -0,0 +1,198 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/pkg/errors"
+)
+
+const (
+	s3Service         = "s3"
+	s3DefaultRegion   = "us-east-1"
+	s3RequestTimeout  = 10 * time.Minute
+	emptyPayloadHash  = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	contentHashHeader = "X-Amz-Content-Sha256"
+)
+
+// s3Destination stores the backup archives in a bucket of an S3 compatible API.
+// Path style requests are used so that any S3 compatible server can be targeted.
+type s3Destination struct {
+	settings portainer.S3BackupSettings
+	client   *http.Client
+	signer   *v4.Signer
+}
+
+type s3ListResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func newS3Destination(settings portainer.S3BackupSettings) *s3Destination {
+	if settings.Region == "" {
+		settings.Region = s3DefaultRegion
+	}
+
+	if settings.Endpoint == "" {
+		settings.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", settings.Region)
+	}
+
+	return &s3Destination{
+		settings: settings,
+		client:   &http.Client{Timeout: s3RequestTimeout},
+		signer:   v4.NewSigner(),
+	}
+}
+
+func (d *s3Destination) Upload(name, archivePath string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return errors.Wrap(err, "failed to compute the archive checksum")
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, d.objectURL(d.settings.Prefix+name), file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := d.do(req, hex.EncodeToString(hash.Sum(nil)))
+	if err != nil {
+		return errors.WithMessage(err, "failed to upload the backup archive")
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+func (d *s3Destination) List() ([]Archive, error) {
+	archives := make([]Archive, 0)
+	continuationToken := ""
+
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", d.settings.Prefix+ArchivePrefix)
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, d.bucketURL()+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := d.do(req, emptyPayloadHash)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to list the backup archives")
+		}
+
+		var result s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode the list of backup archives")
+		}
+
+		for _, object := range result.Contents {
+			archives = append(archives, Archive{
+				Name:    strings.TrimPrefix(object.Key, d.settings.Prefix),
+				ModTime: object.LastModified,
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return archives, nil
+		}
+
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+func (d *s3Destination) Delete(name string) error {
+	req, err := http.NewRequest(http.MethodDelete, d.objectURL(d.settings.Prefix+name), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := d.do(req, emptyPayloadHash)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to remove the backup archive %s", name)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+func (d *s3Destination) bucketURL() string {
+	return strings.TrimSuffix(d.settings.Endpoint, "/") + "/" + url.PathEscape(d.settings.BucketName)
+}
+
+func (d *s3Destination) objectURL(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return d.bucketURL() + "/" + strings.Join(segments, "/")
+}
+
+// do signs the request with the AWS signature version 4 and sends it,
+// responses with a status code other than 2xx are returned as errors
+func (d *s3Destination) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	req.Header.Set(contentHashHeader, payloadHash)
+
+	credentials := aws.Credentials{
+		AccessKeyID:     d.settings.AccessKeyID,
+		SecretAccessKey: d.settings.SecretAccessKey,
+	}
+
+	err := d.signer.SignHTTP(context.Background(), credentials, req, payloadHash, s3Service, d.settings.Region, time.Now())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign the request")
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+		return nil, errors.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return resp, nil
+}
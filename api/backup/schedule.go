@@ -0,0 +1,237 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/http/offlinegate"
+	"github.com/portainer/portainer/api/scheduler"
+
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog/log"
+)
+
+// Status represents the state of the automatic backups
+type Status struct {
+	// Whether the automatic backups are enabled
+	Enabled bool `json:"Enabled" example:"true"`
+	// Cron expression of the backup schedule
+	CronExpression string `json:"CronExpression" example:"0 2 * * *"`
+	// Destination of the backup archives
+	Destination portainer.BackupDestinationType `json:"Destination" example:"s3"`
+	// Unix timestamp of the next scheduled backup
+	NextRun int64 `json:"NextRun,omitempty" example:"1587399600"`
+	// Unix timestamp of the last backup attempt
+	LastRun int64 `json:"LastRun,omitempty" example:"1587399600"`
+	// Whether the last backup attempt succeeded
+	LastRunSuccess bool `json:"LastRunSuccess" example:"true"`
+	// Error of the last backup attempt
+	LastRunError string `json:"LastRunError,omitempty"`
+	// Name of the archive created by the last successful backup
+	LastArchive string `json:"LastArchive,omitempty" example:"portainer-backup_2020-04-20_16-20-00.tar.gz"`
+}
+
+// ScheduledBackupService creates backup archives according to the backup settings
+// and stores them in the configured destination
+type ScheduledBackupService struct {
+	scheduler     *scheduler.Scheduler
+	dataStore     dataservices.DataStore
+	gate          *offlinegate.OfflineGate
+	filestorePath string
+
+	mu       sync.Mutex
+	runMu    sync.Mutex
+	jobID    string
+	schedule cron.Schedule
+	status   Status
+}
+
+// NewScheduledBackupService returns a new ScheduledBackupService instance
+func NewScheduledBackupService(scheduler *scheduler.Scheduler, dataStore dataservices.DataStore, gate *offlinegate.OfflineGate, filestorePath string) *ScheduledBackupService {
+	return &ScheduledBackupService{
+		scheduler:     scheduler,
+		dataStore:     dataStore,
+		gate:          gate,
+		filestorePath: filestorePath,
+	}
+}
+
+// ValidateSettings returns an error when the backup settings cannot be used to schedule backups
+func ValidateSettings(settings portainer.BackupSettings) error {
+	if settings.KeepCount < 0 || settings.KeepDays < 0 {
+		return errors.New("Invalid retention policy, values cannot be negative")
+	}
+
+	if !settings.Enabled {
+		return nil
+	}
+
+	_, err := cron.ParseStandard(settings.CronExpression)
+	if err != nil {
+		return errors.Wrap(err, "Invalid cron expression")
+	}
+
+	switch settings.Destination {
+	case portainer.BackupDestinationLocal:
+		if settings.LocalDirectory == "" {
+			return errors.New("Invalid local directory")
+		}
+	case portainer.BackupDestinationS3:
+		if settings.S3Settings.BucketName == "" {
+			return errors.New("Invalid S3 bucket name")
+		}
+
+		if settings.S3Settings.AccessKeyID == "" || settings.S3Settings.SecretAccessKey == "" {
+			return errors.New("Invalid S3 credentials")
+		}
+	default:
+		return errors.New("Invalid backup destination, valid values are local or s3")
+	}
+
+	return nil
+}
+
+// Start schedules the backups described by the settings stored in the database
+func (service *ScheduledBackupService) Start() error {
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	return service.Reschedule(settings.BackupSettings)
+}
+
+// Reschedule replaces the current backup schedule with the one described by the given settings
+func (service *ScheduledBackupService) Reschedule(settings portainer.BackupSettings) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if service.jobID != "" {
+		err := service.scheduler.StopJob(service.jobID)
+		if err != nil {
+			return err
+		}
+
+		service.jobID = ""
+	}
+
+	service.schedule = nil
+	service.status.Enabled = settings.Enabled
+	service.status.CronExpression = settings.CronExpression
+	service.status.Destination = settings.Destination
+
+	if !settings.Enabled {
+		return nil
+	}
+
+	schedule, err := cron.ParseStandard(settings.CronExpression)
+	if err != nil {
+		return errors.Wrapf(err, "invalid cron expression %q", settings.CronExpression)
+	}
+
+	jobID, err := service.scheduler.StartJobWithCron(settings.CronExpression, func() error {
+		_, err := service.Run()
+		if err != nil {
+			log.Error().Err(err).Msg("scheduled backup failed")
+		}
+
+		// the job keeps running on failure, the error is reported by the backup status
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	service.jobID = jobID
+	service.schedule = schedule
+
+	return nil
+}
+
+// Run creates a backup archive, stores it in the configured destination and removes the archives
+// exceeding the retention policy. Returns the name of the stored archive.
+func (service *ScheduledBackupService) Run() (string, error) {
+	service.runMu.Lock()
+	defer service.runMu.Unlock()
+
+	name, err := service.run()
+
+	service.mu.Lock()
+	service.status.LastRun = time.Now().Unix()
+	service.status.LastRunSuccess = err == nil
+	service.status.LastRunError = ""
+	if err != nil {
+		service.status.LastRunError = err.Error()
+	} else {
+		service.status.LastArchive = name
+	}
+	service.mu.Unlock()
+
+	return name, err
+}
+
+func (service *ScheduledBackupService) run() (string, error) {
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to retrieve the settings")
+	}
+	backupSettings := settings.BackupSettings
+
+	destination, err := NewDestination(backupSettings)
+	if err != nil {
+		return "", err
+	}
+
+	archivePath, err := CreateBackupArchive(backupSettings.Password, service.gate, service.dataStore, service.filestorePath)
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(filepath.Dir(archivePath))
+
+	name := ArchivePrefix + filepath.Base(archivePath)
+
+	err = destination.Upload(name, archivePath)
+	if err != nil {
+		return "", err
+	}
+
+	log.Info().Str("archive", name).Str("destination", string(backupSettings.Destination)).Msg("backup created")
+
+	archives, err := destination.List()
+	if err != nil {
+		return name, errors.WithMessage(err, "backup created but the retention policy could not be applied")
+	}
+
+	for _, archive := range ExpiredArchives(archives, backupSettings.KeepCount, backupSettings.KeepDays, time.Now()) {
+		if archive.Name == name {
+			continue
+		}
+
+		err = destination.Delete(archive.Name)
+		if err != nil {
+			return name, errors.WithMessage(err, "backup created but the retention policy could not be applied")
+		}
+
+		log.Debug().Str("archive", archive.Name).Msg("expired backup removed")
+	}
+
+	return name, nil
+}
+
+// Status returns the state of the automatic backups
+func (service *ScheduledBackupService) Status() Status {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	status := service.status
+	if service.schedule != nil {
+		status.NextRun = service.schedule.Next(time.Now()).Unix()
+	}
+
+	return status
+}
@@ -65,6 +65,24 @@ func (service *Service) GetTunnelDetails(endpointID portainer.EndpointID) portai
 	return *service.getTunnelDetails(endpointID)
 }
 
+// TunnelStatusCounts returns the number of tunnels for each tunnel status
+func (service *Service) TunnelStatusCounts() map[string]int {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	counts := map[string]int{
+		portainer.EdgeAgentIdle:               0,
+		portainer.EdgeAgentManagementRequired: 0,
+		portainer.EdgeAgentActive:             0,
+	}
+
+	for _, tunnel := range service.tunnelDetailsMap {
+		counts[tunnel.Status]++
+	}
+
+	return counts
+}
+
 // GetActiveTunnel retrieves an active tunnel which allows communicating with edge agent
 func (service *Service) GetActiveTunnel(endpoint *portainer.Endpoint) (portainer.TunnelDetails, error) {
 	if endpoint.Edge.AsyncMode {
@@ -21,6 +21,7 @@ var (
 	errSocketOrNamedPipeNotFound     = errors.New("Unable to locate Unix socket or named pipe")
 	errInvalidSnapshotInterval       = errors.New("Invalid snapshot interval")
 	errAdminPassExcludeAdminPassFile = errors.New("Cannot use --admin-password with --admin-password-file")
+	errMigrateDatastoreToBoltDB      = errors.New("Cannot use --migrate-datastore without selecting another backend with --datastore-type")
 )
 
 // ParseFlags parse the CLI flags and return a portainer.Flags struct
@@ -63,6 +64,12 @@ func (*Service) ParseFlags(version string) (*portainer.CLIFlags, error) {
 		SecretKeyName:             kingpin.Flag("secret-key-name", "Secret key name for encryption and will be used as /run/secrets/<secret-key-name>.").Default(defaultSecretKeyName).String(),
 		LogLevel:                  kingpin.Flag("log-level", "Set the minimum logging level to show").Default("INFO").Enum("DEBUG", "INFO", "WARN", "ERROR"),
 		LogMode:                   kingpin.Flag("log-mode", "Set the logging output mode").Default("PRETTY").Enum("PRETTY", "JSON"),
+		AuditLogRetention:         kingpin.Flag("audit-log-retention", "Number of days the audit logs are kept, 0 keeps them forever").Default("90").Int(),
+		Metrics:                   kingpin.Flag("metrics", "Expose Prometheus metrics on /metrics").Bool(),
+		MetricsToken:              kingpin.Flag("metrics-token", "Bearer token required to scrape the metrics, the metrics are public when empty").String(),
+		DatastoreType:             kingpin.Flag("datastore-type", "Database backend used to store the Portainer data").Default("boltdb").Enum("boltdb", "sqlite"),
+		MigrateDatastore:          kingpin.Flag("migrate-datastore", "Copy the BoltDB database to the backend selected with --datastore-type, validate it and exit").Bool(),
+		HelmBackend:               kingpin.Flag("helm-backend", "Helm package manager backend, sdk requires a build with the helmsdk tag").Default("binary").Enum("binary", "sdk"),
 	}
 
 	kingpin.Parse()
@@ -97,6 +104,10 @@ func (*Service) ValidateFlags(flags *portainer.CLIFlags) error {
 		return errAdminPassExcludeAdminPassFile
 	}
 
+	if *flags.MigrateDatastore && *flags.DatastoreType == "boltdb" {
+		return errMigrateDatastoreToBoltDB
+	}
+
 	return nil
 }
 
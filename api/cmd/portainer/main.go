@@ -32,6 +32,7 @@ import (
 	"github.com/portainer/portainer/api/http/client"
 	"github.com/portainer/portainer/api/http/proxy"
 	kubeproxy "github.com/portainer/portainer/api/http/proxy/factory/kubernetes"
+	"github.com/portainer/portainer/api/internal/audit"
 	"github.com/portainer/portainer/api/internal/authorization"
 	"github.com/portainer/portainer/api/internal/edge"
 	"github.com/portainer/portainer/api/internal/edge/edgestacks"
@@ -42,9 +43,14 @@ import (
 	"github.com/portainer/portainer/api/kubernetes"
 	kubecli "github.com/portainer/portainer/api/kubernetes/cli"
 	"github.com/portainer/portainer/api/ldap"
+	"github.com/portainer/portainer/api/metrics"
+	"github.com/portainer/portainer/api/mfa"
+	"github.com/portainer/portainer/api/notifications"
 	"github.com/portainer/portainer/api/oauth"
+	"github.com/portainer/portainer/api/scanner"
 	"github.com/portainer/portainer/api/scheduler"
 	"github.com/portainer/portainer/api/stacks/deployments"
+	"github.com/portainer/portainer/api/stacks/drift"
 	"github.com/portainer/portainer/pkg/featureflags"
 	"github.com/portainer/portainer/pkg/libhelm"
 
@@ -76,8 +82,22 @@ func initFileService(dataStorePath string) portainer.FileService {
 	return fileService
 }
 
+func migrateDatastore(flags *portainer.CLIFlags, secretKey []byte) {
+	counts, err := database.MigrateDatastore(*flags.DatastoreType, *flags.Data, secretKey)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed migrating the datastore")
+	}
+
+	for bucket, count := range counts {
+		log.Info().Str("bucket", bucket).Int("objects", count).Msg("bucket migrated")
+	}
+
+	log.Info().Str("datastore_type", *flags.DatastoreType).Msg("exiting datastore migration, restart Portainer with the same --datastore-type to use the new datastore")
+	os.Exit(0)
+}
+
 func initDataStore(flags *portainer.CLIFlags, secretKey []byte, fileService portainer.FileService, shutdownCtx context.Context) dataservices.DataStore {
-	connection, err := database.NewDatabase("boltdb", *flags.Data, secretKey)
+	connection, err := database.NewDatabase(*flags.DatastoreType, *flags.Data, secretKey)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed creating database connection")
 	}
@@ -86,8 +106,6 @@ func initDataStore(flags *portainer.CLIFlags, secretKey []byte, fileService port
 		bconn.MaxBatchSize = *flags.MaxBatchSize
 		bconn.MaxBatchDelay = *flags.MaxBatchDelay
 		bconn.InitialMmapSize = *flags.InitialMmapSize
-	} else {
-		log.Fatal().Msg("failed creating database connection: expecting a boltdb database type but a different one was received")
 	}
 
 	store := datastore.NewStore(*flags.Data, fileService, connection)
@@ -175,14 +193,29 @@ func initKubernetesDeployer(kubernetesTokenCacheManager *kubeproxy.TokenCacheMan
 	return exec.NewKubernetesDeployer(kubernetesTokenCacheManager, kubernetesClientFactory, dataStore, reverseTunnelService, signatureService, proxyManager, assetsPath)
 }
 
-func initHelmPackageManager(assetsPath string) (libhelm.HelmPackageManager, error) {
-	return libhelm.NewHelmPackageManager(libhelm.HelmConfig{BinaryPath: assetsPath})
+func initHelmPackageManager(backend, assetsPath string) (libhelm.HelmPackageManager, error) {
+	return libhelm.NewHelmPackageManager(libhelm.HelmConfig{Backend: backend, BinaryPath: assetsPath})
 }
 
 func initAPIKeyService(datastore dataservices.DataStore) apikey.APIKeyService {
 	return apikey.NewAPIKeyService(datastore.APIKeyRepository(), datastore.User())
 }
 
+// initMFAService encrypts the TOTP secrets with the encryption key of the database when there is one,
+// otherwise with a key stored in the data directory
+func initMFAService(dataStore dataservices.DataStore, dataPath string, encryptionKey []byte) (*mfa.Service, error) {
+	key := encryptionKey
+	if key == nil {
+		var err error
+		key, err = mfa.LoadOrCreateKey(path.Join(dataPath, "mfa.key"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return mfa.NewService(dataStore, key), nil
+}
+
 func initJWTService(userSessionTimeout string, dataStore dataservices.DataStore) (dataservices.JWTService, error) {
 	if userSessionTimeout == "" {
 		userSessionTimeout = portainer.DefaultUserSessionTimeout
@@ -246,12 +279,13 @@ func initSnapshotService(
 	dataStore dataservices.DataStore,
 	dockerClientFactory *docker.ClientFactory,
 	kubernetesClientFactory *kubecli.ClientFactory,
+	notificationService *notifications.Service,
 	shutdownCtx context.Context,
 ) (portainer.SnapshotService, error) {
 	dockerSnapshotter := docker.NewSnapshotter(dockerClientFactory)
 	kubernetesSnapshotter := kubernetes.NewSnapshotter(kubernetesClientFactory)
 
-	snapshotService, err := snapshot.NewService(snapshotIntervalFromFlag, dataStore, dockerSnapshotter, kubernetesSnapshotter, shutdownCtx)
+	snapshotService, err := snapshot.NewService(snapshotIntervalFromFlag, dataStore, dockerSnapshotter, kubernetesSnapshotter, notificationService, shutdownCtx)
 	if err != nil {
 		return nil, err
 	}
@@ -517,6 +551,10 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 		log.Info().Msg("proceeding without encryption key")
 	}
 
+	if *flags.MigrateDatastore {
+		migrateDatastore(flags, encryptionKey)
+	}
+
 	dataStore := initDataStore(flags, encryptionKey, fileService, shutdownCtx)
 
 	if err := dataStore.CheckCurrentEdition(); err != nil {
@@ -530,6 +568,11 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 
 	apiKeyService := initAPIKeyService(dataStore)
 
+	mfaService, err := initMFAService(dataStore, *flags.Data, encryptionKey)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed initializing the MFA service")
+	}
+
 	settings, err := dataStore.Settings().Settings()
 	if err != nil {
 		log.Fatal().Err(err).Msg("")
@@ -540,6 +583,15 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 		log.Fatal().Err(err).Msg("failed initializing JWT service")
 	}
 
+	if settings.SessionIdleTimeout != "" {
+		sessionIdleTimeout, err := time.ParseDuration(settings.SessionIdleTimeout)
+		if err != nil {
+			log.Warn().Err(err).Str("session_idle_timeout", settings.SessionIdleTimeout).Msg("invalid session idle timeout, the sessions are not revoked on inactivity")
+		} else {
+			jwtService.SetSessionIdleTimeout(sessionIdleTimeout)
+		}
+	}
+
 	ldapService := initLDAPService()
 
 	oauthService := initOAuthService()
@@ -571,10 +623,20 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 
 	reverseTunnelService := chisel.NewService(dataStore, shutdownCtx)
 
+	if *flags.Metrics {
+		err = metrics.RegisterTunnelCollector(reverseTunnelService.TunnelStatusCounts)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed registering the tunnel metrics")
+		}
+	}
+
 	dockerClientFactory := initDockerClientFactory(digitalSignatureService, reverseTunnelService)
 	kubernetesClientFactory, err := initKubernetesClientFactory(digitalSignatureService, reverseTunnelService, dataStore, instanceID, *flags.AddrHTTPS, settings.UserSessionTimeout)
 
-	snapshotService, err := initSnapshotService(*flags.SnapshotInterval, dataStore, dockerClientFactory, kubernetesClientFactory, shutdownCtx)
+	notificationService := notifications.NewService(dataStore, shutdownCtx)
+	notificationService.Start()
+
+	snapshotService, err := initSnapshotService(*flags.SnapshotInterval, dataStore, dockerClientFactory, kubernetesClientFactory, notificationService, shutdownCtx)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed initializing snapshot service")
 	}
@@ -607,7 +669,7 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 
 	kubernetesDeployer := initKubernetesDeployer(kubernetesTokenCacheManager, kubernetesClientFactory, dataStore, reverseTunnelService, digitalSignatureService, proxyManager, *flags.Assets)
 
-	helmPackageManager, err := initHelmPackageManager(*flags.Assets)
+	helmPackageManager, err := initHelmPackageManager(*flags.HelmBackend, *flags.Assets)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed initializing helm package manager")
 	}
@@ -676,9 +738,38 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 	}
 
 	scheduler := scheduler.NewScheduler(shutdownCtx)
-	stackDeployer := deployments.NewStackDeployer(swarmStackManager, composeStackManager, kubernetesDeployer)
+
+	imageScanService := scanner.NewService(scanner.NewTrivyScanner(*flags.Assets, path.Join(*flags.Data, "trivy")), dataStore, dockerClientFactory, fileService, scheduler)
+	err = imageScanService.Start()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to schedule the image vulnerability scans")
+	}
+
+	ldapSyncService := ldap.NewSyncService(dataStore, ldapService, apiKeyService, scheduler)
+	err = ldapSyncService.Start()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to schedule the LDAP synchronization")
+	}
+
+	stackDeployer := deployments.NewStackDeployer(swarmStackManager, composeStackManager, kubernetesDeployer, notificationService, imageScanService)
 	deployments.StartStackSchedules(scheduler, stackDeployer, dataStore, gitService)
 
+	driftService := drift.NewService(dataStore, dockerClientFactory, fileService, composeStackManager, stackDeployer, scheduler)
+	err = driftService.Start()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to schedule the stack drift checks")
+	}
+
+	edgeStacksAutoUpdateService := edgestacks.NewAutoUpdateService(dataStore, fileService, gitService, kubernetesDeployer, scheduler)
+	err = edgeStacksAutoUpdateService.StartSchedules()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to schedule the edge stacks auto updates")
+	}
+
+	edgestacks.NewRolloutService(dataStore, scheduler).Start()
+
+	audit.StartRetentionJob(scheduler, dataStore, *flags.AuditLogRetention)
+
 	sslDBSettings, err := dataStore.SSLSettings().Settings()
 	if err != nil {
 		log.Fatal().Msg("failed to fetch SSL settings from DB")
@@ -709,9 +800,12 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 		BindAddress:                 *flags.Addr,
 		BindAddressHTTPS:            *flags.AddrHTTPS,
 		HTTPEnabled:                 sslDBSettings.HTTPEnabled,
+		MetricsEnabled:              *flags.Metrics,
+		MetricsToken:                *flags.MetricsToken,
 		AssetsPath:                  *flags.Assets,
 		DataStore:                   dataStore,
 		EdgeStacksService:           edgeStacksService,
+		EdgeStacksAutoUpdateService: edgeStacksAutoUpdateService,
 		SwarmStackManager:           swarmStackManager,
 		ComposeStackManager:         composeStackManager,
 		KubernetesDeployer:          kubernetesDeployer,
@@ -721,6 +815,8 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 		JWTService:                  jwtService,
 		FileService:                 fileService,
 		LDAPService:                 ldapService,
+		LDAPSyncService:             ldapSyncService,
+		NotificationService:         notificationService,
 		OAuthService:                oauthService,
 		GitService:                  gitService,
 		OpenAMTService:              openAMTService,
@@ -731,6 +827,9 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 		SnapshotService:             snapshotService,
 		SSLService:                  sslService,
 		DockerClientFactory:         dockerClientFactory,
+		ImageScanService:            imageScanService,
+		DriftService:                driftService,
+		MFAService:                  mfaService,
 		KubernetesClientFactory:     kubernetesClientFactory,
 		Scheduler:                   scheduler,
 		ShutdownCtx:                 shutdownCtx,
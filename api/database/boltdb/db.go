@@ -11,6 +11,7 @@ import (
 
 	portainer "github.com/portainer/portainer/api"
 	dserrors "github.com/portainer/portainer/api/dataservices/errors"
+	"github.com/portainer/portainer/api/metrics"
 
 	"github.com/rs/zerolog/log"
 	bolt "go.etcd.io/bbolt"
@@ -158,6 +159,8 @@ func (connection *DbConnection) txFn(fn func(portainer.Transaction) error) func(
 
 // UpdateTx executes the given function inside a read-write transaction
 func (connection *DbConnection) UpdateTx(fn func(portainer.Transaction) error) error {
+	defer observeTransaction("update", time.Now())
+
 	if connection.MaxBatchDelay > 0 && connection.MaxBatchSize > 1 {
 		return connection.Batch(connection.txFn(fn))
 	}
@@ -167,9 +170,15 @@ func (connection *DbConnection) UpdateTx(fn func(portainer.Transaction) error) e
 
 // ViewTx executes the given function inside a read-only transaction
 func (connection *DbConnection) ViewTx(fn func(portainer.Transaction) error) error {
+	defer observeTransaction("view", time.Now())
+
 	return connection.View(connection.txFn(fn))
 }
 
+func observeTransaction(txType string, start time.Time) {
+	metrics.ObserveDBTransaction(txType, time.Since(start))
+}
+
 // BackupTo backs up db to a provided writer.
 // It does hot backup and doesn't block other database reads and writes
 func (connection *DbConnection) BackupTo(w io.Writer) error {
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/portainer/portainer/api/database/boltdb"
+	"github.com/portainer/portainer/api/database/sqlite"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	bolt "go.etcd.io/bbolt"
+)
+
+// MigrateDatastore copies every bucket of the BoltDB database stored in storePath to a new database
+// of type storeType, then validates that each bucket holds the same number of objects in both databases.
+// The values are copied as is, the target database is encrypted when the BoltDB database is.
+// It returns the number of objects copied per bucket.
+func MigrateDatastore(storeType, storePath string, encryptionKey []byte) (map[string]int, error) {
+	if storeType != "sqlite" {
+		return nil, fmt.Errorf("Unsupported target database: %s", storeType)
+	}
+
+	source := &boltdb.DbConnection{Path: storePath, EncryptionKey: encryptionKey}
+	target := &sqlite.DbConnection{Path: storePath, EncryptionKey: encryptionKey}
+
+	needsEncryption, err := source.NeedsEncryptionMigration()
+	if err != nil {
+		return nil, err
+	}
+
+	if needsEncryption {
+		return nil, errors.New("the BoltDB database is not encrypted yet, start Portainer once with the BoltDB backend to encrypt it before migrating")
+	}
+
+	if _, err := os.Stat(source.GetDatabaseFilePath()); err != nil {
+		return nil, errors.Wrap(err, "unable to find the BoltDB database")
+	}
+
+	if _, err := target.NeedsEncryptionMigration(); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(target.GetDatabaseFilePath()); err == nil {
+		return nil, fmt.Errorf("the target database %s already exists", target.GetDatabaseFilePath())
+	}
+
+	counts, err := copyDatastore(source, target)
+	if err != nil {
+		// Remove the partial target database so that the migration can be retried
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			if err := os.Remove(target.GetDatabaseFilePath() + suffix); err != nil && !os.IsNotExist(err) {
+				log.Warn().Err(err).Str("path", target.GetDatabaseFilePath()+suffix).Msg("unable to remove the partial target database")
+			}
+		}
+	}
+
+	return counts, err
+}
+
+// copyDatastore copies every bucket of source to target and validates the number of objects of each bucket
+func copyDatastore(source *boltdb.DbConnection, target *sqlite.DbConnection) (map[string]int, error) {
+	if err := source.Open(); err != nil {
+		return nil, errors.Wrap(err, "unable to open the BoltDB database")
+	}
+	defer source.Close()
+
+	if err := target.Open(); err != nil {
+		return nil, errors.Wrap(err, "unable to open the target database")
+	}
+	defer target.Close()
+
+	counts := map[string]int{}
+
+	err := source.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			bucketName := string(name)
+			count := 0
+
+			err := target.ImportBucket(bucketName, bucket.Sequence(), func(put func(key, value []byte) error) error {
+				return bucket.ForEach(func(k, v []byte) error {
+					if v == nil {
+						// nested buckets are not used by Portainer
+						return nil
+					}
+
+					count++
+
+					return put(k, v)
+				})
+			})
+			if err != nil {
+				return errors.Wrapf(err, "unable to copy the %s bucket", bucketName)
+			}
+
+			counts[bucketName] = count
+
+			log.Debug().Str("bucket", bucketName).Int("objects", count).Msg("bucket copied")
+
+			return nil
+		})
+	})
+	if err != nil {
+		return counts, err
+	}
+
+	for bucketName, expected := range counts {
+		count, err := target.CountObjects(bucketName)
+		if err != nil {
+			return counts, err
+		}
+
+		if count != expected {
+			return counts, fmt.Errorf("the %s bucket holds %d objects in the target database, %d were expected", bucketName, count, expected)
+		}
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"os"
+	"testing"
+
+	"github.com/portainer/portainer/api/database/boltdb"
+	"github.com/portainer/portainer/api/database/sqlite"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testObject struct {
+	ID   int
+	Name string
+}
+
+func TestMigrateDatastore(t *testing.T) {
+	for name, key := range map[string][]byte{"unencrypted": nil, "encrypted": []byte("apassphrasewhichneedstobe32bytes")} {
+		t.Run(name, func(t *testing.T) {
+			is := assert.New(t)
+			storePath := t.TempDir()
+
+			source := &boltdb.DbConnection{Path: storePath, EncryptionKey: key}
+			_, err := source.NeedsEncryptionMigration()
+			require.NoError(t, err)
+			require.NoError(t, source.Open())
+
+			require.NoError(t, source.SetServiceName("objects"))
+			require.NoError(t, source.SetServiceName("version"))
+			require.NoError(t, source.SetServiceName("empty"))
+			for i := 0; i < 3; i++ {
+				err := source.CreateObject("objects", func(id uint64) (int, interface{}) {
+					return int(id), testObject{ID: int(id), Name: "object"}
+				})
+				require.NoError(t, err)
+			}
+			require.NoError(t, source.CreateObjectWithStringId("version", []byte("DB_VERSION"), "80"))
+			require.NoError(t, source.Close())
+
+			counts, err := MigrateDatastore("sqlite", storePath, key)
+			require.NoError(t, err)
+			is.Equal(map[string]int{"objects": 3, "version": 1, "empty": 0}, counts)
+
+			target := &sqlite.DbConnection{Path: storePath, EncryptionKey: key}
+			_, err = target.NeedsEncryptionMigration()
+			require.NoError(t, err)
+			require.NoError(t, target.Open())
+			defer target.Close()
+
+			obj := testObject{}
+			is.NoError(target.GetObject("objects", target.ConvertToKey(2), &obj))
+			is.Equal(testObject{ID: 2, Name: "object"}, obj)
+
+			var version string
+			is.NoError(target.GetObject("version", []byte("DB_VERSION"), &version))
+			is.Equal("80", version)
+
+			is.Equal(4, target.GetNextIdentifier("objects"), "the bucket sequences should be copied")
+
+			_, err = MigrateDatastore("sqlite", storePath, key)
+			is.Error(err, "an existing target database should not be overwritten")
+		})
+	}
+}
+
+func TestMigrateDatastore_RemovesPartialTarget(t *testing.T) {
+	is := assert.New(t)
+	storePath := t.TempDir()
+
+	source := &boltdb.DbConnection{Path: storePath}
+	_, err := source.NeedsEncryptionMigration()
+	require.NoError(t, err)
+	require.NoError(t, source.Open())
+	require.NoError(t, source.SetServiceName("objects"))
+	require.NoError(t, source.Close())
+
+	target := &sqlite.DbConnection{Path: storePath}
+	_, err = target.NeedsEncryptionMigration()
+	require.NoError(t, err)
+
+	// a directory in place of the write-ahead log makes the target database fail after its creation
+	walPath := target.GetDatabaseFilePath() + "-wal"
+	require.NoError(t, os.Mkdir(walPath, 0700))
+
+	_, err = MigrateDatastore("sqlite", storePath, nil)
+	require.Error(t, err)
+	is.NoFileExists(target.GetDatabaseFilePath(), "the partial target database should be removed")
+
+	require.NoError(t, os.RemoveAll(walPath))
+
+	counts, err := MigrateDatastore("sqlite", storePath, nil)
+	require.NoError(t, err, "the migration should be retried")
+	is.Equal(map[string]int{"objects": 0}, counts)
+}
@@ -0,0 +1,416 @@
+// Package sqlite implements a portainer.Connection backed by an embedded SQLite database.
+//
+// The buckets of the BoltDB store are mapped to a single objects table keyed by (bucket, key),
+// and the objects are encoded exactly like the BoltDB backend does, so that the raw content of
+// a BoltDB database can be copied as is.
+//
+// The driver is a pure Go port of SQLite, the backend is available in the binaries built with CGO_ENABLED=0.
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/metrics"
+
+	"github.com/rs/zerolog/log"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	DatabaseFileName          = "portainer.sqlite"
+	EncryptedDatabaseFileName = "portainer.esqlite"
+)
+
+var (
+	ErrHaveEncryptedAndUnencrypted = errors.New("Portainer has detected both an encrypted and un-encrypted database and cannot start.  Only one database should exist")
+	ErrHaveEncryptedWithNoKey      = errors.New("The portainer database is encrypted, but no secret was loaded")
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS buckets (
+	name TEXT NOT NULL PRIMARY KEY,
+	sequence INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS objects (
+	bucket TEXT NOT NULL,
+	key BLOB NOT NULL,
+	value BLOB NOT NULL,
+	PRIMARY KEY (bucket, key)
+) WITHOUT ROWID;
+`
+
+type DbConnection struct {
+	Path          string
+	EncryptionKey []byte
+	isEncrypted   bool
+
+	// SQLite only supports a single writer, the write transactions are serialized here
+	// rather than failing with SQLITE_BUSY
+	writeLock sync.Mutex
+
+	*sql.DB
+}
+
+// GetDatabaseFileName get the database filename
+func (connection *DbConnection) GetDatabaseFileName() string {
+	if connection.IsEncryptedStore() {
+		return EncryptedDatabaseFileName
+	}
+
+	return DatabaseFileName
+}
+
+// GetDatabaseFilePath get the path + filename for the database file
+func (connection *DbConnection) GetDatabaseFilePath() string {
+	return path.Join(connection.Path, connection.GetDatabaseFileName())
+}
+
+// GetStorePath get the filename and path for the database file
+func (connection *DbConnection) GetStorePath() string {
+	return connection.Path
+}
+
+func (connection *DbConnection) SetEncrypted(flag bool) {
+	connection.isEncrypted = flag
+}
+
+// IsEncryptedStore returns true if the database is encrypted
+func (connection *DbConnection) IsEncryptedStore() bool {
+	return connection.getEncryptionKey() != nil
+}
+
+// NeedsEncryptionMigration returns true if database encryption is enabled and
+// we have an un-encrypted DB that requires migration to an encrypted DB.
+// It follows the same rules as the BoltDB backend.
+func (connection *DbConnection) NeedsEncryptionMigration() (bool, error) {
+	if connection.EncryptionKey != nil {
+		connection.SetEncrypted(true)
+	}
+
+	_, err := os.Stat(path.Join(connection.Path, DatabaseFileName))
+	haveDbFile := err == nil
+
+	_, err = os.Stat(path.Join(connection.Path, EncryptedDatabaseFileName))
+	haveEdbFile := err == nil
+
+	if haveDbFile && haveEdbFile {
+		return false, ErrHaveEncryptedAndUnencrypted
+	}
+
+	if haveDbFile && connection.EncryptionKey != nil {
+		return true, nil
+	}
+
+	if haveEdbFile && connection.EncryptionKey == nil {
+		return false, ErrHaveEncryptedWithNoKey
+	}
+
+	return false, nil
+}
+
+// Open opens and initializes the SQLite database.
+func (connection *DbConnection) Open() error {
+	log.Info().Str("filename", connection.GetDatabaseFileName()).Msg("loading PortainerDB")
+
+	databasePath := connection.GetDatabaseFilePath()
+
+	// Create the file with restricted permissions, the driver would use 0644
+	f, err := os.OpenFile(databasePath, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	db, err := sql.Open("sqlite", "file:"+databasePath+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)")
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return fmt.Errorf("failed initializing the database schema: %w", err)
+	}
+
+	connection.DB = db
+
+	return nil
+}
+
+// Close closes the SQLite database.
+// Safe to being called multiple times.
+func (connection *DbConnection) Close() error {
+	if connection.DB == nil {
+		return nil
+	}
+
+	err := connection.DB.Close()
+	connection.DB = nil
+
+	return err
+}
+
+// UpdateTx executes the given function inside a read-write transaction
+func (connection *DbConnection) UpdateTx(fn func(portainer.Transaction) error) error {
+	defer observeTransaction("update", time.Now())
+
+	connection.writeLock.Lock()
+	defer connection.writeLock.Unlock()
+
+	return connection.runTx(false, fn)
+}
+
+// ViewTx executes the given function inside a read-only transaction
+func (connection *DbConnection) ViewTx(fn func(portainer.Transaction) error) error {
+	defer observeTransaction("view", time.Now())
+
+	return connection.runTx(true, fn)
+}
+
+func (connection *DbConnection) runTx(readOnly bool, fn func(portainer.Transaction) error) error {
+	tx, err := connection.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = fn(&DbTransaction{conn: connection, tx: tx, readOnly: readOnly})
+	if err != nil || readOnly {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func observeTransaction(txType string, start time.Time) {
+	metrics.ObserveDBTransaction(txType, time.Since(start))
+}
+
+// BackupTo backs up db to a provided writer.
+// It uses VACUUM INTO to get a consistent copy without blocking the other database reads and writes
+func (connection *DbConnection) BackupTo(w io.Writer) error {
+	dir, err := os.MkdirTemp("", "portainer-sqlite-backup")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	backupPath := filepath.Join(dir, DatabaseFileName)
+	if _, err := connection.Exec("VACUUM INTO ?", backupPath); err != nil {
+		return err
+	}
+
+	f, err := os.Open(backupPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+
+	return err
+}
+
+func (connection *DbConnection) ExportRaw(filename string) error {
+	b, err := connection.ExportJSON(true)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, b, 0600)
+}
+
+// ConvertToKey returns an 8-byte big endian representation of v.
+// This is the same encoding as the BoltDB backend, the keys sort in the same order.
+func (connection *DbConnection) ConvertToKey(v int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(v))
+	return b
+}
+
+// SetServiceName creates the bucket if it does not exist yet.
+func (connection *DbConnection) SetServiceName(bucketName string) error {
+	return connection.UpdateTx(func(tx portainer.Transaction) error {
+		return tx.SetServiceName(bucketName)
+	})
+}
+
+// GetObject is a generic function used to retrieve an unmarshalled object from a database.
+func (connection *DbConnection) GetObject(bucketName string, key []byte, object interface{}) error {
+	return connection.ViewTx(func(tx portainer.Transaction) error {
+		return tx.GetObject(bucketName, key, object)
+	})
+}
+
+func (connection *DbConnection) getEncryptionKey() []byte {
+	if !connection.isEncrypted {
+		return nil
+	}
+
+	return connection.EncryptionKey
+}
+
+// UpdateObject is a generic function used to update an object inside a database.
+func (connection *DbConnection) UpdateObject(bucketName string, key []byte, object interface{}) error {
+	return connection.UpdateTx(func(tx portainer.Transaction) error {
+		return tx.UpdateObject(bucketName, key, object)
+	})
+}
+
+// UpdateObjectFunc is a generic function used to update an object safely without race conditions.
+func (connection *DbConnection) UpdateObjectFunc(bucketName string, key []byte, object any, updateFn func()) error {
+	return connection.UpdateTx(func(tx portainer.Transaction) error {
+		err := tx.GetObject(bucketName, key, object)
+		if err != nil {
+			return err
+		}
+
+		updateFn()
+
+		return tx.UpdateObject(bucketName, key, object)
+	})
+}
+
+// DeleteObject is a generic function used to delete an object inside a database.
+func (connection *DbConnection) DeleteObject(bucketName string, key []byte) error {
+	return connection.UpdateTx(func(tx portainer.Transaction) error {
+		return tx.DeleteObject(bucketName, key)
+	})
+}
+
+// DeleteAllObjects delete all objects where matching() returns (id, ok).
+func (connection *DbConnection) DeleteAllObjects(bucketName string, obj interface{}, matching func(o interface{}) (id int, ok bool)) error {
+	return connection.UpdateTx(func(tx portainer.Transaction) error {
+		return tx.DeleteAllObjects(bucketName, obj, matching)
+	})
+}
+
+// GetNextIdentifier is a generic function that returns the specified bucket identifier incremented by 1.
+func (connection *DbConnection) GetNextIdentifier(bucketName string) int {
+	var identifier int
+
+	_ = connection.UpdateTx(func(tx portainer.Transaction) error {
+		identifier = tx.GetNextIdentifier(bucketName)
+		return nil
+	})
+
+	return identifier
+}
+
+// CreateObject creates a new object in the bucket, using the next bucket sequence id
+func (connection *DbConnection) CreateObject(bucketName string, fn func(uint64) (int, interface{})) error {
+	return connection.UpdateTx(func(tx portainer.Transaction) error {
+		return tx.CreateObject(bucketName, fn)
+	})
+}
+
+// CreateObjectWithId creates a new object in the bucket, using the specified id
+func (connection *DbConnection) CreateObjectWithId(bucketName string, id int, obj interface{}) error {
+	return connection.UpdateTx(func(tx portainer.Transaction) error {
+		return tx.CreateObjectWithId(bucketName, id, obj)
+	})
+}
+
+// CreateObjectWithStringId creates a new object in the bucket, using the specified id
+func (connection *DbConnection) CreateObjectWithStringId(bucketName string, id []byte, obj interface{}) error {
+	return connection.UpdateTx(func(tx portainer.Transaction) error {
+		return tx.CreateObjectWithStringId(bucketName, id, obj)
+	})
+}
+
+func (connection *DbConnection) GetAll(bucketName string, obj interface{}, append func(o interface{}) (interface{}, error)) error {
+	return connection.ViewTx(func(tx portainer.Transaction) error {
+		return tx.GetAll(bucketName, obj, append)
+	})
+}
+
+func (connection *DbConnection) GetAllWithJsoniter(bucketName string, obj interface{}, append func(o interface{}) (interface{}, error)) error {
+	return connection.ViewTx(func(tx portainer.Transaction) error {
+		return tx.GetAllWithJsoniter(bucketName, obj, append)
+	})
+}
+
+func (connection *DbConnection) GetAllWithKeyPrefix(bucketName string, keyPrefix []byte, obj interface{}, append func(o interface{}) (interface{}, error)) error {
+	return connection.ViewTx(func(tx portainer.Transaction) error {
+		return tx.GetAllWithKeyPrefix(bucketName, keyPrefix, obj, append)
+	})
+}
+
+// BackupMetadata will return a copy of the sequence numbers for all buckets.
+func (connection *DbConnection) BackupMetadata() (map[string]interface{}, error) {
+	buckets := map[string]interface{}{}
+
+	rows, err := connection.Query("SELECT name, sequence FROM buckets")
+	if err != nil {
+		return buckets, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var sequence int
+		if err := rows.Scan(&name, &sequence); err != nil {
+			return buckets, err
+		}
+
+		buckets[name] = sequence
+	}
+
+	return buckets, rows.Err()
+}
+
+// RestoreMetadata will restore the sequence numbers for all buckets.
+func (connection *DbConnection) RestoreMetadata(s map[string]interface{}) error {
+	var err error
+
+	for bucketName, v := range s {
+		id, ok := v.(float64) // JSON ints are unmarshalled to interface as float64. See: https://pkg.go.dev/encoding/json#Decoder.Decode
+		if !ok {
+			log.Error().Str("bucket", bucketName).Msg("failed to restore metadata to bucket, skipped")
+			continue
+		}
+
+		err = connection.UpdateTx(func(tx portainer.Transaction) error {
+			return tx.(*DbTransaction).setSequence(bucketName, uint64(id))
+		})
+	}
+
+	return err
+}
+
+// ImportBucket replaces the content of a bucket with raw entries, the values are stored as is.
+// forEach must call put for every entry of the bucket.
+func (connection *DbConnection) ImportBucket(bucketName string, sequence uint64, forEach func(put func(key, value []byte) error) error) error {
+	return connection.UpdateTx(func(portainerTx portainer.Transaction) error {
+		tx := portainerTx.(*DbTransaction)
+
+		if err := tx.setSequence(bucketName, sequence); err != nil {
+			return err
+		}
+
+		if _, err := tx.tx.Exec("DELETE FROM objects WHERE bucket = ?", bucketName); err != nil {
+			return err
+		}
+
+		return forEach(func(key, value []byte) error {
+			return tx.put(bucketName, key, value)
+		})
+	})
+}
+
+// CountObjects returns the number of objects stored in a bucket
+func (connection *DbConnection) CountObjects(bucketName string) (int, error) {
+	var count int
+	err := connection.QueryRow("SELECT COUNT(*) FROM objects WHERE bucket = ?", bucketName).Scan(&count)
+
+	return count, err
+}
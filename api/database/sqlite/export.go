@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"encoding/json"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ExportJSON creates a JSON representation of the database, in the same format as the BoltDB backend.
+// You can include the database's metadata or ignore it.
+func (connection *DbConnection) ExportJSON(metadata bool) ([]byte, error) {
+	backup := make(map[string]interface{})
+
+	meta, err := connection.BackupMetadata()
+	if err != nil {
+		return []byte("{}"), err
+	}
+
+	if metadata {
+		backup["__metadata"] = meta
+	}
+
+	tx, err := connection.Begin()
+	if err != nil {
+		return []byte("{}"), err
+	}
+	defer tx.Rollback()
+
+	dbTx := &DbTransaction{conn: connection, tx: tx, readOnly: true}
+
+	for bucketName := range meta {
+		entries, err := dbTx.entries(bucketName, nil)
+		if err != nil {
+			return []byte("{}"), err
+		}
+
+		if bucketName == "version" {
+			version := make(map[string]string)
+			for _, e := range entries {
+				version[string(e.key)] = string(e.value)
+			}
+
+			backup[bucketName] = version
+			continue
+		}
+
+		var list []interface{}
+		for _, e := range entries {
+			var obj interface{}
+			err := connection.UnmarshalObject(e.value, &obj)
+			if err != nil {
+				log.Error().
+					Str("bucket", bucketName).
+					Str("object", string(e.value)).
+					Err(err).
+					Msg("failed to unmarshal")
+
+				obj = e.value
+			}
+
+			list = append(list, obj)
+		}
+
+		if len(list) == 0 {
+			continue
+		}
+
+		if bucketName == "ssl" ||
+			bucketName == "settings" ||
+			bucketName == "tunnel_server" {
+			backup[bucketName] = list[0]
+			continue
+		}
+
+		backup[bucketName] = list
+	}
+
+	return json.MarshalIndent(backup, "", "  ")
+}
@@ -0,0 +1,20 @@
+package sqlite
+
+import "github.com/portainer/portainer/api/database/codec"
+
+// MarshalObject encodes an object to binary format
+func (connection *DbConnection) MarshalObject(object interface{}) ([]byte, error) {
+	return codec.MarshalObject(object, connection.getEncryptionKey())
+}
+
+// UnmarshalObject decodes an object from binary data
+func (connection *DbConnection) UnmarshalObject(data []byte, object interface{}) error {
+	return codec.UnmarshalObject(data, object, connection.getEncryptionKey())
+}
+
+// UnmarshalObjectWithJsoniter decodes an object from binary data
+// using the jsoniter library. It is mainly used to accelerate environment(endpoint)
+// decoding at the moment.
+func (connection *DbConnection) UnmarshalObjectWithJsoniter(data []byte, object interface{}) error {
+	return codec.UnmarshalObjectWithJsoniter(data, object, connection.getEncryptionKey())
+}
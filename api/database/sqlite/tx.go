@@ -0,0 +1,242 @@
+package sqlite
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+
+	dserrors "github.com/portainer/portainer/api/dataservices/errors"
+
+	"github.com/rs/zerolog/log"
+)
+
+var errReadOnlyTransaction = errors.New("cannot write inside a read-only transaction")
+
+type DbTransaction struct {
+	conn     *DbConnection
+	tx       *sql.Tx
+	readOnly bool
+}
+
+type entry struct {
+	key   []byte
+	value []byte
+}
+
+func (tx *DbTransaction) SetServiceName(bucketName string) error {
+	if tx.readOnly {
+		return errReadOnlyTransaction
+	}
+
+	_, err := tx.tx.Exec("INSERT OR IGNORE INTO buckets (name) VALUES (?)", bucketName)
+	return err
+}
+
+func (tx *DbTransaction) GetObject(bucketName string, key []byte, object interface{}) error {
+	var data []byte
+
+	err := tx.tx.QueryRow("SELECT value FROM objects WHERE bucket = ? AND key = ?", bucketName, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return dserrors.ErrObjectNotFound
+	} else if err != nil {
+		return err
+	}
+
+	return tx.conn.UnmarshalObjectWithJsoniter(data, object)
+}
+
+func (tx *DbTransaction) UpdateObject(bucketName string, key []byte, object interface{}) error {
+	data, err := tx.conn.MarshalObject(object)
+	if err != nil {
+		return err
+	}
+
+	return tx.put(bucketName, key, data)
+}
+
+func (tx *DbTransaction) DeleteObject(bucketName string, key []byte) error {
+	if tx.readOnly {
+		return errReadOnlyTransaction
+	}
+
+	_, err := tx.tx.Exec("DELETE FROM objects WHERE bucket = ? AND key = ?", bucketName, key)
+	return err
+}
+
+func (tx *DbTransaction) DeleteAllObjects(bucketName string, obj interface{}, matching func(o interface{}) (id int, ok bool)) error {
+	entries, err := tx.entries(bucketName, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		err := tx.conn.UnmarshalObject(e.value, &obj)
+		if err != nil {
+			return err
+		}
+
+		if id, ok := matching(obj); ok {
+			err := tx.DeleteObject(bucketName, tx.conn.ConvertToKey(id))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (tx *DbTransaction) GetNextIdentifier(bucketName string) int {
+	id, err := tx.nextSequence(bucketName)
+	if err != nil {
+		log.Error().Err(err).Str("bucket", bucketName).Msg("failed to get the next identifer")
+
+		return 0
+	}
+
+	return int(id)
+}
+
+func (tx *DbTransaction) CreateObject(bucketName string, fn func(uint64) (int, interface{})) error {
+	seqId, err := tx.nextSequence(bucketName)
+	if err != nil {
+		return err
+	}
+
+	id, obj := fn(seqId)
+
+	data, err := tx.conn.MarshalObject(obj)
+	if err != nil {
+		return err
+	}
+
+	return tx.put(bucketName, tx.conn.ConvertToKey(id), data)
+}
+
+func (tx *DbTransaction) CreateObjectWithId(bucketName string, id int, obj interface{}) error {
+	data, err := tx.conn.MarshalObject(obj)
+	if err != nil {
+		return err
+	}
+
+	return tx.put(bucketName, tx.conn.ConvertToKey(id), data)
+}
+
+func (tx *DbTransaction) CreateObjectWithStringId(bucketName string, id []byte, obj interface{}) error {
+	data, err := tx.conn.MarshalObject(obj)
+	if err != nil {
+		return err
+	}
+
+	return tx.put(bucketName, id, data)
+}
+
+func (tx *DbTransaction) GetAll(bucketName string, obj interface{}, append func(o interface{}) (interface{}, error)) error {
+	entries, err := tx.entries(bucketName, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		err := tx.conn.UnmarshalObject(e.value, obj)
+		if err != nil {
+			return err
+		}
+
+		obj, err = append(obj)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (tx *DbTransaction) GetAllWithJsoniter(bucketName string, obj interface{}, append func(o interface{}) (interface{}, error)) error {
+	return tx.GetAllWithKeyPrefix(bucketName, nil, obj, append)
+}
+
+func (tx *DbTransaction) GetAllWithKeyPrefix(bucketName string, keyPrefix []byte, obj interface{}, append func(o interface{}) (interface{}, error)) error {
+	entries, err := tx.entries(bucketName, keyPrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		err := tx.conn.UnmarshalObjectWithJsoniter(e.value, obj)
+		if err != nil {
+			return err
+		}
+
+		obj, err = append(obj)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// entries returns the entries of a bucket whose key starts with keyPrefix, sorted by key.
+// The rows are read entirely before being returned so that the callers can write
+// inside the same transaction while iterating.
+func (tx *DbTransaction) entries(bucketName string, keyPrefix []byte) ([]entry, error) {
+	if keyPrefix == nil {
+		keyPrefix = []byte{}
+	}
+
+	rows, err := tx.tx.Query("SELECT key, value FROM objects WHERE bucket = ? AND key >= ? ORDER BY key", bucketName, keyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.key, &e.value); err != nil {
+			return nil, err
+		}
+
+		if !bytes.HasPrefix(e.key, keyPrefix) {
+			break
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+func (tx *DbTransaction) put(bucketName string, key, value []byte) error {
+	if tx.readOnly {
+		return errReadOnlyTransaction
+	}
+
+	_, err := tx.tx.Exec("INSERT INTO objects (bucket, key, value) VALUES (?, ?, ?) ON CONFLICT (bucket, key) DO UPDATE SET value = excluded.value", bucketName, key, value)
+	return err
+}
+
+func (tx *DbTransaction) nextSequence(bucketName string) (uint64, error) {
+	if err := tx.SetServiceName(bucketName); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.tx.Exec("UPDATE buckets SET sequence = sequence + 1 WHERE name = ?", bucketName); err != nil {
+		return 0, err
+	}
+
+	var sequence uint64
+	err := tx.tx.QueryRow("SELECT sequence FROM buckets WHERE name = ?", bucketName).Scan(&sequence)
+
+	return sequence, err
+}
+
+func (tx *DbTransaction) setSequence(bucketName string, sequence uint64) error {
+	if tx.readOnly {
+		return errReadOnlyTransaction
+	}
+
+	_, err := tx.tx.Exec("INSERT INTO buckets (name, sequence) VALUES (?, ?) ON CONFLICT (name) DO UPDATE SET sequence = excluded.sequence", bucketName, int64(sequence))
+	return err
+}
@@ -0,0 +1,160 @@
+package sqlite
+
+import (
+	"errors"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	dserrors "github.com/portainer/portainer/api/dataservices/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testBucketName = "test-bucket"
+const testId = 1234
+
+type testStruct struct {
+	Key   string
+	Value string
+}
+
+func newTestConnection(t *testing.T, encryptionKey []byte) *DbConnection {
+	conn := &DbConnection{
+		Path:          t.TempDir(),
+		EncryptionKey: encryptionKey,
+	}
+
+	_, err := conn.NeedsEncryptionMigration()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = conn.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	err = conn.SetServiceName(testBucketName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return conn
+}
+
+func TestTxs(t *testing.T) {
+	is := assert.New(t)
+
+	for name, key := range map[string][]byte{"unencrypted": nil, "encrypted": []byte("apassphrasewhichneedstobe32bytes")} {
+		t.Run(name, func(t *testing.T) {
+			conn := newTestConnection(t, key)
+
+			// Error propagation
+			err := conn.UpdateTx(func(tx portainer.Transaction) error {
+				if err := tx.CreateObjectWithId(testBucketName, testId, testStruct{Key: "rolled", Value: "back"}); err != nil {
+					return err
+				}
+
+				return errors.New("this is an error")
+			})
+			is.Error(err)
+
+			obj := testStruct{}
+			err = conn.GetObject(testBucketName, conn.ConvertToKey(testId), &obj)
+			is.ErrorIs(err, dserrors.ErrObjectNotFound, "the failed transaction should be rolled back")
+
+			// Create, update and get an object
+			err = conn.CreateObjectWithId(testBucketName, testId, testStruct{Key: "key", Value: "value"})
+			is.NoError(err)
+
+			err = conn.UpdateObject(testBucketName, conn.ConvertToKey(testId), &testStruct{Key: "updated-key", Value: "updated-value"})
+			is.NoError(err)
+
+			err = conn.GetObject(testBucketName, conn.ConvertToKey(testId), &obj)
+			is.NoError(err)
+			is.Equal(testStruct{Key: "updated-key", Value: "updated-value"}, obj)
+
+			// Writes are rejected inside read-only transactions
+			err = conn.ViewTx(func(tx portainer.Transaction) error {
+				return tx.DeleteObject(testBucketName, conn.ConvertToKey(testId))
+			})
+			is.Error(err)
+
+			// Delete an object
+			err = conn.DeleteObject(testBucketName, conn.ConvertToKey(testId))
+			is.NoError(err)
+
+			err = conn.GetObject(testBucketName, conn.ConvertToKey(testId), &obj)
+			is.ErrorIs(err, dserrors.ErrObjectNotFound)
+		})
+	}
+}
+
+func TestSequences(t *testing.T) {
+	is := assert.New(t)
+	conn := newTestConnection(t, nil)
+
+	is.Equal(1, conn.GetNextIdentifier(testBucketName))
+
+	err := conn.CreateObject(testBucketName, func(id uint64) (int, interface{}) {
+		return int(id), testStruct{Key: "created", Value: "value"}
+	})
+	is.NoError(err)
+
+	obj := testStruct{}
+	err = conn.GetObject(testBucketName, conn.ConvertToKey(2), &obj)
+	is.NoError(err)
+	is.Equal("created", obj.Key)
+
+	metadata, err := conn.BackupMetadata()
+	is.NoError(err)
+	is.Equal(2, metadata[testBucketName])
+
+	err = conn.RestoreMetadata(map[string]interface{}{testBucketName: float64(10)})
+	is.NoError(err)
+	is.Equal(11, conn.GetNextIdentifier(testBucketName))
+}
+
+func TestGetAll(t *testing.T) {
+	is := assert.New(t)
+	conn := newTestConnection(t, nil)
+
+	// Inserted out of order, GetAll must return the objects sorted by key like BoltDB
+	for _, id := range []int{3, 1, 300, 2} {
+		err := conn.CreateObjectWithId(testBucketName, id, testStruct{Key: "id", Value: string(rune('a' + id%26))})
+		is.NoError(err)
+	}
+
+	for _, key := range []string{"prefix-b", "other", "prefix-a"} {
+		err := conn.CreateObjectWithStringId("strings", []byte(key), testStruct{Key: key})
+		is.NoError(err)
+	}
+
+	var values []string
+	err := conn.GetAll(testBucketName, &testStruct{}, func(o interface{}) (interface{}, error) {
+		values = append(values, o.(*testStruct).Value)
+		return &testStruct{}, nil
+	})
+	is.NoError(err)
+	is.Equal([]string{"b", "c", "d", "o"}, values)
+
+	var keys []string
+	err = conn.GetAllWithKeyPrefix("strings", []byte("prefix-"), &testStruct{}, func(o interface{}) (interface{}, error) {
+		keys = append(keys, o.(*testStruct).Key)
+		return &testStruct{}, nil
+	})
+	is.NoError(err)
+	is.Equal([]string{"prefix-a", "prefix-b"}, keys)
+
+	// Delete the objects with an odd identifier
+	err = conn.DeleteAllObjects(testBucketName, &testStruct{}, func(o interface{}) (int, bool) {
+		id := int(o.(*testStruct).Value[0] - 'a')
+		return id, id%2 == 1
+	})
+	is.NoError(err)
+
+	count, err := conn.CountObjects(testBucketName)
+	is.NoError(err)
+	is.Equal(2, count)
+}
@@ -32,6 +32,28 @@ func NewService(connection portainer.Connection) (*Service, error) {
 	}, nil
 }
 
+// GetAPIKeys returns the API keys of all the users.
+func (service *Service) GetAPIKeys() ([]portainer.APIKey, error) {
+	var result = make([]portainer.APIKey, 0)
+
+	err := service.connection.GetAll(
+		BucketName,
+		&portainer.APIKey{},
+		func(obj interface{}) (interface{}, error) {
+			record, ok := obj.(*portainer.APIKey)
+			if !ok {
+				log.Debug().Str("obj", fmt.Sprintf("%#v", obj)).Msg("failed to convert to APIKey object")
+				return nil, fmt.Errorf("Failed to convert to APIKey object: %s", obj)
+			}
+
+			result = append(result, *record)
+
+			return &portainer.APIKey{}, nil
+		})
+
+	return result, err
+}
+
 // GetAPIKeysByUserID returns a slice containing all the APIKeys a user has access to.
 func (service *Service) GetAPIKeysByUserID(userID portainer.UserID) ([]portainer.APIKey, error) {
 	var result = make([]portainer.APIKey, 0)
@@ -0,0 +1,90 @@
+package auditlog
+
+import (
+	"fmt"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "audit_logs"
+)
+
+// Service represents a service for managing audit log data.
+type Service struct {
+	connection portainer.Connection
+}
+
+func (service *Service) BucketName() string {
+	return BucketName
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection portainer.Connection) (*Service, error) {
+	err := connection.SetServiceName(BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		connection: connection,
+	}, nil
+}
+
+// AuditLogs returns an array containing all the audit logs.
+func (service *Service) AuditLogs() ([]portainer.AuditLog, error) {
+	var logs = make([]portainer.AuditLog, 0)
+
+	err := service.connection.GetAll(
+		BucketName,
+		&portainer.AuditLog{},
+		func(obj interface{}) (interface{}, error) {
+			auditLog, ok := obj.(*portainer.AuditLog)
+			if !ok {
+				log.Debug().Str("obj", fmt.Sprintf("%#v", obj)).Msg("failed to convert to AuditLog object")
+
+				return nil, fmt.Errorf("Failed to convert to AuditLog object: %s", obj)
+			}
+
+			logs = append(logs, *auditLog)
+
+			return &portainer.AuditLog{}, nil
+		})
+
+	return logs, err
+}
+
+// Create assigns an ID to a new audit log and saves it.
+func (service *Service) Create(auditLog *portainer.AuditLog) error {
+	return service.connection.CreateObject(
+		BucketName,
+		func(id uint64) (int, interface{}) {
+			auditLog.ID = portainer.AuditLogID(id)
+			return int(auditLog.ID), auditLog
+		},
+	)
+}
+
+// DeleteAuditLogsBefore deletes all the audit logs recorded before the specified timestamp.
+func (service *Service) DeleteAuditLogsBefore(timestamp int64) error {
+	return service.connection.DeleteAllObjects(
+		BucketName,
+		&portainer.AuditLog{},
+		func(obj interface{}) (id int, ok bool) {
+			auditLog, ok := obj.(*portainer.AuditLog)
+			if !ok {
+				log.Debug().Str("obj", fmt.Sprintf("%#v", obj)).Msg("failed to convert to AuditLog object")
+
+				return -1, false
+			}
+
+			if auditLog.Timestamp < timestamp {
+				return int(auditLog.ID), true
+			}
+
+			return -1, false
+		})
+}
@@ -16,6 +16,8 @@ const BucketName = "edge_stack"
 type Service struct {
 	connection          portainer.Connection
 	idxVersion          map[portainer.EdgeStackID]int
+	idxRollout          map[portainer.EdgeStackID]*portainer.EdgeStackRollout
+	idxRolledBack       map[portainer.EdgeStackID]map[portainer.EndpointID]int
 	mu                  sync.RWMutex
 	cacheInvalidationFn func(portainer.EdgeStackID)
 }
@@ -34,6 +36,8 @@ func NewService(connection portainer.Connection, cacheInvalidationFn func(portai
 	s := &Service{
 		connection:          connection,
 		idxVersion:          make(map[portainer.EdgeStackID]int),
+		idxRollout:          make(map[portainer.EdgeStackID]*portainer.EdgeStackRollout),
+		idxRolledBack:       make(map[portainer.EdgeStackID]map[portainer.EndpointID]int),
 		cacheInvalidationFn: cacheInvalidationFn,
 	}
 
@@ -46,8 +50,8 @@ func NewService(connection portainer.Connection, cacheInvalidationFn func(portai
 		return nil, err
 	}
 
-	for _, e := range es {
-		s.idxVersion[e.ID] = e.Version
+	for i := range es {
+		s.index(es[i].ID, &es[i])
 	}
 
 	return s, nil
@@ -104,6 +108,57 @@ func (service *Service) EdgeStackVersion(ID portainer.EdgeStackID) (int, bool) {
 	return v, ok
 }
 
+// EdgeStackRollout returns the rollout of the given edge stack ID directly from an in-memory index.
+// The returned rollout must not be modified.
+func (service *Service) EdgeStackRollout(ID portainer.EdgeStackID) (*portainer.EdgeStackRollout, bool) {
+	service.mu.RLock()
+	rollout, ok := service.idxRollout[ID]
+	service.mu.RUnlock()
+
+	return rollout, ok
+}
+
+// EdgeStackRolledBackVersion returns the version deployed to an environment rolled back individually
+// directly from an in-memory index
+func (service *Service) EdgeStackRolledBackVersion(ID portainer.EdgeStackID, endpointID portainer.EndpointID) (int, bool) {
+	service.mu.RLock()
+	v, ok := service.idxRolledBack[ID][endpointID]
+	service.mu.RUnlock()
+
+	return v, ok
+}
+
+// index updates the in-memory indexes of an edge stack, the caller must hold the lock
+func (service *Service) index(ID portainer.EdgeStackID, edgeStack *portainer.EdgeStack) {
+	service.idxVersion[ID] = edgeStack.Version
+
+	if len(edgeStack.RolledBackEndpoints) == 0 {
+		delete(service.idxRolledBack, ID)
+	} else {
+		rolledBack := make(map[portainer.EndpointID]int, len(edgeStack.RolledBackEndpoints))
+		for endpointID, version := range edgeStack.RolledBackEndpoints {
+			rolledBack[endpointID] = version
+		}
+
+		service.idxRolledBack[ID] = rolledBack
+	}
+
+	if edgeStack.Rollout == nil {
+		delete(service.idxRollout, ID)
+		return
+	}
+
+	rollout := *edgeStack.Rollout
+	service.idxRollout[ID] = &rollout
+}
+
+// unindex removes an edge stack from the in-memory indexes, the caller must hold the lock
+func (service *Service) unindex(ID portainer.EdgeStackID) {
+	delete(service.idxVersion, ID)
+	delete(service.idxRollout, ID)
+	delete(service.idxRolledBack, ID)
+}
+
 // CreateEdgeStack saves an Edge stack object to db.
 func (service *Service) Create(id portainer.EdgeStackID, edgeStack *portainer.EdgeStack) error {
 	edgeStack.ID = id
@@ -118,7 +173,7 @@ func (service *Service) Create(id portainer.EdgeStackID, edgeStack *portainer.Ed
 	}
 
 	service.mu.Lock()
-	service.idxVersion[id] = edgeStack.Version
+	service.index(id, edgeStack)
 	service.cacheInvalidationFn(id)
 	service.mu.Unlock()
 
@@ -137,7 +192,7 @@ func (service *Service) UpdateEdgeStack(ID portainer.EdgeStackID, edgeStack *por
 		return err
 	}
 
-	service.idxVersion[ID] = edgeStack.Version
+	service.index(ID, edgeStack)
 	service.cacheInvalidationFn(ID)
 
 	return nil
@@ -154,7 +209,7 @@ func (service *Service) UpdateEdgeStackFunc(ID portainer.EdgeStackID, updateFunc
 	return service.connection.UpdateObjectFunc(BucketName, id, edgeStack, func() {
 		updateFunc(edgeStack)
 
-		service.idxVersion[ID] = edgeStack.Version
+		service.index(ID, edgeStack)
 		service.cacheInvalidationFn(ID)
 	})
 }
@@ -176,7 +231,7 @@ func (service *Service) DeleteEdgeStack(ID portainer.EdgeStackID) error {
 		return err
 	}
 
-	delete(service.idxVersion, ID)
+	service.unindex(ID)
 
 	service.cacheInvalidationFn(ID)
 
@@ -61,6 +61,17 @@ func (service ServiceTx) EdgeStackVersion(ID portainer.EdgeStackID) (int, bool)
 	return v, ok
 }
 
+// EdgeStackRollout returns the rollout of the given edge stack ID directly from an in-memory index
+func (service ServiceTx) EdgeStackRollout(ID portainer.EdgeStackID) (*portainer.EdgeStackRollout, bool) {
+	return service.service.EdgeStackRollout(ID)
+}
+
+// EdgeStackRolledBackVersion returns the version deployed to an environment rolled back individually
+// directly from an in-memory index
+func (service ServiceTx) EdgeStackRolledBackVersion(ID portainer.EdgeStackID, endpointID portainer.EndpointID) (int, bool) {
+	return service.service.EdgeStackRolledBackVersion(ID, endpointID)
+}
+
 // CreateEdgeStack saves an Edge stack object to db.
 func (service ServiceTx) Create(id portainer.EdgeStackID, edgeStack *portainer.EdgeStack) error {
 	edgeStack.ID = id
@@ -75,7 +86,7 @@ func (service ServiceTx) Create(id portainer.EdgeStackID, edgeStack *portainer.E
 	}
 
 	service.service.mu.Lock()
-	service.service.idxVersion[id] = edgeStack.Version
+	service.service.index(id, edgeStack)
 	service.service.cacheInvalidationFn(id)
 	service.service.mu.Unlock()
 
@@ -94,7 +105,7 @@ func (service ServiceTx) UpdateEdgeStack(ID portainer.EdgeStackID, edgeStack *po
 		return err
 	}
 
-	service.service.idxVersion[ID] = edgeStack.Version
+	service.service.index(ID, edgeStack)
 	service.service.cacheInvalidationFn(ID)
 
 	return nil
@@ -124,7 +135,7 @@ func (service ServiceTx) DeleteEdgeStack(ID portainer.EdgeStackID) error {
 		return err
 	}
 
-	delete(service.service.idxVersion, ID)
+	service.service.unindex(ID)
 
 	service.service.cacheInvalidationFn(ID)
 
@@ -0,0 +1,81 @@
+package imagescan
+
+import (
+	"fmt"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "image_scans"
+)
+
+// Service represents a service for managing image vulnerability scan data.
+// The scans are stored per image identifier.
+type Service struct {
+	connection portainer.Connection
+}
+
+func (service *Service) BucketName() string {
+	return BucketName
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection portainer.Connection) (*Service, error) {
+	err := connection.SetServiceName(BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		connection: connection,
+	}, nil
+}
+
+// ImageScan returns the last scan of an image.
+func (service *Service) ImageScan(imageID string) (*portainer.ImageScan, error) {
+	var scan portainer.ImageScan
+
+	err := service.connection.GetObject(BucketName, []byte(imageID), &scan)
+	if err != nil {
+		return nil, err
+	}
+
+	return &scan, nil
+}
+
+// ImageScans returns the last scan of every scanned image.
+func (service *Service) ImageScans() ([]portainer.ImageScan, error) {
+	var scans = make([]portainer.ImageScan, 0)
+
+	err := service.connection.GetAll(
+		BucketName,
+		&portainer.ImageScan{},
+		func(obj interface{}) (interface{}, error) {
+			scan, ok := obj.(*portainer.ImageScan)
+			if !ok {
+				log.Debug().Str("obj", fmt.Sprintf("%#v", obj)).Msg("failed to convert to ImageScan object")
+
+				return nil, fmt.Errorf("Failed to convert to ImageScan object: %s", obj)
+			}
+
+			scans = append(scans, *scan)
+
+			return &portainer.ImageScan{}, nil
+		})
+
+	return scans, err
+}
+
+// UpdateImageScan saves the scan of an image, replacing the previous one.
+func (service *Service) UpdateImageScan(scan *portainer.ImageScan) error {
+	return service.connection.UpdateObject(BucketName, []byte(scan.ImageID), scan)
+}
+
+// DeleteImageScan deletes the scan of an image.
+func (service *Service) DeleteImageScan(imageID string) error {
+	return service.connection.DeleteObject(BucketName, []byte(imageID))
+}
@@ -15,6 +15,7 @@ import (
 type (
 	DataStoreTx interface {
 		IsErrObjectNotFound(err error) bool
+		AuditLog() AuditLogService
 		CustomTemplate() CustomTemplateService
 		EdgeGroup() EdgeGroupService
 		EdgeJob() EdgeJobService
@@ -24,14 +25,18 @@ type (
 		EndpointRelation() EndpointRelationService
 		FDOProfile() FDOProfileService
 		HelmUserRepository() HelmUserRepositoryService
+		ImageScan() ImageScanService
+		NotificationChannel() NotificationChannelService
 		Registry() RegistryService
 		ResourceControl() ResourceControlService
 		Role() RoleService
 		APIKeyRepository() APIKeyRepository
+		Session() SessionService
 		Settings() SettingsService
 		Snapshot() SnapshotService
 		SSLSettings() SSLSettingsService
 		Stack() StackService
+		StackVersion() StackVersionService
 		Tag() TagService
 		TeamMembership() TeamMembershipService
 		Team() TeamService
@@ -53,6 +58,7 @@ type (
 		CheckCurrentEdition() error
 		BackupTo(w io.Writer) error
 		Export(filename string) (err error)
+		Connection() portainer.Connection
 
 		DataStoreTx
 	}
@@ -96,6 +102,8 @@ type (
 		EdgeStacks() ([]portainer.EdgeStack, error)
 		EdgeStack(ID portainer.EdgeStackID) (*portainer.EdgeStack, error)
 		EdgeStackVersion(ID portainer.EdgeStackID) (int, bool)
+		EdgeStackRollout(ID portainer.EdgeStackID) (*portainer.EdgeStackRollout, bool)
+		EdgeStackRolledBackVersion(ID portainer.EdgeStackID, endpointID portainer.EndpointID) (int, bool)
 		Create(id portainer.EdgeStackID, edgeStack *portainer.EdgeStack) error
 		UpdateEdgeStack(ID portainer.EdgeStackID, edgeStack *portainer.EdgeStack) error
 		UpdateEdgeStackFunc(ID portainer.EdgeStackID, updateFunc func(edgeStack *portainer.EdgeStack)) error
@@ -164,8 +172,29 @@ type (
 		GenerateToken(data *portainer.TokenData) (string, error)
 		GenerateTokenForOAuth(data *portainer.TokenData, expiryTime *time.Time) (string, error)
 		GenerateTokenForKubeconfig(data *portainer.TokenData) (string, error)
+		GenerateSessionToken(data *portainer.TokenData, ipAddress, userAgent string) (string, error)
+		IsSessionActive(session *portainer.Session, now time.Time) bool
 		ParseAndVerifyToken(token string) (*portainer.TokenData, error)
 		SetUserSessionDuration(userSessionDuration time.Duration)
+		SetSessionIdleTimeout(sessionIdleTimeout time.Duration)
+	}
+
+	// AuditLogService represents a service for managing audit log data
+	AuditLogService interface {
+		AuditLogs() ([]portainer.AuditLog, error)
+		Create(auditLog *portainer.AuditLog) error
+		DeleteAuditLogsBefore(timestamp int64) error
+		BucketName() string
+	}
+
+	// NotificationChannelService represents a service for managing notification channel data
+	NotificationChannelService interface {
+		NotificationChannels() ([]portainer.NotificationChannel, error)
+		NotificationChannel(ID portainer.NotificationChannelID) (*portainer.NotificationChannel, error)
+		Create(channel *portainer.NotificationChannel) error
+		UpdateNotificationChannel(ID portainer.NotificationChannelID, channel *portainer.NotificationChannel) error
+		DeleteNotificationChannel(ID portainer.NotificationChannelID) error
+		BucketName() string
 	}
 
 	// RegistryService represents a service for managing registry data
@@ -204,6 +233,7 @@ type (
 		GetAPIKey(keyID portainer.APIKeyID) (*portainer.APIKey, error)
 		UpdateAPIKey(key *portainer.APIKey) error
 		DeleteAPIKey(ID portainer.APIKeyID) error
+		GetAPIKeys() ([]portainer.APIKey, error)
 		GetAPIKeysByUserID(userID portainer.UserID) ([]portainer.APIKey, error)
 		GetAPIKeyByDigest(digest []byte) (*portainer.APIKey, error)
 	}
@@ -239,6 +269,7 @@ type (
 		Stacks() ([]portainer.Stack, error)
 		Create(stack *portainer.Stack) error
 		UpdateStack(ID portainer.StackID, stack *portainer.Stack) error
+		UpdateStackFunc(ID portainer.StackID, updateFunc func(stack *portainer.Stack)) error
 		DeleteStack(ID portainer.StackID) error
 		GetNextIdentifier() int
 		StackByWebhookID(ID string) (*portainer.Stack, error)
@@ -246,6 +277,35 @@ type (
 		BucketName() string
 	}
 
+	// ImageScanService represents a service for managing image vulnerability scan data
+	ImageScanService interface {
+		ImageScan(imageID string) (*portainer.ImageScan, error)
+		ImageScans() ([]portainer.ImageScan, error)
+		UpdateImageScan(scan *portainer.ImageScan) error
+		DeleteImageScan(imageID string) error
+	}
+
+	// SessionService represents a service for managing session data
+	SessionService interface {
+		Session(ID portainer.SessionID) (*portainer.Session, error)
+		Sessions() ([]portainer.Session, error)
+		SessionsByUserID(userID portainer.UserID) ([]portainer.Session, error)
+		Create(session *portainer.Session) error
+		UpdateSession(ID portainer.SessionID, session *portainer.Session) error
+		DeleteSession(ID portainer.SessionID) error
+		DeleteSessionsByUserID(userID portainer.UserID) error
+		BucketName() string
+	}
+
+	// StackVersionService represents a service for managing stack version data
+	StackVersionService interface {
+		StackVersions(stackID portainer.StackID) ([]portainer.StackVersion, error)
+		Create(version *portainer.StackVersion) error
+		DeleteStackVersion(ID portainer.StackVersionID) error
+		DeleteStackVersions(stackID portainer.StackID) error
+		BucketName() string
+	}
+
 	// TagService represents a service for managing tag data
 	TagService interface {
 		Tags() ([]portainer.Tag, error)
@@ -320,6 +380,7 @@ type (
 		WebhookByResourceID(resourceID string) (*portainer.Webhook, error)
 		WebhookByToken(token string) (*portainer.Webhook, error)
 		DeleteWebhook(ID portainer.WebhookID) error
+		DeleteResourceWebhooks(endpointID portainer.EndpointID, resourceID string, webhookType portainer.WebhookType) error
 		BucketName() string
 	}
 )
@@ -0,0 +1,94 @@
+package notificationchannel
+
+import (
+	"fmt"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "notification_channels"
+)
+
+// Service represents a service for managing notification channel data.
+type Service struct {
+	connection portainer.Connection
+}
+
+func (service *Service) BucketName() string {
+	return BucketName
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection portainer.Connection) (*Service, error) {
+	err := connection.SetServiceName(BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		connection: connection,
+	}, nil
+}
+
+// NotificationChannels returns an array containing all the notification channels.
+func (service *Service) NotificationChannels() ([]portainer.NotificationChannel, error) {
+	var channels = make([]portainer.NotificationChannel, 0)
+
+	err := service.connection.GetAll(
+		BucketName,
+		&portainer.NotificationChannel{},
+		func(obj interface{}) (interface{}, error) {
+			channel, ok := obj.(*portainer.NotificationChannel)
+			if !ok {
+				log.Debug().Str("obj", fmt.Sprintf("%#v", obj)).Msg("failed to convert to NotificationChannel object")
+
+				return nil, fmt.Errorf("Failed to convert to NotificationChannel object: %s", obj)
+			}
+
+			channels = append(channels, *channel)
+
+			return &portainer.NotificationChannel{}, nil
+		})
+
+	return channels, err
+}
+
+// NotificationChannel returns a notification channel by ID.
+func (service *Service) NotificationChannel(ID portainer.NotificationChannelID) (*portainer.NotificationChannel, error) {
+	var channel portainer.NotificationChannel
+	identifier := service.connection.ConvertToKey(int(ID))
+
+	err := service.connection.GetObject(BucketName, identifier, &channel)
+	if err != nil {
+		return nil, err
+	}
+
+	return &channel, nil
+}
+
+// Create assigns an ID to a new notification channel and saves it.
+func (service *Service) Create(channel *portainer.NotificationChannel) error {
+	return service.connection.CreateObject(
+		BucketName,
+		func(id uint64) (int, interface{}) {
+			channel.ID = portainer.NotificationChannelID(id)
+			return int(channel.ID), channel
+		},
+	)
+}
+
+// UpdateNotificationChannel updates a notification channel.
+func (service *Service) UpdateNotificationChannel(ID portainer.NotificationChannelID, channel *portainer.NotificationChannel) error {
+	identifier := service.connection.ConvertToKey(int(ID))
+	return service.connection.UpdateObject(BucketName, identifier, channel)
+}
+
+// DeleteNotificationChannel deletes a notification channel.
+func (service *Service) DeleteNotificationChannel(ID portainer.NotificationChannelID) error {
+	identifier := service.connection.ConvertToKey(int(ID))
+	return service.connection.DeleteObject(BucketName, identifier)
+}
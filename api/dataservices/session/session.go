@@ -0,0 +1,140 @@
+package session
+
+import (
+	"fmt"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "sessions"
+)
+
+// Service represents a service for managing session data.
+type Service struct {
+	connection portainer.Connection
+}
+
+func (service *Service) BucketName() string {
+	return BucketName
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection portainer.Connection) (*Service, error) {
+	err := connection.SetServiceName(BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		connection: connection,
+	}, nil
+}
+
+// Session returns a session by ID.
+func (service *Service) Session(ID portainer.SessionID) (*portainer.Session, error) {
+	var session portainer.Session
+	identifier := service.connection.ConvertToKey(int(ID))
+
+	err := service.connection.GetObject(BucketName, identifier, &session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// Sessions returns an array containing all the sessions.
+func (service *Service) Sessions() ([]portainer.Session, error) {
+	var sessions = make([]portainer.Session, 0)
+
+	err := service.connection.GetAll(
+		BucketName,
+		&portainer.Session{},
+		func(obj interface{}) (interface{}, error) {
+			session, ok := obj.(*portainer.Session)
+			if !ok {
+				log.Debug().Str("obj", fmt.Sprintf("%#v", obj)).Msg("failed to convert to Session object")
+
+				return nil, fmt.Errorf("Failed to convert to Session object: %s", obj)
+			}
+
+			sessions = append(sessions, *session)
+
+			return &portainer.Session{}, nil
+		})
+
+	return sessions, err
+}
+
+// SessionsByUserID returns an array containing all the sessions of a user.
+func (service *Service) SessionsByUserID(userID portainer.UserID) ([]portainer.Session, error) {
+	var sessions = make([]portainer.Session, 0)
+
+	err := service.connection.GetAll(
+		BucketName,
+		&portainer.Session{},
+		func(obj interface{}) (interface{}, error) {
+			session, ok := obj.(*portainer.Session)
+			if !ok {
+				log.Debug().Str("obj", fmt.Sprintf("%#v", obj)).Msg("failed to convert to Session object")
+
+				return nil, fmt.Errorf("Failed to convert to Session object: %s", obj)
+			}
+
+			if session.UserID == userID {
+				sessions = append(sessions, *session)
+			}
+
+			return &portainer.Session{}, nil
+		})
+
+	return sessions, err
+}
+
+// Create assigns an ID to a new session and saves it.
+func (service *Service) Create(session *portainer.Session) error {
+	return service.connection.CreateObject(
+		BucketName,
+		func(id uint64) (int, interface{}) {
+			session.ID = portainer.SessionID(id)
+			return int(session.ID), session
+		},
+	)
+}
+
+// UpdateSession saves a session.
+func (service *Service) UpdateSession(ID portainer.SessionID, session *portainer.Session) error {
+	identifier := service.connection.ConvertToKey(int(ID))
+	return service.connection.UpdateObject(BucketName, identifier, session)
+}
+
+// DeleteSession deletes a session.
+func (service *Service) DeleteSession(ID portainer.SessionID) error {
+	identifier := service.connection.ConvertToKey(int(ID))
+	return service.connection.DeleteObject(BucketName, identifier)
+}
+
+// DeleteSessionsByUserID deletes all the sessions of a user.
+func (service *Service) DeleteSessionsByUserID(userID portainer.UserID) error {
+	return service.connection.DeleteAllObjects(
+		BucketName,
+		&portainer.Session{},
+		func(obj interface{}) (id int, ok bool) {
+			session, ok := obj.(*portainer.Session)
+			if !ok {
+				log.Debug().Str("obj", fmt.Sprintf("%#v", obj)).Msg("failed to convert to Session object")
+
+				return -1, false
+			}
+
+			if session.UserID == userID {
+				return int(session.ID), true
+			}
+
+			return -1, false
+		})
+}
@@ -143,6 +143,16 @@ func (service *Service) UpdateStack(ID portainer.StackID, stack *portainer.Stack
 	return service.connection.UpdateObject(BucketName, identifier, stack)
 }
 
+// UpdateStackFunc updates a stack inside a transaction avoiding data races.
+func (service *Service) UpdateStackFunc(ID portainer.StackID, updateFunc func(stack *portainer.Stack)) error {
+	id := service.connection.ConvertToKey(int(ID))
+	stack := &portainer.Stack{}
+
+	return service.connection.UpdateObjectFunc(BucketName, id, stack, func() {
+		updateFunc(stack)
+	})
+}
+
 // DeleteStack deletes a stack.
 func (service *Service) DeleteStack(ID portainer.StackID) error {
 	identifier := service.connection.ConvertToKey(int(ID))
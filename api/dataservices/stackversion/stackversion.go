@@ -0,0 +1,98 @@
+package stackversion
+
+import (
+	"fmt"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "stack_versions"
+)
+
+// Service represents a service for managing stack version data.
+type Service struct {
+	connection portainer.Connection
+}
+
+func (service *Service) BucketName() string {
+	return BucketName
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection portainer.Connection) (*Service, error) {
+	err := connection.SetServiceName(BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		connection: connection,
+	}, nil
+}
+
+// StackVersions returns an array containing all the versions of a stack.
+func (service *Service) StackVersions(stackID portainer.StackID) ([]portainer.StackVersion, error) {
+	var versions = make([]portainer.StackVersion, 0)
+
+	err := service.connection.GetAll(
+		BucketName,
+		&portainer.StackVersion{},
+		func(obj interface{}) (interface{}, error) {
+			version, ok := obj.(*portainer.StackVersion)
+			if !ok {
+				log.Debug().Str("obj", fmt.Sprintf("%#v", obj)).Msg("failed to convert to StackVersion object")
+
+				return nil, fmt.Errorf("Failed to convert to StackVersion object: %s", obj)
+			}
+
+			if version.StackID == stackID {
+				versions = append(versions, *version)
+			}
+
+			return &portainer.StackVersion{}, nil
+		})
+
+	return versions, err
+}
+
+// Create assigns an ID to a new stack version and saves it.
+func (service *Service) Create(version *portainer.StackVersion) error {
+	return service.connection.CreateObject(
+		BucketName,
+		func(id uint64) (int, interface{}) {
+			version.ID = portainer.StackVersionID(id)
+			return int(version.ID), version
+		},
+	)
+}
+
+// DeleteStackVersion deletes a stack version.
+func (service *Service) DeleteStackVersion(ID portainer.StackVersionID) error {
+	identifier := service.connection.ConvertToKey(int(ID))
+	return service.connection.DeleteObject(BucketName, identifier)
+}
+
+// DeleteStackVersions deletes all the versions of a stack.
+func (service *Service) DeleteStackVersions(stackID portainer.StackID) error {
+	return service.connection.DeleteAllObjects(
+		BucketName,
+		&portainer.StackVersion{},
+		func(obj interface{}) (id int, ok bool) {
+			version, ok := obj.(*portainer.StackVersion)
+			if !ok {
+				log.Debug().Str("obj", fmt.Sprintf("%#v", obj)).Msg("failed to convert to StackVersion object")
+
+				return -1, false
+			}
+
+			if version.StackID == stackID {
+				return int(version.ID), true
+			}
+
+			return -1, false
+		})
+}
@@ -144,6 +144,27 @@ func (service *Service) DeleteWebhook(ID portainer.WebhookID) error {
 	return service.connection.DeleteObject(BucketName, identifier)
 }
 
+// DeleteResourceWebhooks deletes the webhooks of the given type associated with a resource of an environment.
+func (service *Service) DeleteResourceWebhooks(endpointID portainer.EndpointID, resourceID string, webhookType portainer.WebhookType) error {
+	webhooks, err := service.Webhooks()
+	if err != nil {
+		return err
+	}
+
+	for _, webhook := range webhooks {
+		if webhook.EndpointID != endpointID || webhook.ResourceID != resourceID || webhook.WebhookType != webhookType {
+			continue
+		}
+
+		err = service.DeleteWebhook(webhook.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // CreateWebhook assign an ID to a new webhook and saves it.
 func (service *Service) Create(webhook *portainer.Webhook) error {
 	return service.connection.CreateObject(
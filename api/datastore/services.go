@@ -9,6 +9,7 @@ import (
 	"github.com/portainer/portainer/api/database/models"
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/dataservices/apikeyrepository"
+	"github.com/portainer/portainer/api/dataservices/auditlog"
 	"github.com/portainer/portainer/api/dataservices/customtemplate"
 	"github.com/portainer/portainer/api/dataservices/dockerhub"
 	"github.com/portainer/portainer/api/dataservices/edgegroup"
@@ -20,14 +21,18 @@ import (
 	"github.com/portainer/portainer/api/dataservices/extension"
 	"github.com/portainer/portainer/api/dataservices/fdoprofile"
 	"github.com/portainer/portainer/api/dataservices/helmuserrepository"
+	"github.com/portainer/portainer/api/dataservices/imagescan"
+	"github.com/portainer/portainer/api/dataservices/notificationchannel"
 	"github.com/portainer/portainer/api/dataservices/registry"
 	"github.com/portainer/portainer/api/dataservices/resourcecontrol"
 	"github.com/portainer/portainer/api/dataservices/role"
 	"github.com/portainer/portainer/api/dataservices/schedule"
+	"github.com/portainer/portainer/api/dataservices/session"
 	"github.com/portainer/portainer/api/dataservices/settings"
 	"github.com/portainer/portainer/api/dataservices/snapshot"
 	"github.com/portainer/portainer/api/dataservices/ssl"
 	"github.com/portainer/portainer/api/dataservices/stack"
+	"github.com/portainer/portainer/api/dataservices/stackversion"
 	"github.com/portainer/portainer/api/dataservices/tag"
 	"github.com/portainer/portainer/api/dataservices/team"
 	"github.com/portainer/portainer/api/dataservices/teammembership"
@@ -44,34 +49,39 @@ import (
 type Store struct {
 	connection portainer.Connection
 
-	fileService               portainer.FileService
-	CustomTemplateService     *customtemplate.Service
-	DockerHubService          *dockerhub.Service
-	EdgeGroupService          *edgegroup.Service
-	EdgeJobService            *edgejob.Service
-	EdgeStackService          *edgestack.Service
-	EndpointGroupService      *endpointgroup.Service
-	EndpointService           *endpoint.Service
-	EndpointRelationService   *endpointrelation.Service
-	ExtensionService          *extension.Service
-	FDOProfilesService        *fdoprofile.Service
-	HelmUserRepositoryService *helmuserrepository.Service
-	RegistryService           *registry.Service
-	ResourceControlService    *resourcecontrol.Service
-	RoleService               *role.Service
-	APIKeyRepositoryService   *apikeyrepository.Service
-	ScheduleService           *schedule.Service
-	SettingsService           *settings.Service
-	SnapshotService           *snapshot.Service
-	SSLSettingsService        *ssl.Service
-	StackService              *stack.Service
-	TagService                *tag.Service
-	TeamMembershipService     *teammembership.Service
-	TeamService               *team.Service
-	TunnelServerService       *tunnelserver.Service
-	UserService               *user.Service
-	VersionService            *version.Service
-	WebhookService            *webhook.Service
+	fileService                portainer.FileService
+	CustomTemplateService      *customtemplate.Service
+	DockerHubService           *dockerhub.Service
+	EdgeGroupService           *edgegroup.Service
+	EdgeJobService             *edgejob.Service
+	EdgeStackService           *edgestack.Service
+	EndpointGroupService       *endpointgroup.Service
+	EndpointService            *endpoint.Service
+	EndpointRelationService    *endpointrelation.Service
+	ExtensionService           *extension.Service
+	FDOProfilesService         *fdoprofile.Service
+	HelmUserRepositoryService  *helmuserrepository.Service
+	ImageScanService           *imagescan.Service
+	NotificationChannelService *notificationchannel.Service
+	RegistryService            *registry.Service
+	ResourceControlService     *resourcecontrol.Service
+	RoleService                *role.Service
+	APIKeyRepositoryService    *apikeyrepository.Service
+	AuditLogService            *auditlog.Service
+	ScheduleService            *schedule.Service
+	SessionService             *session.Service
+	SettingsService            *settings.Service
+	SnapshotService            *snapshot.Service
+	SSLSettingsService         *ssl.Service
+	StackService               *stack.Service
+	StackVersionService        *stackversion.Service
+	TagService                 *tag.Service
+	TeamMembershipService      *teammembership.Service
+	TeamService                *team.Service
+	TunnelServerService        *tunnelserver.Service
+	UserService                *user.Service
+	VersionService             *version.Service
+	WebhookService             *webhook.Service
 }
 
 func (store *Store) initServices() error {
@@ -81,6 +91,12 @@ func (store *Store) initServices() error {
 	}
 	store.RoleService = authorizationsetService
 
+	auditLogService, err := auditlog.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.AuditLogService = auditLogService
+
 	customTemplateService, err := customtemplate.NewService(store.connection)
 	if err != nil {
 		return err
@@ -148,6 +164,12 @@ func (store *Store) initServices() error {
 	}
 	store.HelmUserRepositoryService = helmUserRepositoryService
 
+	notificationChannelService, err := notificationchannel.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.NotificationChannelService = notificationChannelService
+
 	registryService, err := registry.NewService(store.connection)
 	if err != nil {
 		return err
@@ -184,6 +206,24 @@ func (store *Store) initServices() error {
 	}
 	store.StackService = stackService
 
+	imageScanService, err := imagescan.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.ImageScanService = imageScanService
+
+	sessionService, err := session.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.SessionService = sessionService
+
+	stackVersionService, err := stackversion.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.StackVersionService = stackVersionService
+
 	tagService, err := tag.NewService(store.connection)
 	if err != nil {
 		return err
@@ -286,6 +326,11 @@ func (store *Store) HelmUserRepository() dataservices.HelmUserRepositoryService
 	return store.HelmUserRepositoryService
 }
 
+// NotificationChannel gives access to the NotificationChannel data management layer
+func (store *Store) NotificationChannel() dataservices.NotificationChannelService {
+	return store.NotificationChannelService
+}
+
 // Registry gives access to the Registry data management layer
 func (store *Store) Registry() dataservices.RegistryService {
 	return store.RegistryService
@@ -301,6 +346,11 @@ func (store *Store) Role() dataservices.RoleService {
 	return store.RoleService
 }
 
+// AuditLog gives access to the AuditLog data management layer
+func (store *Store) AuditLog() dataservices.AuditLogService {
+	return store.AuditLogService
+}
+
 // APIKeyRepository gives access to the api-key data management layer
 func (store *Store) APIKeyRepository() dataservices.APIKeyRepository {
 	return store.APIKeyRepositoryService
@@ -325,6 +375,21 @@ func (store *Store) Stack() dataservices.StackService {
 	return store.StackService
 }
 
+// ImageScan gives access to the ImageScan data management layer
+func (store *Store) ImageScan() dataservices.ImageScanService {
+	return store.ImageScanService
+}
+
+// Session gives access to the Session data management layer
+func (store *Store) Session() dataservices.SessionService {
+	return store.SessionService
+}
+
+// StackVersion gives access to the StackVersion data management layer
+func (store *Store) StackVersion() dataservices.StackVersionService {
+	return store.StackVersionService
+}
+
 // Tag gives access to the Tag data management layer
 func (store *Store) Tag() dataservices.TagService {
 	return store.TagService
@@ -361,31 +426,32 @@ func (store *Store) Webhook() dataservices.WebhookService {
 }
 
 type storeExport struct {
-	CustomTemplate     []portainer.CustomTemplate     `json:"customtemplates,omitempty"`
-	EdgeGroup          []portainer.EdgeGroup          `json:"edgegroups,omitempty"`
-	EdgeJob            []portainer.EdgeJob            `json:"edgejobs,omitempty"`
-	EdgeStack          []portainer.EdgeStack          `json:"edge_stack,omitempty"`
-	Endpoint           []portainer.Endpoint           `json:"endpoints,omitempty"`
-	EndpointGroup      []portainer.EndpointGroup      `json:"endpoint_groups,omitempty"`
-	EndpointRelation   []portainer.EndpointRelation   `json:"endpoint_relations,omitempty"`
-	Extensions         []portainer.Extension          `json:"extension,omitempty"`
-	HelmUserRepository []portainer.HelmUserRepository `json:"helm_user_repository,omitempty"`
-	Registry           []portainer.Registry           `json:"registries,omitempty"`
-	ResourceControl    []portainer.ResourceControl    `json:"resource_control,omitempty"`
-	Role               []portainer.Role               `json:"roles,omitempty"`
-	Schedules          []portainer.Schedule           `json:"schedules,omitempty"`
-	Settings           portainer.Settings             `json:"settings,omitempty"`
-	Snapshot           []portainer.Snapshot           `json:"snapshots,omitempty"`
-	SSLSettings        portainer.SSLSettings          `json:"ssl,omitempty"`
-	Stack              []portainer.Stack              `json:"stacks,omitempty"`
-	Tag                []portainer.Tag                `json:"tags,omitempty"`
-	TeamMembership     []portainer.TeamMembership     `json:"team_membership,omitempty"`
-	Team               []portainer.Team               `json:"teams,omitempty"`
-	TunnelServer       portainer.TunnelServerInfo     `json:"tunnel_server,omitempty"`
-	User               []portainer.User               `json:"users,omitempty"`
-	Version            models.Version                 `json:"version,omitempty"`
-	Webhook            []portainer.Webhook            `json:"webhooks,omitempty"`
-	Metadata           map[string]interface{}         `json:"metadata,omitempty"`
+	CustomTemplate      []portainer.CustomTemplate      `json:"customtemplates,omitempty"`
+	EdgeGroup           []portainer.EdgeGroup           `json:"edgegroups,omitempty"`
+	EdgeJob             []portainer.EdgeJob             `json:"edgejobs,omitempty"`
+	EdgeStack           []portainer.EdgeStack           `json:"edge_stack,omitempty"`
+	Endpoint            []portainer.Endpoint            `json:"endpoints,omitempty"`
+	EndpointGroup       []portainer.EndpointGroup       `json:"endpoint_groups,omitempty"`
+	EndpointRelation    []portainer.EndpointRelation    `json:"endpoint_relations,omitempty"`
+	Extensions          []portainer.Extension           `json:"extension,omitempty"`
+	HelmUserRepository  []portainer.HelmUserRepository  `json:"helm_user_repository,omitempty"`
+	NotificationChannel []portainer.NotificationChannel `json:"notification_channels,omitempty"`
+	Registry            []portainer.Registry            `json:"registries,omitempty"`
+	ResourceControl     []portainer.ResourceControl     `json:"resource_control,omitempty"`
+	Role                []portainer.Role                `json:"roles,omitempty"`
+	Schedules           []portainer.Schedule            `json:"schedules,omitempty"`
+	Settings            portainer.Settings              `json:"settings,omitempty"`
+	Snapshot            []portainer.Snapshot            `json:"snapshots,omitempty"`
+	SSLSettings         portainer.SSLSettings           `json:"ssl,omitempty"`
+	Stack               []portainer.Stack               `json:"stacks,omitempty"`
+	Tag                 []portainer.Tag                 `json:"tags,omitempty"`
+	TeamMembership      []portainer.TeamMembership      `json:"team_membership,omitempty"`
+	Team                []portainer.Team                `json:"teams,omitempty"`
+	TunnelServer        portainer.TunnelServerInfo      `json:"tunnel_server,omitempty"`
+	User                []portainer.User                `json:"users,omitempty"`
+	Version             models.Version                  `json:"version,omitempty"`
+	Webhook             []portainer.Webhook             `json:"webhooks,omitempty"`
+	Metadata            map[string]interface{}          `json:"metadata,omitempty"`
 }
 
 func (store *Store) Export(filename string) (err error) {
@@ -464,6 +530,14 @@ func (store *Store) Export(filename string) (err error) {
 		backup.HelmUserRepository = r
 	}
 
+	if c, err := store.NotificationChannel().NotificationChannels(); err != nil {
+		if !store.IsErrObjectNotFound(err) {
+			log.Error().Err(err).Msg("exporting Notification Channels")
+		}
+	} else {
+		backup.NotificationChannel = c
+	}
+
 	if r, err := store.Registry().Registries(); err != nil {
 		if !store.IsErrObjectNotFound(err) {
 			log.Error().Err(err).Msg("exporting Registries")
@@ -642,6 +716,10 @@ func (store *Store) Import(filename string) (err error) {
 		store.HelmUserRepository().UpdateHelmUserRepository(v.ID, &v)
 	}
 
+	for _, v := range backup.NotificationChannel {
+		store.NotificationChannel().UpdateNotificationChannel(v.ID, &v)
+	}
+
 	for _, v := range backup.Registry {
 		store.Registry().UpdateRegistry(v.ID, &v)
 	}
@@ -40,8 +40,12 @@ func (tx *StoreTx) EndpointRelation() dataservices.EndpointRelationService {
 	return tx.store.EndpointRelationService.Tx(tx.tx)
 }
 
-func (tx *StoreTx) FDOProfile() dataservices.FDOProfileService                 { return nil }
-func (tx *StoreTx) HelmUserRepository() dataservices.HelmUserRepositoryService { return nil }
+func (tx *StoreTx) AuditLog() dataservices.AuditLogService { return nil }
+
+func (tx *StoreTx) FDOProfile() dataservices.FDOProfileService                   { return nil }
+func (tx *StoreTx) HelmUserRepository() dataservices.HelmUserRepositoryService   { return nil }
+func (tx *StoreTx) ImageScan() dataservices.ImageScanService                     { return nil }
+func (tx *StoreTx) NotificationChannel() dataservices.NotificationChannelService { return nil }
 
 func (tx *StoreTx) Registry() dataservices.RegistryService {
 	return nil
@@ -54,14 +58,16 @@ func (tx *StoreTx) Role() dataservices.RoleService {
 }
 
 func (tx *StoreTx) APIKeyRepository() dataservices.APIKeyRepository { return nil }
+func (tx *StoreTx) Session() dataservices.SessionService            { return nil }
 func (tx *StoreTx) Settings() dataservices.SettingsService          { return nil }
 
 func (tx *StoreTx) Snapshot() dataservices.SnapshotService {
 	return tx.store.SnapshotService.Tx(tx.tx)
 }
 
-func (tx *StoreTx) SSLSettings() dataservices.SSLSettingsService { return nil }
-func (tx *StoreTx) Stack() dataservices.StackService             { return nil }
+func (tx *StoreTx) SSLSettings() dataservices.SSLSettingsService   { return nil }
+func (tx *StoreTx) Stack() dataservices.StackService               { return nil }
+func (tx *StoreTx) StackVersion() dataservices.StackVersionService { return nil }
 
 func (tx *StoreTx) Tag() dataservices.TagService {
 	return tx.store.TagService.Tx(tx.tx)
@@ -0,0 +1,54 @@
+package datastore
+
+import (
+	"bytes"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/database"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/filesystem"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreWithSQLite(t *testing.T) {
+	is := assert.New(t)
+	storePath := t.TempDir()
+
+	fileService, err := filesystem.NewService(storePath, "")
+	require.NoError(t, err)
+
+	connection, err := database.NewDatabase("sqlite", storePath, []byte("apassphrasewhichneedstobe32bytes"))
+	require.NoError(t, err)
+
+	store := NewStore(storePath, fileService, connection)
+	isNew, err := store.Open()
+	require.NoError(t, err)
+	defer store.Close()
+
+	is.True(isNew)
+	require.NoError(t, store.Init())
+
+	settings, err := store.Settings().Settings()
+	require.NoError(t, err)
+	is.Equal(portainer.AuthenticationInternal, settings.AuthenticationMethod)
+
+	user := &portainer.User{Username: "admin", Role: portainer.AdministratorRole}
+	require.NoError(t, store.User().Create(user))
+	is.NotZero(user.ID)
+
+	err = store.UpdateTx(func(tx dataservices.DataStoreTx) error {
+		return tx.Tag().Create(&portainer.Tag{Name: "tag"})
+	})
+	require.NoError(t, err)
+
+	tags, err := store.Tag().Tags()
+	require.NoError(t, err)
+	is.Len(tags, 1)
+
+	var backup bytes.Buffer
+	is.NoError(store.BackupTo(&backup))
+	is.NotZero(backup.Len())
+}
@@ -0,0 +1,216 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// ContainerService represents a service used to manage the containers of a Docker environment(endpoint)
+type ContainerService struct {
+	factory *ClientFactory
+}
+
+// NewContainerService returns a new ContainerService instance
+func NewContainerService(factory *ClientFactory) *ContainerService {
+	return &ContainerService{
+		factory: factory,
+	}
+}
+
+// Recreate pulls the image of a container, optionally with a different tag, and replaces the container
+// with a new one created from the same configuration. The previous container is restored if the new one
+// cannot be created or started.
+// registryAuth is the base64 encoded registry authentication header used to pull the image, if any.
+func (c *ContainerService) Recreate(ctx context.Context, endpoint *portainer.Endpoint, containerID string, imageTag string, registryAuth string) (*types.ContainerJSON, error) {
+	cli, err := c.factory.CreateClient(endpoint, "", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create a Docker client")
+	}
+	defer cli.Close()
+
+	container, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to inspect the container")
+	}
+
+	image := imageWithTag(container.Config.Image, imageTag)
+
+	err = pullImage(ctx, cli, image, registryAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug().Str("container", container.Name).Str("image", image).Msg("recreating container")
+
+	wasRunning := container.State != nil && container.State.Running
+	if wasRunning {
+		err = cli.ContainerStop(ctx, container.ID, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to stop the container")
+		}
+	}
+
+	name := strings.TrimPrefix(container.Name, "/")
+	tempName := fmt.Sprintf("%s-old-%d", name, time.Now().Unix())
+
+	err = cli.ContainerRename(ctx, container.ID, tempName)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to rename the container")
+	}
+
+	newContainerID, err := createContainer(ctx, cli, container, image, name)
+	if err != nil {
+		restoreContainer(ctx, cli, container.ID, name, wasRunning)
+		return nil, err
+	}
+
+	err = cli.ContainerStart(ctx, newContainerID, types.ContainerStartOptions{})
+	if err != nil {
+		cli.ContainerRemove(ctx, newContainerID, types.ContainerRemoveOptions{Force: true})
+		restoreContainer(ctx, cli, container.ID, name, wasRunning)
+		return nil, errors.Wrap(err, "unable to start the new container")
+	}
+
+	err = cli.ContainerRemove(ctx, container.ID, types.ContainerRemoveOptions{})
+	if err != nil {
+		log.Warn().Err(err).Str("container", tempName).Msg("unable to remove the previous container")
+	}
+
+	newContainer, err := cli.ContainerInspect(ctx, newContainerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to inspect the new container")
+	}
+
+	return &newContainer, nil
+}
+
+func imageWithTag(image, tag string) string {
+	image = strings.Split(image, "@sha")[0]
+	if tag == "" {
+		return image
+	}
+
+	// ignore the colon of a registry port, e.g. registry:5000/image
+	tagIndex := strings.LastIndex(image, ":")
+	if tagIndex == -1 || strings.Contains(image[tagIndex:], "/") {
+		tagIndex = len(image)
+	}
+
+	return image[:tagIndex] + ":" + tag
+}
+
+func pullImage(ctx context.Context, cli *client.Client, image, registryAuth string) error {
+	rc, err := cli.ImagePull(ctx, image, types.ImagePullOptions{RegistryAuth: registryAuth})
+	if err != nil {
+		return errors.Wrapf(err, "unable to pull the image %s", image)
+	}
+	defer rc.Close()
+
+	// the pull is only complete once the progress stream has been consumed
+	_, err = io.Copy(io.Discard, rc)
+	if err != nil {
+		return errors.Wrapf(err, "unable to pull the image %s", image)
+	}
+
+	return nil
+}
+
+func createContainer(ctx context.Context, cli *client.Client, container types.ContainerJSON, image, name string) (string, error) {
+	config := container.Config
+	config.Image = image
+
+	// let Docker generate the default hostname of the new container
+	if len(container.ID) >= 12 && config.Hostname == container.ID[:12] {
+		config.Hostname = ""
+	}
+
+	// only one network can be specified on creation, the others are connected afterwards
+	networks := map[string]*network.EndpointSettings{}
+	if container.NetworkSettings != nil {
+		networks = container.NetworkSettings.Networks
+	}
+
+	networkMode := container.HostConfig.NetworkMode
+	primaryNetwork := string(networkMode)
+	if networkMode.IsDefault() {
+		primaryNetwork = "bridge"
+	}
+
+	// containers sharing the network stack of the host or of another container cannot join other networks
+	if networkMode.IsHost() || networkMode.IsNone() || networkMode.IsContainer() {
+		networks = map[string]*network.EndpointSettings{}
+	}
+
+	networkingConfig := &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{}}
+	if settings, ok := networks[primaryNetwork]; ok {
+		networkingConfig.EndpointsConfig[primaryNetwork] = endpointSettings(settings, container.ID)
+	}
+
+	created, err := cli.ContainerCreate(ctx, config, container.HostConfig, networkingConfig, nil, name)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to create the new container")
+	}
+
+	for networkName, settings := range networks {
+		if networkName == primaryNetwork {
+			continue
+		}
+
+		err = cli.NetworkConnect(ctx, networkName, created.ID, endpointSettings(settings, container.ID))
+		if err != nil {
+			cli.ContainerRemove(ctx, created.ID, types.ContainerRemoveOptions{Force: true})
+			return "", errors.Wrapf(err, "unable to connect the new container to the network %s", networkName)
+		}
+	}
+
+	return created.ID, nil
+}
+
+// endpointSettings keeps the user defined settings of a network endpoint,
+// the alias generated by Docker from the previous container ID is dropped
+func endpointSettings(settings *network.EndpointSettings, containerID string) *network.EndpointSettings {
+	if settings == nil {
+		return nil
+	}
+
+	aliases := make([]string, 0, len(settings.Aliases))
+	for _, alias := range settings.Aliases {
+		if !strings.HasPrefix(containerID, alias) {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	return &network.EndpointSettings{
+		IPAMConfig: settings.IPAMConfig,
+		Links:      settings.Links,
+		Aliases:    aliases,
+		DriverOpts: settings.DriverOpts,
+	}
+}
+
+func restoreContainer(ctx context.Context, cli *client.Client, containerID, name string, start bool) {
+	err := cli.ContainerRename(ctx, containerID, name)
+	if err != nil {
+		log.Error().Err(err).Str("container", name).Msg("unable to restore the name of the previous container")
+	}
+
+	if !start {
+		return
+	}
+
+	err = cli.ContainerStart(ctx, containerID, types.ContainerStartOptions{})
+	if err != nil {
+		log.Error().Err(err).Str("container", name).Msg("unable to restart the previous container")
+	}
+}
@@ -0,0 +1,32 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_imageWithTag(t *testing.T) {
+	is := assert.New(t)
+
+	is.Equal("nginx:latest", imageWithTag("nginx:latest", ""))
+	is.Equal("nginx:1.25", imageWithTag("nginx:latest", "1.25"))
+	is.Equal("nginx:1.25", imageWithTag("nginx", "1.25"))
+	is.Equal("nginx:latest", imageWithTag("nginx:latest@sha256:abcdef", ""))
+	is.Equal("registry:5000/app:v2", imageWithTag("registry:5000/app", "v2"))
+	is.Equal("registry:5000/app:v2", imageWithTag("registry:5000/app:v1", "v2"))
+}
+
+func Test_endpointSettings(t *testing.T) {
+	is := assert.New(t)
+
+	settings := endpointSettings(&network.EndpointSettings{
+		Aliases:   []string{"web", "0123456789ab"},
+		IPAddress: "172.17.0.2",
+	}, "0123456789abcdef")
+
+	is.Equal([]string{"web"}, settings.Aliases)
+	is.Empty(settings.IPAddress, "the runtime settings should not be copied")
+	is.Nil(endpointSettings(nil, "0123456789abcdef"))
+}
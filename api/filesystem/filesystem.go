@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/gofrs/uuid"
@@ -35,6 +36,8 @@ const (
 	ManifestFileDefaultName = "k8s-deployment.yml"
 	// EdgeStackStorePath represents the subfolder where edge stack files are stored in the file store folder.
 	EdgeStackStorePath = "edge_stacks"
+	// EdgeStackVersionsPath represents the subfolder of an edge stack folder where the files of its versions are retained.
+	EdgeStackVersionsPath = ".versions"
 	// FDOProfileStorePath represents the subfolder where FDO profiles files are stored in the file store folder.
 	FDOProfileStorePath = "fdo_profiles"
 	// PrivateKeyFile represents the name on disk of the file containing the private key.
@@ -45,6 +48,8 @@ const (
 	BinaryStorePath = "bin"
 	// EdgeJobStorePath represents the subfolder where schedule files are stored.
 	EdgeJobStorePath = "edge_jobs"
+	// EdgeJobRunsPath represents the subfolder of an Edge job folder where the logs of its runs are retained.
+	EdgeJobRunsPath = "runs"
 	// DockerConfigPath represents the subfolder where docker configuration is stored.
 	DockerConfigPath = "docker_config"
 	// ExtensionRegistryManagementStorePath represents the subfolder where files related to the
@@ -316,6 +321,33 @@ func (service *Service) StoreEdgeStackFileFromBytes(edgeStackIdentifier, fileNam
 	return service.wrapFileStore(stackStorePath), nil
 }
 
+// GetEdgeStackProjectPathByVersion returns the absolute path on the FS where the files of a version
+// of an edge stack are retained.
+func (service *Service) GetEdgeStackProjectPathByVersion(edgeStackIdentifier string, version int) string {
+	return JoinPaths(service.GetEdgeStackProjectPath(edgeStackIdentifier), EdgeStackVersionsPath, strconv.Itoa(version))
+}
+
+// StoreEdgeStackFileVersionFromBytes stores a file of a version of an edge stack, the file name can contain subfolders.
+// It returns the path to the folder where the files of the version are stored.
+func (service *Service) StoreEdgeStackFileVersionFromBytes(edgeStackIdentifier string, version int, fileName string, data []byte) (string, error) {
+	versionStorePath := JoinPaths(EdgeStackStorePath, edgeStackIdentifier, EdgeStackVersionsPath, strconv.Itoa(version))
+
+	filePath := JoinPaths(versionStorePath, fileName)
+	err := service.createDirectoryInStore(filepath.Dir(filePath))
+	if err != nil {
+		return "", err
+	}
+
+	r := bytes.NewReader(data)
+
+	err = service.createFileInStore(filePath, r)
+	if err != nil {
+		return "", err
+	}
+
+	return service.wrapFileStore(versionStorePath), nil
+}
+
 // StoreRegistryManagementFileFromBytes creates a subfolder in the
 // ExtensionRegistryManagementStorePath and stores a new file from bytes.
 // It returns the path to the folder where the file is stored.
@@ -632,6 +664,36 @@ func (service *Service) StoreEdgeJobTaskLogFileFromBytes(edgeJobID, taskID strin
 	return service.createFileInStore(filePath, r)
 }
 
+// GetEdgeJobRunLogsFolder returns the absolute path of the folder holding the logs of a run of an Edge job
+func (service *Service) GetEdgeJobRunLogsFolder(edgeJobID, runID string) string {
+	return JoinPaths(service.GetEdgeJobFolder(edgeJobID), EdgeJobRunsPath, runID)
+}
+
+// StoreEdgeJobRunLogFileFromBytes stores the log file of a run of an Edge job on an environment
+func (service *Service) StoreEdgeJobRunLogFileFromBytes(edgeJobID, runID, taskID string, data []byte) error {
+	runStorePath := JoinPaths(EdgeJobStorePath, edgeJobID, EdgeJobRunsPath, runID)
+	err := service.createDirectoryInStore(runStorePath)
+	if err != nil {
+		return err
+	}
+
+	filePath := JoinPaths(runStorePath, fmt.Sprintf("logs_%s", taskID))
+	r := bytes.NewReader(data)
+	return service.createFileInStore(filePath, r)
+}
+
+// GetEdgeJobRunLogFileContent fetches the logs of a run of an Edge job on an environment
+func (service *Service) GetEdgeJobRunLogFileContent(edgeJobID, runID, taskID string) (string, error) {
+	path := JoinPaths(service.GetEdgeJobRunLogsFolder(edgeJobID, runID), fmt.Sprintf("logs_%s", taskID))
+
+	fileContent, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return string(fileContent), nil
+}
+
 func (service *Service) getEdgeJobTaskLogPath(edgeJobID string, taskID string) string {
 	return fmt.Sprintf("%s/logs_%s", service.GetEdgeJobFolder(edgeJobID), taskID)
 }
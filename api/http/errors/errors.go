@@ -11,4 +11,6 @@ var (
 	ErrResourceAccessDenied = errors.New("Access denied to resource")
 	// ErrNotAvailableInDemo feature is not allowed in demo
 	ErrNotAvailableInDemo = errors.New("This feature is not available in the demo version of Portainer")
+	// ErrTooManyRequests rate limit exceeded error
+	ErrTooManyRequests = errors.New("Too many requests, retry later")
 )
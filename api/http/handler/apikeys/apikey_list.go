@@ -0,0 +1,72 @@
+package apikeys
+
+import (
+	"net/http"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+)
+
+type apiKeyResponse struct {
+	portainer.APIKey
+	// Username of the owner of the API key
+	Username string `json:"username" example:"admin"`
+	// True when the API key has expired
+	Expired bool `json:"expired" example:"false"`
+}
+
+// @id APIKeyList
+// @summary List the API keys of all the users
+// @description List the API keys of all the users, along with their restrictions. The digests of the API keys are not returned.
+// @description **Access policy**: administrator
+// @tags users
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param userId query int false "Only list the API keys of this user"
+// @success 200 {array} apiKeyResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /apikeys [get]
+func (handler *Handler) apiKeyList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	userID, _ := request.RetrieveNumericQueryParameter(r, "userId", true)
+
+	var apiKeys []portainer.APIKey
+	var err error
+	if userID != 0 {
+		apiKeys, err = handler.apiKeyService.GetAPIKeys(portainer.UserID(userID))
+	} else {
+		apiKeys, err = handler.apiKeyService.GetAllAPIKeys()
+	}
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the API keys from the database", err)
+	}
+
+	users, err := handler.DataStore.User().Users()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve users from the database", err)
+	}
+
+	usernames := make(map[portainer.UserID]string, len(users))
+	for _, user := range users {
+		usernames[user.ID] = user.Username
+	}
+
+	now := time.Now()
+	result := make([]apiKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		apiKey.Digest = nil
+
+		result = append(result, apiKeyResponse{
+			APIKey:   apiKey,
+			Username: usernames[apiKey.UserID],
+			Expired:  apikey.IsExpired(apiKey, now),
+		})
+	}
+
+	return response.JSON(w, result)
+}
@@ -0,0 +1,44 @@
+package apikeys
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+)
+
+// @id APIKeyRevoke
+// @summary Revoke an API key
+// @description Revoke the API key of any user, the API key is deleted and cannot be used anymore.
+// @description **Access policy**: administrator
+// @tags users
+// @security ApiKeyAuth
+// @security jwt
+// @param id path int true "API key identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "API key not found"
+// @failure 500 "Server error"
+// @router /apikeys/{id} [delete]
+func (handler *Handler) apiKeyRevoke(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	apiKeyID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid API key identifier route variable", err)
+	}
+
+	_, err = handler.apiKeyService.GetAPIKey(portainer.APIKeyID(apiKeyID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find an API key with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find an API key with the specified identifier inside the database", err)
+	}
+
+	err = handler.apiKeyService.DeleteAPIKey(portainer.APIKeyID(apiKeyID))
+	if err != nil {
+		return httperror.InternalServerError("Unable to revoke the API key", err)
+	}
+
+	return response.Empty(w)
+}
@@ -0,0 +1,97 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/jwt"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_apiKeyListAndRevoke(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	adminUser := &portainer.User{ID: 1, Username: "admin", Role: portainer.AdministratorRole}
+	require.NoError(t, store.User().Create(adminUser))
+
+	standardUser := &portainer.User{ID: 2, Username: "standard", Role: portainer.StandardUserRole}
+	require.NoError(t, store.User().Create(standardUser))
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+	apiKeyService := apikey.NewAPIKeyService(store.APIKeyRepository(), store.User())
+	h := NewHandler(security.NewRequestBouncer(store, jwtService, apiKeyService), store, apiKeyService)
+
+	_, _, err = apiKeyService.GenerateApiKey(*adminUser, "admin-key")
+	require.NoError(t, err)
+
+	rawAPIKey, ciKey, err := apiKeyService.GenerateRestrictedApiKey(*standardUser, "ci", portainer.APIKeyRestrictions{
+		ExpiresAt:   time.Now().Add(time.Hour).Unix(),
+		EndpointIDs: []portainer.EndpointID{1},
+		Scopes:      []string{"stacks"},
+	})
+	require.NoError(t, err)
+
+	serve := func(user *portainer.User, method, url string) *httptest.ResponseRecorder {
+		token, _ := jwtService.GenerateToken(&portainer.TokenData{ID: user.ID, Username: user.Username, Role: user.Role})
+
+		req := httptest.NewRequest(method, url, nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	t.Run("admin lists the API keys of all the users", func(t *testing.T) {
+		rr := serve(adminUser, http.MethodGet, "/apikeys")
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var keys []apiKeyResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&keys))
+		require.Len(t, keys, 2)
+
+		for _, key := range keys {
+			is.Nil(key.Digest, "the digest should not be returned")
+		}
+
+		rr = serve(adminUser, http.MethodGet, fmt.Sprintf("/apikeys?userId=%d", standardUser.ID))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&keys))
+		require.Len(t, keys, 1)
+		is.Equal("standard", keys[0].Username)
+		is.Equal([]string{"stacks"}, keys[0].Scopes)
+		is.False(keys[0].Expired)
+	})
+
+	t.Run("standard user cannot list the API keys", func(t *testing.T) {
+		rr := serve(standardUser, http.MethodGet, "/apikeys")
+		is.Equal(http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("admin revokes an API key", func(t *testing.T) {
+		rr := serve(adminUser, http.MethodDelete, fmt.Sprintf("/apikeys/%d", ciKey.ID))
+		is.Equal(http.StatusNoContent, rr.Code)
+
+		_, _, err := apiKeyService.GetDigestUserAndKey(apiKeyService.HashRaw(rawAPIKey))
+		is.Error(err, "the revoked API key should not be usable")
+
+		rr = serve(adminUser, http.MethodDelete, fmt.Sprintf("/apikeys/%d", ciKey.ID))
+		is.Equal(http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,34 @@
+package apikeys
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+// Handler is the HTTP handler used to manage the API keys of all the users.
+type Handler struct {
+	*mux.Router
+	DataStore     dataservices.DataStore
+	apiKeyService apikey.APIKeyService
+}
+
+// NewHandler creates a handler to manage the API keys of all the users.
+func NewHandler(bouncer *security.RequestBouncer, dataStore dataservices.DataStore, apiKeyService apikey.APIKeyService) *Handler {
+	h := &Handler{
+		Router:        mux.NewRouter(),
+		DataStore:     dataStore,
+		apiKeyService: apiKeyService,
+	}
+
+	h.Handle("/apikeys",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.apiKeyList))).Methods(http.MethodGet)
+	h.Handle("/apikeys/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.apiKeyRevoke))).Methods(http.MethodDelete)
+
+	return h
+}
@@ -0,0 +1,144 @@
+package auditlogs
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+)
+
+type auditLogFilters struct {
+	userID       portainer.UserID
+	endpointID   portainer.EndpointID
+	resourceType string
+	from         int64
+	to           int64
+}
+
+// @id AuditLogList
+// @summary List audit logs
+// @description List the mutating API calls recorded in the audit log, most recent first.
+// @description The logs can be exported as CSV using the format query parameter.
+// @description **Access policy**: administrator
+// @tags audit_logs
+// @security ApiKeyAuth
+// @security jwt
+// @produce json,text/csv
+// @param userId query int false "Only return the logs of this user"
+// @param endpointId query int false "Only return the logs targeting this environment(endpoint)"
+// @param resourceType query string false "Only return the logs targeting this type of resource" example(stacks)
+// @param from query int false "Only return the logs recorded after this unix timestamp"
+// @param to query int false "Only return the logs recorded before this unix timestamp"
+// @param format query string false "Export format" Enum("json", "csv")
+// @success 200 {array} portainer.AuditLog "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /audit-logs [get]
+func (handler *Handler) auditLogList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	userID, _ := request.RetrieveNumericQueryParameter(r, "userId", true)
+	endpointID, _ := request.RetrieveNumericQueryParameter(r, "endpointId", true)
+	resourceType, _ := request.RetrieveQueryParameter(r, "resourceType", true)
+	from, _ := request.RetrieveNumericQueryParameter(r, "from", true)
+	to, _ := request.RetrieveNumericQueryParameter(r, "to", true)
+
+	format, _ := request.RetrieveQueryParameter(r, "format", true)
+	if format != "" && format != "json" && format != "csv" {
+		return httperror.BadRequest("Invalid format query parameter, supported values are json and csv", nil)
+	}
+
+	if from != 0 && to != 0 && from > to {
+		return httperror.BadRequest("Invalid time range, from must be before to", nil)
+	}
+
+	auditLogs, err := handler.DataStore.AuditLog().AuditLogs()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve audit logs from the database", err)
+	}
+
+	auditLogs = filterAuditLogs(auditLogs, auditLogFilters{
+		userID:       portainer.UserID(userID),
+		endpointID:   portainer.EndpointID(endpointID),
+		resourceType: resourceType,
+		from:         int64(from),
+		to:           int64(to),
+	})
+
+	sort.SliceStable(auditLogs, func(i, j int) bool {
+		return auditLogs[i].ID > auditLogs[j].ID
+	})
+
+	if format == "csv" {
+		return writeCSV(w, auditLogs)
+	}
+
+	return response.JSON(w, auditLogs)
+}
+
+func filterAuditLogs(auditLogs []portainer.AuditLog, filters auditLogFilters) []portainer.AuditLog {
+	filtered := make([]portainer.AuditLog, 0, len(auditLogs))
+
+	for _, auditLog := range auditLogs {
+		if filters.userID != 0 && auditLog.UserID != filters.userID {
+			continue
+		}
+
+		if filters.endpointID != 0 && auditLog.EndpointID != filters.endpointID {
+			continue
+		}
+
+		if filters.resourceType != "" && auditLog.ResourceType != filters.resourceType {
+			continue
+		}
+
+		if filters.from != 0 && auditLog.Timestamp < filters.from {
+			continue
+		}
+
+		if filters.to != 0 && auditLog.Timestamp > filters.to {
+			continue
+		}
+
+		filtered = append(filtered, auditLog)
+	}
+
+	return filtered
+}
+
+func writeCSV(w http.ResponseWriter, auditLogs []portainer.AuditLog) *httperror.HandlerError {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=portainer-audit-logs_%s.csv", time.Now().Format("20060102150405")))
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"Id", "Timestamp", "UserId", "Username", "ApiKeyId", "EndpointId", "ResourceType", "Method", "Route", "Path", "Status", "Body"})
+
+	for _, auditLog := range auditLogs {
+		writer.Write([]string{
+			strconv.Itoa(int(auditLog.ID)),
+			time.Unix(auditLog.Timestamp, 0).UTC().Format(time.RFC3339),
+			strconv.Itoa(int(auditLog.UserID)),
+			auditLog.Username,
+			strconv.Itoa(int(auditLog.APIKeyID)),
+			strconv.Itoa(int(auditLog.EndpointID)),
+			auditLog.ResourceType,
+			auditLog.Method,
+			auditLog.Route,
+			auditLog.Path,
+			strconv.Itoa(auditLog.Status),
+			auditLog.Body,
+		})
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return httperror.InternalServerError("Unable to write the audit logs", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/asaskevich/govalidator"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/mfa"
+)
+
+type authenticateMFAPayload struct {
+	// Token of the MFA challenge returned by /auth
+	MFAToken string `json:"mfaToken" validate:"required"`
+	// TOTP code, or recovery code
+	Code string `json:"code" example:"123456" validate:"required"`
+}
+
+func (payload *authenticateMFAPayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.MFAToken) {
+		return errors.New("Invalid MFA token")
+	}
+
+	if govalidator.IsNull(payload.Code) {
+		return errors.New("Invalid code")
+	}
+
+	return nil
+}
+
+type authenticateMFAEnrollPayload struct {
+	// Token of the MFA challenge returned by /auth
+	MFAToken string `json:"mfaToken" validate:"required"`
+}
+
+func (payload *authenticateMFAEnrollPayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.MFAToken) {
+		return errors.New("Invalid MFA token")
+	}
+
+	return nil
+}
+
+type authenticateMFAActivateResponse struct {
+	// JWT token used to authenticate against the API
+	JWT string `json:"jwt"`
+	// Single use recovery codes, they cannot be retrieved afterwards
+	RecoveryCodes []string `json:"recoveryCodes" example:"abcde-fghij"`
+}
+
+// @id AuthenticateMFA
+// @summary Solve an MFA challenge
+// @description **Access policy**: public
+// @description Use this environment(endpoint) to solve the MFA challenge returned by /auth with a TOTP code or a recovery code.
+// @description The challenge is revoked after too many invalid codes.
+// @tags auth
+// @accept json
+// @produce json
+// @param body body authenticateMFAPayload true "MFA challenge and code"
+// @success 200 {object} authenticateResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 422 "Invalid code or challenge"
+// @failure 500 "Server error"
+// @router /auth/mfa [post]
+func (handler *Handler) authenticateMFA(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload authenticateMFAPayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	challenge, user, httpErr := handler.retrieveMFAChallenge(payload.MFAToken)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	if challenge.EnrollmentRequired {
+		return httperror.BadRequest("Multi-factor authentication enrollment is required", mfa.ErrNotEnrolled)
+	}
+
+	err = handler.MFAService.Verify(user, payload.Code)
+	if err != nil {
+		return handler.failMFAChallenge(r, payload.MFAToken, user, err)
+	}
+
+	handler.MFAService.CompleteChallenge(payload.MFAToken)
+
+	return handler.writeToken(w, r, user, challenge.ForceChangePassword)
+}
+
+// @id AuthenticateMFAEnroll
+// @summary Enroll a TOTP authenticator during the login
+// @description **Access policy**: public
+// @description Use this environment(endpoint) to generate the TOTP secret of a user who must enroll before being issued a JWT.
+// @description The enrollment is completed by /auth/mfa/activate.
+// @tags auth
+// @accept json
+// @produce json
+// @param body body authenticateMFAEnrollPayload true "MFA challenge"
+// @success 200 {object} mfa.Enrollment "Success"
+// @failure 400 "Invalid request"
+// @failure 422 "Invalid challenge"
+// @failure 500 "Server error"
+// @router /auth/mfa/enroll [post]
+func (handler *Handler) authenticateMFAEnroll(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload authenticateMFAEnrollPayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	challenge, user, httpErr := handler.retrieveMFAChallenge(payload.MFAToken)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	if !challenge.EnrollmentRequired {
+		return httperror.BadRequest("Multi-factor authentication is already enabled", mfa.ErrAlreadyEnabled)
+	}
+
+	enrollment, err := handler.MFAService.Enroll(user)
+	if err != nil {
+		return httperror.InternalServerError("Unable to enroll the user", err)
+	}
+
+	return response.JSON(w, enrollment)
+}
+
+// @id AuthenticateMFAActivate
+// @summary Activate a TOTP authenticator during the login
+// @description **Access policy**: public
+// @description Use this environment(endpoint) to complete the enrollment started by /auth/mfa/enroll with a TOTP code.
+// @description The JWT is returned along with the recovery codes of the user.
+// @tags auth
+// @accept json
+// @produce json
+// @param body body authenticateMFAPayload true "MFA challenge and TOTP code"
+// @success 200 {object} authenticateMFAActivateResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 422 "Invalid code or challenge"
+// @failure 500 "Server error"
+// @router /auth/mfa/activate [post]
+func (handler *Handler) authenticateMFAActivate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload authenticateMFAPayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	challenge, user, httpErr := handler.retrieveMFAChallenge(payload.MFAToken)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	if !challenge.EnrollmentRequired {
+		return httperror.BadRequest("Multi-factor authentication is already enabled", mfa.ErrAlreadyEnabled)
+	}
+
+	recoveryCodes, err := handler.MFAService.Activate(user, payload.Code)
+	if errors.Is(err, mfa.ErrNotEnrolled) {
+		return httperror.BadRequest("Multi-factor authentication enrollment has not been started", err)
+	} else if err != nil {
+		return handler.failMFAChallenge(r, payload.MFAToken, user, err)
+	}
+
+	handler.MFAService.CompleteChallenge(payload.MFAToken)
+
+	token, err := handler.JWTService.GenerateSessionToken(composeTokenData(user, challenge.ForceChangePassword), security.StripAddrPort(r.RemoteAddr), r.UserAgent())
+	if err != nil {
+		return httperror.InternalServerError("Unable to generate JWT token", err)
+	}
+
+	return response.JSON(w, &authenticateMFAActivateResponse{JWT: token, RecoveryCodes: recoveryCodes})
+}
+
+func (handler *Handler) writeMFAChallenge(w http.ResponseWriter, user *portainer.User, forceChangePassword bool) *httperror.HandlerError {
+	enrollmentRequired := !user.MFA.Enabled
+
+	token, err := handler.MFAService.NewChallenge(user, enrollmentRequired, forceChangePassword)
+	if err != nil {
+		return httperror.InternalServerError("Unable to create the multi-factor authentication challenge", err)
+	}
+
+	return response.JSON(w, &authenticateResponse{
+		MFARequired:           true,
+		MFAEnrollmentRequired: enrollmentRequired,
+		MFAToken:              token,
+	})
+}
+
+func (handler *Handler) retrieveMFAChallenge(token string) (*mfa.Challenge, *portainer.User, *httperror.HandlerError) {
+	challenge, err := handler.MFAService.Challenge(token)
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{StatusCode: http.StatusUnprocessableEntity, Message: "Invalid multi-factor authentication challenge", Err: err}
+	}
+
+	user, err := handler.DataStore.User().User(challenge.UserID)
+	if err != nil {
+		return nil, nil, httperror.InternalServerError("Unable to retrieve the user of the challenge from the database", err)
+	}
+
+	return challenge, user, nil
+}
+
+func (handler *Handler) failMFAChallenge(r *http.Request, token string, user *portainer.User, err error) *httperror.HandlerError {
+	if !errors.Is(err, mfa.ErrInvalidCode) {
+		return httperror.InternalServerError("Unable to verify the multi-factor authentication code", err)
+	}
+
+	handler.MFAService.FailChallenge(token)
+	handler.publishAuthFailure(r, user.Username)
+
+	return &httperror.HandlerError{StatusCode: http.StatusUnprocessableEntity, Message: "Invalid multi-factor authentication code", Err: httperrors.ErrUnauthorized}
+}
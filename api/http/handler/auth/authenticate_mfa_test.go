@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/crypto"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/jwt"
+	"github.com/portainer/portainer/api/mfa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_authenticateWithMFA(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	cryptoService := &crypto.Service{}
+	hash, err := cryptoService.Hash("a-long-enough-password")
+	require.NoError(t, err)
+
+	admin := &portainer.User{ID: 1, Username: "admin", Password: hash, Role: portainer.AdministratorRole}
+	require.NoError(t, store.User().Create(admin))
+
+	settings, err := store.Settings().Settings()
+	require.NoError(t, err)
+	settings.AuthenticationMethod = portainer.AuthenticationInternal
+	settings.InternalAuthSettings.EnforceAdminMFA = true
+	require.NoError(t, store.Settings().UpdateSettings(settings))
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+
+	h := NewHandler(
+		security.NewRequestBouncer(store, jwtService, nil),
+		security.NewRateLimiter(100, time.Second, time.Hour),
+		security.NewPasswordStrengthChecker(store.Settings()),
+	)
+	h.DataStore = store
+	h.CryptoService = cryptoService
+	h.JWTService = jwtService
+	h.MFAService = mfa.NewService(store, []byte("secret"))
+
+	post := func(url string, payload interface{}, result interface{}) int {
+		data, err := json.Marshal(payload)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, url, bytes.NewBuffer(data)))
+
+		if result != nil && rr.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(result))
+		}
+
+		return rr.Code
+	}
+
+	login := func() authenticateResponse {
+		var resp authenticateResponse
+		is.Equal(http.StatusOK, post("/auth", authenticatePayload{Username: "admin", Password: "a-long-enough-password"}, &resp))
+		is.Empty(resp.JWT, "the JWT should not be issued before the second factor is verified")
+		is.True(resp.MFARequired)
+
+		return resp
+	}
+
+	// the enrollment is enforced for the administrators
+	challenge := login()
+	is.True(challenge.MFAEnrollmentRequired)
+	is.Equal(http.StatusBadRequest, post("/auth/mfa", authenticateMFAPayload{MFAToken: challenge.MFAToken, Code: "000000"}, nil))
+
+	var enrollment mfa.Enrollment
+	is.Equal(http.StatusOK, post("/auth/mfa/enroll", authenticateMFAEnrollPayload{MFAToken: challenge.MFAToken}, &enrollment))
+
+	code := func() string {
+		// the codes cannot be used twice, the code of the next time step is accepted as well
+		code, err := mfa.GenerateCode(enrollment.Secret, time.Now().Add(30*time.Second))
+		require.NoError(t, err)
+
+		return code
+	}
+
+	var activation authenticateMFAActivateResponse
+	is.Equal(http.StatusOK, post("/auth/mfa/activate", authenticateMFAPayload{MFAToken: challenge.MFAToken, Code: code()}, &activation))
+	is.NotEmpty(activation.JWT)
+	is.Len(activation.RecoveryCodes, 10)
+
+	is.Equal(http.StatusUnprocessableEntity, post("/auth/mfa/activate", authenticateMFAPayload{MFAToken: challenge.MFAToken, Code: code()}, nil), "the challenge should be revoked once solved")
+
+	// the next logins require a code
+	challenge = login()
+	is.False(challenge.MFAEnrollmentRequired)
+	is.Equal(http.StatusUnprocessableEntity, post("/auth/mfa", authenticateMFAPayload{MFAToken: challenge.MFAToken, Code: "000000"}, nil))
+
+	var resp authenticateResponse
+	is.Equal(http.StatusOK, post("/auth/mfa", authenticateMFAPayload{MFAToken: challenge.MFAToken, Code: activation.RecoveryCodes[0]}, &resp))
+	is.NotEmpty(resp.JWT)
+
+	tokenData, err := jwtService.ParseAndVerifyToken(resp.JWT)
+	require.NoError(t, err)
+	is.Equal(admin.ID, tokenData.ID)
+}
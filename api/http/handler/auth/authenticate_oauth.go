@@ -1,8 +1,12 @@
 package auth
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"net/http"
+	"regexp"
+	"time"
 
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/libhttp/request"
@@ -13,9 +17,14 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// oauthRequestCookieName is the name of the cookie holding the authorization request initiated by /auth/oauth/login
+const oauthRequestCookieName = "portainer_oauth_request"
+
 type oauthPayload struct {
 	// OAuth code returned from OAuth Provided
 	Code string
+	// State returned by the authorization server, required when the authorization request was initiated by /auth/oauth/login
+	State string
 }
 
 func (payload *oauthPayload) Validate(r *http.Request) error {
@@ -26,21 +35,62 @@ func (payload *oauthPayload) Validate(r *http.Request) error {
 	return nil
 }
 
-func (handler *Handler) authenticateOAuth(code string, settings *portainer.OAuthSettings) (string, error) {
+func (handler *Handler) authenticateOAuth(code string, request *portainer.OAuthAuthorizationRequest, settings *portainer.OAuthSettings) (*portainer.OAuthUserInfo, error) {
 	if code == "" {
-		return "", errors.New("Invalid OAuth authorization code")
+		return nil, errors.New("Invalid OAuth authorization code")
 	}
 
 	if settings == nil {
-		return "", errors.New("Invalid OAuth configuration")
+		return nil, errors.New("Invalid OAuth configuration")
 	}
 
-	username, err := handler.OAuthService.Authenticate(code, settings)
+	return handler.OAuthService.AuthenticateUser(code, request, settings)
+}
+
+// @id OAuthLogin
+// @summary Redirect to the OAuth authorization server
+// @description **Access policy**: public
+// @description Redirects the user to the authorization server of the OpenID Connect provider.
+// @description The authorization request is protected by a state, PKCE and a nonce. It is kept in a cookie of the client
+// @description until the authorization code is validated by /auth/oauth/validate with the state returned by the authorization server.
+// @tags auth
+// @success 302 "Redirection to the authorization server"
+// @failure 403 "OAuth authentication is not enabled"
+// @failure 500 "Server error"
+// @router /auth/oauth/login [get]
+func (handler *Handler) oauthLogin(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	settings, err := handler.DataStore.Settings().Settings()
 	if err != nil {
-		return "", err
+		return httperror.InternalServerError("Unable to retrieve settings from the database", err)
 	}
 
-	return username, nil
+	if settings.AuthenticationMethod != portainer.AuthenticationOAuth {
+		return httperror.Forbidden("OAuth authentication is not enabled", errors.New("OAuth authentication is not enabled"))
+	}
+
+	authorizationURL, authorizationRequest, err := handler.OAuthService.AuthorizationURL(&settings.OAuthSettings)
+	if err != nil {
+		return httperror.InternalServerError("Unable to initiate the OAuth authorization request", err)
+	}
+
+	value, err := json.Marshal(authorizationRequest)
+	if err != nil {
+		return httperror.InternalServerError("Unable to encode the OAuth authorization request", err)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthRequestCookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(value),
+		Path:     "/",
+		Expires:  time.Unix(authorizationRequest.ExpiresAt, 0),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, authorizationURL, http.StatusFound)
+
+	return nil
 }
 
 // @id ValidateOAuth
@@ -52,7 +102,7 @@ func (handler *Handler) authenticateOAuth(code string, settings *portainer.OAuth
 // @param body body oauthPayload true "OAuth Credentials used for authentication"
 // @success 200 {object} authenticateResponse "Success"
 // @failure 400 "Invalid request"
-// @failure 422 "Invalid Credentials"
+// @failure 422 "Invalid Credentials or state"
 // @failure 500 "Server error"
 // @router /auth/oauth/validate [post]
 func (handler *Handler) validateOAuth(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
@@ -71,25 +121,34 @@ func (handler *Handler) validateOAuth(w http.ResponseWriter, r *http.Request) *h
 		return httperror.Forbidden("OAuth authentication is not enabled", errors.New("OAuth authentication is not enabled"))
 	}
 
-	username, err := handler.authenticateOAuth(payload.Code, &settings.OAuthSettings)
+	authorizationRequest := takeOAuthRequest(w, r)
+	if authorizationRequest != nil && (payload.State == "" || payload.State != authorizationRequest.State) {
+		return &httperror.HandlerError{StatusCode: http.StatusUnprocessableEntity, Message: "Invalid OAuth state", Err: httperrors.ErrUnauthorized}
+	}
+
+	oauthUser, err := handler.authenticateOAuth(payload.Code, authorizationRequest, &settings.OAuthSettings)
 	if err != nil {
 		log.Debug().Err(err).Msg("OAuth authentication error")
 
 		return httperror.InternalServerError("Unable to authenticate through OAuth", httperrors.ErrUnauthorized)
 	}
 
-	user, err := handler.DataStore.User().UserByUsername(username)
+	user, err := handler.DataStore.User().UserByUsername(oauthUser.Username)
 	if err != nil && !handler.DataStore.IsErrObjectNotFound(err) {
 		return httperror.InternalServerError("Unable to retrieve a user with the specified username from the database", err)
 	}
 
+	if user != nil && user.Disabled {
+		return httperror.Forbidden("Account disabled", httperrors.ErrUnauthorized)
+	}
+
 	if user == nil && !settings.OAuthSettings.OAuthAutoCreateUsers {
 		return httperror.Forbidden("Account not created beforehand in Portainer and automatic user provisioning not enabled", httperrors.ErrUnauthorized)
 	}
 
 	if user == nil {
 		user = &portainer.User{
-			Username: username,
+			Username: oauthUser.Username,
 			Role:     portainer.StandardUserRole,
 		}
 
@@ -113,5 +172,111 @@ func (handler *Handler) validateOAuth(w http.ResponseWriter, r *http.Request) *h
 
 	}
 
-	return handler.writeToken(w, user, false)
+	if settings.OAuthSettings.GroupsClaim != "" {
+		err = handler.syncUserTeamsWithOAuthGroups(user, oauthUser.Groups, &settings.OAuthSettings)
+		if err != nil {
+			return httperror.InternalServerError("Unable to synchronize the team memberships of the user", err)
+		}
+	}
+
+	return handler.writeToken(w, r, user, false)
+}
+
+// takeOAuthRequest returns the authorization request kept in the cookie of the client and removes the cookie,
+// nil when the authorization request was not initiated by /auth/oauth/login
+func takeOAuthRequest(w http.ResponseWriter, r *http.Request) *portainer.OAuthAuthorizationRequest {
+	cookie, err := r.Cookie(oauthRequestCookieName)
+	if err != nil {
+		return nil
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthRequestCookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	value, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return nil
+	}
+
+	var authorizationRequest portainer.OAuthAuthorizationRequest
+	if err := json.Unmarshal(value, &authorizationRequest); err != nil || authorizationRequest.State == "" {
+		return nil
+	}
+
+	return &authorizationRequest
+}
+
+// syncUserTeamsWithOAuthGroups synchronizes the team memberships of the user with its groups.
+// The groups are matched with the group mappings, or with the team names when there are no mappings.
+// The user is added to the matching teams and removed from the other ones, except from the default team.
+func (handler *Handler) syncUserTeamsWithOAuthGroups(user *portainer.User, groups []string, settings *portainer.OAuthSettings) error {
+	teams, err := handler.DataStore.Team().Teams()
+	if err != nil {
+		return err
+	}
+
+	matchingTeams := make(map[portainer.TeamID]bool)
+	for _, team := range teams {
+		if len(settings.GroupMappings) == 0 && teamExists(team.Name, groups) {
+			matchingTeams[team.ID] = true
+		}
+	}
+
+	for _, mapping := range settings.GroupMappings {
+		groupRegex, err := regexp.Compile("^(?:" + mapping.Group + ")$")
+		if err != nil {
+			return err
+		}
+
+		for _, group := range groups {
+			if groupRegex.MatchString(group) {
+				matchingTeams[mapping.TeamID] = true
+				break
+			}
+		}
+	}
+
+	memberships, err := handler.DataStore.TeamMembership().TeamMembershipsByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, membership := range memberships {
+		if matchingTeams[membership.TeamID] {
+			delete(matchingTeams, membership.TeamID)
+			continue
+		}
+
+		if membership.TeamID == settings.DefaultTeamID {
+			continue
+		}
+
+		err := handler.DataStore.TeamMembership().DeleteTeamMembership(membership.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, team := range teams {
+		if !matchingTeams[team.ID] {
+			continue
+		}
+
+		err := handler.DataStore.TeamMembership().Create(&portainer.TeamMembership{
+			UserID: user.ID,
+			TeamID: team.ID,
+			Role:   portainer.TeamMember,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
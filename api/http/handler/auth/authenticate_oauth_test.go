@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_syncUserTeamsWithOAuthGroups(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	for _, team := range []*portainer.Team{
+		{ID: 1, Name: "default"},
+		{ID: 2, Name: "developers"},
+		{ID: 3, Name: "operators"},
+		{ID: 4, Name: "legacy"},
+	} {
+		require.NoError(t, store.Team().Create(team))
+	}
+
+	user := &portainer.User{ID: 2, Username: "oidc-user", Role: portainer.StandardUserRole}
+	require.NoError(t, store.User().Create(user))
+
+	handler := &Handler{DataStore: store}
+
+	userTeams := func() []int {
+		memberships, err := store.TeamMembership().TeamMembershipsByUserID(user.ID)
+		require.NoError(t, err)
+
+		teamIDs := make([]int, 0, len(memberships))
+		for _, membership := range memberships {
+			teamIDs = append(teamIDs, int(membership.TeamID))
+		}
+		sort.Ints(teamIDs)
+
+		return teamIDs
+	}
+
+	for _, teamID := range []portainer.TeamID{1, 4} {
+		require.NoError(t, store.TeamMembership().Create(&portainer.TeamMembership{UserID: user.ID, TeamID: teamID, Role: portainer.TeamMember}))
+	}
+
+	t.Run("should match the groups with the team names without mappings", func(t *testing.T) {
+		err := handler.syncUserTeamsWithOAuthGroups(user, []string{"Developers"}, &portainer.OAuthSettings{DefaultTeamID: 1})
+		require.NoError(t, err)
+
+		is.Equal([]int{1, 2}, userTeams(), "the default team membership should be kept and legacy removed")
+	})
+
+	t.Run("should use the group mappings", func(t *testing.T) {
+		settings := &portainer.OAuthSettings{
+			DefaultTeamID: 1,
+			GroupMappings: []portainer.OAuthGroupMapping{
+				{Group: "ops-.*", TeamID: 3},
+				{Group: "dev", TeamID: 2},
+			},
+		}
+
+		err := handler.syncUserTeamsWithOAuthGroups(user, []string{"ops-eu", "developers"}, settings)
+		require.NoError(t, err)
+
+		is.Equal([]int{1, 3}, userTeams(), "the mappings should match the whole group name")
+	})
+}
+
+type testOAuthService struct {
+	request *portainer.OAuthAuthorizationRequest
+}
+
+func (service *testOAuthService) Authenticate(code string, configuration *portainer.OAuthSettings) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (service *testOAuthService) AuthenticateUser(code string, request *portainer.OAuthAuthorizationRequest, configuration *portainer.OAuthSettings) (*portainer.OAuthUserInfo, error) {
+	service.request = request
+
+	return &portainer.OAuthUserInfo{Username: "oauth-user"}, nil
+}
+
+func (service *testOAuthService) AuthorizationURL(configuration *portainer.OAuthSettings) (string, *portainer.OAuthAuthorizationRequest, error) {
+	return "https://idp.example.com/authorize", &portainer.OAuthAuthorizationRequest{
+		State:        "server-state",
+		CodeVerifier: "verifier",
+		Nonce:        "nonce",
+		ExpiresAt:    time.Now().Add(time.Minute).Unix(),
+	}, nil
+}
+
+func Test_oauthLogin(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	settings, err := store.Settings().Settings()
+	require.NoError(t, err)
+	settings.AuthenticationMethod = portainer.AuthenticationOAuth
+	require.NoError(t, store.Settings().UpdateSettings(settings))
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+
+	oauthService := &testOAuthService{}
+	h := NewHandler(
+		security.NewRequestBouncer(store, jwtService, nil),
+		security.NewRateLimiter(100, time.Second, time.Hour),
+		security.NewPasswordStrengthChecker(store.Settings()),
+	)
+	h.DataStore = store
+	h.OAuthService = oauthService
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/auth/oauth/login?state=client-state", nil))
+	require.Equal(t, http.StatusFound, rr.Code)
+	is.Equal("https://idp.example.com/authorize", rr.Header().Get("Location"))
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	is.Equal(oauthRequestCookieName, cookies[0].Name)
+	is.True(cookies[0].HttpOnly)
+
+	validate := func(state string) *httptest.ResponseRecorder {
+		data, err := json.Marshal(oauthPayload{Code: "code", State: state})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "/auth/oauth/validate", bytes.NewBuffer(data))
+		req.AddCookie(cookies[0])
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	t.Run("the state must match the authorization request of the client", func(t *testing.T) {
+		rr := validate("client-state")
+		is.Equal(http.StatusUnprocessableEntity, rr.Code)
+		is.Nil(oauthService.request)
+
+		cookies := rr.Result().Cookies()
+		require.Len(t, cookies, 1)
+		is.Negative(cookies[0].MaxAge, "the authorization request should be removed")
+	})
+
+	t.Run("the authorization request is used to exchange the code", func(t *testing.T) {
+		validate("server-state")
+
+		require.NotNil(t, oauthService.request)
+		is.Equal("verifier", oauthService.request.CodeVerifier)
+	})
+}
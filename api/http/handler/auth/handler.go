@@ -9,6 +9,8 @@ import (
 	"github.com/portainer/portainer/api/http/proxy"
 	"github.com/portainer/portainer/api/http/proxy/factory/kubernetes"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/mfa"
+	"github.com/portainer/portainer/api/notifications"
 
 	"github.com/gorilla/mux"
 )
@@ -23,6 +25,8 @@ type Handler struct {
 	OAuthService                portainer.OAuthService
 	ProxyManager                *proxy.Manager
 	KubernetesTokenCacheManager *kubernetes.TokenCacheManager
+	NotificationService         *notifications.Service
+	MFAService                  *mfa.Service
 	passwordStrengthChecker     security.PasswordStrengthChecker
 }
 
@@ -33,10 +37,18 @@ func NewHandler(bouncer *security.RequestBouncer, rateLimiter *security.RateLimi
 		passwordStrengthChecker: passwordStrengthChecker,
 	}
 
+	h.Handle("/auth/oauth/login",
+		rateLimiter.LimitAccess(bouncer.PublicAccess(httperror.LoggerHandler(h.oauthLogin)))).Methods(http.MethodGet)
 	h.Handle("/auth/oauth/validate",
 		rateLimiter.LimitAccess(bouncer.PublicAccess(httperror.LoggerHandler(h.validateOAuth)))).Methods(http.MethodPost)
 	h.Handle("/auth",
 		rateLimiter.LimitAccess(bouncer.PublicAccess(httperror.LoggerHandler(h.authenticate)))).Methods(http.MethodPost)
+	h.Handle("/auth/mfa",
+		rateLimiter.LimitAccess(bouncer.PublicAccess(httperror.LoggerHandler(h.authenticateMFA)))).Methods(http.MethodPost)
+	h.Handle("/auth/mfa/enroll",
+		rateLimiter.LimitAccess(bouncer.PublicAccess(httperror.LoggerHandler(h.authenticateMFAEnroll)))).Methods(http.MethodPost)
+	h.Handle("/auth/mfa/activate",
+		rateLimiter.LimitAccess(bouncer.PublicAccess(httperror.LoggerHandler(h.authenticateMFAActivate)))).Methods(http.MethodPost)
 	h.Handle("/auth/logout",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.logout))).Methods(http.MethodPost)
 
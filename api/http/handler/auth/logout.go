@@ -25,5 +25,12 @@ func (handler *Handler) logout(w http.ResponseWriter, r *http.Request) *httperro
 
 	handler.KubernetesTokenCacheManager.RemoveUserFromCache(tokenData.ID)
 
+	if tokenData.SessionID != 0 {
+		err := handler.DataStore.Session().DeleteSession(tokenData.SessionID)
+		if err != nil && !handler.DataStore.IsErrObjectNotFound(err) {
+			return httperror.InternalServerError("Unable to revoke the session", err)
+		}
+	}
+
 	return response.Empty(w)
 }
@@ -0,0 +1,147 @@
+package backup
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	operations "github.com/portainer/portainer/api/backup"
+)
+
+type backupSettingsUpdatePayload struct {
+	// Whether the automatic backups are enabled
+	Enabled bool `example:"true"`
+	// Cron expression of the backup schedule
+	CronExpression string `example:"0 2 * * *"`
+	// Password used to encrypt the backup archives, the current password is kept when omitted
+	// and the backups are not encrypted when empty
+	Password *string `example:"secret"`
+	// Number of backups to keep, 0 keeps every backup
+	KeepCount int `example:"7"`
+	// Number of days a backup is kept, 0 keeps every backup
+	KeepDays int `example:"30"`
+	// Destination of the backup archives, valid values are "local" and "s3"
+	Destination portainer.BackupDestinationType `example:"s3"`
+	// Directory used to store the backup archives for the local destination
+	LocalDirectory string `example:"/backups"`
+	// Settings of the S3 compatible destination, the current secret access key is kept when empty
+	S3Settings portainer.S3BackupSettings
+}
+
+func (payload *backupSettingsUpdatePayload) Validate(r *http.Request) error {
+	return nil
+}
+
+// @id BackupSettingsInspect
+// @summary Retrieve the settings of the automatic backups
+// @description **Access policy**: admin
+// @tags backup
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @success 200 {object} portainer.BackupSettings "Success"
+// @failure 500 "Server error"
+// @router /backup/settings [get]
+func (h *Handler) backupSettingsInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	settings, err := h.dataStore.Settings().Settings()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the settings from the database", err)
+	}
+
+	return response.JSON(w, hideBackupSecrets(settings.BackupSettings))
+}
+
+// @id BackupSettingsUpdate
+// @summary Update the settings of the automatic backups
+// @description Update the schedule, the retention policy and the destination of the automatic backups.
+// @description **Access policy**: admin
+// @tags backup
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @produce json
+// @param body body backupSettingsUpdatePayload true "Backup settings"
+// @success 200 {object} portainer.BackupSettings "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /backup/settings [put]
+func (h *Handler) backupSettingsUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload backupSettingsUpdatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	settings, err := h.dataStore.Settings().Settings()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the settings from the database", err)
+	}
+
+	backupSettings := portainer.BackupSettings{
+		Enabled:        payload.Enabled,
+		CronExpression: payload.CronExpression,
+		Password:       settings.BackupSettings.Password,
+		KeepCount:      payload.KeepCount,
+		KeepDays:       payload.KeepDays,
+		Destination:    payload.Destination,
+		LocalDirectory: payload.LocalDirectory,
+		S3Settings:     payload.S3Settings,
+	}
+
+	if payload.Password != nil {
+		backupSettings.Password = *payload.Password
+	}
+
+	if backupSettings.S3Settings.SecretAccessKey == "" {
+		backupSettings.S3Settings.SecretAccessKey = settings.BackupSettings.S3Settings.SecretAccessKey
+	}
+
+	err = operations.ValidateSettings(backupSettings)
+	if err != nil {
+		return httperror.BadRequest("Invalid backup settings", err)
+	}
+
+	settings.BackupSettings = backupSettings
+
+	err = h.dataStore.Settings().UpdateSettings(settings)
+	if err != nil {
+		return httperror.InternalServerError("Unable to persist the settings inside the database", err)
+	}
+
+	if h.backupService != nil {
+		err = h.backupService.Reschedule(backupSettings)
+		if err != nil {
+			return httperror.InternalServerError("Unable to schedule the automatic backups", err)
+		}
+	}
+
+	return response.JSON(w, hideBackupSecrets(backupSettings))
+}
+
+// @id BackupStatus
+// @summary Retrieve the status of the automatic backups
+// @description Retrieve the schedule and the result of the last run of the automatic backups.
+// @description **Access policy**: admin
+// @tags backup
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @success 200 {object} operations.Status "Success"
+// @failure 500 "Server error"
+// @router /backup/status [get]
+func (h *Handler) backupStatus(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	if h.backupService == nil {
+		return response.JSON(w, operations.Status{})
+	}
+
+	return response.JSON(w, h.backupService.Status())
+}
+
+func hideBackupSecrets(settings portainer.BackupSettings) portainer.BackupSettings {
+	settings.Password = ""
+	settings.S3Settings.SecretAccessKey = ""
+
+	return settings
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/gorilla/mux"
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/portainer/api/adminmonitor"
+	operations "github.com/portainer/portainer/api/backup"
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/demo"
 	"github.com/portainer/portainer/api/http/middlewares"
@@ -23,6 +24,7 @@ type Handler struct {
 	filestorePath   string
 	shutdownTrigger context.CancelFunc
 	adminMonitor    *adminmonitor.Monitor
+	backupService   *operations.ScheduledBackupService
 }
 
 // NewHandler creates an new instance of backup handler
@@ -34,7 +36,7 @@ func NewHandler(
 	shutdownTrigger context.CancelFunc,
 	adminMonitor *adminmonitor.Monitor,
 	demoService *demo.Service,
-
+	backupService *operations.ScheduledBackupService,
 ) *Handler {
 
 	h := &Handler{
@@ -45,6 +47,7 @@ func NewHandler(
 		filestorePath:   filestorePath,
 		shutdownTrigger: shutdownTrigger,
 		adminMonitor:    adminMonitor,
+		backupService:   backupService,
 	}
 
 	demoRestrictedRouter := h.NewRoute().Subrouter()
@@ -52,6 +55,10 @@ func NewHandler(
 
 	demoRestrictedRouter.Handle("/backup", bouncer.RestrictedAccess(adminAccess(httperror.LoggerHandler(h.backup)))).Methods(http.MethodPost)
 	demoRestrictedRouter.Handle("/restore", bouncer.PublicAccess(httperror.LoggerHandler(h.restore))).Methods(http.MethodPost)
+	demoRestrictedRouter.Handle("/backup/settings", bouncer.RestrictedAccess(adminAccess(httperror.LoggerHandler(h.backupSettingsUpdate)))).Methods(http.MethodPut)
+
+	h.Handle("/backup/settings", bouncer.RestrictedAccess(adminAccess(httperror.LoggerHandler(h.backupSettingsInspect)))).Methods(http.MethodGet)
+	h.Handle("/backup/status", bouncer.RestrictedAccess(adminAccess(httperror.LoggerHandler(h.backupStatus)))).Methods(http.MethodGet)
 
 	return h
 }
@@ -11,9 +11,11 @@ import (
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/http/handler/docker/containers"
+	"github.com/portainer/portainer/api/http/handler/docker/images"
 	"github.com/portainer/portainer/api/http/middlewares"
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/scanner"
 )
 
 // Handler is the HTTP handler which will natively deal with to external environments(endpoints).
@@ -26,7 +28,8 @@ type Handler struct {
 }
 
 // NewHandler creates a handler to process non-proxied requests to docker APIs directly.
-func NewHandler(bouncer *security.RequestBouncer, authorizationService *authorization.Service, dataStore dataservices.DataStore, dockerClientFactory *docker.ClientFactory) *Handler {
+// The image vulnerability routes are served under /{id}/docker/images, they are reached through /api/endpoints.
+func NewHandler(bouncer *security.RequestBouncer, authorizationService *authorization.Service, dataStore dataservices.DataStore, dockerClientFactory *docker.ClientFactory, imageScanService *scanner.Service) *Handler {
 	h := &Handler{
 		Router:               mux.NewRouter(),
 		requestBouncer:       bouncer,
@@ -42,6 +45,9 @@ func NewHandler(bouncer *security.RequestBouncer, authorizationService *authoriz
 
 	containersHandler := containers.NewHandler("/{id}/containers", bouncer, dockerClientFactory)
 	endpointRouter.PathPrefix("/containers").Handler(containersHandler)
+
+	imagesHandler := images.NewHandler("/{id}/docker/images", bouncer, imageScanService)
+	endpointRouter.PathPrefix("/docker/images").Handler(imagesHandler)
 	return h
 }
 
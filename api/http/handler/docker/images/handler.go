@@ -0,0 +1,32 @@
+package images
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/scanner"
+)
+
+type Handler struct {
+	*mux.Router
+	requestBouncer   *security.RequestBouncer
+	imageScanService *scanner.Service
+}
+
+// NewHandler creates a handler to process the vulnerability scans of the images of an environment.
+func NewHandler(routePrefix string, bouncer *security.RequestBouncer, imageScanService *scanner.Service) *Handler {
+	h := &Handler{
+		Router:           mux.NewRouter(),
+		requestBouncer:   bouncer,
+		imageScanService: imageScanService,
+	}
+
+	router := h.PathPrefix(routePrefix).Subrouter()
+
+	router.Handle("/vulnerabilities", bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.imageVulnerabilitiesList))).Methods(http.MethodGet)
+	router.Handle("/vulnerabilities/scan", bouncer.AdminAccess(httperror.LoggerHandler(h.imageVulnerabilitiesScan))).Methods(http.MethodPost)
+
+	return h
+}
@@ -0,0 +1,68 @@
+package images
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/middlewares"
+	"github.com/portainer/portainer/api/scanner"
+)
+
+// @id dockerImageVulnerabilitiesList
+// @summary List the vulnerabilities of the images of an environment
+// @description List the images of a Docker environment along with the result of their last vulnerability scan.
+// @description The scan of the images that were never scanned is null.
+// @description **Access policy**: authenticated
+// @tags docker
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param id path int true "Environment identifier"
+// @param severity query string false "Only return the images having vulnerabilities with this severity or higher" Enums(UNKNOWN, LOW, MEDIUM, HIGH, CRITICAL)
+// @success 200 {array} scanner.EndpointImage "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Environment not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/docker/images/vulnerabilities [get]
+func (handler *Handler) imageVulnerabilitiesList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	severity, _ := request.RetrieveQueryParameter(r, "severity", true)
+	if severity != "" && !scanner.ValidSeverity(portainer.VulnerabilitySeverity(severity)) {
+		return httperror.BadRequest("Invalid severity query parameter", nil)
+	}
+
+	endpoint, err := middlewares.FetchEndpoint(r)
+	if err != nil {
+		return httperror.NotFound("Unable to find an environment on request context", err)
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return httperror.Forbidden("Permission denied to access environment", err)
+	}
+
+	images, err := handler.imageScanService.EndpointImages(r.Context(), endpoint)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the images of the environment", err)
+	}
+
+	if severity != "" {
+		images = filterImagesBySeverity(images, portainer.VulnerabilitySeverity(severity))
+	}
+
+	return response.JSON(w, images)
+}
+
+func filterImagesBySeverity(images []scanner.EndpointImage, severity portainer.VulnerabilitySeverity) []scanner.EndpointImage {
+	filtered := make([]scanner.EndpointImage, 0)
+	for _, image := range images {
+		if image.Scan != nil && scanner.CountAtOrAbove(image.Scan, severity) > 0 {
+			filtered = append(filtered, image)
+		}
+	}
+
+	return filtered
+}
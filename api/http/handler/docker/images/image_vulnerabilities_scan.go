@@ -0,0 +1,72 @@
+package images
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	"github.com/portainer/portainer/api/http/middlewares"
+)
+
+type imageVulnerabilitiesScanPayload struct {
+	// Identifiers of the images to scan, every image of the environment is scanned when empty
+	ImageIDs []string `json:"ImageIds" example:"sha256:89a8ad3a2e8cd6e23e3b8a7ef0b4c0c5c8c4d3a0f5c7b1d1b1e9c3e0f5d6e7a8"`
+	// Scan the images again even if they were already scanned
+	Force bool `json:"Force" example:"false"`
+}
+
+func (payload *imageVulnerabilitiesScanPayload) Validate(r *http.Request) error {
+	return nil
+}
+
+// @id dockerImageVulnerabilitiesScan
+// @summary Scan the images of an environment
+// @description Scan the images of a Docker environment for known vulnerabilities and store the results.
+// @description The images that were already scanned are only scanned again when Force is true.
+// @description The images that cannot be scanned are returned with the error of the scan, the other images are still scanned.
+// @description **Access policy**: administrator
+// @tags docker
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Environment identifier"
+// @param body body imageVulnerabilitiesScanPayload false "Images to scan"
+// @success 200 {array} scanner.EndpointImage "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Environment not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/docker/images/vulnerabilities/scan [post]
+func (handler *Handler) imageVulnerabilitiesScan(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload imageVulnerabilitiesScanPayload
+	if r.ContentLength > 0 {
+		err := request.DecodeAndValidateJSONPayload(r, &payload)
+		if err != nil {
+			return httperror.BadRequest("Invalid request payload", err)
+		}
+	}
+
+	endpoint, err := middlewares.FetchEndpoint(r)
+	if err != nil {
+		return httperror.NotFound("Unable to find an environment on request context", err)
+	}
+
+	images, err := handler.imageScanService.ScanEndpointImages(r.Context(), endpoint, payload.ImageIDs, scanMaxAge(payload.Force))
+	if err != nil {
+		return httperror.InternalServerError("Unable to scan the images of the environment", err)
+	}
+
+	return response.JSON(w, images)
+}
+
+// scanMaxAge returns the maximum age of the scans that are kept, 0 scans every image again
+func scanMaxAge(force bool) time.Duration {
+	if force {
+		return 0
+	}
+
+	return math.MaxInt64
+}
@@ -25,6 +25,8 @@ type edgeJobBasePayload struct {
 	Recurring      bool
 	Endpoints      []portainer.EndpointID
 	EdgeGroups     []portainer.EdgeGroupID
+	// Number of runs whose logs are retained, defaults to 10
+	LogsRetention int
 }
 
 func (handler *Handler) edgeJobCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
@@ -69,6 +71,10 @@ func (payload *edgeJobCreateFromFileContentPayload) Validate(r *http.Request) er
 		return errors.New("invalid script file content")
 	}
 
+	if payload.LogsRetention < 0 {
+		return errors.New("invalid logs retention")
+	}
+
 	return nil
 }
 
@@ -166,6 +172,12 @@ func (payload *edgeJobCreateFromFilePayload) Validate(r *http.Request) error {
 		return errors.New("no environments or groups have been provided")
 	}
 
+	logsRetention, err := request.RetrieveNumericMultiPartFormValue(r, "LogsRetention", true)
+	if err != nil || logsRetention < 0 {
+		return errors.New("invalid logs retention")
+	}
+	payload.LogsRetention = logsRetention
+
 	file, _, err := request.RetrieveMultiPartFormFile(r, "file")
 	if err != nil {
 		return errors.New("invalid script file. Ensure that the file is uploaded correctly")
@@ -189,6 +201,7 @@ func (payload *edgeJobCreateFromFilePayload) Validate(r *http.Request) error {
 // @param EdgeGroups formData string true "JSON stringified array of Edge Groups ids"
 // @param Endpoints formData string true "JSON stringified array of Environment ids"
 // @param Recurring formData bool false "If recurring"
+// @param LogsRetention formData int false "Number of runs whose logs are retained, defaults to 10"
 // @success 200 {object} portainer.EdgeGroup
 // @failure 503 "Edge compute features are disabled"
 // @failure 500
@@ -225,6 +238,7 @@ func (handler *Handler) createEdgeJobObjectFromPayload(tx dataservices.DataStore
 		EdgeGroups:          payload.EdgeGroups,
 		Version:             1,
 		GroupLogsCollection: map[portainer.EndpointID]portainer.EdgeJobEndpointMeta{},
+		LogsRetention:       payload.LogsRetention,
 	}
 }
 
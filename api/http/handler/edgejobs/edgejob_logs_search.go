@@ -0,0 +1,63 @@
+package edgejobs
+
+import (
+	"errors"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	edgejobservice "github.com/portainer/portainer/api/internal/edge/edgejobs"
+)
+
+// @id EdgeJobLogsSearch
+// @summary Search the logs of an EdgeJob
+// @description Search the retained logs of the runs of an EdgeJob for the lines containing a text, ignoring the case.
+// @description The runs are searched from the most recent one and at most 1000 lines are returned.
+// @description **Access policy**: administrator
+// @tags edge_jobs
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param id path int true "EdgeJob Id"
+// @param query query string true "Text to search for"
+// @param runId query int false "Only search the logs of this run"
+// @success 200 {array} edgejobs.LogMatch
+// @failure 400 "Invalid request"
+// @failure 404 "EdgeJob or run not found"
+// @failure 500 "Server error"
+// @failure 503 "Edge compute features are disabled"
+// @router /edge_jobs/{id}/logs/search [get]
+func (handler *Handler) edgeJobLogsSearch(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	edgeJobID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid Edge job identifier route variable", err)
+	}
+
+	query, err := request.RetrieveQueryParameter(r, "query", false)
+	if err != nil {
+		return httperror.BadRequest("Invalid query parameter: query", err)
+	}
+
+	runID, err := request.RetrieveNumericQueryParameter(r, "runId", true)
+	if err != nil {
+		return httperror.BadRequest("Invalid query parameter: runId", err)
+	}
+
+	edgeJob, err := handler.DataStore.EdgeJob().EdgeJob(portainer.EdgeJobID(edgeJobID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find an Edge job with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find an Edge job with the specified identifier inside the database", err)
+	}
+
+	matches, err := edgejobservice.SearchLogs(handler.FileService, edgeJob, query, int64(runID))
+	if errors.Is(err, edgejobservice.ErrRunNotFound) {
+		return httperror.NotFound("Unable to find the logs of the specified run", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to search the logs of the Edge job", err)
+	}
+
+	return response.JSON(w, matches)
+}
@@ -0,0 +1,63 @@
+package edgejobs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	portainer "github.com/portainer/portainer/api"
+	edgejobservice "github.com/portainer/portainer/api/internal/edge/edgejobs"
+)
+
+// @id EdgeJobRunLogsDownload
+// @summary Download the logs of a run of an EdgeJob
+// @description Download a tar.gz archive holding the logs collected from each environment for a run of an EdgeJob,
+// @description along with the summary of the run.
+// @description **Access policy**: administrator
+// @tags edge_jobs
+// @security ApiKeyAuth
+// @security jwt
+// @produce octet-stream
+// @param id path int true "EdgeJob Id"
+// @param runID path int true "Run Id"
+// @success 200 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "EdgeJob or run not found"
+// @failure 500 "Server error"
+// @failure 503 "Edge compute features are disabled"
+// @router /edge_jobs/{id}/runs/{runID}/logs [get]
+func (handler *Handler) edgeJobRunLogsDownload(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	edgeJobID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid Edge job identifier route variable", err)
+	}
+
+	runID, err := request.RetrieveNumericRouteVariableValue(r, "runID")
+	if err != nil {
+		return httperror.BadRequest("Invalid run identifier route variable", err)
+	}
+
+	edgeJob, err := handler.DataStore.EdgeJob().EdgeJob(portainer.EdgeJobID(edgeJobID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find an Edge job with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find an Edge job with the specified identifier inside the database", err)
+	}
+
+	archivePath, err := edgejobservice.ArchiveRunLogs(handler.FileService, edgeJob, int64(runID))
+	if errors.Is(err, edgejobservice.ErrRunNotFound) {
+		return httperror.NotFound("Unable to find the logs of the specified run", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to archive the logs of the run", err)
+	}
+	defer os.RemoveAll(filepath.Dir(archivePath))
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=edge-job-%d_run-%d_logs.tar.gz", edgeJob.ID, runID))
+	http.ServeFile(w, r, archivePath)
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package edgejobs
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	edgejobservice "github.com/portainer/portainer/api/internal/edge/edgejobs"
+)
+
+// @id EdgeJobRunsList
+// @summary Fetch the results of the runs of an EdgeJob
+// @description List the runs of an EdgeJob whose logs are retained, from the most recent one,
+// @description with the number of environments which succeeded or failed.
+// @description **Access policy**: administrator
+// @tags edge_jobs
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param id path int true "EdgeJob Id"
+// @success 200 {array} edgejobs.RunSummary
+// @failure 400 "Invalid request"
+// @failure 404 "EdgeJob not found"
+// @failure 500 "Server error"
+// @failure 503 "Edge compute features are disabled"
+// @router /edge_jobs/{id}/runs [get]
+func (handler *Handler) edgeJobRunsList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	edgeJobID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid Edge job identifier route variable", err)
+	}
+
+	edgeJob, err := handler.DataStore.EdgeJob().EdgeJob(portainer.EdgeJobID(edgeJobID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find an Edge job with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find an Edge job with the specified identifier inside the database", err)
+	}
+
+	summaries := make([]edgejobservice.RunSummary, 0, len(edgeJob.Runs))
+	for i := len(edgeJob.Runs) - 1; i >= 0; i-- {
+		summaries = append(summaries, edgejobservice.SummarizeRun(edgeJob.Runs[i]))
+	}
+
+	return response.JSON(w, summaries)
+}
@@ -17,6 +17,8 @@ type taskContainer struct {
 	ID         string                      `json:"Id"`
 	EndpointID portainer.EndpointID        `json:"EndpointId"`
 	LogsStatus portainer.EdgeJobLogsStatus `json:"LogsStatus"`
+	// Exit code of the last run collected from the environment
+	ExitCode *int `json:"ExitCode"`
 }
 
 // @id EdgeJobTasksList
@@ -79,6 +81,7 @@ func listEdgeJobTasks(tx dataservices.DataStoreTx, edgeJobID portainer.EdgeJobID
 			ID:         fmt.Sprintf("edgejob_task_%d_%d", edgeJob.ID, endpointID),
 			EndpointID: endpointID,
 			LogsStatus: meta.LogsStatus,
+			ExitCode:   meta.ExitCode,
 		})
 	}
 
@@ -10,6 +10,7 @@ import (
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/internal/edge"
+	edgejobservice "github.com/portainer/portainer/api/internal/edge/edgejobs"
 	"github.com/portainer/portainer/api/internal/endpointutils"
 	"github.com/portainer/portainer/api/internal/maps"
 	"github.com/portainer/portainer/api/internal/slices"
@@ -25,6 +26,8 @@ type edgeJobUpdatePayload struct {
 	Endpoints      []portainer.EndpointID
 	EdgeGroups     []portainer.EdgeGroupID
 	FileContent    *string
+	// Number of runs whose logs are retained, the logs of the oldest runs are removed when lowered
+	LogsRetention *int
 }
 
 func (payload *edgeJobUpdatePayload) Validate(r *http.Request) error {
@@ -32,6 +35,10 @@ func (payload *edgeJobUpdatePayload) Validate(r *http.Request) error {
 		return errors.New("invalid Edge job name format. Allowed characters are: [a-zA-Z0-9_.-]")
 	}
 
+	if payload.LogsRetention != nil && *payload.LogsRetention < 0 {
+		return errors.New("invalid logs retention")
+	}
+
 	return nil
 }
 
@@ -225,6 +232,11 @@ func (handler *Handler) updateEdgeSchedule(tx dataservices.DataStoreTx, edgeJob
 		edgeJob.Version++
 	}
 
+	if payload.LogsRetention != nil {
+		edgeJob.LogsRetention = *payload.LogsRetention
+		edgejobservice.PruneRuns(handler.FileService, edgeJob)
+	}
+
 	maps.Copy(endpointsFromGroupsToAddMap, edgeJob.Endpoints)
 
 	for endpointID := range endpointsFromGroupsToAddMap {
@@ -12,7 +12,10 @@ import (
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/filesystem"
 	gittypes "github.com/portainer/portainer/api/git/types"
+	"github.com/portainer/portainer/api/git/update"
 	"github.com/portainer/portainer/api/http/security"
+	edgestackservice "github.com/portainer/portainer/api/internal/edge/edgestacks"
+	"github.com/portainer/portainer/api/stacks/stackutils"
 )
 
 type InvalidPayloadError struct {
@@ -41,11 +44,25 @@ func (handler *Handler) edgeStackCreate(w http.ResponseWriter, r *http.Request)
 		switch {
 		case errors.As(err, &payloadError):
 			return httperror.BadRequest("Invalid payload", err)
+		case errors.Is(err, stackutils.ErrWebhookIDAlreadyExists):
+			return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: "Unable to create Edge stack", Err: err}
 		default:
 			return httperror.InternalServerError("Unable to create Edge stack", err)
 		}
 	}
 
+	if !dryrun {
+		// retain the files of the first version to be able to roll back to it
+		handler.recordFileVersion(edgeStack)
+
+		err = handler.DataStore.EdgeStack().UpdateEdgeStackFunc(edgeStack.ID, func(stack *portainer.EdgeStack) {
+			stack.FileVersions = edgeStack.FileVersions
+		})
+		if err != nil {
+			return httperror.InternalServerError("Unable to persist the edge stack changes inside the database", err)
+		}
+	}
+
 	return response.JSON(w, edgeStack)
 }
 
@@ -79,6 +96,10 @@ type swarmStackFromFileContentPayload struct {
 	Registries []portainer.RegistryID
 	// Uses the manifest's namespaces instead of the default one
 	UseManifestNamespaces bool
+	// Environment variables sent to the environments along with the stack file
+	Env []portainer.Pair
+	// Render the stack file and the environment variables as templates for each environment, e.g. {{ .Endpoint.Name }}
+	UseTemplating bool `example:"false"`
 }
 
 func (payload *swarmStackFromFileContentPayload) Validate(r *http.Request) error {
@@ -91,6 +112,14 @@ func (payload *swarmStackFromFileContentPayload) Validate(r *http.Request) error
 	if len(payload.EdgeGroups) == 0 {
 		return &InvalidPayloadError{msg: "Edge Groups are mandatory for an Edge stack"}
 	}
+	if err := edgestackservice.ValidateEnv(payload.Env); err != nil {
+		return &InvalidPayloadError{msg: err.Error()}
+	}
+	if payload.UseTemplating {
+		if err := edgestackservice.ValidateTemplate([]byte(payload.StackFileContent)); err != nil {
+			return &InvalidPayloadError{msg: err.Error()}
+		}
+	}
 	return nil
 }
 
@@ -119,6 +148,9 @@ func (handler *Handler) createSwarmStackFromFileContent(r *http.Request, dryrun
 		return nil, errors.Wrap(err, "failed to create Edge stack object")
 	}
 
+	stack.Env = payload.Env
+	stack.UseTemplating = payload.UseTemplating
+
 	if dryrun {
 		return stack, nil
 	}
@@ -201,6 +233,12 @@ type swarmStackFromGitRepositoryPayload struct {
 	UseManifestNamespaces bool
 	// TLSSkipVerify skips SSL verification when cloning the Git repository
 	TLSSkipVerify bool `example:"false"`
+	// Optional GitOps update configuration
+	AutoUpdate *portainer.AutoUpdateSettings
+	// Environment variables sent to the environments along with the stack file
+	Env []portainer.Pair
+	// Render the stack file and the environment variables as templates for each environment, e.g. {{ .Endpoint.Name }}
+	UseTemplating bool `example:"false"`
 }
 
 func (payload *swarmStackFromGitRepositoryPayload) Validate(r *http.Request) error {
@@ -224,6 +262,12 @@ func (payload *swarmStackFromGitRepositoryPayload) Validate(r *http.Request) err
 	if len(payload.EdgeGroups) == 0 {
 		return &InvalidPayloadError{msg: "Edge Groups are mandatory for an Edge stack"}
 	}
+	if err := update.ValidateAutoUpdateSettings(payload.AutoUpdate); err != nil {
+		return &InvalidPayloadError{msg: err.Error()}
+	}
+	if err := edgestackservice.ValidateEnv(payload.Env); err != nil {
+		return &InvalidPayloadError{msg: err.Error()}
+	}
 	return nil
 }
 
@@ -238,6 +282,7 @@ func (payload *swarmStackFromGitRepositoryPayload) Validate(r *http.Request) err
 // @param body body swarmStackFromGitRepositoryPayload true "stack config"
 // @param dryrun query string false "if true, will not create an edge stack, but just will check the settings and return a non-persisted edge stack object"
 // @success 200 {object} portainer.EdgeStack
+// @failure 409 "Webhook ID already exists"
 // @failure 500
 // @failure 503 "Edge compute features are disabled"
 // @router /edge_stacks/create/repository [post]
@@ -253,6 +298,9 @@ func (handler *Handler) createSwarmStackFromGitRepository(r *http.Request, dryru
 		return nil, errors.Wrap(err, "failed to create edge stack object")
 	}
 
+	stack.Env = payload.Env
+	stack.UseTemplating = payload.UseTemplating
+
 	if dryrun {
 		return stack, nil
 	}
@@ -271,9 +319,50 @@ func (handler *Handler) createSwarmStackFromGitRepository(r *http.Request, dryru
 		}
 	}
 
-	return handler.edgeStacksService.PersistEdgeStack(stack, func(stackFolder string, relatedEndpointIds []portainer.EndpointID) (composePath string, manifestPath string, projectPath string, err error) {
+	if payload.AutoUpdate != nil && payload.AutoUpdate.Webhook != "" {
+		webhookStack, err := handler.edgeStackByWebhookID(payload.AutoUpdate.Webhook)
+		if err != nil {
+			return nil, errors.WithMessage(err, "unable to check for webhook ID collision")
+		}
+
+		if webhookStack != nil {
+			return nil, stackutils.ErrWebhookIDAlreadyExists
+		}
+	}
+
+	commitHash, err := handler.GitService.LatestCommitID(repoConfig.URL, repoConfig.ReferenceName, payload.RepositoryUsername, payload.RepositoryPassword, repoConfig.TLSSkipVerify)
+	if err != nil {
+		return nil, errors.WithMessage(err, "unable to fetch the latest commit of the git repository")
+	}
+
+	repoConfig.ConfigHash = commitHash
+	stack.GitConfig = &repoConfig
+	stack.AutoUpdate = payload.AutoUpdate
+
+	stack, err = handler.edgeStacksService.PersistEdgeStack(stack, func(stackFolder string, relatedEndpointIds []portainer.EndpointID) (composePath string, manifestPath string, projectPath string, err error) {
 		return handler.storeManifestFromGitRepository(stackFolder, relatedEndpointIds, payload.DeploymentType, userID, repoConfig)
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	if stack.AutoUpdate != nil && stack.AutoUpdate.Interval != "" {
+		jobID, err := handler.AutoUpdateService.StartAutoUpdate(stack.ID, stack.AutoUpdate.Interval)
+		if err != nil {
+			return nil, err
+		}
+
+		stack.AutoUpdate.JobID = jobID
+
+		err = handler.DataStore.EdgeStack().UpdateEdgeStack(stack.ID, stack)
+		if err != nil {
+			return nil, errors.WithMessage(err, "unable to persist the edge stack auto update job")
+		}
+	}
+
+	hideGitCredentials(stack)
+
+	return stack, nil
 }
 
 type swarmStackFromFileUploadPayload struct {
@@ -289,6 +378,10 @@ type swarmStackFromFileUploadPayload struct {
 	Registries     []portainer.RegistryID
 	// Uses the manifest's namespaces instead of the default one
 	UseManifestNamespaces bool
+	// Environment variables sent to the environments along with the stack file
+	Env []portainer.Pair
+	// Render the stack file and the environment variables as templates for each environment, e.g. {{ .Endpoint.Name }}
+	UseTemplating bool `example:"false"`
 }
 
 func (payload *swarmStackFromFileUploadPayload) Validate(r *http.Request) error {
@@ -327,6 +420,24 @@ func (payload *swarmStackFromFileUploadPayload) Validate(r *http.Request) error
 	useManifestNamespaces, _ := request.RetrieveBooleanMultiPartFormValue(r, "UseManifestNamespaces", true)
 	payload.UseManifestNamespaces = useManifestNamespaces
 
+	var env []portainer.Pair
+	err = request.RetrieveMultiPartFormJSONValue(r, "Env", &env, true)
+	if err != nil {
+		return &InvalidPayloadError{msg: "Invalid environment variables"}
+	}
+	if err := edgestackservice.ValidateEnv(env); err != nil {
+		return &InvalidPayloadError{msg: err.Error()}
+	}
+	payload.Env = env
+
+	useTemplating, _ := request.RetrieveBooleanMultiPartFormValue(r, "UseTemplating", true)
+	if useTemplating {
+		if err := edgestackservice.ValidateTemplate(payload.StackFileContent); err != nil {
+			return &InvalidPayloadError{msg: err.Error()}
+		}
+	}
+	payload.UseTemplating = useTemplating
+
 	return nil
 }
 
@@ -346,6 +457,8 @@ func (payload *swarmStackFromFileUploadPayload) Validate(r *http.Request) error
 // @param UseManifestNamespaces formData bool false "Uses the manifest's namespaces instead of the default one, relevant only for kube environments"
 // @param PrePullImage formData bool false "Pre Pull image"
 // @param RetryDeploy formData bool false "Retry deploy"
+// @param Env formData string false "JSON stringified array of environment variables, e.g. [{\"name\": \"SITE\", \"value\": \"{{ .Endpoint.Name }}\"}]"
+// @param UseTemplating formData bool false "Render the stack file and the environment variables as templates for each environment"
 // @param dryrun query string false "if true, will not create an edge stack, but just will check the settings and return a non-persisted edge stack object"
 // @success 200 {object} portainer.EdgeStack
 // @failure 500
@@ -363,6 +476,9 @@ func (handler *Handler) createSwarmStackFromFileUpload(r *http.Request, dryrun b
 		return nil, errors.Wrap(err, "failed to create edge stack object")
 	}
 
+	stack.Env = payload.Env
+	stack.UseTemplating = payload.UseTemplating
+
 	if dryrun {
 		return stack, nil
 	}
@@ -34,6 +34,10 @@ func (handler *Handler) edgeStackDelete(w http.ResponseWriter, r *http.Request)
 		return httperror.InternalServerError("Unable to find an edge stack with the specified identifier inside the database", err)
 	}
 
+	if edgeStack.AutoUpdate != nil {
+		handler.AutoUpdateService.StopAutoUpdate(edgeStack.ID, edgeStack.AutoUpdate.JobID)
+	}
+
 	err = handler.edgeStacksService.DeleteEdgeStack(edgeStack.ID, edgeStack.EdgeGroups)
 	if err != nil {
 		return httperror.InternalServerError("Unable to delete edge stack", err)
@@ -33,5 +33,7 @@ func (handler *Handler) edgeStackInspect(w http.ResponseWriter, r *http.Request)
 		return handler.handlerDBErr(err, "Unable to find an edge stack with the specified identifier inside the database")
 	}
 
+	hideGitCredentials(edgeStack)
+
 	return response.JSON(w, edgeStack)
 }
@@ -25,5 +25,9 @@ func (handler *Handler) edgeStackList(w http.ResponseWriter, r *http.Request) *h
 		return httperror.InternalServerError("Unable to retrieve edge stacks from the database", err)
 	}
 
+	for i := range edgeStacks {
+		hideGitCredentials(&edgeStacks[i])
+	}
+
 	return response.JSON(w, edgeStacks)
 }
@@ -0,0 +1,121 @@
+package edgestacks
+
+import (
+	"errors"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	edgestackservice "github.com/portainer/portainer/api/internal/edge/edgestacks"
+)
+
+type edgeStackRollbackPayload struct {
+	// Version to roll back to, defaults to the most recent retained version preceding the current one
+	Version int `example:"3"`
+	// Only roll back the environments reporting an error, the other environments keep the current version
+	FailedOnly bool `example:"false"`
+}
+
+func (payload *edgeStackRollbackPayload) Validate(r *http.Request) error {
+	if payload.Version < 0 {
+		return errors.New("Invalid version")
+	}
+
+	return nil
+}
+
+// @id EdgeStackRollback
+// @summary Rollback an EdgeStack to a previous version
+// @description Roll an EdgeStack back to a version whose files are retained.
+// @description The files of the version are released to all the environments as a new version,
+// @description or only to the environments reporting an error when FailedOnly is set.
+// @description **Access policy**: administrator
+// @tags edge_stacks
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "EdgeStack Id"
+// @param body body edgeStackRollbackPayload true "Rollback details"
+// @success 200 {object} portainer.EdgeStack
+// @failure 400 "Invalid request"
+// @failure 404 "EdgeStack or version not found"
+// @failure 409 "No environment reported an error"
+// @failure 500 "Server error"
+// @failure 503 "Edge compute features are disabled"
+// @router /edge_stacks/{id}/rollback [post]
+func (handler *Handler) edgeStackRollback(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	edgeStackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid edge stack identifier route variable", err)
+	}
+
+	var payload edgeStackRollbackPayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	edgeStack, err := handler.DataStore.EdgeStack().EdgeStack(portainer.EdgeStackID(edgeStackID))
+	if err != nil {
+		return handler.handlerDBErr(err, "Unable to find an edge stack with the specified identifier inside the database")
+	}
+
+	version := payload.Version
+	if version == 0 {
+		previousVersion, ok := edgestackservice.PreviousFileVersion(edgeStack, edgeStack.Version)
+		if !ok {
+			return httperror.NotFound("Unable to find a previous version of the edge stack", edgestackservice.ErrFileVersionNotFound)
+		}
+
+		version = previousVersion
+	}
+
+	if payload.FailedOnly {
+		return handler.rollbackFailedEndpoints(w, edgeStack.ID, version)
+	}
+
+	err = edgestackservice.Rollback(handler.FileService, edgeStack, version)
+	if errors.Is(err, edgestackservice.ErrFileVersionNotFound) {
+		return httperror.NotFound("Unable to find the specified version of the edge stack", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to restore the files of the edge stack version", err)
+	}
+
+	err = handler.DataStore.EdgeStack().UpdateEdgeStack(edgeStack.ID, edgeStack)
+	if err != nil {
+		return httperror.InternalServerError("Unable to persist the edge stack changes inside the database", err)
+	}
+
+	hideGitCredentials(edgeStack)
+
+	return response.JSON(w, edgeStack)
+}
+
+func (handler *Handler) rollbackFailedEndpoints(w http.ResponseWriter, edgeStackID portainer.EdgeStackID, version int) *httperror.HandlerError {
+	var stack portainer.EdgeStack
+	var rollbackErr error
+
+	err := handler.DataStore.EdgeStack().UpdateEdgeStackFunc(edgeStackID, func(edgeStack *portainer.EdgeStack) {
+		_, rollbackErr = edgestackservice.RollbackFailedEndpoints(edgeStack, version)
+		stack = *edgeStack
+	})
+	if err != nil {
+		return handler.handlerDBErr(err, "Unable to persist the edge stack changes inside the database")
+	}
+
+	switch {
+	case errors.Is(rollbackErr, edgestackservice.ErrFileVersionNotFound):
+		return httperror.NotFound("Unable to find the specified version of the edge stack", rollbackErr)
+	case errors.Is(rollbackErr, edgestackservice.ErrNoFailedEndpoints):
+		return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: rollbackErr.Error(), Err: rollbackErr}
+	case rollbackErr != nil:
+		return httperror.InternalServerError("Unable to roll back the failed environments", rollbackErr)
+	}
+
+	hideGitCredentials(&stack)
+
+	return response.JSON(w, stack)
+}
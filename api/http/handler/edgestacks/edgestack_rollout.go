@@ -0,0 +1,80 @@
+package edgestacks
+
+import (
+	"errors"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	edgestackservice "github.com/portainer/portainer/api/internal/edge/edgestacks"
+)
+
+// @id EdgeStackRolloutPromote
+// @summary Release the next batch of an EdgeStack rollout
+// @description Release the next batch of environments of the staged rollout of an EdgeStack, a halted rollout is resumed.
+// @description **Access policy**: administrator
+// @tags edge_stacks
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param id path int true "EdgeStack Id"
+// @success 200 {object} portainer.EdgeStack
+// @failure 400 "Invalid request"
+// @failure 404 "Not found"
+// @failure 409 "The EdgeStack is not being rolled out"
+// @failure 500 "Server error"
+// @failure 503 "Edge compute features are disabled"
+// @router /edge_stacks/{id}/rollout/promote [post]
+func (handler *Handler) edgeStackRolloutPromote(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return handler.updateRollout(w, r, edgestackservice.PromoteRollout)
+}
+
+// @id EdgeStackRolloutAbort
+// @summary Abort an EdgeStack rollout
+// @description Stop the staged rollout of an EdgeStack, the environments which are not released yet keep the previous version.
+// @description **Access policy**: administrator
+// @tags edge_stacks
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param id path int true "EdgeStack Id"
+// @success 200 {object} portainer.EdgeStack
+// @failure 400 "Invalid request"
+// @failure 404 "Not found"
+// @failure 409 "The EdgeStack is not being rolled out"
+// @failure 500 "Server error"
+// @failure 503 "Edge compute features are disabled"
+// @router /edge_stacks/{id}/rollout/abort [post]
+func (handler *Handler) edgeStackRolloutAbort(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return handler.updateRollout(w, r, edgestackservice.AbortRollout)
+}
+
+func (handler *Handler) updateRollout(w http.ResponseWriter, r *http.Request, updateFunc func(edgeStack *portainer.EdgeStack) error) *httperror.HandlerError {
+	edgeStackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid edge stack identifier route variable", err)
+	}
+
+	var stack portainer.EdgeStack
+	var rolloutErr error
+
+	err = handler.DataStore.EdgeStack().UpdateEdgeStackFunc(portainer.EdgeStackID(edgeStackID), func(edgeStack *portainer.EdgeStack) {
+		rolloutErr = updateFunc(edgeStack)
+		stack = *edgeStack
+	})
+	if err != nil {
+		return handler.handlerDBErr(err, "Unable to persist the edge stack changes inside the database")
+	}
+
+	if errors.Is(rolloutErr, edgestackservice.ErrNoActiveRollout) {
+		return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: rolloutErr.Error(), Err: rolloutErr}
+	} else if rolloutErr != nil {
+		return httperror.InternalServerError("Unable to update the edge stack rollout", rolloutErr)
+	}
+
+	hideGitCredentials(&stack)
+
+	return response.JSON(w, stack)
+}
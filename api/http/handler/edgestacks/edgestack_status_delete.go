@@ -51,5 +51,7 @@ func (handler *Handler) edgeStackStatusDelete(w http.ResponseWriter, r *http.Req
 		return httperror.InternalServerError("Unable to persist the stack changes inside the database", err)
 	}
 
+	hideGitCredentials(stack)
+
 	return response.JSON(w, stack)
 }
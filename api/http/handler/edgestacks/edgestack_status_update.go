@@ -2,11 +2,15 @@ package edgestacks
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/portainer/libhttp/request"
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
+	edgestackservice "github.com/portainer/portainer/api/internal/edge/edgestacks"
+	"github.com/portainer/portainer/api/notifications"
+	"github.com/rs/zerolog/log"
 
 	"github.com/asaskevich/govalidator"
 	httperror "github.com/portainer/libhttp/error"
@@ -94,11 +98,35 @@ func (handler *Handler) edgeStackStatusUpdate(w http.ResponseWriter, r *http.Req
 			EndpointID: payload.EndpointID,
 		}
 
+		if *payload.Status == portainer.EdgeStackStatusError {
+			if edgestackservice.HaltRolloutOnFailures(edgeStack) {
+				log.Warn().Int("edge_stack_id", int(edgeStack.ID)).Str("reason", edgeStack.Rollout.Message).Msg("edge stack rollout halted")
+			}
+
+			if version, ok := edgestackservice.AutoRollback(edgeStack, payload.EndpointID); ok {
+				log.Info().Int("edge_stack_id", int(edgeStack.ID)).Int("endpoint_id", int(payload.EndpointID)).Int("version", version).Msg("edge stack rolled back on the environment")
+			}
+		}
+
 		stack = *edgeStack
 	})
 	if err != nil {
 		return handler.handlerDBErr(err, "Unable to persist the stack changes inside the database")
 	}
 
+	if *payload.Status == portainer.EdgeStackStatusError {
+		handler.NotificationService.Publish(notifications.Event{
+			Type:       portainer.NotificationEventEdgeStackError,
+			Message:    fmt.Sprintf("edge stack %s failed to deploy on environment %s", stack.Name, endpoint.Name),
+			EndpointID: endpoint.ID,
+			Details: map[string]string{
+				"edgeStack": stack.Name,
+				"error":     payload.Error,
+			},
+		})
+	}
+
+	hideGitCredentials(&stack)
+
 	return response.JSON(w, stack)
 }
@@ -16,6 +16,7 @@ import (
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/datastore"
 	"github.com/portainer/portainer/api/filesystem"
+	gittypes "github.com/portainer/portainer/api/git/types"
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/edge/edgestacks"
 	"github.com/portainer/portainer/api/internal/testhelpers"
@@ -901,3 +902,59 @@ func TestDeleteStatus(t *testing.T) {
 		t.Fatalf("expected a %d response, found: %d", http.StatusOK, rec.Code)
 	}
 }
+
+func TestStatusResponsesHideGitCredentials(t *testing.T) {
+	handler, _, teardown := setupHandler(t)
+	defer teardown()
+
+	endpoint := createEndpoint(t, handler.DataStore)
+	edgeStack := createEdgeStack(t, handler.DataStore, endpoint.ID)
+
+	err := handler.DataStore.EdgeStack().UpdateEdgeStackFunc(edgeStack.ID, func(edgeStack *portainer.EdgeStack) {
+		edgeStack.GitConfig = &gittypes.RepoConfig{
+			URL:            "https://github.com/portainer/stacks",
+			Authentication: &gittypes.GitAuthentication{Username: "user", Password: "secret"},
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := portainer.EdgeStackStatusOk
+	jsonPayload, err := json.Marshal(updateStatusPayload{Status: &status, EndpointID: endpoint.ID})
+	if err != nil {
+		t.Fatal("request error:", err)
+	}
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPut, fmt.Sprintf("/edge_stacks/%d/status", edgeStack.ID), bytes.NewBuffer(jsonPayload)),
+		httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/edge_stacks/%d/status/%d", edgeStack.ID, endpoint.ID), nil),
+	} {
+		req.Header.Set(portainer.PortainerAgentEdgeIDHeader, endpoint.EdgeID)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected a %d response, found: %d", http.StatusOK, rec.Code)
+		}
+
+		data := portainer.EdgeStack{}
+		err = json.NewDecoder(rec.Body).Decode(&data)
+		if err != nil {
+			t.Fatal("error decoding response:", err)
+		}
+
+		if data.GitConfig.Authentication.Password != "" {
+			t.Fatalf("expected the git password to be hidden from the %s response", req.Method)
+		}
+	}
+
+	stored, err := handler.DataStore.EdgeStack().EdgeStack(edgeStack.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.GitConfig.Authentication.Password != "secret" {
+		t.Fatal("expected the git password to be kept in the database")
+	}
+}
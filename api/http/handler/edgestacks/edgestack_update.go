@@ -11,6 +11,7 @@ import (
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/filesystem"
 	"github.com/portainer/portainer/api/internal/edge"
+	edgestackservice "github.com/portainer/portainer/api/internal/edge/edgestacks"
 	"github.com/portainer/portainer/api/internal/endpointutils"
 	"github.com/rs/zerolog/log"
 )
@@ -22,6 +23,14 @@ type updateEdgeStackPayload struct {
 	DeploymentType   portainer.EdgeStackDeploymentType
 	// Uses the manifest's namespaces instead of the default one
 	UseManifestNamespaces bool
+	// Staged release of the new versions, kept unchanged when omitted and removed when the batch size and percentage are empty
+	RolloutStrategy *portainer.EdgeStackRolloutStrategy
+	// Roll the environments reporting a deployment error back to the previous version automatically, kept unchanged when omitted
+	AutoRollback *bool `example:"false"`
+	// Environment variables sent to the environments, kept unchanged when omitted
+	Env []portainer.Pair
+	// Render the stack file and the environment variables as templates for each environment, kept unchanged when omitted
+	UseTemplating *bool `example:"false"`
 }
 
 func (payload *updateEdgeStackPayload) Validate(r *http.Request) error {
@@ -31,6 +40,12 @@ func (payload *updateEdgeStackPayload) Validate(r *http.Request) error {
 	if len(payload.EdgeGroups) == 0 {
 		return errors.New("Edge Groups are mandatory for an Edge stack")
 	}
+	if err := edgestackservice.ValidateRolloutStrategy(payload.RolloutStrategy); err != nil {
+		return err
+	}
+	if err := edgestackservice.ValidateEnv(payload.Env); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -66,6 +81,23 @@ func (handler *Handler) edgeStackUpdate(w http.ResponseWriter, r *http.Request)
 		return httperror.BadRequest("Invalid request payload", err)
 	}
 
+	renderingChanged := templateChanged(stack, payload.Env, payload.UseTemplating)
+
+	if payload.UseTemplating != nil {
+		stack.UseTemplating = *payload.UseTemplating
+	}
+
+	if stack.UseTemplating {
+		err = edgestackservice.ValidateTemplate([]byte(payload.StackFileContent))
+		if err != nil {
+			return httperror.BadRequest("Invalid stack file template", err)
+		}
+	}
+
+	if payload.Env != nil {
+		stack.Env = payload.Env
+	}
+
 	relationConfig, err := edge.FetchEndpointRelationsConfig(handler.DataStore)
 	if err != nil {
 		return httperror.InternalServerError("Unable to retrieve environments relations config from database", err)
@@ -132,6 +164,17 @@ func (handler *Handler) edgeStackUpdate(w http.ResponseWriter, r *http.Request)
 		relatedEndpointIds = newRelated
 	}
 
+	if payload.DeploymentType == portainer.EdgeStackDeploymentKubernetes {
+		hasDockerEndpoint, err := hasDockerEndpoint(handler.DataStore.Endpoint(), relatedEndpointIds)
+		if err != nil {
+			return httperror.InternalServerError("Unable to check for existence of docker environment", err)
+		}
+
+		if hasDockerEndpoint {
+			return httperror.BadRequest("Edge stack with docker environment cannot be deployed with kubernetes config", err)
+		}
+	}
+
 	if stack.DeploymentType != payload.DeploymentType {
 		// deployment type was changed - need to delete the old file
 		err = handler.FileService.RemoveDirectory(stack.ProjectPath)
@@ -142,6 +185,16 @@ func (handler *Handler) edgeStackUpdate(w http.ResponseWriter, r *http.Request)
 		stack.EntryPoint = ""
 		stack.ManifestPath = ""
 		stack.DeploymentType = payload.DeploymentType
+
+		// the files of the previous versions were removed along with the project
+		stack.FileVersions = nil
+		stack.RolledBackEndpoints = nil
+	} else {
+		// keep the files of the deployed version before they are overwritten, the environments outside of a rollout are served them
+		err = edgestackservice.RetainFileVersion(handler.FileService, stack)
+		if err != nil {
+			return httperror.InternalServerError("Unable to retain the files of the deployed edge stack version", err)
+		}
 	}
 
 	stackFolder := strconv.Itoa(int(stack.ID))
@@ -171,27 +224,41 @@ func (handler *Handler) edgeStackUpdate(w http.ResponseWriter, r *http.Request)
 
 		stack.UseManifestNamespaces = payload.UseManifestNamespaces
 
-		hasDockerEndpoint, err := hasDockerEndpoint(handler.DataStore.Endpoint(), relatedEndpointIds)
-		if err != nil {
-			return httperror.InternalServerError("Unable to check for existence of docker environment", err)
-		}
-
-		if hasDockerEndpoint {
-			return httperror.BadRequest("Edge stack with docker environment cannot be deployed with kubernetes config", err)
-		}
-
 		_, err = handler.FileService.StoreEdgeStackFileFromBytes(stackFolder, stack.ManifestPath, []byte(payload.StackFileContent))
 		if err != nil {
 			return httperror.InternalServerError("Unable to persist updated Kubernetes manifest file on disk", err)
 		}
 	}
 
-	versionUpdated := payload.Version != nil && *payload.Version != stack.Version
+	edgestackservice.SetRolloutStrategy(stack, payload.RolloutStrategy)
+
+	if payload.AutoRollback != nil {
+		stack.AutoRollback = *payload.AutoRollback
+	}
+
+	newVersion := stack.Version
+	if payload.Version != nil {
+		newVersion = *payload.Version
+	}
+
+	// the environments fetch the edge stack again when its environment variables or its templating change
+	if newVersion == stack.Version && renderingChanged {
+		newVersion++
+	}
+
+	versionUpdated := newVersion != stack.Version
 	if versionUpdated {
-		stack.Version = *payload.Version
+		previousVersion := stack.Version
+		previousVersions := edgestackservice.EndpointVersions(stack, relatedEndpointIds)
+		stack.Version = newVersion
 		stack.Status = map[portainer.EndpointID]portainer.EdgeStackStatus{}
+		stack.RolledBackEndpoints = nil
+
+		edgestackservice.StartRollout(stack, previousVersion, previousVersions, relatedEndpointIds)
 	}
 
+	handler.recordFileVersion(stack)
+
 	stack.NumDeployments = len(relatedEndpointIds)
 
 	if versionUpdated {
@@ -203,5 +270,7 @@ func (handler *Handler) edgeStackUpdate(w http.ResponseWriter, r *http.Request)
 		return httperror.InternalServerError("Unable to persist the stack changes inside the database", err)
 	}
 
+	hideGitCredentials(stack)
+
 	return response.JSON(w, stack)
 }
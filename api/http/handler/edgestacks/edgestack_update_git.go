@@ -0,0 +1,208 @@
+package edgestacks
+
+import (
+	"errors"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	gittypes "github.com/portainer/portainer/api/git/types"
+	"github.com/portainer/portainer/api/git/update"
+	edgestackservice "github.com/portainer/portainer/api/internal/edge/edgestacks"
+	"github.com/portainer/portainer/api/stacks/stackutils"
+)
+
+type edgeStackGitUpdatePayload struct {
+	// Optional GitOps update configuration, the auto update is disabled when empty
+	AutoUpdate *portainer.AutoUpdateSettings
+	// Reference name of a Git repository hosting the Stack file
+	RepositoryReferenceName string `example:"refs/heads/master"`
+	// Use basic authentication to clone the Git repository
+	RepositoryAuthentication bool `example:"true"`
+	// Username used in basic authentication. Required when RepositoryAuthentication is true.
+	RepositoryUsername string `example:"myGitUsername"`
+	// Password used in basic authentication, the saved password is kept when empty
+	RepositoryPassword string `example:"myGitPassword"`
+	// TLSSkipVerify skips SSL verification when cloning the Git repository
+	TLSSkipVerify bool `example:"false"`
+	// Staged release of the new versions, kept unchanged when omitted and removed when the batch size and percentage are empty
+	RolloutStrategy *portainer.EdgeStackRolloutStrategy
+	// Roll the environments reporting a deployment error back to the previous version automatically, kept unchanged when omitted
+	AutoRollback *bool `example:"false"`
+	// Environment variables sent to the environments, kept unchanged when omitted
+	Env []portainer.Pair
+	// Render the stack file and the environment variables as templates for each environment, kept unchanged when omitted
+	UseTemplating *bool `example:"false"`
+}
+
+func (payload *edgeStackGitUpdatePayload) Validate(r *http.Request) error {
+	if err := update.ValidateAutoUpdateSettings(payload.AutoUpdate); err != nil {
+		return err
+	}
+	if err := edgestackservice.ValidateRolloutStrategy(payload.RolloutStrategy); err != nil {
+		return err
+	}
+	if err := edgestackservice.ValidateEnv(payload.Env); err != nil {
+		return err
+	}
+	return nil
+}
+
+// @id EdgeStackUpdateGit
+// @summary Update the Git configs of an EdgeStack
+// @description Update the Git settings of an EdgeStack, e.g., RepositoryReferenceName and AutoUpdate.
+// @description The EdgeStack is redeployed when the reference is changed and the repository has new commits.
+// @description **Access policy**: administrator
+// @tags edge_stacks
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "EdgeStack Id"
+// @param body body edgeStackGitUpdatePayload true "Git configs of the EdgeStack"
+// @success 200 {object} portainer.EdgeStack
+// @failure 400 "Invalid request"
+// @failure 404 "Not found"
+// @failure 409 "Webhook ID already exists"
+// @failure 500 "Server error"
+// @failure 503 "Edge compute features are disabled"
+// @router /edge_stacks/{id}/git [put]
+func (handler *Handler) edgeStackUpdateGit(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	edgeStackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid edge stack identifier route variable", err)
+	}
+
+	var payload edgeStackGitUpdatePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	edgeStack, err := handler.DataStore.EdgeStack().EdgeStack(portainer.EdgeStackID(edgeStackID))
+	if err != nil {
+		return handler.handlerDBErr(err, "Unable to find an edge stack with the specified identifier inside the database")
+	} else if edgeStack.GitConfig == nil {
+		msg := "No Git config in the found edge stack"
+		return httperror.BadRequest(msg, errors.New(msg))
+	}
+
+	if payload.AutoUpdate != nil && payload.AutoUpdate.Webhook != "" {
+		webhookStack, err := handler.edgeStackByWebhookID(payload.AutoUpdate.Webhook)
+		if err != nil {
+			return httperror.InternalServerError("Unable to check for webhook ID collision", err)
+		}
+
+		if webhookStack != nil && webhookStack.ID != edgeStack.ID {
+			return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: "Webhook ID: " + payload.AutoUpdate.Webhook + " already exists", Err: stackutils.ErrWebhookIDAlreadyExists}
+		}
+	}
+
+	referenceChanged := payload.RepositoryReferenceName != "" && payload.RepositoryReferenceName != edgeStack.GitConfig.ReferenceName
+	if payload.RepositoryReferenceName != "" {
+		edgeStack.GitConfig.ReferenceName = payload.RepositoryReferenceName
+	}
+	edgeStack.GitConfig.TLSSkipVerify = payload.TLSSkipVerify
+
+	if payload.RepositoryAuthentication {
+		password := payload.RepositoryPassword
+
+		// When the existing edge stack is using the custom username/password and the password is not updated,
+		// the edge stack should keep using the saved username/password
+		if password == "" && edgeStack.GitConfig.Authentication != nil {
+			password = edgeStack.GitConfig.Authentication.Password
+		}
+
+		edgeStack.GitConfig.Authentication = &gittypes.GitAuthentication{
+			Username: payload.RepositoryUsername,
+			Password: password,
+		}
+	} else {
+		edgeStack.GitConfig.Authentication = nil
+	}
+
+	username, password := "", ""
+	if edgeStack.GitConfig.Authentication != nil {
+		username = edgeStack.GitConfig.Authentication.Username
+		password = edgeStack.GitConfig.Authentication.Password
+	}
+
+	_, err = handler.GitService.LatestCommitID(edgeStack.GitConfig.URL, edgeStack.GitConfig.ReferenceName, username, password, edgeStack.GitConfig.TLSSkipVerify)
+	if err != nil {
+		return httperror.InternalServerError("Unable to fetch git repository", err)
+	}
+
+	renderingChanged := templateChanged(edgeStack, payload.Env, payload.UseTemplating)
+
+	if payload.Env != nil {
+		edgeStack.Env = payload.Env
+	}
+
+	if payload.UseTemplating != nil {
+		edgeStack.UseTemplating = *payload.UseTemplating
+	}
+
+	if edgeStack.UseTemplating {
+		err = edgestackservice.ValidateTemplateFiles(handler.FileService, edgeStack)
+		if err != nil {
+			return httperror.BadRequest("Invalid stack file template", err)
+		}
+	}
+
+	//stop the autoupdate job if there is any
+	if edgeStack.AutoUpdate != nil {
+		handler.AutoUpdateService.StopAutoUpdate(edgeStack.ID, edgeStack.AutoUpdate.JobID)
+	}
+
+	edgeStack.AutoUpdate = payload.AutoUpdate
+	edgestackservice.SetRolloutStrategy(edgeStack, payload.RolloutStrategy)
+
+	if payload.AutoRollback != nil {
+		edgeStack.AutoRollback = *payload.AutoRollback
+	}
+
+	if edgeStack.AutoUpdate != nil && edgeStack.AutoUpdate.Interval != "" {
+		jobID, err := handler.AutoUpdateService.StartAutoUpdate(edgeStack.ID, edgeStack.AutoUpdate.Interval)
+		if err != nil {
+			return httperror.BadRequest("Unable to start the auto update of the edge stack", err)
+		}
+
+		edgeStack.AutoUpdate.JobID = jobID
+	}
+
+	err = handler.DataStore.EdgeStack().UpdateEdgeStack(edgeStack.ID, edgeStack)
+	if err != nil {
+		return httperror.InternalServerError("Unable to persist the edge stack changes inside the database", err)
+	}
+
+	updated := false
+	if referenceChanged {
+		updated, err = handler.AutoUpdateService.UpdateWhenChanged(edgeStack.ID)
+		if err != nil {
+			return httperror.InternalServerError("Unable to update the edge stack from the git repository", err)
+		}
+	}
+
+	// the environments fetch the edge stack again when its environment variables or its templating change
+	if renderingChanged && !updated {
+		err = handler.DataStore.EdgeStack().UpdateEdgeStackFunc(edgeStack.ID, func(edgeStack *portainer.EdgeStack) {
+			edgestackservice.BumpVersion(handler.FileService, edgeStack)
+		})
+		if err != nil {
+			return httperror.InternalServerError("Unable to persist the edge stack changes inside the database", err)
+		}
+	}
+
+	if referenceChanged || renderingChanged {
+		edgeStack, err = handler.DataStore.EdgeStack().EdgeStack(edgeStack.ID)
+		if err != nil {
+			return handler.handlerDBErr(err, "Unable to find an edge stack with the specified identifier inside the database")
+		}
+	}
+
+	hideGitCredentials(edgeStack)
+
+	return response.JSON(w, edgeStack)
+}
@@ -0,0 +1,165 @@
+package edgestacks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	gittypes "github.com/portainer/portainer/api/git/types"
+	"github.com/portainer/portainer/api/internal/edge/edgestacks"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/portainer/portainer/api/scheduler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitAutoUpdate(t *testing.T) {
+	is := assert.New(t)
+
+	handler, rawAPIKey, teardown := setupHandler(t)
+	defer teardown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler.GitService = testhelpers.NewGitService(nil, "new-commit")
+	handler.AutoUpdateService = edgestacks.NewAutoUpdateService(handler.DataStore, handler.FileService, handler.GitService, nil, scheduler.NewScheduler(ctx))
+
+	const webhookID = "05de31a2-79fa-4644-9c12-faa67e5c49f0"
+
+	endpoint := createEndpoint(t, handler.DataStore)
+	edgeStack := createEdgeStack(t, handler.DataStore, endpoint.ID)
+
+	projectPath, err := handler.FileService.StoreEdgeStackFileFromBytes(strconv.Itoa(int(edgeStack.ID)), edgeStack.EntryPoint, []byte("version: 1"))
+	require.NoError(t, err)
+
+	edgeStack.ProjectPath = projectPath
+	edgeStack.ManifestPath = ""
+	edgeStack.GitConfig = &gittypes.RepoConfig{
+		URL:            "https://github.com/portainer/edge-stack",
+		ReferenceName:  "refs/heads/main",
+		ConfigFilePath: "manifest.yml",
+		ConfigHash:     "old-commit",
+		Authentication: &gittypes.GitAuthentication{Username: "user", Password: "secret"},
+	}
+	edgeStack.AutoUpdate = &portainer.AutoUpdateSettings{Webhook: webhookID}
+	require.NoError(t, handler.DataStore.EdgeStack().UpdateEdgeStack(edgeStack.ID, &edgeStack))
+
+	t.Run("the webhook redeploys the edge stack when the repository changed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/edge_stacks/webhooks/"+webhookID, nil))
+		require.Equal(t, http.StatusNoContent, rec.Code)
+
+		updatedStack, err := handler.DataStore.EdgeStack().EdgeStack(edgeStack.ID)
+		require.NoError(t, err)
+		is.Equal(edgeStack.Version+1, updatedStack.Version)
+		is.Equal("new-commit", updatedStack.GitConfig.ConfigHash)
+		is.Empty(updatedStack.Status)
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/edge_stacks/webhooks/"+webhookID, nil))
+		require.Equal(t, http.StatusNoContent, rec.Code)
+
+		updatedStack, err = handler.DataStore.EdgeStack().EdgeStack(edgeStack.ID)
+		require.NoError(t, err)
+		is.Equal(edgeStack.Version+1, updatedStack.Version, "the edge stack should not be redeployed without new commits")
+	})
+
+	t.Run("an unknown webhook is rejected", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/edge_stacks/webhooks/9a7a3b1e-8d1b-4bde-8d7b-46f2b6e0c1d2", nil))
+		is.Equal(http.StatusNotFound, rec.Code)
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/edge_stacks/webhooks/invalid", nil))
+		is.Equal(http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("the git settings update schedules the polling and keeps the saved password", func(t *testing.T) {
+		payload, err := json.Marshal(edgeStackGitUpdatePayload{
+			AutoUpdate:               &portainer.AutoUpdateSettings{Interval: "5m"},
+			RepositoryReferenceName:  "refs/heads/main",
+			RepositoryAuthentication: true,
+			RepositoryUsername:       "user",
+		})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/edge_stacks/%d/git", edgeStack.ID), bytes.NewReader(payload))
+		req.Header.Add("x-api-key", rawAPIKey)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		response := portainer.EdgeStack{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+		is.Empty(response.GitConfig.Authentication.Password, "the password should not be returned")
+
+		updatedStack, err := handler.DataStore.EdgeStack().EdgeStack(edgeStack.ID)
+		require.NoError(t, err)
+		is.Equal("secret", updatedStack.GitConfig.Authentication.Password)
+		is.Equal("5m", updatedStack.AutoUpdate.Interval)
+		is.NotEmpty(updatedStack.AutoUpdate.JobID)
+	})
+
+	updateGit := func(payload edgeStackGitUpdatePayload) *httptest.ResponseRecorder {
+		body, err := json.Marshal(payload)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/edge_stacks/%d/git", edgeStack.ID), bytes.NewReader(body))
+		req.Header.Add("x-api-key", rawAPIKey)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("the git settings update releases a new version when the environment variables change", func(t *testing.T) {
+		previousStack, err := handler.DataStore.EdgeStack().EdgeStack(edgeStack.ID)
+		require.NoError(t, err)
+
+		rec := updateGit(edgeStackGitUpdatePayload{Env: []portainer.Pair{{Name: "VERSION", Value: "2"}}})
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		updatedStack, err := handler.DataStore.EdgeStack().EdgeStack(edgeStack.ID)
+		require.NoError(t, err)
+		is.Equal(previousStack.Version+1, updatedStack.Version)
+
+		rec = updateGit(edgeStackGitUpdatePayload{Env: []portainer.Pair{{Name: "VERSION", Value: "2"}}})
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		updatedStack, err = handler.DataStore.EdgeStack().EdgeStack(edgeStack.ID)
+		require.NoError(t, err)
+		is.Equal(previousStack.Version+1, updatedStack.Version, "the version should not change with the same environment variables")
+	})
+
+	t.Run("the git settings update rejects the templating of an invalid entry point", func(t *testing.T) {
+		_, err := handler.FileService.StoreEdgeStackFileFromBytes(strconv.Itoa(int(edgeStack.ID)), edgeStack.EntryPoint, []byte("image: app:{{ .Env.VERSION"))
+		require.NoError(t, err)
+
+		useTemplating := true
+		rec := updateGit(edgeStackGitUpdatePayload{UseTemplating: &useTemplating})
+		is.Equal(http.StatusBadRequest, rec.Code)
+
+		updatedStack, err := handler.DataStore.EdgeStack().EdgeStack(edgeStack.ID)
+		require.NoError(t, err)
+		is.False(updatedStack.UseTemplating)
+	})
+
+	t.Run("the git settings update rejects the edge stacks without a git config", func(t *testing.T) {
+		otherStack := &portainer.EdgeStack{ID: 20, Name: "file-stack", Version: 1}
+		require.NoError(t, handler.DataStore.EdgeStack().Create(otherStack.ID, otherStack))
+
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/edge_stacks/%d/git", otherStack.ID), bytes.NewReader([]byte(`{}`)))
+		req.Header.Add("x-api-key", rawAPIKey)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		is.Equal(http.StatusBadRequest, rec.Code)
+	})
+}
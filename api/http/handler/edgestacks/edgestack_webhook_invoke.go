@@ -0,0 +1,49 @@
+package edgestacks
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+)
+
+// @id EdgeStackWebhookInvoke
+// @summary Webhook for triggering edge stack updates from git
+// @description The edge stack is redeployed on the environments when its git repository changed.
+// @description **Access policy**: public
+// @tags edge_stacks
+// @param webhookID path string true "Webhook identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Not found"
+// @failure 500 "Server error"
+// @failure 503 "Edge compute features are disabled"
+// @router /edge_stacks/webhooks/{webhookID} [post]
+func (handler *Handler) edgeStackWebhookInvoke(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	rawWebhookID, err := request.RetrieveRouteVariableValue(r, "webhookID")
+	if err != nil {
+		return httperror.BadRequest("Invalid webhook identifier route variable", err)
+	}
+
+	webhookID, err := uuid.FromString(rawWebhookID)
+	if err != nil {
+		return httperror.BadRequest("Invalid webhook identifier route variable", err)
+	}
+
+	edgeStack, err := handler.edgeStackByWebhookID(webhookID.String())
+	if err != nil {
+		return httperror.InternalServerError("Unable to find the edge stack by webhook ID", err)
+	} else if edgeStack == nil {
+		return httperror.NotFound("Unable to find the edge stack by webhook ID", errors.New("no edge stack is associated with the webhook ID"))
+	}
+
+	_, err = handler.AutoUpdateService.UpdateWhenChanged(edgeStack.ID)
+	if err != nil {
+		return httperror.InternalServerError("Failed to update the edge stack", err)
+	}
+
+	return response.Empty(w)
+}
@@ -3,6 +3,7 @@ package edgestacks
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 	httperror "github.com/portainer/libhttp/error"
@@ -12,17 +13,22 @@ import (
 	"github.com/portainer/portainer/api/http/middlewares"
 	"github.com/portainer/portainer/api/http/security"
 	edgestackservice "github.com/portainer/portainer/api/internal/edge/edgestacks"
+	"github.com/portainer/portainer/api/notifications"
+
+	"github.com/rs/zerolog/log"
 )
 
 // Handler is the HTTP handler used to handle environment(endpoint) group operations.
 type Handler struct {
 	*mux.Router
-	requestBouncer     *security.RequestBouncer
-	DataStore          dataservices.DataStore
-	FileService        portainer.FileService
-	GitService         portainer.GitService
-	edgeStacksService  *edgestackservice.Service
-	KubernetesDeployer portainer.KubernetesDeployer
+	requestBouncer      *security.RequestBouncer
+	DataStore           dataservices.DataStore
+	FileService         portainer.FileService
+	GitService          portainer.GitService
+	edgeStacksService   *edgestackservice.Service
+	KubernetesDeployer  portainer.KubernetesDeployer
+	NotificationService *notifications.Service
+	AutoUpdateService   *edgestackservice.AutoUpdateService
 }
 
 // NewHandler creates a handler to manage environment(endpoint) group operations.
@@ -44,6 +50,16 @@ func NewHandler(bouncer *security.RequestBouncer, dataStore dataservices.DataSto
 		bouncer.AdminAccess(bouncer.EdgeComputeOperation(httperror.LoggerHandler(h.edgeStackUpdate)))).Methods(http.MethodPut)
 	h.Handle("/edge_stacks/{id}",
 		bouncer.AdminAccess(bouncer.EdgeComputeOperation(httperror.LoggerHandler(h.edgeStackDelete)))).Methods(http.MethodDelete)
+	h.Handle("/edge_stacks/{id}/git",
+		bouncer.AdminAccess(bouncer.EdgeComputeOperation(httperror.LoggerHandler(h.edgeStackUpdateGit)))).Methods(http.MethodPut)
+	h.Handle("/edge_stacks/{id}/rollout/promote",
+		bouncer.AdminAccess(bouncer.EdgeComputeOperation(httperror.LoggerHandler(h.edgeStackRolloutPromote)))).Methods(http.MethodPost)
+	h.Handle("/edge_stacks/{id}/rollout/abort",
+		bouncer.AdminAccess(bouncer.EdgeComputeOperation(httperror.LoggerHandler(h.edgeStackRolloutAbort)))).Methods(http.MethodPost)
+	h.Handle("/edge_stacks/{id}/rollback",
+		bouncer.AdminAccess(bouncer.EdgeComputeOperation(httperror.LoggerHandler(h.edgeStackRollback)))).Methods(http.MethodPost)
+	h.Handle("/edge_stacks/webhooks/{webhookID}",
+		bouncer.PublicAccess(bouncer.EdgeComputeOperation(httperror.LoggerHandler(h.edgeStackWebhookInvoke)))).Methods(http.MethodPost)
 	h.Handle("/edge_stacks/{id}/file",
 		bouncer.AdminAccess(bouncer.EdgeComputeOperation(httperror.LoggerHandler(h.edgeStackFile)))).Methods(http.MethodGet)
 	h.Handle("/edge_stacks/{id}/status",
@@ -86,6 +102,29 @@ func (handler *Handler) convertAndStoreKubeManifestIfNeeded(stackFolder string,
 	return komposeFileName, nil
 }
 
+// edgeStackByWebhookID returns the edge stack updated by a webhook, or nil when the webhook is not used by any edge stack
+func (handler *Handler) edgeStackByWebhookID(webhookID string) (*portainer.EdgeStack, error) {
+	edgeStacks, err := handler.DataStore.EdgeStack().EdgeStacks()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range edgeStacks {
+		if edgeStacks[i].AutoUpdate != nil && strings.EqualFold(edgeStacks[i].AutoUpdate.Webhook, webhookID) {
+			return &edgeStacks[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// hideGitCredentials sanitizes the git password in the http responses to minimise possible security leaks
+func hideGitCredentials(edgeStack *portainer.EdgeStack) {
+	if edgeStack.GitConfig != nil && edgeStack.GitConfig.Authentication != nil {
+		edgeStack.GitConfig.Authentication.Password = ""
+	}
+}
+
 func (handler *Handler) handlerDBErr(err error, msg string) *httperror.HandlerError {
 	httpErr := httperror.InternalServerError(msg, err)
 
@@ -95,3 +134,18 @@ func (handler *Handler) handlerDBErr(err error, msg string) *httperror.HandlerEr
 
 	return httpErr
 }
+
+// templateChanged returns true when the environment variables or the templating of an edge stack are changed by an update,
+// the version of the edge stack is then bumped so that the environments render it again
+func templateChanged(edgeStack *portainer.EdgeStack, env []portainer.Pair, useTemplating *bool) bool {
+	return (env != nil && !edgestackservice.EnvEqual(env, edgeStack.Env)) || (useTemplating != nil && *useTemplating != edgeStack.UseTemplating)
+}
+
+// recordFileVersion retains the files of the current version of an edge stack,
+// the files are retained on a best effort basis so a failure does not prevent the update of the edge stack
+func (handler *Handler) recordFileVersion(edgeStack *portainer.EdgeStack) {
+	err := edgestackservice.RecordFileVersion(handler.FileService, edgeStack)
+	if err != nil {
+		log.Warn().Err(err).Int("edge_stack_id", int(edgeStack.ID)).Msg("unable to retain the files of the edge stack version")
+	}
+}
@@ -1,26 +1,40 @@
 package endpointedge
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/libhttp/request"
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/http/middlewares"
+	"github.com/portainer/portainer/api/internal/edge/edgejobs"
 )
 
 type logsPayload struct {
 	FileContent string
+	// Exit code of the job, omitted by the agents which do not report it
+	ExitCode *int
+	// Unix timestamp of the run of the job, it selects an earlier activation of the schedule of the job than the latest one
+	RunDate int64
 }
 
 func (payload *logsPayload) Validate(r *http.Request) error {
+	if payload.RunDate < 0 {
+		return errors.New("Invalid run date")
+	}
+
 	return nil
 }
 
 // endpointEdgeJobsLogs
 // @summary Inspect an EdgeJob Log
+// @description Store the logs and the exit code of a run of an EdgeJob on an environment.
+// @description The logs are retained per run, the runs are identified by the activation of the schedule of the EdgeJob.
+// @description The run date must be between the creation of the EdgeJob and the collection time.
 // @description **Access policy**: public
 // @tags edge, endpoints
 // @accept json
@@ -65,19 +79,36 @@ func (handler *Handler) endpointEdgeJobsLogs(w http.ResponseWriter, r *http.Requ
 		return httperror.InternalServerError("Unable to save task log to the filesystem", err)
 	}
 
-	meta := portainer.EdgeJobEndpointMeta{CollectLogs: false, LogsStatus: portainer.EdgeJobLogsStatusCollected}
-	if _, ok := edgeJob.GroupLogsCollection[endpoint.ID]; ok {
-		edgeJob.GroupLogsCollection[endpoint.ID] = meta
-	} else {
-		edgeJob.Endpoints[endpoint.ID] = meta
+	runID, err := edgejobs.RunID(edgeJob, payload.RunDate, time.Now())
+	if errors.Is(err, edgejobs.ErrInvalidRunDate) {
+		return httperror.BadRequest("Invalid run date", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to identify the run of the edge job", err)
 	}
 
-	err = handler.DataStore.EdgeJob().UpdateEdgeJob(edgeJob.ID, edgeJob)
+	var recordErr error
+	err = handler.DataStore.EdgeJob().UpdateEdgeJobFunc(edgeJob.ID, func(j *portainer.EdgeJob) {
+		recordErr = edgejobs.RecordRunResult(handler.FileService, j, endpoint.ID, runID, payload.ExitCode, []byte(payload.FileContent))
+		if recordErr != nil {
+			return
+		}
+
+		meta := portainer.EdgeJobEndpointMeta{CollectLogs: false, LogsStatus: portainer.EdgeJobLogsStatusCollected, ExitCode: payload.ExitCode}
+		if _, ok := j.GroupLogsCollection[endpoint.ID]; ok {
+			j.GroupLogsCollection[endpoint.ID] = meta
+		} else {
+			j.Endpoints[endpoint.ID] = meta
+		}
+
+		edgeJob = j
+	})
 
 	handler.ReverseTunnelService.AddEdgeJob(endpoint, edgeJob)
 
 	if err != nil {
 		return httperror.InternalServerError("Unable to persist edge job changes to the database", err)
+	} else if recordErr != nil {
+		return httperror.InternalServerError("Unable to save the run logs to the filesystem", recordErr)
 	}
 
 	return response.JSON(w, nil)
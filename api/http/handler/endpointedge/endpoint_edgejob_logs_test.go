@@ -0,0 +1,97 @@
+package endpointedge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEdgeJobLogsRuns(t *testing.T) {
+	is := assert.New(t)
+
+	handler, teardown, err := setupHandler(t)
+	defer teardown()
+	require.NoError(t, err)
+
+	endpointIDs := []portainer.EndpointID{8, 9, 10, 11}
+	for _, endpointID := range endpointIDs {
+		err = createEndpoint(handler, portainer.Endpoint{
+			ID:              endpointID,
+			Name:            fmt.Sprintf("edge-%d", endpointID),
+			Type:            portainer.EdgeAgentOnDockerEnvironment,
+			URL:             "https://portainer.io:9443",
+			EdgeID:          fmt.Sprintf("edge-id-%d", endpointID),
+			LastCheckInDate: time.Now().Unix(),
+		}, portainer.EndpointRelation{EndpointID: endpointID})
+		require.NoError(t, err)
+	}
+
+	edgeJob := &portainer.EdgeJob{
+		ID:                  1,
+		Name:                "cleanup",
+		CronExpression:      "0 0 1 1 *",
+		Recurring:           true,
+		Created:             time.Now().AddDate(-2, 0, 0).Unix(),
+		Endpoints:           map[portainer.EndpointID]portainer.EdgeJobEndpointMeta{},
+		GroupLogsCollection: map[portainer.EndpointID]portainer.EdgeJobEndpointMeta{},
+		LogsRetention:       1,
+	}
+	for _, endpointID := range endpointIDs {
+		edgeJob.Endpoints[endpointID] = portainer.EdgeJobEndpointMeta{CollectLogs: true}
+	}
+	require.NoError(t, handler.DataStore.EdgeJob().Create(edgeJob.ID, edgeJob))
+
+	uploadLogs := func(endpointID portainer.EndpointID, payload logsPayload) int {
+		body, err := json.Marshal(payload)
+		is.NoError(err)
+
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/endpoints/%d/edge/jobs/%d/logs", endpointID, edgeJob.ID), bytes.NewReader(body))
+		req.Header.Set(portainer.PortainerAgentEdgeIDHeader, fmt.Sprintf("edge-id-%d", endpointID))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	var wg sync.WaitGroup
+	for _, endpointID := range endpointIDs {
+		wg.Add(1)
+
+		go func(endpointID portainer.EndpointID) {
+			defer wg.Done()
+
+			is.Equal(http.StatusOK, uploadLogs(endpointID, logsPayload{FileContent: "done"}))
+		}(endpointID)
+	}
+	wg.Wait()
+
+	is.Equal(http.StatusOK, uploadLogs(8, logsPayload{FileContent: "done again", RunDate: time.Now().Unix()}))
+
+	updatedJob, err := handler.DataStore.EdgeJob().EdgeJob(edgeJob.ID)
+	require.NoError(t, err)
+	require.Len(t, updatedJob.Runs, 1, "the uploads of a single activation should belong to the same run")
+	is.Len(updatedJob.Runs[0].Results, len(endpointIDs), "the concurrent uploads should all be recorded")
+	runID := updatedJob.Runs[0].ID
+
+	t.Run("the run dates outside of the schedule are rejected", func(t *testing.T) {
+		is.Equal(http.StatusBadRequest, uploadLogs(9, logsPayload{FileContent: "future", RunDate: time.Now().AddDate(1, 0, 0).Unix()}))
+		is.Equal(http.StatusBadRequest, uploadLogs(9, logsPayload{FileContent: "past", RunDate: time.Now().AddDate(-3, 0, 0).Unix()}))
+
+		updatedJob, err := handler.DataStore.EdgeJob().EdgeJob(edgeJob.ID)
+		require.NoError(t, err)
+		require.Len(t, updatedJob.Runs, 1)
+		is.Equal(runID, updatedJob.Runs[0].ID, "the rejected uploads should not evict the retained run")
+		is.Equal(int64(len("done again")), updatedJob.Runs[0].Results[8].LogsSize)
+	})
+}
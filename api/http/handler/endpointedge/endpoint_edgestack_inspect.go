@@ -9,6 +9,7 @@ import (
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/http/middlewares"
+	"github.com/portainer/portainer/api/internal/edge/edgestacks"
 	"github.com/portainer/portainer/api/internal/endpointutils"
 	"github.com/portainer/portainer/api/kubernetes"
 )
@@ -18,6 +19,8 @@ type configResponse struct {
 	Name             string
 	// Namespace to use for Kubernetes manifests, leave empty to use the namespaces defined in the manifest
 	Namespace string
+	// Environment variables of the stack
+	EnvVars []portainer.Pair
 }
 
 // @summary Inspect an Edge Stack for an Environment(Endpoint)
@@ -76,14 +79,39 @@ func (handler *Handler) endpointEdgeStackInspect(w http.ResponseWriter, r *http.
 
 	}
 
-	stackFileContent, err := handler.FileService.GetFileContent(edgeStack.ProjectPath, fileName)
+	// the environments which did not deploy the current version get the files of their version
+	projectPath, err := edgestacks.EndpointProjectPath(handler.FileService, edgeStack, endpoint.ID)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the files of the edge stack version deployed to the environment", err)
+	}
+
+	stackFileContent, err := handler.FileService.GetFileContent(projectPath, fileName)
 	if err != nil {
 		return httperror.InternalServerError("Unable to retrieve Compose file from disk", err)
 	}
 
+	envVars := edgeStack.Env
+	if edgeStack.UseTemplating {
+		data, err := edgestacks.BuildTemplateData(handler.DataStore, edgeStack, endpoint)
+		if err != nil {
+			return httperror.InternalServerError("Unable to retrieve the edge stack template data", err)
+		}
+
+		stackFileContent, err = edgestacks.RenderFile(stackFileContent, data)
+		if err != nil {
+			return httperror.InternalServerError("Unable to render the edge stack file", err)
+		}
+
+		envVars, err = edgestacks.RenderEnv(edgeStack.Env, data)
+		if err != nil {
+			return httperror.InternalServerError("Unable to render the edge stack environment variables", err)
+		}
+	}
+
 	return response.JSON(w, configResponse{
 		StackFileContent: string(stackFileContent),
 		Name:             edgeStack.Name,
 		Namespace:        namespace,
+		EnvVars:          envVars,
 	})
 }
@@ -0,0 +1,92 @@
+package endpointedge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEdgeStackInspectTemplating(t *testing.T) {
+	is := assert.New(t)
+
+	handler, teardown, err := setupHandler(t)
+	defer teardown()
+	require.NoError(t, err)
+
+	tag := &portainer.Tag{ID: 1, Name: "region=eu", Endpoints: map[portainer.EndpointID]bool{}}
+	require.NoError(t, handler.DataStore.Tag().Create(tag))
+
+	group := &portainer.EndpointGroup{ID: 2, Name: "factories", Metadata: map[string]string{"line": "a", "site": "default"}}
+	require.NoError(t, handler.DataStore.EndpointGroup().Create(group))
+
+	endpoint := portainer.Endpoint{
+		ID:              portainer.EndpointID(8),
+		Name:            "paris-01",
+		Type:            portainer.EdgeAgentOnDockerEnvironment,
+		URL:             "https://portainer.io:9443",
+		EdgeID:          "edge-id",
+		GroupID:         group.ID,
+		TagIDs:          []portainer.TagID{tag.ID},
+		Metadata:        map[string]string{"site": "paris"},
+		LastCheckInDate: time.Now().Unix(),
+	}
+
+	projectPath, err := handler.FileService.StoreEdgeStackFileFromBytes("18", "docker-compose.yml", []byte("name: {{ .Endpoint.Name }}\nregion: {{ .Endpoint.Tags.region }}\nsite: {{ .Metadata.site }}\nline: {{ .Group.Metadata.line }}\nmissing: '{{ .Metadata.missing }}'\n"))
+	require.NoError(t, err)
+
+	edgeStack := portainer.EdgeStack{
+		ID:            portainer.EdgeStackID(18),
+		Name:          "templated",
+		Status:        map[portainer.EndpointID]portainer.EdgeStackStatus{},
+		Version:       1,
+		ProjectPath:   projectPath,
+		EntryPoint:    "docker-compose.yml",
+		UseTemplating: true,
+		Env:           []portainer.Pair{{Name: "SITE_NAME", Value: "{{ .Endpoint.Name }}-{{ .Group.Name }}"}},
+	}
+	require.NoError(t, handler.DataStore.EdgeStack().Create(edgeStack.ID, &edgeStack))
+
+	err = createEndpoint(handler, endpoint, portainer.EndpointRelation{
+		EndpointID: endpoint.ID,
+		EdgeStacks: map[portainer.EdgeStackID]bool{edgeStack.ID: true},
+	})
+	require.NoError(t, err)
+
+	inspect := func() configResponse {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/endpoints/%d/edge/stacks/%d", endpoint.ID, edgeStack.ID), nil)
+		require.NoError(t, err)
+		req.Header.Set(portainer.PortainerAgentEdgeIDHeader, "edge-id")
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var data configResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&data))
+
+		return data
+	}
+
+	data := inspect()
+	is.Equal("name: paris-01\nregion: eu\nsite: paris\nline: a\nmissing: ''\n", data.StackFileContent)
+	is.Equal([]portainer.Pair{{Name: "SITE_NAME", Value: "paris-01-factories"}}, data.EnvVars)
+
+	t.Run("the file is sent verbatim when the templating is disabled", func(t *testing.T) {
+		err := handler.DataStore.EdgeStack().UpdateEdgeStackFunc(edgeStack.ID, func(edgeStack *portainer.EdgeStack) {
+			edgeStack.UseTemplating = false
+		})
+		require.NoError(t, err)
+
+		data := inspect()
+		is.Contains(data.StackFileContent, "name: {{ .Endpoint.Name }}")
+		is.Equal("{{ .Endpoint.Name }}-{{ .Group.Name }}", data.EnvVars[0].Value)
+	})
+}
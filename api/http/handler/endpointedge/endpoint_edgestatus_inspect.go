@@ -18,6 +18,7 @@ import (
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/internal/edge/cache"
+	"github.com/portainer/portainer/api/internal/edge/edgestacks"
 )
 
 type stackStatusResponse struct {
@@ -223,6 +224,15 @@ func (handler *Handler) buildEdgeStacks(endpointID portainer.EndpointID) ([]stac
 			return nil, httperror.InternalServerError("Unable to retrieve edge stack from the database", err)
 		}
 
+		// the environments rolled back individually keep the version they were rolled back to
+		if rolledBackVersion, ok := handler.DataStore.EdgeStack().EdgeStackRolledBackVersion(stackID, endpointID); ok {
+			version = rolledBackVersion
+		} else {
+			// the environments which are not released yet by a staged rollout keep the previous version
+			rollout, _ := handler.DataStore.EdgeStack().EdgeStackRollout(stackID)
+			version = edgestacks.EndpointVersion(version, rollout, endpointID)
+		}
+
 		stackStatus := stackStatusResponse{
 			ID:      stackID,
 			Version: version,
@@ -373,6 +373,93 @@ func TestEdgeStackStatus(t *testing.T) {
 	assert.Equal(t, edgeStack.Version, data.Stacks[0].Version)
 }
 
+func TestEdgeStackStatusDuringRollout(t *testing.T) {
+	handler, teardown, err := setupHandler(t)
+	defer teardown()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := portainer.Endpoint{
+		ID:              portainer.EndpointID(7),
+		Name:            "test-endpoint-7",
+		Type:            portainer.EdgeAgentOnDockerEnvironment,
+		URL:             "https://portainer.io:9443",
+		EdgeID:          "edge-id",
+		LastCheckInDate: time.Now().Unix(),
+	}
+
+	edgeStack := portainer.EdgeStack{
+		ID:      portainer.EdgeStackID(17),
+		Name:    "test-edge-stack-17",
+		Status:  map[portainer.EndpointID]portainer.EdgeStackStatus{},
+		Version: 238,
+		Rollout: &portainer.EdgeStackRollout{
+			Version:         238,
+			PreviousVersion: 237,
+			Status:          portainer.EdgeStackRolloutInProgress,
+			Endpoints:       []portainer.EndpointID{3, endpoint.ID},
+			ReleasedCount:   1,
+			BatchSize:       1,
+		},
+	}
+
+	err = handler.DataStore.EdgeStack().Create(edgeStack.ID, &edgeStack)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = createEndpoint(handler, endpoint, portainer.EndpointRelation{
+		EndpointID: endpoint.ID,
+		EdgeStacks: map[portainer.EdgeStackID]bool{edgeStack.ID: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inspectVersion := func() int {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/endpoints/%d/edge/status", endpoint.ID), nil)
+		if err != nil {
+			t.Fatal("request error:", err)
+		}
+		req.Header.Set(portainer.PortainerAgentEdgeIDHeader, "edge-id")
+		req.Header.Set(portainer.HTTPResponseAgentPlatform, "1")
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		var data endpointEdgeStatusInspectResponse
+		err = json.NewDecoder(rec.Body).Decode(&data)
+		if err != nil {
+			t.Fatal("error decoding response:", err)
+		}
+
+		assert.Len(t, data.Stacks, 1)
+		return data.Stacks[0].Version
+	}
+
+	assert.Equal(t, 237, inspectVersion(), "the environments which are not released should keep the previous version")
+
+	err = handler.DataStore.EdgeStack().UpdateEdgeStackFunc(edgeStack.ID, func(edgeStack *portainer.EdgeStack) {
+		edgeStack.Rollout.ReleasedCount = 2
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 238, inspectVersion())
+
+	err = handler.DataStore.EdgeStack().UpdateEdgeStackFunc(edgeStack.ID, func(edgeStack *portainer.EdgeStack) {
+		edgeStack.RolledBackEndpoints = map[portainer.EndpointID]int{endpoint.ID: 236}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 236, inspectVersion(), "the environments rolled back should keep the version they were rolled back to")
+}
+
 func TestEdgeJobsResponse(t *testing.T) {
 	handler, teardown, err := setupHandler(t)
 	defer teardown()
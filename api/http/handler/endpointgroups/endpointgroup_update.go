@@ -8,6 +8,7 @@ import (
 	"github.com/portainer/libhttp/request"
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/edge/edgestacks"
 	"github.com/portainer/portainer/api/internal/tag"
 )
 
@@ -20,10 +21,12 @@ type endpointGroupUpdatePayload struct {
 	TagIDs             []portainer.TagID `example:"3,4"`
 	UserAccessPolicies portainer.UserAccessPolicies
 	TeamAccessPolicies portainer.TeamAccessPolicies
+	// Custom key/value metadata available to the templated edge stacks, kept unchanged when omitted
+	Metadata map[string]string `example:"region:eu"`
 }
 
 func (payload *endpointGroupUpdatePayload) Validate(r *http.Request) error {
-	return nil
+	return edgestacks.ValidateMetadata(payload.Metadata)
 }
 
 // @id EndpointGroupUpdate
@@ -61,7 +64,11 @@ func (handler *Handler) endpointGroupUpdate(w http.ResponseWriter, r *http.Reque
 		return httperror.InternalServerError("Unable to find an environment group with the specified identifier inside the database", err)
 	}
 
+	// the templated edge stacks are rendered with the name, the tags and the metadata of the group of the environment
+	templateDataChanged := false
+
 	if payload.Name != "" {
+		templateDataChanged = payload.Name != endpointGroup.Name
 		endpointGroup.Name = payload.Name
 	}
 
@@ -69,6 +76,11 @@ func (handler *Handler) endpointGroupUpdate(w http.ResponseWriter, r *http.Reque
 		endpointGroup.Description = payload.Description
 	}
 
+	if payload.Metadata != nil {
+		templateDataChanged = templateDataChanged || !edgestacks.MetadataEqual(payload.Metadata, endpointGroup.Metadata)
+		endpointGroup.Metadata = payload.Metadata
+	}
+
 	tagsChanged := false
 	if payload.TagIDs != nil {
 		payloadTagSet := tag.Set(payload.TagIDs)
@@ -158,5 +170,29 @@ func (handler *Handler) endpointGroupUpdate(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
+	if templateDataChanged || tagsChanged {
+		err = handler.updateTemplatedEdgeStacks(endpointGroup.ID)
+		if err != nil {
+			return httperror.InternalServerError("Unable to update the templated edge stacks of the environment group", err)
+		}
+	}
+
 	return response.JSON(w, endpointGroup)
 }
+
+// updateTemplatedEdgeStacks bumps the version of the templated edge stacks related to the environments of a group
+func (handler *Handler) updateTemplatedEdgeStacks(endpointGroupID portainer.EndpointGroupID) error {
+	endpoints, err := handler.DataStore.Endpoint().Endpoints()
+	if err != nil {
+		return err
+	}
+
+	endpointIDs := []portainer.EndpointID{}
+	for _, endpoint := range endpoints {
+		if endpoint.GroupID == endpointGroupID {
+			endpointIDs = append(endpointIDs, endpoint.ID)
+		}
+	}
+
+	return edgestacks.UpdateTemplatedEdgeStacks(handler.DataStore, handler.FileService, endpointIDs)
+}
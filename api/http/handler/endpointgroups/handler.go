@@ -3,6 +3,7 @@ package endpointgroups
 import (
 	"net/http"
 
+	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/internal/authorization"
 
@@ -16,6 +17,7 @@ type Handler struct {
 	*mux.Router
 	AuthorizationService *authorization.Service
 	DataStore            dataservices.DataStore
+	FileService          portainer.FileService
 }
 
 // NewHandler creates a handler to manage environment(endpoint) group operations.
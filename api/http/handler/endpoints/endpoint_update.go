@@ -11,6 +11,7 @@ import (
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/http/client"
 	"github.com/portainer/portainer/api/internal/edge"
+	"github.com/portainer/portainer/api/internal/edge/edgestacks"
 	"github.com/portainer/portainer/api/internal/tag"
 )
 
@@ -48,10 +49,12 @@ type endpointUpdatePayload struct {
 	EdgeCheckinInterval *int `example:"5"`
 	// Associated Kubernetes data
 	Kubernetes *portainer.KubernetesData
+	// Custom key/value metadata available to the templated edge stacks, kept unchanged when omitted
+	Metadata map[string]string `example:"site:paris"`
 }
 
 func (payload *endpointUpdatePayload) Validate(r *http.Request) error {
-	return nil
+	return edgestacks.ValidateMetadata(payload.Metadata)
 }
 
 // @id EndpointUpdate
@@ -89,6 +92,9 @@ func (handler *Handler) endpointUpdate(w http.ResponseWriter, r *http.Request) *
 		return httperror.InternalServerError("Unable to find an environment with the specified identifier inside the database", err)
 	}
 
+	// the templated edge stacks are rendered with the name, the group, the tags and the metadata of the environment
+	templateDataChanged := false
+
 	if payload.Name != nil {
 		name := *payload.Name
 		isUnique, err := handler.isNameUnique(name, endpoint.ID)
@@ -100,8 +106,8 @@ func (handler *Handler) endpointUpdate(w http.ResponseWriter, r *http.Request) *
 			return httperror.NewError(http.StatusConflict, "Name is not unique", nil)
 		}
 
+		templateDataChanged = name != endpoint.Name
 		endpoint.Name = name
-
 	}
 
 	if payload.URL != nil {
@@ -120,6 +126,11 @@ func (handler *Handler) endpointUpdate(w http.ResponseWriter, r *http.Request) *
 		endpoint.EdgeCheckinInterval = *payload.EdgeCheckinInterval
 	}
 
+	if payload.Metadata != nil {
+		templateDataChanged = templateDataChanged || !edgestacks.MetadataEqual(payload.Metadata, endpoint.Metadata)
+		endpoint.Metadata = payload.Metadata
+	}
+
 	groupIDChanged := false
 	if payload.GroupID != nil {
 		groupID := portainer.EndpointGroupID(*payload.GroupID)
@@ -322,6 +333,13 @@ func (handler *Handler) endpointUpdate(w http.ResponseWriter, r *http.Request) *
 		}
 	}
 
+	if templateDataChanged || groupIDChanged || tagsChanged {
+		err = edgestacks.UpdateTemplatedEdgeStacks(handler.DataStore, handler.FileService, []portainer.EndpointID{endpoint.ID})
+		if err != nil {
+			return httperror.InternalServerError("Unable to update the templated edge stacks of the environment", err)
+		}
+	}
+
 	err = handler.SnapshotService.FillSnapshotData(endpoint)
 	if err != nil {
 		return httperror.InternalServerError("Unable to add snapshot data", err)
@@ -7,8 +7,10 @@ import (
 	httperror "github.com/portainer/libhttp/error"
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/dataservices"
+	httperrors "github.com/portainer/portainer/api/http/errors"
 	"github.com/portainer/portainer/api/http/middlewares"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/registryutils"
 	"github.com/portainer/portainer/api/kubernetes"
 	"github.com/portainer/portainer/pkg/libhelm"
 	"github.com/portainer/portainer/pkg/libhelm/options"
@@ -27,6 +29,7 @@ type Handler struct {
 	kubeClusterAccessService kubernetes.KubeClusterAccessService
 	kubernetesDeployer       portainer.KubernetesDeployer
 	helmPackageManager       libhelm.HelmPackageManager
+	indexCacheDir            string
 }
 
 // NewHandler creates a handler to manage endpoint group operations.
@@ -51,6 +54,18 @@ func NewHandler(bouncer requestBouncer, dataStore dataservices.DataStore, jwtSer
 	h.Handle("/{id}/kubernetes/helm/{release}",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.helmDelete))).Methods(http.MethodDelete)
 
+	// `helm upgrade RELEASE_NAME CHART flags`
+	h.Handle("/{id}/kubernetes/helm/{release}",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.helmUpgrade))).Methods(http.MethodPut)
+
+	// `helm history RELEASE_NAME`
+	h.Handle("/{id}/kubernetes/helm/{release}/history",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.helmHistory))).Methods(http.MethodGet)
+
+	// `helm rollback RELEASE_NAME [REVISION]`
+	h.Handle("/{id}/kubernetes/helm/{release}/rollback",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.helmRollback))).Methods(http.MethodPost)
+
 	// `helm install [NAME] [CHART] flags`
 	h.Handle("/{id}/kubernetes/helm",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.helmInstall))).Methods(http.MethodPost)
@@ -64,11 +79,13 @@ func NewHandler(bouncer requestBouncer, dataStore dataservices.DataStore, jwtSer
 }
 
 // NewTemplateHandler creates a template handler to manage environment(endpoint) group operations.
-func NewTemplateHandler(bouncer requestBouncer, helmPackageManager libhelm.HelmPackageManager) *Handler {
+// The indexes of the chart repositories are cached in the index cache directory.
+func NewTemplateHandler(bouncer requestBouncer, helmPackageManager libhelm.HelmPackageManager, indexCacheDir string) *Handler {
 	h := &Handler{
 		Router:             mux.NewRouter(),
 		helmPackageManager: helmPackageManager,
 		requestBouncer:     bouncer,
+		indexCacheDir:      indexCacheDir,
 	}
 
 	h.Handle("/templates/helm",
@@ -117,3 +134,59 @@ func (handler *Handler) getHelmClusterAccess(r *http.Request) (*options.Kubernet
 		AuthToken:                bearerToken,
 	}, nil
 }
+
+// getHelmRegistryAccess obtains the credentials of the registry storing an OCI chart.
+// No credentials are returned when no registry is specified, the chart is then pulled anonymously.
+// The user must be allowed to use the registry on the environment of the request.
+func (handler *Handler) getHelmRegistryAccess(r *http.Request, registryID portainer.RegistryID) (*options.RegistryAccess, *httperror.HandlerError) {
+	if registryID == 0 {
+		return nil, nil
+	}
+
+	endpoint, err := middlewares.FetchEndpoint(r)
+	if err != nil {
+		return nil, httperror.NotFound("Unable to find an environment on request context", err)
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return nil, httperror.InternalServerError("Unable to retrieve user authentication token", err)
+	}
+
+	user, err := handler.dataStore.User().User(tokenData.ID)
+	if err != nil {
+		return nil, httperror.InternalServerError("Unable to load user information from the database", err)
+	}
+
+	memberships, err := handler.dataStore.TeamMembership().TeamMembershipsByUserID(user.ID)
+	if err != nil {
+		return nil, httperror.InternalServerError("Unable to retrieve user team memberships from the database", err)
+	}
+
+	registry, err := handler.dataStore.Registry().Registry(registryID)
+	if handler.dataStore.IsErrObjectNotFound(err) {
+		return nil, httperror.NotFound("Unable to find a registry with the specified identifier inside the database", err)
+	} else if err != nil {
+		return nil, httperror.InternalServerError("Unable to find a registry with the specified identifier inside the database", err)
+	}
+
+	if !security.AuthorizedRegistryAccess(registry, user, memberships, endpoint.ID) {
+		return nil, httperror.Forbidden("Permission denied to access registry", httperrors.ErrResourceAccessDenied)
+	}
+
+	if !registry.Authentication {
+		return nil, nil
+	}
+
+	err = registryutils.EnsureRegTokenValid(handler.dataStore, registry)
+	if err != nil {
+		return nil, httperror.InternalServerError("Unable to refresh the registry token", err)
+	}
+
+	username, password, err := registryutils.GetRegEffectiveCredential(registry)
+	if err != nil {
+		return nil, httperror.InternalServerError("Unable to retrieve the registry credentials", err)
+	}
+
+	return &options.RegistryAccess{Username: username, Password: password}, nil
+}
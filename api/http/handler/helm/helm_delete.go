@@ -1,11 +1,13 @@
 package helm
 
 import (
+	"errors"
 	"net/http"
 
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/libhttp/request"
 	"github.com/portainer/libhttp/response"
+	libhelmerrors "github.com/portainer/portainer/pkg/libhelm/errors"
 	"github.com/portainer/portainer/pkg/libhelm/options"
 )
 
@@ -22,7 +24,7 @@ import (
 // @success 204 "Success"
 // @failure 400 "Invalid environment(endpoint) id or bad request"
 // @failure 401 "Unauthorized"
-// @failure 404 "Environment(Endpoint) or ServiceAccount not found"
+// @failure 404 "Environment(Endpoint), ServiceAccount or release not found"
 // @failure 500 "Server error or helm error"
 // @router /endpoints/{id}/kubernetes/helm/{release} [delete]
 func (handler *Handler) helmDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
@@ -47,7 +49,9 @@ func (handler *Handler) helmDelete(w http.ResponseWriter, r *http.Request) *http
 	}
 
 	err = handler.helmPackageManager.Uninstall(uninstallOpts)
-	if err != nil {
+	if errors.Is(err, libhelmerrors.ErrReleaseNotFound) {
+		return httperror.NotFound("Unable to find the release", err)
+	} else if err != nil {
 		return httperror.InternalServerError("Helm returned an error", err)
 	}
 
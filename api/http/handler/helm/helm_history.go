@@ -0,0 +1,59 @@
+package helm
+
+import (
+	"errors"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	libhelmerrors "github.com/portainer/portainer/pkg/libhelm/errors"
+	"github.com/portainer/portainer/pkg/libhelm/options"
+)
+
+// @id HelmHistory
+// @summary List the revisions of a Helm Release
+// @description
+// @description **Access policy**: authenticated
+// @tags helm
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param id path int true "Environment(Endpoint) identifier"
+// @param release path string true "The name of the release/application"
+// @param namespace query string false "An optional namespace"
+// @param max query int false "Maximum number of revisions to return"
+// @success 200 {array} release.ReleaseHistoryElement "Success"
+// @failure 400 "Invalid environment(endpoint) id or bad request"
+// @failure 401 "Unauthorized"
+// @failure 404 "Environment(Endpoint), ServiceAccount or release not found"
+// @failure 500 "Server error or helm error"
+// @router /endpoints/{id}/kubernetes/helm/{release}/history [get]
+func (handler *Handler) helmHistory(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	releaseName, err := request.RetrieveRouteVariableValue(r, "release")
+	if err != nil {
+		return httperror.BadRequest("No release specified", err)
+	}
+
+	namespace, _ := request.RetrieveQueryParameter(r, "namespace", true)
+	max, _ := request.RetrieveNumericQueryParameter(r, "max", true)
+
+	clusterAccess, httperr := handler.getHelmClusterAccess(r)
+	if httperr != nil {
+		return httperr
+	}
+
+	history, err := handler.helmPackageManager.History(options.HistoryOptions{
+		Name:                    releaseName,
+		Namespace:               namespace,
+		Max:                     max,
+		KubernetesClusterAccess: clusterAccess,
+	})
+	if errors.Is(err, libhelmerrors.ErrReleaseNotFound) {
+		return httperror.NotFound("Unable to find the release", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Helm returned an error", err)
+	}
+
+	return response.JSON(w, history)
+}
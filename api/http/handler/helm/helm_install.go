@@ -10,6 +10,7 @@ import (
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/libhttp/request"
 	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/http/middlewares"
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/kubernetes"
@@ -22,9 +23,15 @@ import (
 type installChartPayload struct {
 	Namespace string `json:"namespace"`
 	Name      string `json:"name"`
-	Chart     string `json:"chart"`
-	Repo      string `json:"repo"`
-	Values    string `json:"values"`
+	// Name of the chart, or reference of the chart in an OCI registry (oci://)
+	Chart string `json:"chart"`
+	// Chart repository URL, HTTP(S) or OCI (oci://), not required for OCI chart references
+	Repo string `json:"repo"`
+	// Version of the chart, the latest version is used when empty
+	Version string `json:"version"`
+	Values  string `json:"values"`
+	// Identifier of the registry whose credentials are used to pull an OCI chart, the chart is pulled anonymously when empty
+	RegistryID portainer.RegistryID `json:"registryId"`
 }
 
 var errChartNameInvalid = errors.New("invalid chart name. " +
@@ -44,8 +51,10 @@ var errChartNameInvalid = errors.New("invalid chart name. " +
 // @param id path int true "Environment(Endpoint) identifier"
 // @param payload body installChartPayload true "Chart details"
 // @success 201 {object} release.Release "Created"
+// @failure 400 "Invalid request"
 // @failure 401 "Unauthorized"
-// @failure 404 "Environment(Endpoint) or ServiceAccount not found"
+// @failure 403 "Permission denied to access the registry"
+// @failure 404 "Environment(Endpoint), ServiceAccount or registry not found"
 // @failure 500 "Server error"
 // @router /endpoints/{id}/kubernetes/helm [post]
 func (handler *Handler) helmInstall(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
@@ -55,7 +64,12 @@ func (handler *Handler) helmInstall(w http.ResponseWriter, r *http.Request) *htt
 		return httperror.BadRequest("Invalid Helm install payload", err)
 	}
 
-	release, err := handler.installChart(r, payload)
+	registryAccess, httpErr := handler.getHelmRegistryAccess(r, payload.RegistryID)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	release, err := handler.installChart(r, payload, registryAccess)
 	if err != nil {
 		return httperror.InternalServerError("Unable to install a chart", err)
 	}
@@ -66,7 +80,7 @@ func (handler *Handler) helmInstall(w http.ResponseWriter, r *http.Request) *htt
 
 func (p *installChartPayload) Validate(_ *http.Request) error {
 	var required []string
-	if p.Repo == "" {
+	if p.Repo == "" && !options.IsOCIReference(p.Chart) {
 		required = append(required, "repo")
 	}
 	if p.Name == "" {
@@ -89,7 +103,7 @@ func (p *installChartPayload) Validate(_ *http.Request) error {
 	return nil
 }
 
-func (handler *Handler) installChart(r *http.Request, p installChartPayload) (*release.Release, error) {
+func (handler *Handler) installChart(r *http.Request, p installChartPayload, registryAccess *options.RegistryAccess) (*release.Release, error) {
 	clusterAccess, httperr := handler.getHelmClusterAccess(r)
 	if httperr != nil {
 		return nil, httperr.Err
@@ -99,6 +113,8 @@ func (handler *Handler) installChart(r *http.Request, p installChartPayload) (*r
 		Chart:     p.Chart,
 		Namespace: p.Namespace,
 		Repo:      p.Repo,
+		Version:   p.Version,
+		Registry:  registryAccess,
 		KubernetesClusterAccess: &options.KubernetesClusterAccess{
 			ClusterServerURL:         clusterAccess.ClusterServerURL,
 			CertificateAuthorityFile: clusterAccess.CertificateAuthorityFile,
@@ -107,21 +123,12 @@ func (handler *Handler) installChart(r *http.Request, p installChartPayload) (*r
 	}
 
 	if p.Values != "" {
-		file, err := os.CreateTemp("", "helm-values")
-		if err != nil {
-			return nil, err
-		}
-		defer os.Remove(file.Name())
-		_, err = file.WriteString(p.Values)
+		valuesFile, err := writeValuesFile(p.Values)
 		if err != nil {
-			file.Close()
 			return nil, err
 		}
-		err = file.Close()
-		if err != nil {
-			return nil, err
-		}
-		installOpts.ValuesFile = file.Name()
+		defer os.Remove(valuesFile)
+		installOpts.ValuesFile = valuesFile
 	}
 
 	release, err := handler.helmPackageManager.Install(installOpts)
@@ -129,7 +136,7 @@ func (handler *Handler) installChart(r *http.Request, p installChartPayload) (*r
 		return nil, err
 	}
 
-	manifest, err := handler.applyPortainerLabelsToHelmAppManifest(r, installOpts, release.Manifest)
+	manifest, err := handler.applyPortainerLabelsToHelmAppManifest(r, installOpts.Name, release.Manifest)
 	if err != nil {
 		return nil, err
 	}
@@ -142,10 +149,33 @@ func (handler *Handler) installChart(r *http.Request, p installChartPayload) (*r
 	return release, nil
 }
 
+// writeValuesFile writes the chart values to a temporary file which must be removed by the caller
+func writeValuesFile(values string) (string, error) {
+	file, err := os.CreateTemp("", "helm-values")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = file.WriteString(values)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	err = file.Close()
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
 // applyPortainerLabelsToHelmAppManifest will patch all the resources deployed in the helm release manifest
 // with portainer specific labels. This is to mark the resources as managed by portainer - hence the helm apps
 // wont appear external in the portainer UI.
-func (handler *Handler) applyPortainerLabelsToHelmAppManifest(r *http.Request, installOpts options.InstallOptions, manifest string) ([]byte, error) {
+func (handler *Handler) applyPortainerLabelsToHelmAppManifest(r *http.Request, releaseName string, manifest string) ([]byte, error) {
 	// Patch helm release by adding with portainer labels to all deployed resources
 	tokenData, err := security.RetrieveTokenData(r)
 	if err != nil {
@@ -156,7 +186,7 @@ func (handler *Handler) applyPortainerLabelsToHelmAppManifest(r *http.Request, i
 		return nil, errors.Wrap(err, "unable to load user information from the database")
 	}
 
-	appLabels := kubernetes.GetHelmAppLabels(installOpts.Name, user.Username)
+	appLabels := kubernetes.GetHelmAppLabels(releaseName, user.Username)
 	labeledManifest, err := kubernetes.AddAppLabels([]byte(manifest), appLabels)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to label helm release manifest")
@@ -168,7 +198,7 @@ func (handler *Handler) applyPortainerLabelsToHelmAppManifest(r *http.Request, i
 // updateHelmAppManifest will update the resources of helm release manifest with portainer labels using kubectl.
 // The resources of the manifest will be updated in parallel and individuallly since resources of a chart
 // can be deployed to different namespaces.
-// NOTE: These updates will need to be re-applied when upgrading the helm release
+// NOTE: These updates are re-applied when upgrading or rolling back the helm release
 func (handler *Handler) updateHelmAppManifest(r *http.Request, manifest []byte, namespace string) error {
 	endpoint, err := middlewares.FetchEndpoint(r)
 	if err != nil {
@@ -70,3 +70,58 @@ func Test_helmInstall(t *testing.T) {
 		is.EqualValues(options.Namespace, resp.Namespace, "Namespace doesn't match")
 	})
 }
+
+func Test_helmInstallOCI(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	err := store.Endpoint().Create(&portainer.Endpoint{ID: 1})
+	is.NoError(err, "error creating environment")
+
+	err = store.User().Create(&portainer.User{ID: 1, Username: "admin", Role: portainer.AdministratorRole})
+	is.NoError(err, "error creating a user")
+
+	jwtService, err := jwt.NewService("1h", store)
+	is.NoError(err, "Error initiating jwt service")
+
+	helmPackageManager := test.NewMockHelmBinaryPackageManager("")
+	h := NewHandler(helper.NewTestRequestBouncer(), store, jwtService, exectest.NewKubernetesDeployer(), helmPackageManager, kubernetes.NewKubeClusterAccessService("", "", ""))
+
+	// the releases of the mock are shared by the tests
+	defer helmPackageManager.Uninstall(options.UninstallOptions{Name: "nginx-oci", Namespace: "default"})
+
+	err = store.User().Create(&portainer.User{ID: 2, Username: "standard", Role: portainer.StandardUserRole})
+	is.NoError(err, "error creating a user")
+
+	err = store.Registry().Create(&portainer.Registry{ID: 1, Name: "charts", URL: "registry.example.com", Authentication: true, Username: "user", Password: "pass"})
+	is.NoError(err, "error creating a registry")
+
+	install := func(tokenData *portainer.TokenData, payload installChartPayload) int {
+		data, err := json.Marshal(payload)
+		is.NoError(err)
+
+		req := httptest.NewRequest(http.MethodPost, "/1/kubernetes/helm", bytes.NewBuffer(data))
+		req = req.WithContext(security.StoreTokenData(req, tokenData))
+		req.Header.Add("Authorization", "Bearer dummytoken")
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		return rr.Code
+	}
+
+	admin := &portainer.TokenData{ID: 1, Username: "admin", Role: portainer.AdministratorRole}
+	standard := &portainer.TokenData{ID: 2, Username: "standard", Role: portainer.StandardUserRole}
+
+	payload := installChartPayload{Name: "nginx-oci", Namespace: "default", Chart: "oci://registry.example.com/charts/nginx", Version: "1.0.0", RegistryID: 1}
+	is.Equal(http.StatusCreated, install(admin, payload), "Status should be 201")
+	is.Equal(http.StatusForbidden, install(standard, payload), "Status should be 403 without access to the registry")
+
+	payload.RegistryID = 2
+	is.Equal(http.StatusNotFound, install(admin, payload), "Status should be 404 for an unknown registry")
+
+	payload = installChartPayload{Name: "nginx-no-repo", Namespace: "default", Chart: "nginx"}
+	is.Equal(http.StatusBadRequest, install(admin, payload), "Status should be 400 without repository")
+}
@@ -4,18 +4,25 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/pkg/errors"
 	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
 	"github.com/portainer/portainer/pkg/libhelm/options"
 )
 
+// indexCacheTTL is the duration during which the cached index of a repository is used without being revalidated
+const indexCacheTTL = 10 * time.Minute
+
 // @id HelmRepoSearch
 // @summary Search Helm Charts
-// @description
+// @description Search the charts of a Helm repository. The index of the repository is cached
+// @description and revalidated against the repository once the cache expires or when a refresh is requested.
 // @description **Access policy**: authenticated
 // @tags helm
 // @param repo query string true "Helm repository URL"
+// @param refresh query bool false "Revalidate the cached index of the repository against the repository"
 // @security ApiKeyAuth
 // @security jwt
 // @produce json
@@ -36,8 +43,19 @@ func (handler *Handler) helmRepoSearch(w http.ResponseWriter, r *http.Request) *
 		return httperror.BadRequest("Bad request", errors.Wrap(err, fmt.Sprintf("provided URL %q is not valid", repo)))
 	}
 
+	if options.IsOCIReference(repo) {
+		return httperror.BadRequest("Bad request", errors.New("OCI repositories cannot be searched"))
+	}
+
+	refresh, _ := request.RetrieveBooleanQueryParameter(r, "refresh", true)
+
 	searchOpts := options.SearchRepoOptions{
-		Repo: repo,
+		Repo:     repo,
+		CacheDir: handler.indexCacheDir,
+		CacheTTL: indexCacheTTL,
+	}
+	if refresh {
+		searchOpts.CacheTTL = 0
 	}
 
 	result, err := handler.helmPackageManager.SearchRepo(searchOpts)
@@ -0,0 +1,78 @@
+package helm
+
+import (
+	"errors"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	libhelmerrors "github.com/portainer/portainer/pkg/libhelm/errors"
+	"github.com/portainer/portainer/pkg/libhelm/options"
+	"github.com/portainer/portainer/pkg/libhelm/release"
+)
+
+// @id HelmRollback
+// @summary Roll back a Helm Release
+// @description Roll back a release to one of its previous revisions.
+// @description **Access policy**: authenticated
+// @tags helm
+// @security ApiKeyAuth
+// @security jwt
+// @param id path int true "Environment(Endpoint) identifier"
+// @param release path string true "The name of the release/application to roll back"
+// @param namespace query string false "An optional namespace"
+// @param revision query int false "Revision to roll back to, defaults to the previous revision"
+// @success 204 "Success"
+// @failure 400 "Invalid environment(endpoint) id or bad request"
+// @failure 401 "Unauthorized"
+// @failure 404 "Environment(Endpoint), ServiceAccount or release not found"
+// @failure 500 "Server error or helm error"
+// @router /endpoints/{id}/kubernetes/helm/{release}/rollback [post]
+func (handler *Handler) helmRollback(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	releaseName, err := request.RetrieveRouteVariableValue(r, "release")
+	if err != nil {
+		return httperror.BadRequest("No release specified", err)
+	}
+
+	namespace, _ := request.RetrieveQueryParameter(r, "namespace", true)
+
+	revision, err := request.RetrieveNumericQueryParameter(r, "revision", true)
+	if err != nil || revision < 0 {
+		return httperror.BadRequest("Invalid query parameter: revision", err)
+	}
+
+	clusterAccess, httperr := handler.getHelmClusterAccess(r)
+	if httperr != nil {
+		return httperr
+	}
+
+	err = handler.helmPackageManager.Rollback(options.RollbackOptions{
+		Name:                    releaseName,
+		Namespace:               namespace,
+		Revision:                revision,
+		KubernetesClusterAccess: clusterAccess,
+	})
+	if errors.Is(err, libhelmerrors.ErrReleaseNotFound) {
+		return httperror.NotFound("Unable to find the release", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Helm returned an error", err)
+	}
+
+	manifest, err := handler.helmPackageManager.Get(options.GetOptions{
+		Name:                    releaseName,
+		Namespace:               namespace,
+		ReleaseResource:         options.GetManifest,
+		KubernetesClusterAccess: clusterAccess,
+	})
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the manifest of the release", err)
+	}
+
+	err = handler.labelHelmAppResources(r, releaseName, namespace, &release.Release{Manifest: string(manifest)})
+	if err != nil {
+		return httperror.InternalServerError("Unable to label the resources of the release", err)
+	}
+
+	return response.Empty(w)
+}
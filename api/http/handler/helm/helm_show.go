@@ -18,8 +18,9 @@ import (
 // @description
 // @description **Access policy**: authenticated
 // @tags helm
-// @param repo query string true "Helm repository URL"
-// @param chart query string true "Chart name"
+// @param repo query string false "Helm repository URL, not required for OCI chart references"
+// @param chart query string true "Chart name or OCI chart reference (oci://)"
+// @param version query string false "Chart version, the latest version is used when empty"
 // @param command path string true "chart/values/readme"
 // @security ApiKeyAuth
 // @security jwt
@@ -31,20 +32,22 @@ import (
 // @failure 500 "Server error"
 // @router /templates/helm/{command} [get]
 func (handler *Handler) helmShow(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
-	repo := r.URL.Query().Get("repo")
-	if repo == "" {
-		return httperror.BadRequest("Bad request", errors.New("missing `repo` query parameter"))
-	}
-	_, err := url.ParseRequestURI(repo)
-	if err != nil {
-		return httperror.BadRequest("Bad request", errors.Wrap(err, fmt.Sprintf("provided URL %q is not valid", repo)))
-	}
-
 	chart := r.URL.Query().Get("chart")
 	if chart == "" {
 		return httperror.BadRequest("Bad request", errors.New("missing `chart` query parameter"))
 	}
 
+	repo := r.URL.Query().Get("repo")
+	if repo == "" && !options.IsOCIReference(chart) {
+		return httperror.BadRequest("Bad request", errors.New("missing `repo` query parameter"))
+	}
+	if repo != "" {
+		_, err := url.ParseRequestURI(repo)
+		if err != nil {
+			return httperror.BadRequest("Bad request", errors.Wrap(err, fmt.Sprintf("provided URL %q is not valid", repo)))
+		}
+	}
+
 	cmd, err := request.RetrieveRouteVariableValue(r, "command")
 	if err != nil {
 		cmd = "all"
@@ -55,6 +58,7 @@ func (handler *Handler) helmShow(w http.ResponseWriter, r *http.Request) *httper
 		OutputFormat: options.ShowOutputFormat(cmd),
 		Chart:        chart,
 		Repo:         repo,
+		Version:      r.URL.Query().Get("version"),
 	}
 	result, err := handler.helmPackageManager.Show(showOptions)
 	if err != nil {
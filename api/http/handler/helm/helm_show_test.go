@@ -17,7 +17,7 @@ func Test_helmShow(t *testing.T) {
 	is := assert.New(t)
 
 	helmPackageManager := test.NewMockHelmBinaryPackageManager("")
-	h := NewTemplateHandler(helper.NewTestRequestBouncer(), helmPackageManager)
+	h := NewTemplateHandler(helper.NewTestRequestBouncer(), helmPackageManager, t.TempDir())
 
 	is.NotNil(h, "Handler should not fail")
 
@@ -0,0 +1,133 @@
+package helm
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/pkg/libhelm/options"
+	"github.com/portainer/portainer/pkg/libhelm/release"
+)
+
+type upgradeChartPayload struct {
+	Namespace string `json:"namespace"`
+	// Name of the chart, or reference of the chart in an OCI registry (oci://)
+	Chart string `json:"chart"`
+	// Chart repository URL, HTTP(S) or OCI (oci://), not required for OCI chart references
+	Repo string `json:"repo"`
+	// Version of the chart, the latest version is used when empty
+	Version string `json:"version"`
+	Values  string `json:"values"`
+	// Reuse the values of the current revision and merge the provided values into them
+	ReuseValues bool `json:"reuseValues"`
+	// Identifier of the registry whose credentials are used to pull an OCI chart, the chart is pulled anonymously when empty
+	RegistryID portainer.RegistryID `json:"registryId"`
+}
+
+func (p *upgradeChartPayload) Validate(_ *http.Request) error {
+	var required []string
+	if p.Repo == "" && !options.IsOCIReference(p.Chart) {
+		required = append(required, "repo")
+	}
+	if p.Namespace == "" {
+		required = append(required, "namespace")
+	}
+	if p.Chart == "" {
+		required = append(required, "chart")
+	}
+	if len(required) > 0 {
+		return fmt.Errorf("required field(s) missing: %s", strings.Join(required, ", "))
+	}
+
+	return nil
+}
+
+// @id HelmUpgrade
+// @summary Upgrade Helm Release
+// @description Upgrade a release to a new version of its chart and/or new values.
+// @description **Access policy**: authenticated
+// @tags helm
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Environment(Endpoint) identifier"
+// @param release path string true "The name of the release/application to upgrade"
+// @param payload body upgradeChartPayload true "Chart details"
+// @success 200 {object} release.Release "Success"
+// @failure 400 "Invalid request"
+// @failure 401 "Unauthorized"
+// @failure 403 "Permission denied to access the registry"
+// @failure 404 "Environment(Endpoint), ServiceAccount or registry not found"
+// @failure 500 "Server error or helm error"
+// @router /endpoints/{id}/kubernetes/helm/{release} [put]
+func (handler *Handler) helmUpgrade(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	releaseName, err := request.RetrieveRouteVariableValue(r, "release")
+	if err != nil {
+		return httperror.BadRequest("No release specified", err)
+	}
+
+	var payload upgradeChartPayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return httperror.BadRequest("Invalid Helm upgrade payload", err)
+	}
+
+	clusterAccess, httperr := handler.getHelmClusterAccess(r)
+	if httperr != nil {
+		return httperr
+	}
+
+	registryAccess, httperr := handler.getHelmRegistryAccess(r, payload.RegistryID)
+	if httperr != nil {
+		return httperr
+	}
+
+	upgradeOpts := options.UpgradeOptions{
+		Name:                    releaseName,
+		Chart:                   payload.Chart,
+		Namespace:               payload.Namespace,
+		Repo:                    payload.Repo,
+		Version:                 payload.Version,
+		ReuseValues:             payload.ReuseValues,
+		KubernetesClusterAccess: clusterAccess,
+		Registry:                registryAccess,
+	}
+
+	if payload.Values != "" {
+		valuesFile, err := writeValuesFile(payload.Values)
+		if err != nil {
+			return httperror.InternalServerError("Unable to write the chart values", err)
+		}
+		defer os.Remove(valuesFile)
+		upgradeOpts.ValuesFile = valuesFile
+	}
+
+	upgradedRelease, err := handler.helmPackageManager.Upgrade(upgradeOpts)
+	if err != nil {
+		return httperror.InternalServerError("Helm returned an error", err)
+	}
+
+	err = handler.labelHelmAppResources(r, releaseName, payload.Namespace, upgradedRelease)
+	if err != nil {
+		return httperror.InternalServerError("Unable to label the resources of the release", err)
+	}
+
+	return response.JSON(w, upgradedRelease)
+}
+
+// labelHelmAppResources applies the portainer labels to the resources of a release,
+// the new resources of an upgraded or rolled back release are not labeled by helm
+func (handler *Handler) labelHelmAppResources(r *http.Request, releaseName, namespace string, rel *release.Release) error {
+	manifest, err := handler.applyPortainerLabelsToHelmAppManifest(r, releaseName, rel.Manifest)
+	if err != nil {
+		return err
+	}
+
+	return handler.updateHelmAppManifest(r, manifest, namespace)
+}
@@ -0,0 +1,108 @@
+package helm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/exec/exectest"
+	"github.com/portainer/portainer/api/http/security"
+	helper "github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/portainer/portainer/api/jwt"
+	"github.com/portainer/portainer/api/kubernetes"
+	"github.com/portainer/portainer/pkg/libhelm/binary/test"
+	"github.com/portainer/portainer/pkg/libhelm/options"
+	"github.com/portainer/portainer/pkg/libhelm/release"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_helmUpgradeHistoryRollback(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	err := store.Endpoint().Create(&portainer.Endpoint{ID: 1})
+	is.NoError(err, "error creating environment")
+
+	err = store.User().Create(&portainer.User{Username: "admin", Role: portainer.AdministratorRole})
+	is.NoError(err, "error creating a user")
+
+	jwtService, err := jwt.NewService("1h", store)
+	is.NoError(err, "Error initiating jwt service")
+
+	kubernetesDeployer := exectest.NewKubernetesDeployer()
+	helmPackageManager := test.NewMockHelmBinaryPackageManager("")
+	kubeClusterAccessService := kubernetes.NewKubeClusterAccessService("", "", "")
+	h := NewHandler(helper.NewTestRequestBouncer(), store, jwtService, kubernetesDeployer, helmPackageManager, kubeClusterAccessService)
+
+	_, err = h.helmPackageManager.Install(options.InstallOptions{Name: "nginx-upgrade", Chart: "nginx", Namespace: "default"})
+	is.NoError(err)
+
+	serve := func(method, url string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		ctx := security.StoreTokenData(req, &portainer.TokenData{ID: 1, Username: "admin", Role: 1})
+		req = req.WithContext(ctx)
+		req.Header.Add("Authorization", "Bearer dummytoken")
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	history := func() []release.ReleaseHistoryElement {
+		rr := serve(http.MethodGet, "/1/kubernetes/helm/nginx-upgrade/history?namespace=default", nil)
+		is.Equal(http.StatusOK, rr.Code, "Status should be 200")
+
+		var revisions []release.ReleaseHistoryElement
+		is.NoError(json.NewDecoder(rr.Body).Decode(&revisions), "response should be json")
+
+		return revisions
+	}
+
+	t.Run("helmUpgrade upgrades the release to a new chart", func(t *testing.T) {
+		payload, _ := json.Marshal(upgradeChartPayload{Namespace: "default", Chart: "nginx-2", Repo: "https://charts.bitnami.com/bitnami", Values: "replicaCount: 2"})
+		rr := serve(http.MethodPut, "/1/kubernetes/helm/nginx-upgrade", payload)
+		is.Equal(http.StatusOK, rr.Code, "Status should be 200")
+
+		resp := release.Release{}
+		is.NoError(json.NewDecoder(rr.Body).Decode(&resp), "response should be json")
+		is.Equal("nginx-upgrade", resp.Name)
+		is.Equal(2, resp.Version)
+	})
+
+	t.Run("helmUpgrade fails without chart", func(t *testing.T) {
+		payload, _ := json.Marshal(upgradeChartPayload{Namespace: "default", Repo: "https://charts.bitnami.com/bitnami"})
+		rr := serve(http.MethodPut, "/1/kubernetes/helm/nginx-upgrade", payload)
+		is.Equal(http.StatusBadRequest, rr.Code, "Status should be 400")
+	})
+
+	t.Run("helmHistory lists the revisions", func(t *testing.T) {
+		revisions := history()
+		is.Len(revisions, 2)
+		is.Equal("superseded", revisions[0].Status)
+		is.Equal("nginx-2", revisions[1].Chart)
+		is.Equal("deployed", revisions[1].Status)
+	})
+
+	t.Run("helmRollback rolls back to the given revision", func(t *testing.T) {
+		rr := serve(http.MethodPost, fmt.Sprintf("/1/kubernetes/helm/nginx-upgrade/rollback?namespace=default&revision=%d", 1), nil)
+		is.Equal(http.StatusNoContent, rr.Code, "Status should be 204")
+
+		revisions := history()
+		is.Len(revisions, 3)
+		is.Equal("nginx", revisions[2].Chart)
+		is.Equal("Rollback to 1", revisions[2].Description)
+	})
+
+	t.Run("helmRollback fails for an unknown release", func(t *testing.T) {
+		rr := serve(http.MethodPost, "/1/kubernetes/helm/unknown/rollback?namespace=default", nil)
+		is.Equal(http.StatusInternalServerError, rr.Code, "Status should be 500")
+	})
+}
@@ -18,6 +18,7 @@ import (
 // @id GetKubernetesConfig
 // @summary Generates kubeconfig file enabling client communication with k8s api server
 // @description Generates kubeconfig file enabling client communication with k8s api server
+// @description The kubeconfig cannot be generated with an API key, its token would not be bound to the restrictions of the key.
 // @description **Access policy**: authenticated
 // @tags kubernetes
 // @security ApiKeyAuth
@@ -38,6 +39,11 @@ func (handler *Handler) getKubernetesConfig(w http.ResponseWriter, r *http.Reque
 	if err != nil {
 		return httperror.Forbidden("Permission denied to access environment", err)
 	}
+
+	if tokenData.APIKeyID != 0 {
+		return httperror.Forbidden("A kubeconfig cannot be generated with an API key", errors.New("kubeconfig generation is not allowed for API keys"))
+	}
+
 	bearerToken, err := handler.JwtService.GenerateTokenForKubeconfig(tokenData)
 	if err != nil {
 		return httperror.InternalServerError("Unable to generate JWT token", err)
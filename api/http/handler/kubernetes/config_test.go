@@ -0,0 +1,78 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/jwt"
+	"github.com/portainer/portainer/api/kubernetes"
+	kcli "github.com/portainer/portainer/api/kubernetes/cli"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clientV1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+func TestGetKubernetesConfig_APIKey(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	user := &portainer.User{ID: 1, Username: "admin", Role: portainer.AdministratorRole}
+	require.NoError(t, store.User().Create(user))
+	require.NoError(t, store.Endpoint().Create(&portainer.Endpoint{ID: 1, Name: "cluster", Type: portainer.KubernetesLocalEnvironment}))
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+
+	apiKeyService := apikey.NewAPIKeyService(store.APIKeyRepository(), store.User())
+	bouncer := security.NewRequestBouncer(store, jwtService, apiKeyService)
+
+	factory, err := kcli.NewClientFactory(nil, nil, store, "instance", "", "")
+	require.NoError(t, err)
+
+	h := NewHandler(bouncer, nil, store, jwtService, kubernetes.NewKubeClusterAccessService("", ":9443", ""), factory, nil)
+
+	getConfig := func(setAuth func(req *http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/kubernetes/config", nil)
+		setAuth(req)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	t.Run("a user session gets a kubeconfig", func(t *testing.T) {
+		token, err := jwtService.GenerateToken(&portainer.TokenData{ID: user.ID, Username: user.Username, Role: user.Role})
+		require.NoError(t, err)
+
+		rr := getConfig(func(req *http.Request) {
+			req.Header.Add("Authorization", "Bearer "+token)
+		})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		var config clientV1.Config
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&config))
+		require.Len(t, config.AuthInfos, 1)
+		is.NotEmpty(config.AuthInfos[0].AuthInfo.Token)
+	})
+
+	t.Run("a read-only API key cannot get an unrestricted token", func(t *testing.T) {
+		rawAPIKey, _, err := apiKeyService.GenerateRestrictedApiKey(*user, "monitoring", portainer.APIKeyRestrictions{ReadOnly: true})
+		require.NoError(t, err)
+
+		rr := getConfig(func(req *http.Request) {
+			req.Header.Add("x-api-key", rawAPIKey)
+		})
+		is.Equal(http.StatusForbidden, rr.Code)
+		is.NotContains(rr.Body.String(), `"users"`)
+	})
+}
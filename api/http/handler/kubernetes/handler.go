@@ -29,6 +29,7 @@ type Handler struct {
 	KubernetesClientFactory  *cli.ClientFactory
 	JwtService               dataservices.JWTService
 	kubeClusterAccessService kubernetes.KubeClusterAccessService
+	requestBouncer           *security.RequestBouncer
 }
 
 // NewHandler creates a handler to process pre-proxied requests to external APIs.
@@ -40,6 +41,7 @@ func NewHandler(bouncer *security.RequestBouncer, authorizationService *authoriz
 		JwtService:               jwtService,
 		kubeClusterAccessService: kubeClusterAccessService,
 		KubernetesClientFactory:  kubernetesClientFactory,
+		requestBouncer:           bouncer,
 	}
 
 	kubeRouter := h.PathPrefix("/kubernetes").Subrouter()
@@ -139,6 +141,17 @@ func (handler *Handler) kubeClient(next http.Handler) http.Handler {
 			return
 		}
 
+		err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+		if err != nil {
+			httperror.WriteError(
+				w,
+				http.StatusForbidden,
+				"Permission denied to access environment",
+				err,
+			)
+			return
+		}
+
 		if handler.KubernetesClientFactory == nil {
 			next.ServeHTTP(w, r)
 			return
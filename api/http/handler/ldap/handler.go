@@ -9,6 +9,7 @@ import (
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/filesystem"
 	"github.com/portainer/portainer/api/http/security"
+	portainerldap "github.com/portainer/portainer/api/ldap"
 )
 
 // Handler is the HTTP handler used to handle LDAP search Operations
@@ -17,6 +18,7 @@ type Handler struct {
 	DataStore   dataservices.DataStore
 	FileService portainer.FileService
 	LDAPService portainer.LDAPService
+	SyncService *portainerldap.SyncService
 }
 
 // NewHandler returns a new Handler
@@ -27,6 +29,10 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 
 	h.Handle("/ldap/check",
 		bouncer.AdminAccess(httperror.LoggerHandler(h.ldapCheck))).Methods(http.MethodPost)
+	h.Handle("/ldap/sync",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.ldapSync))).Methods(http.MethodPost)
+	h.Handle("/ldap/sync",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.ldapSyncReports))).Methods(http.MethodGet)
 
 	return h
 }
@@ -0,0 +1,53 @@
+package ldap
+
+import (
+	"errors"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainerldap "github.com/portainer/portainer/api/ldap"
+)
+
+// @id LDAPSync
+// @summary Synchronize the users with the LDAP directory
+// @description Creates, disables or removes the users provisioned from the LDAP directory and reconciles the memberships
+// @description of the teams named after a LDAP group. In dry-run mode, the changes are reported but not applied.
+// @description **Access policy**: administrator
+// @tags ldap
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param dryRun query boolean false "Only report the changes without applying them"
+// @success 200 {object} portainerldap.SyncReport "Success"
+// @failure 400 "LDAP authentication is not enabled"
+// @failure 500 "Server error"
+// @router /ldap/sync [post]
+func (handler *Handler) ldapSync(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	dryRun, _ := request.RetrieveBooleanQueryParameter(r, "dryRun", true)
+
+	report, err := handler.SyncService.Sync(dryRun)
+	if errors.Is(err, portainerldap.ErrSyncNotAvailable) {
+		return httperror.BadRequest("Unable to synchronize the users", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to synchronize the users with the LDAP directory", err)
+	}
+
+	return response.JSON(w, report)
+}
+
+// @id LDAPSyncReports
+// @summary List the LDAP synchronization reports
+// @description List the reports of the latest LDAP synchronizations, the most recent first.
+// @description **Access policy**: administrator
+// @tags ldap
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @success 200 {array} portainerldap.SyncReport "Success"
+// @failure 500 "Server error"
+// @router /ldap/sync [get]
+func (handler *Handler) ldapSyncReports(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return response.JSON(w, handler.SyncService.Reports())
+}
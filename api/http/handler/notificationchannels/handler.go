@@ -0,0 +1,49 @@
+package notificationchannels
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/notifications"
+
+	"github.com/gorilla/mux"
+)
+
+// Handler is the HTTP handler used to handle notification channel operations.
+type Handler struct {
+	*mux.Router
+	DataStore           dataservices.DataStore
+	NotificationService *notifications.Service
+}
+
+// NewHandler creates a handler to manage notification channel operations.
+func NewHandler(bouncer *security.RequestBouncer, dataStore dataservices.DataStore, notificationService *notifications.Service) *Handler {
+	h := &Handler{
+		Router:              mux.NewRouter(),
+		DataStore:           dataStore,
+		NotificationService: notificationService,
+	}
+
+	h.Handle("/notification_channels",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.notificationChannelCreate))).Methods(http.MethodPost)
+	h.Handle("/notification_channels",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.notificationChannelList))).Methods(http.MethodGet)
+	h.Handle("/notification_channels/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.notificationChannelInspect))).Methods(http.MethodGet)
+	h.Handle("/notification_channels/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.notificationChannelUpdate))).Methods(http.MethodPut)
+	h.Handle("/notification_channels/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.notificationChannelDelete))).Methods(http.MethodDelete)
+	h.Handle("/notification_channels/{id}/test",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.notificationChannelTest))).Methods(http.MethodPost)
+
+	return h
+}
+
+func hideFields(channel *portainer.NotificationChannel) {
+	channel.Secret = ""
+	channel.SMTP.Password = ""
+}
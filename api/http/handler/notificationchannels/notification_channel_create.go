@@ -0,0 +1,154 @@
+package notificationchannels
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/asaskevich/govalidator"
+)
+
+type notificationChannelCreatePayload struct {
+	// Name of the notification channel
+	Name string `validate:"required" example:"ops-alerts"`
+	// Channel type (1 - webhook, 2 - slack, 3 - email)
+	Type portainer.NotificationChannelType `validate:"required" example:"1" enums:"1,2,3"`
+	// Whether events are delivered to this channel
+	Enabled bool `example:"true"`
+	// Events delivered to this channel, every event is delivered when empty
+	Events []portainer.NotificationEventType `example:"endpoint.down"`
+	// URL receiving the notification (webhook and slack channels)
+	URL string `example:"https://hooks.mydomain.tld/portainer"`
+	// Secret used to sign webhook payloads with HMAC-SHA256
+	Secret string `example:"my-secret"`
+	// SMTP relay configuration (email channels)
+	SMTP portainer.NotificationSMTPSettings
+	// Number of delivery retries before an event is dropped
+	MaxRetries int `example:"3"`
+}
+
+var supportedEvents = map[portainer.NotificationEventType]bool{
+	portainer.NotificationEventStackDeployFailed: true,
+	portainer.NotificationEventEndpointDown:      true,
+	portainer.NotificationEventEdgeStackError:    true,
+	portainer.NotificationEventAuthFailed:        true,
+}
+
+func (payload *notificationChannelCreatePayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Name) {
+		return errors.New("Invalid notification channel name")
+	}
+
+	return validateChannel(payload.Type, payload.URL, payload.Events, &payload.SMTP, payload.MaxRetries)
+}
+
+func validateChannel(channelType portainer.NotificationChannelType, url string, events []portainer.NotificationEventType, smtp *portainer.NotificationSMTPSettings, maxRetries int) error {
+	switch channelType {
+	case portainer.WebhookNotificationChannel, portainer.SlackNotificationChannel:
+		if !govalidator.IsURL(url) {
+			return errors.New("Invalid notification channel URL")
+		}
+	case portainer.EmailNotificationChannel:
+		if govalidator.IsNull(smtp.Host) {
+			return errors.New("Invalid SMTP host")
+		}
+
+		if !govalidator.IsEmail(smtp.From) {
+			return errors.New("Invalid SMTP sender address")
+		}
+
+		if len(smtp.To) == 0 {
+			return errors.New("At least one SMTP recipient is required")
+		}
+
+		for _, to := range smtp.To {
+			if !govalidator.IsEmail(to) {
+				return fmt.Errorf("Invalid SMTP recipient address: %s", to)
+			}
+		}
+	default:
+		return errors.New("Invalid notification channel type. Valid values are: 1 (webhook), 2 (slack) or 3 (email)")
+	}
+
+	for _, event := range events {
+		if !supportedEvents[event] {
+			return fmt.Errorf("Unsupported event type: %s", event)
+		}
+	}
+
+	if maxRetries < 0 {
+		return errors.New("Invalid maximum number of retries")
+	}
+
+	return nil
+}
+
+// @id NotificationChannelCreate
+// @summary Create a new notification channel
+// @description Create a new notification channel.
+// @description **Access policy**: administrator
+// @tags notification_channels
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @produce json
+// @param body body notificationChannelCreatePayload true "Notification channel details"
+// @success 200 {object} portainer.NotificationChannel "Success"
+// @failure 400 "Invalid request"
+// @failure 409 "Notification channel name exists"
+// @failure 500 "Server error"
+// @router /notification_channels [post]
+func (handler *Handler) notificationChannelCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload notificationChannelCreatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	unique, err := handler.isUniqueName(payload.Name, 0)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve notification channels from the database", err)
+	}
+	if !unique {
+		return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: "A notification channel with the same name already exists", Err: errors.New("name is not unique")}
+	}
+
+	channel := &portainer.NotificationChannel{
+		Name:       payload.Name,
+		Type:       payload.Type,
+		Enabled:    payload.Enabled,
+		Events:     payload.Events,
+		URL:        payload.URL,
+		Secret:     payload.Secret,
+		SMTP:       payload.SMTP,
+		MaxRetries: payload.MaxRetries,
+	}
+
+	err = handler.DataStore.NotificationChannel().Create(channel)
+	if err != nil {
+		return httperror.InternalServerError("Unable to persist the notification channel inside the database", err)
+	}
+
+	hideFields(channel)
+	return response.JSON(w, channel)
+}
+
+func (handler *Handler) isUniqueName(name string, channelID portainer.NotificationChannelID) (bool, error) {
+	channels, err := handler.DataStore.NotificationChannel().NotificationChannels()
+	if err != nil {
+		return false, err
+	}
+
+	for _, channel := range channels {
+		if channel.Name == name && channel.ID != channelID {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,44 @@
+package notificationchannels
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+)
+
+// @id NotificationChannelDelete
+// @summary Remove a notification channel
+// @description Remove a notification channel.
+// @description **Access policy**: administrator
+// @tags notification_channels
+// @security ApiKeyAuth
+// @security jwt
+// @param id path int true "Notification channel identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Notification channel not found"
+// @failure 500 "Server error"
+// @router /notification_channels/{id} [delete]
+func (handler *Handler) notificationChannelDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	channelID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid notification channel identifier route variable", err)
+	}
+
+	_, err = handler.DataStore.NotificationChannel().NotificationChannel(portainer.NotificationChannelID(channelID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find a notification channel with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find a notification channel with the specified identifier inside the database", err)
+	}
+
+	err = handler.DataStore.NotificationChannel().DeleteNotificationChannel(portainer.NotificationChannelID(channelID))
+	if err != nil {
+		return httperror.InternalServerError("Unable to remove the notification channel from the database", err)
+	}
+
+	return response.Empty(w)
+}
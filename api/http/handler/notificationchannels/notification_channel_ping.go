@@ -0,0 +1,49 @@
+package notificationchannels
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/notifications"
+)
+
+// @id NotificationChannelTest
+// @summary Send a test notification
+// @description Send a test event to a notification channel, without retry, and report the delivery error if any.
+// @description **Access policy**: administrator
+// @tags notification_channels
+// @security ApiKeyAuth
+// @security jwt
+// @param id path int true "Notification channel identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Notification channel not found"
+// @failure 502 "Unable to deliver the notification"
+// @failure 500 "Server error"
+// @router /notification_channels/{id}/test [post]
+func (handler *Handler) notificationChannelTest(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	channelID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid notification channel identifier route variable", err)
+	}
+
+	channel, err := handler.DataStore.NotificationChannel().NotificationChannel(portainer.NotificationChannelID(channelID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find a notification channel with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find a notification channel with the specified identifier inside the database", err)
+	}
+
+	err = handler.NotificationService.Send(channel, notifications.Event{
+		Type:    "test",
+		Message: "this is a test notification sent from Portainer",
+	})
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadGateway, Message: "Unable to deliver the notification", Err: err}
+	}
+
+	return response.Empty(w)
+}
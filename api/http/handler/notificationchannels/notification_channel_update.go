@@ -0,0 +1,126 @@
+package notificationchannels
+
+import (
+	"errors"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+)
+
+type notificationChannelUpdatePayload struct {
+	// Name of the notification channel
+	Name *string `example:"ops-alerts"`
+	// Whether events are delivered to this channel
+	Enabled *bool `example:"true"`
+	// Events delivered to this channel, every event is delivered when empty
+	Events []portainer.NotificationEventType `example:"endpoint.down"`
+	// URL receiving the notification (webhook and slack channels)
+	URL *string `example:"https://hooks.mydomain.tld/portainer"`
+	// Secret used to sign webhook payloads with HMAC-SHA256, the current secret is kept when omitted
+	Secret *string `example:"my-secret"`
+	// SMTP relay configuration (email channels), the current password is kept when omitted
+	SMTP *portainer.NotificationSMTPSettings
+	// Number of delivery retries before an event is dropped
+	MaxRetries *int `example:"3"`
+}
+
+func (payload *notificationChannelUpdatePayload) Validate(r *http.Request) error {
+	if payload.Name != nil && *payload.Name == "" {
+		return errors.New("Invalid notification channel name")
+	}
+
+	return nil
+}
+
+// @id NotificationChannelUpdate
+// @summary Update a notification channel
+// @description Update a notification channel.
+// @description **Access policy**: administrator
+// @tags notification_channels
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Notification channel identifier"
+// @param body body notificationChannelUpdatePayload true "Notification channel details"
+// @success 200 {object} portainer.NotificationChannel "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Notification channel not found"
+// @failure 409 "Notification channel name exists"
+// @failure 500 "Server error"
+// @router /notification_channels/{id} [put]
+func (handler *Handler) notificationChannelUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	channelID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid notification channel identifier route variable", err)
+	}
+
+	var payload notificationChannelUpdatePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	channel, err := handler.DataStore.NotificationChannel().NotificationChannel(portainer.NotificationChannelID(channelID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find a notification channel with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find a notification channel with the specified identifier inside the database", err)
+	}
+
+	if payload.Name != nil {
+		unique, err := handler.isUniqueName(*payload.Name, channel.ID)
+		if err != nil {
+			return httperror.InternalServerError("Unable to retrieve notification channels from the database", err)
+		}
+		if !unique {
+			return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: "A notification channel with the same name already exists", Err: errors.New("name is not unique")}
+		}
+
+		channel.Name = *payload.Name
+	}
+
+	if payload.Enabled != nil {
+		channel.Enabled = *payload.Enabled
+	}
+
+	if payload.Events != nil {
+		channel.Events = payload.Events
+	}
+
+	if payload.URL != nil {
+		channel.URL = *payload.URL
+	}
+
+	if payload.Secret != nil {
+		channel.Secret = *payload.Secret
+	}
+
+	if payload.SMTP != nil {
+		password := channel.SMTP.Password
+		channel.SMTP = *payload.SMTP
+		if channel.SMTP.Password == "" {
+			channel.SMTP.Password = password
+		}
+	}
+
+	if payload.MaxRetries != nil {
+		channel.MaxRetries = *payload.MaxRetries
+	}
+
+	err = validateChannel(channel.Type, channel.URL, channel.Events, &channel.SMTP, channel.MaxRetries)
+	if err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	err = handler.DataStore.NotificationChannel().UpdateNotificationChannel(channel.ID, channel)
+	if err != nil {
+		return httperror.InternalServerError("Unable to persist the notification channel changes inside the database", err)
+	}
+
+	hideFields(channel)
+	return response.JSON(w, channel)
+}
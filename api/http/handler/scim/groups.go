@@ -0,0 +1,620 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	portainer "github.com/portainer/portainer/api"
+)
+
+// memberPathRegex matches the member paths of the remove operations, such as members[value eq "2"]
+var memberPathRegex = regexp.MustCompile(`^(?i:members)\[\s*(?i:value)\s+(?i:eq)\s+"([^"]*)"\s*\]$`)
+
+type scimGroup struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []scimMember `json:"members"`
+	Meta        *scimMeta    `json:"meta,omitempty"`
+}
+
+func (payload *scimGroup) validate() error {
+	if strings.TrimSpace(payload.DisplayName) == "" {
+		return fmt.Errorf("%w: displayName is required", errInvalidValue)
+	}
+
+	return nil
+}
+
+func (handler *Handler) toSCIMGroup(r *http.Request, team *portainer.Team, usernames map[portainer.UserID]string) (scimGroup, error) {
+	id := strconv.Itoa(int(team.ID))
+
+	memberships, err := handler.dataStore.TeamMembership().TeamMembershipsByTeamID(team.ID)
+	if err != nil {
+		return scimGroup{}, err
+	}
+
+	members := []scimMember{}
+	for _, membership := range memberships {
+		members = append(members, scimMember{
+			Value:   strconv.Itoa(int(membership.UserID)),
+			Display: usernames[membership.UserID],
+		})
+	}
+
+	return scimGroup{
+		Schemas:     []string{groupSchema},
+		ID:          id,
+		DisplayName: team.Name,
+		Members:     members,
+		Meta:        &scimMeta{ResourceType: "Group", Location: resourceLocation(r, "Groups", id)},
+	}, nil
+}
+
+func (handler *Handler) usernames() (map[portainer.UserID]string, error) {
+	users, err := handler.dataStore.User().Users()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[portainer.UserID]string, len(users))
+	for _, user := range users {
+		names[user.ID] = user.Username
+	}
+
+	return names, nil
+}
+
+// retrieveTeam returns the team identified by the id route variable
+func (handler *Handler) retrieveTeam(r *http.Request) (*portainer.Team, *httperror.HandlerError) {
+	id, _ := request.RetrieveRouteVariableValue(r, "id")
+
+	teamID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, httperror.NotFound("Unable to find a team with the specified identifier", err)
+	}
+
+	team, err := handler.dataStore.Team().Team(portainer.TeamID(teamID))
+	if handler.dataStore.IsErrObjectNotFound(err) {
+		return nil, httperror.NotFound("Unable to find a team with the specified identifier", err)
+	} else if err != nil {
+		return nil, httperror.InternalServerError("Unable to find a team with the specified identifier inside the database", err)
+	}
+
+	return team, nil
+}
+
+func (handler *Handler) writeGroup(w http.ResponseWriter, r *http.Request, statusCode int, team *portainer.Team) *httperror.HandlerError {
+	usernames, err := handler.usernames()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the users from the database", err)
+	}
+
+	resource, err := handler.toSCIMGroup(r, team, usernames)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the team memberships from the database", err)
+	}
+
+	return writeJSON(w, statusCode, resource)
+}
+
+// @id SCIMGroupList
+// @summary List the teams
+// @description List the teams as SCIM groups, the groups can be filtered with an equality filter on displayName or id.
+// @description **Access policy**: SCIM token
+// @tags scim
+// @produce json
+// @param filter query string false "SCIM filter, such as displayName eq \"developers\""
+// @param startIndex query int false "1-based index of the first result"
+// @param count query int false "Maximum number of results"
+// @success 200 "Success"
+// @failure 400 "Invalid filter"
+// @failure 401 "Invalid SCIM token"
+// @failure 500 "Server error"
+// @router /scim/v2/Groups [get]
+func (handler *Handler) groupList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	filter, handlerErr := parseFilter(r, "displayName", "id")
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	teams, err := handler.dataStore.Team().Teams()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the teams from the database", err)
+	}
+
+	sort.Slice(teams, func(i, j int) bool { return teams[i].ID < teams[j].ID })
+
+	usernames, err := handler.usernames()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the users from the database", err)
+	}
+
+	resources := []scimGroup{}
+	for i := range teams {
+		team := &teams[i]
+
+		if filter != nil {
+			if filter.attribute == "displayName" && !strings.EqualFold(team.Name, filter.value) {
+				continue
+			}
+
+			if filter.attribute == "id" && strconv.Itoa(int(team.ID)) != filter.value {
+				continue
+			}
+		}
+
+		resource, err := handler.toSCIMGroup(r, team, usernames)
+		if err != nil {
+			return httperror.InternalServerError("Unable to retrieve the team memberships from the database", err)
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return writeJSON(w, http.StatusOK, paginate(r, resources))
+}
+
+// @id SCIMGroupInspect
+// @summary Inspect a team
+// @description Retrieve a team as a SCIM group.
+// @description **Access policy**: SCIM token
+// @tags scim
+// @produce json
+// @param id path int true "Team identifier"
+// @success 200 "Success"
+// @failure 401 "Invalid SCIM token"
+// @failure 404 "Team not found"
+// @failure 500 "Server error"
+// @router /scim/v2/Groups/{id} [get]
+func (handler *Handler) groupInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	team, handlerErr := handler.retrieveTeam(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	return handler.writeGroup(w, r, http.StatusOK, team)
+}
+
+// @id SCIMGroupCreate
+// @summary Provision a team
+// @description Create a team and its memberships from a SCIM group.
+// @description **Access policy**: SCIM token
+// @tags scim
+// @accept json
+// @produce json
+// @success 201 "Created"
+// @failure 400 "Invalid request"
+// @failure 401 "Invalid SCIM token"
+// @failure 409 "Team already exists"
+// @failure 500 "Server error"
+// @router /scim/v2/Groups [post]
+func (handler *Handler) groupCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload scimGroup
+	if handlerErr := decodeJSON(r, &payload); handlerErr != nil {
+		return handlerErr
+	}
+
+	if err := payload.validate(); err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	userIDs, handlerErr := handler.memberIDs(payload.Members)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	team := &portainer.Team{Name: payload.DisplayName}
+	if handlerErr := handler.setTeamName(team, payload.DisplayName); handlerErr != nil {
+		return handlerErr
+	}
+
+	err := handler.dataStore.Team().Create(team)
+	if err != nil {
+		return httperror.InternalServerError("Unable to persist the team inside the database", err)
+	}
+
+	if handlerErr := handler.addMembers(team, userIDs); handlerErr != nil {
+		return handlerErr
+	}
+
+	return handler.writeGroup(w, r, http.StatusCreated, team)
+}
+
+// @id SCIMGroupReplace
+// @summary Replace a team
+// @description Replace the name and the members of a team.
+// @description **Access policy**: SCIM token
+// @tags scim
+// @accept json
+// @produce json
+// @param id path int true "Team identifier"
+// @success 200 "Success"
+// @failure 400 "Invalid request"
+// @failure 401 "Invalid SCIM token"
+// @failure 404 "Team not found"
+// @failure 409 "Team already exists"
+// @failure 500 "Server error"
+// @router /scim/v2/Groups/{id} [put]
+func (handler *Handler) groupReplace(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	team, handlerErr := handler.retrieveTeam(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	var payload scimGroup
+	if handlerErr := decodeJSON(r, &payload); handlerErr != nil {
+		return handlerErr
+	}
+
+	if err := payload.validate(); err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	userIDs, handlerErr := handler.memberIDs(payload.Members)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	if handlerErr := handler.updateTeamName(team, payload.DisplayName); handlerErr != nil {
+		return handlerErr
+	}
+
+	if handlerErr := handler.replaceMembers(team, userIDs); handlerErr != nil {
+		return handlerErr
+	}
+
+	return handler.writeGroup(w, r, http.StatusOK, team)
+}
+
+// @id SCIMGroupPatch
+// @summary Patch a team
+// @description Apply SCIM patch operations to the displayName and the members of a team.
+// @description **Access policy**: SCIM token
+// @tags scim
+// @accept json
+// @produce json
+// @param id path int true "Team identifier"
+// @success 200 "Success"
+// @failure 400 "Invalid request"
+// @failure 401 "Invalid SCIM token"
+// @failure 404 "Team not found"
+// @failure 409 "Team already exists"
+// @failure 500 "Server error"
+// @router /scim/v2/Groups/{id} [patch]
+func (handler *Handler) groupPatch(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	team, handlerErr := handler.retrieveTeam(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	var payload scimPatchRequest
+	if handlerErr := decodeJSON(r, &payload); handlerErr != nil {
+		return handlerErr
+	}
+
+	for _, operation := range payload.Operations {
+		if handlerErr := handler.applyGroupOperation(team, operation); handlerErr != nil {
+			return handlerErr
+		}
+	}
+
+	return handler.writeGroup(w, r, http.StatusOK, team)
+}
+
+func (handler *Handler) applyGroupOperation(team *portainer.Team, operation scimPatchOperation) *httperror.HandlerError {
+	op := strings.ToLower(operation.Op)
+
+	if op == "remove" {
+		if matches := memberPathRegex.FindStringSubmatch(operation.Path); matches != nil {
+			userIDs, handlerErr := handler.memberIDs([]scimMember{{Value: matches[1]}})
+			if handlerErr != nil {
+				return handlerErr
+			}
+
+			return handler.removeMembers(team, userIDs)
+		}
+
+		if !strings.EqualFold(operation.Path, "members") {
+			return httperror.BadRequest("Unsupported patch operation", fmt.Errorf("%w: unsupported path %q", errInvalidValue, operation.Path))
+		}
+
+		// removes the listed members, or every member when no value is given
+		if len(operation.Value) == 0 || string(operation.Value) == "null" {
+			return handler.replaceMembers(team, nil)
+		}
+
+		members, handlerErr := parseMembers(operation.Value)
+		if handlerErr != nil {
+			return handlerErr
+		}
+
+		userIDs, handlerErr := handler.memberIDs(members)
+		if handlerErr != nil {
+			return handlerErr
+		}
+
+		return handler.removeMembers(team, userIDs)
+	}
+
+	if op != "add" && op != "replace" {
+		return httperror.BadRequest("Unsupported patch operation", fmt.Errorf("%w: unsupported operation %q", errInvalidValue, operation.Op))
+	}
+
+	attributes := map[string]json.RawMessage{}
+	if operation.Path == "" {
+		err := json.Unmarshal(operation.Value, &attributes)
+		if err != nil {
+			return httperror.BadRequest("Invalid patch operation", fmt.Errorf("%w: %s", errInvalidValue, err))
+		}
+	} else {
+		attributes[operation.Path] = operation.Value
+	}
+
+	for attribute, value := range attributes {
+		switch {
+		case strings.EqualFold(attribute, "displayName"):
+			name, err := parseString(value)
+			if err != nil {
+				return httperror.BadRequest("Invalid displayName", err)
+			}
+
+			if handlerErr := handler.updateTeamName(team, name); handlerErr != nil {
+				return handlerErr
+			}
+
+		case strings.EqualFold(attribute, "members"):
+			members, handlerErr := parseMembers(value)
+			if handlerErr != nil {
+				return handlerErr
+			}
+
+			userIDs, handlerErr := handler.memberIDs(members)
+			if handlerErr != nil {
+				return handlerErr
+			}
+
+			if op == "add" {
+				handlerErr = handler.addMembers(team, userIDs)
+			} else {
+				handlerErr = handler.replaceMembers(team, userIDs)
+			}
+
+			if handlerErr != nil {
+				return handlerErr
+			}
+		}
+	}
+
+	return nil
+}
+
+// @id SCIMGroupDelete
+// @summary Deprovision a team
+// @description Remove a team and its memberships.
+// @description **Access policy**: SCIM token
+// @tags scim
+// @param id path int true "Team identifier"
+// @success 204 "Success"
+// @failure 401 "Invalid SCIM token"
+// @failure 404 "Team not found"
+// @failure 500 "Server error"
+// @router /scim/v2/Groups/{id} [delete]
+func (handler *Handler) groupDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	team, handlerErr := handler.retrieveTeam(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	err := handler.dataStore.Team().DeleteTeam(team.ID)
+	if err != nil {
+		return httperror.InternalServerError("Unable to delete the team from the database", err)
+	}
+
+	err = handler.dataStore.TeamMembership().DeleteTeamMembershipByTeamID(team.ID)
+	if err != nil {
+		return httperror.InternalServerError("Unable to delete associated team memberships from the database", err)
+	}
+
+	settings, err := handler.dataStore.Settings().Settings()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the settings from the database", err)
+	}
+
+	if settings.OAuthSettings.DefaultTeamID == team.ID {
+		settings.OAuthSettings.DefaultTeamID = 0
+
+		err = handler.dataStore.Settings().UpdateSettings(settings)
+		if err != nil {
+			return httperror.InternalServerError("Unable to reset the default team", err)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func parseMembers(value json.RawMessage) ([]scimMember, *httperror.HandlerError) {
+	var members []scimMember
+
+	err := json.Unmarshal(value, &members)
+	if err != nil {
+		return nil, httperror.BadRequest("Invalid members", fmt.Errorf("%w: %s", errInvalidValue, err))
+	}
+
+	return members, nil
+}
+
+// memberIDs returns the identifiers of the users referenced by the members, only the users provisioned
+// through SCIM can be members
+func (handler *Handler) memberIDs(members []scimMember) ([]portainer.UserID, *httperror.HandlerError) {
+	userIDs := make([]portainer.UserID, 0, len(members))
+
+	for _, member := range members {
+		id, err := strconv.Atoi(member.Value)
+		if err != nil {
+			return nil, httperror.BadRequest("Invalid member", fmt.Errorf("%w: unknown user %q", errInvalidValue, member.Value))
+		}
+
+		user, err := handler.dataStore.User().User(portainer.UserID(id))
+		if handler.dataStore.IsErrObjectNotFound(err) {
+			return nil, httperror.BadRequest("Invalid member", fmt.Errorf("%w: unknown user %q", errInvalidValue, member.Value))
+		} else if err != nil {
+			return nil, httperror.InternalServerError("Unable to find a user with the specified identifier inside the database", err)
+		}
+
+		if !isManagedUser(user) {
+			return nil, httperror.Forbidden("The user is not provisioned through SCIM", errNotManaged)
+		}
+
+		userIDs = append(userIDs, portainer.UserID(id))
+	}
+
+	return userIDs, nil
+}
+
+// setTeamName sets the name of a team after verifying that no other team uses it
+func (handler *Handler) setTeamName(team *portainer.Team, name string) *httperror.HandlerError {
+	if strings.TrimSpace(name) == "" {
+		return httperror.BadRequest("Invalid displayName", fmt.Errorf("%w: displayName cannot be empty", errInvalidValue))
+	}
+
+	existingTeam, err := handler.dataStore.Team().TeamByName(name)
+	if err != nil && !handler.dataStore.IsErrObjectNotFound(err) {
+		return httperror.InternalServerError("Unable to verify team name uniqueness", err)
+	}
+
+	if existingTeam != nil && existingTeam.ID != team.ID {
+		return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: "A team with the same name already exists", Err: errUniqueness}
+	}
+
+	team.Name = name
+
+	return nil
+}
+
+func (handler *Handler) updateTeamName(team *portainer.Team, name string) *httperror.HandlerError {
+	if name == team.Name {
+		return nil
+	}
+
+	if handlerErr := handler.setTeamName(team, name); handlerErr != nil {
+		return handlerErr
+	}
+
+	err := handler.dataStore.Team().UpdateTeam(team.ID, team)
+	if err != nil {
+		return httperror.InternalServerError("Unable to persist team changes inside the database", err)
+	}
+
+	return nil
+}
+
+func (handler *Handler) teamMembers(team *portainer.Team) (map[portainer.UserID]portainer.TeamMembership, *httperror.HandlerError) {
+	memberships, err := handler.dataStore.TeamMembership().TeamMembershipsByTeamID(team.ID)
+	if err != nil {
+		return nil, httperror.InternalServerError("Unable to retrieve the team memberships from the database", err)
+	}
+
+	members := make(map[portainer.UserID]portainer.TeamMembership, len(memberships))
+	for _, membership := range memberships {
+		members[membership.UserID] = membership
+	}
+
+	return members, nil
+}
+
+func (handler *Handler) addMembers(team *portainer.Team, userIDs []portainer.UserID) *httperror.HandlerError {
+	members, handlerErr := handler.teamMembers(team)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	for _, userID := range userIDs {
+		if _, ok := members[userID]; ok {
+			continue
+		}
+
+		membership := portainer.TeamMembership{
+			UserID: userID,
+			TeamID: team.ID,
+			Role:   portainer.TeamMember,
+		}
+
+		err := handler.dataStore.TeamMembership().Create(&membership)
+		if err != nil {
+			return httperror.InternalServerError("Unable to persist the team membership inside the database", err)
+		}
+
+		members[userID] = membership
+	}
+
+	return nil
+}
+
+func (handler *Handler) removeMembers(team *portainer.Team, userIDs []portainer.UserID) *httperror.HandlerError {
+	members, handlerErr := handler.teamMembers(team)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	for _, userID := range userIDs {
+		membership, ok := members[userID]
+		if !ok {
+			continue
+		}
+
+		err := handler.dataStore.TeamMembership().DeleteTeamMembership(membership.ID)
+		if err != nil {
+			return httperror.InternalServerError("Unable to remove the team membership from the database", err)
+		}
+
+		delete(members, userID)
+	}
+
+	return nil
+}
+
+// replaceMembers sets the members of a team, the team leaders that are kept remain leaders.
+// The members that are not provisioned through SCIM are kept.
+func (handler *Handler) replaceMembers(team *portainer.Team, userIDs []portainer.UserID) *httperror.HandlerError {
+	members, handlerErr := handler.teamMembers(team)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	users, err := handler.dataStore.User().Users()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the users from the database", err)
+	}
+
+	managed := make(map[portainer.UserID]bool, len(users))
+	for i := range users {
+		managed[users[i].ID] = isManagedUser(&users[i])
+	}
+
+	kept := make(map[portainer.UserID]bool, len(userIDs))
+	for _, userID := range userIDs {
+		kept[userID] = true
+	}
+
+	removed := []portainer.UserID{}
+	for userID := range members {
+		if !kept[userID] && managed[userID] {
+			removed = append(removed, userID)
+		}
+	}
+
+	if handlerErr := handler.removeMembers(team, removed); handlerErr != nil {
+		return handlerErr
+	}
+
+	return handler.addMembers(team, userIDs)
+}
@@ -0,0 +1,62 @@
+package scim
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+// Handler is the HTTP handler used to provision the users and the teams with the SCIM 2.0 protocol
+type Handler struct {
+	*mux.Router
+	dataStore     dataservices.DataStore
+	apiKeyService apikey.APIKeyService
+}
+
+// NewHandler creates a handler to provision the users and the teams with SCIM.
+// The SCIM resources are authenticated with the SCIM bearer token instead of the Portainer authentication.
+func NewHandler(bouncer *security.RequestBouncer, dataStore dataservices.DataStore, apiKeyService apikey.APIKeyService) *Handler {
+	h := &Handler{
+		Router:        mux.NewRouter(),
+		dataStore:     dataStore,
+		apiKeyService: apiKeyService,
+	}
+
+	h.Handle("/scim/token",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.scimTokenCreate))).Methods(http.MethodPost)
+
+	h.Handle("/scim/v2/ServiceProviderConfig",
+		bouncer.PublicAccess(h.scimAccess(h.serviceProviderConfig))).Methods(http.MethodGet)
+
+	h.Handle("/scim/v2/Users",
+		bouncer.PublicAccess(h.scimAccess(h.userList))).Methods(http.MethodGet)
+	h.Handle("/scim/v2/Users",
+		bouncer.PublicAccess(h.scimAccess(h.userCreate))).Methods(http.MethodPost)
+	h.Handle("/scim/v2/Users/{id}",
+		bouncer.PublicAccess(h.scimAccess(h.userInspect))).Methods(http.MethodGet)
+	h.Handle("/scim/v2/Users/{id}",
+		bouncer.PublicAccess(h.scimAccess(h.userReplace))).Methods(http.MethodPut)
+	h.Handle("/scim/v2/Users/{id}",
+		bouncer.PublicAccess(h.scimAccess(h.userPatch))).Methods(http.MethodPatch)
+	h.Handle("/scim/v2/Users/{id}",
+		bouncer.PublicAccess(h.scimAccess(h.userDelete))).Methods(http.MethodDelete)
+
+	h.Handle("/scim/v2/Groups",
+		bouncer.PublicAccess(h.scimAccess(h.groupList))).Methods(http.MethodGet)
+	h.Handle("/scim/v2/Groups",
+		bouncer.PublicAccess(h.scimAccess(h.groupCreate))).Methods(http.MethodPost)
+	h.Handle("/scim/v2/Groups/{id}",
+		bouncer.PublicAccess(h.scimAccess(h.groupInspect))).Methods(http.MethodGet)
+	h.Handle("/scim/v2/Groups/{id}",
+		bouncer.PublicAccess(h.scimAccess(h.groupReplace))).Methods(http.MethodPut)
+	h.Handle("/scim/v2/Groups/{id}",
+		bouncer.PublicAccess(h.scimAccess(h.groupPatch))).Methods(http.MethodPatch)
+	h.Handle("/scim/v2/Groups/{id}",
+		bouncer.PublicAccess(h.scimAccess(h.groupDelete))).Methods(http.MethodDelete)
+
+	return h
+}
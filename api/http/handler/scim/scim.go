@@ -0,0 +1,306 @@
+package scim
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	userSchema                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	groupSchema                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	listResponseSchema          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	patchOpSchema               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	errorSchema                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	serviceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+	contentType = "application/scim+json"
+)
+
+var (
+	errInvalidFilter = errors.New("invalid filter, only the eq operator is supported")
+	errInvalidValue  = errors.New("invalid value")
+	errUniqueness    = errors.New("resource already exists")
+	errNotManaged    = errors.New("the user is not provisioned through SCIM")
+
+	filterRegex = regexp.MustCompile(`^\s*([A-Za-z][\w.]*)\s+(?i:eq)\s+"((?:[^"\\]|\\.)*)"\s*$`)
+)
+
+type (
+	scimMeta struct {
+		ResourceType string `json:"resourceType"`
+		Location     string `json:"location"`
+	}
+
+	// scimMember is a reference to a user from a group or to a group from a user
+	scimMember struct {
+		Value   string `json:"value"`
+		Display string `json:"display,omitempty"`
+	}
+
+	scimListResponse struct {
+		Schemas      []string    `json:"schemas"`
+		TotalResults int         `json:"totalResults"`
+		StartIndex   int         `json:"startIndex"`
+		ItemsPerPage int         `json:"itemsPerPage"`
+		Resources    interface{} `json:"Resources"`
+	}
+
+	scimPatchOperation struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	}
+
+	scimPatchRequest struct {
+		Schemas    []string             `json:"schemas"`
+		Operations []scimPatchOperation `json:"Operations"`
+	}
+
+	scimErrorResponse struct {
+		Schemas  []string `json:"schemas"`
+		Status   string   `json:"status"`
+		ScimType string   `json:"scimType,omitempty"`
+		Detail   string   `json:"detail,omitempty"`
+	}
+
+	// scimFilter is an equality filter on a single attribute
+	scimFilter struct {
+		attribute string
+		value     string
+	}
+)
+
+// scimAccess authenticates the request with the SCIM bearer token and writes the errors in the SCIM format
+func (handler *Handler) scimAccess(next httperror.LoggerHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerErr := handler.authenticate(r)
+		if handlerErr == nil {
+			handlerErr = next(w, r)
+		}
+
+		if handlerErr != nil {
+			writeError(w, handlerErr)
+		}
+	})
+}
+
+func (handler *Handler) authenticate(r *http.Request) *httperror.HandlerError {
+	settings, err := handler.dataStore.Settings().Settings()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the settings from the database", err)
+	}
+
+	if !settings.SCIMSettings.Enabled || settings.SCIMSettings.TokenDigest == "" {
+		return httperror.Forbidden("SCIM provisioning is not enabled", httperrors.ErrUnauthorized)
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || !tokenMatches(token, settings.SCIMSettings.TokenDigest) {
+		return httperror.Unauthorized("Invalid SCIM token", httperrors.ErrUnauthorized)
+	}
+
+	return nil
+}
+
+// tokenDigest returns the digest of a SCIM token as stored in the settings
+func tokenDigest(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
+}
+
+func tokenMatches(token, digest string) bool {
+	return subtle.ConstantTimeCompare([]byte(tokenDigest(token)), []byte(digest)) == 1
+}
+
+func writeError(w http.ResponseWriter, handlerErr *httperror.HandlerError) {
+	if handlerErr.Err == nil {
+		handlerErr.Err = errors.New(handlerErr.Message)
+	}
+
+	log.Debug().Err(handlerErr.Err).Int("status_code", handlerErr.StatusCode).Str("msg", handlerErr.Message).Msg("SCIM error")
+
+	scimType := ""
+	switch {
+	case errors.Is(handlerErr.Err, errInvalidFilter):
+		scimType = "invalidFilter"
+	case errors.Is(handlerErr.Err, errInvalidValue):
+		scimType = "invalidValue"
+	case errors.Is(handlerErr.Err, errUniqueness):
+		scimType = "uniqueness"
+	}
+
+	writeJSON(w, handlerErr.StatusCode, scimErrorResponse{
+		Schemas:  []string{errorSchema},
+		Status:   strconv.Itoa(handlerErr.StatusCode),
+		ScimType: scimType,
+		Detail:   handlerErr.Message + ": " + handlerErr.Err.Error(),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, data interface{}) *httperror.HandlerError {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+
+	err := json.NewEncoder(w).Encode(data)
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to write the SCIM response")
+	}
+
+	return nil
+}
+
+// decodeJSON decodes a SCIM request body, the identity providers do not always set a JSON content type
+func decodeJSON(r *http.Request, data interface{}) *httperror.HandlerError {
+	err := json.NewDecoder(r.Body).Decode(data)
+	if err != nil {
+		return httperror.BadRequest("Invalid request payload", fmt.Errorf("%w: %s", errInvalidValue, err))
+	}
+
+	return nil
+}
+
+// parseFilter parses the filter query parameter, only the equality filters on a single attribute are supported
+func parseFilter(r *http.Request, attributes ...string) (*scimFilter, *httperror.HandlerError) {
+	rawFilter, _ := request.RetrieveQueryParameter(r, "filter", true)
+	if rawFilter == "" {
+		return nil, nil
+	}
+
+	matches := filterRegex.FindStringSubmatch(rawFilter)
+	if matches == nil {
+		return nil, httperror.BadRequest("Unsupported filter", errInvalidFilter)
+	}
+
+	for _, attribute := range attributes {
+		if strings.EqualFold(attribute, matches[1]) {
+			value, err := strconv.Unquote(`"` + matches[2] + `"`)
+			if err != nil {
+				return nil, httperror.BadRequest("Invalid filter value", errInvalidFilter)
+			}
+
+			return &scimFilter{attribute: attribute, value: value}, nil
+		}
+	}
+
+	return nil, httperror.BadRequest("Unsupported filter attribute "+matches[1], errInvalidFilter)
+}
+
+// paginate returns the page of resources described by the startIndex and count query parameters
+func paginate[T any](r *http.Request, resources []T) scimListResponse {
+	startIndex, _ := request.RetrieveNumericQueryParameter(r, "startIndex", true)
+	if startIndex < 1 {
+		startIndex = 1
+	}
+
+	count, err := request.RetrieveNumericQueryParameter(r, "count", true)
+	if err != nil || r.URL.Query().Get("count") == "" || count > len(resources) {
+		count = len(resources)
+	}
+
+	if count < 0 {
+		count = 0
+	}
+
+	start := startIndex - 1
+	if start > len(resources) {
+		start = len(resources)
+	}
+
+	end := start + count
+	if end > len(resources) {
+		end = len(resources)
+	}
+
+	return scimListResponse{
+		Schemas:      []string{listResponseSchema},
+		TotalResults: len(resources),
+		StartIndex:   startIndex,
+		ItemsPerPage: end - start,
+		Resources:    resources[start:end],
+	}
+}
+
+// resourceLocation returns the URL of a SCIM resource
+func resourceLocation(r *http.Request, resourceType, id string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return fmt.Sprintf("%s://%s/api/scim/v2/%s/%s", scheme, r.Host, resourceType, id)
+}
+
+// parseBool parses a boolean patch value, some identity providers send the booleans as strings
+func parseBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return false, fmt.Errorf("%w: expected a boolean", errInvalidValue)
+	}
+
+	b, err := strconv.ParseBool(strings.ToLower(s))
+	if err != nil {
+		return false, fmt.Errorf("%w: expected a boolean", errInvalidValue)
+	}
+
+	return b, nil
+}
+
+func parseString(value json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return "", fmt.Errorf("%w: expected a string", errInvalidValue)
+	}
+
+	return s, nil
+}
+
+// @id SCIMServiceProviderConfig
+// @summary Describe the SCIM features
+// @description Describe the SCIM features supported by Portainer.
+// @description **Access policy**: SCIM token
+// @tags scim
+// @produce json
+// @success 200 "Success"
+// @failure 401 "Invalid SCIM token"
+// @router /scim/v2/ServiceProviderConfig [get]
+func (handler *Handler) serviceProviderConfig(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	supported := func(supported bool) map[string]bool {
+		return map[string]bool{"supported": supported}
+	}
+
+	return writeJSON(w, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{serviceProviderConfigSchema},
+		"patch":          supported(true),
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": 0},
+		"changePassword": supported(false),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]interface{}{
+			{
+				"type":        "oauthbearertoken",
+				"name":        "OAuth Bearer Token",
+				"description": "Authentication with the SCIM token generated by a Portainer administrator",
+				"primary":     true,
+			},
+		},
+	})
+}
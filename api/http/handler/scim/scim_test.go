@@ -0,0 +1,223 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/jwt"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_scimProvisioning(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	admin := &portainer.User{ID: 1, Username: "admin", Password: "hash", Role: portainer.AdministratorRole}
+	require.NoError(t, store.User().Create(admin))
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+	apiKeyService := apikey.NewAPIKeyService(store.APIKeyRepository(), store.User())
+	h := NewHandler(security.NewRequestBouncer(store, jwtService, apiKeyService), store, apiKeyService)
+
+	adminToken, err := jwtService.GenerateToken(&portainer.TokenData{ID: admin.ID, Username: admin.Username, Role: admin.Role})
+	require.NoError(t, err)
+
+	scimToken := ""
+
+	serve := func(token, method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	decode := func(rr *httptest.ResponseRecorder) map[string]interface{} {
+		var data map[string]interface{}
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&data))
+		return data
+	}
+
+	t.Run("should reject the requests while SCIM is disabled", func(t *testing.T) {
+		rr := serve("any", http.MethodGet, "/scim/v2/Users", "")
+		is.Equal(http.StatusForbidden, rr.Code)
+		is.Equal(contentType, rr.Header().Get("Content-Type"))
+		is.Equal([]interface{}{errorSchema}, decode(rr)["schemas"])
+	})
+
+	t.Run("an administrator generates the SCIM token", func(t *testing.T) {
+		rr := serve(adminToken, http.MethodPost, "/scim/token", "")
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp scimTokenResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		scimToken = resp.Token
+
+		settings, err := store.Settings().Settings()
+		require.NoError(t, err)
+		is.Equal(tokenDigest(scimToken), settings.SCIMSettings.TokenDigest)
+
+		settings.SCIMSettings.Enabled = true
+		require.NoError(t, store.Settings().UpdateSettings(settings))
+
+		rr = serve("invalid", http.MethodGet, "/scim/v2/Users", "")
+		is.Equal(http.StatusUnauthorized, rr.Code)
+
+		rr = serve(adminToken, http.MethodGet, "/scim/v2/Users", "")
+		is.Equal(http.StatusUnauthorized, rr.Code, "the Portainer tokens should not authenticate SCIM requests")
+	})
+
+	userID := ""
+	t.Run("should provision a user", func(t *testing.T) {
+		rr := serve(scimToken, http.MethodPost, "/scim/v2/Users", `{"schemas":["`+userSchema+`"],"userName":"bob","name":{"givenName":"Bob"},"active":true}`)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		resource := decode(rr)
+		is.Equal("bob", resource["userName"])
+		is.Equal(true, resource["active"])
+		userID = resource["id"].(string)
+
+		rr = serve(scimToken, http.MethodPost, "/scim/v2/Users", `{"userName":"bob"}`)
+		is.Equal(http.StatusConflict, rr.Code)
+		is.Equal("uniqueness", decode(rr)["scimType"])
+	})
+
+	t.Run("should filter the users", func(t *testing.T) {
+		rr := serve(scimToken, http.MethodGet, `/scim/v2/Users?filter=userName+eq+%22BOB%22`, "")
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		list := decode(rr)
+		is.Equal(float64(1), list["totalResults"])
+		is.Equal(userID, list["Resources"].([]interface{})[0].(map[string]interface{})["id"])
+
+		rr = serve(scimToken, http.MethodGet, `/scim/v2/Users?startIndex=2&count=5`, "")
+		list = decode(rr)
+		is.Equal(float64(2), list["totalResults"])
+		is.Equal(float64(1), list["itemsPerPage"])
+
+		rr = serve(scimToken, http.MethodGet, `/scim/v2/Users?filter=userName+co+%22b%22`, "")
+		is.Equal(http.StatusBadRequest, rr.Code)
+		is.Equal("invalidFilter", decode(rr)["scimType"])
+	})
+
+	t.Run("should deactivate a user with a patch operation", func(t *testing.T) {
+		rr := serve(scimToken, http.MethodPatch, "/scim/v2/Users/"+userID, `{"schemas":["`+patchOpSchema+`"],"Operations":[{"op":"Replace","path":"active","value":"False"}]}`)
+		require.Equal(t, http.StatusOK, rr.Code)
+		is.Equal(false, decode(rr)["active"])
+
+		user, err := store.User().UserByUsername("bob")
+		require.NoError(t, err)
+		is.True(user.Disabled)
+		is.NotZero(user.TokenIssueAt)
+
+		rr = serve(scimToken, http.MethodPatch, "/scim/v2/Users/"+userID, `{"Operations":[{"op":"replace","value":{"active":true,"userName":"robert"}}]}`)
+		require.Equal(t, http.StatusOK, rr.Code)
+		is.Equal("robert", decode(rr)["userName"])
+	})
+
+	groupID := ""
+	t.Run("should provision a group with its members", func(t *testing.T) {
+		rr := serve(scimToken, http.MethodPost, "/scim/v2/Groups", `{"displayName":"developers","members":[{"value":"`+userID+`"}]}`)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		group := decode(rr)
+		groupID = group["id"].(string)
+		is.Equal([]interface{}{map[string]interface{}{"value": userID, "display": "robert"}}, group["members"])
+
+		rr = serve(scimToken, http.MethodPost, "/scim/v2/Groups", `{"displayName":"testers","members":[{"value":"42"}]}`)
+		is.Equal(http.StatusBadRequest, rr.Code)
+		is.Equal("invalidValue", decode(rr)["scimType"])
+
+		rr = serve(scimToken, http.MethodGet, "/scim/v2/Users/"+userID, "")
+		is.Equal([]interface{}{map[string]interface{}{"value": groupID, "display": "developers"}}, decode(rr)["groups"])
+	})
+
+	t.Run("should patch the group members and name", func(t *testing.T) {
+		rr := serve(scimToken, http.MethodPatch, "/scim/v2/Groups/"+groupID, `{"Operations":[{"op":"remove","path":"members[value eq \"`+userID+`\"]"},{"op":"replace","path":"displayName","value":"devs"}]}`)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		group := decode(rr)
+		is.Equal("devs", group["displayName"])
+		is.Empty(group["members"])
+
+		rr = serve(scimToken, http.MethodPatch, "/scim/v2/Groups/"+groupID, `{"Operations":[{"op":"add","path":"members","value":[{"value":"`+userID+`"}]}]}`)
+		require.Equal(t, http.StatusOK, rr.Code)
+		is.Len(decode(rr)["members"], 1)
+
+		rr = serve(scimToken, http.MethodPatch, "/scim/v2/Groups/"+groupID, `{"Operations":[{"op":"add","path":"members","value":[{"value":"1"}]}]}`)
+		is.Equal(http.StatusForbidden, rr.Code, "the users not provisioned through SCIM should not be added")
+	})
+
+	t.Run("should keep the members not provisioned through SCIM", func(t *testing.T) {
+		team, err := store.Team().TeamByName("devs")
+		require.NoError(t, err)
+		require.NoError(t, store.TeamMembership().Create(&portainer.TeamMembership{UserID: admin.ID, TeamID: team.ID, Role: portainer.TeamLeader}))
+
+		rr := serve(scimToken, http.MethodPut, "/scim/v2/Groups/"+groupID, `{"displayName":"devs","members":[]}`)
+		require.Equal(t, http.StatusOK, rr.Code)
+		is.Equal([]interface{}{map[string]interface{}{"value": "1", "display": "admin"}}, decode(rr)["members"])
+
+		require.NoError(t, store.TeamMembership().DeleteTeamMembershipByUserID(admin.ID))
+	})
+
+	t.Run("should only modify the users provisioned through SCIM", func(t *testing.T) {
+		for _, user := range []*portainer.User{
+			{ID: 10, Username: "local", Password: "hash", Role: portainer.StandardUserRole},
+			{ID: 11, Username: "oauth-user", Role: portainer.StandardUserRole},
+			{ID: 12, Username: "scim-admin", Role: portainer.AdministratorRole, ProvisioningSource: portainer.UserProvisionedBySCIM},
+		} {
+			require.NoError(t, store.User().Create(user))
+
+			id := fmt.Sprint(user.ID)
+			rr := serve(scimToken, http.MethodPatch, "/scim/v2/Users/"+id, `{"Operations":[{"op":"replace","path":"active","value":false}]}`)
+			is.Equal(http.StatusForbidden, rr.Code, user.Username)
+
+			rr = serve(scimToken, http.MethodPut, "/scim/v2/Users/"+id, `{"userName":"renamed"}`)
+			is.Equal(http.StatusForbidden, rr.Code, user.Username)
+
+			rr = serve(scimToken, http.MethodDelete, "/scim/v2/Users/"+id, "")
+			is.Equal(http.StatusForbidden, rr.Code, user.Username)
+
+			stored, err := store.User().User(user.ID)
+			require.NoError(t, err)
+			is.Equal(user.Username, stored.Username)
+			is.False(stored.Disabled)
+		}
+	})
+
+	t.Run("should deprovision the users and the groups", func(t *testing.T) {
+		rr := serve(scimToken, http.MethodDelete, "/scim/v2/Users/1", "")
+		is.Equal(http.StatusForbidden, rr.Code, "the initial administrator should not be removed")
+
+		rr = serve(scimToken, http.MethodDelete, "/scim/v2/Users/"+userID, "")
+		is.Equal(http.StatusNoContent, rr.Code)
+
+		_, err := store.User().UserByUsername("robert")
+		is.True(store.IsErrObjectNotFound(err))
+
+		rr = serve(scimToken, http.MethodDelete, "/scim/v2/Groups/"+groupID, "")
+		is.Equal(http.StatusNoContent, rr.Code)
+
+		memberships, err := store.TeamMembership().TeamMemberships()
+		require.NoError(t, err)
+		is.Empty(memberships)
+
+		rr = serve(scimToken, http.MethodGet, "/scim/v2/Groups/"+groupID, "")
+		is.Equal(http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,51 @@
+package scim
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+)
+
+type scimTokenResponse struct {
+	// Bearer token authenticating the SCIM requests, it is only returned once
+	Token string `json:"token" example:"9kR1xI3zRr2SvoWuBCZ7hGT3XPGZ-Pk5ahoLsM7b3Mg"`
+}
+
+// @id SCIMTokenCreate
+// @summary Generate the SCIM token
+// @description Generate the bearer token used by the identity provider to authenticate the SCIM requests.
+// @description The previous token is revoked. The token is only returned once, Portainer only stores its digest.
+// @description **Access policy**: administrator
+// @tags scim
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @success 200 {object} scimTokenResponse "Success"
+// @failure 500 "Server error"
+// @router /scim/token [post]
+func (handler *Handler) scimTokenCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return httperror.InternalServerError("Unable to generate the SCIM token", err)
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	settings, err := handler.dataStore.Settings().Settings()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the settings from the database", err)
+	}
+
+	settings.SCIMSettings.TokenDigest = tokenDigest(token)
+
+	err = handler.dataStore.Settings().UpdateSettings(settings)
+	if err != nil {
+		return httperror.InternalServerError("Unable to persist the settings inside the database", err)
+	}
+
+	return response.JSON(w, scimTokenResponse{Token: token})
+}
@@ -0,0 +1,457 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/authorization"
+)
+
+type scimUser struct {
+	Schemas  []string     `json:"schemas"`
+	ID       string       `json:"id,omitempty"`
+	UserName string       `json:"userName"`
+	Active   *bool        `json:"active,omitempty"`
+	Groups   []scimMember `json:"groups,omitempty"`
+	Meta     *scimMeta    `json:"meta,omitempty"`
+}
+
+func (payload *scimUser) validate() error {
+	if strings.TrimSpace(payload.UserName) == "" {
+		return fmt.Errorf("%w: userName is required", errInvalidValue)
+	}
+
+	return nil
+}
+
+func (handler *Handler) toSCIMUser(r *http.Request, user *portainer.User, teams map[portainer.TeamID]string) (scimUser, error) {
+	id := strconv.Itoa(int(user.ID))
+	active := !user.Disabled
+
+	memberships, err := handler.dataStore.TeamMembership().TeamMembershipsByUserID(user.ID)
+	if err != nil {
+		return scimUser{}, err
+	}
+
+	groups := []scimMember{}
+	for _, membership := range memberships {
+		groups = append(groups, scimMember{
+			Value:   strconv.Itoa(int(membership.TeamID)),
+			Display: teams[membership.TeamID],
+		})
+	}
+
+	return scimUser{
+		Schemas:  []string{userSchema},
+		ID:       id,
+		UserName: user.Username,
+		Active:   &active,
+		Groups:   groups,
+		Meta:     &scimMeta{ResourceType: "User", Location: resourceLocation(r, "Users", id)},
+	}, nil
+}
+
+func (handler *Handler) teamNames() (map[portainer.TeamID]string, error) {
+	teams, err := handler.dataStore.Team().Teams()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[portainer.TeamID]string, len(teams))
+	for _, team := range teams {
+		names[team.ID] = team.Name
+	}
+
+	return names, nil
+}
+
+// retrieveUser returns the user identified by the id route variable
+func (handler *Handler) retrieveUser(r *http.Request) (*portainer.User, *httperror.HandlerError) {
+	id, _ := request.RetrieveRouteVariableValue(r, "id")
+
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, httperror.NotFound("Unable to find a user with the specified identifier", err)
+	}
+
+	user, err := handler.dataStore.User().User(portainer.UserID(userID))
+	if handler.dataStore.IsErrObjectNotFound(err) {
+		return nil, httperror.NotFound("Unable to find a user with the specified identifier", err)
+	} else if err != nil {
+		return nil, httperror.InternalServerError("Unable to find a user with the specified identifier inside the database", err)
+	}
+
+	return user, nil
+}
+
+func (handler *Handler) writeUser(w http.ResponseWriter, r *http.Request, statusCode int, user *portainer.User) *httperror.HandlerError {
+	teams, err := handler.teamNames()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the teams from the database", err)
+	}
+
+	resource, err := handler.toSCIMUser(r, user, teams)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the user memberships from the database", err)
+	}
+
+	return writeJSON(w, statusCode, resource)
+}
+
+// @id SCIMUserList
+// @summary List the users
+// @description List the users as SCIM resources, the users can be filtered with an equality filter on userName or id.
+// @description **Access policy**: SCIM token
+// @tags scim
+// @produce json
+// @param filter query string false "SCIM filter, such as userName eq \"bob\""
+// @param startIndex query int false "1-based index of the first result"
+// @param count query int false "Maximum number of results"
+// @success 200 "Success"
+// @failure 400 "Invalid filter"
+// @failure 401 "Invalid SCIM token"
+// @failure 500 "Server error"
+// @router /scim/v2/Users [get]
+func (handler *Handler) userList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	filter, handlerErr := parseFilter(r, "userName", "id")
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	users, err := handler.dataStore.User().Users()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the users from the database", err)
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	teams, err := handler.teamNames()
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the teams from the database", err)
+	}
+
+	resources := []scimUser{}
+	for i := range users {
+		user := &users[i]
+
+		if filter != nil {
+			if filter.attribute == "userName" && !strings.EqualFold(user.Username, filter.value) {
+				continue
+			}
+
+			if filter.attribute == "id" && strconv.Itoa(int(user.ID)) != filter.value {
+				continue
+			}
+		}
+
+		resource, err := handler.toSCIMUser(r, user, teams)
+		if err != nil {
+			return httperror.InternalServerError("Unable to retrieve the user memberships from the database", err)
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return writeJSON(w, http.StatusOK, paginate(r, resources))
+}
+
+// @id SCIMUserInspect
+// @summary Inspect a user
+// @description Retrieve a user as a SCIM resource.
+// @description **Access policy**: SCIM token
+// @tags scim
+// @produce json
+// @param id path int true "User identifier"
+// @success 200 "Success"
+// @failure 401 "Invalid SCIM token"
+// @failure 404 "User not found"
+// @failure 500 "Server error"
+// @router /scim/v2/Users/{id} [get]
+func (handler *Handler) userInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	user, handlerErr := handler.retrieveUser(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	return handler.writeUser(w, r, http.StatusOK, user)
+}
+
+// @id SCIMUserCreate
+// @summary Provision a user
+// @description Create a standard user without password, the user authenticates with the LDAP or OAuth authentication.
+// @description **Access policy**: SCIM token
+// @tags scim
+// @accept json
+// @produce json
+// @success 201 "Created"
+// @failure 400 "Invalid request"
+// @failure 401 "Invalid SCIM token"
+// @failure 409 "User already exists"
+// @failure 500 "Server error"
+// @router /scim/v2/Users [post]
+func (handler *Handler) userCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload scimUser
+	if handlerErr := decodeJSON(r, &payload); handlerErr != nil {
+		return handlerErr
+	}
+
+	if err := payload.validate(); err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	_, err := handler.dataStore.User().UserByUsername(payload.UserName)
+	if err == nil {
+		return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: "A user with the same username already exists", Err: errUniqueness}
+	} else if !handler.dataStore.IsErrObjectNotFound(err) {
+		return httperror.InternalServerError("Unable to verify user uniqueness", err)
+	}
+
+	user := &portainer.User{
+		Username:                payload.UserName,
+		Role:                    portainer.StandardUserRole,
+		PortainerAuthorizations: authorization.DefaultPortainerAuthorizations(),
+		Disabled:                payload.Active != nil && !*payload.Active,
+		ProvisioningSource:      portainer.UserProvisionedBySCIM,
+	}
+
+	err = handler.dataStore.User().Create(user)
+	if err != nil {
+		return httperror.InternalServerError("Unable to persist the user inside the database", err)
+	}
+
+	return handler.writeUser(w, r, http.StatusCreated, user)
+}
+
+// @id SCIMUserReplace
+// @summary Replace a user
+// @description Replace the username and the active state of a user.
+// @description **Access policy**: SCIM token
+// @tags scim
+// @accept json
+// @produce json
+// @param id path int true "User identifier"
+// @success 200 "Success"
+// @failure 400 "Invalid request"
+// @failure 401 "Invalid SCIM token"
+// @failure 403 "The user is not provisioned through SCIM"
+// @failure 404 "User not found"
+// @failure 409 "User already exists"
+// @failure 500 "Server error"
+// @router /scim/v2/Users/{id} [put]
+func (handler *Handler) userReplace(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	user, handlerErr := handler.retrieveManagedUser(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	var payload scimUser
+	if handlerErr := decodeJSON(r, &payload); handlerErr != nil {
+		return handlerErr
+	}
+
+	if err := payload.validate(); err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	if handlerErr := handler.setUsername(user, payload.UserName); handlerErr != nil {
+		return handlerErr
+	}
+
+	setActive(user, payload.Active == nil || *payload.Active)
+
+	return handler.updateUser(w, r, user)
+}
+
+// @id SCIMUserPatch
+// @summary Patch a user
+// @description Apply SCIM patch operations to the userName and active attributes of a user, the other attributes are ignored.
+// @description **Access policy**: SCIM token
+// @tags scim
+// @accept json
+// @produce json
+// @param id path int true "User identifier"
+// @success 200 "Success"
+// @failure 400 "Invalid request"
+// @failure 401 "Invalid SCIM token"
+// @failure 403 "The user is not provisioned through SCIM"
+// @failure 404 "User not found"
+// @failure 409 "User already exists"
+// @failure 500 "Server error"
+// @router /scim/v2/Users/{id} [patch]
+func (handler *Handler) userPatch(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	user, handlerErr := handler.retrieveManagedUser(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	var payload scimPatchRequest
+	if handlerErr := decodeJSON(r, &payload); handlerErr != nil {
+		return handlerErr
+	}
+
+	for _, operation := range payload.Operations {
+		if strings.EqualFold(operation.Op, "remove") {
+			continue
+		}
+
+		attributes := map[string]json.RawMessage{}
+		if operation.Path == "" {
+			err := json.Unmarshal(operation.Value, &attributes)
+			if err != nil {
+				return httperror.BadRequest("Invalid patch operation", fmt.Errorf("%w: %s", errInvalidValue, err))
+			}
+		} else {
+			attributes[operation.Path] = operation.Value
+		}
+
+		for attribute, value := range attributes {
+			switch {
+			case strings.EqualFold(attribute, "userName"):
+				username, err := parseString(value)
+				if err != nil {
+					return httperror.BadRequest("Invalid userName", err)
+				}
+
+				if handlerErr := handler.setUsername(user, username); handlerErr != nil {
+					return handlerErr
+				}
+
+			case strings.EqualFold(attribute, "active"):
+				active, err := parseBool(value)
+				if err != nil {
+					return httperror.BadRequest("Invalid active state", err)
+				}
+
+				setActive(user, active)
+			}
+		}
+	}
+
+	return handler.updateUser(w, r, user)
+}
+
+// @id SCIMUserDelete
+// @summary Deprovision a user
+// @description Remove a user, its team memberships and its API keys.
+// @description **Access policy**: SCIM token
+// @tags scim
+// @param id path int true "User identifier"
+// @success 204 "Success"
+// @failure 401 "Invalid SCIM token"
+// @failure 403 "The user is not provisioned through SCIM"
+// @failure 404 "User not found"
+// @failure 500 "Server error"
+// @router /scim/v2/Users/{id} [delete]
+func (handler *Handler) userDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	user, handlerErr := handler.retrieveManagedUser(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	err := handler.dataStore.User().DeleteUser(user.ID)
+	if err != nil {
+		return httperror.InternalServerError("Unable to remove user from the database", err)
+	}
+
+	err = handler.dataStore.TeamMembership().DeleteTeamMembershipByUserID(user.ID)
+	if err != nil {
+		return httperror.InternalServerError("Unable to remove user memberships from the database", err)
+	}
+
+	err = handler.dataStore.Session().DeleteSessionsByUserID(user.ID)
+	if err != nil {
+		return httperror.InternalServerError("Unable to remove user sessions from the database", err)
+	}
+
+	apiKeys, err := handler.apiKeyService.GetAPIKeys(user.ID)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve user API keys from the database", err)
+	}
+
+	for _, k := range apiKeys {
+		err = handler.apiKeyService.DeleteAPIKey(k.ID)
+		if err != nil {
+			return httperror.InternalServerError("Unable to remove user API key from the database", err)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// retrieveManagedUser returns the user identified by the id route variable when it can be modified by the identity provider
+func (handler *Handler) retrieveManagedUser(r *http.Request) (*portainer.User, *httperror.HandlerError) {
+	user, handlerErr := handler.retrieveUser(r)
+	if handlerErr != nil {
+		return nil, handlerErr
+	}
+
+	if !isManagedUser(user) {
+		return nil, httperror.Forbidden("The user is not provisioned through SCIM", errNotManaged)
+	}
+
+	return user, nil
+}
+
+// isManagedUser returns true when the user was provisioned through SCIM, the administrators
+// and the users with a password are managed by Portainer even when they were created through SCIM
+func isManagedUser(user *portainer.User) bool {
+	return user.ID != 1 &&
+		user.Role != portainer.AdministratorRole &&
+		user.Password == "" &&
+		user.ProvisioningSource == portainer.UserProvisionedBySCIM
+}
+
+func (handler *Handler) setUsername(user *portainer.User, username string) *httperror.HandlerError {
+	if strings.TrimSpace(username) == "" {
+		return httperror.BadRequest("Invalid userName", fmt.Errorf("%w: userName cannot be empty", errInvalidValue))
+	}
+
+	if username == user.Username {
+		return nil
+	}
+
+	existingUser, err := handler.dataStore.User().UserByUsername(username)
+	if err != nil && !handler.dataStore.IsErrObjectNotFound(err) {
+		return httperror.InternalServerError("Unable to verify user uniqueness", err)
+	}
+
+	if existingUser != nil && existingUser.ID != user.ID {
+		return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: "A user with the same username already exists", Err: errUniqueness}
+	}
+
+	user.Username = username
+
+	return nil
+}
+
+// setActive enables or disables a user, the tokens issued to a disabled user are revoked
+func setActive(user *portainer.User, active bool) {
+	if user.Disabled == !active {
+		return
+	}
+
+	user.Disabled = !active
+	if user.Disabled {
+		user.TokenIssueAt = time.Now().Unix()
+	}
+}
+
+func (handler *Handler) updateUser(w http.ResponseWriter, r *http.Request, user *portainer.User) *httperror.HandlerError {
+	err := handler.dataStore.User().UpdateUser(user.ID, user)
+	if err != nil {
+		return httperror.InternalServerError("Unable to persist user changes inside the database", err)
+	}
+
+	handler.apiKeyService.InvalidateUserKeyCache(user.ID)
+
+	return handler.writeUser(w, r, http.StatusOK, user)
+}
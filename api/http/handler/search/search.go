@@ -0,0 +1,327 @@
+package search
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/authorization"
+)
+
+const defaultLimit = 50
+
+// Types of the search results, the results with the same score are sorted in this order
+const (
+	typeEndpoint       = "endpoint"
+	typeEndpointGroup  = "endpoint_group"
+	typeTag            = "tag"
+	typeStack          = "stack"
+	typeEdgeStack      = "edge_stack"
+	typeCustomTemplate = "custom_template"
+	typeRegistry       = "registry"
+	typeUser           = "user"
+	typeTeam           = "team"
+	typeContainer      = "container"
+	typeImage          = "image"
+	typeVolume         = "volume"
+	typeNamespace      = "namespace"
+)
+
+var typeOrder = map[string]int{
+	typeEndpoint:       0,
+	typeEndpointGroup:  1,
+	typeTag:            2,
+	typeStack:          3,
+	typeEdgeStack:      4,
+	typeCustomTemplate: 5,
+	typeRegistry:       6,
+	typeUser:           7,
+	typeTeam:           8,
+	typeContainer:      9,
+	typeImage:          10,
+	typeVolume:         11,
+	typeNamespace:      12,
+}
+
+// Scores of the matches, the closer the match the higher the score
+const (
+	scoreExact     = 100
+	scorePrefix    = 75
+	scoreWordStart = 50
+	scoreContains  = 25
+)
+
+type searchResult struct {
+	// Type of the resource, one of endpoint, endpoint_group, tag, stack, edge_stack, custom_template,
+	// registry, user, team, container, image, volume or namespace
+	Type string `json:"Type" example:"container"`
+	// Identifier of the resource
+	ID string `json:"Id" example:"1"`
+	// Name of the resource
+	Name string `json:"Name" example:"nginx"`
+	// Environment(Endpoint) identifier of the stacks and of the resources found in the snapshots
+	EndpointID portainer.EndpointID `json:"EndpointId,omitempty" example:"1"`
+	// Relevance of the result, the higher the better
+	Score int `json:"Score" example:"75"`
+}
+
+// @id Search
+// @summary Search across the resources
+// @description Search the environments(endpoints), environment(endpoint) groups, tags, stacks, edge stacks, custom templates,
+// @description registries, users, teams and the content of the environment(endpoint) snapshots (containers, images, volumes and namespaces).
+// @description Only the resources accessible by the current user are returned, sorted by relevance.
+// @description For the non administrators, the containers and volumes of the snapshots are filtered through their resource controls
+// @description and the environment(endpoint) authorizations of the user, and the namespaces through their access policies.
+// @description **Access policy**: authenticated
+// @tags search
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param q query string true "Search query, matched case-insensitively against the names of the resources"
+// @param limit query int false "Maximum number of results, defaults to 50"
+// @success 200 {array} searchResult "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /search [get]
+func (handler *Handler) search(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	query, err := request.RetrieveQueryParameter(r, "q", false)
+	if err != nil {
+		return httperror.BadRequest("Invalid query parameter: q", err)
+	}
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return httperror.BadRequest("Invalid query parameter: q", nil)
+	}
+
+	limit, _ := request.RetrieveNumericQueryParameter(r, "limit", true)
+	if limit < 0 {
+		return httperror.BadRequest("Invalid query parameter: limit", nil)
+	}
+	if limit == 0 {
+		limit = defaultLimit
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve info from request context", err)
+	}
+
+	results, httpErr := handler.searchResources(query, securityContext)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	sortResults(results)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return response.JSON(w, results)
+}
+
+func (handler *Handler) searchResources(query string, securityContext *security.RestrictedRequestContext) ([]searchResult, *httperror.HandlerError) {
+	results := make([]searchResult, 0)
+
+	add := func(resourceType, id, name string, endpointID portainer.EndpointID, candidates ...string) {
+		score := matchScore(query, append([]string{name}, candidates...)...)
+		if score > 0 {
+			results = append(results, searchResult{Type: resourceType, ID: id, Name: name, EndpointID: endpointID, Score: score})
+		}
+	}
+
+	endpointGroups, err := handler.DataStore.EndpointGroup().EndpointGroups()
+	if err != nil {
+		return nil, httperror.InternalServerError("Unable to retrieve environment groups from the database", err)
+	}
+
+	endpoints, err := handler.DataStore.Endpoint().Endpoints()
+	if err != nil {
+		return nil, httperror.InternalServerError("Unable to retrieve environments from the database", err)
+	}
+
+	// the environments must be filtered first as the groups are filtered in place
+	endpoints = security.FilterEndpoints(endpoints, endpointGroups, securityContext)
+	endpointGroups = security.FilterEndpointGroups(endpointGroups, securityContext)
+
+	accessibleEndpoints := make(map[portainer.EndpointID]*portainer.Endpoint, len(endpoints))
+	for i := range endpoints {
+		endpoint := &endpoints[i]
+		accessibleEndpoints[endpoint.ID] = endpoint
+		add(typeEndpoint, strconv.Itoa(int(endpoint.ID)), endpoint.Name, endpoint.ID, endpoint.URL)
+	}
+
+	for _, group := range endpointGroups {
+		add(typeEndpointGroup, strconv.Itoa(int(group.ID)), group.Name, 0)
+	}
+
+	tags, err := handler.DataStore.Tag().Tags()
+	if err != nil {
+		return nil, httperror.InternalServerError("Unable to retrieve tags from the database", err)
+	}
+
+	for _, tag := range tags {
+		add(typeTag, strconv.Itoa(int(tag.ID)), tag.Name, 0)
+	}
+
+	resourceControls, err := handler.DataStore.ResourceControl().ResourceControls()
+	if err != nil {
+		return nil, httperror.InternalServerError("Unable to retrieve resource controls from the database", err)
+	}
+
+	stacks, err := handler.DataStore.Stack().Stacks()
+	if err != nil {
+		return nil, httperror.InternalServerError("Unable to retrieve stacks from the database", err)
+	}
+	stacks = authorization.DecorateStacks(stacks, resourceControls)
+
+	customTemplates, err := handler.DataStore.CustomTemplate().CustomTemplates()
+	if err != nil {
+		return nil, httperror.InternalServerError("Unable to retrieve custom templates from the database", err)
+	}
+	customTemplates = authorization.DecorateCustomTemplates(customTemplates, resourceControls)
+
+	var user *portainer.User
+	userTeamIDs := make([]portainer.TeamID, 0)
+	if !securityContext.IsAdmin {
+		user, err = handler.DataStore.User().User(securityContext.UserID)
+		if err != nil {
+			return nil, httperror.InternalServerError("Unable to retrieve user information from the database", err)
+		}
+
+		for _, membership := range securityContext.UserMemberships {
+			userTeamIDs = append(userTeamIDs, membership.TeamID)
+		}
+
+		stacks = authorization.FilterAuthorizedStacks(stacks, user, userTeamIDs)
+		customTemplates = authorization.FilterAuthorizedCustomTemplates(customTemplates, user, userTeamIDs)
+	}
+
+	for _, stack := range stacks {
+		add(typeStack, strconv.Itoa(int(stack.ID)), stack.Name, stack.EndpointID)
+	}
+
+	for _, template := range customTemplates {
+		add(typeCustomTemplate, strconv.Itoa(int(template.ID)), template.Title, 0, template.Description)
+	}
+
+	// the edge stacks and the registries can only be listed by the administrators
+	if securityContext.IsAdmin {
+		edgeStacks, err := handler.DataStore.EdgeStack().EdgeStacks()
+		if err != nil {
+			return nil, httperror.InternalServerError("Unable to retrieve edge stacks from the database", err)
+		}
+
+		for _, edgeStack := range edgeStacks {
+			add(typeEdgeStack, strconv.Itoa(int(edgeStack.ID)), edgeStack.Name, 0)
+		}
+
+		registries, err := handler.DataStore.Registry().Registries()
+		if err != nil {
+			return nil, httperror.InternalServerError("Unable to retrieve registries from the database", err)
+		}
+
+		for _, registry := range registries {
+			add(typeRegistry, strconv.Itoa(int(registry.ID)), registry.Name, 0, registry.URL)
+		}
+	}
+
+	users, err := handler.DataStore.User().Users()
+	if err != nil {
+		return nil, httperror.InternalServerError("Unable to retrieve users from the database", err)
+	}
+
+	for _, user := range security.FilterUsers(users, securityContext) {
+		add(typeUser, strconv.Itoa(int(user.ID)), user.Username, 0)
+	}
+
+	teams, err := handler.DataStore.Team().Teams()
+	if err != nil {
+		return nil, httperror.InternalServerError("Unable to retrieve teams from the database", err)
+	}
+
+	for _, team := range security.FilterUserTeams(teams, securityContext) {
+		add(typeTeam, strconv.Itoa(int(team.ID)), team.Name, 0)
+	}
+
+	snapshots, err := handler.DataStore.Snapshot().Snapshots()
+	if err != nil {
+		return nil, httperror.InternalServerError("Unable to retrieve environment snapshots from the database", err)
+	}
+
+	for _, snapshot := range snapshots {
+		endpoint, ok := accessibleEndpoints[snapshot.EndpointID]
+		if !ok {
+			continue
+		}
+
+		// the snapshots hold every resource of the environments, the ones the non administrators cannot access are filtered out
+		var access *snapshotAccess
+		if !securityContext.IsAdmin {
+			access = handler.newSnapshotAccess(endpoint, snapshot, user, userTeamIDs, resourceControls)
+		}
+
+		searchSnapshot(snapshot, access, add)
+	}
+
+	return results, nil
+}
+
+// matchScore returns the score of the best match of the lower case query in the candidates, 0 when none matches
+func matchScore(query string, candidates ...string) int {
+	best := 0
+	for _, candidate := range candidates {
+		candidate = strings.ToLower(candidate)
+
+		score := 0
+		switch {
+		case candidate == query:
+			score = scoreExact
+		case strings.HasPrefix(candidate, query):
+			score = scorePrefix
+		case matchesWordStart(candidate, query):
+			score = scoreWordStart
+		case strings.Contains(candidate, query):
+			score = scoreContains
+		}
+
+		if score > best {
+			best = score
+		}
+	}
+
+	return best
+}
+
+// matchesWordStart returns true when the query starts a word of the candidate,
+// the words being separated by the usual resource name separators
+func matchesWordStart(candidate, query string) bool {
+	for i := 0; i < len(candidate); i++ {
+		if strings.ContainsRune(" -_./:@", rune(candidate[i])) && strings.HasPrefix(candidate[i+1:], query) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sortResults sorts the results by decreasing score, then by type and name
+func sortResults(results []searchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+
+		if results[i].Type != results[j].Type {
+			return typeOrder[results[i].Type] < typeOrder[results[j].Type]
+		}
+
+		return strings.ToLower(results[i].Name) < strings.ToLower(results[j].Name)
+	})
+}
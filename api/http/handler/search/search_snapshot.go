@@ -0,0 +1,201 @@
+package search
+
+import (
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/internal/snapshot"
+	"github.com/portainer/portainer/api/stacks/stackutils"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	labelDockerServiceID          = "com.docker.swarm.service.id"
+	labelDockerSwarmStackName     = "com.docker.stack.namespace"
+	labelDockerComposeProjectName = "com.docker.compose.project"
+)
+
+// searchSnapshot matches the containers, images and volumes of the Docker snapshots
+// and the namespaces of the Kubernetes snapshots. Every resource is matched when access is nil.
+func searchSnapshot(snapshot portainer.Snapshot, access *snapshotAccess, add func(resourceType, id, name string, endpointID portainer.EndpointID, candidates ...string)) {
+	if snapshot.Docker != nil {
+		raw := snapshot.Docker.SnapshotRaw
+
+		for _, container := range raw.Containers {
+			if access != nil && !access.canAccessContainer(container) {
+				continue
+			}
+
+			name := container.ID
+			if len(container.Names) > 0 {
+				name = strings.TrimPrefix(container.Names[0], "/")
+			}
+
+			add(typeContainer, container.ID, name, snapshot.EndpointID, container.Image)
+		}
+
+		if access == nil || access.canAccessImage() {
+			for _, image := range raw.Images {
+				name := image.ID
+				if len(image.RepoTags) > 0 {
+					name = image.RepoTags[0]
+				}
+
+				add(typeImage, image.ID, name, snapshot.EndpointID, image.RepoTags...)
+			}
+		}
+
+		for _, volume := range raw.Volumes.Volumes {
+			if volume == nil || (access != nil && !access.canAccessVolume(snapshot.Docker, volume.Name, volume.Labels)) {
+				continue
+			}
+
+			add(typeVolume, volume.Name, volume.Name, snapshot.EndpointID)
+		}
+	}
+
+	if snapshot.Kubernetes != nil {
+		for _, namespace := range snapshot.Kubernetes.Namespaces {
+			if access != nil && !access.canAccessNamespace(namespace) {
+				continue
+			}
+
+			add(typeNamespace, namespace, namespace, snapshot.EndpointID)
+		}
+	}
+}
+
+// snapshotAccess filters the content of the snapshot of an environment for a non administrator.
+// The containers and volumes are filtered through their resource controls like in the Docker proxy,
+// unless the user can access every resource of the environment, and the namespaces through their access policies.
+type snapshotAccess struct {
+	endpointID       portainer.EndpointID
+	userID           portainer.UserID
+	userTeamIDs      []portainer.TeamID
+	authorizations   portainer.Authorizations
+	resourceControls []portainer.ResourceControl
+	// namespaces accessible by the user, nil when the access policies of the environment could not be retrieved
+	namespaces map[string]bool
+}
+
+func (access *snapshotAccess) allowed(authorization portainer.Authorization) bool {
+	return access.authorizations[authorization]
+}
+
+// canAccessResource returns true when the user can access a Docker resource, either directly or through the
+// service or the stack it belongs to
+func (access *snapshotAccess) canAccessResource(resourceID string, resourceType portainer.ResourceControlType, labels map[string]string) bool {
+	if access.allowed(portainer.EndpointResourcesAccess) {
+		return true
+	}
+
+	resourceControl := authorization.GetResourceControlByResourceIDAndType(resourceID, resourceType, access.resourceControls)
+
+	if resourceControl == nil && labels[labelDockerServiceID] != "" {
+		resourceControl = authorization.GetResourceControlByResourceIDAndType(labels[labelDockerServiceID], portainer.ServiceResourceControl, access.resourceControls)
+	}
+
+	for _, label := range []string{labelDockerSwarmStackName, labelDockerComposeProjectName} {
+		if resourceControl == nil && labels[label] != "" {
+			resourceControl = authorization.GetResourceControlByResourceIDAndType(stackutils.ResourceControlID(access.endpointID, labels[label]), portainer.StackResourceControl, access.resourceControls)
+		}
+	}
+
+	return authorization.UserCanAccessResource(access.userID, access.userTeamIDs, resourceControl)
+}
+
+func (access *snapshotAccess) canAccessContainer(container portainer.DockerContainerSnapshot) bool {
+	return access.allowed(portainer.OperationDockerContainerList) && access.canAccessResource(container.ID, portainer.ContainerResourceControl, container.Labels)
+}
+
+func (access *snapshotAccess) canAccessImage() bool {
+	return access.allowed(portainer.OperationDockerImageList)
+}
+
+// canAccessVolume returns true when the user can access a volume, the resource controls of the volumes
+// are identified by the name of the volume and the Docker identifier of the environment
+func (access *snapshotAccess) canAccessVolume(dockerSnapshot *portainer.DockerSnapshot, name string, labels map[string]string) bool {
+	if !access.allowed(portainer.OperationDockerVolumeList) {
+		return false
+	}
+
+	dockerID, err := snapshot.FetchDockerID(*dockerSnapshot)
+	if err != nil {
+		log.Debug().Err(err).Int("endpoint_id", int(access.endpointID)).Msg("unable to retrieve the Docker identifier of the snapshot")
+	}
+
+	return access.canAccessResource(name+"_"+dockerID, portainer.VolumeResourceControl, labels)
+}
+
+func (access *snapshotAccess) canAccessNamespace(namespace string) bool {
+	return access.allowed(portainer.EndpointResourcesAccess) || access.namespaces[namespace]
+}
+
+// accessibleNamespaces returns the namespaces of a Kubernetes environment that a non administrator can access,
+// the default namespace is accessible unless it is restricted
+func accessibleNamespaces(namespaces []string, accessPolicies map[string]portainer.K8sNamespaceAccessPolicy, restrictDefaultNamespace bool, userID portainer.UserID, userTeamIDs []portainer.TeamID) map[string]bool {
+	accessible := make(map[string]bool)
+
+	for _, namespace := range namespaces {
+		if namespace == "default" && !restrictDefaultNamespace {
+			accessible[namespace] = true
+			continue
+		}
+
+		policy, ok := accessPolicies[namespace]
+		if !ok {
+			continue
+		}
+
+		if _, ok := policy.UserAccessPolicies[userID]; ok {
+			accessible[namespace] = true
+			continue
+		}
+
+		for _, teamID := range userTeamIDs {
+			if _, ok := policy.TeamAccessPolicies[teamID]; ok {
+				accessible[namespace] = true
+				break
+			}
+		}
+	}
+
+	return accessible
+}
+
+// newSnapshotAccess returns the access of a non administrator to the content of the snapshot of an environment
+func (handler *Handler) newSnapshotAccess(endpoint *portainer.Endpoint, snapshot portainer.Snapshot, user *portainer.User, userTeamIDs []portainer.TeamID, resourceControls []portainer.ResourceControl) *snapshotAccess {
+	access := &snapshotAccess{
+		endpointID:       endpoint.ID,
+		userID:           user.ID,
+		userTeamIDs:      userTeamIDs,
+		authorizations:   user.EndpointAuthorizations[endpoint.ID],
+		resourceControls: resourceControls,
+	}
+
+	if snapshot.Kubernetes == nil || len(snapshot.Kubernetes.Namespaces) == 0 || access.allowed(portainer.EndpointResourcesAccess) {
+		return access
+	}
+
+	if handler.KubernetesClientFactory == nil {
+		return access
+	}
+
+	kcl, err := handler.KubernetesClientFactory.GetKubeClient(endpoint)
+	if err != nil {
+		log.Warn().Err(err).Int("endpoint_id", int(endpoint.ID)).Msg("unable to create the Kubernetes client, the namespaces are not searched")
+		return access
+	}
+
+	accessPolicies, err := kcl.GetNamespaceAccessPolicies()
+	if err != nil {
+		log.Warn().Err(err).Int("endpoint_id", int(endpoint.ID)).Msg("unable to retrieve the namespace access policies, the namespaces are not searched")
+		return access
+	}
+
+	access.namespaces = accessibleNamespaces(snapshot.Kubernetes.Namespaces, accessPolicies, endpoint.Kubernetes.Configuration.RestrictDefaultNamespace, user.ID, userTeamIDs)
+
+	return access
+}
@@ -0,0 +1,176 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/jwt"
+	"github.com/portainer/portainer/api/stacks/stackutils"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_matchScore(t *testing.T) {
+	is := assert.New(t)
+
+	is.Equal(scoreExact, matchScore("nginx", "NGINX"))
+	is.Equal(scorePrefix, matchScore("nginx", "nginx:latest"))
+	is.Equal(scoreWordStart, matchScore("nginx", "library/nginx"))
+	is.Equal(scoreContains, matchScore("nginx", "mynginx"))
+	is.Equal(0, matchScore("nginx", "apache"))
+	is.Equal(scorePrefix, matchScore("nginx", "mynginx", "nginx-proxy"), "the best candidate should be used")
+}
+
+func Test_search(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	adminUser := &portainer.User{ID: 1, Username: "admin", Role: portainer.AdministratorRole}
+	require.NoError(t, store.User().Create(adminUser))
+
+	standardUser := &portainer.User{
+		ID:                      2,
+		Username:                "standard",
+		Role:                    portainer.StandardUserRole,
+		PortainerAuthorizations: authorization.DefaultPortainerAuthorizations(),
+		EndpointAuthorizations: portainer.EndpointAuthorizations{
+			1: {portainer.OperationDockerContainerList: true, portainer.OperationDockerVolumeList: true},
+		},
+	}
+	require.NoError(t, store.User().Create(standardUser))
+
+	require.NoError(t, store.EndpointGroup().Create(&portainer.EndpointGroup{ID: 1, Name: "web-group"}))
+
+	allowedEndpoint := &portainer.Endpoint{
+		ID:                 1,
+		Name:               "web-production",
+		GroupID:            1,
+		UserAccessPolicies: portainer.UserAccessPolicies{standardUser.ID: {RoleID: 1}},
+	}
+	require.NoError(t, store.Endpoint().Create(allowedEndpoint))
+	require.NoError(t, store.Endpoint().Create(&portainer.Endpoint{ID: 2, Name: "web-staging", GroupID: 1}))
+
+	dockerSnapshot := func(containerName string) *portainer.DockerSnapshot {
+		return &portainer.DockerSnapshot{SnapshotRaw: portainer.DockerSnapshotRaw{
+			Containers: []portainer.DockerContainerSnapshot{{Container: types.Container{ID: "abc", Names: []string{"/" + containerName}, Image: "nginx"}}},
+			Volumes:    volume.VolumeListOKBody{Volumes: []*types.Volume{{Name: containerName + "-data"}}},
+		}}
+	}
+	productionSnapshot := dockerSnapshot("web")
+	productionSnapshot.SnapshotRaw.Info.ID = "docker1"
+	productionSnapshot.SnapshotRaw.Containers = append(productionSnapshot.SnapshotRaw.Containers, portainer.DockerContainerSnapshot{
+		Container: types.Container{ID: "def", Names: []string{"/web-app"}, Image: "nginx", Labels: map[string]string{"com.docker.compose.project": "webstack"}},
+	})
+	productionSnapshot.SnapshotRaw.Images = []types.ImageSummary{{ID: "sha256:1", RepoTags: []string{"web-image:latest"}}}
+	require.NoError(t, store.Snapshot().Create(&portainer.Snapshot{
+		EndpointID: 1,
+		Docker:     productionSnapshot,
+		Kubernetes: &portainer.KubernetesSnapshot{Namespaces: []string{"web-namespace"}},
+	}))
+
+	// the standard user can access the containers of the stack and the volume, not the other container
+	userAccesses := []portainer.UserResourceAccess{{UserID: standardUser.ID, AccessLevel: portainer.ReadWriteAccessLevel}}
+	require.NoError(t, store.ResourceControl().Create(&portainer.ResourceControl{ID: 1, ResourceID: stackutils.ResourceControlID(1, "webstack"), Type: portainer.StackResourceControl, UserAccesses: userAccesses}))
+	require.NoError(t, store.ResourceControl().Create(&portainer.ResourceControl{ID: 2, ResourceID: "web-data_docker1", Type: portainer.VolumeResourceControl, UserAccesses: userAccesses}))
+	require.NoError(t, store.Snapshot().Create(&portainer.Snapshot{EndpointID: 2, Docker: dockerSnapshot("web-staging-proxy")}))
+
+	require.NoError(t, store.Registry().Create(&portainer.Registry{ID: 1, Name: "web-registry"}))
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+	apiKeyService := apikey.NewAPIKeyService(store.APIKeyRepository(), store.User())
+	h := NewHandler(security.NewRequestBouncer(store, jwtService, apiKeyService), store)
+
+	search := func(user *portainer.User, query string) []searchResult {
+		token, _ := jwtService.GenerateToken(&portainer.TokenData{ID: user.ID, Username: user.Username, Role: user.Role})
+
+		req := httptest.NewRequest(http.MethodGet, "/search?q="+query, nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var results []searchResult
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&results))
+
+		return results
+	}
+
+	names := func(results []searchResult) []string {
+		names := make([]string, 0, len(results))
+		for _, result := range results {
+			names = append(names, result.Type+":"+result.Name)
+		}
+
+		return names
+	}
+
+	t.Run("admin finds every resource, sorted by relevance", func(t *testing.T) {
+		results := search(adminUser, "web")
+
+		is.Equal([]string{
+			"container:web",
+			"endpoint:web-production",
+			"endpoint:web-staging",
+			"endpoint_group:web-group",
+			"registry:web-registry",
+			"container:web-app",
+			"container:web-staging-proxy",
+			"image:web-image:latest",
+			"volume:web-data",
+			"volume:web-staging-proxy-data",
+			"namespace:web-namespace",
+		}, names(results))
+		is.Equal(scoreExact, results[0].Score)
+		is.Equal(portainer.EndpointID(1), results[0].EndpointID)
+	})
+
+	t.Run("standard user only finds the resources they can access, including the content of the snapshots", func(t *testing.T) {
+		results := search(standardUser, "web")
+
+		is.Equal([]string{
+			"endpoint:web-production",
+			"container:web-app",
+			"volume:web-data",
+		}, names(results), "the resources without access and the images without authorization should be filtered out")
+	})
+
+	t.Run("query is required", func(t *testing.T) {
+		token, _ := jwtService.GenerateToken(&portainer.TokenData{ID: adminUser.ID, Username: adminUser.Username, Role: adminUser.Role})
+
+		req := httptest.NewRequest(http.MethodGet, "/search", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		is.Equal(http.StatusBadRequest, rr.Code)
+	})
+}
+
+func Test_accessibleNamespaces(t *testing.T) {
+	is := assert.New(t)
+
+	namespaces := []string{"default", "team-ns", "user-ns", "other-ns"}
+	policies := map[string]portainer.K8sNamespaceAccessPolicy{
+		"team-ns":  {TeamAccessPolicies: portainer.TeamAccessPolicies{3: {}}},
+		"user-ns":  {UserAccessPolicies: portainer.UserAccessPolicies{2: {}}},
+		"other-ns": {UserAccessPolicies: portainer.UserAccessPolicies{5: {}}},
+	}
+
+	is.Equal(map[string]bool{"default": true, "team-ns": true, "user-ns": true}, accessibleNamespaces(namespaces, policies, false, 2, []portainer.TeamID{3}))
+	is.Equal(map[string]bool{"user-ns": true}, accessibleNamespaces(namespaces, policies, true, 2, nil), "the default namespace can be restricted")
+}
@@ -9,23 +9,33 @@ import (
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/demo"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/ldap"
+	"github.com/portainer/portainer/api/scanner"
+	"github.com/portainer/portainer/api/stacks/drift"
 )
 
 func hideFields(settings *portainer.Settings) {
 	settings.LDAPSettings.Password = ""
 	settings.OAuthSettings.ClientSecret = ""
 	settings.OAuthSettings.KubeSecretKey = nil
+	settings.BackupSettings.Password = ""
+	settings.BackupSettings.S3Settings.SecretAccessKey = ""
+	settings.SCIMSettings.TokenDigest = ""
 }
 
 // Handler is the HTTP handler used to handle settings operations.
 type Handler struct {
 	*mux.Router
-	DataStore       dataservices.DataStore
-	FileService     portainer.FileService
-	JWTService      dataservices.JWTService
-	LDAPService     portainer.LDAPService
-	SnapshotService portainer.SnapshotService
-	demoService     *demo.Service
+	DataStore        dataservices.DataStore
+	FileService      portainer.FileService
+	JWTService       dataservices.JWTService
+	LDAPService      portainer.LDAPService
+	LDAPSyncService  *ldap.SyncService
+	SnapshotService  portainer.SnapshotService
+	ImageScanService *scanner.Service
+	DriftService     *drift.Service
+	APIRateLimiter   *security.APIRateLimiter
+	demoService      *demo.Service
 }
 
 // NewHandler creates a handler to manage settings operations.
@@ -38,6 +48,8 @@ func NewHandler(bouncer *security.RequestBouncer, demoService *demo.Service) *Ha
 		bouncer.AdminAccess(httperror.LoggerHandler(h.settingsInspect))).Methods(http.MethodGet)
 	h.Handle("/settings",
 		bouncer.AdminAccess(httperror.LoggerHandler(h.settingsUpdate))).Methods(http.MethodPut)
+	h.Handle("/settings/ratelimits",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.settingsRateLimitUsage))).Methods(http.MethodGet)
 	h.Handle("/settings/public",
 		bouncer.PublicAccess(httperror.LoggerHandler(h.settingsPublic))).Methods(http.MethodGet)
 
@@ -92,14 +92,20 @@ func generatePublicSettings(appSettings *portainer.Settings) *publicSettingsResp
 	//if OAuth authentication is on, compose the related fields from application settings
 	if publicSettings.AuthenticationMethod == portainer.AuthenticationOAuth {
 		publicSettings.OAuthLogoutURI = appSettings.OAuthSettings.LogoutURI
-		publicSettings.OAuthLoginURI = fmt.Sprintf("%s?response_type=code&client_id=%s&redirect_uri=%s&scope=%s",
-			appSettings.OAuthSettings.AuthorizationURI,
-			appSettings.OAuthSettings.ClientID,
-			appSettings.OAuthSettings.RedirectURI,
-			appSettings.OAuthSettings.Scopes)
-		//control prompt=login param according to the SSO setting
-		if !appSettings.OAuthSettings.SSO {
-			publicSettings.OAuthLoginURI += "&prompt=login"
+		// the authorization requests to an OpenID Connect provider are initiated by Portainer to use PKCE,
+		// the state of the request is generated by Portainer as well
+		if appSettings.OAuthSettings.OIDCIssuerURL != "" {
+			publicSettings.OAuthLoginURI = "api/auth/oauth/login"
+		} else {
+			publicSettings.OAuthLoginURI = fmt.Sprintf("%s?response_type=code&client_id=%s&redirect_uri=%s&scope=%s",
+				appSettings.OAuthSettings.AuthorizationURI,
+				appSettings.OAuthSettings.ClientID,
+				appSettings.OAuthSettings.RedirectURI,
+				appSettings.OAuthSettings.Scopes)
+			//control prompt=login param according to the SSO setting
+			if !appSettings.OAuthSettings.SSO {
+				publicSettings.OAuthLoginURI += "&prompt=login"
+			}
 		}
 	}
 	//if LDAP authentication is on, compose the related fields from application settings
@@ -0,0 +1,29 @@
+package settings
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+// @id SettingsRateLimitUsage
+// @summary Retrieve the usage of the API rate limits
+// @description Retrieve the current usage of the rate limit budgets of the users and of the API keys.
+// @description Only the budgets that are being consumed are returned.
+// @description **Access policy**: administrator
+// @tags settings
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @success 200 {array} security.RateLimitUsage "Success"
+// @failure 500 "Server error"
+// @router /settings/ratelimits [get]
+func (handler *Handler) settingsRateLimitUsage(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	if handler.APIRateLimiter == nil {
+		return response.JSON(w, []security.RateLimitUsage{})
+	}
+
+	return response.JSON(w, handler.APIRateLimiter.Usage())
+}
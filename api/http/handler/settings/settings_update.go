@@ -12,10 +12,18 @@ import (
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/filesystem"
+	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/edge"
+	"github.com/portainer/portainer/api/ldap"
+	"github.com/portainer/portainer/api/oauth"
+	"github.com/portainer/portainer/api/scanner"
+	"github.com/portainer/portainer/api/stacks/drift"
 	"github.com/portainer/portainer/pkg/libhelm"
 )
 
+// minSessionIdleTimeout is the shortest idle timeout, the session activity is only recorded once per minute
+const minSessionIdleTimeout = 5 * time.Minute
+
 type settingsUpdatePayload struct {
 	// URL to a logo that will be displayed on the login page as well as on top of the sidebar. Will use default Portainer logo when value is empty string
 	LogoURL *string `example:"https://mycompany.mydomain.tld/logo.png"`
@@ -23,7 +31,7 @@ type settingsUpdatePayload struct {
 	BlackListedLabels []portainer.Pair
 	// Active authentication method for the Portainer instance. Valid values are: 1 for internal, 2 for LDAP, or 3 for oauth
 	AuthenticationMethod *int `example:"1"`
-	InternalAuthSettings *portainer.InternalAuthSettings
+	InternalAuthSettings *internalAuthSettingsPayload
 	LDAPSettings         *portainer.LDAPSettings
 	OAuthSettings        *portainer.OAuthSettings
 	// The interval in which environment(endpoint) snapshots are created
@@ -38,6 +46,8 @@ type settingsUpdatePayload struct {
 	EnableEdgeComputeFeatures *bool `example:"true"`
 	// The duration of a user session
 	UserSessionTimeout *string `example:"5m"`
+	// The duration of inactivity after which a user session is revoked, an empty value disables the idle timeout
+	SessionIdleTimeout *string `example:"30m"`
 	// The expiry of a Kubeconfig
 	KubeconfigExpiry *string `example:"24h" default:"0"`
 	// Whether telemetry is enabled
@@ -52,6 +62,20 @@ type settingsUpdatePayload struct {
 	EnforceEdgeID *bool `example:"false"`
 	// EdgePortainerURL is the URL that is exposed to edge agents
 	EdgePortainerURL *string `json:"EdgePortainerURL"`
+	// Settings of the image vulnerability scanning
+	VulnerabilityScanSettings *portainer.VulnerabilityScanSettings `json:"VulnerabilityScanSettings"`
+	// Settings of the compose stacks drift detection
+	StackDriftSettings *portainer.StackDriftSettings `json:"StackDriftSettings"`
+	// Settings of the rate limiting of the API requests
+	APIRateLimitSettings *portainer.APIRateLimitSettings `json:"APIRateLimitSettings"`
+	// Settings of the SCIM provisioning, the token is generated with the SCIM token endpoint
+	SCIMSettings *portainer.SCIMSettings `json:"SCIMSettings"`
+}
+
+type internalAuthSettingsPayload struct {
+	RequiredPasswordLength int
+	// Whether the administrators must use a multi-factor authentication to log in, unchanged when omitted
+	EnforceAdminMFA *bool `example:"false"`
 }
 
 func (payload *settingsUpdatePayload) Validate(r *http.Request) error {
@@ -73,6 +97,12 @@ func (payload *settingsUpdatePayload) Validate(r *http.Request) error {
 			return errors.New("Invalid user session timeout")
 		}
 	}
+	if payload.SessionIdleTimeout != nil && *payload.SessionIdleTimeout != "" {
+		sessionIdleTimeout, err := time.ParseDuration(*payload.SessionIdleTimeout)
+		if err != nil || sessionIdleTimeout < minSessionIdleTimeout {
+			return errors.New("Invalid session idle timeout. Must be a duration of at least 5 minutes")
+		}
+	}
 	if payload.KubeconfigExpiry != nil {
 		_, err := time.ParseDuration(*payload.KubeconfigExpiry)
 		if err != nil {
@@ -87,6 +117,41 @@ func (payload *settingsUpdatePayload) Validate(r *http.Request) error {
 		}
 	}
 
+	if payload.VulnerabilityScanSettings != nil {
+		err := scanner.ValidateSettings(*payload.VulnerabilityScanSettings)
+		if err != nil {
+			return err
+		}
+	}
+
+	if payload.LDAPSettings != nil {
+		err := ldap.ValidateSyncSettings(*payload.LDAPSettings)
+		if err != nil {
+			return err
+		}
+	}
+
+	if payload.StackDriftSettings != nil {
+		err := drift.ValidateSettings(*payload.StackDriftSettings)
+		if err != nil {
+			return err
+		}
+	}
+
+	if payload.OAuthSettings != nil {
+		err := oauth.ValidateSettings(*payload.OAuthSettings)
+		if err != nil {
+			return err
+		}
+	}
+
+	if payload.APIRateLimitSettings != nil {
+		err := security.ValidateAPIRateLimitSettings(*payload.APIRateLimitSettings)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -161,6 +226,10 @@ func (handler *Handler) settingsUpdate(w http.ResponseWriter, r *http.Request) *
 
 	if payload.InternalAuthSettings != nil {
 		settings.InternalAuthSettings.RequiredPasswordLength = payload.InternalAuthSettings.RequiredPasswordLength
+
+		if payload.InternalAuthSettings.EnforceAdminMFA != nil {
+			settings.InternalAuthSettings.EnforceAdminMFA = *payload.InternalAuthSettings.EnforceAdminMFA
+		}
 	}
 
 	if payload.LDAPSettings != nil {
@@ -175,6 +244,13 @@ func (handler *Handler) settingsUpdate(w http.ResponseWriter, r *http.Request) *
 			ldapPassword = payload.LDAPSettings.Password
 		}
 
+		if payload.LDAPSettings.SyncInterval != settings.LDAPSettings.SyncInterval && handler.LDAPSyncService != nil {
+			err := handler.LDAPSyncService.Reschedule(payload.LDAPSettings.SyncInterval)
+			if err != nil {
+				return httperror.InternalServerError("Unable to update the LDAP synchronization interval", err)
+			}
+		}
+
 		settings.LDAPSettings = *payload.LDAPSettings
 		settings.LDAPSettings.ReaderDN = ldapReaderDN
 		settings.LDAPSettings.Password = ldapPassword
@@ -221,6 +297,40 @@ func (handler *Handler) settingsUpdate(w http.ResponseWriter, r *http.Request) *
 		settings.EdgeAgentCheckinInterval = *payload.EdgeAgentCheckinInterval
 	}
 
+	if payload.VulnerabilityScanSettings != nil {
+		if payload.VulnerabilityScanSettings.ScanInterval != settings.VulnerabilityScanSettings.ScanInterval && handler.ImageScanService != nil {
+			err := handler.ImageScanService.Reschedule(*payload.VulnerabilityScanSettings)
+			if err != nil {
+				return httperror.InternalServerError("Unable to update the image scan interval", err)
+			}
+		}
+
+		settings.VulnerabilityScanSettings = *payload.VulnerabilityScanSettings
+	}
+
+	if payload.StackDriftSettings != nil {
+		if *payload.StackDriftSettings != settings.StackDriftSettings && handler.DriftService != nil {
+			err := handler.DriftService.Reschedule(*payload.StackDriftSettings)
+			if err != nil {
+				return httperror.InternalServerError("Unable to update the stack drift check interval", err)
+			}
+		}
+
+		settings.StackDriftSettings = *payload.StackDriftSettings
+	}
+
+	if payload.APIRateLimitSettings != nil {
+		if *payload.APIRateLimitSettings != settings.APIRateLimitSettings && handler.APIRateLimiter != nil {
+			handler.APIRateLimiter.SetSettings(*payload.APIRateLimitSettings)
+		}
+
+		settings.APIRateLimitSettings = *payload.APIRateLimitSettings
+	}
+
+	if payload.SCIMSettings != nil {
+		settings.SCIMSettings.Enabled = payload.SCIMSettings.Enabled
+	}
+
 	if payload.KubeconfigExpiry != nil {
 		settings.KubeconfigExpiry = *payload.KubeconfigExpiry
 	}
@@ -233,6 +343,14 @@ func (handler *Handler) settingsUpdate(w http.ResponseWriter, r *http.Request) *
 		handler.JWTService.SetUserSessionDuration(userSessionDuration)
 	}
 
+	if payload.SessionIdleTimeout != nil {
+		settings.SessionIdleTimeout = *payload.SessionIdleTimeout
+
+		sessionIdleTimeout, _ := time.ParseDuration(*payload.SessionIdleTimeout)
+
+		handler.JWTService.SetSessionIdleTimeout(sessionIdleTimeout)
+	}
+
 	if payload.EnableTelemetry != nil {
 		settings.EnableTelemetry = *payload.EnableTelemetry
 	}
@@ -0,0 +1,62 @@
+package settings
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/demo"
+	"github.com/portainer/portainer/api/filesystem"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettingsUpdate_EnforceAdminMFA(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	fileService, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+
+	h := &Handler{
+		DataStore:   store,
+		FileService: fileService,
+		demoService: demo.NewService(),
+	}
+
+	settings, err := store.Settings().Settings()
+	require.NoError(t, err)
+	settings.InternalAuthSettings.EnforceAdminMFA = true
+	require.NoError(t, store.Settings().UpdateSettings(settings))
+
+	updateSettings := func(payload string) {
+		req := httptest.NewRequest(http.MethodPut, "/settings", bytes.NewBufferString(payload))
+		rr := httptest.NewRecorder()
+
+		herr := h.settingsUpdate(rr, req)
+		require.Nil(t, herr)
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	t.Run("an omitted field keeps the enforcement", func(t *testing.T) {
+		updateSettings(`{"InternalAuthSettings": {"RequiredPasswordLength": 14}}`)
+
+		settings, err := store.Settings().Settings()
+		require.NoError(t, err)
+		is.Equal(14, settings.InternalAuthSettings.RequiredPasswordLength)
+		is.True(settings.InternalAuthSettings.EnforceAdminMFA)
+	})
+
+	t.Run("the enforcement is disabled explicitly", func(t *testing.T) {
+		updateSettings(`{"InternalAuthSettings": {"RequiredPasswordLength": 14, "EnforceAdminMFA": false}}`)
+
+		settings, err := store.Settings().Settings()
+		require.NoError(t, err)
+		is.False(settings.InternalAuthSettings.EnforceAdminMFA)
+	})
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/portainer/portainer/api/kubernetes/cli"
 	"github.com/portainer/portainer/api/scheduler"
 	"github.com/portainer/portainer/api/stacks/deployments"
+	"github.com/portainer/portainer/api/stacks/drift"
 	"github.com/portainer/portainer/api/stacks/stackutils"
 )
 
@@ -39,6 +40,7 @@ type Handler struct {
 	KubernetesClientFactory *cli.ClientFactory
 	Scheduler               *scheduler.Scheduler
 	StackDeployer           deployments.StackDeployer
+	DriftService            *drift.Service
 }
 
 func stackExistsError(name string) *httperror.HandlerError {
@@ -74,6 +76,12 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackGitRedeploy))).Methods(http.MethodPut)
 	h.Handle("/stacks/{id}/file",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackFile))).Methods(http.MethodGet)
+	h.Handle("/stacks/{id}/versions",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackVersionList))).Methods(http.MethodGet)
+	h.Handle("/stacks/{id}/rollback/{version}",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackRollback))).Methods(http.MethodPost)
+	h.Handle("/stacks/{id}/drift",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackDrift))).Methods(http.MethodGet)
 	h.Handle("/stacks/{id}/migrate",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackMigrate))).Methods(http.MethodPost)
 	h.Handle("/stacks/{id}/start",
@@ -128,6 +128,16 @@ func (handler *Handler) stackDelete(w http.ResponseWriter, r *http.Request) *htt
 		return httperror.InternalServerError("Unable to remove the stack from the database", err)
 	}
 
+	err = handler.DataStore.StackVersion().DeleteStackVersions(stack.ID)
+	if err != nil {
+		log.Warn().Err(err).Msg("Unable to remove the stack versions from the database")
+	}
+
+	err = handler.DataStore.Webhook().DeleteResourceWebhooks(stack.EndpointID, strconv.Itoa(int(stack.ID)), portainer.StackWebhook)
+	if err != nil {
+		log.Warn().Err(err).Msg("Unable to remove the stack webhooks from the database")
+	}
+
 	if resourceControl != nil {
 		err = handler.DataStore.ResourceControl().DeleteResourceControl(resourceControl.ID)
 		if err != nil {
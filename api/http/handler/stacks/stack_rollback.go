@@ -0,0 +1,177 @@
+package stacks
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/stacks/stackutils"
+	"github.com/rs/zerolog/log"
+)
+
+// @id StackRollback
+// @summary Rollback a stack to a previous version
+// @description Restore the files and the environment variables of a previous version of a stack and redeploy it.
+// @description The rollback is recorded as a new version of the stack.
+// @description **Access policy**: authenticated
+// @tags stacks
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param id path int true "Stack identifier"
+// @param version path int true "Stack version number"
+// @success 200 {object} portainer.Stack "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Stack or version not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/rollback/{version} [post]
+func (handler *Handler) stackRollback(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	stackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid stack identifier route variable", err)
+	}
+
+	versionNumber, err := request.RetrieveNumericRouteVariableValue(r, "version")
+	if err != nil {
+		return httperror.BadRequest("Invalid stack version route variable", err)
+	}
+
+	stack, err := handler.DataStore.Stack().Stack(portainer.StackID(stackID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find a stack with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find a stack with the specified identifier inside the database", err)
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(stack.EndpointID)
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find the environment associated to the stack inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find the environment associated to the stack inside the database", err)
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return httperror.Forbidden("Permission denied to access environment", err)
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve info from request context", err)
+	}
+
+	//only check resource control when it is a DockerSwarmStack or a DockerComposeStack
+	if stack.Type == portainer.DockerSwarmStack || stack.Type == portainer.DockerComposeStack {
+		resourceControl, err := handler.DataStore.ResourceControl().ResourceControlByResourceIDAndType(stackutils.ResourceControlID(stack.EndpointID, stack.Name), portainer.StackResourceControl)
+		if err != nil {
+			return httperror.InternalServerError("Unable to retrieve a resource control associated to the stack", err)
+		}
+
+		access, err := handler.userCanAccessStack(securityContext, endpoint.ID, resourceControl)
+		if err != nil {
+			return httperror.InternalServerError("Unable to verify user authorizations to validate stack access", err)
+		}
+		if !access {
+			return httperror.Forbidden("Access denied to resource", httperrors.ErrResourceAccessDenied)
+		}
+	}
+
+	canManage, err := handler.userCanManageStacks(securityContext, endpoint)
+	if err != nil {
+		return httperror.InternalServerError("Unable to verify user authorizations to validate stack management", err)
+	}
+	if !canManage {
+		errMsg := "Stack management is disabled for non-admin users"
+		return httperror.Forbidden(errMsg, errors.New(errMsg))
+	}
+
+	versions, err := handler.DataStore.StackVersion().StackVersions(stack.ID)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the stack versions from the database", err)
+	}
+
+	var version *portainer.StackVersion
+	for i := range versions {
+		if versions[i].Version == versionNumber {
+			version = &versions[i]
+			break
+		}
+	}
+	if version == nil {
+		return httperror.NotFound("Unable to find the specified version of the stack", errors.Errorf("version %d not found for the stack %d", versionNumber, stack.ID))
+	}
+
+	// keep a revision of the stack as it is currently deployed
+	handler.recordStackVersion(stack)
+
+	stackFolder := strconv.Itoa(int(stack.ID))
+	restoredFiles := make([]string, 0, len(version.Files))
+	rollbackFiles := func() {
+		for _, fileName := range restoredFiles {
+			if rollbackErr := handler.FileService.RollbackStackFile(stackFolder, fileName); rollbackErr != nil {
+				log.Warn().Err(rollbackErr).Msg("rollback stack file error")
+			}
+		}
+	}
+
+	for fileName, content := range version.Files {
+		restoredFiles = append(restoredFiles, fileName)
+
+		_, err = handler.FileService.UpdateStoreStackFileFromBytes(stackFolder, fileName, []byte(content))
+		if err != nil {
+			rollbackFiles()
+			return httperror.InternalServerError("Unable to persist the stack files on disk", err)
+		}
+	}
+
+	// the revisions recorded before the entry point was tracked keep the current files
+	if version.EntryPoint != "" {
+		stack.EntryPoint = version.EntryPoint
+		stack.AdditionalFiles = version.AdditionalFiles
+	}
+
+	stack.Env = version.Env
+	if stack.GitConfig != nil {
+		stack.GitConfig.ConfigHash = version.ConfigHash
+	}
+
+	httpErr := handler.deployStack(r, stack, false, endpoint)
+	if httpErr != nil {
+		rollbackFiles()
+		return httpErr
+	}
+
+	for _, fileName := range restoredFiles {
+		handler.FileService.RemoveStackFileBackup(stackFolder, fileName)
+	}
+
+	user, err := handler.DataStore.User().User(securityContext.UserID)
+	if err != nil {
+		return httperror.BadRequest("Cannot find context user", errors.Wrap(err, "failed to fetch the user"))
+	}
+	stack.UpdatedBy = user.Username
+	stack.UpdateDate = time.Now().Unix()
+	stack.Status = portainer.StackStatusActive
+
+	err = handler.DataStore.Stack().UpdateStack(stack.ID, stack)
+	if err != nil {
+		return httperror.InternalServerError("Unable to persist the stack changes inside the database", err)
+	}
+
+	handler.recordStackVersion(stack)
+
+	if stack.GitConfig != nil && stack.GitConfig.Authentication != nil && stack.GitConfig.Authentication.Password != "" {
+		// sanitize password in the http response to minimise possible security leaks
+		stack.GitConfig.Authentication.Password = ""
+	}
+
+	return response.JSON(w, stack)
+}
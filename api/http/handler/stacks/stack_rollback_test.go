@@ -0,0 +1,139 @@
+package stacks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/filesystem"
+	gittypes "github.com/portainer/portainer/api/git/types"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/jwt"
+	"github.com/portainer/portainer/api/stacks/stackutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testStackDeployer struct {
+	deployedEntryPoints []string
+}
+
+func (deployer *testStackDeployer) DeploySwarmStack(stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, prune bool, pullImage bool) error {
+	deployer.deployedEntryPoints = append(deployer.deployedEntryPoints, stack.EntryPoint)
+	return nil
+}
+
+func (deployer *testStackDeployer) DeployComposeStack(stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, forcePullImage bool, forceRecreate bool) error {
+	deployer.deployedEntryPoints = append(deployer.deployedEntryPoints, stack.EntryPoint)
+	return nil
+}
+
+func (deployer *testStackDeployer) DeployKubernetesStack(stack *portainer.Stack, endpoint *portainer.Endpoint, user *portainer.User) error {
+	return nil
+}
+
+func TestStackRollback(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	fileService, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+
+	deployer := &testStackDeployer{}
+
+	h := NewHandler(security.NewRequestBouncer(store, jwtService, apikey.NewAPIKeyService(store.APIKeyRepository(), store.User())))
+	h.DataStore = store
+	h.FileService = fileService
+	h.StackDeployer = deployer
+
+	admin := &portainer.User{ID: 1, Username: "admin", Role: portainer.AdministratorRole}
+	require.NoError(t, store.User().Create(admin))
+
+	endpoint := &portainer.Endpoint{ID: 1, Name: "local", Type: portainer.DockerEnvironment}
+	require.NoError(t, store.Endpoint().Create(endpoint))
+
+	stackFolder := "1"
+	projectPath, err := fileService.StoreStackFileFromBytes(stackFolder, "docker-compose.yml", []byte("services:\n  web:\n    image: nginx:1.23\n"))
+	require.NoError(t, err)
+
+	stack := &portainer.Stack{
+		ID:          1,
+		Name:        "web",
+		Type:        portainer.DockerComposeStack,
+		EndpointID:  endpoint.ID,
+		EntryPoint:  "docker-compose.yml",
+		ProjectPath: projectPath,
+		Status:      portainer.StackStatusActive,
+		CreatedBy:   admin.Username,
+	}
+	require.NoError(t, store.Stack().Create(stack))
+
+	first, err := stackutils.RecordStackVersion(store, fileService, stack)
+	require.NoError(t, err)
+	is.Equal("docker-compose.yml", first.EntryPoint)
+
+	// the second version moves the services to another entry point with an override file
+	_, err = fileService.StoreStackFileFromBytes(stackFolder, "compose.yml", []byte("services:\n  web:\n    image: nginx:1.24\n"))
+	require.NoError(t, err)
+	_, err = fileService.StoreStackFileFromBytes(stackFolder, "override.yml", []byte("services:\n  web:\n    restart: always\n"))
+	require.NoError(t, err)
+	_, err = fileService.StoreStackFileFromBytes(stackFolder, "docker-compose.yml", []byte("# moved to compose.yml\n"))
+	require.NoError(t, err)
+
+	stack.EntryPoint = "compose.yml"
+	stack.AdditionalFiles = []string{"override.yml"}
+	require.NoError(t, store.Stack().UpdateStack(stack.ID, stack))
+
+	second, err := stackutils.RecordStackVersion(store, fileService, stack)
+	require.NoError(t, err)
+	is.Equal(2, second.Version)
+	is.Equal([]string{"override.yml"}, second.AdditionalFiles)
+
+	token, err := jwtService.GenerateToken(&portainer.TokenData{ID: admin.ID, Username: admin.Username, Role: admin.Role})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/stacks/%d/rollback/%d", stack.ID, first.Version), nil)
+	req.Header.Add("Authorization", "Bearer "+token)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var response portainer.Stack
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	is.Equal("docker-compose.yml", response.EntryPoint)
+	is.Empty(response.AdditionalFiles)
+
+	is.Equal([]string{"docker-compose.yml"}, deployer.deployedEntryPoints, "the entry point of the version should be deployed")
+
+	updatedStack, err := store.Stack().Stack(stack.ID)
+	require.NoError(t, err)
+	is.Equal("docker-compose.yml", updatedStack.EntryPoint)
+	is.Empty(updatedStack.AdditionalFiles)
+
+	content, err := fileService.GetFileContent(projectPath, "docker-compose.yml")
+	require.NoError(t, err)
+	is.Equal("services:\n  web:\n    image: nginx:1.23\n", string(content))
+
+	versions, err := store.StackVersion().StackVersions(stack.ID)
+	require.NoError(t, err)
+	is.Len(versions, 3, "the rollback should be recorded as a new version")
+}
+
+func Test_kubernetesStackKind(t *testing.T) {
+	is := assert.New(t)
+
+	is.Equal("content", kubernetesStackKind(&portainer.Stack{ID: 1, Type: portainer.KubernetesStack}))
+	is.Equal("git", kubernetesStackKind(&portainer.Stack{ID: 2, Type: portainer.KubernetesStack, GitConfig: &gittypes.RepoConfig{URL: "https://github.com/portainer/stacks"}}))
+}
@@ -157,6 +157,9 @@ func (handler *Handler) stackGitRedeploy(w http.ResponseWriter, r *http.Request)
 		TLSSkipVerify: stack.GitConfig.TLSSkipVerify,
 	}
 
+	// keep a revision of the stack as it was deployed before this update
+	handler.recordStackVersion(stack)
+
 	clean, err := git.CloneWithBackup(handler.GitService, handler.FileService, cloneOptions)
 	if err != nil {
 		return httperror.InternalServerError("Unable to clone git repository directory", err)
@@ -188,6 +191,8 @@ func (handler *Handler) stackGitRedeploy(w http.ResponseWriter, r *http.Request)
 		return httperror.InternalServerError("Unable to persist the stack changes inside the database", errors.Wrap(err, "failed to update the stack"))
 	}
 
+	handler.recordStackVersion(stack)
+
 	if stack.GitConfig != nil && stack.GitConfig.Authentication != nil && stack.GitConfig.Authentication.Password != "" {
 		// sanitize password in the http response to minimise possible security leaks
 		stack.GitConfig.Authentication.Password = ""
@@ -249,7 +254,7 @@ func (handler *Handler) deployStack(r *http.Request, stack *portainer.Stack, pul
 			StackID:   int(stack.ID),
 			StackName: stack.Name,
 			Owner:     tokenData.Username,
-			Kind:      "git",
+			Kind:      kubernetesStackKind(stack),
 		}
 
 		deploymentConfiger, err = deployments.CreateKubernetesStackDeploymentConfig(stack, handler.KubernetesDeployer, appLabel, user, endpoint)
@@ -266,3 +271,12 @@ func (handler *Handler) deployStack(r *http.Request, stack *portainer.Stack, pul
 	}
 	return nil
 }
+
+// kubernetesStackKind returns the kind of deployment recorded in the labels of the applications of a Kubernetes stack
+func kubernetesStackKind(stack *portainer.Stack) string {
+	if stack.GitConfig != nil {
+		return "git"
+	}
+
+	return "content"
+}
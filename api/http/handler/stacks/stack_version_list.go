@@ -0,0 +1,128 @@
+package stacks
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/stacks/stackutils"
+	"github.com/rs/zerolog/log"
+)
+
+type stackVersionResponse struct {
+	portainer.StackVersion
+	// Unified diff of the files and of the environment variables against the previous version
+	Diff string `json:"Diff" example:"--- docker-compose.yml (version 1)\n+++ docker-compose.yml (version 2)\n"`
+}
+
+// @id StackVersionList
+// @summary List the versions of a stack
+// @description List the revisions recorded for a stack, from the most recent to the oldest one.
+// @description Each version contains the diff against the previous one.
+// @description **Access policy**: restricted
+// @tags stacks
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param id path int true "Stack identifier"
+// @success 200 {array} stackVersionResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Stack not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/versions [get]
+func (handler *Handler) stackVersionList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	stackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return httperror.BadRequest("Invalid stack identifier route variable", err)
+	}
+
+	stack, err := handler.DataStore.Stack().Stack(portainer.StackID(stackID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find a stack with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find a stack with the specified identifier inside the database", err)
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve info from request context", err)
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(stack.EndpointID)
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		if !securityContext.IsAdmin {
+			return httperror.NotFound("Unable to find an environment with the specified identifier inside the database", err)
+		}
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find an environment with the specified identifier inside the database", err)
+	}
+
+	canManage, err := handler.userCanManageStacks(securityContext, endpoint)
+	if err != nil {
+		return httperror.InternalServerError("Unable to verify user authorizations to validate stack management", err)
+	}
+	if !canManage {
+		errMsg := "Stack management is disabled for non-admin users"
+		return httperror.Forbidden(errMsg, errors.New(errMsg))
+	}
+
+	if endpoint != nil {
+		err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+		if err != nil {
+			return httperror.Forbidden("Permission denied to access environment", err)
+		}
+
+		if stack.Type == portainer.DockerSwarmStack || stack.Type == portainer.DockerComposeStack {
+			resourceControl, err := handler.DataStore.ResourceControl().ResourceControlByResourceIDAndType(stackutils.ResourceControlID(stack.EndpointID, stack.Name), portainer.StackResourceControl)
+			if err != nil {
+				return httperror.InternalServerError("Unable to retrieve a resource control associated to the stack", err)
+			}
+
+			access, err := handler.userCanAccessStack(securityContext, endpoint.ID, resourceControl)
+			if err != nil {
+				return httperror.InternalServerError("Unable to verify user authorizations to validate stack access", err)
+			}
+			if !access {
+				return httperror.Forbidden("Access denied to resource", httperrors.ErrResourceAccessDenied)
+			}
+		}
+	}
+
+	versions, err := handler.DataStore.StackVersion().StackVersions(stack.ID)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the stack versions from the database", err)
+	}
+	stackutils.SortStackVersions(versions)
+
+	result := make([]stackVersionResponse, len(versions))
+	for i := range versions {
+		var previous *portainer.StackVersion
+		if i > 0 {
+			previous = &versions[i-1]
+		}
+
+		diff, err := stackutils.StackVersionDiff(previous, &versions[i])
+		if err != nil {
+			return httperror.InternalServerError("Unable to compute the stack version diff", err)
+		}
+
+		// most recent version first
+		result[len(versions)-1-i] = stackVersionResponse{StackVersion: versions[i], Diff: diff}
+	}
+
+	return response.JSON(w, result)
+}
+
+// recordStackVersion stores a revision of the stack, a failure does not prevent the stack operation
+func (handler *Handler) recordStackVersion(stack *portainer.Stack) {
+	_, err := stackutils.RecordStackVersion(handler.DataStore, handler.FileService, stack)
+	if err != nil {
+		log.Warn().Err(err).Int("stack_id", int(stack.ID)).Msg("unable to record the stack version")
+	}
+}
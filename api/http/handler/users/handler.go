@@ -10,6 +10,7 @@ import (
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/demo"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/mfa"
 
 	"net/http"
 
@@ -26,6 +27,7 @@ var (
 
 func hideFields(user *portainer.User) {
 	user.Password = ""
+	user.MFA = portainer.UserMFA{Enabled: user.MFA.Enabled}
 }
 
 // Handler is the HTTP handler used to handle user operations.
@@ -37,6 +39,8 @@ type Handler struct {
 	DataStore               dataservices.DataStore
 	CryptoService           portainer.CryptoService
 	passwordStrengthChecker security.PasswordStrengthChecker
+	MFAService              *mfa.Service
+	JWTService              dataservices.JWTService
 }
 
 // NewHandler creates a handler to manage user operations.
@@ -73,6 +77,12 @@ func NewHandler(bouncer *security.RequestBouncer, rateLimiter *security.RateLimi
 	restrictedRouter.Handle("/users/{id}/tokens", rateLimiter.LimitAccess(httperror.LoggerHandler(h.userCreateAccessToken))).Methods(http.MethodPost)
 	restrictedRouter.Handle("/users/{id}/tokens/{keyID}", httperror.LoggerHandler(h.userRemoveAccessToken)).Methods(http.MethodDelete)
 	restrictedRouter.Handle("/users/{id}/memberships", httperror.LoggerHandler(h.userMemberships)).Methods(http.MethodGet)
+	authenticatedRouter.Handle("/users/{id}/mfa/enroll", rateLimiter.LimitAccess(httperror.LoggerHandler(h.userMFAEnroll))).Methods(http.MethodPost)
+	authenticatedRouter.Handle("/users/{id}/mfa/activate", rateLimiter.LimitAccess(httperror.LoggerHandler(h.userMFAActivate))).Methods(http.MethodPost)
+	authenticatedRouter.Handle("/users/{id}/mfa", rateLimiter.LimitAccess(httperror.LoggerHandler(h.userMFAReset))).Methods(http.MethodDelete)
+	authenticatedRouter.Handle("/users/{id}/sessions", httperror.LoggerHandler(h.userSessionList)).Methods(http.MethodGet)
+	authenticatedRouter.Handle("/users/{id}/sessions", httperror.LoggerHandler(h.userSessionDeleteAll)).Methods(http.MethodDelete)
+	authenticatedRouter.Handle("/users/{id}/sessions/{sessionId}", httperror.LoggerHandler(h.userSessionDelete)).Methods(http.MethodDelete)
 	authenticatedRouter.Handle("/users/{id}/passwd", rateLimiter.LimitAccess(httperror.LoggerHandler(h.userUpdatePassword))).Methods(http.MethodPut)
 	publicRouter.Handle("/users/admin/check", httperror.LoggerHandler(h.adminCheck)).Methods(http.MethodGet)
 	publicRouter.Handle("/users/admin/init", httperror.LoggerHandler(h.adminInit)).Methods(http.MethodPost)
@@ -9,12 +9,31 @@ import (
 	"github.com/portainer/libhttp/request"
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
 	httperrors "github.com/portainer/portainer/api/http/errors"
 	"github.com/portainer/portainer/api/http/security"
 )
 
 type userAccessTokenCreatePayload struct {
 	Description string `validate:"required" example:"github-api-key" json:"description"`
+	// Unix timestamp (UTC) after which the API key is rejected, the API key never expires when 0
+	ExpiresAt int64 `example:"1672531200" json:"expiresAt"`
+	// Only allow the read-only requests (GET, HEAD and OPTIONS)
+	ReadOnly bool `example:"false" json:"readOnly"`
+	// Environment(Endpoint) identifiers the API key is restricted to, all the environments are allowed when empty
+	EndpointIDs []portainer.EndpointID `example:"1" json:"endpointIds"`
+	// Route scopes the API key is restricted to, all the routes are allowed when empty.
+	// A scope is an API path prefix without the identifiers, such as "stacks" or "endpoints/docker"
+	Scopes []string `example:"stacks" json:"scopes"`
+}
+
+func (payload *userAccessTokenCreatePayload) restrictions() portainer.APIKeyRestrictions {
+	return portainer.APIKeyRestrictions{
+		ExpiresAt:   payload.ExpiresAt,
+		ReadOnly:    payload.ReadOnly,
+		EndpointIDs: payload.EndpointIDs,
+		Scopes:      payload.Scopes,
+	}
 }
 
 func (payload *userAccessTokenCreatePayload) Validate(r *http.Request) error {
@@ -27,7 +46,7 @@ func (payload *userAccessTokenCreatePayload) Validate(r *http.Request) error {
 	if govalidator.MinStringLength(payload.Description, "128") {
 		return errors.New("invalid description. cannot be longer than 128 characters")
 	}
-	return nil
+	return apikey.ValidateRestrictions(payload.restrictions())
 }
 
 type accessTokenResponse struct {
@@ -39,6 +58,7 @@ type accessTokenResponse struct {
 // @summary Generate an API key for a user
 // @description Generates an API key for a user.
 // @description Only the calling user can generate a token for themselves.
+// @description The API key can be restricted with an expiry, a read-only flag, an allow-list of environments and route scopes.
 // @description **Access policy**: restricted
 // @tags users
 // @security jwt
@@ -84,7 +104,16 @@ func (handler *Handler) userCreateAccessToken(w http.ResponseWriter, r *http.Req
 		return httperror.BadRequest("Unable to find a user", err)
 	}
 
-	rawAPIKey, apiKey, err := handler.apiKeyService.GenerateApiKey(*user, payload.Description)
+	for _, endpointID := range payload.EndpointIDs {
+		_, err := handler.DataStore.Endpoint().Endpoint(endpointID)
+		if handler.DataStore.IsErrObjectNotFound(err) {
+			return httperror.BadRequest("Unable to find an environment with the specified identifier inside the database", err)
+		} else if err != nil {
+			return httperror.InternalServerError("Unable to find an environment with the specified identifier inside the database", err)
+		}
+	}
+
+	rawAPIKey, apiKey, err := handler.apiKeyService.GenerateRestrictedApiKey(*user, payload.Description, payload.restrictions())
 	if err != nil {
 		return httperror.InternalServerError("Internal Server Error", err)
 	}
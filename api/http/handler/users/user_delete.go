@@ -94,6 +94,11 @@ func (handler *Handler) deleteUser(w http.ResponseWriter, user *portainer.User)
 		return httperror.InternalServerError("Unable to remove user memberships from the database", err)
 	}
 
+	err = handler.DataStore.Session().DeleteSessionsByUserID(user.ID)
+	if err != nil {
+		return httperror.InternalServerError("Unable to remove user sessions from the database", err)
+	}
+
 	// Remove all of the users persisted API keys
 	apiKeys, err := handler.apiKeyService.GetAPIKeys(user.ID)
 	if err != nil {
@@ -0,0 +1,218 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/asaskevich/govalidator"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/mfa"
+)
+
+type userMFAActivatePayload struct {
+	// TOTP code generated with the secret returned by the enrollment
+	Code string `json:"code" example:"123456" validate:"required"`
+}
+
+func (payload *userMFAActivatePayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Code) {
+		return errors.New("Invalid code")
+	}
+
+	return nil
+}
+
+type userMFAResetPayload struct {
+	// TOTP code or recovery code of the user, required when users reset their own multi-factor authentication
+	Code string `json:"code" example:"123456" validate:"required"`
+}
+
+func (payload *userMFAResetPayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Code) {
+		return errors.New("Invalid code")
+	}
+
+	return nil
+}
+
+type userMFAActivateResponse struct {
+	// Single use recovery codes, they cannot be retrieved afterwards
+	RecoveryCodes []string `json:"recoveryCodes" example:"abcde-fghij"`
+}
+
+// @id UserMFAEnroll
+// @summary Start the TOTP enrollment of a user
+// @description Generate a new TOTP secret for a user, the multi-factor authentication is enabled once a code is verified by the activation.
+// @description Only the calling user can enroll themselves.
+// @description **Access policy**: authenticated
+// @tags users
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param id path int true "User identifier"
+// @success 200 {object} mfa.Enrollment "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "User not found"
+// @failure 409 "Multi-factor authentication is already enabled"
+// @failure 500 "Server error"
+// @router /users/{id}/mfa/enroll [post]
+func (handler *Handler) userMFAEnroll(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	user, httpErr := handler.retrieveMFAUser(r, false)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	enrollment, err := handler.MFAService.Enroll(user)
+	if errors.Is(err, mfa.ErrAlreadyEnabled) {
+		return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: "Multi-factor authentication is already enabled, it must be reset first", Err: err}
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to enroll the user", err)
+	}
+
+	return response.JSON(w, enrollment)
+}
+
+// @id UserMFAActivate
+// @summary Complete the TOTP enrollment of a user
+// @description Enable the multi-factor authentication of a user with a TOTP code and return the recovery codes of the user.
+// @description Only the calling user can activate their multi-factor authentication.
+// @description **Access policy**: authenticated
+// @tags users
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "User identifier"
+// @param body body userMFAActivatePayload true "TOTP code"
+// @success 200 {object} userMFAActivateResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "User not found"
+// @failure 409 "Multi-factor authentication is already enabled"
+// @failure 422 "Invalid code"
+// @failure 500 "Server error"
+// @router /users/{id}/mfa/activate [post]
+func (handler *Handler) userMFAActivate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload userMFAActivatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return httperror.BadRequest("Invalid request payload", err)
+	}
+
+	user, httpErr := handler.retrieveMFAUser(r, false)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	recoveryCodes, err := handler.MFAService.Activate(user, payload.Code)
+	switch {
+	case errors.Is(err, mfa.ErrAlreadyEnabled):
+		return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: "Multi-factor authentication is already enabled", Err: err}
+	case errors.Is(err, mfa.ErrNotEnrolled):
+		return httperror.BadRequest("Multi-factor authentication enrollment has not been started", err)
+	case errors.Is(err, mfa.ErrInvalidCode):
+		return &httperror.HandlerError{StatusCode: http.StatusUnprocessableEntity, Message: "Invalid multi-factor authentication code", Err: err}
+	case err != nil:
+		return httperror.InternalServerError("Unable to activate the multi-factor authentication", err)
+	}
+
+	return response.JSON(w, &userMFAActivateResponse{RecoveryCodes: recoveryCodes})
+}
+
+// @id UserMFAReset
+// @summary Reset the multi-factor authentication of a user
+// @description Disable the multi-factor authentication of a user and remove its TOTP secret and recovery codes.
+// @description Administrators can reset the multi-factor authentication of any user, the other users can only reset their own.
+// @description Users resetting their own multi-factor authentication must provide a TOTP code or a recovery code.
+// @description An administrator cannot reset their own multi-factor authentication when it is enforced for the administrators.
+// @description **Access policy**: authenticated
+// @tags users
+// @security ApiKeyAuth
+// @security jwt
+// @accept json
+// @param id path int true "User identifier"
+// @param body body userMFAResetPayload false "TOTP code or recovery code, required to reset the multi-factor authentication of the calling user"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "User not found"
+// @failure 422 "Invalid code"
+// @failure 500 "Server error"
+// @router /users/{id}/mfa [delete]
+func (handler *Handler) userMFAReset(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	user, httpErr := handler.retrieveMFAUser(r, true)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve user authentication token", err)
+	}
+
+	if tokenData.ID == user.ID {
+		settings, err := handler.DataStore.Settings().Settings()
+		if err != nil {
+			return httperror.InternalServerError("Unable to retrieve settings from the database", err)
+		}
+
+		if mfa.IsEnforced(user, settings) {
+			return httperror.Forbidden("Multi-factor authentication is enforced for the administrators", httperrors.ErrUnauthorized)
+		}
+
+		// a stolen session must not be enough to remove the second factor of a user
+		if user.MFA.Enabled {
+			var payload userMFAResetPayload
+			err = request.DecodeAndValidateJSONPayload(r, &payload)
+			if err != nil {
+				return httperror.BadRequest("Invalid request payload", err)
+			}
+
+			err = handler.MFAService.Verify(user, payload.Code)
+			if errors.Is(err, mfa.ErrInvalidCode) {
+				return &httperror.HandlerError{StatusCode: http.StatusUnprocessableEntity, Message: "Invalid multi-factor authentication code", Err: err}
+			} else if err != nil {
+				return httperror.InternalServerError("Unable to verify the multi-factor authentication code", err)
+			}
+		}
+	}
+
+	err = handler.MFAService.Reset(user)
+	if err != nil {
+		return httperror.InternalServerError("Unable to reset the multi-factor authentication", err)
+	}
+
+	return response.Empty(w)
+}
+
+// retrieveMFAUser retrieves the user of the request, allowAdmin allows the administrators to operate on any user
+func (handler *Handler) retrieveMFAUser(r *http.Request, allowAdmin bool) (*portainer.User, *httperror.HandlerError) {
+	userID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, httperror.BadRequest("Invalid user identifier route variable", err)
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return nil, httperror.InternalServerError("Unable to retrieve user authentication token", err)
+	}
+
+	if tokenData.ID != portainer.UserID(userID) && !(allowAdmin && tokenData.Role == portainer.AdministratorRole) {
+		return nil, httperror.Forbidden("Permission denied to update the multi-factor authentication of the user", httperrors.ErrUnauthorized)
+	}
+
+	user, err := handler.DataStore.User().User(portainer.UserID(userID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return nil, httperror.NotFound("Unable to find a user with the specified identifier inside the database", err)
+	} else if err != nil {
+		return nil, httperror.InternalServerError("Unable to find a user with the specified identifier inside the database", err)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,94 @@
+package users
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/jwt"
+	"github.com/portainer/portainer/api/mfa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_userMFAReset(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	adminUser := &portainer.User{ID: 1, Username: "admin", Role: portainer.AdministratorRole}
+	require.NoError(t, store.User().Create(adminUser))
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+	apiKeyService := apikey.NewAPIKeyService(store.APIKeyRepository(), store.User())
+	requestBouncer := security.NewRequestBouncer(store, jwtService, apiKeyService)
+	rateLimiter := security.NewRateLimiter(10, 1*time.Second, 1*time.Hour)
+	passwordChecker := security.NewPasswordStrengthChecker(store.SettingsService)
+
+	h := NewHandler(requestBouncer, rateLimiter, apiKeyService, nil, passwordChecker)
+	h.DataStore = store
+	h.MFAService = mfa.NewService(store, []byte("secret"))
+
+	// enableMFA creates a user whose MFA is enabled and returns its recovery codes
+	enableMFA := func(id portainer.UserID) (*portainer.User, []string) {
+		user := &portainer.User{ID: id, Username: fmt.Sprintf("standard%d", id), Role: portainer.StandardUserRole}
+		require.NoError(t, store.User().Create(user))
+
+		enrollment, err := h.MFAService.Enroll(user)
+		require.NoError(t, err)
+
+		code, err := mfa.GenerateCode(enrollment.Secret, time.Now())
+		require.NoError(t, err)
+
+		recoveryCodes, err := h.MFAService.Activate(user, code)
+		require.NoError(t, err)
+
+		return user, recoveryCodes
+	}
+
+	reset := func(caller, user *portainer.User, body string) int {
+		token, _ := jwtService.GenerateToken(&portainer.TokenData{ID: caller.ID, Username: caller.Username, Role: caller.Role})
+
+		req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%d/mfa", user.ID), strings.NewReader(body))
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		return rr.Code
+	}
+
+	isEnabled := func(user *portainer.User) bool {
+		user, err := store.User().User(user.ID)
+		require.NoError(t, err)
+
+		return user.MFA.Enabled
+	}
+
+	t.Run("users need a code to reset their own MFA", func(t *testing.T) {
+		user, recoveryCodes := enableMFA(2)
+
+		is.Equal(http.StatusBadRequest, reset(user, user, ""))
+		is.Equal(http.StatusUnprocessableEntity, reset(user, user, `{"code":"00000-00000"}`))
+		is.True(isEnabled(user))
+
+		is.Equal(http.StatusNoContent, reset(user, user, fmt.Sprintf(`{"code":%q}`, recoveryCodes[0])))
+		is.False(isEnabled(user))
+	})
+
+	t.Run("administrators reset the MFA of the other users without a code", func(t *testing.T) {
+		user, _ := enableMFA(3)
+
+		is.Equal(http.StatusNoContent, reset(adminUser, user, ""))
+		is.False(isEnabled(user))
+	})
+}
@@ -0,0 +1,169 @@
+package users
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+type userSession struct {
+	portainer.Session
+	// Whether the session is the one used by the request
+	Current bool `json:"Current" example:"true"`
+}
+
+// @id UserSessionList
+// @summary List the active sessions of a user
+// @description List the active sessions of a user, the most recently used first.
+// @description Only the calling user or an administrator can list the sessions.
+// @description **Access policy**: authenticated
+// @tags users
+// @security ApiKeyAuth
+// @security jwt
+// @produce json
+// @param id path int true "User identifier"
+// @success 200 {array} userSession "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "User not found"
+// @failure 500 "Server error"
+// @router /users/{id}/sessions [get]
+func (handler *Handler) userSessionList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	user, tokenData, httpErr := handler.retrieveSessionUser(r)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	sessions, err := handler.DataStore.Session().SessionsByUserID(user.ID)
+	if err != nil {
+		return httperror.InternalServerError("Unable to retrieve the sessions from the database", err)
+	}
+
+	now := time.Now()
+	activeSessions := make([]userSession, 0, len(sessions))
+	for _, session := range sessions {
+		if !handler.JWTService.IsSessionActive(&session, now) {
+			continue
+		}
+
+		activeSessions = append(activeSessions, userSession{
+			Session: session,
+			Current: session.ID == tokenData.SessionID,
+		})
+	}
+
+	sort.SliceStable(activeSessions, func(i, j int) bool {
+		return activeSessions[i].LastSeenAt > activeSessions[j].LastSeenAt
+	})
+
+	return response.JSON(w, activeSessions)
+}
+
+// @id UserSessionDelete
+// @summary Revoke a session of a user
+// @description Revoke a session of a user, the tokens of the session are rejected afterwards.
+// @description Only the calling user or an administrator can revoke the session.
+// @description **Access policy**: authenticated
+// @tags users
+// @security ApiKeyAuth
+// @security jwt
+// @param id path int true "User identifier"
+// @param sessionId path int true "Session identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Session not found"
+// @failure 500 "Server error"
+// @router /users/{id}/sessions/{sessionId} [delete]
+func (handler *Handler) userSessionDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	sessionID, err := request.RetrieveNumericRouteVariableValue(r, "sessionId")
+	if err != nil {
+		return httperror.BadRequest("Invalid session identifier route variable", err)
+	}
+
+	user, _, httpErr := handler.retrieveSessionUser(r)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	session, err := handler.DataStore.Session().Session(portainer.SessionID(sessionID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find a session with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find a session with the specified identifier inside the database", err)
+	}
+
+	// the sessions of the other users are reported as missing to avoid disclosing them
+	if session.UserID != user.ID {
+		return httperror.NotFound("Unable to find a session with the specified identifier inside the database", httperrors.ErrUnauthorized)
+	}
+
+	err = handler.DataStore.Session().DeleteSession(session.ID)
+	if err != nil {
+		return httperror.InternalServerError("Unable to remove the session from the database", err)
+	}
+
+	return response.Empty(w)
+}
+
+// @id UserSessionDeleteAll
+// @summary Revoke all the sessions of a user
+// @description Revoke all the sessions of a user, including the session used by the request.
+// @description Only the calling user or an administrator can revoke the sessions.
+// @description **Access policy**: authenticated
+// @tags users
+// @security ApiKeyAuth
+// @security jwt
+// @param id path int true "User identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "User not found"
+// @failure 500 "Server error"
+// @router /users/{id}/sessions [delete]
+func (handler *Handler) userSessionDeleteAll(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	user, _, httpErr := handler.retrieveSessionUser(r)
+	if httpErr != nil {
+		return httpErr
+	}
+
+	err := handler.DataStore.Session().DeleteSessionsByUserID(user.ID)
+	if err != nil {
+		return httperror.InternalServerError("Unable to remove the sessions from the database", err)
+	}
+
+	return response.Empty(w)
+}
+
+// retrieveSessionUser retrieves the user of the request, the administrators can operate on the sessions of any user
+func (handler *Handler) retrieveSessionUser(r *http.Request) (*portainer.User, *portainer.TokenData, *httperror.HandlerError) {
+	userID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, nil, httperror.BadRequest("Invalid user identifier route variable", err)
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return nil, nil, httperror.InternalServerError("Unable to retrieve user authentication token", err)
+	}
+
+	if tokenData.ID != portainer.UserID(userID) && tokenData.Role != portainer.AdministratorRole {
+		return nil, nil, httperror.Forbidden("Permission denied to manage the sessions of the user", httperrors.ErrUnauthorized)
+	}
+
+	user, err := handler.DataStore.User().User(portainer.UserID(userID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return nil, nil, httperror.NotFound("Unable to find a user with the specified identifier inside the database", err)
+	} else if err != nil {
+		return nil, nil, httperror.InternalServerError("Unable to find a user with the specified identifier inside the database", err)
+	}
+
+	return user, tokenData, nil
+}
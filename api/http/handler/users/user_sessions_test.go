@@ -0,0 +1,116 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_userSessions(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	adminUser := &portainer.User{ID: 1, Username: "admin", Role: portainer.AdministratorRole}
+	require.NoError(t, store.User().Create(adminUser))
+
+	user := &portainer.User{ID: 2, Username: "standard", Role: portainer.StandardUserRole}
+	require.NoError(t, store.User().Create(user))
+
+	otherUser := &portainer.User{ID: 3, Username: "other", Role: portainer.StandardUserRole}
+	require.NoError(t, store.User().Create(otherUser))
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+	apiKeyService := apikey.NewAPIKeyService(store.APIKeyRepository(), store.User())
+	requestBouncer := security.NewRequestBouncer(store, jwtService, apiKeyService)
+	rateLimiter := security.NewRateLimiter(10, 1*time.Second, 1*time.Hour)
+	passwordChecker := security.NewPasswordStrengthChecker(store.SettingsService)
+
+	h := NewHandler(requestBouncer, rateLimiter, apiKeyService, nil, passwordChecker)
+	h.DataStore = store
+	h.JWTService = jwtService
+
+	newSession := func(u *portainer.User, userAgent string) (string, portainer.SessionID) {
+		tokenData := &portainer.TokenData{ID: u.ID, Username: u.Username, Role: u.Role}
+		token, err := jwtService.GenerateSessionToken(tokenData, "10.0.0.1", userAgent)
+		require.NoError(t, err)
+
+		return token, tokenData.SessionID
+	}
+
+	serve := func(token, method, url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	adminJWT, _ := newSession(adminUser, "admin-browser")
+	userJWT, userSessionID := newSession(user, "browser")
+	laptopJWT, laptopSessionID := newSession(user, "laptop")
+	otherJWT, otherSessionID := newSession(otherUser, "other-browser")
+
+	t.Run("a user lists their sessions", func(t *testing.T) {
+		rr := serve(userJWT, http.MethodGet, "/users/2/sessions")
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var sessions []userSession
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&sessions))
+		require.Len(t, sessions, 2)
+
+		for _, session := range sessions {
+			is.Equal(user.ID, session.UserID)
+			is.Equal(session.ID == userSessionID, session.Current)
+		}
+	})
+
+	t.Run("a user cannot list or revoke the sessions of another user", func(t *testing.T) {
+		rr := serve(userJWT, http.MethodGet, "/users/3/sessions")
+		is.Equal(http.StatusForbidden, rr.Code)
+
+		rr = serve(userJWT, http.MethodDelete, fmt.Sprintf("/users/2/sessions/%d", otherSessionID))
+		is.Equal(http.StatusNotFound, rr.Code)
+
+		rr = serve(otherJWT, http.MethodGet, "/users/3/sessions")
+		is.Equal(http.StatusOK, rr.Code)
+	})
+
+	t.Run("a user revokes one of their sessions", func(t *testing.T) {
+		rr := serve(userJWT, http.MethodDelete, fmt.Sprintf("/users/2/sessions/%d", laptopSessionID))
+		is.Equal(http.StatusNoContent, rr.Code)
+
+		rr = serve(laptopJWT, http.MethodGet, "/users/2/sessions")
+		is.Equal(http.StatusUnauthorized, rr.Code)
+
+		rr = serve(userJWT, http.MethodGet, "/users/2/sessions")
+		is.Equal(http.StatusOK, rr.Code)
+	})
+
+	t.Run("an administrator revokes all the sessions of a user", func(t *testing.T) {
+		rr := serve(adminJWT, http.MethodDelete, "/users/3/sessions")
+		is.Equal(http.StatusNoContent, rr.Code)
+
+		rr = serve(otherJWT, http.MethodGet, "/users/3/sessions")
+		is.Equal(http.StatusUnauthorized, rr.Code)
+
+		sessions, err := store.Session().SessionsByUserID(otherUser.ID)
+		require.NoError(t, err)
+		is.Empty(sessions)
+	})
+}
@@ -133,8 +133,8 @@ func (handler *Handler) userUpdate(w http.ResponseWriter, r *http.Request) *http
 	// remove all of the users persisted API keys
 	handler.apiKeyService.InvalidateUserKeyCache(user.ID)
 
-	// hide the password field in the response payload
-	user.Password = ""
+	// hide the password and the MFA secrets in the response payload
+	hideFields(user)
 
 	return response.JSON(w, user)
 }
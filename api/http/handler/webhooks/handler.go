@@ -8,6 +8,7 @@ import (
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/portainer/api/docker"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/stacks/deployments"
 
 	"github.com/gorilla/mux"
 )
@@ -18,6 +19,8 @@ type Handler struct {
 	requestBouncer      *security.RequestBouncer
 	DataStore           dataservices.DataStore
 	DockerClientFactory *docker.ClientFactory
+	ContainerService    *docker.ContainerService
+	StackDeployer       deployments.StackDeployer
 }
 
 // NewHandler creates a handler to manage webhooks operations.
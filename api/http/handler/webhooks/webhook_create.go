@@ -3,6 +3,7 @@ package webhooks
 import (
 	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/registryutils/access"
@@ -30,13 +31,21 @@ func (payload *webhookCreatePayload) Validate(r *http.Request) error {
 	if payload.EndpointID == 0 {
 		return errors.New("Invalid EndpointID")
 	}
-	if payload.WebhookType != 1 {
+	switch portainer.WebhookType(payload.WebhookType) {
+	case portainer.ServiceWebhook, portainer.ContainerWebhook:
+	case portainer.StackWebhook:
+		if !govalidator.IsInt(payload.ResourceID) {
+			return errors.New("Invalid ResourceID, the identifier of the stack is expected")
+		}
+	default:
 		return errors.New("Invalid WebhookType")
 	}
 	return nil
 }
 
 // @summary Create a webhook
+// @description Create a webhook for a Swarm service (type 1), a standalone container (type 2) or a compose/swarm stack (type 3).
+// @description The resource identifier of a stack webhook is the identifier of the stack.
 // @description **Access policy**: authenticated
 // @security ApiKeyAuth
 // @security jwt
@@ -75,6 +84,25 @@ func (handler *Handler) webhookCreate(w http.ResponseWriter, r *http.Request) *h
 		return httperror.Forbidden("Not authorized to create a webhook", errors.New("not authorized to create a webhook"))
 	}
 
+	if portainer.WebhookType(payload.WebhookType) == portainer.StackWebhook {
+		stackID, _ := strconv.Atoi(payload.ResourceID)
+
+		stack, err := handler.DataStore.Stack().Stack(portainer.StackID(stackID))
+		if handler.DataStore.IsErrObjectNotFound(err) {
+			return httperror.NotFound("Unable to find a stack with the specified identifier inside the database", err)
+		} else if err != nil {
+			return httperror.InternalServerError("Unable to find a stack with the specified identifier inside the database", err)
+		}
+
+		if stack.EndpointID != endpointID {
+			return httperror.BadRequest("The stack is not deployed on the specified environment", errors.New("stack environment mismatch"))
+		}
+
+		if stack.Type == portainer.KubernetesStack {
+			return httperror.BadRequest("Webhooks are not supported for Kubernetes stacks", errors.New("unsupported stack type"))
+		}
+	}
+
 	if payload.RegistryID != 0 {
 		tokenData, err := security.RetrieveTokenData(r)
 		if err != nil {
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/portainer/portainer/api/internal/registryutils"
 
@@ -17,7 +19,8 @@ import (
 )
 
 // @summary Execute a webhook
-// @description Acts on a passed in token UUID to restart the docker service
+// @description Acts on a passed in token UUID to restart the docker service, recreate the container
+// @description or redeploy the stack associated to the webhook. The image is pulled again before the update.
 // @description **Access policy**: public
 // @tags webhooks
 // @param id path string true "Webhook token"
@@ -58,6 +61,10 @@ func (handler *Handler) webhookExecute(w http.ResponseWriter, r *http.Request) *
 	switch webhookType {
 	case portainer.ServiceWebhook:
 		return handler.executeServiceWebhook(w, endpoint, resourceID, registryID, imageTag)
+	case portainer.ContainerWebhook:
+		return handler.executeContainerWebhook(w, webhook, endpoint, registryID, imageTag)
+	case portainer.StackWebhook:
+		return handler.executeStackWebhook(w, endpoint, resourceID, registryID)
 	default:
 		return httperror.InternalServerError("Unsupported webhook type", errors.New("Webhooks for this resource are not currently supported"))
 	}
@@ -99,20 +106,11 @@ func (handler *Handler) executeServiceWebhook(
 		QueryRegistry: true,
 	}
 
-	if registryID != 0 {
-		registry, err := handler.DataStore.Registry().Registry(registryID)
-		if err != nil {
-			return httperror.InternalServerError("Error getting registry", err)
-		}
-
-		if registry.Authentication {
-			registryutils.EnsureRegTokenValid(handler.DataStore, registry)
-			serviceUpdateOptions.EncodedRegistryAuth, err = registryutils.GetRegistryAuthHeader(registry)
-			if err != nil {
-				return httperror.InternalServerError("Error getting registry auth header", err)
-			}
-		}
+	_, serviceUpdateOptions.EncodedRegistryAuth, err = handler.registryAuth(registryID)
+	if err != nil {
+		return httperror.InternalServerError("Error getting registry auth header", err)
 	}
+
 	if imageTag != "" {
 		rc, err := dockerClient.ImagePull(context.Background(), service.Spec.TaskTemplate.ContainerSpec.Image, dockertypes.ImagePullOptions{RegistryAuth: serviceUpdateOptions.EncodedRegistryAuth})
 		if err != nil {
@@ -129,3 +127,114 @@ func (handler *Handler) executeServiceWebhook(
 	}
 	return response.Empty(w)
 }
+
+func (handler *Handler) executeContainerWebhook(
+	w http.ResponseWriter,
+	webhook *portainer.Webhook,
+	endpoint *portainer.Endpoint,
+	registryID portainer.RegistryID,
+	imageTag string,
+) *httperror.HandlerError {
+	_, registryAuth, err := handler.registryAuth(registryID)
+	if err != nil {
+		return httperror.InternalServerError("Error getting registry auth header", err)
+	}
+
+	container, err := handler.ContainerService.Recreate(context.Background(), endpoint, webhook.ResourceID, imageTag, registryAuth)
+	if err != nil {
+		return httperror.InternalServerError("Error recreating container", err)
+	}
+
+	// the recreated container has a new identifier, the webhook must follow it to be executed again
+	if container.ID != webhook.ResourceID {
+		webhook.ResourceID = container.ID
+
+		err = handler.DataStore.Webhook().UpdateWebhook(webhook.ID, webhook)
+		if err != nil {
+			return httperror.InternalServerError("Unable to persist the webhook changes inside the database", err)
+		}
+	}
+
+	return response.Empty(w)
+}
+
+func (handler *Handler) executeStackWebhook(
+	w http.ResponseWriter,
+	endpoint *portainer.Endpoint,
+	resourceID string,
+	registryID portainer.RegistryID,
+) *httperror.HandlerError {
+	stackID, err := strconv.Atoi(resourceID)
+	if err != nil {
+		return httperror.InternalServerError("Invalid stack identifier", err)
+	}
+
+	stack, err := handler.DataStore.Stack().Stack(portainer.StackID(stackID))
+	if handler.DataStore.IsErrObjectNotFound(err) {
+		return httperror.NotFound("Unable to find a stack with the specified identifier inside the database", err)
+	} else if err != nil {
+		return httperror.InternalServerError("Unable to find a stack with the specified identifier inside the database", err)
+	}
+
+	if stack.Status != portainer.StackStatusActive {
+		return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: "The stack is not running", Err: errors.New("the stack is not running")}
+	}
+
+	registry, _, err := handler.registryAuth(registryID)
+	if err != nil {
+		return httperror.InternalServerError("Error getting registry", err)
+	}
+
+	registries := []portainer.Registry{}
+	if registry != nil {
+		registries = append(registries, *registry)
+	}
+
+	switch stack.Type {
+	case portainer.DockerComposeStack:
+		err = handler.StackDeployer.DeployComposeStack(stack, endpoint, registries, true, false)
+	case portainer.DockerSwarmStack:
+		prune := stack.Option != nil && stack.Option.Prune
+		err = handler.StackDeployer.DeploySwarmStack(stack, endpoint, registries, prune, true)
+	default:
+		return httperror.BadRequest("Unsupported stack type", errors.New("webhooks are only supported for compose and swarm stacks"))
+	}
+
+	if err != nil {
+		return httperror.InternalServerError("Error redeploying stack", err)
+	}
+
+	stack.UpdateDate = time.Now().Unix()
+	err = handler.DataStore.Stack().UpdateStack(stack.ID, stack)
+	if err != nil {
+		return httperror.InternalServerError("Unable to persist the stack changes inside the database", err)
+	}
+
+	return response.Empty(w)
+}
+
+// registryAuth returns the registry associated to a webhook and the header used to authenticate
+// against it, the header is empty when the registry doesn't require authentication
+func (handler *Handler) registryAuth(registryID portainer.RegistryID) (*portainer.Registry, string, error) {
+	if registryID == 0 {
+		return nil, "", nil
+	}
+
+	registry, err := handler.DataStore.Registry().Registry(registryID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !registry.Authentication {
+		return registry, "", nil
+	}
+
+	registryutils.EnsureRegTokenValid(handler.DataStore, registry)
+
+	authHeader, err := registryutils.GetRegistryAuthHeader(registry)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return registry, authHeader, nil
+}
@@ -0,0 +1,153 @@
+package webhooks
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/jwt"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testStackDeployer struct {
+	deployedStacks []portainer.StackID
+}
+
+func (deployer *testStackDeployer) DeploySwarmStack(stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, prune bool, pullImage bool) error {
+	deployer.deployedStacks = append(deployer.deployedStacks, stack.ID)
+	return nil
+}
+
+func (deployer *testStackDeployer) DeployComposeStack(stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, forcePullImage bool, forceRecreate bool) error {
+	deployer.deployedStacks = append(deployer.deployedStacks, stack.ID)
+	return nil
+}
+
+func (deployer *testStackDeployer) DeployKubernetesStack(stack *portainer.Stack, endpoint *portainer.Endpoint, user *portainer.User) error {
+	return nil
+}
+
+// newDockerServer emulates the Docker API calls used to recreate the container oldID as newID
+func newDockerServer(oldID, newID string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+
+		path := r.URL.Path
+		switch {
+		case strings.HasSuffix(path, "/containers/"+oldID+"/json"), strings.HasSuffix(path, "/containers/"+newID+"/json"):
+			id := oldID
+			if strings.Contains(path, newID) {
+				id = newID
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"Id":%q,"Name":"/web","Config":{"Image":"nginx:latest"},"HostConfig":{"NetworkMode":"default"},"State":{"Running":false}}`, id)
+		case strings.HasSuffix(path, "/images/create"):
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"status":"Downloaded newer image for nginx:latest"}`)
+		case strings.HasSuffix(path, "/containers/create"):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"Id":%q}`, newID)
+		case strings.HasSuffix(path, "/rename"), strings.HasSuffix(path, "/start"), r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func setupHandler(t *testing.T) (*Handler, func()) {
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+
+	jwtService, err := jwt.NewService("1h", store)
+	require.NoError(t, err)
+
+	handler := NewHandler(security.NewRequestBouncer(store, jwtService, apikey.NewAPIKeyService(nil, nil)))
+	handler.DataStore = store
+	handler.DockerClientFactory = docker.NewClientFactory(nil, nil)
+	handler.ContainerService = docker.NewContainerService(handler.DockerClientFactory)
+
+	return handler, teardown
+}
+
+func executeWebhook(handler *Handler, token string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhooks/"+token, nil))
+
+	return rec
+}
+
+func TestWebhookExecute(t *testing.T) {
+	is := assert.New(t)
+
+	handler, teardown := setupHandler(t)
+	defer teardown()
+
+	const oldID, newID = "0123456789ab0123456789ab", "ba9876543210ba9876543210"
+
+	server := newDockerServer(oldID, newID)
+	defer server.Close()
+
+	endpoint := &portainer.Endpoint{ID: 1, Name: "local", Type: portainer.DockerEnvironment, URL: strings.Replace(server.URL, "http://", "tcp://", 1)}
+	require.NoError(t, handler.DataStore.Endpoint().Create(endpoint))
+
+	deployer := &testStackDeployer{}
+	handler.StackDeployer = deployer
+
+	stack := &portainer.Stack{ID: 2, Name: "web", Type: portainer.DockerComposeStack, EndpointID: endpoint.ID, Status: portainer.StackStatusActive}
+	require.NoError(t, handler.DataStore.Stack().Create(stack))
+
+	webhooks := []*portainer.Webhook{
+		{Token: "container-token", ResourceID: oldID, EndpointID: endpoint.ID, WebhookType: portainer.ContainerWebhook},
+		{Token: "stack-token", ResourceID: strconv.Itoa(int(stack.ID)), EndpointID: endpoint.ID, WebhookType: portainer.StackWebhook},
+		{Token: "missing-stack-token", ResourceID: "99", EndpointID: endpoint.ID, WebhookType: portainer.StackWebhook},
+	}
+	for _, webhook := range webhooks {
+		require.NoError(t, handler.DataStore.Webhook().Create(webhook))
+	}
+
+	t.Run("the container webhook recreates the container and follows it", func(t *testing.T) {
+		rec := executeWebhook(handler, "container-token")
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+		webhook, err := handler.DataStore.Webhook().WebhookByToken("container-token")
+		require.NoError(t, err)
+		is.Equal(newID, webhook.ResourceID)
+	})
+
+	t.Run("the stack webhook redeploys the stack", func(t *testing.T) {
+		rec := executeWebhook(handler, "stack-token")
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+		is.Equal([]portainer.StackID{stack.ID}, deployer.deployedStacks)
+
+		updatedStack, err := handler.DataStore.Stack().Stack(stack.ID)
+		require.NoError(t, err)
+		is.NotZero(updatedStack.UpdateDate)
+	})
+
+	t.Run("the webhooks of missing resources are not found", func(t *testing.T) {
+		is.Equal(http.StatusNotFound, executeWebhook(handler, "unknown-token").Code)
+		is.Equal(http.StatusNotFound, executeWebhook(handler, "missing-stack-token").Code)
+		is.Len(deployer.deployedStacks, 1)
+	})
+
+	t.Run("the webhooks are removed along with their resource", func(t *testing.T) {
+		require.NoError(t, handler.DataStore.Webhook().DeleteResourceWebhooks(endpoint.ID, strconv.Itoa(int(stack.ID)), portainer.StackWebhook))
+
+		is.Equal(http.StatusNotFound, executeWebhook(handler, "stack-token").Code)
+		is.Equal(http.StatusNoContent, executeWebhook(handler, "container-token").Code)
+	})
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/portainer/portainer/api/http/proxy/factory/utils"
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/authorization"
+
+	"github.com/rs/zerolog/log"
 )
 
 const (
@@ -243,3 +245,18 @@ func (transport *Transport) decorateContainerCreationOperation(request *http.Req
 
 	return response, err
 }
+
+// executeContainerDeletionOperation removes a container along with its resource control and its webhooks
+func (transport *Transport) executeContainerDeletionOperation(request *http.Request, containerID string) (*http.Response, error) {
+	response, err := transport.executeGenericResourceDeletionOperation(request, containerID, containerID, portainer.ContainerResourceControl)
+	if err != nil || (response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK) {
+		return response, err
+	}
+
+	err = transport.dataStore.Webhook().DeleteResourceWebhooks(transport.endpoint.ID, containerID, portainer.ContainerWebhook)
+	if err != nil {
+		log.Warn().Err(err).Str("container_id", containerID).Msg("unable to remove the webhooks of the container")
+	}
+
+	return response, nil
+}
@@ -12,6 +12,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/dataservices"
@@ -20,6 +21,7 @@ import (
 	"github.com/portainer/portainer/api/http/proxy/factory/utils"
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/metrics"
 
 	"github.com/rs/zerolog/log"
 )
@@ -80,7 +82,17 @@ func NewTransport(parameters *TransportParameters, httpTransport *http.Transport
 
 // RoundTrip is the implementation of the the http.RoundTripper interface
 func (transport *Transport) RoundTrip(request *http.Request) (*http.Response, error) {
-	return transport.ProxyDockerRequest(request)
+	start := time.Now()
+
+	response, err := transport.ProxyDockerRequest(request)
+
+	statusCode := 0
+	if response != nil {
+		statusCode = response.StatusCode
+	}
+	metrics.ObserveProxyRequest(int(transport.endpoint.ID), statusCode, time.Since(start))
+
+	return response, err
 }
 
 // ProxyDockerRequest intercepts a Docker API request and apply logic based
@@ -253,7 +265,7 @@ func (transport *Transport) proxyContainerRequest(request *http.Request) (*http.
 			containerID := path.Base(requestPath)
 
 			if request.Method == http.MethodDelete {
-				return transport.executeGenericResourceDeletionOperation(request, containerID, containerID, portainer.ContainerResourceControl)
+				return transport.executeContainerDeletionOperation(request, containerID)
 			}
 
 			return transport.restrictedResourceOperation(request, containerID, containerID, portainer.ContainerResourceControl, false)
@@ -0,0 +1,247 @@
+package security
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// RateLimitClass is the class of a request, each class has its own budget
+type RateLimitClass string
+
+const (
+	// ReadRateLimitClass is the class of the read requests (GET, HEAD and OPTIONS)
+	ReadRateLimitClass RateLimitClass = "read"
+	// WriteRateLimitClass is the class of the mutating requests
+	WriteRateLimitClass RateLimitClass = "write"
+	// ProxyRateLimitClass is the class of the requests proxied to the Docker, Kubernetes and Azure APIs of the environments(endpoints)
+	ProxyRateLimitClass RateLimitClass = "proxy"
+)
+
+// bucketsPruneInterval is the interval at which the buckets that are full again are dropped
+const bucketsPruneInterval = 5 * time.Minute
+
+type (
+	// APIRateLimiter limits the requests of each user and of each API key with token buckets,
+	// using a separate budget for each class of requests
+	APIRateLimiter struct {
+		mu         sync.Mutex
+		settings   portainer.APIRateLimitSettings
+		buckets    map[rateLimitBucketKey]*tokenBucket
+		prunedAt   time.Time
+		timeSource func() time.Time
+	}
+
+	// RateLimitSubject identifies the user, or the API key of the user, a budget is consumed by
+	RateLimitSubject struct {
+		UserID portainer.UserID `json:"UserId" example:"1"`
+		// Prefix of the API key, empty when the requests are authenticated with a JWT
+		APIKeyPrefix string `json:"APIKeyPrefix,omitempty" example:"ptr_abc"`
+	}
+
+	// RateLimitStatus is the outcome of the rate limiting of a request
+	RateLimitStatus struct {
+		Allowed bool
+		// Maximum number of requests in a burst, 0 when the requests are not limited
+		Limit int
+		// Number of requests that can still be sent without waiting
+		Remaining int
+		// Duration to wait before the next request is allowed, when the request is not allowed
+		RetryAfter time.Duration
+	}
+
+	// RateLimitUsage represents the current usage of a budget
+	RateLimitUsage struct {
+		RateLimitSubject
+		Class RateLimitClass `json:"Class" example:"read"`
+		// Number of requests refilled per minute
+		RequestsPerMinute int `json:"RequestsPerMinute" example:"600"`
+		// Maximum number of requests in a burst
+		Limit int `json:"Limit" example:"100"`
+		// Number of requests that can still be sent without waiting
+		Remaining int `json:"Remaining" example:"42"`
+	}
+
+	rateLimitBucketKey struct {
+		subject RateLimitSubject
+		class   RateLimitClass
+	}
+
+	tokenBucket struct {
+		tokens    float64
+		updatedAt time.Time
+	}
+)
+
+// NewAPIRateLimiter initializes a new APIRateLimiter
+func NewAPIRateLimiter(settings portainer.APIRateLimitSettings) *APIRateLimiter {
+	return &APIRateLimiter{
+		settings:   settings,
+		buckets:    make(map[rateLimitBucketKey]*tokenBucket),
+		timeSource: time.Now,
+	}
+}
+
+// ValidateAPIRateLimitSettings validates the settings of the rate limiting of the API
+func ValidateAPIRateLimitSettings(settings portainer.APIRateLimitSettings) error {
+	for _, budget := range []portainer.RateLimitBudget{settings.Read, settings.Write, settings.Proxy} {
+		if budget.RequestsPerMinute < 0 || budget.Burst < 0 {
+			return errors.New("invalid rate limit budget. The number of requests cannot be negative")
+		}
+	}
+
+	return nil
+}
+
+// Settings returns the settings of the rate limiter
+func (limiter *APIRateLimiter) Settings() portainer.APIRateLimitSettings {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	return limiter.settings
+}
+
+// SetSettings replaces the settings of the rate limiter, the budgets are reset
+func (limiter *APIRateLimiter) SetSettings(settings portainer.APIRateLimitSettings) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	limiter.settings = settings
+	limiter.buckets = make(map[rateLimitBucketKey]*tokenBucket)
+}
+
+// Take consumes a request from the budget of the subject for the class
+func (limiter *APIRateLimiter) Take(subject RateLimitSubject, class RateLimitClass) RateLimitStatus {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	budget, limited := limiter.budget(class)
+	if !limited {
+		return RateLimitStatus{Allowed: true}
+	}
+
+	now := limiter.timeSource()
+	limiter.prune(now)
+
+	key := rateLimitBucketKey{subject: subject, class: class}
+	bucket, ok := limiter.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(burst(budget)), updatedAt: now}
+		limiter.buckets[key] = bucket
+	}
+	bucket.refill(budget, now)
+
+	status := RateLimitStatus{Limit: burst(budget)}
+	if bucket.tokens < 1 {
+		perSecond := float64(budget.RequestsPerMinute) / 60
+		status.RetryAfter = time.Duration((1 - bucket.tokens) / perSecond * float64(time.Second))
+		return status
+	}
+
+	bucket.tokens--
+	status.Allowed = true
+	status.Remaining = int(bucket.tokens)
+
+	return status
+}
+
+// Usage returns the current usage of the budgets that are being consumed, sorted by subject and class
+func (limiter *APIRateLimiter) Usage() []RateLimitUsage {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := limiter.timeSource()
+
+	usage := make([]RateLimitUsage, 0, len(limiter.buckets))
+	for key, bucket := range limiter.buckets {
+		budget, limited := limiter.budget(key.class)
+		if !limited {
+			continue
+		}
+
+		bucket.refill(budget, now)
+
+		usage = append(usage, RateLimitUsage{
+			RateLimitSubject:  key.subject,
+			Class:             key.class,
+			RequestsPerMinute: budget.RequestsPerMinute,
+			Limit:             burst(budget),
+			Remaining:         int(bucket.tokens),
+		})
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].UserID != usage[j].UserID {
+			return usage[i].UserID < usage[j].UserID
+		}
+		if usage[i].APIKeyPrefix != usage[j].APIKeyPrefix {
+			return usage[i].APIKeyPrefix < usage[j].APIKeyPrefix
+		}
+		return usage[i].Class < usage[j].Class
+	})
+
+	return usage
+}
+
+// budget returns the budget of the class and whether the requests of the class are limited
+func (limiter *APIRateLimiter) budget(class RateLimitClass) (portainer.RateLimitBudget, bool) {
+	if !limiter.settings.Enabled {
+		return portainer.RateLimitBudget{}, false
+	}
+
+	var budget portainer.RateLimitBudget
+	switch class {
+	case ReadRateLimitClass:
+		budget = limiter.settings.Read
+	case WriteRateLimitClass:
+		budget = limiter.settings.Write
+	case ProxyRateLimitClass:
+		budget = limiter.settings.Proxy
+	}
+
+	return budget, budget.RequestsPerMinute > 0
+}
+
+// prune drops the buckets that are full again, they are recreated full on the next request
+func (limiter *APIRateLimiter) prune(now time.Time) {
+	if now.Sub(limiter.prunedAt) < bucketsPruneInterval {
+		return
+	}
+	limiter.prunedAt = now
+
+	for key, bucket := range limiter.buckets {
+		budget, limited := limiter.budget(key.class)
+		if !limited {
+			delete(limiter.buckets, key)
+			continue
+		}
+
+		bucket.refill(budget, now)
+		if bucket.tokens >= float64(burst(budget)) {
+			delete(limiter.buckets, key)
+		}
+	}
+}
+
+func (bucket *tokenBucket) refill(budget portainer.RateLimitBudget, now time.Time) {
+	elapsed := now.Sub(bucket.updatedAt).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	bucket.tokens = math.Min(float64(burst(budget)), bucket.tokens+elapsed*float64(budget.RequestsPerMinute)/60)
+	bucket.updatedAt = now
+}
+
+// burst returns the size of the bucket of the budget, defaults to the number of requests per minute
+func burst(budget portainer.RateLimitBudget) int {
+	if budget.Burst > 0 {
+		return budget.Burst
+	}
+
+	return budget.RequestsPerMinute
+}
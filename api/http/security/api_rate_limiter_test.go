@@ -0,0 +1,122 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_APIRateLimiter_Take(t *testing.T) {
+	is := assert.New(t)
+
+	now := time.Unix(1672531200, 0)
+	limiter := NewAPIRateLimiter(portainer.APIRateLimitSettings{
+		Enabled: true,
+		Read:    portainer.RateLimitBudget{RequestsPerMinute: 60, Burst: 2},
+	})
+	limiter.timeSource = func() time.Time { return now }
+
+	user := RateLimitSubject{UserID: 1}
+	apiKey := RateLimitSubject{UserID: 1, APIKeyPrefix: "ptr_abc"}
+
+	is.Equal(RateLimitStatus{Allowed: true, Limit: 2, Remaining: 1}, limiter.Take(user, ReadRateLimitClass))
+	is.Equal(RateLimitStatus{Allowed: true, Limit: 2, Remaining: 0}, limiter.Take(user, ReadRateLimitClass))
+
+	status := limiter.Take(user, ReadRateLimitClass)
+	is.False(status.Allowed, "the budget should be exhausted")
+	is.Equal(time.Second, status.RetryAfter)
+
+	is.True(limiter.Take(apiKey, ReadRateLimitClass).Allowed, "the API keys should have their own budget")
+	is.True(limiter.Take(user, WriteRateLimitClass).Allowed, "the classes without budget should not be limited")
+
+	now = now.Add(500 * time.Millisecond)
+	status = limiter.Take(user, ReadRateLimitClass)
+	is.False(status.Allowed)
+	is.Equal(500*time.Millisecond, status.RetryAfter)
+
+	now = now.Add(500 * time.Millisecond)
+	is.True(limiter.Take(user, ReadRateLimitClass).Allowed, "a request should be refilled after a second")
+
+	is.Equal([]RateLimitUsage{
+		{RateLimitSubject: user, Class: ReadRateLimitClass, RequestsPerMinute: 60, Limit: 2, Remaining: 0},
+		{RateLimitSubject: apiKey, Class: ReadRateLimitClass, RequestsPerMinute: 60, Limit: 2, Remaining: 2},
+	}, limiter.Usage())
+
+	limiter.SetSettings(portainer.APIRateLimitSettings{})
+	is.Equal(RateLimitStatus{Allowed: true}, limiter.Take(user, ReadRateLimitClass), "the requests should not be limited once disabled")
+	is.Empty(limiter.Usage())
+}
+
+func Test_ValidateAPIRateLimitSettings(t *testing.T) {
+	is := assert.New(t)
+
+	is.NoError(ValidateAPIRateLimitSettings(portainer.APIRateLimitSettings{Enabled: true, Proxy: portainer.RateLimitBudget{RequestsPerMinute: 600}}))
+	is.Error(ValidateAPIRateLimitSettings(portainer.APIRateLimitSettings{Write: portainer.RateLimitBudget{RequestsPerMinute: 10, Burst: -1}}))
+}
+
+func Test_mwRateLimit(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	user := &portainer.User{ID: 2, Username: "standard", Role: portainer.StandardUserRole}
+	is.NoError(store.User().Create(user), "error creating user")
+
+	jwtService, err := jwt.NewService("1h", store)
+	is.NoError(err, "Error initiating jwt service")
+	apiKeyService := apikey.NewAPIKeyService(store.APIKeyRepository(), store.User())
+	bouncer := NewRequestBouncer(store, jwtService, apiKeyService)
+
+	bouncer.APIRateLimiter().SetSettings(portainer.APIRateLimitSettings{
+		Enabled: true,
+		Read:    portainer.RateLimitBudget{RequestsPerMinute: 1},
+		Write:   portainer.RateLimitBudget{RequestsPerMinute: 1},
+		Proxy:   portainer.RateLimitBudget{RequestsPerMinute: 1},
+	})
+
+	rawAPIKey, _, err := apiKeyService.GenerateApiKey(*user, "script")
+	is.NoError(err)
+
+	token, err := jwtService.GenerateToken(&portainer.TokenData{ID: user.ID, Username: user.Username, Role: user.Role})
+	is.NoError(err)
+
+	serve := func(h http.Handler, method string, useAPIKey bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/endpoints/1/docker/containers/json", nil)
+		if useAPIKey {
+			req.Header.Add("x-api-key", rawAPIKey)
+		} else {
+			req.Header.Add("Authorization", "Bearer "+token)
+		}
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	authenticated := bouncer.AuthenticatedAccess(testHandler200)
+	proxy := bouncer.ProxyAccess(testHandler200)
+
+	rr := serve(authenticated, http.MethodGet, false)
+	is.Equal(http.StatusOK, rr.Code)
+	is.Equal("1", rr.Header().Get("X-RateLimit-Limit"))
+	is.Equal("0", rr.Header().Get("X-RateLimit-Remaining"))
+
+	rr = serve(authenticated, http.MethodGet, false)
+	is.Equal(http.StatusTooManyRequests, rr.Code, "the read budget should be exhausted")
+	is.Equal("60", rr.Header().Get("Retry-After"))
+
+	is.Equal(http.StatusOK, serve(authenticated, http.MethodPost, false).Code, "the mutating requests should have their own budget")
+	is.Equal(http.StatusOK, serve(proxy, http.MethodGet, false).Code, "the proxied requests should have their own budget")
+	is.Equal(http.StatusTooManyRequests, serve(proxy, http.MethodPost, false).Code)
+	is.Equal(http.StatusOK, serve(authenticated, http.MethodGet, true).Code, "the API key should have its own budget")
+	is.Equal(http.StatusTooManyRequests, serve(authenticated, http.MethodGet, true).Code)
+}
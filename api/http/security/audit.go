@@ -0,0 +1,170 @@
+package security
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/audit"
+
+	"github.com/rs/zerolog/log"
+)
+
+// auditResponseWriter records the status code written by the next handlers
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *auditResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack is required by the Docker proxy for attach and exec operations
+func (w *auditResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+
+	w.status = http.StatusSwitchingProtocols
+
+	return hijacker.Hijack()
+}
+
+// mwAuditLog records every non-GET request in the audit log once the request has been handled.
+// The requests performed on the public routes are recorded without a user.
+func (bouncer *RequestBouncer) mwAuditLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, truncated, err := peekBody(r)
+		if err != nil {
+			log.Warn().Err(err).Msg("unable to read the request body for the audit log")
+		}
+
+		recorder := &auditResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		auditLog := newAuditLog(r, recorder.status)
+		auditLog.Body = audit.RedactBody(body, r.Header.Get("Content-Type"), truncated)
+
+		err = bouncer.dataStore.AuditLog().Create(auditLog)
+		if err != nil {
+			log.Error().Err(err).Str("route", auditLog.Route).Msg("unable to persist the audit log")
+		}
+	})
+}
+
+// peekBody reads the first bytes of the request body and restores it
+// so that it can still be consumed by the next handlers
+func peekBody(r *http.Request) ([]byte, bool, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, false, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, audit.MaxBodySize+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+
+	if len(body) > audit.MaxBodySize {
+		return body[:audit.MaxBodySize], true, err
+	}
+
+	return body, false, err
+}
+
+func newAuditLog(r *http.Request, status int) *portainer.AuditLog {
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	auditLog := &portainer.AuditLog{
+		Timestamp: time.Now().Unix(),
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Route:     r.URL.Path,
+		Status:    status,
+	}
+
+	tokenData, err := RetrieveTokenData(r)
+	if err == nil {
+		auditLog.UserID = tokenData.ID
+		auditLog.Username = tokenData.Username
+		auditLog.APIKeyID = tokenData.APIKeyID
+	}
+
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			auditLog.Route = template
+		}
+	}
+
+	auditLog.ResourceType = resourceType(auditLog.Route)
+	auditLog.EndpointID = requestEndpointID(r, auditLog.Route)
+
+	return auditLog
+}
+
+// resourceType returns the type of the resource targeted by a route, e.g. "stacks" for /stacks/{id}.
+// The sub resource is used for the routes of an environment(endpoint), e.g. "docker" for /endpoints/{id}/docker.
+func resourceType(route string) string {
+	segments := strings.Split(strings.Trim(route, "/"), "/")
+
+	if segments[0] == "endpoints" && len(segments) > 2 && strings.HasPrefix(segments[1], "{") {
+		return segments[2]
+	}
+
+	return segments[0]
+}
+
+func requestEndpointID(r *http.Request, route string) portainer.EndpointID {
+	vars := mux.Vars(r)
+
+	value := vars["endpointId"]
+	if value == "" && strings.HasPrefix(route, "/endpoints/{id") {
+		value = vars["id"]
+	}
+
+	if value == "" {
+		value = r.URL.Query().Get("endpointId")
+	}
+
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+
+	return portainer.EndpointID(id)
+}
@@ -0,0 +1,106 @@
+package security
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/jwt"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_mwAuditLog(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	jwtService, err := jwt.NewService("1h", store)
+	is.NoError(err)
+
+	bouncer := NewRequestBouncer(store, jwtService, apikey.NewAPIKeyService(nil, nil))
+
+	var receivedBody string
+	router := mux.NewRouter()
+	router.Handle("/endpoints/{id}/docker/containers/create", bouncer.mwAuditLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receivedBody = string(body)
+		w.WriteHeader(http.StatusCreated)
+	})))
+
+	t.Run("mutating request is recorded with a redacted body", func(t *testing.T) {
+		body := `{"Image":"nginx","Env":{"DB_PASSWORD":"secret"}}`
+		req := httptest.NewRequest(http.MethodPost, "/endpoints/3/docker/containers/create", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(StoreTokenData(req, &portainer.TokenData{ID: 2, Username: "bob", APIKeyID: 5}))
+
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		is.Equal(body, receivedBody, "the body should still be readable by the next handler")
+
+		auditLogs, err := store.AuditLog().AuditLogs()
+		is.NoError(err)
+		is.Len(auditLogs, 1)
+
+		auditLog := auditLogs[0]
+		is.Equal(portainer.UserID(2), auditLog.UserID)
+		is.Equal("bob", auditLog.Username)
+		is.Equal(portainer.APIKeyID(5), auditLog.APIKeyID)
+		is.Equal(portainer.EndpointID(3), auditLog.EndpointID)
+		is.Equal("docker", auditLog.ResourceType)
+		is.Equal("/endpoints/{id}/docker/containers/create", auditLog.Route)
+		is.Equal(http.StatusCreated, auditLog.Status)
+		is.Equal(`{"Env":{"DB_PASSWORD":"[REDACTED]"},"Image":"nginx"}`, auditLog.Body)
+	})
+
+	t.Run("read request is not recorded", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/endpoints/3/docker/containers/create", nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		auditLogs, err := store.AuditLog().AuditLogs()
+		is.NoError(err)
+		is.Len(auditLogs, 1)
+	})
+}
+
+func Test_PublicAccessAuditLog(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	jwtService, err := jwt.NewService("1h", store)
+	is.NoError(err)
+
+	bouncer := NewRequestBouncer(store, jwtService, apikey.NewAPIKeyService(nil, nil))
+
+	router := mux.NewRouter()
+	router.Handle("/webhooks/{token}", bouncer.PublicAccess(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/abc", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	auditLogs, err := store.AuditLog().AuditLogs()
+	is.NoError(err)
+	is.Len(auditLogs, 1)
+	is.Equal(portainer.UserID(0), auditLogs[0].UserID)
+	is.Equal("/webhooks/{token}", auditLogs[0].Route)
+	is.Equal(http.StatusNoContent, auditLogs[0].Status)
+}
+
+func Test_resourceType(t *testing.T) {
+	is := assert.New(t)
+
+	is.Equal("stacks", resourceType("/stacks/{id}"))
+	is.Equal("endpoints", resourceType("/endpoints/{id}"))
+	is.Equal("kubernetes", resourceType("/endpoints/{id}/kubernetes/api"))
+	is.Equal("settings", resourceType("/settings"))
+}
@@ -1,7 +1,10 @@
 package security
 
 import (
+	"context"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,6 +22,7 @@ type (
 		dataStore     dataservices.DataStore
 		jwtService    dataservices.JWTService
 		apiKeyService apikey.APIKeyService
+		rateLimiter   *APIRateLimiter
 	}
 
 	// RestrictedRequestContext is a data structure containing information
@@ -42,12 +46,20 @@ func NewRequestBouncer(dataStore dataservices.DataStore, jwtService dataservices
 		dataStore:     dataStore,
 		jwtService:    jwtService,
 		apiKeyService: apiKeyService,
+		rateLimiter:   NewAPIRateLimiter(portainer.APIRateLimitSettings{}),
 	}
 }
 
+// APIRateLimiter returns the rate limiter of the authenticated requests
+func (bouncer *RequestBouncer) APIRateLimiter() *APIRateLimiter {
+	return bouncer.rateLimiter
+}
+
 // PublicAccess defines a security check for public API environments(endpoints).
 // No authentication is required to access these environments(endpoints).
+// The mutating requests are still recorded in the audit log.
 func (bouncer *RequestBouncer) PublicAccess(h http.Handler) http.Handler {
+	h = bouncer.mwAuditLog(h)
 	return mwSecureHeaders(h)
 }
 
@@ -70,6 +82,7 @@ func (bouncer *RequestBouncer) AdminAccess(h http.Handler) http.Handler {
 // that might be used later to inside the API operation for extra authorization validation
 // and resource filtering.
 func (bouncer *RequestBouncer) RestrictedAccess(h http.Handler) http.Handler {
+	// the requests are recorded in the audit log by mwAuthenticatedUser
 	h = bouncer.mwUpgradeToRestrictedRequest(h)
 	h = bouncer.mwCheckPortainerAuthorizations(h, false)
 	h = bouncer.mwAuthenticatedUser(h)
@@ -100,6 +113,17 @@ func (bouncer *RequestBouncer) AuthenticatedAccess(h http.Handler) http.Handler
 	return h
 }
 
+// ProxyAccess defines a security check for the requests proxied to the Docker, Kubernetes and Azure APIs
+// of the environments(endpoints). It is the same as AuthenticatedAccess, except that the requests
+// consume the proxy budget of the rate limiter instead of the read and write ones.
+func (bouncer *RequestBouncer) ProxyAccess(h http.Handler) http.Handler {
+	h = bouncer.AuthenticatedAccess(h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), contextRateLimitClass, ProxyRateLimitClass)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // AuthorizedEndpointOperation retrieves the JWT token from the request context and verifies
 // that the user can access the specified environment(endpoint).
 // An error is returned when access to the environments(endpoints) is denied or if the user do not have the required
@@ -110,6 +134,15 @@ func (bouncer *RequestBouncer) AuthorizedEndpointOperation(r *http.Request, endp
 		return err
 	}
 
+	apiKey, err := bouncer.requestAPIKey(tokenData)
+	if err != nil {
+		return err
+	}
+
+	if apiKey != nil && !apikey.AllowsEndpoint(*apiKey, endpoint.ID) {
+		return httperrors.ErrEndpointAccessDenied
+	}
+
 	if tokenData.Role == portainer.AdministratorRole {
 		return nil
 	}
@@ -168,10 +201,29 @@ func (bouncer *RequestBouncer) TrustedEdgeEnvironmentAccess(endpoint *portainer.
 	return nil
 }
 
+// KubernetesProxyAccess defines a security check for the requests proxied to the Kubernetes API of the
+// environments(endpoints). It is the same as ProxyAccess, except that the tokens issued for a kubeconfig
+// are accepted, every other route rejects them.
+func (bouncer *RequestBouncer) KubernetesProxyAccess(h http.Handler) http.Handler {
+	h = bouncer.ProxyAccess(h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), contextKubeconfigAccess, true)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // mwAuthenticatedUser authenticates a request by
 // - adding a secure handlers to the response
 // - authenticating the request with a valid token
+// - rejecting the kubeconfig tokens outside of the Kubernetes API proxy
+// - enforcing the restrictions of the API key used to authenticate the request, if any
+// - rate limiting the requests of the user or of the API key
+// - recording the mutating requests in the audit log
 func (bouncer *RequestBouncer) mwAuthenticatedUser(h http.Handler) http.Handler {
+	h = bouncer.mwAuditLog(h)
+	h = bouncer.mwRateLimit(h)
+	h = bouncer.mwCheckAPIKeyRestrictions(h)
+	h = mwCheckKubeconfigAccess(h)
 	h = bouncer.mwAuthenticateFirst([]tokenLookup{
 		bouncer.JWTAuthLookup,
 		bouncer.apiKeyLookup,
@@ -215,6 +267,168 @@ func (bouncer *RequestBouncer) mwCheckPortainerAuthorizations(next http.Handler,
 	})
 }
 
+// mwCheckKubeconfigAccess rejects the requests authenticated with a kubeconfig token, unless they are
+// proxied to the Kubernetes API of an environment(endpoint).
+func mwCheckKubeconfigAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenData, err := RetrieveTokenData(r)
+		if err != nil {
+			httperror.WriteError(w, http.StatusForbidden, "Access denied", httperrors.ErrUnauthorized)
+			return
+		}
+
+		if allowed, _ := r.Context().Value(contextKubeconfigAccess).(bool); tokenData.Kubeconfig && !allowed {
+			httperror.WriteError(w, http.StatusForbidden, "The kubeconfig token is only allowed to access the Kubernetes API", httperrors.ErrUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// mwCheckAPIKeyRestrictions verifies that the request is allowed by the restrictions of the API key
+// used to authenticate it: the read-only flag, the route scopes and the environment(endpoint) allow-list.
+// The environments(endpoints) are the ones referenced by the path or the endpointId query parameter.
+func (bouncer *RequestBouncer) mwCheckAPIKeyRestrictions(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenData, err := RetrieveTokenData(r)
+		if err != nil {
+			httperror.WriteError(w, http.StatusForbidden, "Access denied", httperrors.ErrUnauthorized)
+			return
+		}
+
+		apiKey, err := bouncer.requestAPIKey(tokenData)
+		if err != nil {
+			httperror.WriteError(w, http.StatusUnauthorized, "Unauthorized", httperrors.ErrUnauthorized)
+			return
+		}
+
+		if apiKey == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !apikey.AllowsMethod(*apiKey, r.Method) {
+			httperror.WriteError(w, http.StatusForbidden, "The API key is read-only", httperrors.ErrUnauthorized)
+			return
+		}
+
+		if !apikey.AllowsPath(*apiKey, r.URL.Path) {
+			httperror.WriteError(w, http.StatusForbidden, "The API key is not allowed to access this route", httperrors.ErrUnauthorized)
+			return
+		}
+
+		for _, endpointID := range requestEndpointIDs(r) {
+			if !apikey.AllowsEndpoint(*apiKey, endpointID) {
+				httperror.WriteError(w, http.StatusForbidden, "The API key is not allowed to access this environment", httperrors.ErrEndpointAccessDenied)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// mwRateLimit consumes a request from the budget of the user, or of the API key used to authenticate the request.
+// The read, mutating and proxied requests have separate budgets. The request is rejected with a 429 status code
+// and a Retry-After header once the budget is exhausted.
+func (bouncer *RequestBouncer) mwRateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !bouncer.rateLimiter.Settings().Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		tokenData, err := RetrieveTokenData(r)
+		if err != nil {
+			httperror.WriteError(w, http.StatusForbidden, "Access denied", httperrors.ErrUnauthorized)
+			return
+		}
+
+		subject := RateLimitSubject{UserID: tokenData.ID}
+
+		apiKey, err := bouncer.requestAPIKey(tokenData)
+		if err != nil {
+			httperror.WriteError(w, http.StatusUnauthorized, "Unauthorized", httperrors.ErrUnauthorized)
+			return
+		}
+		if apiKey != nil {
+			subject.APIKeyPrefix = apiKey.Prefix
+		}
+
+		status := bouncer.rateLimiter.Take(subject, requestRateLimitClass(r))
+		if status.Limit > 0 {
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(status.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(status.Remaining))
+		}
+
+		if !status.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(status.RetryAfter.Seconds()))))
+			httperror.WriteError(w, http.StatusTooManyRequests, "Too many requests", httperrors.ErrTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestRateLimitClass returns the class of the budget consumed by the request
+func requestRateLimitClass(r *http.Request) RateLimitClass {
+	if class, ok := r.Context().Value(contextRateLimitClass).(RateLimitClass); ok {
+		return class
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return ReadRateLimitClass
+	}
+
+	return WriteRateLimitClass
+}
+
+// requestAPIKey returns the API key used to authenticate the request, nil when the request was not authenticated with an API key
+func (bouncer *RequestBouncer) requestAPIKey(tokenData *portainer.TokenData) (*portainer.APIKey, error) {
+	if tokenData.APIKeyID == 0 {
+		return nil, nil
+	}
+
+	return bouncer.apiKeyService.GetAPIKey(tokenData.APIKeyID)
+}
+
+// requestEndpointIDs returns the identifiers of the environments(endpoints) referenced by the path,
+// either /endpoints/{id} or /kubernetes/{id}, or by the endpointId query parameter of the request
+func requestEndpointIDs(r *http.Request) []portainer.EndpointID {
+	endpointIDs := make([]portainer.EndpointID, 0)
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	for i := 0; i < len(segments)-1; i++ {
+		if segments[i] != "endpoints" && segments[i] != "kubernetes" {
+			continue
+		}
+
+		if id, err := strconv.Atoi(segments[i+1]); err == nil {
+			endpointIDs = append(endpointIDs, portainer.EndpointID(id))
+		}
+	}
+
+	for key, values := range r.URL.Query() {
+		if !strings.EqualFold(key, "endpointId") {
+			continue
+		}
+
+		for _, value := range values {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				// an invalid identifier cannot be checked, it is rejected
+				id = -1
+			}
+			endpointIDs = append(endpointIDs, portainer.EndpointID(id))
+		}
+	}
+
+	return endpointIDs
+}
+
 // mwUpgradeToRestrictedRequest will enhance the current request with
 // a new RestrictedRequestContext object.
 func (bouncer *RequestBouncer) mwUpgradeToRestrictedRequest(next http.Handler) http.Handler {
@@ -307,6 +521,7 @@ func (bouncer *RequestBouncer) JWTAuthLookup(r *http.Request) *portainer.TokenDa
 // - computing the digest of the raw api-key
 // - verifying it exists in cache/database
 // - matching the key to a user (ID, Role)
+// - verifying the key has not expired
 // If the key is valid/verified, the last updated time of the key is updated.
 // Successful verification of the key will return a TokenData object - since the downstream handlers
 // utilise the token injected in the request context.
@@ -323,10 +538,15 @@ func (bouncer *RequestBouncer) apiKeyLookup(r *http.Request) *portainer.TokenDat
 		return nil
 	}
 
+	if user.Disabled || apikey.IsExpired(apiKey, time.Now()) {
+		return nil
+	}
+
 	tokenData := &portainer.TokenData{
 		ID:       user.ID,
 		Username: user.Username,
 		Role:     user.Role,
+		APIKeyID: apiKey.ID,
 	}
 	if _, err := bouncer.jwtService.GenerateToken(tokenData); err != nil {
 		return nil
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/apikey"
@@ -289,7 +290,7 @@ func Test_apiKeyLookup(t *testing.T) {
 	})
 
 	t.Run("valid x-api-key header succeeds api-key lookup", func(t *testing.T) {
-		rawAPIKey, _, err := apiKeyService.GenerateApiKey(*user, "test")
+		rawAPIKey, apiKey, err := apiKeyService.GenerateApiKey(*user, "test")
 		is.NoError(err)
 
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -297,7 +298,7 @@ func Test_apiKeyLookup(t *testing.T) {
 
 		token := bouncer.apiKeyLookup(req)
 
-		expectedToken := &portainer.TokenData{ID: user.ID, Username: user.Username, Role: portainer.StandardUserRole}
+		expectedToken := &portainer.TokenData{ID: user.ID, Username: user.Username, Role: portainer.StandardUserRole, APIKeyID: apiKey.ID}
 		is.Equal(expectedToken, token)
 	})
 
@@ -311,7 +312,7 @@ func Test_apiKeyLookup(t *testing.T) {
 
 		token := bouncer.apiKeyLookup(req)
 
-		expectedToken := &portainer.TokenData{ID: user.ID, Username: user.Username, Role: portainer.StandardUserRole}
+		expectedToken := &portainer.TokenData{ID: user.ID, Username: user.Username, Role: portainer.StandardUserRole, APIKeyID: apiKey.ID}
 		is.Equal(expectedToken, token)
 	})
 
@@ -325,7 +326,7 @@ func Test_apiKeyLookup(t *testing.T) {
 
 		token := bouncer.apiKeyLookup(req)
 
-		expectedToken := &portainer.TokenData{ID: user.ID, Username: user.Username, Role: portainer.StandardUserRole}
+		expectedToken := &portainer.TokenData{ID: user.ID, Username: user.Username, Role: portainer.StandardUserRole, APIKeyID: apiKey.ID}
 		is.Equal(expectedToken, token)
 
 		_, apiKeyUpdated, err := apiKeyService.GetDigestUserAndKey(apiKey.Digest)
@@ -333,4 +334,141 @@ func Test_apiKeyLookup(t *testing.T) {
 
 		is.True(apiKeyUpdated.LastUsed > apiKey.LastUsed)
 	})
+
+	t.Run("expired api-key fails api-key lookup", func(t *testing.T) {
+		rawAPIKey, apiKey, err := apiKeyService.GenerateRestrictedApiKey(*user, "test", portainer.APIKeyRestrictions{ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+		is.NoError(err)
+		defer apiKeyService.DeleteAPIKey(apiKey.ID)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Add("x-api-key", rawAPIKey)
+
+		token := bouncer.apiKeyLookup(req)
+		is.Nil(token)
+	})
+}
+
+func Test_mwCheckAPIKeyRestrictions(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	user := &portainer.User{ID: 2, Username: "standard", Role: portainer.StandardUserRole}
+	is.NoError(store.User().Create(user), "error creating user")
+
+	jwtService, err := jwt.NewService("1h", store)
+	is.NoError(err, "Error initiating jwt service")
+	apiKeyService := apikey.NewAPIKeyService(store.APIKeyRepository(), store.User())
+	bouncer := NewRequestBouncer(store, jwtService, apiKeyService)
+
+	h := bouncer.AuthenticatedAccess(testHandler200)
+
+	rawAPIKey, _, err := apiKeyService.GenerateRestrictedApiKey(*user, "ci", portainer.APIKeyRestrictions{
+		EndpointIDs: []portainer.EndpointID{1},
+		Scopes:      []string{"stacks"},
+	})
+	is.NoError(err)
+
+	readOnlyAPIKey, _, err := apiKeyService.GenerateRestrictedApiKey(*user, "monitoring", portainer.APIKeyRestrictions{ReadOnly: true})
+	is.NoError(err)
+
+	kubernetesAPIKey, _, err := apiKeyService.GenerateRestrictedApiKey(*user, "cluster", portainer.APIKeyRestrictions{
+		EndpointIDs: []portainer.EndpointID{1},
+		Scopes:      []string{"kubernetes"},
+	})
+	is.NoError(err)
+
+	tests := []struct {
+		name           string
+		apiKey         string
+		method         string
+		url            string
+		wantStatusCode int
+	}{
+		{"scoped route on allowed environment", rawAPIKey, http.MethodPost, "/stacks/create/standalone/repository?endpointId=1", http.StatusOK},
+		{"scoped route on another environment", rawAPIKey, http.MethodPut, "/stacks/3/git/redeploy?endpointId=2", http.StatusForbidden},
+		{"scoped route with invalid environment", rawAPIKey, http.MethodPut, "/stacks/3?endpointId=abc", http.StatusForbidden},
+		{"route out of scope", rawAPIKey, http.MethodGet, "/endpoints/1/docker/containers/json", http.StatusForbidden},
+		{"read-only key reads", readOnlyAPIKey, http.MethodGet, "/endpoints/2/docker/containers/json", http.StatusOK},
+		{"read-only key writes", readOnlyAPIKey, http.MethodDelete, "/stacks/1", http.StatusForbidden},
+		{"kubernetes route on allowed environment", kubernetesAPIKey, http.MethodGet, "/kubernetes/1/namespaces", http.StatusOK},
+		{"kubernetes route on another environment", kubernetesAPIKey, http.MethodGet, "/kubernetes/2/namespaces", http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.url, nil)
+			req.Header.Add("x-api-key", test.apiKey)
+
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			is.Equal(test.wantStatusCode, rr.Code)
+		})
+	}
+
+	t.Run("restricted api-key cannot operate on another environment", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/stacks", nil)
+		req.Header.Add("x-api-key", rawAPIKey)
+		req = req.WithContext(StoreTokenData(req, bouncer.apiKeyLookup(req)))
+
+		is.NoError(store.EndpointGroup().Create(&portainer.EndpointGroup{ID: 1, Name: "default"}))
+
+		is.NoError(bouncer.AuthorizedEndpointOperation(req, &portainer.Endpoint{ID: 1, GroupID: 1, UserAccessPolicies: portainer.UserAccessPolicies{user.ID: {}}}))
+		is.ErrorIs(bouncer.AuthorizedEndpointOperation(req, &portainer.Endpoint{ID: 2, GroupID: 1, UserAccessPolicies: portainer.UserAccessPolicies{user.ID: {}}}), httperrors.ErrEndpointAccessDenied)
+	})
+}
+
+func Test_mwCheckKubeconfigAccess(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	user := &portainer.User{ID: 2, Username: "standard", Role: portainer.StandardUserRole}
+	is.NoError(store.User().Create(user), "error creating user")
+
+	settings, err := store.Settings().Settings()
+	is.NoError(err)
+	settings.KubeconfigExpiry = "24h"
+	is.NoError(store.Settings().UpdateSettings(settings))
+
+	jwtService, err := jwt.NewService("1h", store)
+	is.NoError(err, "Error initiating jwt service")
+	apiKeyService := apikey.NewAPIKeyService(store.APIKeyRepository(), store.User())
+	bouncer := NewRequestBouncer(store, jwtService, apiKeyService)
+
+	tokenData := &portainer.TokenData{ID: user.ID, Username: user.Username, Role: user.Role}
+
+	sessionToken, err := jwtService.GenerateToken(tokenData)
+	is.NoError(err)
+
+	kubeconfigToken, err := jwtService.GenerateTokenForKubeconfig(tokenData)
+	is.NoError(err)
+
+	tests := []struct {
+		name           string
+		handler        http.Handler
+		token          string
+		wantStatusCode int
+	}{
+		{"kubeconfig token on the Kubernetes API proxy", bouncer.KubernetesProxyAccess(testHandler200), kubeconfigToken, http.StatusOK},
+		{"kubeconfig token on the Docker API proxy", bouncer.ProxyAccess(testHandler200), kubeconfigToken, http.StatusForbidden},
+		{"kubeconfig token on another route", bouncer.AuthenticatedAccess(testHandler200), kubeconfigToken, http.StatusForbidden},
+		{"session token on another route", bouncer.AuthenticatedAccess(testHandler200), sessionToken, http.StatusOK},
+		{"session token on the Kubernetes API proxy", bouncer.KubernetesProxyAccess(testHandler200), sessionToken, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Add("Authorization", "Bearer "+test.token)
+
+			rr := httptest.NewRecorder()
+			test.handler.ServeHTTP(rr, req)
+
+			is.Equal(test.wantStatusCode, rr.Code)
+		})
+	}
 }
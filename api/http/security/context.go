@@ -15,6 +15,8 @@ type (
 const (
 	contextAuthenticationKey contextKey = iota
 	contextRestrictedRequest
+	contextRateLimitClass
+	contextKubeconfigAccess
 )
 
 // StoreTokenData stores a TokenData object inside the request context and returns the enhanced context.
@@ -10,11 +10,14 @@ import (
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/adminmonitor"
 	"github.com/portainer/portainer/api/apikey"
+	operations "github.com/portainer/portainer/api/backup"
 	"github.com/portainer/portainer/api/crypto"
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/demo"
 	"github.com/portainer/portainer/api/docker"
 	"github.com/portainer/portainer/api/http/handler"
+	"github.com/portainer/portainer/api/http/handler/apikeys"
+	"github.com/portainer/portainer/api/http/handler/auditlogs"
 	"github.com/portainer/portainer/api/http/handler/auth"
 	"github.com/portainer/portainer/api/http/handler/backup"
 	"github.com/portainer/portainer/api/http/handler/customtemplates"
@@ -35,9 +38,12 @@ import (
 	kubehandler "github.com/portainer/portainer/api/http/handler/kubernetes"
 	"github.com/portainer/portainer/api/http/handler/ldap"
 	"github.com/portainer/portainer/api/http/handler/motd"
+	"github.com/portainer/portainer/api/http/handler/notificationchannels"
 	"github.com/portainer/portainer/api/http/handler/registries"
 	"github.com/portainer/portainer/api/http/handler/resourcecontrols"
 	"github.com/portainer/portainer/api/http/handler/roles"
+	"github.com/portainer/portainer/api/http/handler/scim"
+	"github.com/portainer/portainer/api/http/handler/search"
 	"github.com/portainer/portainer/api/http/handler/settings"
 	sslhandler "github.com/portainer/portainer/api/http/handler/ssl"
 	"github.com/portainer/portainer/api/http/handler/stacks"
@@ -61,8 +67,14 @@ import (
 	"github.com/portainer/portainer/api/internal/upgrade"
 	k8s "github.com/portainer/portainer/api/kubernetes"
 	"github.com/portainer/portainer/api/kubernetes/cli"
+	portainerldap "github.com/portainer/portainer/api/ldap"
+	"github.com/portainer/portainer/api/metrics"
+	"github.com/portainer/portainer/api/mfa"
+	"github.com/portainer/portainer/api/notifications"
+	"github.com/portainer/portainer/api/scanner"
 	"github.com/portainer/portainer/api/scheduler"
 	"github.com/portainer/portainer/api/stacks/deployments"
+	"github.com/portainer/portainer/api/stacks/drift"
 	"github.com/portainer/portainer/pkg/libhelm"
 
 	"github.com/rs/zerolog/log"
@@ -74,12 +86,15 @@ type Server struct {
 	BindAddress                 string
 	BindAddressHTTPS            string
 	HTTPEnabled                 bool
+	MetricsEnabled              bool
+	MetricsToken                string
 	AssetsPath                  string
 	Status                      *portainer.Status
 	ReverseTunnelService        portainer.ReverseTunnelService
 	ComposeStackManager         portainer.ComposeStackManager
 	CryptoService               portainer.CryptoService
 	EdgeStacksService           *edgestackservice.Service
+	EdgeStacksAutoUpdateService *edgestackservice.AutoUpdateService
 	SignatureService            portainer.DigitalSignatureService
 	SnapshotService             portainer.SnapshotService
 	FileService                 portainer.FileService
@@ -89,6 +104,8 @@ type Server struct {
 	APIKeyService               apikey.APIKeyService
 	JWTService                  dataservices.JWTService
 	LDAPService                 portainer.LDAPService
+	LDAPSyncService             *portainerldap.SyncService
+	NotificationService         *notifications.Service
 	OAuthService                portainer.OAuthService
 	SwarmStackManager           portainer.SwarmStackManager
 	ProxyManager                *proxy.Manager
@@ -97,6 +114,9 @@ type Server struct {
 	Handler                     *handler.Handler
 	SSLService                  *ssl.Service
 	DockerClientFactory         *docker.ClientFactory
+	ImageScanService            *scanner.Service
+	DriftService                *drift.Service
+	MFAService                  *mfa.Service
 	KubernetesClientFactory     *cli.ClientFactory
 	KubernetesDeployer          portainer.KubernetesDeployer
 	HelmPackageManager          libhelm.HelmPackageManager
@@ -114,23 +134,39 @@ func (server *Server) Start() error {
 
 	requestBouncer := security.NewRequestBouncer(server.DataStore, server.JWTService, server.APIKeyService)
 
+	if portainerSettings, err := server.DataStore.Settings().Settings(); err != nil {
+		log.Error().Err(err).Msg("unable to retrieve the settings, the API requests are not rate limited")
+	} else {
+		requestBouncer.APIRateLimiter().SetSettings(portainerSettings.APIRateLimitSettings)
+	}
+
 	rateLimiter := security.NewRateLimiter(10, 1*time.Second, 1*time.Hour)
 	offlineGate := offlinegate.NewOfflineGate()
 
 	passwordStrengthChecker := security.NewPasswordStrengthChecker(server.DataStore.Settings())
 
+	var auditLogHandler = auditlogs.NewHandler(requestBouncer, server.DataStore)
+
 	var authHandler = auth.NewHandler(requestBouncer, rateLimiter, passwordStrengthChecker)
 	authHandler.DataStore = server.DataStore
 	authHandler.CryptoService = server.CryptoService
+	authHandler.MFAService = server.MFAService
 	authHandler.JWTService = server.JWTService
 	authHandler.LDAPService = server.LDAPService
 	authHandler.ProxyManager = server.ProxyManager
 	authHandler.KubernetesTokenCacheManager = kubernetesTokenCacheManager
 	authHandler.OAuthService = server.OAuthService
+	authHandler.NotificationService = server.NotificationService
 
 	adminMonitor := adminmonitor.New(5*time.Minute, server.DataStore, server.ShutdownCtx)
 	adminMonitor.Start()
 
+	scheduledBackupService := operations.NewScheduledBackupService(server.Scheduler, server.DataStore, offlineGate, server.FileService.GetDatastorePath())
+	err := scheduledBackupService.Start()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to schedule the automatic backups")
+	}
+
 	var backupHandler = backup.NewHandler(
 		requestBouncer,
 		server.DataStore,
@@ -139,11 +175,18 @@ func (server *Server) Start() error {
 		server.ShutdownTrigger,
 		adminMonitor,
 		server.DemoService,
+		scheduledBackupService,
 	)
 
 	var roleHandler = roles.NewHandler(requestBouncer)
+
 	roleHandler.DataStore = server.DataStore
 
+	var searchHandler = search.NewHandler(requestBouncer, server.DataStore)
+	searchHandler.KubernetesClientFactory = server.KubernetesClientFactory
+
+	var apiKeyHandler = apikeys.NewHandler(requestBouncer, server.DataStore, server.APIKeyService)
+
 	var customTemplatesHandler = customtemplates.NewHandler(requestBouncer, server.DataStore, server.FileService, server.GitService)
 
 	var edgeGroupsHandler = edgegroups.NewHandler(requestBouncer)
@@ -159,6 +202,8 @@ func (server *Server) Start() error {
 	edgeStacksHandler.FileService = server.FileService
 	edgeStacksHandler.GitService = server.GitService
 	edgeStacksHandler.KubernetesDeployer = server.KubernetesDeployer
+	edgeStacksHandler.NotificationService = server.NotificationService
+	edgeStacksHandler.AutoUpdateService = server.EdgeStacksAutoUpdateService
 
 	var edgeTemplatesHandler = edgetemplates.NewHandler(requestBouncer)
 	edgeTemplatesHandler.DataStore = server.DataStore
@@ -180,6 +225,7 @@ func (server *Server) Start() error {
 	var endpointGroupHandler = endpointgroups.NewHandler(requestBouncer)
 	endpointGroupHandler.AuthorizationService = server.AuthorizationService
 	endpointGroupHandler.DataStore = server.DataStore
+	endpointGroupHandler.FileService = server.FileService
 
 	var endpointProxyHandler = endpointproxy.NewHandler(requestBouncer)
 	endpointProxyHandler.DataStore = server.DataStore
@@ -188,7 +234,7 @@ func (server *Server) Start() error {
 
 	var kubernetesHandler = kubehandler.NewHandler(requestBouncer, server.AuthorizationService, server.DataStore, server.JWTService, server.KubeClusterAccessService, server.KubernetesClientFactory, nil)
 
-	var dockerHandler = dockerhandler.NewHandler(requestBouncer, server.AuthorizationService, server.DataStore, server.DockerClientFactory)
+	var dockerHandler = dockerhandler.NewHandler(requestBouncer, server.AuthorizationService, server.DataStore, server.DockerClientFactory, server.ImageScanService)
 
 	var fileHandler = file.NewHandler(filepath.Join(server.AssetsPath, "public"), adminMonitor.WasInstanceDisabled)
 
@@ -196,15 +242,18 @@ func (server *Server) Start() error {
 
 	var gitOperationHandler = gitops.NewHandler(requestBouncer, server.DataStore, server.GitService, server.FileService)
 
-	var helmTemplatesHandler = helm.NewTemplateHandler(requestBouncer, server.HelmPackageManager)
+	var helmTemplatesHandler = helm.NewTemplateHandler(requestBouncer, server.HelmPackageManager, filepath.Join(server.FileService.GetDatastorePath(), "helm", "index-cache"))
 
 	var ldapHandler = ldap.NewHandler(requestBouncer)
 	ldapHandler.DataStore = server.DataStore
 	ldapHandler.FileService = server.FileService
 	ldapHandler.LDAPService = server.LDAPService
+	ldapHandler.SyncService = server.LDAPSyncService
 
 	var motdHandler = motd.NewHandler(requestBouncer)
 
+	var notificationHandler = notificationchannels.NewHandler(requestBouncer, server.DataStore, server.NotificationService)
+
 	var registryHandler = registries.NewHandler(requestBouncer)
 	registryHandler.DataStore = server.DataStore
 	registryHandler.FileService = server.FileService
@@ -219,7 +268,11 @@ func (server *Server) Start() error {
 	settingsHandler.FileService = server.FileService
 	settingsHandler.JWTService = server.JWTService
 	settingsHandler.LDAPService = server.LDAPService
+	settingsHandler.LDAPSyncService = server.LDAPSyncService
 	settingsHandler.SnapshotService = server.SnapshotService
+	settingsHandler.ImageScanService = server.ImageScanService
+	settingsHandler.DriftService = server.DriftService
+	settingsHandler.APIRateLimiter = requestBouncer.APIRateLimiter()
 
 	var sslHandler = sslhandler.NewHandler(requestBouncer)
 	sslHandler.SSLService = server.SSLService
@@ -242,12 +295,15 @@ func (server *Server) Start() error {
 	stackHandler.SwarmStackManager = server.SwarmStackManager
 	stackHandler.ComposeStackManager = server.ComposeStackManager
 	stackHandler.StackDeployer = server.StackDeployer
+	stackHandler.DriftService = server.DriftService
 
 	var storybookHandler = storybook.NewHandler(server.AssetsPath)
 
 	var tagHandler = tags.NewHandler(requestBouncer)
 	tagHandler.DataStore = server.DataStore
 
+	var scimHandler = scim.NewHandler(requestBouncer, server.DataStore, server.APIKeyService)
+
 	var teamHandler = teams.NewHandler(requestBouncer)
 	teamHandler.DataStore = server.DataStore
 
@@ -271,6 +327,8 @@ func (server *Server) Start() error {
 	var userHandler = users.NewHandler(requestBouncer, rateLimiter, server.APIKeyService, server.DemoService, passwordStrengthChecker)
 	userHandler.DataStore = server.DataStore
 	userHandler.CryptoService = server.CryptoService
+	userHandler.MFAService = server.MFAService
+	userHandler.JWTService = server.JWTService
 
 	var websocketHandler = websocket.NewHandler(server.KubernetesTokenCacheManager, requestBouncer)
 	websocketHandler.DataStore = server.DataStore
@@ -281,9 +339,14 @@ func (server *Server) Start() error {
 	var webhookHandler = webhooks.NewHandler(requestBouncer)
 	webhookHandler.DataStore = server.DataStore
 	webhookHandler.DockerClientFactory = server.DockerClientFactory
+	webhookHandler.ContainerService = docker.NewContainerService(server.DockerClientFactory)
+	webhookHandler.StackDeployer = server.StackDeployer
 
 	server.Handler = &handler.Handler{
 		RoleHandler:            roleHandler,
+		SearchHandler:          searchHandler,
+		APIKeyHandler:          apiKeyHandler,
+		AuditLogHandler:        auditLogHandler,
 		AuthHandler:            authHandler,
 		BackupHandler:          backupHandler,
 		CustomTemplatesHandler: customTemplatesHandler,
@@ -303,6 +366,7 @@ func (server *Server) Start() error {
 		HelmTemplatesHandler:   helmTemplatesHandler,
 		KubernetesHandler:      kubernetesHandler,
 		MOTDHandler:            motdHandler,
+		NotificationHandler:    notificationHandler,
 		OpenAMTHandler:         openAMTHandler,
 		FDOHandler:             fdoHandler,
 		RegistryHandler:        registryHandler,
@@ -313,6 +377,7 @@ func (server *Server) Start() error {
 		StorybookHandler:       storybookHandler,
 		SystemHandler:          systemHandler,
 		TagHandler:             tagHandler,
+		SCIMHandler:            scimHandler,
 		TeamHandler:            teamHandler,
 		TeamMembershipHandler:  teamMembershipHandler,
 		TemplatesHandler:       templatesHandler,
@@ -322,7 +387,11 @@ func (server *Server) Start() error {
 		WebhookHandler:         webhookHandler,
 	}
 
-	handler := adminMonitor.WithRedirect(offlineGate.WaitingMiddleware(time.Minute, server.Handler))
+	var handler http.Handler = adminMonitor.WithRedirect(offlineGate.WaitingMiddleware(time.Minute, server.Handler))
+	if server.MetricsEnabled {
+		handler = metrics.WithMetricsEndpoint(handler, server.MetricsToken)
+	}
+
 	if server.HTTPEnabled {
 		go func() {
 			log.Info().Str("bind_address", server.BindAddress).Msg("starting HTTP server")
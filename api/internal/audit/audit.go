@@ -0,0 +1,170 @@
+package audit
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/scheduler"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// MaxBodySize is the maximum number of bytes of a request body recorded in an audit log
+	MaxBodySize = 16 * 1024
+	// RedactedValue replaces the sensitive values of a recorded request body
+	RedactedValue = "[REDACTED]"
+
+	retentionJobInterval = 24 * time.Hour
+)
+
+var sensitiveKeys = []string{
+	"password",
+	"passwd",
+	"passphrase",
+	"secret",
+	"token",
+	"apikey",
+	"api_key",
+	"privatekey",
+	"credential",
+}
+
+// sensitiveExactKeys are redacted only when they match the whole key, e.g. the MFA codes sent to the public
+// authentication routes
+var sensitiveExactKeys = []string{
+	"code",
+	"recoverycode",
+}
+
+// RedactBody returns a printable version of a request body where the values of the
+// sensitive fields are replaced. Only JSON bodies are recorded, the content of any
+// other type of body is omitted.
+func RedactBody(body []byte, contentType string, truncated bool) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	contentType = strings.TrimSpace(strings.Split(contentType, ";")[0])
+	if contentType != "" && !strings.Contains(contentType, "json") {
+		return "[" + contentType + " content omitted]"
+	}
+
+	if truncated {
+		return "[content exceeding the maximum size omitted]"
+	}
+
+	var payload interface{}
+	err := json.Unmarshal(body, &payload)
+	if err != nil {
+		return "[invalid JSON content omitted]"
+	}
+
+	redacted, err := json.Marshal(redact(payload))
+	if err != nil {
+		return "[invalid JSON content omitted]"
+	}
+
+	return string(redacted)
+}
+
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		// the environment variables of the stacks are sent as name/value pairs
+		sensitivePair := hasSensitiveName(v)
+
+		for key, field := range v {
+			if isSensitive(key) || (sensitivePair && strings.EqualFold(key, "value")) {
+				v[key] = RedactedValue
+				continue
+			}
+
+			// the environment variables of the containers are sent as KEY=value strings
+			if list, ok := field.([]interface{}); ok && strings.EqualFold(key, "env") {
+				v[key] = redactEnv(list)
+				continue
+			}
+
+			v[key] = redact(field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redact(item)
+		}
+	}
+
+	return value
+}
+
+func hasSensitiveName(pair map[string]interface{}) bool {
+	for key, field := range pair {
+		if !strings.EqualFold(key, "name") {
+			continue
+		}
+
+		name, ok := field.(string)
+		return ok && isSensitive(name)
+	}
+
+	return false
+}
+
+func redactEnv(env []interface{}) []interface{} {
+	for i, item := range env {
+		variable, ok := item.(string)
+		if !ok {
+			env[i] = redact(item)
+			continue
+		}
+
+		name, _, found := strings.Cut(variable, "=")
+		if found && isSensitive(name) {
+			env[i] = name + "=" + RedactedValue
+		}
+	}
+
+	return env
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveExactKeys {
+		if key == sensitive {
+			return true
+		}
+	}
+
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StartRetentionJob schedules a daily job removing the audit logs older than retentionDays.
+// The audit logs are kept forever when retentionDays is not positive.
+func StartRetentionJob(scheduler *scheduler.Scheduler, dataStore dataservices.DataStore, retentionDays int) {
+	if retentionDays <= 0 {
+		return
+	}
+
+	prune := func() error {
+		before := time.Now().AddDate(0, 0, -retentionDays).Unix()
+
+		err := dataStore.AuditLog().DeleteAuditLogsBefore(before)
+		if err != nil {
+			log.Error().Err(err).Msg("unable to remove expired audit logs")
+		}
+
+		// never stop the job, a failure is retried on the next run
+		return nil
+	}
+
+	prune()
+	scheduler.StartJobEvery(retentionJobInterval, prune)
+}
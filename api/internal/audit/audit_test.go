@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RedactBody(t *testing.T) {
+	is := assert.New(t)
+
+	body := []byte(`{"Username":"admin","Password":"secret","Nested":{"ApiKey":"abc","TLSSecret":"x","Name":"n"},"List":[{"token":"t"}]}`)
+
+	var result map[string]interface{}
+	is.NoError(json.Unmarshal([]byte(RedactBody(body, "application/json", false)), &result))
+
+	is.Equal("admin", result["Username"])
+	is.Equal(RedactedValue, result["Password"])
+
+	nested := result["Nested"].(map[string]interface{})
+	is.Equal(RedactedValue, nested["ApiKey"])
+	is.Equal(RedactedValue, nested["TLSSecret"])
+	is.Equal("n", nested["Name"])
+
+	list := result["List"].([]interface{})
+	is.Equal(RedactedValue, list[0].(map[string]interface{})["token"])
+}
+
+func Test_RedactBody_MFACode(t *testing.T) {
+	is := assert.New(t)
+
+	is.Equal(`{"code":"[REDACTED]","mfaToken":"[REDACTED]"}`, RedactBody([]byte(`{"mfaToken":"t","code":"123456"}`), "application/json", false))
+	is.Equal(`{"ExitCode":1}`, RedactBody([]byte(`{"ExitCode":1}`), "application/json", false))
+}
+
+func Test_RedactBody_EnvironmentVariables(t *testing.T) {
+	is := assert.New(t)
+
+	is.Equal(
+		`{"env":[{"name":"DB_PASSWORD","value":"[REDACTED]"},{"name":"DB_HOST","value":"db"}]}`,
+		RedactBody([]byte(`{"env":[{"name":"DB_PASSWORD","value":"p4ss"},{"name":"DB_HOST","value":"db"}]}`), "application/json", false),
+	)
+
+	is.Equal(
+		`{"Env":["PASSWORD=[REDACTED]","API_TOKEN=[REDACTED]","MODE=production"],"Image":"nginx"}`,
+		RedactBody([]byte(`{"Image":"nginx","Env":["PASSWORD=p4ss","API_TOKEN=abc","MODE=production"]}`), "application/json", false),
+	)
+}
+
+func Test_RedactBody_NonJSON(t *testing.T) {
+	is := assert.New(t)
+
+	is.Equal("", RedactBody(nil, "application/json", false))
+	is.Equal("[multipart/form-data content omitted]", RedactBody([]byte("data"), "multipart/form-data; boundary=x", false))
+	is.Equal("[content exceeding the maximum size omitted]", RedactBody([]byte(`{"a":`), "", true))
+	is.Equal("[invalid JSON content omitted]", RedactBody([]byte("not json"), "", false))
+}
@@ -0,0 +1,319 @@
+package edgejobs
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/archive"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultLogsRetention is the number of runs whose logs are retained for an Edge job without a logs retention,
+// the logs of the oldest runs are removed first
+const DefaultLogsRetention = 10
+
+// MaxSearchMatches is the maximum number of lines returned when searching the logs of an Edge job
+const MaxSearchMatches = 1000
+
+// MaxRunDateSkew is the tolerance applied to the run dates reported ahead of the server clock by the environments
+const MaxRunDateSkew = 5 * time.Minute
+
+var (
+	// ErrRunNotFound is returned when the logs of an Edge job run are not retained
+	ErrRunNotFound = errors.New("The logs of the Edge job run are not retained")
+	// ErrInvalidRunDate is returned when a run date does not match any activation of the schedule of an Edge job
+	ErrInvalidRunDate = errors.New("The run date does not match the schedule of the Edge job")
+)
+
+// RunSummary aggregates the results collected from the environments for a run of an Edge job
+type RunSummary struct {
+	RunID int64 `json:"RunId" example:"1678881600"`
+	// Number of environments which reported a zero exit code
+	SuccessCount int `json:"SuccessCount" example:"3"`
+	// Number of environments which reported a non zero exit code
+	FailureCount int `json:"FailureCount" example:"1"`
+	// Number of environments which did not report an exit code
+	UnknownCount int `json:"UnknownCount" example:"0"`
+	// Results of the run indexed by environment
+	Results map[portainer.EndpointID]portainer.EdgeJobRunResult `json:"Results"`
+}
+
+// LogMatch is a line of the logs of an Edge job run matching a search
+type LogMatch struct {
+	RunID      int64                `json:"RunId" example:"1678881600"`
+	EndpointID portainer.EndpointID `json:"EndpointId" example:"1"`
+	// Line number, starting at 1
+	Line    int    `json:"Line" example:"12"`
+	Content string `json:"Content" example:"error: no space left on device"`
+}
+
+// RunID returns the identifier of the run of an Edge job whose logs are collected at the given time.
+// The run is the latest activation of the schedule of the job, it is shared by all the environments and by the successive collections of its logs.
+// The run date reported by an environment only selects an earlier activation, ErrInvalidRunDate is returned when it precedes the creation
+// of the job or exceeds the collection time by more than MaxRunDateSkew.
+func RunID(edgeJob *portainer.EdgeJob, runDate int64, now time.Time) (int64, error) {
+	schedule, err := cron.ParseStandard(edgeJob.CronExpression)
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to parse the schedule of the edge job %d", edgeJob.ID)
+	}
+
+	created := time.Unix(edgeJob.Created, 0).UTC().Truncate(time.Minute)
+	reference := now.UTC()
+
+	if runDate != 0 {
+		reported := time.Unix(runDate, 0).UTC()
+		if reported.Before(created) || reported.After(reference.Add(MaxRunDateSkew)) {
+			return 0, ErrInvalidRunDate
+		}
+
+		reference = reported
+	}
+
+	activation, ok := lastActivation(schedule, created, reference)
+	if !ok {
+		return 0, ErrInvalidRunDate
+	}
+
+	return activation.Unix(), nil
+}
+
+// LogsRetention returns the number of runs whose logs are retained for an Edge job
+func LogsRetention(edgeJob *portainer.EdgeJob) int {
+	if edgeJob.LogsRetention <= 0 {
+		return DefaultLogsRetention
+	}
+
+	return edgeJob.LogsRetention
+}
+
+// FindRun returns the run of an Edge job with the given identifier
+func FindRun(edgeJob *portainer.EdgeJob, runID int64) (*portainer.EdgeJobRun, bool) {
+	for i := range edgeJob.Runs {
+		if edgeJob.Runs[i].ID == runID {
+			return &edgeJob.Runs[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// RecordRunResult retains the logs and the exit code collected from an environment for a run of an Edge job.
+// The logs of the oldest runs are removed beyond the logs retention of the job.
+func RecordRunResult(fileService portainer.FileService, edgeJob *portainer.EdgeJob, endpointID portainer.EndpointID, runID int64, exitCode *int, logs []byte) error {
+	edgeJobFolder := strconv.Itoa(int(edgeJob.ID))
+
+	err := fileService.StoreEdgeJobRunLogFileFromBytes(edgeJobFolder, strconv.FormatInt(runID, 10), strconv.Itoa(int(endpointID)), logs)
+	if err != nil {
+		return errors.WithMessagef(err, "unable to retain the logs of the run %d of the edge job %d", runID, edgeJob.ID)
+	}
+
+	run, ok := FindRun(edgeJob, runID)
+	if !ok {
+		edgeJob.Runs = append(edgeJob.Runs, portainer.EdgeJobRun{ID: runID})
+		sort.Slice(edgeJob.Runs, func(i, j int) bool { return edgeJob.Runs[i].ID < edgeJob.Runs[j].ID })
+
+		run, _ = FindRun(edgeJob, runID)
+	}
+
+	if run.Results == nil {
+		run.Results = make(map[portainer.EndpointID]portainer.EdgeJobRunResult)
+	}
+
+	run.Results[endpointID] = portainer.EdgeJobRunResult{
+		ExitCode:    exitCode,
+		CollectedAt: time.Now().Unix(),
+		LogsSize:    int64(len(logs)),
+	}
+
+	PruneRuns(fileService, edgeJob)
+
+	return nil
+}
+
+// PruneRuns removes the logs of the oldest runs of an Edge job beyond its logs retention
+func PruneRuns(fileService portainer.FileService, edgeJob *portainer.EdgeJob) {
+	excess := len(edgeJob.Runs) - LogsRetention(edgeJob)
+	if excess <= 0 {
+		return
+	}
+
+	edgeJobFolder := strconv.Itoa(int(edgeJob.ID))
+	for _, run := range edgeJob.Runs[:excess] {
+		err := fileService.RemoveDirectory(fileService.GetEdgeJobRunLogsFolder(edgeJobFolder, strconv.FormatInt(run.ID, 10)))
+		if err != nil {
+			log.Warn().Err(err).Int("edge_job_id", int(edgeJob.ID)).Int64("run_id", run.ID).Msg("unable to remove the logs of the edge job run")
+		}
+	}
+
+	edgeJob.Runs = append([]portainer.EdgeJobRun{}, edgeJob.Runs[excess:]...)
+}
+
+// SummarizeRun counts the successes and the failures reported by the environments for a run of an Edge job
+func SummarizeRun(run portainer.EdgeJobRun) RunSummary {
+	summary := RunSummary{
+		RunID:   run.ID,
+		Results: run.Results,
+	}
+
+	for _, result := range run.Results {
+		switch {
+		case result.ExitCode == nil:
+			summary.UnknownCount++
+		case *result.ExitCode == 0:
+			summary.SuccessCount++
+		default:
+			summary.FailureCount++
+		}
+	}
+
+	return summary
+}
+
+// SearchLogs returns the lines of the retained logs of an Edge job containing the query, ignoring the case.
+// The runs are searched from the most recent one, all the runs are searched when runID is zero.
+// At most MaxSearchMatches lines are returned.
+func SearchLogs(fileService portainer.FileService, edgeJob *portainer.EdgeJob, query string, runID int64) ([]LogMatch, error) {
+	if runID != 0 {
+		if _, ok := FindRun(edgeJob, runID); !ok {
+			return nil, ErrRunNotFound
+		}
+	}
+
+	edgeJobFolder := strconv.Itoa(int(edgeJob.ID))
+	query = strings.ToLower(query)
+	matches := []LogMatch{}
+
+	for i := len(edgeJob.Runs) - 1; i >= 0; i-- {
+		run := edgeJob.Runs[i]
+		if runID != 0 && run.ID != runID {
+			continue
+		}
+
+		for _, endpointID := range runEndpoints(run) {
+			content, err := fileService.GetEdgeJobRunLogFileContent(edgeJobFolder, strconv.FormatInt(run.ID, 10), strconv.Itoa(int(endpointID)))
+			if err != nil {
+				return nil, errors.WithMessagef(err, "unable to read the logs of the run %d of the edge job %d", run.ID, edgeJob.ID)
+			}
+
+			scanner := bufio.NewScanner(strings.NewReader(content))
+			scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+
+			for line := 1; scanner.Scan(); line++ {
+				if !strings.Contains(strings.ToLower(scanner.Text()), query) {
+					continue
+				}
+
+				matches = append(matches, LogMatch{
+					RunID:      run.ID,
+					EndpointID: endpointID,
+					Line:       line,
+					Content:    scanner.Text(),
+				})
+
+				if len(matches) == MaxSearchMatches {
+					return matches, nil
+				}
+			}
+		}
+	}
+
+	return matches, nil
+}
+
+// ArchiveRunLogs creates a tar.gz archive holding the logs collected from each environment for a run of an Edge job
+// along with the summary of the run. It returns the path of the archive, the caller is responsible for removing its folder.
+func ArchiveRunLogs(fileService portainer.FileService, edgeJob *portainer.EdgeJob, runID int64) (string, error) {
+	run, ok := FindRun(edgeJob, runID)
+	if !ok {
+		return "", ErrRunNotFound
+	}
+
+	archiveFolder, err := fileService.GetTemporaryPath()
+	if err != nil {
+		return "", errors.WithMessage(err, "unable to create a temporary folder")
+	}
+
+	err = os.MkdirAll(archiveFolder, 0700)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to create a temporary folder")
+	}
+
+	archivePath, err := archiveRunLogs(fileService, edgeJob, *run, archiveFolder)
+	if err != nil {
+		os.RemoveAll(archiveFolder)
+		return "", err
+	}
+
+	return archivePath, nil
+}
+
+func archiveRunLogs(fileService portainer.FileService, edgeJob *portainer.EdgeJob, run portainer.EdgeJobRun, archiveFolder string) (string, error) {
+	edgeJobFolder := strconv.Itoa(int(edgeJob.ID))
+
+	for _, endpointID := range runEndpoints(run) {
+		content, err := fileService.GetEdgeJobRunLogFileContent(edgeJobFolder, strconv.FormatInt(run.ID, 10), strconv.Itoa(int(endpointID)))
+		if err != nil {
+			return "", errors.WithMessagef(err, "unable to read the logs of the run %d of the edge job %d", run.ID, edgeJob.ID)
+		}
+
+		err = os.WriteFile(filepath.Join(archiveFolder, fmt.Sprintf("endpoint_%d.log", endpointID)), []byte(content), 0600)
+		if err != nil {
+			return "", errors.Wrap(err, "unable to copy the logs to the temporary folder")
+		}
+	}
+
+	summary, err := json.MarshalIndent(SummarizeRun(run), "", "  ")
+	if err != nil {
+		return "", errors.Wrap(err, "unable to encode the summary of the run")
+	}
+
+	err = os.WriteFile(filepath.Join(archiveFolder, "summary.json"), summary, 0600)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to write the summary of the run")
+	}
+
+	return archive.TarGzDir(archiveFolder)
+}
+
+// lastActivation returns the latest activation of a schedule between the given times, both included.
+// The search window grows backwards from the end so that frequent schedules do not walk their whole history.
+func lastActivation(schedule cron.Schedule, from, to time.Time) (time.Time, bool) {
+	for window := time.Hour; ; window *= 2 {
+		start := to.Add(-window)
+		if start.Before(from) {
+			start = from
+		}
+
+		last, found := time.Time{}, false
+		for next := schedule.Next(start.Add(-time.Second)); !next.IsZero() && !next.After(to); next = schedule.Next(next) {
+			last, found = next, true
+		}
+
+		if found || !start.After(from) {
+			return last, found
+		}
+	}
+}
+
+// runEndpoints returns the environments which reported a result for a run, sorted by identifier
+func runEndpoints(run portainer.EdgeJobRun) []portainer.EndpointID {
+	endpointIDs := make([]portainer.EndpointID, 0, len(run.Results))
+	for endpointID := range run.Results {
+		endpointIDs = append(endpointIDs, endpointID)
+	}
+
+	sort.Slice(endpointIDs, func(i, j int) bool { return endpointIDs[i] < endpointIDs[j] })
+
+	return endpointIDs
+}
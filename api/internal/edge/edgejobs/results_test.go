@@ -0,0 +1,153 @@
+package edgejobs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/archive"
+	"github.com/portainer/portainer/api/filesystem"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exitCode(code int) *int {
+	return &code
+}
+
+func Test_RunID(t *testing.T) {
+	is := assert.New(t)
+
+	edgeJob := &portainer.EdgeJob{
+		ID:             1,
+		CronExpression: "0 */6 * * *",
+		Created:        time.Date(2023, 3, 14, 9, 30, 0, 0, time.UTC).Unix(),
+	}
+	now := time.Date(2023, 3, 15, 13, 42, 0, 0, time.UTC)
+	latestRun := time.Date(2023, 3, 15, 12, 0, 0, 0, time.UTC).Unix()
+
+	runID, err := RunID(edgeJob, 0, now)
+	require.NoError(t, err)
+	is.Equal(latestRun, runID, "the run should default to the latest activation of the schedule")
+
+	runID, err = RunID(edgeJob, time.Date(2023, 3, 15, 12, 3, 0, 0, time.UTC).Unix(), now)
+	require.NoError(t, err)
+	is.Equal(latestRun, runID, "the collections at different minutes should belong to the same run")
+
+	runID, err = RunID(edgeJob, time.Date(2023, 3, 15, 1, 0, 0, 0, time.UTC).Unix(), now)
+	require.NoError(t, err)
+	is.Equal(time.Date(2023, 3, 15, 0, 0, 0, 0, time.UTC).Unix(), runID)
+
+	runID, err = RunID(edgeJob, now.Add(2*time.Minute).Unix(), now)
+	require.NoError(t, err)
+	is.Equal(latestRun, runID, "the run dates slightly ahead of the server clock should be accepted")
+
+	_, err = RunID(edgeJob, now.Add(24*time.Hour).Unix(), now)
+	is.ErrorIs(err, ErrInvalidRunDate, "the run dates in the future should be rejected")
+
+	_, err = RunID(edgeJob, time.Date(2023, 3, 13, 12, 0, 0, 0, time.UTC).Unix(), now)
+	is.ErrorIs(err, ErrInvalidRunDate, "the run dates preceding the creation of the job should be rejected")
+
+	_, err = RunID(edgeJob, 0, time.Date(2023, 3, 14, 10, 0, 0, 0, time.UTC))
+	is.ErrorIs(err, ErrInvalidRunDate, "the logs collected before the first activation should be rejected")
+}
+
+func Test_RecordRunResult(t *testing.T) {
+	is := assert.New(t)
+
+	fileService, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+
+	edgeJob := &portainer.EdgeJob{ID: 1, LogsRetention: 2}
+
+	require.NoError(t, RecordRunResult(fileService, edgeJob, 3, 120, exitCode(0), []byte("done")))
+	require.NoError(t, RecordRunResult(fileService, edgeJob, 4, 120, exitCode(2), []byte("failed")))
+	require.NoError(t, RecordRunResult(fileService, edgeJob, 3, 60, nil, []byte("older")))
+
+	is.Len(edgeJob.Runs, 2)
+	is.Equal(int64(60), edgeJob.Runs[0].ID, "the runs should be ordered from the oldest one")
+
+	summary := SummarizeRun(edgeJob.Runs[1])
+	is.Equal(1, summary.SuccessCount)
+	is.Equal(1, summary.FailureCount)
+	is.Equal(0, summary.UnknownCount)
+	is.Equal(int64(len("failed")), summary.Results[4].LogsSize)
+
+	is.Equal(1, SummarizeRun(edgeJob.Runs[0]).UnknownCount)
+
+	t.Run("should remove the logs of the oldest runs beyond the retention", func(t *testing.T) {
+		require.NoError(t, RecordRunResult(fileService, edgeJob, 3, 180, exitCode(0), []byte("latest")))
+
+		is.Len(edgeJob.Runs, 2)
+		is.Equal(int64(120), edgeJob.Runs[0].ID)
+
+		exists, err := fileService.FileExists(fileService.GetEdgeJobRunLogsFolder("1", "60"))
+		require.NoError(t, err)
+		is.False(exists)
+
+		content, err := fileService.GetEdgeJobRunLogFileContent("1", "120", "4")
+		require.NoError(t, err)
+		is.Equal("failed", content)
+	})
+}
+
+func Test_SearchLogs(t *testing.T) {
+	is := assert.New(t)
+
+	fileService, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+
+	edgeJob := &portainer.EdgeJob{ID: 1}
+	require.NoError(t, RecordRunResult(fileService, edgeJob, 3, 60, exitCode(1), []byte("starting\nERROR: disk full\n")))
+	require.NoError(t, RecordRunResult(fileService, edgeJob, 3, 120, exitCode(0), []byte("starting\ndone\n")))
+	require.NoError(t, RecordRunResult(fileService, edgeJob, 4, 120, exitCode(1), []byte("error: timeout\n")))
+
+	matches, err := SearchLogs(fileService, edgeJob, "error", 0)
+	require.NoError(t, err)
+	is.Equal([]LogMatch{
+		{RunID: 120, EndpointID: 4, Line: 1, Content: "error: timeout"},
+		{RunID: 60, EndpointID: 3, Line: 2, Content: "ERROR: disk full"},
+	}, matches)
+
+	matches, err = SearchLogs(fileService, edgeJob, "starting", 60)
+	require.NoError(t, err)
+	is.Len(matches, 1)
+
+	_, err = SearchLogs(fileService, edgeJob, "starting", 180)
+	is.ErrorIs(err, ErrRunNotFound)
+}
+
+func Test_ArchiveRunLogs(t *testing.T) {
+	is := assert.New(t)
+
+	fileService, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+
+	edgeJob := &portainer.EdgeJob{ID: 1}
+	require.NoError(t, RecordRunResult(fileService, edgeJob, 3, 60, exitCode(0), []byte("done")))
+	require.NoError(t, RecordRunResult(fileService, edgeJob, 4, 60, exitCode(1), []byte("failed")))
+
+	_, err = ArchiveRunLogs(fileService, edgeJob, 120)
+	is.ErrorIs(err, ErrRunNotFound)
+
+	archivePath, err := ArchiveRunLogs(fileService, edgeJob, 60)
+	require.NoError(t, err)
+	defer os.RemoveAll(filepath.Dir(archivePath))
+
+	file, err := os.Open(archivePath)
+	require.NoError(t, err)
+	defer file.Close()
+
+	outputPath := t.TempDir()
+	require.NoError(t, archive.ExtractTarGz(file, outputPath))
+
+	content, err := os.ReadFile(filepath.Join(outputPath, "endpoint_4.log"))
+	require.NoError(t, err)
+	is.Equal("failed", string(content))
+
+	is.FileExists(filepath.Join(outputPath, "endpoint_3.log"))
+	is.FileExists(filepath.Join(outputPath, "summary.json"))
+}
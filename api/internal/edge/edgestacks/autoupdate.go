@@ -0,0 +1,211 @@
+package edgestacks
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/git/update"
+	"github.com/portainer/portainer/api/internal/edge"
+	"github.com/portainer/portainer/api/metrics"
+	"github.com/portainer/portainer/api/scheduler"
+
+	"github.com/rs/zerolog/log"
+)
+
+// AutoUpdateService keeps the edge stacks deployed from a git repository up to date.
+// The version of an edge stack is bumped when its repository changes so that the agents redeploy it.
+type AutoUpdateService struct {
+	dataStore          dataservices.DataStore
+	fileService        portainer.FileService
+	gitService         portainer.GitService
+	kubernetesDeployer portainer.KubernetesDeployer
+	scheduler          *scheduler.Scheduler
+}
+
+// NewAutoUpdateService returns a new instance of the edge stacks auto update service
+func NewAutoUpdateService(dataStore dataservices.DataStore, fileService portainer.FileService, gitService portainer.GitService, kubernetesDeployer portainer.KubernetesDeployer, scheduler *scheduler.Scheduler) *AutoUpdateService {
+	return &AutoUpdateService{
+		dataStore:          dataStore,
+		fileService:        fileService,
+		gitService:         gitService,
+		kubernetesDeployer: kubernetesDeployer,
+		scheduler:          scheduler,
+	}
+}
+
+// StartSchedules schedules the polling of the edge stacks configured with an auto update interval
+func (service *AutoUpdateService) StartSchedules() error {
+	edgeStacks, err := service.dataStore.EdgeStack().EdgeStacks()
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch the edge stacks")
+	}
+
+	for _, edgeStack := range edgeStacks {
+		if edgeStack.AutoUpdate == nil || edgeStack.AutoUpdate.Interval == "" {
+			continue
+		}
+
+		jobID, err := service.StartAutoUpdate(edgeStack.ID, edgeStack.AutoUpdate.Interval)
+		if err != nil {
+			return err
+		}
+
+		err = service.dataStore.EdgeStack().UpdateEdgeStackFunc(edgeStack.ID, func(edgeStack *portainer.EdgeStack) {
+			if edgeStack.AutoUpdate != nil {
+				edgeStack.AutoUpdate.JobID = jobID
+			}
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to update the edge stack job id")
+		}
+	}
+
+	return nil
+}
+
+// StartAutoUpdate polls the repository of an edge stack at the given interval, it returns the identifier of the job
+func (service *AutoUpdateService) StartAutoUpdate(edgeStackID portainer.EdgeStackID, interval string) (string, error) {
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to parse the auto update interval of the edge stack")
+	}
+
+	jobID := service.scheduler.StartJobEvery(d, func() error {
+		_, err := service.UpdateWhenChanged(edgeStackID)
+		return err
+	})
+
+	return jobID, nil
+}
+
+// StopAutoUpdate stops the polling job of an edge stack
+func (service *AutoUpdateService) StopAutoUpdate(edgeStackID portainer.EdgeStackID, jobID string) {
+	if jobID == "" {
+		return
+	}
+
+	if err := service.scheduler.StopJob(jobID); err != nil {
+		log.Warn().Int("edge_stack_id", int(edgeStackID)).Msg("could not stop the job for the edge stack")
+	}
+}
+
+// UpdateWhenChanged pulls the repository of an edge stack and bumps the edge stack version when the repository changed.
+// It returns true when the edge stack was updated.
+func (service *AutoUpdateService) UpdateWhenChanged(edgeStackID portainer.EdgeStackID) (bool, error) {
+	log.Debug().Int("edge_stack_id", int(edgeStackID)).Msg("updating edge stack")
+
+	edgeStack, err := service.dataStore.EdgeStack().EdgeStack(edgeStackID)
+	if err != nil {
+		return false, errors.WithMessagef(err, "failed to get the edge stack %v", edgeStackID)
+	}
+
+	if edgeStack.GitConfig == nil {
+		return false, nil // do nothing if it isn't a git-based edge stack
+	}
+
+	// keep the files of the deployed version before pulling the new ones, the environments outside of a rollout are served them
+	deployedVersion := edgeStack.Version
+	if !HasFileVersion(edgeStack, deployedVersion) {
+		err := storeFileVersion(service.fileService, edgeStack)
+		if err != nil {
+			return false, errors.WithMessagef(err, "failed to retain the files of the edge stack %v", edgeStackID)
+		}
+	}
+
+	updated, newHash, err := update.UpdateGitObject(service.gitService, fmt.Sprintf("edge_stack:%d", edgeStackID), edgeStack.GitConfig, false, edgeStack.ProjectPath)
+	if err != nil {
+		metrics.CountGitPoll(metrics.GitPollError)
+		return false, err
+	}
+
+	if !updated {
+		metrics.CountGitPoll(metrics.GitPollUnchanged)
+		return false, nil
+	}
+
+	if edgeStack.UseTemplating {
+		err := ValidateTemplateFiles(service.fileService, edgeStack)
+		if err != nil {
+			metrics.CountGitPoll(metrics.GitPollError)
+
+			// the environments keep being served the files of the deployed version
+			restoreErr := restoreFileVersion(service.fileService, edgeStack, deployedVersion)
+			if restoreErr != nil {
+				log.Warn().Err(restoreErr).Int("edge_stack_id", int(edgeStackID)).Msg("unable to restore the files of the deployed edge stack version")
+			}
+
+			return false, errors.WithMessagef(err, "the repository of the edge stack %v is not a valid template", edgeStackID)
+		}
+	}
+
+	metrics.CountGitPoll(metrics.GitPollUpdated)
+
+	// the compose edge stacks deployed to kubernetes environments rely on a converted manifest
+	if edgeStack.DeploymentType == portainer.EdgeStackDeploymentCompose && edgeStack.ManifestPath != "" {
+		err := service.convertComposeFile(edgeStack)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	relationConfig, err := edge.FetchEndpointRelationsConfig(service.dataStore)
+	if err != nil {
+		return false, errors.WithMessage(err, "unable to retrieve the environments relations config from the database")
+	}
+
+	relatedEndpointIDs, err := edge.EdgeStackRelatedEndpoints(edgeStack.EdgeGroups, relationConfig.Endpoints, relationConfig.EndpointGroups, relationConfig.EdgeGroups)
+	if err != nil {
+		return false, errors.WithMessage(err, "unable to retrieve the edge stack related environments from the database")
+	}
+
+	err = service.dataStore.EdgeStack().UpdateEdgeStackFunc(edgeStackID, func(edgeStack *portainer.EdgeStack) {
+		if edgeStack.GitConfig != nil {
+			edgeStack.GitConfig.ConfigHash = newHash
+		}
+
+		if edgeStack.Version == deployedVersion {
+			addFileVersion(edgeStack, deployedVersion)
+		}
+
+		previousVersion := edgeStack.Version
+		previousVersions := EndpointVersions(edgeStack, relatedEndpointIDs)
+		edgeStack.Version++
+		edgeStack.Status = make(map[portainer.EndpointID]portainer.EdgeStackStatus)
+		edgeStack.RolledBackEndpoints = nil
+
+		StartRollout(edgeStack, previousVersion, previousVersions, relatedEndpointIDs)
+
+		err := RecordFileVersion(service.fileService, edgeStack)
+		if err != nil {
+			log.Warn().Err(err).Int("edge_stack_id", int(edgeStack.ID)).Msg("unable to retain the files of the edge stack version")
+		}
+	})
+	if err != nil {
+		return false, errors.WithMessagef(err, "failed to update the edge stack %v", edgeStackID)
+	}
+
+	return true, nil
+}
+
+func (service *AutoUpdateService) convertComposeFile(edgeStack *portainer.EdgeStack) error {
+	composeConfig, err := service.fileService.GetFileContent(edgeStack.ProjectPath, edgeStack.EntryPoint)
+	if err != nil {
+		return errors.WithMessage(err, "unable to retrieve the Compose file from disk")
+	}
+
+	kompose, err := service.kubernetesDeployer.ConvertCompose(composeConfig)
+	if err != nil {
+		return errors.WithMessage(err, "failed converting the Compose file to a kubernetes manifest")
+	}
+
+	_, err = service.fileService.StoreEdgeStackFileFromBytes(strconv.Itoa(int(edgeStack.ID)), edgeStack.ManifestPath, kompose)
+	if err != nil {
+		return errors.WithMessage(err, "failed to store the kubernetes manifest file")
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package edgestacks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/filesystem"
+	gittypes "github.com/portainer/portainer/api/git/types"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_UpdateWhenChanged(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, false)
+	defer teardown()
+
+	fileService, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+
+	projectPath, err := fileService.StoreEdgeStackFileFromBytes("1", "docker-compose.yml", []byte("version: 1"))
+	require.NoError(t, err)
+
+	edgeStack := &portainer.EdgeStack{
+		ID:             1,
+		Name:           "git-stack",
+		Version:        1,
+		DeploymentType: portainer.EdgeStackDeploymentCompose,
+		ProjectPath:    projectPath,
+		EntryPoint:     "docker-compose.yml",
+		Status: map[portainer.EndpointID]portainer.EdgeStackStatus{
+			1: {EndpointID: 1, Details: portainer.EdgeStackStatusDetails{Ok: true}},
+		},
+		GitConfig: &gittypes.RepoConfig{
+			URL:            "https://github.com/portainer/edge-stack",
+			ReferenceName:  "refs/heads/main",
+			ConfigFilePath: "docker-compose.yml",
+			ConfigHash:     "hash1",
+		},
+	}
+	require.NoError(t, store.EdgeStack().Create(edgeStack.ID, edgeStack))
+
+	require.NoError(t, store.EdgeStack().Create(2, &portainer.EdgeStack{ID: 2, Name: "file-stack", Version: 1}))
+
+	t.Run("should not update the edge stack when the repository did not change", func(t *testing.T) {
+		service := NewAutoUpdateService(store, fileService, testhelpers.NewGitService(nil, "hash1"), nil, nil)
+
+		updated, err := service.UpdateWhenChanged(edgeStack.ID)
+		require.NoError(t, err)
+		is.False(updated)
+
+		version, _ := store.EdgeStack().EdgeStackVersion(edgeStack.ID)
+		is.Equal(1, version)
+	})
+
+	t.Run("should bump the version when the repository changed", func(t *testing.T) {
+		service := NewAutoUpdateService(store, fileService, testhelpers.NewGitService(nil, "hash2"), nil, nil)
+
+		updated, err := service.UpdateWhenChanged(edgeStack.ID)
+		require.NoError(t, err)
+		is.True(updated)
+
+		updatedStack, err := store.EdgeStack().EdgeStack(edgeStack.ID)
+		require.NoError(t, err)
+		is.Equal(2, updatedStack.Version)
+		is.Equal("hash2", updatedStack.GitConfig.ConfigHash)
+		is.Empty(updatedStack.Status, "the statuses of the previous version should be cleared")
+		is.Equal([]int{1, 2}, updatedStack.FileVersions, "the files of the previous version should be retained before pulling the new ones")
+	})
+
+	t.Run("should ignore the edge stacks without a git config", func(t *testing.T) {
+		service := NewAutoUpdateService(store, fileService, testhelpers.NewGitService(nil, "hash3"), nil, nil)
+
+		updated, err := service.UpdateWhenChanged(2)
+		require.NoError(t, err)
+		is.False(updated)
+	})
+}
+
+// templateGitService pulls a repository holding the given Compose file
+type templateGitService struct {
+	portainer.GitService
+	content string
+}
+
+func (g *templateGitService) CloneRepository(destination, repositoryURL, referenceName, username, password string, tlsSkipVerify bool) error {
+	return os.WriteFile(filepath.Join(destination, "docker-compose.yml"), []byte(g.content), 0644)
+}
+
+func Test_UpdateWhenChanged_InvalidTemplate(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, false)
+	defer teardown()
+
+	fileService, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+
+	edgeStack := newVersionedEdgeStack(t, fileService, "image: app:{{ .Env.VERSION }}")
+	edgeStack.UseTemplating = true
+	edgeStack.GitConfig = &gittypes.RepoConfig{
+		URL:            "https://github.com/portainer/edge-stack",
+		ReferenceName:  "refs/heads/main",
+		ConfigFilePath: "docker-compose.yml",
+		ConfigHash:     "hash1",
+	}
+	require.NoError(t, store.EdgeStack().Create(edgeStack.ID, edgeStack))
+
+	gitService := &templateGitService{GitService: testhelpers.NewGitService(nil, "hash2"), content: "image: app:{{ .Env.VERSION"}
+	service := NewAutoUpdateService(store, fileService, gitService, nil, nil)
+
+	updated, err := service.UpdateWhenChanged(edgeStack.ID)
+	is.Error(err)
+	is.False(updated)
+
+	updatedStack, err := store.EdgeStack().EdgeStack(edgeStack.ID)
+	require.NoError(t, err)
+	is.Equal(1, updatedStack.Version, "the version should not be bumped for an invalid template")
+	is.Equal("hash1", updatedStack.GitConfig.ConfigHash)
+
+	content, err := fileService.GetFileContent(edgeStack.ProjectPath, edgeStack.EntryPoint)
+	require.NoError(t, err)
+	is.Equal("image: app:{{ .Env.VERSION }}", string(content), "the files of the deployed version should be restored")
+
+	gitService.content = "image: app:{{ .Env.VERSION }}-fixed"
+
+	updated, err = service.UpdateWhenChanged(edgeStack.ID)
+	require.NoError(t, err)
+	is.True(updated)
+}
@@ -0,0 +1,281 @@
+package edgestacks
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/rs/zerolog/log"
+)
+
+// MaxEdgeStackFileVersions is the number of versions whose files are retained for each edge stack,
+// the oldest versions which are not deployed anymore are removed first
+const MaxEdgeStackFileVersions = 10
+
+var (
+	// ErrFileVersionNotFound is returned when rolling an edge stack back to a version whose files are not retained
+	ErrFileVersionNotFound = errors.New("The files of the edge stack version are not retained")
+	// ErrNoFailedEndpoints is returned when rolling back the failed environments of an edge stack without any failure
+	ErrNoFailedEndpoints = errors.New("No environment reported an error for the edge stack")
+)
+
+// HasFileVersion returns true when the files of a version of an edge stack are retained
+func HasFileVersion(edgeStack *portainer.EdgeStack, version int) bool {
+	for _, v := range edgeStack.FileVersions {
+		if v == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PreviousFileVersion returns the most recent retained version of an edge stack preceding the given version
+func PreviousFileVersion(edgeStack *portainer.EdgeStack, version int) (int, bool) {
+	previousVersion := 0
+	for _, v := range edgeStack.FileVersions {
+		if v < version {
+			previousVersion = v
+		}
+	}
+
+	return previousVersion, previousVersion != 0
+}
+
+// RecordFileVersion retains the files currently stored in the project path of an edge stack as the files of its current version.
+// The files of the oldest versions are removed beyond MaxEdgeStackFileVersions.
+func RecordFileVersion(fileService portainer.FileService, edgeStack *portainer.EdgeStack) error {
+	err := storeFileVersion(fileService, edgeStack)
+	if err != nil {
+		return err
+	}
+
+	addFileVersion(edgeStack, edgeStack.Version)
+	pruneFileVersions(fileService, edgeStack)
+
+	return nil
+}
+
+// RetainFileVersion retains the files of the current version of an edge stack when they are not retained yet.
+// It must succeed before the files of the edge stack are overwritten, the environments outside of a rollout keep being served these files.
+func RetainFileVersion(fileService portainer.FileService, edgeStack *portainer.EdgeStack) error {
+	if HasFileVersion(edgeStack, edgeStack.Version) {
+		return nil
+	}
+
+	return RecordFileVersion(fileService, edgeStack)
+}
+
+// Rollback restores the files of a retained version of an edge stack and releases them to all the environments at once as a new version
+func Rollback(fileService portainer.FileService, edgeStack *portainer.EdgeStack, version int) error {
+	if version == edgeStack.Version || !HasFileVersion(edgeStack, version) {
+		return ErrFileVersionNotFound
+	}
+
+	// keep the files of the current version to be able to undo the rollback
+	err := RetainFileVersion(fileService, edgeStack)
+	if err != nil {
+		return err
+	}
+
+	err = restoreFileVersion(fileService, edgeStack, version)
+	if err != nil {
+		return err
+	}
+
+	edgeStack.Version++
+	edgeStack.Status = make(map[portainer.EndpointID]portainer.EdgeStackStatus)
+	edgeStack.Rollout = nil
+	edgeStack.RolledBackEndpoints = nil
+
+	return RecordFileVersion(fileService, edgeStack)
+}
+
+// RollbackFailedEndpoints rolls the environments reporting an error back to a retained version of an edge stack,
+// the other environments keep their version. It returns the identifiers of the environments rolled back.
+func RollbackFailedEndpoints(edgeStack *portainer.EdgeStack, version int) ([]portainer.EndpointID, error) {
+	if !HasFileVersion(edgeStack, version) {
+		return nil, ErrFileVersionNotFound
+	}
+
+	endpointIDs := []portainer.EndpointID{}
+	for endpointID, status := range edgeStack.Status {
+		if status.Details.Error {
+			endpointIDs = append(endpointIDs, endpointID)
+		}
+	}
+
+	if len(endpointIDs) == 0 {
+		return nil, ErrNoFailedEndpoints
+	}
+
+	sort.Slice(endpointIDs, func(i, j int) bool { return endpointIDs[i] < endpointIDs[j] })
+
+	for _, endpointID := range endpointIDs {
+		rollbackEndpoint(edgeStack, endpointID, version)
+
+		// the status is reported again once the environment deployed the version
+		delete(edgeStack.Status, endpointID)
+	}
+
+	return endpointIDs, nil
+}
+
+// AutoRollback rolls an environment reporting an error back to the version preceding the one deployed to it
+// when the automatic rollback is enabled. An environment is only rolled back automatically once per version.
+// It returns the version the environment was rolled back to.
+func AutoRollback(edgeStack *portainer.EdgeStack, endpointID portainer.EndpointID) (int, bool) {
+	if !edgeStack.AutoRollback {
+		return 0, false
+	}
+
+	if _, ok := edgeStack.RolledBackEndpoints[endpointID]; ok {
+		return 0, false
+	}
+
+	previousVersion, ok := PreviousFileVersion(edgeStack, EndpointVersion(edgeStack.Version, edgeStack.Rollout, endpointID))
+	if !ok {
+		return 0, false
+	}
+
+	rollbackEndpoint(edgeStack, endpointID, previousVersion)
+
+	return previousVersion, true
+}
+
+// EdgeStackEndpointVersion returns the version of an edge stack deployed to an environment,
+// taking the individual rollbacks and the staged rollout into account
+func EdgeStackEndpointVersion(edgeStack *portainer.EdgeStack, endpointID portainer.EndpointID) int {
+	if version, ok := edgeStack.RolledBackEndpoints[endpointID]; ok {
+		return version
+	}
+
+	return EndpointVersion(edgeStack.Version, edgeStack.Rollout, endpointID)
+}
+
+// EndpointProjectPath returns the folder holding the files of the version of an edge stack deployed to an environment.
+// It returns ErrFileVersionNotFound when the files of that version are not retained, the files of the current version are never served in their place.
+func EndpointProjectPath(fileService portainer.FileService, edgeStack *portainer.EdgeStack, endpointID portainer.EndpointID) (string, error) {
+	version := EdgeStackEndpointVersion(edgeStack, endpointID)
+	if version == edgeStack.Version {
+		return edgeStack.ProjectPath, nil
+	}
+
+	if !HasFileVersion(edgeStack, version) {
+		return "", ErrFileVersionNotFound
+	}
+
+	return fileService.GetEdgeStackProjectPathByVersion(strconv.Itoa(int(edgeStack.ID)), version), nil
+}
+
+// restoreFileVersion copies the retained files of a version of an edge stack to its project path
+func restoreFileVersion(fileService portainer.FileService, edgeStack *portainer.EdgeStack, version int) error {
+	stackFolder := strconv.Itoa(int(edgeStack.ID))
+	versionPath := fileService.GetEdgeStackProjectPathByVersion(stackFolder, version)
+
+	for _, fileName := range edgeStackFiles(edgeStack) {
+		content, err := fileService.GetFileContent(versionPath, fileName)
+		if err != nil {
+			return errors.WithMessagef(err, "unable to read the file %s of the version %d of the edge stack %d", fileName, version, edgeStack.ID)
+		}
+
+		_, err = fileService.StoreEdgeStackFileFromBytes(stackFolder, fileName, content)
+		if err != nil {
+			return errors.WithMessagef(err, "unable to restore the file %s of the edge stack %d", fileName, edgeStack.ID)
+		}
+	}
+
+	return nil
+}
+
+// storeFileVersion copies the files currently stored in the project path of an edge stack to the folder of its current version
+func storeFileVersion(fileService portainer.FileService, edgeStack *portainer.EdgeStack) error {
+	stackFolder := strconv.Itoa(int(edgeStack.ID))
+
+	for _, fileName := range edgeStackFiles(edgeStack) {
+		content, err := fileService.GetFileContent(edgeStack.ProjectPath, fileName)
+		if err != nil {
+			return errors.WithMessagef(err, "unable to read the file %s of the edge stack %d", fileName, edgeStack.ID)
+		}
+
+		_, err = fileService.StoreEdgeStackFileVersionFromBytes(stackFolder, edgeStack.Version, fileName, content)
+		if err != nil {
+			return errors.WithMessagef(err, "unable to retain the file %s of the version %d of the edge stack %d", fileName, edgeStack.Version, edgeStack.ID)
+		}
+	}
+
+	return nil
+}
+
+func addFileVersion(edgeStack *portainer.EdgeStack, version int) {
+	if HasFileVersion(edgeStack, version) {
+		return
+	}
+
+	edgeStack.FileVersions = append(edgeStack.FileVersions, version)
+	sort.Ints(edgeStack.FileVersions)
+}
+
+func rollbackEndpoint(edgeStack *portainer.EdgeStack, endpointID portainer.EndpointID, version int) {
+	if edgeStack.RolledBackEndpoints == nil {
+		edgeStack.RolledBackEndpoints = make(map[portainer.EndpointID]int)
+	}
+
+	edgeStack.RolledBackEndpoints[endpointID] = version
+}
+
+// pruneFileVersions removes the files of the oldest versions beyond MaxEdgeStackFileVersions,
+// the versions still deployed to some environments are kept
+func pruneFileVersions(fileService portainer.FileService, edgeStack *portainer.EdgeStack) {
+	excess := len(edgeStack.FileVersions) - MaxEdgeStackFileVersions
+	if excess <= 0 {
+		return
+	}
+
+	inUse := map[int]bool{edgeStack.Version: true}
+	if edgeStack.Rollout != nil && edgeStack.Rollout.Status != portainer.EdgeStackRolloutCompleted {
+		inUse[edgeStack.Rollout.PreviousVersion] = true
+
+		for _, version := range edgeStack.Rollout.PreviousVersions {
+			inUse[version] = true
+		}
+	}
+
+	for _, version := range edgeStack.RolledBackEndpoints {
+		inUse[version] = true
+	}
+
+	stackFolder := strconv.Itoa(int(edgeStack.ID))
+	retained := make([]int, 0, len(edgeStack.FileVersions))
+	for _, version := range edgeStack.FileVersions {
+		if excess == 0 || inUse[version] {
+			retained = append(retained, version)
+			continue
+		}
+
+		err := fileService.RemoveDirectory(fileService.GetEdgeStackProjectPathByVersion(stackFolder, version))
+		if err != nil {
+			log.Warn().Err(err).Int("edge_stack_id", int(edgeStack.ID)).Int("version", version).Msg("unable to remove the files of the edge stack version")
+		}
+
+		excess--
+	}
+
+	edgeStack.FileVersions = retained
+}
+
+// edgeStackFiles returns the files of an edge stack relative to its project path
+func edgeStackFiles(edgeStack *portainer.EdgeStack) []string {
+	files := []string{}
+	if edgeStack.EntryPoint != "" {
+		files = append(files, edgeStack.EntryPoint)
+	}
+
+	if edgeStack.ManifestPath != "" && edgeStack.ManifestPath != edgeStack.EntryPoint {
+		files = append(files, edgeStack.ManifestPath)
+	}
+
+	return files
+}
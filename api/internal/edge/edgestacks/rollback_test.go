@@ -0,0 +1,185 @@
+package edgestacks
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/filesystem"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newVersionedEdgeStack(t *testing.T, fileService portainer.FileService, content string) *portainer.EdgeStack {
+	t.Helper()
+
+	projectPath, err := fileService.StoreEdgeStackFileFromBytes("1", "docker-compose.yml", []byte(content))
+	require.NoError(t, err)
+
+	edgeStack := &portainer.EdgeStack{
+		ID:          1,
+		Version:     1,
+		ProjectPath: projectPath,
+		EntryPoint:  "docker-compose.yml",
+		Status:      map[portainer.EndpointID]portainer.EdgeStackStatus{},
+	}
+	require.NoError(t, RecordFileVersion(fileService, edgeStack))
+
+	return edgeStack
+}
+
+func updateVersionedEdgeStack(t *testing.T, fileService portainer.FileService, edgeStack *portainer.EdgeStack, content string) {
+	t.Helper()
+
+	_, err := fileService.StoreEdgeStackFileFromBytes("1", edgeStack.EntryPoint, []byte(content))
+	require.NoError(t, err)
+
+	edgeStack.Version++
+	require.NoError(t, RecordFileVersion(fileService, edgeStack))
+}
+
+func Test_RecordFileVersion(t *testing.T) {
+	is := assert.New(t)
+
+	fileService, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+
+	edgeStack := newVersionedEdgeStack(t, fileService, "version: 1")
+	updateVersionedEdgeStack(t, fileService, edgeStack, "version: 2")
+
+	is.Equal([]int{1, 2}, edgeStack.FileVersions)
+
+	content, err := fileService.GetFileContent(fileService.GetEdgeStackProjectPathByVersion("1", 1), "docker-compose.yml")
+	require.NoError(t, err)
+	is.Equal("version: 1", string(content))
+
+	t.Run("should remove the oldest versions which are not deployed anymore", func(t *testing.T) {
+		edgeStack.RolledBackEndpoints = map[portainer.EndpointID]int{5: 1}
+
+		for edgeStack.Version < MaxEdgeStackFileVersions+2 {
+			updateVersionedEdgeStack(t, fileService, edgeStack, "version: next")
+		}
+
+		is.Len(edgeStack.FileVersions, MaxEdgeStackFileVersions)
+		is.Equal(1, edgeStack.FileVersions[0], "the version deployed to a rolled back environment should be kept")
+		is.False(HasFileVersion(edgeStack, 2))
+
+		exists, err := fileService.FileExists(fileService.GetEdgeStackProjectPathByVersion("1", 2))
+		require.NoError(t, err)
+		is.False(exists)
+	})
+}
+
+func Test_Rollback(t *testing.T) {
+	is := assert.New(t)
+
+	fileService, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+
+	edgeStack := newVersionedEdgeStack(t, fileService, "version: 1")
+	updateVersionedEdgeStack(t, fileService, edgeStack, "version: 2")
+	edgeStack.Status[3] = portainer.EdgeStackStatus{Details: portainer.EdgeStackStatusDetails{Error: true}}
+
+	is.ErrorIs(Rollback(fileService, edgeStack, 7), ErrFileVersionNotFound)
+	is.ErrorIs(Rollback(fileService, edgeStack, 2), ErrFileVersionNotFound, "the current version should not be restored")
+
+	require.NoError(t, Rollback(fileService, edgeStack, 1))
+
+	is.Equal(3, edgeStack.Version, "the rollback should be released as a new version")
+	is.Empty(edgeStack.Status)
+	is.Equal([]int{1, 2, 3}, edgeStack.FileVersions)
+
+	content, err := fileService.GetFileContent(edgeStack.ProjectPath, "docker-compose.yml")
+	require.NoError(t, err)
+	is.Equal("version: 1", string(content))
+}
+
+func Test_RollbackFailedEndpoints(t *testing.T) {
+	is := assert.New(t)
+
+	fileService, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+
+	edgeStack := newVersionedEdgeStack(t, fileService, "version: 1")
+	updateVersionedEdgeStack(t, fileService, edgeStack, "version: 2")
+
+	_, err = RollbackFailedEndpoints(edgeStack, 1)
+	is.ErrorIs(err, ErrNoFailedEndpoints)
+
+	edgeStack.Status[3] = portainer.EdgeStackStatus{Details: portainer.EdgeStackStatusDetails{Error: true}}
+	edgeStack.Status[4] = portainer.EdgeStackStatus{Details: portainer.EdgeStackStatusDetails{Ok: true}}
+
+	endpointIDs, err := RollbackFailedEndpoints(edgeStack, 1)
+	require.NoError(t, err)
+	is.Equal([]portainer.EndpointID{3}, endpointIDs)
+
+	is.Equal(1, EdgeStackEndpointVersion(edgeStack, 3))
+	is.Equal(2, EdgeStackEndpointVersion(edgeStack, 4))
+	is.NotContains(edgeStack.Status, portainer.EndpointID(3))
+
+	projectPath, err := EndpointProjectPath(fileService, edgeStack, 3)
+	require.NoError(t, err)
+	is.Equal(fileService.GetEdgeStackProjectPathByVersion("1", 1), projectPath)
+
+	projectPath, err = EndpointProjectPath(fileService, edgeStack, 4)
+	require.NoError(t, err)
+	is.Equal(edgeStack.ProjectPath, projectPath)
+}
+
+func Test_EndpointProjectPath(t *testing.T) {
+	is := assert.New(t)
+
+	fileService, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+
+	edgeStack := newVersionedEdgeStack(t, fileService, "version: 1")
+	edgeStack.RolloutStrategy = &portainer.EdgeStackRolloutStrategy{BatchSize: 1}
+	updateVersionedEdgeStack(t, fileService, edgeStack, "version: 2")
+	StartRollout(edgeStack, 1, nil, []portainer.EndpointID{1, 2})
+	require.NotNil(t, edgeStack.Rollout)
+
+	projectPath, err := EndpointProjectPath(fileService, edgeStack, 2)
+	require.NoError(t, err)
+
+	content, err := fileService.GetFileContent(projectPath, edgeStack.EntryPoint)
+	require.NoError(t, err)
+	is.Equal("version: 1", string(content), "the environments outside of the rollout should get the files of the previous version")
+
+	t.Run("should not serve the current files when the previous version is not retained", func(t *testing.T) {
+		edgeStack.FileVersions = []int{2}
+
+		_, err := EndpointProjectPath(fileService, edgeStack, 2)
+		is.ErrorIs(err, ErrFileVersionNotFound)
+	})
+}
+
+func Test_AutoRollback(t *testing.T) {
+	is := assert.New(t)
+
+	fileService, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+
+	edgeStack := newVersionedEdgeStack(t, fileService, "version: 1")
+	updateVersionedEdgeStack(t, fileService, edgeStack, "version: 2")
+
+	_, ok := AutoRollback(edgeStack, 3)
+	is.False(ok, "the automatic rollback should be disabled by default")
+
+	edgeStack.AutoRollback = true
+
+	version, ok := AutoRollback(edgeStack, 3)
+	is.True(ok)
+	is.Equal(1, version)
+	is.Equal(1, EdgeStackEndpointVersion(edgeStack, 3))
+
+	_, ok = AutoRollback(edgeStack, 3)
+	is.False(ok, "an environment should only be rolled back once")
+
+	t.Run("should not roll back without a previous version", func(t *testing.T) {
+		edgeStack := newVersionedEdgeStack(t, fileService, "version: 1")
+		edgeStack.AutoRollback = true
+
+		_, ok := AutoRollback(edgeStack, 3)
+		is.False(ok)
+	})
+}
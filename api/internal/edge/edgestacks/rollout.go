@@ -0,0 +1,313 @@
+package edgestacks
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/scheduler"
+
+	"github.com/rs/zerolog/log"
+)
+
+// rolloutCheckInterval is the interval at which the progress of the rollouts is checked
+const rolloutCheckInterval = 30 * time.Second
+
+// ErrNoActiveRollout is returned when promoting or aborting an edge stack which is not being rolled out
+var ErrNoActiveRollout = errors.New("The edge stack is not being rolled out")
+
+// ValidateRolloutStrategy validates a rollout strategy, an empty batch size and percentage disables the staged rollouts
+func ValidateRolloutStrategy(strategy *portainer.EdgeStackRolloutStrategy) error {
+	if strategy == nil {
+		return nil
+	}
+
+	if strategy.BatchSize < 0 {
+		return errors.New("Invalid rollout batch size")
+	}
+
+	if strategy.BatchPercentage < 0 || strategy.BatchPercentage > 100 {
+		return errors.New("Invalid rollout batch percentage. Must be between 0 and 100")
+	}
+
+	if strategy.FailureThreshold < 0 || strategy.FailureThreshold > 100 {
+		return errors.New("Invalid rollout failure threshold. Must be between 0 and 100")
+	}
+
+	if strategy.PauseBetweenBatches != "" {
+		pause, err := time.ParseDuration(strategy.PauseBetweenBatches)
+		if err != nil || pause <= 0 {
+			return errors.New("Invalid pause between the rollout batches")
+		}
+	}
+
+	return nil
+}
+
+// SetRolloutStrategy updates the rollout strategy of an edge stack, a strategy without batch size and percentage removes it
+func SetRolloutStrategy(edgeStack *portainer.EdgeStack, strategy *portainer.EdgeStackRolloutStrategy) {
+	if strategy == nil {
+		return
+	}
+
+	if strategy.BatchSize == 0 && strategy.BatchPercentage == 0 {
+		edgeStack.RolloutStrategy = nil
+		return
+	}
+
+	edgeStack.RolloutStrategy = strategy
+}
+
+// StartRollout starts the staged release of the current version of an edge stack following its rollout strategy.
+// The environments which are not released yet keep the version they deployed before, previousVersions holds the versions
+// of the environments which differ from previousVersion, see EndpointVersions. The rollout is only started when their files are retained.
+func StartRollout(edgeStack *portainer.EdgeStack, previousVersion int, previousVersions map[portainer.EndpointID]int, relatedEndpointIDs []portainer.EndpointID) {
+	edgeStack.Rollout = nil
+
+	strategy := edgeStack.RolloutStrategy
+	if strategy == nil || previousVersion <= 0 || previousVersion == edgeStack.Version || len(relatedEndpointIDs) == 0 {
+		return
+	}
+
+	endpointPreviousVersions := make(map[portainer.EndpointID]int)
+	for _, endpointID := range relatedEndpointIDs {
+		if version, ok := previousVersions[endpointID]; ok && version > 0 && version != previousVersion {
+			endpointPreviousVersions[endpointID] = version
+		}
+	}
+
+	versions := []int{previousVersion}
+	for _, version := range endpointPreviousVersions {
+		versions = append(versions, version)
+	}
+
+	for _, version := range versions {
+		if !HasFileVersion(edgeStack, version) {
+			log.Warn().Int("edge_stack_id", int(edgeStack.ID)).Int("version", version).Msg("the files of a previous edge stack version are not retained, releasing the new version to all the environments")
+			return
+		}
+	}
+
+	if len(endpointPreviousVersions) == 0 {
+		endpointPreviousVersions = nil
+	}
+
+	endpointIDs := make([]portainer.EndpointID, len(relatedEndpointIDs))
+	copy(endpointIDs, relatedEndpointIDs)
+	sort.Slice(endpointIDs, func(i, j int) bool { return endpointIDs[i] < endpointIDs[j] })
+
+	batchSize := strategy.BatchSize
+	if batchSize == 0 {
+		batchSize = (len(endpointIDs)*strategy.BatchPercentage + 99) / 100
+	}
+
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	edgeStack.Rollout = &portainer.EdgeStackRollout{
+		Version:          edgeStack.Version,
+		PreviousVersion:  previousVersion,
+		PreviousVersions: endpointPreviousVersions,
+		Status:           portainer.EdgeStackRolloutInProgress,
+		Strategy:         *strategy,
+		Endpoints:        endpointIDs,
+		BatchSize:        batchSize,
+	}
+
+	releaseNextBatch(edgeStack.Rollout, time.Now())
+}
+
+// PromoteRollout releases the next batch of a rollout, a halted rollout is resumed
+func PromoteRollout(edgeStack *portainer.EdgeStack) error {
+	rollout := edgeStack.Rollout
+	if rollout == nil || (rollout.Status != portainer.EdgeStackRolloutInProgress && rollout.Status != portainer.EdgeStackRolloutHalted) {
+		return ErrNoActiveRollout
+	}
+
+	rollout.Status = portainer.EdgeStackRolloutInProgress
+	rollout.Message = ""
+
+	releaseNextBatch(rollout, time.Now())
+
+	return nil
+}
+
+// AbortRollout stops a rollout, the environments which are not released yet keep the previous version
+func AbortRollout(edgeStack *portainer.EdgeStack) error {
+	rollout := edgeStack.Rollout
+	if rollout == nil || (rollout.Status != portainer.EdgeStackRolloutInProgress && rollout.Status != portainer.EdgeStackRolloutHalted) {
+		return ErrNoActiveRollout
+	}
+
+	rollout.Status = portainer.EdgeStackRolloutAborted
+
+	return nil
+}
+
+// HaltRolloutOnFailures halts a rollout when the error rate of the released environments exceeds the failure threshold.
+// It returns true when the rollout was halted.
+func HaltRolloutOnFailures(edgeStack *portainer.EdgeStack) bool {
+	rollout := edgeStack.Rollout
+	if rollout == nil || rollout.Status != portainer.EdgeStackRolloutInProgress || rollout.Strategy.FailureThreshold == 0 || rollout.ReleasedCount == 0 {
+		return false
+	}
+
+	failed := 0
+	for _, endpointID := range rollout.Endpoints[:rollout.ReleasedCount] {
+		if edgeStack.Status[endpointID].Details.Error {
+			failed++
+		}
+	}
+
+	if failed*100 <= rollout.Strategy.FailureThreshold*rollout.ReleasedCount {
+		return false
+	}
+
+	rollout.Status = portainer.EdgeStackRolloutHalted
+	rollout.Message = fmt.Sprintf("%d of %d released environments failed to deploy the edge stack", failed, rollout.ReleasedCount)
+
+	return true
+}
+
+// EndpointVersions returns the versions of an edge stack deployed to the environments, it is used to carry
+// the versions of the environments which are not released yet to the rollout of the next version
+func EndpointVersions(edgeStack *portainer.EdgeStack, endpointIDs []portainer.EndpointID) map[portainer.EndpointID]int {
+	versions := make(map[portainer.EndpointID]int, len(endpointIDs))
+	for _, endpointID := range endpointIDs {
+		versions[endpointID] = EdgeStackEndpointVersion(edgeStack, endpointID)
+	}
+
+	return versions
+}
+
+// EndpointVersion returns the version of an edge stack advertised to an environment,
+// the environments which are not released yet by a rollout keep the previous version
+func EndpointVersion(version int, rollout *portainer.EdgeStackRollout, endpointID portainer.EndpointID) int {
+	if rollout == nil || rollout.Status == portainer.EdgeStackRolloutCompleted || rollout.Version != version {
+		return version
+	}
+
+	for i, id := range rollout.Endpoints {
+		if id != endpointID {
+			continue
+		}
+
+		if i < rollout.ReleasedCount {
+			return version
+		}
+
+		if previousVersion, ok := rollout.PreviousVersions[endpointID]; ok {
+			return previousVersion
+		}
+
+		return rollout.PreviousVersion
+	}
+
+	// the environments added during the rollout did not deploy the previous version
+	return version
+}
+
+func releaseNextBatch(rollout *portainer.EdgeStackRollout, now time.Time) {
+	rollout.ReleasedCount += rollout.BatchSize
+	if rollout.ReleasedCount >= len(rollout.Endpoints) {
+		rollout.ReleasedCount = len(rollout.Endpoints)
+		rollout.Status = portainer.EdgeStackRolloutCompleted
+	}
+
+	rollout.LastReleaseDate = now.Unix()
+}
+
+// rolloutDue returns true when the pause after the last released batch of a rollout is over
+func rolloutDue(rollout *portainer.EdgeStackRollout, now time.Time) bool {
+	if rollout.Status != portainer.EdgeStackRolloutInProgress || rollout.Strategy.PauseBetweenBatches == "" {
+		return false
+	}
+
+	pause, err := time.ParseDuration(rollout.Strategy.PauseBetweenBatches)
+	if err != nil {
+		return false
+	}
+
+	return !now.Before(time.Unix(rollout.LastReleaseDate, 0).Add(pause))
+}
+
+// RolloutService releases the batches of the edge stack rollouts and halts them when too many environments fail
+type RolloutService struct {
+	dataStore dataservices.DataStore
+	scheduler *scheduler.Scheduler
+	mu        sync.Mutex
+	jobID     string
+}
+
+// NewRolloutService returns a new instance of the edge stacks rollout service
+func NewRolloutService(dataStore dataservices.DataStore, scheduler *scheduler.Scheduler) *RolloutService {
+	return &RolloutService{
+		dataStore: dataStore,
+		scheduler: scheduler,
+	}
+}
+
+// Start schedules the progress checks of the rollouts
+func (service *RolloutService) Start() {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if service.jobID != "" {
+		return
+	}
+
+	service.jobID = service.scheduler.StartJobEvery(rolloutCheckInterval, func() error {
+		err := service.CheckRollouts(time.Now())
+		if err != nil {
+			log.Error().Err(err).Msg("unable to check the edge stack rollouts")
+		}
+
+		// the job is kept on errors to retry at the next interval
+		return nil
+	})
+}
+
+// CheckRollouts halts the failing rollouts and releases the next batch of the rollouts whose pause is over
+func (service *RolloutService) CheckRollouts(now time.Time) error {
+	edgeStacks, err := service.dataStore.EdgeStack().EdgeStacks()
+	if err != nil {
+		return errors.Wrap(err, "unable to retrieve the edge stacks")
+	}
+
+	for i := range edgeStacks {
+		edgeStack := &edgeStacks[i]
+		if edgeStack.Rollout == nil || edgeStack.Rollout.Status != portainer.EdgeStackRolloutInProgress {
+			continue
+		}
+
+		// the changes are only persisted when the rollout progresses
+		if !HaltRolloutOnFailures(edgeStack) && !rolloutDue(edgeStack.Rollout, now) {
+			continue
+		}
+
+		err := service.dataStore.EdgeStack().UpdateEdgeStackFunc(edgeStack.ID, func(edgeStack *portainer.EdgeStack) {
+			if edgeStack.Rollout == nil || edgeStack.Rollout.Status != portainer.EdgeStackRolloutInProgress {
+				return
+			}
+
+			if HaltRolloutOnFailures(edgeStack) {
+				log.Warn().Int("edge_stack_id", int(edgeStack.ID)).Str("reason", edgeStack.Rollout.Message).Msg("edge stack rollout halted")
+				return
+			}
+
+			if rolloutDue(edgeStack.Rollout, now) {
+				releaseNextBatch(edgeStack.Rollout, now)
+			}
+		})
+		if err != nil {
+			return errors.WithMessagef(err, "unable to update the rollout of the edge stack %d", edgeStack.ID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package edgestacks
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_StartRollout(t *testing.T) {
+	is := assert.New(t)
+
+	edgeStack := &portainer.EdgeStack{
+		Version:         3,
+		FileVersions:    []int{2},
+		RolloutStrategy: &portainer.EdgeStackRolloutStrategy{BatchPercentage: 30},
+	}
+
+	StartRollout(edgeStack, 2, nil, []portainer.EndpointID{5, 1, 4, 2, 3})
+	require.NotNil(t, edgeStack.Rollout)
+
+	rollout := edgeStack.Rollout
+	is.Equal([]portainer.EndpointID{1, 2, 3, 4, 5}, rollout.Endpoints)
+	is.Equal(2, rollout.BatchSize, "the batch size should be rounded up")
+	is.Equal(2, rollout.ReleasedCount, "the first batch should be released immediately")
+	is.Equal(portainer.EdgeStackRolloutInProgress, rollout.Status)
+
+	is.Equal(3, EndpointVersion(3, rollout, 2))
+	is.Equal(2, EndpointVersion(3, rollout, 3))
+	is.Equal(3, EndpointVersion(3, rollout, 9), "the environments outside of the rollout should get the current version")
+
+	require.NoError(t, PromoteRollout(edgeStack))
+	require.NoError(t, PromoteRollout(edgeStack))
+	is.Equal(5, rollout.ReleasedCount)
+	is.Equal(portainer.EdgeStackRolloutCompleted, rollout.Status)
+	is.Equal(3, EndpointVersion(3, rollout, 5))
+
+	is.ErrorIs(PromoteRollout(edgeStack), ErrNoActiveRollout)
+	is.ErrorIs(AbortRollout(edgeStack), ErrNoActiveRollout)
+
+	t.Run("should not start a rollout without strategy or previous version", func(t *testing.T) {
+		edgeStack := &portainer.EdgeStack{Version: 2}
+		StartRollout(edgeStack, 1, nil, []portainer.EndpointID{1})
+		is.Nil(edgeStack.Rollout)
+
+		edgeStack = &portainer.EdgeStack{Version: 1, RolloutStrategy: &portainer.EdgeStackRolloutStrategy{BatchSize: 1}}
+		StartRollout(edgeStack, 0, nil, []portainer.EndpointID{1})
+		is.Nil(edgeStack.Rollout)
+	})
+
+	t.Run("should not start a rollout when the files of the previous version are not retained", func(t *testing.T) {
+		edgeStack := &portainer.EdgeStack{Version: 2, RolloutStrategy: &portainer.EdgeStackRolloutStrategy{BatchSize: 1}}
+		StartRollout(edgeStack, 1, nil, []portainer.EndpointID{1, 2})
+		is.Nil(edgeStack.Rollout)
+	})
+}
+
+func Test_HaltRolloutOnFailures(t *testing.T) {
+	is := assert.New(t)
+
+	edgeStack := &portainer.EdgeStack{
+		Version:         2,
+		FileVersions:    []int{1},
+		RolloutStrategy: &portainer.EdgeStackRolloutStrategy{BatchSize: 4, FailureThreshold: 25},
+		Status: map[portainer.EndpointID]portainer.EdgeStackStatus{
+			1: {Details: portainer.EdgeStackStatusDetails{Error: true}},
+			5: {Details: portainer.EdgeStackStatusDetails{Error: true}},
+		},
+	}
+
+	StartRollout(edgeStack, 1, nil, []portainer.EndpointID{1, 2, 3, 4, 5, 6, 7, 8})
+
+	is.False(HaltRolloutOnFailures(edgeStack), "1 failure out of 4 is within the threshold")
+
+	edgeStack.Status[2] = portainer.EdgeStackStatus{Details: portainer.EdgeStackStatusDetails{Error: true}}
+	is.True(HaltRolloutOnFailures(edgeStack))
+	is.Equal(portainer.EdgeStackRolloutHalted, edgeStack.Rollout.Status)
+	is.Equal(1, EndpointVersion(2, edgeStack.Rollout, 6), "a halted rollout should not release more environments")
+
+	require.NoError(t, AbortRollout(edgeStack))
+	is.Equal(portainer.EdgeStackRolloutAborted, edgeStack.Rollout.Status)
+	is.Equal(1, EndpointVersion(2, edgeStack.Rollout, 6))
+}
+
+func Test_StartRollout_AfterAbort(t *testing.T) {
+	is := assert.New(t)
+
+	relatedEndpointIDs := []portainer.EndpointID{1, 2, 3, 4}
+
+	edgeStack := &portainer.EdgeStack{
+		Version:         1,
+		FileVersions:    []int{1},
+		RolloutStrategy: &portainer.EdgeStackRolloutStrategy{BatchSize: 2},
+	}
+
+	previousVersions := EndpointVersions(edgeStack, relatedEndpointIDs)
+	edgeStack.Version = 2
+
+	StartRollout(edgeStack, 1, previousVersions, relatedEndpointIDs)
+	require.NotNil(t, edgeStack.Rollout)
+	is.Nil(edgeStack.Rollout.PreviousVersions)
+	require.NoError(t, AbortRollout(edgeStack))
+
+	// the version 2 is retained when the version 3 is released
+	addFileVersion(edgeStack, 2)
+	previousVersions = EndpointVersions(edgeStack, relatedEndpointIDs)
+	edgeStack.Version = 3
+
+	StartRollout(edgeStack, 2, previousVersions, relatedEndpointIDs)
+	require.NotNil(t, edgeStack.Rollout)
+
+	rollout := edgeStack.Rollout
+	is.Equal(map[portainer.EndpointID]int{3: 1, 4: 1}, rollout.PreviousVersions)
+	is.Equal(3, EndpointVersion(3, rollout, 1))
+	is.Equal(1, EndpointVersion(3, rollout, 3), "the environments not released by the aborted rollout should keep their version")
+	is.Equal(1, EndpointVersion(3, rollout, 4))
+
+	require.NoError(t, PromoteRollout(edgeStack))
+	is.Equal(3, EndpointVersion(3, rollout, 4))
+
+	t.Run("should not start a rollout when the files of an environment version are not retained", func(t *testing.T) {
+		edgeStack := &portainer.EdgeStack{
+			Version:         3,
+			FileVersions:    []int{2},
+			RolloutStrategy: &portainer.EdgeStackRolloutStrategy{BatchSize: 1},
+		}
+
+		StartRollout(edgeStack, 2, map[portainer.EndpointID]int{2: 1}, []portainer.EndpointID{1, 2})
+		is.Nil(edgeStack.Rollout)
+	})
+}
+
+func Test_CheckRollouts(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, false)
+	defer teardown()
+
+	edgeStack := &portainer.EdgeStack{
+		ID:              1,
+		Name:            "staged",
+		Version:         2,
+		FileVersions:    []int{1},
+		Status:          map[portainer.EndpointID]portainer.EdgeStackStatus{},
+		RolloutStrategy: &portainer.EdgeStackRolloutStrategy{BatchSize: 1, PauseBetweenBatches: "10m"},
+	}
+	StartRollout(edgeStack, 1, nil, []portainer.EndpointID{1, 2, 3})
+	require.NoError(t, store.EdgeStack().Create(edgeStack.ID, edgeStack))
+
+	rollout, ok := store.EdgeStack().EdgeStackRollout(edgeStack.ID)
+	require.True(t, ok)
+	is.Equal(1, rollout.ReleasedCount)
+
+	service := NewRolloutService(store, nil)
+
+	require.NoError(t, service.CheckRollouts(time.Now().Add(5*time.Minute)))
+	rollout, _ = store.EdgeStack().EdgeStackRollout(edgeStack.ID)
+	is.Equal(1, rollout.ReleasedCount, "the next batch should wait for the end of the pause")
+
+	require.NoError(t, service.CheckRollouts(time.Now().Add(11*time.Minute)))
+	rollout, _ = store.EdgeStack().EdgeStackRollout(edgeStack.ID)
+	is.Equal(2, rollout.ReleasedCount)
+}
@@ -0,0 +1,299 @@
+package edgestacks
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+
+	"github.com/rs/zerolog/log"
+)
+
+// metadataKeyPattern restricts the metadata keys to identifiers so that they can be used as `{{ .Metadata.key }}`
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// TemplateData is the data available to the edge stack files rendered for an environment, e.g. `{{ .Endpoint.Name }}`
+type TemplateData struct {
+	Endpoint TemplateEndpoint
+	Group    TemplateGroup
+	// The environment variables of the edge stack
+	Env map[string]string
+	// The metadata of the group overridden by the metadata of the environment
+	Metadata map[string]string
+}
+
+// TemplateEndpoint describes the environment an edge stack file is rendered for
+type TemplateEndpoint struct {
+	ID     portainer.EndpointID
+	Name   string
+	EdgeID string
+	// The tags of the environment indexed by key, the tags named "key=value" or "key:value" are split
+	// and the value of the other tags is "true"
+	Tags     map[string]string
+	Metadata map[string]string
+}
+
+// TemplateGroup describes the group of the environment an edge stack file is rendered for
+type TemplateGroup struct {
+	ID       portainer.EndpointGroupID
+	Name     string
+	Tags     map[string]string
+	Metadata map[string]string
+}
+
+// ValidateTemplate validates the syntax of a templated edge stack file
+func ValidateTemplate(content []byte) error {
+	_, err := parseTemplate(content)
+	return err
+}
+
+// ValidateTemplateFiles validates the syntax of the files stored in the project path of a templated edge stack
+func ValidateTemplateFiles(fileService portainer.FileService, edgeStack *portainer.EdgeStack) error {
+	for _, fileName := range edgeStackFiles(edgeStack) {
+		content, err := fileService.GetFileContent(edgeStack.ProjectPath, fileName)
+		if err != nil {
+			return errors.WithMessagef(err, "unable to read the file %s of the edge stack %d", fileName, edgeStack.ID)
+		}
+
+		err = ValidateTemplate(content)
+		if err != nil {
+			return errors.WithMessagef(err, "invalid template in the file %s", fileName)
+		}
+	}
+
+	return nil
+}
+
+// EnvEqual returns true when two lists of environment variables of an edge stack are the same
+func EnvEqual(env, other []portainer.Pair) bool {
+	if len(env) != len(other) {
+		return false
+	}
+
+	for i := range env {
+		if env[i] != other[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MetadataEqual returns true when two metadata of environments or of environment groups are the same
+func MetadataEqual(metadata, other map[string]string) bool {
+	if len(metadata) != len(other) {
+		return false
+	}
+
+	for key, value := range metadata {
+		if otherValue, ok := other[key]; !ok || otherValue != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// BumpVersion releases the files of an edge stack as a new version so that the environments fetch it again,
+// it is used when the rendering of the files changes without the files themselves, e.g. when the environment
+// variables or the data of the templates change. A rollout in progress goes on with the new version.
+func BumpVersion(fileService portainer.FileService, edgeStack *portainer.EdgeStack) {
+	previousVersion := edgeStack.Version
+	edgeStack.Version++
+	edgeStack.Status = make(map[portainer.EndpointID]portainer.EdgeStackStatus)
+
+	if edgeStack.Rollout != nil && edgeStack.Rollout.Version == previousVersion {
+		edgeStack.Rollout.Version = edgeStack.Version
+	}
+
+	err := RecordFileVersion(fileService, edgeStack)
+	if err != nil {
+		log.Warn().Err(err).Int("edge_stack_id", int(edgeStack.ID)).Msg("unable to retain the files of the edge stack version")
+	}
+}
+
+// UpdateTemplatedEdgeStacks bumps the version of the templated edge stacks related to the given environments,
+// it is called when the environments, their group or their tags change so that the files are rendered again
+func UpdateTemplatedEdgeStacks(dataStore dataservices.DataStore, fileService portainer.FileService, endpointIDs []portainer.EndpointID) error {
+	edgeStackIDs := make(map[portainer.EdgeStackID]bool)
+	for _, endpointID := range endpointIDs {
+		relation, err := dataStore.EndpointRelation().EndpointRelation(endpointID)
+		if dataStore.IsErrObjectNotFound(err) {
+			continue
+		} else if err != nil {
+			return errors.WithMessage(err, "unable to retrieve the environment relation from the database")
+		}
+
+		for edgeStackID := range relation.EdgeStacks {
+			edgeStackIDs[edgeStackID] = true
+		}
+	}
+
+	for edgeStackID := range edgeStackIDs {
+		err := dataStore.EdgeStack().UpdateEdgeStackFunc(edgeStackID, func(edgeStack *portainer.EdgeStack) {
+			if edgeStack.UseTemplating {
+				BumpVersion(fileService, edgeStack)
+			}
+		})
+		if err != nil {
+			return errors.WithMessagef(err, "unable to update the edge stack %d", edgeStackID)
+		}
+	}
+
+	return nil
+}
+
+// ValidateEnv validates the environment variables of an edge stack
+func ValidateEnv(env []portainer.Pair) error {
+	names := make(map[string]bool, len(env))
+	for _, pair := range env {
+		if strings.TrimSpace(pair.Name) == "" {
+			return errors.New("Invalid environment variable name")
+		}
+
+		if names[pair.Name] {
+			return errors.Errorf("Duplicate environment variable %s", pair.Name)
+		}
+
+		names[pair.Name] = true
+	}
+
+	return nil
+}
+
+// ValidateMetadata validates the metadata of an environment or of an environment group,
+// the keys must be identifiers made of letters, digits and underscores
+func ValidateMetadata(metadata map[string]string) error {
+	for key := range metadata {
+		if !metadataKeyPattern.MatchString(key) {
+			return errors.Errorf("Invalid metadata key %q. Must only contain letters, digits and underscores", key)
+		}
+	}
+
+	return nil
+}
+
+// BuildTemplateData gathers the data used to render the files of an edge stack for an environment
+func BuildTemplateData(dataStore dataservices.DataStore, edgeStack *portainer.EdgeStack, endpoint *portainer.Endpoint) (*TemplateData, error) {
+	tags, err := dataStore.Tag().Tags()
+	if err != nil {
+		return nil, errors.WithMessage(err, "unable to retrieve the tags from the database")
+	}
+
+	tagNames := make(map[portainer.TagID]string, len(tags))
+	for _, tag := range tags {
+		tagNames[tag.ID] = tag.Name
+	}
+
+	data := &TemplateData{
+		Endpoint: TemplateEndpoint{
+			ID:       endpoint.ID,
+			Name:     endpoint.Name,
+			EdgeID:   endpoint.EdgeID,
+			Tags:     templateTags(endpoint.TagIDs, tagNames),
+			Metadata: copyMetadata(endpoint.Metadata),
+		},
+		Env:      make(map[string]string, len(edgeStack.Env)),
+		Metadata: make(map[string]string),
+	}
+
+	for _, pair := range edgeStack.Env {
+		data.Env[pair.Name] = pair.Value
+	}
+
+	group, err := dataStore.EndpointGroup().EndpointGroup(endpoint.GroupID)
+	if err != nil && !dataStore.IsErrObjectNotFound(err) {
+		return nil, errors.WithMessage(err, "unable to retrieve the environment group from the database")
+	}
+
+	if group != nil {
+		data.Group = TemplateGroup{
+			ID:       group.ID,
+			Name:     group.Name,
+			Tags:     templateTags(group.TagIDs, tagNames),
+			Metadata: copyMetadata(group.Metadata),
+		}
+	}
+
+	for key, value := range data.Group.Metadata {
+		data.Metadata[key] = value
+	}
+
+	for key, value := range data.Endpoint.Metadata {
+		data.Metadata[key] = value
+	}
+
+	return data, nil
+}
+
+// RenderFile renders a templated edge stack file, the missing keys are rendered as empty values
+func RenderFile(content []byte, data *TemplateData) ([]byte, error) {
+	tmpl, err := parseTemplate(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var rendered bytes.Buffer
+	err = tmpl.Execute(&rendered, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to render the edge stack file")
+	}
+
+	return rendered.Bytes(), nil
+}
+
+// RenderEnv renders the values of the environment variables of an edge stack
+func RenderEnv(env []portainer.Pair, data *TemplateData) ([]portainer.Pair, error) {
+	rendered := make([]portainer.Pair, 0, len(env))
+	for _, pair := range env {
+		value, err := RenderFile([]byte(pair.Value), data)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "unable to render the environment variable %s", pair.Name)
+		}
+
+		rendered = append(rendered, portainer.Pair{Name: pair.Name, Value: string(value)})
+	}
+
+	return rendered, nil
+}
+
+func parseTemplate(content []byte) (*template.Template, error) {
+	tmpl, err := template.New("edge-stack").Option("missingkey=zero").Parse(string(content))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid template")
+	}
+
+	return tmpl, nil
+}
+
+func templateTags(tagIDs []portainer.TagID, tagNames map[portainer.TagID]string) map[string]string {
+	tags := make(map[string]string, len(tagIDs))
+	for _, tagID := range tagIDs {
+		name, ok := tagNames[tagID]
+		if !ok {
+			continue
+		}
+
+		if i := strings.IndexAny(name, "=:"); i > 0 {
+			tags[strings.TrimSpace(name[:i])] = strings.TrimSpace(name[i+1:])
+			continue
+		}
+
+		tags[name] = "true"
+	}
+
+	return tags
+}
+
+func copyMetadata(metadata map[string]string) map[string]string {
+	result := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		result[key] = value
+	}
+
+	return result
+}
@@ -0,0 +1,79 @@
+package edgestacks
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/filesystem"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ValidateTemplating(t *testing.T) {
+	is := assert.New(t)
+
+	is.NoError(ValidateTemplate([]byte("image: app:{{ .Env.VERSION }}")))
+	is.Error(ValidateTemplate([]byte("image: app:{{ .Env.VERSION")))
+
+	is.NoError(ValidateEnv([]portainer.Pair{{Name: "A", Value: "1"}, {Name: "B"}}))
+	is.Error(ValidateEnv([]portainer.Pair{{Name: " ", Value: "1"}}))
+	is.Error(ValidateEnv([]portainer.Pair{{Name: "A"}, {Name: "A"}}))
+
+	is.NoError(ValidateMetadata(map[string]string{"site_code": "par", "_line2": "a"}))
+	is.Error(ValidateMetadata(map[string]string{"site-code": "par"}))
+	is.Error(ValidateMetadata(map[string]string{"": "par"}))
+}
+
+func Test_templateTags(t *testing.T) {
+	tagNames := map[portainer.TagID]string{1: "region=eu", 2: "tier: gold", 3: "prod"}
+
+	assert.Equal(t, map[string]string{"region": "eu", "tier": "gold", "prod": "true"}, templateTags([]portainer.TagID{1, 2, 3, 4}, tagNames))
+}
+
+func Test_UpdateTemplatedEdgeStacks(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, false)
+	defer teardown()
+
+	fileService, err := filesystem.NewService(t.TempDir(), "")
+	require.NoError(t, err)
+
+	templatedStack := newVersionedEdgeStack(t, fileService, "image: app:{{ .Metadata.version }}")
+	templatedStack.UseTemplating = true
+	templatedStack.Rollout = &portainer.EdgeStackRollout{Version: 1, PreviousVersion: 0, Status: portainer.EdgeStackRolloutInProgress}
+	require.NoError(t, store.EdgeStack().Create(templatedStack.ID, templatedStack))
+
+	require.NoError(t, store.EdgeStack().Create(2, &portainer.EdgeStack{ID: 2, Name: "plain", Version: 1}))
+
+	require.NoError(t, store.EndpointRelation().Create(&portainer.EndpointRelation{
+		EndpointID: 1,
+		EdgeStacks: map[portainer.EdgeStackID]bool{templatedStack.ID: true, 2: true},
+	}))
+
+	require.NoError(t, UpdateTemplatedEdgeStacks(store, fileService, []portainer.EndpointID{1, 2}))
+
+	updatedStack, err := store.EdgeStack().EdgeStack(templatedStack.ID)
+	require.NoError(t, err)
+	is.Equal(2, updatedStack.Version, "the templated edge stack should be released again")
+	is.Equal([]int{1, 2}, updatedStack.FileVersions)
+	is.Equal(2, updatedStack.Rollout.Version, "the rollout in progress should go on with the new version")
+
+	plainStack, err := store.EdgeStack().EdgeStack(2)
+	require.NoError(t, err)
+	is.Equal(1, plainStack.Version, "the edge stacks without templating should keep their version")
+}
+
+func Test_EnvEqual(t *testing.T) {
+	is := assert.New(t)
+
+	is.True(EnvEqual(nil, []portainer.Pair{}))
+	is.True(EnvEqual([]portainer.Pair{{Name: "A", Value: "1"}}, []portainer.Pair{{Name: "A", Value: "1"}}))
+	is.False(EnvEqual([]portainer.Pair{{Name: "A", Value: "1"}}, []portainer.Pair{{Name: "A", Value: "2"}}))
+
+	is.True(MetadataEqual(nil, map[string]string{}))
+	is.False(MetadataEqual(map[string]string{"site": "par"}, map[string]string{"site": "lyo"}))
+	is.False(MetadataEqual(map[string]string{"site": ""}, map[string]string{"region": ""}))
+}
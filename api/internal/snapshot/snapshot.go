@@ -4,12 +4,15 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"time"
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/agent"
 	"github.com/portainer/portainer/api/crypto"
 	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/metrics"
+	"github.com/portainer/portainer/api/notifications"
 
 	"github.com/rs/zerolog/log"
 )
@@ -23,11 +26,12 @@ type Service struct {
 	snapshotIntervalInSeconds float64
 	dockerSnapshotter         portainer.DockerSnapshotter
 	kubernetesSnapshotter     portainer.KubernetesSnapshotter
+	notificationService       *notifications.Service
 	shutdownCtx               context.Context
 }
 
 // NewService creates a new instance of a service
-func NewService(snapshotIntervalFromFlag string, dataStore dataservices.DataStore, dockerSnapshotter portainer.DockerSnapshotter, kubernetesSnapshotter portainer.KubernetesSnapshotter, shutdownCtx context.Context) (*Service, error) {
+func NewService(snapshotIntervalFromFlag string, dataStore dataservices.DataStore, dockerSnapshotter portainer.DockerSnapshotter, kubernetesSnapshotter portainer.KubernetesSnapshotter, notificationService *notifications.Service, shutdownCtx context.Context) (*Service, error) {
 	interval, err := parseSnapshotFrequency(snapshotIntervalFromFlag, dataStore)
 	if err != nil {
 		return nil, err
@@ -39,6 +43,7 @@ func NewService(snapshotIntervalFromFlag string, dataStore dataservices.DataStor
 		snapshotIntervalInSeconds: interval,
 		dockerSnapshotter:         dockerSnapshotter,
 		kubernetesSnapshotter:     kubernetesSnapshotter,
+		notificationService:       notificationService,
 		shutdownCtx:               shutdownCtx,
 	}, nil
 }
@@ -91,6 +96,15 @@ func SupportDirectSnapshot(endpoint *portainer.Endpoint) bool {
 // SnapshotEndpoint will create a snapshot of the environment(endpoint) based on the environment(endpoint) type.
 // If the snapshot is a success, it will be associated to the environment(endpoint).
 func (service *Service) SnapshotEndpoint(endpoint *portainer.Endpoint) error {
+	start := time.Now()
+
+	err := service.snapshotEndpoint(endpoint)
+	metrics.ObserveSnapshot(int(endpoint.ID), time.Since(start), err)
+
+	return err
+}
+
+func (service *Service) snapshotEndpoint(endpoint *portainer.Endpoint) error {
 	if endpoint.Type == portainer.AgentOnDockerEnvironment || endpoint.Type == portainer.AgentOnKubernetesEnvironment {
 		var err error
 		var tlsConfig *tls.Config
@@ -233,6 +247,8 @@ func (service *Service) snapshotEndpoints() error {
 			continue
 		}
 
+		previousStatus := latestEndpointReference.Status
+
 		latestEndpointReference.Status = portainer.EndpointStatusUp
 		if snapshotError != nil {
 			log.Debug().
@@ -241,6 +257,18 @@ func (service *Service) snapshotEndpoints() error {
 				Msg("background schedule error (environment snapshot), unable to create snapshot")
 
 			latestEndpointReference.Status = portainer.EndpointStatusDown
+
+			if previousStatus != portainer.EndpointStatusDown {
+				service.notificationService.Publish(notifications.Event{
+					Type:       portainer.NotificationEventEndpointDown,
+					Message:    fmt.Sprintf("environment %s is unreachable", endpoint.Name),
+					EndpointID: endpoint.ID,
+					Details: map[string]string{
+						"url":   endpoint.URL,
+						"error": snapshotError.Error(),
+					},
+				})
+			}
 		}
 
 		latestEndpointReference.Agent.Version = endpoint.Agent.Version
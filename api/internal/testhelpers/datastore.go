@@ -5,11 +5,13 @@ import (
 	"time"
 
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/database/boltdb"
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/dataservices/errors"
 )
 
 type testDatastore struct {
+	auditLog                dataservices.AuditLogService
 	customTemplate          dataservices.CustomTemplateService
 	edgeGroup               dataservices.EdgeGroupService
 	edgeJob                 dataservices.EdgeJobService
@@ -19,14 +21,18 @@ type testDatastore struct {
 	endpointRelation        dataservices.EndpointRelationService
 	fdoProfile              dataservices.FDOProfileService
 	helmUserRepository      dataservices.HelmUserRepositoryService
+	imageScan               dataservices.ImageScanService
+	notificationChannel     dataservices.NotificationChannelService
 	registry                dataservices.RegistryService
 	resourceControl         dataservices.ResourceControlService
 	apiKeyRepositoryService dataservices.APIKeyRepository
 	role                    dataservices.RoleService
+	session                 dataservices.SessionService
 	sslSettings             dataservices.SSLSettingsService
 	settings                dataservices.SettingsService
 	snapshot                dataservices.SnapshotService
 	stack                   dataservices.StackService
+	stackVersion            dataservices.StackVersionService
 	tag                     dataservices.TagService
 	teamMembership          dataservices.TeamMembershipService
 	team                    dataservices.TeamService
@@ -42,10 +48,12 @@ func (d *testDatastore) Init() error                                         { r
 func (d *testDatastore) Close() error                                        { return nil }
 func (d *testDatastore) UpdateTx(func(dataservices.DataStoreTx) error) error { return nil }
 func (d *testDatastore) ViewTx(func(dataservices.DataStoreTx) error) error   { return nil }
+func (d *testDatastore) Connection() portainer.Connection                    { return &boltdb.DbConnection{} }
 
 func (d *testDatastore) CheckCurrentEdition() error                         { return nil }
 func (d *testDatastore) MigrateData() error                                 { return nil }
 func (d *testDatastore) Rollback(force bool) error                          { return nil }
+func (d *testDatastore) AuditLog() dataservices.AuditLogService             { return d.auditLog }
 func (d *testDatastore) CustomTemplate() dataservices.CustomTemplateService { return d.customTemplate }
 func (d *testDatastore) EdgeGroup() dataservices.EdgeGroupService           { return d.edgeGroup }
 func (d *testDatastore) EdgeJob() dataservices.EdgeJobService               { return d.edgeJob }
@@ -62,6 +70,10 @@ func (d *testDatastore) EndpointRelation() dataservices.EndpointRelationService
 func (d *testDatastore) HelmUserRepository() dataservices.HelmUserRepositoryService {
 	return d.helmUserRepository
 }
+func (d *testDatastore) ImageScan() dataservices.ImageScanService { return d.imageScan }
+func (d *testDatastore) NotificationChannel() dataservices.NotificationChannelService {
+	return d.notificationChannel
+}
 func (d *testDatastore) Registry() dataservices.RegistryService { return d.registry }
 func (d *testDatastore) ResourceControl() dataservices.ResourceControlService {
 	return d.resourceControl
@@ -70,10 +82,12 @@ func (d *testDatastore) Role() dataservices.RoleService { return d.role }
 func (d *testDatastore) APIKeyRepository() dataservices.APIKeyRepository {
 	return d.apiKeyRepositoryService
 }
+func (d *testDatastore) Session() dataservices.SessionService               { return d.session }
 func (d *testDatastore) Settings() dataservices.SettingsService             { return d.settings }
 func (d *testDatastore) Snapshot() dataservices.SnapshotService             { return d.snapshot }
 func (d *testDatastore) SSLSettings() dataservices.SSLSettingsService       { return d.sslSettings }
 func (d *testDatastore) Stack() dataservices.StackService                   { return d.stack }
+func (d *testDatastore) StackVersion() dataservices.StackVersionService     { return d.stackVersion }
 func (d *testDatastore) Tag() dataservices.TagService                       { return d.tag }
 func (d *testDatastore) TeamMembership() dataservices.TeamMembershipService { return d.teamMembership }
 func (d *testDatastore) Team() dataservices.TeamService                     { return d.team }
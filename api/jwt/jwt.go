@@ -22,6 +22,7 @@ type Service struct {
 	secrets            map[scope][]byte
 	userSessionTimeout time.Duration
 	dataStore          dataservices.DataStore
+	sessionIdleTimeout time.Duration
 }
 
 type claims struct {
@@ -30,6 +31,7 @@ type claims struct {
 	Role                int    `json:"role"`
 	Scope               scope  `json:"scope"`
 	ForceChangePassword bool   `json:"forceChangePassword"`
+	SessionID           int    `json:"sid,omitempty"`
 	jwt.StandardClaims
 }
 
@@ -41,6 +43,9 @@ var (
 const (
 	defaultScope    = scope("default")
 	kubeConfigScope = scope("kubeconfig")
+
+	// sessionActivityInterval is the minimal interval between two updates of the last activity of a session
+	sessionActivityInterval = time.Minute
 )
 
 // NewService initializes a new service. It will generate a random key that will be used to sign JWT tokens.
@@ -61,12 +66,12 @@ func NewService(userSessionDuration string, dataStore dataservices.DataStore) (*
 	}
 
 	service := &Service{
-		map[scope][]byte{
+		secrets: map[scope][]byte{
 			defaultScope:    secret,
 			kubeConfigScope: kubeSecret,
 		},
-		userSessionTimeout,
-		dataStore,
+		userSessionTimeout: userSessionTimeout,
+		dataStore:          dataStore,
 	}
 	return service, nil
 }
@@ -111,6 +116,102 @@ func (service *Service) GenerateTokenForOAuth(data *portainer.TokenData, expiryT
 	return service.generateSignedToken(data, expireAt, defaultScope)
 }
 
+// GenerateSessionToken creates a session for the authenticated client and generates a new JWT token referencing it.
+// The token is rejected once the session is revoked or idle for longer than the session idle timeout.
+func (service *Service) GenerateSessionToken(data *portainer.TokenData, ipAddress, userAgent string) (string, error) {
+	now := time.Now()
+	expiresAt := service.defaultExpireAt()
+
+	service.pruneSessions(data.ID, now)
+
+	session := &portainer.Session{
+		UserID:     data.ID,
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+		CreatedAt:  now.Unix(),
+		LastSeenAt: now.Unix(),
+		ExpiresAt:  expiresAt,
+	}
+
+	if _, ok := os.LookupEnv("DOCKER_EXTENSION"); ok {
+		// the token of the docker desktop extension does not expire
+		session.ExpiresAt = 0
+	}
+
+	err := service.dataStore.Session().Create(session)
+	if err != nil {
+		return "", err
+	}
+
+	data.SessionID = session.ID
+
+	return service.generateSignedToken(data, expiresAt, defaultScope)
+}
+
+// IsSessionActive returns false when a session is expired or idle for longer than the session idle timeout
+func (service *Service) IsSessionActive(session *portainer.Session, now time.Time) bool {
+	if session.ExpiresAt != 0 && session.ExpiresAt < now.Unix() {
+		return false
+	}
+
+	idleTimeout := service.sessionIdleTimeout
+	return idleTimeout == 0 || now.Sub(time.Unix(session.LastSeenAt, 0)) <= idleTimeout
+}
+
+// pruneSessions removes the expired and idle sessions of a user
+func (service *Service) pruneSessions(userID portainer.UserID, now time.Time) {
+	sessions, err := service.dataStore.Session().SessionsByUserID(userID)
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to retrieve the sessions to prune")
+		return
+	}
+
+	for i := range sessions {
+		if service.IsSessionActive(&sessions[i], now) {
+			continue
+		}
+
+		err := service.dataStore.Session().DeleteSession(sessions[i].ID)
+		if err != nil {
+			log.Warn().Err(err).Int("session_id", int(sessions[i].ID)).Msg("unable to remove the inactive session")
+		}
+	}
+}
+
+// verifySession returns an error when the session referenced by a token is revoked, expired or idle,
+// and records the activity of the session otherwise
+func (service *Service) verifySession(cl *claims) error {
+	session, err := service.dataStore.Session().Session(portainer.SessionID(cl.SessionID))
+	if err != nil {
+		return errInvalidJWTToken
+	}
+
+	now := time.Now()
+	if session.UserID != portainer.UserID(cl.UserID) {
+		return errInvalidJWTToken
+	}
+
+	if !service.IsSessionActive(session, now) {
+		err := service.dataStore.Session().DeleteSession(session.ID)
+		if err != nil {
+			log.Warn().Err(err).Int("session_id", int(session.ID)).Msg("unable to remove the inactive session")
+		}
+
+		return errInvalidJWTToken
+	}
+
+	if now.Sub(time.Unix(session.LastSeenAt, 0)) >= sessionActivityInterval {
+		session.LastSeenAt = now.Unix()
+
+		err := service.dataStore.Session().UpdateSession(session.ID, session)
+		if err != nil {
+			log.Warn().Err(err).Int("session_id", int(session.ID)).Msg("unable to record the session activity")
+		}
+	}
+
+	return nil
+}
+
 // ParseAndVerifyToken parses a JWT token and verify its validity. It returns an error if token is invalid.
 func (service *Service) ParseAndVerifyToken(token string) (*portainer.TokenData, error) {
 	scope := parseScope(token)
@@ -134,10 +235,21 @@ func (service *Service) ParseAndVerifyToken(token string) (*portainer.TokenData,
 				return nil, errInvalidJWTToken
 			}
 
+			// the kubeconfig tokens are issued without a session and are only bound to their expiry,
+			// the bouncer restricts them to the Kubernetes API proxy
+			if cl.SessionID != 0 {
+				err := service.verifySession(cl)
+				if err != nil {
+					return nil, err
+				}
+			}
+
 			return &portainer.TokenData{
-				ID:       portainer.UserID(cl.UserID),
-				Username: cl.Username,
-				Role:     portainer.UserRole(cl.Role),
+				ID:         portainer.UserID(cl.UserID),
+				Username:   cl.Username,
+				Role:       portainer.UserRole(cl.Role),
+				SessionID:  portainer.SessionID(cl.SessionID),
+				Kubeconfig: cl.Scope == kubeConfigScope,
 			}, nil
 		}
 	}
@@ -163,6 +275,11 @@ func (service *Service) SetUserSessionDuration(userSessionDuration time.Duration
 	service.userSessionTimeout = userSessionDuration
 }
 
+// SetSessionIdleTimeout sets the duration of inactivity after which a session is revoked, 0 disables the idle timeout
+func (service *Service) SetSessionIdleTimeout(sessionIdleTimeout time.Duration) {
+	service.sessionIdleTimeout = sessionIdleTimeout
+}
+
 func (service *Service) generateSignedToken(data *portainer.TokenData, expiresAt int64, scope scope) (string, error) {
 	secret, found := service.secrets[scope]
 	if !found {
@@ -181,6 +298,7 @@ func (service *Service) generateSignedToken(data *portainer.TokenData, expiresAt
 		Role:                int(data.Role),
 		Scope:               scope,
 		ForceChangePassword: data.ForceChangePassword,
+		SessionID:           int(data.SessionID),
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: expiresAt,
 			IssuedAt:  time.Now().Unix(),
@@ -23,5 +23,9 @@ func (service *Service) GenerateTokenForKubeconfig(data *portainer.TokenData) (s
 		expiryAt = 0
 	}
 
-	return service.generateSignedToken(data, expiryAt, kubeConfigScope)
+	// the kubeconfig outlives the session it was generated from, the token is only accepted by the Kubernetes API proxy
+	kubeconfigData := *data
+	kubeconfigData.SessionID = 0
+
+	return service.generateSignedToken(&kubeconfigData, expiryAt, kubeConfigScope)
 }
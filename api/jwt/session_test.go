@@ -0,0 +1,93 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSessionToken(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	user := &portainer.User{ID: 2, Username: "bob", Role: portainer.StandardUserRole}
+	require.NoError(t, store.User().Create(user))
+
+	svc, err := NewService("1h", store)
+	require.NoError(t, err)
+
+	tokenData := &portainer.TokenData{ID: user.ID, Username: user.Username, Role: user.Role}
+	token, err := svc.GenerateSessionToken(tokenData, "10.0.0.1", "curl/7.88")
+	require.NoError(t, err)
+	is.NotZero(tokenData.SessionID)
+
+	t.Run("should record the session", func(t *testing.T) {
+		session, err := store.Session().Session(tokenData.SessionID)
+		require.NoError(t, err)
+		is.Equal(user.ID, session.UserID)
+		is.Equal("10.0.0.1", session.IPAddress)
+		is.Equal("curl/7.88", session.UserAgent)
+
+		parsed, err := svc.ParseAndVerifyToken(token)
+		require.NoError(t, err)
+		is.Equal(tokenData.SessionID, parsed.SessionID)
+	})
+
+	t.Run("should reject the token of an idle session", func(t *testing.T) {
+		session, err := store.Session().Session(tokenData.SessionID)
+		require.NoError(t, err)
+
+		session.LastSeenAt = time.Now().Add(-time.Hour).Unix()
+		require.NoError(t, store.Session().UpdateSession(session.ID, session))
+
+		svc.SetSessionIdleTimeout(30 * time.Minute)
+		defer svc.SetSessionIdleTimeout(0)
+
+		_, err = svc.ParseAndVerifyToken(token)
+		is.ErrorIs(err, errInvalidJWTToken)
+
+		_, err = store.Session().Session(tokenData.SessionID)
+		is.True(store.IsErrObjectNotFound(err), "the idle session should be removed")
+	})
+
+	t.Run("should reject the token of a revoked session", func(t *testing.T) {
+		token, err := svc.GenerateSessionToken(tokenData, "10.0.0.1", "curl/7.88")
+		require.NoError(t, err)
+
+		_, err = svc.ParseAndVerifyToken(token)
+		require.NoError(t, err)
+
+		require.NoError(t, store.Session().DeleteSession(tokenData.SessionID))
+
+		_, err = svc.ParseAndVerifyToken(token)
+		is.ErrorIs(err, errInvalidJWTToken)
+	})
+
+	t.Run("should accept the tokens issued without a session", func(t *testing.T) {
+		token, err := svc.GenerateToken(&portainer.TokenData{ID: user.ID, Username: user.Username, Role: user.Role})
+		require.NoError(t, err)
+
+		parsed, err := svc.ParseAndVerifyToken(token)
+		require.NoError(t, err)
+		is.Zero(parsed.SessionID)
+	})
+}
+
+func TestIsSessionActive(t *testing.T) {
+	svc := &Service{}
+	now := time.Now()
+
+	is := assert.New(t)
+	is.True(svc.IsSessionActive(&portainer.Session{LastSeenAt: now.Add(-24 * time.Hour).Unix()}, now), "sessions without expiry should not expire")
+	is.False(svc.IsSessionActive(&portainer.Session{ExpiresAt: now.Add(-time.Second).Unix(), LastSeenAt: now.Unix()}, now))
+
+	svc.SetSessionIdleTimeout(10 * time.Minute)
+	is.True(svc.IsSessionActive(&portainer.Session{LastSeenAt: now.Add(-5 * time.Minute).Unix()}, now))
+	is.False(svc.IsSessionActive(&portainer.Session{LastSeenAt: now.Add(-15 * time.Minute).Unix()}, now))
+}
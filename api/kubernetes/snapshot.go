@@ -51,6 +51,11 @@ func snapshot(cli *kubernetes.Clientset, endpoint *portainer.Endpoint) (*portain
 		log.Warn().Str("endpoint", endpoint.Name).Err(err).Msg("unable to snapshot cluster nodes")
 	}
 
+	err = snapshotNamespaces(snapshot, cli)
+	if err != nil {
+		log.Warn().Str("endpoint", endpoint.Name).Err(err).Msg("unable to snapshot cluster namespaces")
+	}
+
 	snapshot.Time = time.Now().Unix()
 	return snapshot, nil
 }
@@ -82,3 +87,17 @@ func snapshotNodes(snapshot *portainer.KubernetesSnapshot, cli *kubernetes.Clien
 	snapshot.NodeCount = len(nodeList.Items)
 	return nil
 }
+
+func snapshotNamespaces(snapshot *portainer.KubernetesSnapshot, cli *kubernetes.Clientset) error {
+	namespaceList, err := cli.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	snapshot.Namespaces = make([]string, 0, len(namespaceList.Items))
+	for _, namespace := range namespaceList.Items {
+		snapshot.Namespaces = append(snapshot.Namespaces, namespace.Name)
+	}
+
+	return nil
+}
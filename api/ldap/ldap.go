@@ -126,9 +126,29 @@ func (*Service) SearchUsers(settings *portainer.LDAPSettings) ([]string, error)
 		}
 	}
 
+	userDNs, err := searchUserDNs(connection, settings.SearchSettings)
+	if err != nil {
+		return nil, err
+	}
+
 	users := map[string]bool{}
+	for _, username := range userDNs {
+		users[username] = true
+	}
 
-	for _, searchSettings := range settings.SearchSettings {
+	usersList := []string{}
+	for user := range users {
+		usersList = append(usersList, user)
+	}
+
+	return usersList, nil
+}
+
+// searchUserDNs returns the usernames of the users found with the search settings indexed by their lowercased DN
+func searchUserDNs(connection *ldap.Conn, settings []portainer.LDAPSearchSettings) (map[string]string, error) {
+	users := map[string]string{}
+
+	for _, searchSettings := range settings {
 		searchRequest := ldap.NewSearchRequest(
 			searchSettings.BaseDN,
 			ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
@@ -145,20 +165,17 @@ func (*Service) SearchUsers(settings *portainer.LDAPSettings) ([]string, error)
 		for _, user := range sr.Entries {
 			username := user.GetAttributeValue(searchSettings.UserNameAttribute)
 			if username != "" {
-				users[username] = true
+				users[strings.ToLower(user.DN)] = username
 			}
 		}
 	}
 
-	usersList := []string{}
-	for user := range users {
-		usersList = append(usersList, user)
-	}
-
-	return usersList, nil
+	return users, nil
 }
 
-// SearchGroups searches for groups with the specified settings
+// SearchGroups searches for groups with the specified settings.
+// The group members are identified by their username when their DN matches a user of the search settings,
+// and by the value of the group attribute otherwise.
 func (*Service) SearchGroups(settings *portainer.LDAPSettings) ([]portainer.LDAPUser, error) {
 	type groupSet map[string]bool
 
@@ -175,6 +192,11 @@ func (*Service) SearchGroups(settings *portainer.LDAPSettings) ([]portainer.LDAP
 		}
 	}
 
+	userDNs, err := searchUserDNs(connection, settings.SearchSettings)
+	if err != nil {
+		return nil, err
+	}
+
 	userGroups := map[string]groupSet{}
 
 	for _, searchSettings := range settings.GroupSearchSettings {
@@ -193,8 +215,13 @@ func (*Service) SearchGroups(settings *portainer.LDAPSettings) ([]portainer.LDAP
 
 		for _, entry := range sr.Entries {
 			members := entry.GetAttributeValues(searchSettings.GroupAttribute)
-			for _, username := range members {
-				_, ok := userGroups[username]
+			for _, member := range members {
+				username, ok := userDNs[strings.ToLower(member)]
+				if !ok {
+					username = member
+				}
+
+				_, ok = userGroups[username]
 				if !ok {
 					userGroups[username] = groupSet{}
 				}
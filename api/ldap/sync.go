@@ -0,0 +1,492 @@
+package ldap
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/scheduler"
+	"github.com/rs/zerolog/log"
+)
+
+// maxSyncReports is the number of synchronization reports kept in memory
+const maxSyncReports = 10
+
+// ErrSyncNotAvailable is returned when a synchronization is requested while the LDAP authentication is not enabled
+var ErrSyncNotAvailable = errors.New("LDAP authentication is not enabled")
+
+type (
+	// SyncService synchronizes the Portainer users and the memberships of the teams matching a LDAP group
+	// with the LDAP directory, on demand or on a schedule
+	SyncService struct {
+		dataStore     dataservices.DataStore
+		ldapService   portainer.LDAPService
+		apiKeyService apikey.APIKeyService
+		scheduler     *scheduler.Scheduler
+
+		// syncMu prevents concurrent synchronizations
+		syncMu sync.Mutex
+
+		mu      sync.Mutex
+		jobID   string
+		reports []SyncReport
+	}
+
+	// SyncReport describes the changes applied by a synchronization, or the ones that would be applied in dry-run mode
+	SyncReport struct {
+		// Unix timestamp of the start of the synchronization
+		StartedAt int64 `json:"startedAt" example:"1672531200"`
+		// Unix timestamp of the end of the synchronization
+		FinishedAt int64 `json:"finishedAt" example:"1672531201"`
+		// Whether the changes were only computed and not applied
+		DryRun bool `json:"dryRun" example:"false"`
+		// Error that interrupted the synchronization
+		Error string `json:"error,omitempty"`
+		// Users created from the directory
+		CreatedUsers []string `json:"createdUsers"`
+		// Users enabled again as they were found back in the directory
+		EnabledUsers []string `json:"enabledUsers"`
+		// Users disabled as they are no longer found in the directory
+		DisabledUsers []string `json:"disabledUsers"`
+		// Users removed as they are no longer found in the directory
+		RemovedUsers []string `json:"removedUsers"`
+		// Administrators no longer found in the directory, they are never deprovisioned
+		SkippedUsers []string `json:"skippedUsers"`
+		// Team memberships created from the directory groups
+		AddedMemberships []SyncMembership `json:"addedMemberships"`
+		// Team memberships removed as the user is no longer a member of the directory group
+		RemovedMemberships []SyncMembership `json:"removedMemberships"`
+	}
+
+	// SyncMembership represents a team membership changed by a synchronization
+	SyncMembership struct {
+		Username string `json:"username" example:"bob"`
+		Team     string `json:"team" example:"developers"`
+	}
+)
+
+// NewSyncService initializes a new LDAP synchronization service
+func NewSyncService(dataStore dataservices.DataStore, ldapService portainer.LDAPService, apiKeyService apikey.APIKeyService, scheduler *scheduler.Scheduler) *SyncService {
+	return &SyncService{
+		dataStore:     dataStore,
+		ldapService:   ldapService,
+		apiKeyService: apiKeyService,
+		scheduler:     scheduler,
+	}
+}
+
+// ValidateSyncSettings returns an error when the synchronization settings of the LDAP settings cannot be applied
+func ValidateSyncSettings(settings portainer.LDAPSettings) error {
+	switch settings.SyncDeprovisionAction {
+	case portainer.LDAPDeprovisionNone, portainer.LDAPDeprovisionDisable, portainer.LDAPDeprovisionRemove:
+	default:
+		return errors.New("Invalid deprovision action. Value must be one of: \"\" (none), disable or remove")
+	}
+
+	if settings.SyncInterval == "" {
+		return nil
+	}
+
+	interval, err := time.ParseDuration(settings.SyncInterval)
+	if err != nil {
+		return errors.Wrap(err, "Invalid synchronization interval")
+	}
+
+	if interval < 5*time.Minute {
+		return errors.New("Invalid synchronization interval, the directory cannot be synchronized more than once every 5 minutes")
+	}
+
+	return nil
+}
+
+// Start schedules the synchronization with the interval stored in the settings
+func (service *SyncService) Start() error {
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	return service.Reschedule(settings.LDAPSettings.SyncInterval)
+}
+
+// Reschedule replaces the current synchronization schedule with the given interval,
+// the synchronization is no longer scheduled when the interval is empty
+func (service *SyncService) Reschedule(syncInterval string) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if service.jobID != "" {
+		err := service.scheduler.StopJob(service.jobID)
+		if err != nil {
+			return err
+		}
+
+		service.jobID = ""
+	}
+
+	if syncInterval == "" {
+		return nil
+	}
+
+	interval, err := time.ParseDuration(syncInterval)
+	if err != nil {
+		return errors.Wrapf(err, "invalid synchronization interval %q", syncInterval)
+	}
+
+	service.jobID = service.scheduler.StartJobEvery(interval, func() error {
+		_, err := service.Sync(false)
+		if err != nil && !errors.Is(err, ErrSyncNotAvailable) {
+			log.Error().Err(err).Msg("unable to synchronize the users with the LDAP directory")
+		}
+
+		// the job must keep running even if the directory could not be reached
+		return nil
+	})
+
+	return nil
+}
+
+// Reports returns the reports of the latest synchronizations, the most recent first
+func (service *SyncService) Reports() []SyncReport {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	reports := make([]SyncReport, len(service.reports))
+	for i, report := range service.reports {
+		reports[len(service.reports)-1-i] = report
+	}
+
+	return reports
+}
+
+func (service *SyncService) recordReport(report SyncReport) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	service.reports = append(service.reports, report)
+	if len(service.reports) > maxSyncReports {
+		service.reports = service.reports[len(service.reports)-maxSyncReports:]
+	}
+}
+
+// Sync synchronizes the Portainer users with the users of the LDAP directory:
+//   - the users of the directory are created when the automatic user provisioning is enabled
+//   - the users that are no longer found in the directory are disabled or removed depending on the deprovision action
+//   - the memberships of the teams named after a directory group are reconciled with the members of the group
+//
+// Only the users provisioned from the directory are synchronized, the deprovisioning is skipped when
+// the directory returns no user at all.
+// When dryRun is true, the report is computed but the changes are not applied.
+func (service *SyncService) Sync(dryRun bool) (*SyncReport, error) {
+	service.syncMu.Lock()
+	defer service.syncMu.Unlock()
+
+	report := &SyncReport{
+		StartedAt:          time.Now().Unix(),
+		DryRun:             dryRun,
+		CreatedUsers:       []string{},
+		EnabledUsers:       []string{},
+		DisabledUsers:      []string{},
+		RemovedUsers:       []string{},
+		SkippedUsers:       []string{},
+		AddedMemberships:   []SyncMembership{},
+		RemovedMemberships: []SyncMembership{},
+	}
+
+	err := service.sync(report)
+	if errors.Is(err, ErrSyncNotAvailable) {
+		return nil, err
+	}
+
+	report.FinishedAt = time.Now().Unix()
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	service.recordReport(*report)
+
+	return report, err
+}
+
+func (service *SyncService) sync(report *SyncReport) error {
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return errors.Wrap(err, "unable to retrieve the settings")
+	}
+
+	if settings.AuthenticationMethod != portainer.AuthenticationLDAP {
+		return ErrSyncNotAvailable
+	}
+
+	ldapSettings := &settings.LDAPSettings
+
+	directoryUsers, err := service.ldapService.SearchUsers(ldapSettings)
+	if err != nil {
+		return errors.Wrap(err, "unable to search the users of the directory")
+	}
+	sort.Strings(directoryUsers)
+
+	if len(directoryUsers) == 0 {
+		log.Warn().Msg("the LDAP directory returned no user, the users are not deprovisioned")
+	}
+
+	// groups of the directory users and names of the directory groups, lowercased
+	userGroups := map[string]map[string]bool{}
+	directoryGroups := map[string]bool{}
+
+	syncTeams := len(ldapSettings.GroupSearchSettings) > 0 && len(ldapSettings.GroupSearchSettings[0].GroupBaseDN) > 0
+	if syncTeams {
+		groupMembers, err := service.ldapService.SearchGroups(ldapSettings)
+		if err != nil {
+			return errors.Wrap(err, "unable to search the groups of the directory")
+		}
+
+		for _, member := range groupMembers {
+			groups := map[string]bool{}
+			for _, group := range member.Groups {
+				groups[strings.ToLower(group)] = true
+				directoryGroups[strings.ToLower(group)] = true
+			}
+
+			userGroups[strings.ToLower(member.Name)] = groups
+		}
+	}
+
+	users, err := service.dataStore.User().Users()
+	if err != nil {
+		return errors.Wrap(err, "unable to retrieve the users")
+	}
+
+	usersByName := map[string]*portainer.User{}
+	for i := range users {
+		usersByName[strings.ToLower(users[i].Username)] = &users[i]
+	}
+
+	// users of the directory whose team memberships are reconciled
+	activeUsers := []*portainer.User{}
+	inDirectory := map[string]bool{}
+
+	for _, username := range directoryUsers {
+		inDirectory[strings.ToLower(username)] = true
+
+		user, ok := usersByName[strings.ToLower(username)]
+		if !ok {
+			if !ldapSettings.AutoCreateUsers {
+				continue
+			}
+
+			user = &portainer.User{
+				Username:                username,
+				Role:                    portainer.StandardUserRole,
+				PortainerAuthorizations: authorization.DefaultPortainerAuthorizations(),
+				ProvisioningSource:      portainer.UserProvisionedByLDAP,
+			}
+
+			if !report.DryRun {
+				err := service.dataStore.User().Create(user)
+				if err != nil {
+					return errors.Wrapf(err, "unable to create the user %s", username)
+				}
+			}
+
+			report.CreatedUsers = append(report.CreatedUsers, username)
+			activeUsers = append(activeUsers, user)
+			continue
+		}
+
+		if !isDirectoryUser(user) {
+			continue
+		}
+
+		if user.Disabled {
+			user.Disabled = false
+
+			if !report.DryRun {
+				err := service.dataStore.User().UpdateUser(user.ID, user)
+				if err != nil {
+					return errors.Wrapf(err, "unable to enable the user %s", user.Username)
+				}
+			}
+
+			report.EnabledUsers = append(report.EnabledUsers, user.Username)
+		}
+
+		activeUsers = append(activeUsers, user)
+	}
+
+	// an empty result is more likely caused by a misconfigured search than by a directory without users
+	removedUsers := []*portainer.User{}
+	for i := range users {
+		if len(directoryUsers) > 0 && isDirectoryUser(&users[i]) && !inDirectory[strings.ToLower(users[i].Username)] {
+			removedUsers = append(removedUsers, &users[i])
+		}
+	}
+
+	sort.Slice(removedUsers, func(i, j int) bool { return removedUsers[i].Username < removedUsers[j].Username })
+
+	for _, user := range removedUsers {
+		err := service.deprovisionUser(user, ldapSettings.SyncDeprovisionAction, report)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !syncTeams {
+		return nil
+	}
+
+	return service.syncTeams(activeUsers, userGroups, directoryGroups, report)
+}
+
+// isDirectoryUser returns true when the user was provisioned from the directory, the other users,
+// including the ones created by OAuth or SCIM, are managed by Portainer
+func isDirectoryUser(user *portainer.User) bool {
+	return user.ID != 1 && user.ProvisioningSource == portainer.UserProvisionedByLDAP
+}
+
+func (service *SyncService) deprovisionUser(user *portainer.User, action portainer.LDAPDeprovisionAction, report *SyncReport) error {
+	if action == portainer.LDAPDeprovisionNone || (action == portainer.LDAPDeprovisionDisable && user.Disabled) {
+		return nil
+	}
+
+	if user.Role == portainer.AdministratorRole {
+		report.SkippedUsers = append(report.SkippedUsers, user.Username)
+		return nil
+	}
+
+	if action == portainer.LDAPDeprovisionDisable {
+		report.DisabledUsers = append(report.DisabledUsers, user.Username)
+		if report.DryRun {
+			return nil
+		}
+
+		user.Disabled = true
+		// invalidates the tokens issued to the user
+		user.TokenIssueAt = time.Now().Unix()
+
+		err := service.dataStore.User().UpdateUser(user.ID, user)
+		if err != nil {
+			return errors.Wrapf(err, "unable to disable the user %s", user.Username)
+		}
+
+		service.apiKeyService.InvalidateUserKeyCache(user.ID)
+
+		return nil
+	}
+
+	report.RemovedUsers = append(report.RemovedUsers, user.Username)
+	if report.DryRun {
+		return nil
+	}
+
+	err := service.dataStore.User().DeleteUser(user.ID)
+	if err != nil {
+		return errors.Wrapf(err, "unable to remove the user %s", user.Username)
+	}
+
+	err = service.dataStore.TeamMembership().DeleteTeamMembershipByUserID(user.ID)
+	if err != nil {
+		return errors.Wrapf(err, "unable to remove the team memberships of the user %s", user.Username)
+	}
+
+	err = service.dataStore.Session().DeleteSessionsByUserID(user.ID)
+	if err != nil {
+		return errors.Wrapf(err, "unable to remove the sessions of the user %s", user.Username)
+	}
+
+	apiKeys, err := service.apiKeyService.GetAPIKeys(user.ID)
+	if err != nil {
+		return errors.Wrapf(err, "unable to retrieve the API keys of the user %s", user.Username)
+	}
+
+	for _, apiKey := range apiKeys {
+		err := service.apiKeyService.DeleteAPIKey(apiKey.ID)
+		if err != nil {
+			return errors.Wrapf(err, "unable to remove the API keys of the user %s", user.Username)
+		}
+	}
+
+	return nil
+}
+
+// syncTeams reconciles the memberships of the teams named after a directory group with the members of the group
+func (service *SyncService) syncTeams(users []*portainer.User, userGroups map[string]map[string]bool, directoryGroups map[string]bool, report *SyncReport) error {
+	teams, err := service.dataStore.Team().Teams()
+	if err != nil {
+		return errors.Wrap(err, "unable to retrieve the teams")
+	}
+
+	syncedTeams := []portainer.Team{}
+	for _, team := range teams {
+		if directoryGroups[strings.ToLower(team.Name)] {
+			syncedTeams = append(syncedTeams, team)
+		}
+	}
+
+	sort.Slice(syncedTeams, func(i, j int) bool { return syncedTeams[i].Name < syncedTeams[j].Name })
+
+	memberships, err := service.dataStore.TeamMembership().TeamMemberships()
+	if err != nil {
+		return errors.Wrap(err, "unable to retrieve the team memberships")
+	}
+
+	userMemberships := map[portainer.UserID]map[portainer.TeamID]portainer.TeamMembership{}
+	for _, membership := range memberships {
+		if userMemberships[membership.UserID] == nil {
+			userMemberships[membership.UserID] = map[portainer.TeamID]portainer.TeamMembership{}
+		}
+
+		userMemberships[membership.UserID][membership.TeamID] = membership
+	}
+
+	for _, user := range users {
+		groups := userGroups[strings.ToLower(user.Username)]
+
+		for _, team := range syncedTeams {
+			// the users created in dry-run mode have no identifier and no membership
+			var membership portainer.TeamMembership
+			isMember := false
+			if user.ID != 0 {
+				membership, isMember = userMemberships[user.ID][team.ID]
+			}
+
+			inGroup := groups[strings.ToLower(team.Name)]
+
+			if inGroup && !isMember {
+				report.AddedMemberships = append(report.AddedMemberships, SyncMembership{Username: user.Username, Team: team.Name})
+				if report.DryRun {
+					continue
+				}
+
+				err := service.dataStore.TeamMembership().Create(&portainer.TeamMembership{
+					UserID: user.ID,
+					TeamID: team.ID,
+					Role:   portainer.TeamMember,
+				})
+				if err != nil {
+					return errors.Wrapf(err, "unable to add the user %s to the team %s", user.Username, team.Name)
+				}
+			}
+
+			if !inGroup && isMember {
+				report.RemovedMemberships = append(report.RemovedMemberships, SyncMembership{Username: user.Username, Team: team.Name})
+				if report.DryRun {
+					continue
+				}
+
+				err := service.dataStore.TeamMembership().DeleteTeamMembership(membership.ID)
+				if err != nil {
+					return errors.Wrapf(err, "unable to remove the user %s from the team %s", user.Username, team.Name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package ldap
+
+import (
+	"sort"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testLDAPService struct {
+	portainer.LDAPService
+	users  []string
+	groups []portainer.LDAPUser
+}
+
+func (service *testLDAPService) SearchUsers(settings *portainer.LDAPSettings) ([]string, error) {
+	return service.users, nil
+}
+
+func (service *testLDAPService) SearchGroups(settings *portainer.LDAPSettings) ([]portainer.LDAPUser, error) {
+	return service.groups, nil
+}
+
+func Test_SyncService_Sync(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	settings, err := store.Settings().Settings()
+	require.NoError(t, err)
+	settings.AuthenticationMethod = portainer.AuthenticationLDAP
+	settings.LDAPSettings.AutoCreateUsers = true
+	settings.LDAPSettings.SyncDeprovisionAction = portainer.LDAPDeprovisionDisable
+	settings.LDAPSettings.GroupSearchSettings = []portainer.LDAPGroupSearchSettings{{GroupBaseDN: "ou=groups,dc=example,dc=org"}}
+	require.NoError(t, store.Settings().UpdateSettings(settings))
+
+	for _, user := range []*portainer.User{
+		{ID: 1, Username: "admin", Password: "hash", Role: portainer.AdministratorRole},
+		{ID: 2, Username: "alice", Role: portainer.StandardUserRole, ProvisioningSource: portainer.UserProvisionedByLDAP},
+		{ID: 3, Username: "bob", Role: portainer.StandardUserRole, ProvisioningSource: portainer.UserProvisionedByLDAP},
+		{ID: 4, Username: "local", Password: "hash", Role: portainer.StandardUserRole},
+		{ID: 5, Username: "ldap-admin", Role: portainer.AdministratorRole, ProvisioningSource: portainer.UserProvisionedByLDAP},
+		{ID: 6, Username: "oauth-user", Role: portainer.StandardUserRole},
+	} {
+		require.NoError(t, store.User().Create(user))
+	}
+
+	for _, team := range []*portainer.Team{
+		{ID: 1, Name: "developers"},
+		{ID: 2, Name: "operators"},
+		{ID: 3, Name: "local-team"},
+	} {
+		require.NoError(t, store.Team().Create(team))
+	}
+
+	for _, membership := range []*portainer.TeamMembership{
+		{UserID: 2, TeamID: 2, Role: portainer.TeamMember},
+		{UserID: 2, TeamID: 3, Role: portainer.TeamMember},
+	} {
+		require.NoError(t, store.TeamMembership().Create(membership))
+	}
+
+	ldapService := &testLDAPService{
+		users: []string{"alice", "carol"},
+		groups: []portainer.LDAPUser{
+			{Name: "alice", Groups: []string{"Developers"}},
+			{Name: "carol", Groups: []string{"developers", "operators"}},
+			{Name: "dave", Groups: []string{"operators"}},
+		},
+	}
+
+	service := NewSyncService(store, ldapService, apikey.NewAPIKeyService(store.APIKeyRepository(), store.User()), nil)
+
+	teamsOf := func(username string) []string {
+		user, err := store.User().UserByUsername(username)
+		require.NoError(t, err)
+
+		memberships, err := store.TeamMembership().TeamMembershipsByUserID(user.ID)
+		require.NoError(t, err)
+
+		teams := []string{}
+		for _, membership := range memberships {
+			team, err := store.Team().Team(membership.TeamID)
+			require.NoError(t, err)
+			teams = append(teams, team.Name)
+		}
+		sort.Strings(teams)
+
+		return teams
+	}
+
+	expectedMemberships := []SyncMembership{
+		{Username: "alice", Team: "developers"},
+		{Username: "carol", Team: "developers"},
+		{Username: "carol", Team: "operators"},
+	}
+
+	t.Run("dry-run should report the changes without applying them", func(t *testing.T) {
+		report, err := service.Sync(true)
+		require.NoError(t, err)
+
+		is.True(report.DryRun)
+		is.Equal([]string{"carol"}, report.CreatedUsers)
+		is.Equal([]string{"bob"}, report.DisabledUsers)
+		is.Equal([]string{"ldap-admin"}, report.SkippedUsers)
+		is.Equal(expectedMemberships, report.AddedMemberships)
+		is.Equal([]SyncMembership{{Username: "alice", Team: "operators"}}, report.RemovedMemberships)
+
+		_, err = store.User().UserByUsername("carol")
+		is.True(store.IsErrObjectNotFound(err))
+
+		bob, err := store.User().User(3)
+		require.NoError(t, err)
+		is.False(bob.Disabled)
+
+		is.Equal([]string{"local-team", "operators"}, teamsOf("alice"))
+	})
+
+	t.Run("should provision, deprovision and reconcile the team memberships", func(t *testing.T) {
+		report, err := service.Sync(false)
+		require.NoError(t, err)
+
+		is.Equal([]string{"carol"}, report.CreatedUsers)
+		is.Equal([]string{"bob"}, report.DisabledUsers)
+		is.Equal(expectedMemberships, report.AddedMemberships)
+
+		bob, err := store.User().User(3)
+		require.NoError(t, err)
+		is.True(bob.Disabled)
+		is.NotZero(bob.TokenIssueAt)
+
+		local, err := store.User().User(4)
+		require.NoError(t, err)
+		is.False(local.Disabled, "the users with a password should not be synchronized")
+
+		oauthUser, err := store.User().User(6)
+		require.NoError(t, err)
+		is.False(oauthUser.Disabled, "the users not provisioned from the directory should not be synchronized")
+
+		carol, err := store.User().UserByUsername("carol")
+		require.NoError(t, err)
+		is.Equal(portainer.UserProvisionedByLDAP, carol.ProvisioningSource)
+
+		is.Equal([]string{"developers", "local-team"}, teamsOf("alice"), "the teams not matching a group should be kept")
+		is.Equal([]string{"developers", "operators"}, teamsOf("carol"))
+	})
+
+	t.Run("should enable the users found back and remove the deprovisioned users", func(t *testing.T) {
+		ldapService.users = []string{"bob", "carol"}
+
+		settings.LDAPSettings.SyncDeprovisionAction = portainer.LDAPDeprovisionRemove
+		require.NoError(t, store.Settings().UpdateSettings(settings))
+
+		report, err := service.Sync(false)
+		require.NoError(t, err)
+
+		is.Equal([]string{"bob"}, report.EnabledUsers)
+		is.Equal([]string{"alice"}, report.RemovedUsers)
+		is.Empty(report.CreatedUsers)
+
+		bob, err := store.User().User(3)
+		require.NoError(t, err)
+		is.False(bob.Disabled)
+
+		_, err = store.User().UserByUsername("alice")
+		is.True(store.IsErrObjectNotFound(err))
+
+		memberships, err := store.TeamMembership().TeamMembershipsByUserID(2)
+		require.NoError(t, err)
+		is.Empty(memberships)
+	})
+
+	t.Run("should keep the latest reports", func(t *testing.T) {
+		reports := service.Reports()
+		is.Len(reports, 3)
+		is.Equal([]string{"bob"}, reports[0].EnabledUsers, "the most recent report should be first")
+		is.True(reports[2].DryRun)
+	})
+
+	t.Run("should not deprovision the users when the directory returns no user", func(t *testing.T) {
+		ldapService.users = nil
+
+		report, err := service.Sync(false)
+		require.NoError(t, err)
+		is.Empty(report.RemovedUsers)
+
+		_, err = store.User().UserByUsername("bob")
+		is.NoError(err)
+	})
+
+	t.Run("should fail when the LDAP authentication is not enabled", func(t *testing.T) {
+		settings.AuthenticationMethod = portainer.AuthenticationInternal
+		require.NoError(t, store.Settings().UpdateSettings(settings))
+
+		_, err := service.Sync(false)
+		is.ErrorIs(err, ErrSyncNotAvailable)
+		is.Len(service.Reports(), 4)
+	})
+}
+
+func Test_ValidateSyncSettings(t *testing.T) {
+	is := assert.New(t)
+
+	is.NoError(ValidateSyncSettings(portainer.LDAPSettings{}))
+	is.NoError(ValidateSyncSettings(portainer.LDAPSettings{SyncInterval: "1h", SyncDeprovisionAction: portainer.LDAPDeprovisionRemove}))
+	is.Error(ValidateSyncSettings(portainer.LDAPSettings{SyncInterval: "1m"}))
+	is.Error(ValidateSyncSettings(portainer.LDAPSettings{SyncInterval: "hourly"}))
+	is.Error(ValidateSyncSettings(portainer.LDAPSettings{SyncDeprovisionAction: "delete"}))
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Path is the path of the metrics endpoint
+const Path = "/metrics"
+
+// WithMetricsEndpoint serves the metrics on Path and records the duration of every other request
+// before passing it to next. When token is not empty, the scrapers must send it as a bearer token.
+func WithMetricsEndpoint(next http.Handler, token string) http.Handler {
+	metricsHandler := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	instrumented := InstrumentHandler(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != Path {
+			instrumented.ServeHTTP(w, r)
+			return
+		}
+
+		if token != "" && !validToken(r, token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		metricsHandler.ServeHTTP(w, r)
+	})
+}
+
+// InstrumentHandler records the duration of the requests served by next, per API handler
+func InstrumentHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rw, r)
+
+		httpRequestDuration.
+			WithLabelValues(handlerName(r.URL.Path), r.Method, strconv.Itoa(rw.status)).
+			Observe(time.Since(start).Seconds())
+	})
+}
+
+func validToken(r *http.Request, token string) bool {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	value := strings.TrimPrefix(header, "Bearer ")
+
+	return subtle.ConstantTimeCompare([]byte(value), []byte(token)) == 1
+}
+
+// handlerName returns the name of the API handler serving a path, e.g. "stacks" for /api/stacks/1
+// or "endpoints/docker" for /api/endpoints/1/docker/containers/json.
+// The identifiers are ignored to keep a bounded number of label values.
+func handlerName(path string) string {
+	if !strings.HasPrefix(path, "/api/") {
+		return "static"
+	}
+
+	segments := strings.Split(strings.TrimPrefix(path, "/api/"), "/")
+	if segments[0] == "" {
+		return "api"
+	}
+
+	if len(segments) > 2 && segments[0] == "endpoints" {
+		if _, err := strconv.Atoi(segments[1]); err == nil {
+			return segments[0] + "/" + segments[2]
+		}
+	}
+
+	return segments[0]
+}
+
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *statusResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the response writer does not support hijacking")
+	}
+
+	w.status = http.StatusSwitchingProtocols
+
+	return hijacker.Hijack()
+}
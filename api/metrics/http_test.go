@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_handlerName(t *testing.T) {
+	tests := map[string]string{
+		"/":                  "static",
+		"/main.js":           "static",
+		"/api/":              "api",
+		"/api/stacks":        "stacks",
+		"/api/stacks/1/file": "stacks",
+		"/api/endpoints/1":   "endpoints",
+		"/api/endpoints/1/docker/containers/json":  "endpoints/docker",
+		"/api/endpoints/snapshot":                  "endpoints",
+		"/api/endpoints/relations/some/other/path": "endpoints",
+	}
+
+	for path, expected := range tests {
+		assert.Equal(t, expected, handlerName(path), path)
+	}
+}
+
+func Test_WithMetricsEndpoint(t *testing.T) {
+	is := assert.New(t)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	handler := WithMetricsEndpoint(next, "token")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/stacks", nil))
+	is.Equal(http.StatusTeapot, rr.Code, "other requests should be passed to the next handler")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, Path, nil))
+	is.Equal(http.StatusUnauthorized, rr.Code, "the metrics should require the token")
+
+	req := httptest.NewRequest(http.MethodGet, Path, nil)
+	req.Header.Set("Authorization", "Bearer token")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	is.Equal(http.StatusOK, rr.Code)
+
+	body, _ := io.ReadAll(rr.Body)
+	is.Contains(string(body), `portainer_http_request_duration_seconds_count{code="418",handler="stacks",method="GET"} 1`)
+}
@@ -0,0 +1,135 @@
+// Package metrics exposes the internal metrics of the Portainer server in the Prometheus format.
+// The metrics are always collected, they are only exposed when the metrics endpoint is enabled.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "portainer"
+
+// Git auto-update poll results
+const (
+	GitPollUpdated   = "updated"
+	GitPollUnchanged = "unchanged"
+	GitPollError     = "error"
+)
+
+// Registry holds every Portainer metric as well as the Go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of the HTTP requests served by the Portainer API, per handler.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"handler", "method", "code"})
+
+	proxyRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "proxy_request_duration_seconds",
+		Help:      "Round-trip duration of the requests proxied to the Docker API of an environment.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint_id", "code"})
+
+	snapshotDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "snapshot_duration_seconds",
+		Help:      "Duration of the environment snapshots.",
+		Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"endpoint_id"})
+
+	snapshotFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "snapshot_failures_total",
+		Help:      "Number of environment snapshots that failed.",
+	}, []string{"endpoint_id"})
+
+	dbTransactionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_transaction_duration_seconds",
+		Help:      "Duration of the database transactions.",
+		Buckets:   []float64{0.0005, 0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.5, 1},
+	}, []string{"type"})
+
+	gitPolls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "git_autoupdate_polls_total",
+		Help:      "Number of git repository polls made by the stack auto-update, per result.",
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		proxyRequestDuration,
+		snapshotDuration,
+		snapshotFailures,
+		dbTransactionDuration,
+		gitPolls,
+	)
+}
+
+// ObserveProxyRequest records the round-trip duration of a request proxied to an environment,
+// statusCode is 0 when no response was received
+func ObserveProxyRequest(endpointID int, statusCode int, duration time.Duration) {
+	code := "error"
+	if statusCode > 0 {
+		code = strconv.Itoa(statusCode)
+	}
+
+	proxyRequestDuration.WithLabelValues(strconv.Itoa(endpointID), code).Observe(duration.Seconds())
+}
+
+// ObserveSnapshot records the duration of an environment snapshot and counts its failure
+func ObserveSnapshot(endpointID int, duration time.Duration, err error) {
+	id := strconv.Itoa(endpointID)
+
+	snapshotDuration.WithLabelValues(id).Observe(duration.Seconds())
+	if err != nil {
+		snapshotFailures.WithLabelValues(id).Inc()
+	}
+}
+
+// ObserveDBTransaction records the duration of a database transaction, txType is either "update" or "view"
+func ObserveDBTransaction(txType string, duration time.Duration) {
+	dbTransactionDuration.WithLabelValues(txType).Observe(duration.Seconds())
+}
+
+// CountGitPoll counts a poll of a git repository made by the stack auto-update
+func CountGitPoll(result string) {
+	gitPolls.WithLabelValues(result).Inc()
+}
+
+// RegisterTunnelCollector exposes the number of reverse tunnels per status,
+// counts is called every time the metrics are collected
+func RegisterTunnelCollector(counts func() map[string]int) error {
+	return Registry.Register(&tunnelCollector{counts: counts})
+}
+
+type tunnelCollector struct {
+	counts func() map[string]int
+}
+
+var tunnelsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "tunnels"),
+	"Number of reverse tunnels of the Edge environments, per status.",
+	[]string{"status"}, nil,
+)
+
+func (c *tunnelCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- tunnelsDesc
+}
+
+func (c *tunnelCollector) Collect(ch chan<- prometheus.Metric) {
+	for status, count := range c.counts() {
+		ch <- prometheus.MustNewConstMetric(tunnelsDesc, prometheus.GaugeValue, float64(count), status)
+	}
+}
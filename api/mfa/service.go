@@ -0,0 +1,363 @@
+package mfa
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+)
+
+const (
+	// issuer is the issuer displayed by the authenticator applications
+	issuer = "Portainer"
+	// recoveryCodesCount is the number of recovery codes generated when the MFA is activated
+	recoveryCodesCount = 10
+	// challengeTTL is the lifetime of a login challenge
+	challengeTTL = 5 * time.Minute
+	// challengeMaxAttempts is the number of invalid codes after which a login challenge is revoked
+	challengeMaxAttempts = 5
+	// keySize is the size of the key used to encrypt the TOTP secrets
+	keySize = 32
+)
+
+var (
+	// ErrAlreadyEnabled is returned when enrolling a user whose MFA is already enabled
+	ErrAlreadyEnabled = errors.New("multi-factor authentication is already enabled")
+	// ErrNotEnrolled is returned when activating the MFA of a user who has not been enrolled
+	ErrNotEnrolled = errors.New("multi-factor authentication enrollment has not been started")
+	// ErrInvalidCode is returned when a TOTP or recovery code is invalid
+	ErrInvalidCode = errors.New("invalid multi-factor authentication code")
+	// ErrInvalidChallenge is returned when a login challenge is unknown, expired or revoked
+	ErrInvalidChallenge = errors.New("invalid or expired multi-factor authentication challenge")
+)
+
+type (
+	// Service manages the TOTP multi-factor authentication of the internal users
+	Service struct {
+		dataStore  dataservices.DataStore
+		key        []byte
+		mu         sync.Mutex
+		challenges map[string]*Challenge
+		timeSource func() time.Time
+	}
+
+	// Challenge is issued once the password of a user requiring MFA is verified,
+	// it is exchanged for a JWT once the second factor is verified
+	Challenge struct {
+		UserID portainer.UserID
+		// Set when the user has to enroll before being issued a JWT
+		EnrollmentRequired bool
+		// Carried over to the JWT issued once the challenge is solved
+		ForceChangePassword bool
+		expiresAt           time.Time
+		attempts            int
+	}
+
+	// Enrollment contains the details required to register a TOTP secret in an authenticator application
+	Enrollment struct {
+		// Base32 encoded TOTP secret
+		Secret string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+		// otpauth:// URI of the secret, usually rendered as a QR code
+		ProvisioningURI string `json:"provisioningUri" example:"otpauth://totp/Portainer:admin?secret=JBSWY3DPEHPK3PXP"`
+	}
+)
+
+// NewService initializes a new MFA service, the TOTP secrets are encrypted with the key
+func NewService(dataStore dataservices.DataStore, key []byte) *Service {
+	hash := sha256.Sum256(key)
+
+	return &Service{
+		dataStore:  dataStore,
+		key:        hash[:],
+		challenges: make(map[string]*Challenge),
+		timeSource: time.Now,
+	}
+}
+
+// LoadOrCreateKey reads the key used to encrypt the TOTP secrets from the file, the key is generated
+// and written to the file when it does not exist yet
+func LoadOrCreateKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err == nil {
+		return key, nil
+	}
+
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key = make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	return key, os.WriteFile(path, key, 0600)
+}
+
+// IsRequired returns whether a second factor is required to authenticate the user
+func IsRequired(user *portainer.User, settings *portainer.Settings) bool {
+	return user.MFA.Enabled || IsEnforced(user, settings)
+}
+
+// IsEnforced returns whether the user cannot authenticate without MFA
+func IsEnforced(user *portainer.User, settings *portainer.Settings) bool {
+	return user.Role == portainer.AdministratorRole && settings.InternalAuthSettings.EnforceAdminMFA
+}
+
+// Enroll generates a new TOTP secret for the user, the MFA is enabled once a code generated with the
+// secret is verified by Activate
+func (service *Service) Enroll(user *portainer.User) (*Enrollment, error) {
+	if user.MFA.Enabled {
+		return nil, ErrAlreadyEnabled
+	}
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedSecret, err := service.encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	user.MFA = portainer.UserMFA{Secret: encryptedSecret}
+
+	err = service.dataStore.User().UpdateUser(user.ID, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Enrollment{
+		Secret:          secret,
+		ProvisioningURI: ProvisioningURI(issuer, user.Username, secret),
+	}, nil
+}
+
+// Activate enables the MFA of an enrolled user once the code is verified and returns the recovery codes.
+// The recovery codes are only stored as digests, they cannot be retrieved afterwards.
+func (service *Service) Activate(user *portainer.User, code string) ([]string, error) {
+	if user.MFA.Enabled {
+		return nil, ErrAlreadyEnabled
+	}
+
+	if user.MFA.Secret == "" {
+		return nil, ErrNotEnrolled
+	}
+
+	step, err := service.validateTOTP(user, code)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes := make([]string, recoveryCodesCount)
+	digests := make([]string, recoveryCodesCount)
+	for i := range recoveryCodes {
+		recoveryCodes[i], err = generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		digests[i] = digest(recoveryCodes[i])
+	}
+
+	user.MFA.Enabled = true
+	user.MFA.RecoveryCodes = digests
+	user.MFA.LastUsedStep = step
+
+	return recoveryCodes, service.dataStore.User().UpdateUser(user.ID, user)
+}
+
+// Verify verifies a TOTP code, or a recovery code, of a user whose MFA is enabled.
+// A recovery code can only be used once.
+func (service *Service) Verify(user *portainer.User, code string) error {
+	if !user.MFA.Enabled {
+		return ErrNotEnrolled
+	}
+
+	code = strings.TrimSpace(code)
+
+	if strings.Contains(code, "-") {
+		codeDigest := digest(strings.ToLower(code))
+		for i, recoveryCode := range user.MFA.RecoveryCodes {
+			if subtle.ConstantTimeCompare([]byte(recoveryCode), []byte(codeDigest)) == 1 {
+				user.MFA.RecoveryCodes = append(user.MFA.RecoveryCodes[:i], user.MFA.RecoveryCodes[i+1:]...)
+				return service.dataStore.User().UpdateUser(user.ID, user)
+			}
+		}
+
+		return ErrInvalidCode
+	}
+
+	step, err := service.validateTOTP(user, code)
+	if err != nil {
+		return err
+	}
+
+	user.MFA.LastUsedStep = step
+
+	return service.dataStore.User().UpdateUser(user.ID, user)
+}
+
+// Reset disables the MFA of the user and removes its TOTP secret and recovery codes
+func (service *Service) Reset(user *portainer.User) error {
+	user.MFA = portainer.UserMFA{}
+
+	return service.dataStore.User().UpdateUser(user.ID, user)
+}
+
+// NewChallenge issues a login challenge for the user and returns its token
+func (service *Service) NewChallenge(user *portainer.User, enrollmentRequired, forceChangePassword bool) (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	challengeToken := base64.RawURLEncoding.EncodeToString(token)
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	now := service.timeSource()
+	for existingToken, challenge := range service.challenges {
+		if now.After(challenge.expiresAt) {
+			delete(service.challenges, existingToken)
+		}
+	}
+
+	service.challenges[challengeToken] = &Challenge{
+		UserID:              user.ID,
+		EnrollmentRequired:  enrollmentRequired,
+		ForceChangePassword: forceChangePassword,
+		expiresAt:           now.Add(challengeTTL),
+	}
+
+	return challengeToken, nil
+}
+
+// Challenge returns the login challenge of the token
+func (service *Service) Challenge(token string) (*Challenge, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	challenge, ok := service.challenges[token]
+	if !ok {
+		return nil, ErrInvalidChallenge
+	}
+
+	if service.timeSource().After(challenge.expiresAt) {
+		delete(service.challenges, token)
+		return nil, ErrInvalidChallenge
+	}
+
+	c := *challenge
+	return &c, nil
+}
+
+// FailChallenge records an invalid code for the login challenge, the challenge is revoked
+// once the maximum number of attempts is reached
+func (service *Service) FailChallenge(token string) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	challenge, ok := service.challenges[token]
+	if !ok {
+		return
+	}
+
+	challenge.attempts++
+	if challenge.attempts >= challengeMaxAttempts {
+		delete(service.challenges, token)
+	}
+}
+
+// CompleteChallenge revokes the login challenge once it is solved
+func (service *Service) CompleteChallenge(token string) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	delete(service.challenges, token)
+}
+
+func (service *Service) validateTOTP(user *portainer.User, code string) (int64, error) {
+	secret, err := service.decrypt(user.MFA.Secret)
+	if err != nil {
+		return 0, err
+	}
+
+	step, ok := ValidateCode(secret, code, service.timeSource())
+	if !ok || step <= user.MFA.LastUsedStep {
+		return 0, ErrInvalidCode
+	}
+
+	return step, nil
+}
+
+func (service *Service) encrypt(secret string) (string, error) {
+	gcm, err := service.cipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+func (service *Service) decrypt(encryptedSecret string) (string, error) {
+	gcm, err := service.cipher()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encryptedSecret)
+	if err != nil || len(data) < gcm.NonceSize() {
+		return "", errors.New("invalid encrypted TOTP secret")
+	}
+
+	secret, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to decrypt the TOTP secret: %w", err)
+	}
+
+	return string(secret), nil
+}
+
+func (service *Service) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(service.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// generateRecoveryCode generates a random recovery code formatted as xxxxx-xxxxx
+func generateRecoveryCode() (string, error) {
+	data := make([]byte, 8)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(secretEncoding.EncodeToString(data))[:10]
+
+	return code[:5] + "-" + code[5:], nil
+}
+
+func digest(code string) string {
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,113 @@
+package mfa
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ValidateCode(t *testing.T) {
+	is := assert.New(t)
+
+	// RFC 6238 test vector for SHA1
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	step, ok := ValidateCode(secret, "287082", time.Unix(59, 0))
+	is.True(ok)
+	is.Equal(int64(1), step)
+
+	_, ok = ValidateCode(secret, "287082", time.Unix(59+30, 0))
+	is.True(ok, "the code of the previous time step should be accepted")
+
+	_, ok = ValidateCode(secret, "287082", time.Unix(59+90, 0))
+	is.False(ok, "the code should expire")
+
+	_, ok = ValidateCode(secret, "28708", time.Unix(59, 0))
+	is.False(ok)
+}
+
+func Test_Service(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	user := &portainer.User{ID: 1, Username: "admin", Role: portainer.AdministratorRole}
+	require.NoError(t, store.User().Create(user))
+
+	now := time.Unix(1672531200, 0)
+	service := NewService(store, []byte("secret"))
+	service.timeSource = func() time.Time { return now }
+
+	enrollment, err := service.Enroll(user)
+	require.NoError(t, err)
+	is.NotContains(user.MFA.Secret, enrollment.Secret, "the secret should be encrypted")
+	is.Contains(enrollment.ProvisioningURI, "otpauth://totp/Portainer:admin?")
+
+	codeAt := func(t time.Time) string {
+		code, _ := GenerateCode(enrollment.Secret, t)
+		return code
+	}
+
+	_, err = service.Activate(user, "000000")
+	is.ErrorIs(err, ErrInvalidCode)
+
+	recoveryCodes, err := service.Activate(user, codeAt(now))
+	require.NoError(t, err)
+	is.Len(recoveryCodes, recoveryCodesCount)
+
+	user, err = store.User().User(user.ID)
+	require.NoError(t, err)
+	is.True(user.MFA.Enabled)
+
+	_, err = service.Enroll(user)
+	is.ErrorIs(err, ErrAlreadyEnabled)
+
+	is.ErrorIs(service.Verify(user, codeAt(now)), ErrInvalidCode, "a code should not be used twice")
+
+	now = now.Add(30 * time.Second)
+	is.NoError(service.Verify(user, codeAt(now)))
+
+	is.NoError(service.Verify(user, recoveryCodes[0]))
+	is.ErrorIs(service.Verify(user, recoveryCodes[0]), ErrInvalidCode, "a recovery code should not be used twice")
+	is.Len(user.MFA.RecoveryCodes, recoveryCodesCount-1)
+
+	is.NoError(service.Reset(user))
+	is.False(user.MFA.Enabled)
+	is.ErrorIs(service.Verify(user, recoveryCodes[1]), ErrNotEnrolled)
+}
+
+func Test_Challenge(t *testing.T) {
+	is := assert.New(t)
+
+	now := time.Unix(1672531200, 0)
+	service := NewService(nil, []byte("secret"))
+	service.timeSource = func() time.Time { return now }
+
+	token, err := service.NewChallenge(&portainer.User{ID: 2}, true, false)
+	require.NoError(t, err)
+
+	challenge, err := service.Challenge(token)
+	require.NoError(t, err)
+	is.Equal(portainer.UserID(2), challenge.UserID)
+	is.True(challenge.EnrollmentRequired)
+
+	for i := 0; i < challengeMaxAttempts; i++ {
+		service.FailChallenge(token)
+	}
+
+	_, err = service.Challenge(token)
+	is.ErrorIs(err, ErrInvalidChallenge, "the challenge should be revoked after too many attempts")
+
+	token, err = service.NewChallenge(&portainer.User{ID: 2}, false, false)
+	require.NoError(t, err)
+
+	now = now.Add(challengeTTL + time.Second)
+	_, err = service.Challenge(token)
+	is.ErrorIs(err, ErrInvalidChallenge, "the challenge should expire")
+}
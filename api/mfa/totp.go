@@ -0,0 +1,94 @@
+package mfa
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// totpPeriod is the lifetime of a TOTP code
+	totpPeriod = 30 * time.Second
+	// totpDigits is the number of digits of a TOTP code
+	totpDigits = 6
+	// totpSkew is the number of time steps before and after the current one for which the codes are accepted
+	totpSkew = 1
+	// secretSize is the size of a TOTP secret in bytes, as recommended by RFC 4226
+	secretSize = 20
+)
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret generates a random base32 encoded TOTP secret
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return secretEncoding.EncodeToString(secret), nil
+}
+
+// ProvisioningURI returns the otpauth:// URI used to enroll the secret in an authenticator application,
+// usually rendered as a QR code
+func ProvisioningURI(issuer, accountName, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+
+	return fmt.Sprintf("otpauth://totp/%s:%s?%s", url.PathEscape(issuer), url.PathEscape(accountName), query.Encode())
+}
+
+// GenerateCode generates the TOTP code of the secret at the given time
+func GenerateCode(secret string, t time.Time) (string, error) {
+	key, err := secretEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	return hotp(key, t.Unix()/int64(totpPeriod.Seconds())), nil
+}
+
+// ValidateCode validates a TOTP code against the secret at the given time, as defined by RFC 6238.
+// The codes of the adjacent time steps are accepted to tolerate clock drifts.
+// The time step of the code is returned so that a code cannot be used twice.
+func ValidateCode(secret, code string, t time.Time) (int64, bool) {
+	key, err := secretEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	step := t.Unix() / int64(totpPeriod.Seconds())
+	for offset := int64(-totpSkew); offset <= totpSkew; offset++ {
+		if subtle.ConstantTimeCompare([]byte(hotp(key, step+offset)), []byte(code)) == 1 {
+			return step + offset, true
+		}
+	}
+
+	return 0, false
+}
+
+// hotp computes the HOTP code of the counter, as defined by RFC 4226
+func hotp(key []byte, counter int64) string {
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
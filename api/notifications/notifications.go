@@ -0,0 +1,177 @@
+package notifications
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	eventQueueSize    = 100
+	defaultMaxRetries = 3
+	initialBackoff    = 2 * time.Second
+	maxBackoff        = 1 * time.Minute
+	deliveryTimeout   = 10 * time.Second
+)
+
+// Event represents something that happened inside Portainer and that is delivered
+// to the notification channels subscribed to its type
+type Event struct {
+	Type       portainer.NotificationEventType `json:"type" example:"endpoint.down"`
+	Time       int64                           `json:"time" example:"1587399600"`
+	Message    string                          `json:"message" example:"environment local is unreachable"`
+	EndpointID portainer.EndpointID            `json:"endpointId,omitempty" example:"1"`
+	Details    map[string]string               `json:"details,omitempty"`
+}
+
+// Service is the internal event bus. Publishers push events on the bus and
+// the service dispatches them asynchronously to every matching notification channel
+type Service struct {
+	dataStore      dataservices.DataStore
+	events         chan Event
+	shutdownCtx    context.Context
+	httpClient     *http.Client
+	initialBackoff time.Duration
+}
+
+// NewService creates a new instance of the notification service
+func NewService(dataStore dataservices.DataStore, shutdownCtx context.Context) *Service {
+	return &Service{
+		dataStore:      dataStore,
+		events:         make(chan Event, eventQueueSize),
+		shutdownCtx:    shutdownCtx,
+		httpClient:     &http.Client{Timeout: deliveryTimeout},
+		initialBackoff: initialBackoff,
+	}
+}
+
+// Start starts the background routine dispatching the published events
+func (service *Service) Start() {
+	go service.dispatchLoop()
+}
+
+// Publish pushes an event on the bus without blocking the caller.
+// It is safe to call on a nil service, in which case the event is discarded.
+func (service *Service) Publish(event Event) {
+	if service == nil {
+		return
+	}
+
+	if event.Time == 0 {
+		event.Time = time.Now().Unix()
+	}
+
+	select {
+	case service.events <- event:
+	default:
+		log.Warn().Str("event", string(event.Type)).Msg("notification queue is full, dropping event")
+	}
+}
+
+// Send synchronously delivers an event to a single channel without any retry
+func (service *Service) Send(channel *portainer.NotificationChannel, event Event) error {
+	if event.Time == 0 {
+		event.Time = time.Now().Unix()
+	}
+
+	return service.deliver(channel, event)
+}
+
+func (service *Service) dispatchLoop() {
+	for {
+		select {
+		case event := <-service.events:
+			service.dispatch(event)
+		case <-service.shutdownCtx.Done():
+			log.Debug().Msg("shutting down the notification service")
+			return
+		}
+	}
+}
+
+func (service *Service) dispatch(event Event) {
+	channels, err := service.dataStore.NotificationChannel().NotificationChannels()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to retrieve notification channels from the database")
+		return
+	}
+
+	for i := range channels {
+		channel := channels[i]
+		if !Subscribed(&channel, event.Type) {
+			continue
+		}
+
+		go service.deliverWithRetry(&channel, event)
+	}
+}
+
+// Subscribed returns true if the channel is enabled and its filter accepts the event type.
+// An empty filter accepts every event.
+func Subscribed(channel *portainer.NotificationChannel, eventType portainer.NotificationEventType) bool {
+	if !channel.Enabled {
+		return false
+	}
+
+	if len(channel.Events) == 0 {
+		return true
+	}
+
+	for _, t := range channel.Events {
+		if t == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (service *Service) deliverWithRetry(channel *portainer.NotificationChannel, event Event) {
+	maxRetries := channel.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+
+	backoff := service.initialBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := service.deliver(channel, event)
+		if err == nil {
+			return
+		}
+
+		if attempt >= maxRetries {
+			log.Error().
+				Err(err).
+				Str("channel", channel.Name).
+				Str("event", string(event.Type)).
+				Int("attempts", attempt+1).
+				Msg("unable to deliver notification, giving up")
+
+			return
+		}
+
+		log.Debug().
+			Err(err).
+			Str("channel", channel.Name).
+			Str("event", string(event.Type)).
+			Dur("backoff", backoff).
+			Msg("unable to deliver notification, retrying")
+
+		select {
+		case <-time.After(backoff):
+		case <-service.shutdownCtx.Done():
+			return
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
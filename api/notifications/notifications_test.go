@@ -0,0 +1,102 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Subscribed(t *testing.T) {
+	is := assert.New(t)
+
+	channel := &portainer.NotificationChannel{Enabled: true}
+	is.True(Subscribed(channel, portainer.NotificationEventEndpointDown), "empty filter should accept every event")
+
+	channel.Events = []portainer.NotificationEventType{portainer.NotificationEventAuthFailed}
+	is.True(Subscribed(channel, portainer.NotificationEventAuthFailed))
+	is.False(Subscribed(channel, portainer.NotificationEventEndpointDown))
+
+	channel.Enabled = false
+	is.False(Subscribed(channel, portainer.NotificationEventAuthFailed), "disabled channel should not receive events")
+}
+
+func Test_Send_Webhook_IsSigned(t *testing.T) {
+	is := assert.New(t)
+
+	var body []byte
+	var signature, eventType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		eventType = r.Header.Get(EventHeader)
+	}))
+	defer server.Close()
+
+	service := NewService(nil, context.Background())
+	channel := &portainer.NotificationChannel{
+		Type:   portainer.WebhookNotificationChannel,
+		URL:    server.URL,
+		Secret: "secret",
+	}
+
+	err := service.Send(channel, Event{Type: portainer.NotificationEventEndpointDown, Message: "down", EndpointID: 1})
+	is.NoError(err)
+
+	is.Equal(string(portainer.NotificationEventEndpointDown), eventType)
+	is.Equal(Sign("secret", body), signature)
+
+	var event Event
+	is.NoError(json.Unmarshal(body, &event))
+	is.Equal(portainer.EndpointID(1), event.EndpointID)
+	is.NotZero(event.Time)
+}
+
+func Test_Send_Slack(t *testing.T) {
+	is := assert.New(t)
+
+	var message slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&message)
+	}))
+	defer server.Close()
+
+	service := NewService(nil, context.Background())
+	channel := &portainer.NotificationChannel{Type: portainer.SlackNotificationChannel, URL: server.URL}
+
+	err := service.Send(channel, Event{Type: portainer.NotificationEventAuthFailed, Message: "failed", Details: map[string]string{"username": "admin"}})
+	is.NoError(err)
+	is.Equal("[Portainer] auth.failed: failed\nusername: admin", message.Text)
+}
+
+func Test_DeliverWithRetry(t *testing.T) {
+	is := assert.New(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	service := NewService(nil, context.Background())
+	service.initialBackoff = time.Millisecond
+
+	channel := &portainer.NotificationChannel{Type: portainer.WebhookNotificationChannel, URL: server.URL, MaxRetries: 5}
+	service.deliverWithRetry(channel, Event{Type: portainer.NotificationEventStackDeployFailed})
+	is.Equal(int32(3), atomic.LoadInt32(&calls), "delivery should stop after the first success")
+
+	atomic.StoreInt32(&calls, -10)
+	channel.MaxRetries = 1
+	service.deliverWithRetry(channel, Event{Type: portainer.NotificationEventStackDeployFailed})
+	is.Equal(int32(-8), atomic.LoadInt32(&calls), "delivery should give up after the max retries")
+}
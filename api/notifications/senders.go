@@ -0,0 +1,154 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// EventHeader is the name of the header containing the event type of a webhook notification
+	EventHeader = "X-Portainer-Event"
+	// SignatureHeader is the name of the header containing the HMAC-SHA256 signature of a webhook notification
+	SignatureHeader = "X-Portainer-Signature"
+)
+
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+func (service *Service) deliver(channel *portainer.NotificationChannel, event Event) error {
+	switch channel.Type {
+	case portainer.WebhookNotificationChannel:
+		return service.sendWebhook(channel, event)
+	case portainer.SlackNotificationChannel:
+		return service.sendSlack(channel, event)
+	case portainer.EmailNotificationChannel:
+		return sendEmail(channel, event)
+	}
+
+	return fmt.Errorf("unsupported notification channel type %d", channel.Type)
+}
+
+// Sign returns the hex encoded HMAC-SHA256 signature of a payload
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (service *Service) sendWebhook(channel *portainer.NotificationChannel, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "unable to encode the event")
+	}
+
+	headers := map[string]string{EventHeader: string(event.Type)}
+	if channel.Secret != "" {
+		headers[SignatureHeader] = Sign(channel.Secret, body)
+	}
+
+	return service.post(channel.URL, body, headers)
+}
+
+func (service *Service) sendSlack(channel *portainer.NotificationChannel, event Event) error {
+	body, err := json.Marshal(slackMessage{Text: formatText(event)})
+	if err != nil {
+		return errors.Wrap(err, "unable to encode the slack message")
+	}
+
+	return service.post(channel.URL, body, nil)
+}
+
+func (service *Service) post(url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "unable to create the notification request")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := service.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "unable to send the notification request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification endpoint responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func sendEmail(channel *portainer.NotificationChannel, event Event) error {
+	settings := channel.SMTP
+
+	port := settings.Port
+	if port == 0 {
+		port = 25
+	}
+	addr := net.JoinHostPort(settings.Host, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if settings.Username != "" {
+		auth = smtp.PlainAuth("", settings.Username, settings.Password, settings.Host)
+	}
+
+	subject := fmt.Sprintf("[Portainer] %s", event.Type)
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", settings.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(settings.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Unix(event.Time, 0).UTC().Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(formatText(event))
+	msg.WriteString("\r\n")
+
+	err := smtp.SendMail(addr, auth, settings.From, settings.To, []byte(msg.String()))
+	if err != nil {
+		return errors.Wrap(err, "unable to send the notification email")
+	}
+
+	return nil
+}
+
+func formatText(event Event) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "[Portainer] %s: %s", event.Type, event.Message)
+
+	if event.EndpointID != 0 {
+		fmt.Fprintf(&text, " (environment %d)", event.EndpointID)
+	}
+
+	keys := make([]string, 0, len(event.Details))
+	for key := range event.Details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(&text, "\n%s: %s", key, event.Details[key])
+	}
+
+	return text.String()
+}
@@ -2,12 +2,19 @@ package oauth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io"
 	"mime"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	portainer "github.com/portainer/portainer/api"
 
@@ -17,35 +24,149 @@ import (
 	"golang.org/x/oauth2"
 )
 
+// authorizationRequestTTL is the duration during which an authorization request can be completed
+const authorizationRequestTTL = 10 * time.Minute
+
 // Service represents a service used to authenticate users against an authorization server
-type Service struct{}
+type Service struct {
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	providers map[string]*oidcProvider
+}
 
 // NewService returns a pointer to a new instance of this service
 func NewService() *Service {
-	return &Service{}
+	return &Service{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		providers:  make(map[string]*oidcProvider),
+	}
+}
+
+// ValidateSettings validates the OpenID Connect issuer URL and the group mappings of the OAuth settings
+func ValidateSettings(configuration portainer.OAuthSettings) error {
+	if configuration.OIDCIssuerURL != "" {
+		issuerURL, err := url.Parse(configuration.OIDCIssuerURL)
+		if err != nil || (issuerURL.Scheme != "https" && issuerURL.Scheme != "http") || issuerURL.Host == "" {
+			return errors.New("invalid OpenID Connect issuer URL. Must correspond to a valid URL format")
+		}
+	}
+
+	for _, mapping := range configuration.GroupMappings {
+		if _, err := regexp.Compile(mapping.Group); err != nil {
+			return errors.Wrapf(err, "invalid group mapping %q", mapping.Group)
+		}
+
+		if mapping.TeamID == 0 {
+			return fmt.Errorf("invalid group mapping %q. The team is required", mapping.Group)
+		}
+	}
+
+	return nil
+}
+
+// AuthorizationURL returns the URL of the authorization server the user is redirected to in order to log in,
+// along with the authorization request which must be kept by the client of the user until the login is completed.
+// The state of the request is generated here, the request is protected by PKCE and, with an OpenID Connect provider,
+// by a nonce. The authorization code is then exchanged by AuthenticateUser with the same authorization request.
+func (service *Service) AuthorizationURL(configuration *portainer.OAuthSettings) (string, *portainer.OAuthAuthorizationRequest, error) {
+	resolved, _, err := service.resolveConfiguration(configuration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	request := &portainer.OAuthAuthorizationRequest{ExpiresAt: time.Now().Add(authorizationRequestTTL).Unix()}
+	for _, value := range []*string{&request.State, &request.CodeVerifier, &request.Nonce} {
+		*value, err = randomString()
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	challenge := sha256.Sum256([]byte(request.CodeVerifier))
+	options := []oauth2.AuthCodeOption{
+		oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+		oauth2.SetAuthURLParam("nonce", request.Nonce),
+	}
+
+	// control prompt=login param according to the SSO setting
+	if !configuration.SSO {
+		options = append(options, oauth2.SetAuthURLParam("prompt", "login"))
+	}
+
+	return buildConfig(resolved).AuthCodeURL(request.State, options...), request, nil
 }
 
 // Authenticate takes an access code and exchanges it for an access token from portainer OAuthSettings token environment(endpoint).
 // On success, it will then return the username and token expiry time associated to authenticated user by fetching this information
 // from the resource server and matching it with the user identifier setting.
-func (*Service) Authenticate(code string, configuration *portainer.OAuthSettings) (string, error) {
-	token, err := getOAuthToken(code, configuration)
+func (service *Service) Authenticate(code string, configuration *portainer.OAuthSettings) (string, error) {
+	user, err := service.AuthenticateUser(code, nil, configuration)
 	if err != nil {
-		log.Debug().Err(err).Msg("failed retrieving oauth token")
-
 		return "", err
 	}
 
-	idToken, err := getIdToken(token)
+	return user.Username, nil
+}
+
+// AuthenticateUser exchanges the access code for an access token and returns the username and the groups of the user.
+// The authorization request created by AuthorizationURL is required with an OpenID Connect provider whose ID token
+// is then validated against the signing keys of the provider.
+func (service *Service) AuthenticateUser(code string, request *portainer.OAuthAuthorizationRequest, configuration *portainer.OAuthSettings) (*portainer.OAuthUserInfo, error) {
+	resolved, provider, err := service.resolveConfiguration(configuration)
 	if err != nil {
-		log.Debug().Err(err).Msg("failed parsing id_token")
+		return nil, err
+	}
+
+	if request != nil && time.Now().Unix() > request.ExpiresAt {
+		return nil, errors.New("expired OAuth authorization request")
+	}
+
+	if provider != nil && request == nil {
+		return nil, errors.New("missing OAuth authorization request")
+	}
+
+	var options []oauth2.AuthCodeOption
+	if request != nil {
+		options = append(options, oauth2.SetAuthURLParam("code_verifier", request.CodeVerifier))
 	}
 
-	resource, err := getResource(token.AccessToken, configuration)
+	token, err := getOAuthToken(code, resolved, options...)
 	if err != nil {
-		log.Debug().Err(err).Msg("failed retrieving resource")
+		log.Debug().Err(err).Msg("failed retrieving oauth token")
 
-		return "", err
+		return nil, err
+	}
+
+	var idToken map[string]interface{}
+	if provider != nil {
+		rawIDToken, _ := token.Extra("id_token").(string)
+		if rawIDToken == "" {
+			return nil, errors.New("the OpenID Connect provider did not return an id_token")
+		}
+
+		idToken, err = service.verifyIDToken(provider, rawIDToken, configuration.ClientID, request.Nonce)
+		if err != nil {
+			log.Debug().Err(err).Msg("failed verifying id_token")
+
+			return nil, err
+		}
+	} else {
+		idToken, err = getIdToken(token)
+		if err != nil {
+			log.Debug().Err(err).Msg("failed parsing id_token")
+		}
+	}
+
+	resource := make(map[string]interface{})
+	if provider == nil || resolved.ResourceURI != "" {
+		resource, err = getResource(token.AccessToken, resolved)
+		if err != nil {
+			log.Debug().Err(err).Msg("failed retrieving resource")
+
+			return nil, err
+		}
 	}
 
 	resource = mergeSecondIntoFirst(idToken, resource)
@@ -54,10 +175,53 @@ func (*Service) Authenticate(code string, configuration *portainer.OAuthSettings
 	if err != nil {
 		log.Debug().Err(err).Msg("failed retrieving username")
 
+		return nil, err
+	}
+
+	return &portainer.OAuthUserInfo{
+		Username: username,
+		Groups:   getGroups(resource, configuration.GroupsClaim),
+	}, nil
+}
+
+// resolveConfiguration returns a copy of the settings completed with the endpoints of the OpenID Connect provider
+// when an issuer URL is configured, the provider is nil otherwise
+func (service *Service) resolveConfiguration(configuration *portainer.OAuthSettings) (*portainer.OAuthSettings, *oidcProvider, error) {
+	resolved := *configuration
+	if configuration.OIDCIssuerURL == "" {
+		return &resolved, nil, nil
+	}
+
+	provider, err := service.provider(configuration.OIDCIssuerURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resolved.AuthorizationURI = provider.metadata.AuthorizationEndpoint
+	resolved.AccessTokenURI = provider.metadata.TokenEndpoint
+	if resolved.ResourceURI == "" {
+		resolved.ResourceURI = provider.metadata.UserinfoEndpoint
+	}
+
+	hasOpenIDScope := false
+	for _, scope := range strings.Split(resolved.Scopes, ",") {
+		hasOpenIDScope = hasOpenIDScope || strings.TrimSpace(scope) == "openid"
+	}
+
+	if !hasOpenIDScope {
+		resolved.Scopes = strings.Trim("openid,"+resolved.Scopes, ",")
+	}
+
+	return &resolved, provider, nil
+}
+
+func randomString() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
 		return "", err
 	}
 
-	return username, nil
+	return base64.RawURLEncoding.EncodeToString(data), nil
 }
 
 // mergeSecondIntoFirst merges the overlap map into the base overwriting any existing values.
@@ -69,14 +233,14 @@ func mergeSecondIntoFirst(base map[string]interface{}, overlap map[string]interf
 	return base
 }
 
-func getOAuthToken(code string, configuration *portainer.OAuthSettings) (*oauth2.Token, error) {
+func getOAuthToken(code string, configuration *portainer.OAuthSettings, options ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
 	unescapedCode, err := url.QueryUnescape(code)
 	if err != nil {
 		return nil, err
 	}
 
 	config := buildConfig(configuration)
-	token, err := config.Exchange(context.Background(), unescapedCode)
+	token, err := config.Exchange(context.Background(), unescapedCode, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -3,6 +3,7 @@ package oauth
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	portainer "github.com/portainer/portainer/api"
 )
@@ -22,3 +23,39 @@ func getUsername(datamap map[string]interface{}, configuration *portainer.OAuthS
 
 	return "", errors.New("failed to extract username from oauth resource")
 }
+
+// getGroups returns the groups listed by the claim, nested claims are separated by dots.
+// The claim can either be a list of groups or a comma separated string.
+func getGroups(datamap map[string]interface{}, claim string) []string {
+	if claim == "" {
+		return nil
+	}
+
+	var value interface{} = datamap
+	for _, key := range strings.Split(claim, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		value = object[key]
+	}
+
+	var groups []string
+	switch value := value.(type) {
+	case []interface{}:
+		for _, group := range value {
+			if group, ok := group.(string); ok && group != "" {
+				groups = append(groups, group)
+			}
+		}
+	case string:
+		for _, group := range strings.Split(value, ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	return groups
+}
@@ -0,0 +1,179 @@
+package oauthtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/mux"
+	portainer "github.com/portainer/portainer/api"
+)
+
+const (
+	// SigningKeyID is the identifier of the key signing the ID tokens of the OpenID Connect test server
+	SigningKeyID = "test-key"
+)
+
+type oidcAuthorization struct {
+	codeChallenge string
+	nonce         string
+}
+
+// OIDCRoutes is an OpenID Connect compliant handler supporting the discovery, PKCE and signed ID tokens.
+// The ID tokens contain the claims, the same claims are returned by the userinfo endpoint.
+func OIDCRoutes(issuer, code string, key *rsa.PrivateKey, claims map[string]interface{}) http.Handler {
+	router := mux.NewRouter()
+
+	var mu sync.Mutex
+	var authorization oidcAuthorization
+
+	router.HandleFunc(
+		"/.well-known/openid-configuration",
+		func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"issuer":                 issuer,
+				"authorization_endpoint": issuer + "/authorize",
+				"token_endpoint":         issuer + "/token",
+				"userinfo_endpoint":      issuer + "/userinfo",
+				"jwks_uri":               issuer + "/jwks",
+			})
+		},
+	).Methods(http.MethodGet)
+
+	router.HandleFunc(
+		"/jwks",
+		func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"keys": []map[string]string{{
+					"kid": SigningKeyID,
+					"kty": "RSA",
+					"use": "sig",
+					"alg": "RS256",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				}},
+			})
+		},
+	).Methods(http.MethodGet)
+
+	router.HandleFunc(
+		"/authorize",
+		func(w http.ResponseWriter, req *http.Request) {
+			query := req.URL.Query()
+
+			mu.Lock()
+			authorization = oidcAuthorization{
+				codeChallenge: query.Get("code_challenge"),
+				nonce:         query.Get("nonce"),
+			}
+			mu.Unlock()
+
+			location := fmt.Sprintf("%s?code=%s&state=%s", query.Get("redirect_uri"), code, url.QueryEscape(query.Get("state")))
+			http.Redirect(w, req, location, http.StatusFound)
+		},
+	).Methods(http.MethodGet)
+
+	router.HandleFunc(
+		"/token",
+		func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+
+			if err := req.ParseForm(); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			mu.Lock()
+			current := authorization
+			mu.Unlock()
+
+			verifier := sha256.Sum256([]byte(req.FormValue("code_verifier")))
+			if req.FormValue("code") != code || base64.RawURLEncoding.EncodeToString(verifier[:]) != current.codeChallenge {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+
+			clientID, _, ok := req.BasicAuth()
+			if !ok {
+				clientID = req.FormValue("client_id")
+			}
+
+			idTokenClaims := jwt.MapClaims{
+				"iss":   issuer,
+				"aud":   clientID,
+				"iat":   time.Now().Unix(),
+				"exp":   time.Now().Add(time.Hour).Unix(),
+				"nonce": current.nonce,
+			}
+			for k, v := range claims {
+				idTokenClaims[k] = v
+			}
+
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, idTokenClaims)
+			token.Header["kid"] = SigningKeyID
+
+			idToken, err := token.SignedString(key)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"token_type":   "Bearer",
+				"expires_in":   86400,
+				"access_token": AccessToken,
+				"id_token":     idToken,
+			})
+		},
+	).Methods(http.MethodPost)
+
+	router.HandleFunc(
+		"/userinfo",
+		func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+
+			if req.Header.Get("Authorization") != "Bearer "+AccessToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			json.NewEncoder(w).Encode(claims)
+		},
+	).Methods(http.MethodGet)
+
+	return router
+}
+
+// RunOIDCServer is a barebones OpenID Connect test server which can be used to test the OpenID Connect functionality.
+// The issuer URL and the redirect URI of the configuration are set to the ones of the server.
+func RunOIDCServer(code string, config *portainer.OAuthSettings, claims map[string]interface{}) (*httptest.Server, *portainer.OAuthSettings) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.DefaultServeMux)
+
+	issuer := fmt.Sprintf("http://%s", srv.Listener.Addr())
+
+	config.OIDCIssuerURL = issuer
+	config.RedirectURI = issuer + "/"
+
+	srv.Config.Handler = OIDCRoutes(issuer, code, key, claims)
+	srv.Start()
+
+	return srv, config
+}
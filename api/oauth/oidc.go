@@ -0,0 +1,251 @@
+package oauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+)
+
+// providerCacheTTL is the duration during which the discovered configuration of a provider is reused
+const providerCacheTTL = time.Hour
+
+type (
+	// providerMetadata is the subset of the OpenID Connect discovery document used by Portainer
+	providerMetadata struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserinfoEndpoint      string `json:"userinfo_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+		EndSessionEndpoint    string `json:"end_session_endpoint"`
+	}
+
+	// oidcProvider holds the discovered configuration and the signing keys of an OpenID Connect provider
+	oidcProvider struct {
+		metadata     providerMetadata
+		discoveredAt time.Time
+
+		mu   sync.Mutex
+		keys map[string]interface{}
+	}
+
+	jsonWebKey struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		Crv string `json:"crv"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}
+)
+
+// provider returns the OpenID Connect provider of the issuer, its configuration is discovered
+// from the issuer and cached
+func (service *Service) provider(issuer string) (*oidcProvider, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+
+	service.mu.Lock()
+	provider, ok := service.providers[issuer]
+	service.mu.Unlock()
+
+	if ok && time.Since(provider.discoveredAt) < providerCacheTTL {
+		return provider, nil
+	}
+
+	var metadata providerMetadata
+	err := getJSON(service.httpClient, issuer+"/.well-known/openid-configuration", &metadata)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to discover the OpenID Connect provider")
+	}
+
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("the issuer of the discovery document %q does not match the issuer URL %q", metadata.Issuer, issuer)
+	}
+
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("the discovery document of the OpenID Connect provider is incomplete")
+	}
+
+	provider = &oidcProvider{metadata: metadata, discoveredAt: time.Now()}
+
+	service.mu.Lock()
+	service.providers[issuer] = provider
+	service.mu.Unlock()
+
+	return provider, nil
+}
+
+// verifyIDToken validates the signature of the ID token against the keys of the provider, its issuer,
+// its audience, its expiry and its nonce, and returns its claims
+func (service *Service) verifyIDToken(provider *oidcProvider, rawIDToken, clientID, nonce string) (map[string]interface{}, error) {
+	claims := jwt.MapClaims{}
+
+	parser := jwt.Parser{ValidMethods: []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}}
+	_, err := parser.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return service.signingKey(provider, kid)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid id_token")
+	}
+
+	if !claims.VerifyIssuer(provider.metadata.Issuer, true) {
+		return nil, errors.New("invalid id_token issuer")
+	}
+
+	if !claims.VerifyAudience(clientID, true) {
+		return nil, errors.New("invalid id_token audience")
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); nonce != "" && tokenNonce != nonce {
+		return nil, errors.New("invalid id_token nonce")
+	}
+
+	return claims, nil
+}
+
+// signingKey returns the key of the provider with the identifier, the keys are fetched again
+// when the key is unknown to support the rotation of the keys
+func (service *Service) signingKey(provider *oidcProvider, kid string) (interface{}, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if key, ok := lookupKey(provider.keys, kid); ok {
+		return key, nil
+	}
+
+	keys, err := fetchKeys(service.httpClient, provider.metadata.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	provider.keys = keys
+
+	if key, ok := lookupKey(provider.keys, kid); ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey returns the key with the identifier, or the only key of the set when the identifier is empty
+func lookupKey(keys map[string]interface{}, kid string) (interface{}, bool) {
+	if key, ok := keys[kid]; ok {
+		return key, true
+	}
+
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+
+	return nil, false
+}
+
+func fetchKeys(client *http.Client, jwksURI string) (map[string]interface{}, error) {
+	var keySet struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+
+	err := getJSON(client, jwksURI, &keySet)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve the signing keys of the OpenID Connect provider")
+	}
+
+	keys := make(map[string]interface{})
+	for _, jwk := range keySet.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			// the keys of an unsupported type are ignored
+			continue
+		}
+
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func (jwk jsonWebKey) publicKey() (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
+
+func getJSON(client *http.Client, url string, target interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
@@ -0,0 +1,193 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/oauth/oauthtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AuthenticateUser_OIDC(t *testing.T) {
+	is := assert.New(t)
+
+	code := "valid-code"
+	claims := map[string]interface{}{
+		"sub":                "1234",
+		"preferred_username": "oidc-user",
+		"groups":             []string{"developers", "operators"},
+	}
+
+	srv, config := oauthtest.RunOIDCServer(code, &portainer.OAuthSettings{
+		ClientID:       "portainer",
+		ClientSecret:   "secret",
+		UserIdentifier: "preferred_username",
+		GroupsClaim:    "groups",
+		Scopes:         "profile,groups",
+	}, claims)
+	defer srv.Close()
+
+	service := NewService()
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	// authorize follows the redirections of the authorization request and returns the code, the state and the request
+	authorize := func() (string, string, *portainer.OAuthAuthorizationRequest) {
+		authorizationURL, request, err := service.AuthorizationURL(config)
+		require.NoError(t, err)
+
+		query, err := url.Parse(authorizationURL)
+		require.NoError(t, err)
+		is.Equal("S256", query.Query().Get("code_challenge_method"))
+		is.NotEmpty(query.Query().Get("code_challenge"))
+		is.NotEmpty(query.Query().Get("nonce"))
+		is.Equal("openid profile groups", query.Query().Get("scope"))
+
+		resp, err := client.Get(authorizationURL)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		location, err := url.Parse(resp.Header.Get("Location"))
+		require.NoError(t, err)
+
+		return location.Query().Get("code"), location.Query().Get("state"), request
+	}
+
+	t.Run("should authenticate the user and extract its groups", func(t *testing.T) {
+		code, state, request := authorize()
+		is.NotEmpty(state)
+		is.Equal(request.State, state, "the state should be generated by the service")
+
+		user, err := service.AuthenticateUser(code, request, config)
+		require.NoError(t, err)
+		is.Equal("oidc-user", user.Username)
+		is.Equal([]string{"developers", "operators"}, user.Groups)
+	})
+
+	t.Run("should fail without the authorization request", func(t *testing.T) {
+		code, _, _ := authorize()
+
+		_, err := service.AuthenticateUser(code, nil, config)
+		is.Error(err)
+	})
+
+	t.Run("should fail with an expired authorization request", func(t *testing.T) {
+		code, _, request := authorize()
+		request.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+
+		_, err := service.AuthenticateUser(code, request, config)
+		is.Error(err)
+	})
+
+	t.Run("should fail with another code verifier", func(t *testing.T) {
+		code, _, request := authorize()
+		request.CodeVerifier = "another-verifier"
+
+		_, err := service.AuthenticateUser(code, request, config)
+		is.Error(err)
+	})
+
+	t.Run("should reject an id_token that is not signed by the provider", func(t *testing.T) {
+		provider, err := service.provider(config.OIDCIssuerURL)
+		require.NoError(t, err)
+
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss": config.OIDCIssuerURL,
+			"aud": config.ClientID,
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		token.Header["kid"] = oauthtest.SigningKeyID
+
+		idToken, err := token.SignedString(key)
+		require.NoError(t, err)
+
+		_, err = service.verifyIDToken(provider, idToken, config.ClientID, "")
+		is.Error(err)
+	})
+}
+
+func Test_getGroups(t *testing.T) {
+	is := assert.New(t)
+
+	resource := map[string]interface{}{
+		"groups":       []interface{}{"a", "b", 1},
+		"roles":        "admins, users",
+		"realm_access": map[string]interface{}{"roles": []interface{}{"c"}},
+	}
+
+	is.Equal([]string{"a", "b"}, getGroups(resource, "groups"))
+	is.Equal([]string{"admins", "users"}, getGroups(resource, "roles"))
+	is.Equal([]string{"c"}, getGroups(resource, "realm_access.roles"))
+	is.Nil(getGroups(resource, "missing.claim"))
+	is.Nil(getGroups(resource, ""))
+}
+
+func Test_ValidateSettings(t *testing.T) {
+	is := assert.New(t)
+
+	is.NoError(ValidateSettings(portainer.OAuthSettings{
+		OIDCIssuerURL: "https://accounts.example.com",
+		GroupMappings: []portainer.OAuthGroupMapping{{Group: "^admins$", TeamID: 1}},
+	}))
+	is.Error(ValidateSettings(portainer.OAuthSettings{OIDCIssuerURL: "accounts.example.com"}))
+	is.Error(ValidateSettings(portainer.OAuthSettings{GroupMappings: []portainer.OAuthGroupMapping{{Group: "(", TeamID: 1}}}))
+	is.Error(ValidateSettings(portainer.OAuthSettings{GroupMappings: []portainer.OAuthGroupMapping{{Group: "admins"}}}))
+}
+
+func Test_verifyIDToken(t *testing.T) {
+	is := assert.New(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.DefaultServeMux)
+	issuer := "http://" + srv.Listener.Addr().String()
+	srv.Config.Handler = oauthtest.OIDCRoutes(issuer, "code", key, nil)
+	srv.Start()
+	defer srv.Close()
+
+	service := NewService()
+	provider, err := service.provider(issuer)
+	require.NoError(t, err)
+
+	sign := func(claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = oauthtest.SigningKeyID
+
+		idToken, err := token.SignedString(key)
+		require.NoError(t, err)
+
+		return idToken
+	}
+
+	expiresAt := time.Now().Add(time.Hour).Unix()
+
+	claims, err := service.verifyIDToken(provider, sign(jwt.MapClaims{"iss": issuer, "aud": "portainer", "exp": expiresAt, "nonce": "n"}), "portainer", "n")
+	require.NoError(t, err)
+	is.Equal("n", claims["nonce"])
+
+	_, err = service.verifyIDToken(provider, sign(jwt.MapClaims{"iss": issuer, "aud": "another-client", "exp": expiresAt}), "portainer", "")
+	is.Error(err, "the audience should match the client identifier")
+
+	_, err = service.verifyIDToken(provider, sign(jwt.MapClaims{"iss": "http://another-issuer", "aud": "portainer", "exp": expiresAt}), "portainer", "")
+	is.Error(err, "the issuer should match the provider")
+
+	_, err = service.verifyIDToken(provider, sign(jwt.MapClaims{"iss": issuer, "aud": "portainer", "exp": expiresAt, "nonce": "other"}), "portainer", "n")
+	is.Error(err, "the nonce should match the authorization request")
+
+	_, err = service.verifyIDToken(provider, sign(jwt.MapClaims{"iss": issuer, "aud": "portainer", "exp": time.Now().Add(-time.Hour).Unix()}), "portainer", "")
+	is.Error(err, "expired tokens should be rejected")
+}
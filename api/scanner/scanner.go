@@ -0,0 +1,64 @@
+// Package scanner scans the images of the Docker environments for known vulnerabilities.
+package scanner
+
+import (
+	"context"
+	"fmt"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// Scanner finds the known vulnerabilities of an image
+type Scanner interface {
+	// Name returns the name of the scanner, it is stored along with the results
+	Name() string
+	// ScanArchive scans an image exported with docker save
+	ScanArchive(ctx context.Context, archivePath string) ([]portainer.Vulnerability, error)
+}
+
+var severityRanks = map[portainer.VulnerabilitySeverity]int{
+	portainer.VulnerabilitySeverityUnknown:  0,
+	portainer.VulnerabilitySeverityLow:      1,
+	portainer.VulnerabilitySeverityMedium:   2,
+	portainer.VulnerabilitySeverityHigh:     3,
+	portainer.VulnerabilitySeverityCritical: 4,
+}
+
+// ValidSeverity returns true when severity is one of the known severities
+func ValidSeverity(severity portainer.VulnerabilitySeverity) bool {
+	_, ok := severityRanks[severity]
+	return ok
+}
+
+// Summarize counts the vulnerabilities per severity
+func Summarize(vulnerabilities []portainer.Vulnerability) map[portainer.VulnerabilitySeverity]int {
+	summary := make(map[portainer.VulnerabilitySeverity]int)
+	for _, vulnerability := range vulnerabilities {
+		summary[vulnerability.Severity]++
+	}
+
+	return summary
+}
+
+// CountAtOrAbove returns the number of vulnerabilities of the scan whose severity is threshold or higher
+func CountAtOrAbove(scan *portainer.ImageScan, threshold portainer.VulnerabilitySeverity) int {
+	count := 0
+	for severity, n := range scan.Summary {
+		if severityRanks[severity] >= severityRanks[threshold] {
+			count += n
+		}
+	}
+
+	return count
+}
+
+// ThresholdExceededError is returned when an image has vulnerabilities at or above the blocking severity
+type ThresholdExceededError struct {
+	Image     string
+	Count     int
+	Threshold portainer.VulnerabilitySeverity
+}
+
+func (e *ThresholdExceededError) Error() string {
+	return fmt.Sprintf("image %s has %d vulnerabilities with a severity of %s or higher", e.Image, e.Count, e.Threshold)
+}
@@ -0,0 +1,138 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+	"github.com/portainer/portainer/api/docker"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseTrivyReport(t *testing.T) {
+	is := assert.New(t)
+
+	report := []byte(`{
+		"SchemaVersion": 2,
+		"Results": [
+			{
+				"Target": "alpine (alpine 3.17.1)",
+				"Vulnerabilities": [
+					{"VulnerabilityID": "CVE-2023-0286", "PkgName": "libcrypto3", "InstalledVersion": "3.0.7-r2", "FixedVersion": "3.0.8-r0", "Severity": "HIGH", "Title": "openssl: X.400 address type confusion"},
+					{"VulnerabilityID": "CVE-2023-0215", "PkgName": "libssl3", "InstalledVersion": "3.0.7-r2", "Severity": "MEDIUM"}
+				]
+			},
+			{"Target": "app/go.sum"},
+			{
+				"Target": "usr/bin/app",
+				"Vulnerabilities": [
+					{"VulnerabilityID": "GHSA-xxxx", "PkgName": "golang.org/x/net", "Severity": "SOMETHING"}
+				]
+			}
+		]
+	}`)
+
+	vulnerabilities, err := parseTrivyReport(report)
+	is.NoError(err)
+	is.Len(vulnerabilities, 3)
+	is.Equal(portainer.Vulnerability{
+		ID:               "CVE-2023-0286",
+		PkgName:          "libcrypto3",
+		InstalledVersion: "3.0.7-r2",
+		FixedVersion:     "3.0.8-r0",
+		Severity:         portainer.VulnerabilitySeverityHigh,
+		Title:            "openssl: X.400 address type confusion",
+	}, vulnerabilities[0])
+	is.Equal(portainer.VulnerabilitySeverityUnknown, vulnerabilities[2].Severity, "unknown severities should be mapped to UNKNOWN")
+
+	summary := Summarize(vulnerabilities)
+	is.Equal(map[portainer.VulnerabilitySeverity]int{
+		portainer.VulnerabilitySeverityHigh:    1,
+		portainer.VulnerabilitySeverityMedium:  1,
+		portainer.VulnerabilitySeverityUnknown: 1,
+	}, summary)
+
+	scan := &portainer.ImageScan{Summary: summary}
+	is.Equal(0, CountAtOrAbove(scan, portainer.VulnerabilitySeverityCritical))
+	is.Equal(1, CountAtOrAbove(scan, portainer.VulnerabilitySeverityHigh))
+	is.Equal(2, CountAtOrAbove(scan, portainer.VulnerabilitySeverityLow))
+	is.Equal(3, CountAtOrAbove(scan, portainer.VulnerabilitySeverityUnknown))
+}
+
+func Test_ValidateSettings(t *testing.T) {
+	is := assert.New(t)
+
+	is.NoError(ValidateSettings(portainer.VulnerabilityScanSettings{}))
+	is.NoError(ValidateSettings(portainer.VulnerabilityScanSettings{ScanInterval: "24h", BlockDeploymentSeverity: portainer.VulnerabilitySeverityCritical}))
+	is.Error(ValidateSettings(portainer.VulnerabilityScanSettings{ScanInterval: "daily"}))
+	is.Error(ValidateSettings(portainer.VulnerabilityScanSettings{ScanInterval: "5m"}))
+	is.Error(ValidateSettings(portainer.VulnerabilityScanSettings{BlockDeploymentSeverity: "SEVERE"}))
+}
+
+func Test_registryAuth(t *testing.T) {
+	is := assert.New(t)
+
+	service := &Service{}
+	registries := []portainer.Registry{
+		{Name: "anonymous", URL: "quay.io"},
+		{Name: "private", URL: "https://registry.example.com:5000/team", Authentication: true, Username: "user", Password: "pass"},
+	}
+
+	is.NotEmpty(service.registryAuth("registry.example.com:5000/team/app:1.0", registries))
+	is.Empty(service.registryAuth("quay.io/app:1.0", registries), "the registries without authentication should be ignored")
+	is.Empty(service.registryAuth("nginx:latest", registries))
+}
+
+type testScanner struct{}
+
+func (testScanner) Name() string {
+	return "test"
+}
+
+func (testScanner) ScanArchive(ctx context.Context, archivePath string) ([]portainer.Vulnerability, error) {
+	return []portainer.Vulnerability{{ID: "CVE-2023-0001", Severity: portainer.VulnerabilitySeverityHigh}}, nil
+}
+
+func TestScanEndpointImages_ContinuesAfterAnImageError(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, true)
+	defer teardown()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/images/json"):
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `[{"Id":"sha256:broken","RepoTags":["broken:latest"]},{"Id":"sha256:nginx","RepoTags":["nginx:latest"]}]`)
+		case strings.HasSuffix(r.URL.Path, "/images/get") && r.URL.Query().Get("names") == "sha256:nginx":
+			fmt.Fprint(w, "archive")
+		default:
+			http.Error(w, "unable to export the image", http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	endpoint := &portainer.Endpoint{ID: 1, Name: "local", Type: portainer.DockerEnvironment, URL: strings.Replace(server.URL, "http://", "tcp://", 1)}
+
+	service := NewService(testScanner{}, store, docker.NewClientFactory(nil, nil), nil, nil)
+
+	images, err := service.ScanEndpointImages(context.Background(), endpoint, nil, 0)
+	require.NoError(t, err)
+	require.Len(t, images, 2)
+
+	is.Equal("sha256:broken", images[0].ImageID)
+	is.NotEmpty(images[0].Error)
+	is.Nil(images[0].Scan)
+
+	is.Equal("sha256:nginx", images[1].ImageID, "the images after a failure should still be scanned")
+	is.Empty(images[1].Error)
+	require.NotNil(t, images[1].Scan)
+	is.Equal(1, images[1].Scan.Summary[portainer.VulnerabilitySeverityHigh])
+}
@@ -0,0 +1,403 @@
+package scanner
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/internal/endpointutils"
+	"github.com/portainer/portainer/api/internal/registryutils"
+	"github.com/portainer/portainer/api/scheduler"
+	"github.com/portainer/portainer/api/stacks/stackutils"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// EndpointImage represents an image of an environment along with its last vulnerability scan
+type EndpointImage struct {
+	// Image identifier
+	ImageID string `json:"ImageId" example:"sha256:89a8ad3a2e8cd6e23e3b8a7ef0b4c0c5c8c4d3a0f5c7b1d1b1e9c3e0f5d6e7a8"`
+	// Tags of the image
+	RepoTags []string `json:"RepoTags" example:"nginx:latest"`
+	// Last scan of the image, null when the image was never scanned
+	Scan *portainer.ImageScan `json:"Scan"`
+	// Error of the last scan attempt, the previous scan is kept when the image could not be scanned again
+	Error string `json:"Error,omitempty" example:"unable to export the image"`
+}
+
+// Service scans the images of the Docker environments and stores the results per image identifier
+type Service struct {
+	scanner       Scanner
+	dataStore     dataservices.DataStore
+	clientFactory *docker.ClientFactory
+	fileService   portainer.FileService
+	scheduler     *scheduler.Scheduler
+
+	// the scans are resource intensive, a single image is scanned at a time
+	scanMu sync.Mutex
+
+	mu    sync.Mutex
+	jobID string
+}
+
+// NewService initializes a new image scanning service
+func NewService(scanner Scanner, dataStore dataservices.DataStore, clientFactory *docker.ClientFactory, fileService portainer.FileService, scheduler *scheduler.Scheduler) *Service {
+	return &Service{
+		scanner:       scanner,
+		dataStore:     dataStore,
+		clientFactory: clientFactory,
+		fileService:   fileService,
+		scheduler:     scheduler,
+	}
+}
+
+// ValidateSettings returns an error when the scan settings cannot be applied
+func ValidateSettings(settings portainer.VulnerabilityScanSettings) error {
+	if settings.ScanInterval != "" {
+		interval, err := time.ParseDuration(settings.ScanInterval)
+		if err != nil {
+			return errors.Wrap(err, "Invalid scan interval")
+		}
+
+		if interval < time.Hour {
+			return errors.New("Invalid scan interval, the images cannot be scanned more than once an hour")
+		}
+	}
+
+	if settings.BlockDeploymentSeverity != "" && !ValidSeverity(settings.BlockDeploymentSeverity) {
+		return errors.New("Invalid severity, value must be one of: UNKNOWN, LOW, MEDIUM, HIGH or CRITICAL")
+	}
+
+	return nil
+}
+
+// Start schedules the scans with the interval stored in the settings
+func (service *Service) Start() error {
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	return service.Reschedule(settings.VulnerabilityScanSettings)
+}
+
+// Reschedule replaces the current scan schedule with the one described by the given settings
+func (service *Service) Reschedule(settings portainer.VulnerabilityScanSettings) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if service.jobID != "" {
+		err := service.scheduler.StopJob(service.jobID)
+		if err != nil {
+			return err
+		}
+
+		service.jobID = ""
+	}
+
+	if settings.ScanInterval == "" {
+		return nil
+	}
+
+	interval, err := time.ParseDuration(settings.ScanInterval)
+	if err != nil {
+		return errors.Wrapf(err, "invalid scan interval %q", settings.ScanInterval)
+	}
+
+	service.jobID = service.scheduler.StartJobEvery(interval, func() error {
+		service.scanEndpoints(interval)
+
+		// the job must keep running even if some environments could not be scanned
+		return nil
+	})
+
+	return nil
+}
+
+// scanEndpoints scans the images of every reachable Docker environment that were not scanned during the last interval.
+// The Edge environments are skipped as they can only be reached while their tunnel is open.
+func (service *Service) scanEndpoints(interval time.Duration) {
+	endpoints, err := service.dataStore.Endpoint().Endpoints()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to retrieve the environments to scan")
+		return
+	}
+
+	for i := range endpoints {
+		endpoint := &endpoints[i]
+		if !endpointutils.IsDockerEndpoint(endpoint) || endpointutils.IsEdgeEndpoint(endpoint) || endpoint.Status != portainer.EndpointStatusUp {
+			continue
+		}
+
+		_, err := service.ScanEndpointImages(context.Background(), endpoint, nil, interval)
+		if err != nil {
+			log.Warn().Err(err).Int("endpoint_id", int(endpoint.ID)).Msg("unable to scan the images of the environment")
+		}
+	}
+}
+
+// EndpointImages returns the images of an environment along with their last scan
+func (service *Service) EndpointImages(ctx context.Context, endpoint *portainer.Endpoint) ([]EndpointImage, error) {
+	cli, err := service.clientFactory.CreateClient(endpoint, "", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to connect to the Docker daemon")
+	}
+	defer cli.Close()
+
+	images, err := cli.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list the images")
+	}
+
+	result := make([]EndpointImage, 0, len(images))
+	for _, image := range images {
+		scan, err := service.dataStore.ImageScan().ImageScan(image.ID)
+		if err != nil && !service.dataStore.IsErrObjectNotFound(err) {
+			return nil, err
+		}
+
+		result = append(result, EndpointImage{ImageID: image.ID, RepoTags: image.RepoTags, Scan: scan})
+	}
+
+	return result, nil
+}
+
+// ScanEndpointImages scans the images of an environment whose last scan is older than maxAge,
+// every image is scanned when maxAge is 0. Only the images listed in imageIDs are scanned when it is not empty.
+// An image that cannot be scanned does not stop the scan of the others, its error is returned along with the image.
+func (service *Service) ScanEndpointImages(ctx context.Context, endpoint *portainer.Endpoint, imageIDs []string, maxAge time.Duration) ([]EndpointImage, error) {
+	cli, err := service.clientFactory.CreateClient(endpoint, "", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to connect to the Docker daemon")
+	}
+	defer cli.Close()
+
+	images, err := cli.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list the images")
+	}
+
+	selected := make(map[string]bool, len(imageIDs))
+	for _, id := range imageIDs {
+		selected[id] = true
+	}
+
+	result := make([]EndpointImage, 0)
+	for _, image := range images {
+		if len(selected) > 0 && !selected[image.ID] {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		endpointImage := EndpointImage{ImageID: image.ID, RepoTags: image.RepoTags}
+
+		scan, err := service.dataStore.ImageScan().ImageScan(image.ID)
+		if err != nil && !service.dataStore.IsErrObjectNotFound(err) {
+			log.Warn().Err(err).Str("image", image.ID).Msg("unable to retrieve the last scan of the image")
+
+			endpointImage.Error = err.Error()
+			result = append(result, endpointImage)
+
+			continue
+		}
+		endpointImage.Scan = scan
+
+		if scan == nil || maxAge == 0 || time.Since(time.Unix(scan.ScannedAt, 0)) >= maxAge {
+			scan, err := service.scanImage(ctx, cli, image.ID, image.RepoTags)
+			if err != nil {
+				log.Warn().Err(err).Str("image", image.ID).Msg("unable to scan the image")
+
+				endpointImage.Error = err.Error()
+			} else {
+				endpointImage.Scan = scan
+			}
+		}
+
+		result = append(result, endpointImage)
+	}
+
+	return result, nil
+}
+
+// CheckStackImages returns a ThresholdExceededError when an image of the stack has vulnerabilities
+// at or above the blocking severity of the settings. The images that were not scanned yet are scanned first.
+// The images which are not present on the environment are pulled with the credentials of the matching registry,
+// every image is pulled again when pull is set. The check fails when an image cannot be pulled or scanned.
+func (service *Service) CheckStackImages(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, pull bool) error {
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	threshold := settings.VulnerabilityScanSettings.BlockDeploymentSeverity
+	if threshold == "" {
+		return nil
+	}
+
+	images, err := stackutils.GetStackImages(stack, service.fileService)
+	if err != nil {
+		return err
+	}
+
+	cli, err := service.clientFactory.CreateClient(endpoint, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "unable to connect to the Docker daemon")
+	}
+	defer cli.Close()
+
+	for _, image := range images {
+		inspect, err := service.inspectOrPullImage(ctx, cli, image, registries, pull)
+		if err != nil {
+			return err
+		}
+
+		scan, err := service.dataStore.ImageScan().ImageScan(inspect.ID)
+		if service.dataStore.IsErrObjectNotFound(err) {
+			scan, err = service.scanImage(ctx, cli, inspect.ID, inspect.RepoTags)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "unable to scan image %s", image)
+		}
+
+		if count := CountAtOrAbove(scan, threshold); count > 0 {
+			return &ThresholdExceededError{Image: image, Count: count, Threshold: threshold}
+		}
+	}
+
+	return nil
+}
+
+// inspectOrPullImage inspects an image of a stack, the image is pulled first when it is not present or when pull is set
+func (service *Service) inspectOrPullImage(ctx context.Context, cli *client.Client, image string, registries []portainer.Registry, pull bool) (*types.ImageInspect, error) {
+	if !pull {
+		inspect, _, err := cli.ImageInspectWithRaw(ctx, image)
+		if err == nil {
+			return &inspect, nil
+		}
+
+		if !client.IsErrNotFound(err) {
+			return nil, errors.Wrapf(err, "unable to inspect image %s", image)
+		}
+	}
+
+	log.Debug().Str("image", image).Msg("pulling the image to check its vulnerabilities")
+
+	err := pullImage(ctx, cli, image, service.registryAuth(image, registries))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to pull image %s to check its vulnerabilities", image)
+	}
+
+	inspect, _, err := cli.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to inspect image %s", image)
+	}
+
+	return &inspect, nil
+}
+
+// registryAuth returns the authentication header of the registry hosting an image, if any
+func (service *Service) registryAuth(image string, registries []portainer.Registry) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ""
+	}
+
+	domain := reference.Domain(named)
+	for i := range registries {
+		registry := &registries[i]
+		host := strings.TrimPrefix(strings.TrimPrefix(registry.URL, "https://"), "http://")
+		if !registry.Authentication || strings.Split(host, "/")[0] != domain {
+			continue
+		}
+
+		registryutils.EnsureRegTokenValid(service.dataStore, registry)
+
+		header, err := registryutils.GetRegistryAuthHeader(registry)
+		if err != nil {
+			log.Warn().Err(err).Str("registry", registry.Name).Msg("unable to build the registry authentication header")
+			return ""
+		}
+
+		return header
+	}
+
+	return ""
+}
+
+func (service *Service) scanImage(ctx context.Context, cli *client.Client, imageID string, repoTags []string) (*portainer.ImageScan, error) {
+	service.scanMu.Lock()
+	defer service.scanMu.Unlock()
+
+	archive, err := os.CreateTemp("", "portainer-image-scan-*.tar")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(archive.Name())
+
+	err = saveImage(ctx, cli, imageID, archive)
+	archive.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to export the image")
+	}
+
+	vulnerabilities, err := service.scanner.ScanArchive(ctx, archive.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	scan := &portainer.ImageScan{
+		ImageID:         imageID,
+		RepoTags:        repoTags,
+		Scanner:         service.scanner.Name(),
+		ScannedAt:       time.Now().Unix(),
+		Summary:         Summarize(vulnerabilities),
+		Vulnerabilities: vulnerabilities,
+	}
+
+	err = service.dataStore.ImageScan().UpdateImageScan(scan)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to persist the scan result")
+	}
+
+	log.Debug().Str("image", imageID).Int("vulnerabilities", len(vulnerabilities)).Msg("image scanned")
+
+	return scan, nil
+}
+
+func pullImage(ctx context.Context, cli *client.Client, image, registryAuth string) error {
+	reader, err := cli.ImagePull(ctx, image, types.ImagePullOptions{RegistryAuth: registryAuth})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	// the pull is only complete once the progress stream has been consumed
+	_, err = io.Copy(io.Discard, reader)
+
+	return err
+}
+
+func saveImage(ctx context.Context, cli *client.Client, imageID string, w io.Writer) error {
+	reader, err := cli.ImageSave(ctx, []string{imageID})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	_, err = io.Copy(w, reader)
+
+	return err
+}
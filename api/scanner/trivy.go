@@ -0,0 +1,105 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/pkg/errors"
+)
+
+// trivyScanner is a wrapper for the trivy binary which implements Scanner
+type trivyScanner struct {
+	binaryPath string
+	cachePath  string
+}
+
+// NewTrivyScanner initializes a Scanner running the trivy binary stored in binaryPath.
+// The vulnerability database is downloaded by trivy in cachePath.
+func NewTrivyScanner(binaryPath, cachePath string) *trivyScanner {
+	return &trivyScanner{binaryPath: binaryPath, cachePath: cachePath}
+}
+
+func (scanner *trivyScanner) Name() string {
+	return "trivy"
+}
+
+type trivyReport struct {
+	Results []struct {
+		Target          string `json:"Target"`
+		Vulnerabilities []struct {
+			VulnerabilityID  string `json:"VulnerabilityID"`
+			PkgName          string `json:"PkgName"`
+			InstalledVersion string `json:"InstalledVersion"`
+			FixedVersion     string `json:"FixedVersion"`
+			Severity         string `json:"Severity"`
+			Title            string `json:"Title"`
+			PrimaryURL       string `json:"PrimaryURL"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+func (scanner *trivyScanner) ScanArchive(ctx context.Context, archivePath string) ([]portainer.Vulnerability, error) {
+	output, err := scanner.run(ctx, "image", "--input", archivePath, "--format", "json", "--quiet", "--cache-dir", scanner.cachePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTrivyReport(output)
+}
+
+// run executes a trivy command and returns its standard output
+func (scanner *trivyScanner) run(ctx context.Context, args ...string) ([]byte, error) {
+	trivyPath := path.Join(scanner.binaryPath, "trivy")
+	if runtime.GOOS == "windows" {
+		trivyPath = path.Join(scanner.binaryPath, "trivy.exe")
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, trivyPath, args...)
+	cmd.Stderr = &stderr
+	cmd.Env = os.Environ()
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrap(err, stderr.String())
+	}
+
+	return output, nil
+}
+
+func parseTrivyReport(output []byte) ([]portainer.Vulnerability, error) {
+	var report trivyReport
+	err := json.Unmarshal(output, &report)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse the trivy report")
+	}
+
+	vulnerabilities := make([]portainer.Vulnerability, 0)
+	for _, result := range report.Results {
+		for _, v := range result.Vulnerabilities {
+			severity := portainer.VulnerabilitySeverity(v.Severity)
+			if !ValidSeverity(severity) {
+				severity = portainer.VulnerabilitySeverityUnknown
+			}
+
+			vulnerabilities = append(vulnerabilities, portainer.Vulnerability{
+				ID:               v.VulnerabilityID,
+				PkgName:          v.PkgName,
+				InstalledVersion: v.InstalledVersion,
+				FixedVersion:     v.FixedVersion,
+				Severity:         severity,
+				Title:            v.Title,
+				PrimaryURL:       v.PrimaryURL,
+			})
+		}
+	}
+
+	return vulnerabilities, nil
+}
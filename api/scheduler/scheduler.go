@@ -82,6 +82,22 @@ func (s *Scheduler) StopJob(jobID string) error {
 // Returns job id that could be used to stop the given job.
 // When job run returns an error, that job won't be run again.
 func (s *Scheduler) StartJobEvery(duration time.Duration, job func() error) string {
+	return s.startJob(cron.Every(duration), job)
+}
+
+// StartJobWithCron schedules a new job running according to a standard cron expression.
+// Returns job id that could be used to stop the given job.
+// When job run returns an error, that job won't be run again.
+func (s *Scheduler) StartJobWithCron(cronExpression string, job func() error) (string, error) {
+	schedule, err := cron.ParseStandard(cronExpression)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid cron expression %q", cronExpression)
+	}
+
+	return s.startJob(schedule, job), nil
+}
+
+func (s *Scheduler) startJob(schedule cron.Schedule, job func() error) string {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	j := cron.FuncJob(func() {
@@ -91,7 +107,7 @@ func (s *Scheduler) StartJobEvery(duration time.Duration, job func() error) stri
 		}
 	})
 
-	entryID := s.crontab.Schedule(cron.Every(duration), j)
+	entryID := s.crontab.Schedule(schedule, j)
 
 	s.mu.Lock()
 	s.activeJobs[entryID] = cancel
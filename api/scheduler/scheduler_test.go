@@ -118,3 +118,15 @@ func Test_StartJobEvery_Concurrently(t *testing.T) {
 
 	<-ctx.Done()
 }
+
+func Test_StartJobWithCron_RejectsInvalidExpression(t *testing.T) {
+	s := NewScheduler(context.Background())
+	defer s.Shutdown()
+
+	_, err := s.StartJobWithCron("not a cron", func() error { return nil })
+	assert.Error(t, err)
+
+	jobID, err := s.StartJobWithCron("0 2 * * *", func() error { return nil })
+	assert.NoError(t, err)
+	assert.NoError(t, s.StopJob(jobID))
+}
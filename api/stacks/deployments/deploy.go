@@ -8,6 +8,7 @@ import (
 	"github.com/portainer/portainer/api/dataservices"
 	"github.com/portainer/portainer/api/git/update"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/metrics"
 
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
@@ -62,13 +63,17 @@ func RedeployWhenChanged(stackID portainer.StackID, deployer StackDeployer, data
 	if !stack.FromAppTemplate {
 		updated, newHash, err := update.UpdateGitObject(gitService, fmt.Sprintf("stack:%d", stackID), stack.GitConfig, false, stack.ProjectPath)
 		if err != nil {
+			metrics.CountGitPoll(metrics.GitPollError)
 			return err
 		}
 
 		if updated {
+			metrics.CountGitPoll(metrics.GitPollUpdated)
 			stack.GitConfig.ConfigHash = newHash
 			stack.UpdateDate = time.Now().Unix()
 			gitCommitChangedOrForceUpdate = updated
+		} else {
+			metrics.CountGitPoll(metrics.GitPollUnchanged)
 		}
 	}
 
@@ -112,6 +117,21 @@ func RedeployWhenChanged(stackID portainer.StackID, deployer StackDeployer, data
 	return nil
 }
 
+// GetStackRegistries returns the registries of the environment of a stack that the author of the stack can access
+func GetStackRegistries(datastore dataservices.DataStore, stack *portainer.Stack) ([]portainer.Registry, error) {
+	author := stack.UpdatedBy
+	if author == "" {
+		author = stack.CreatedBy
+	}
+
+	user, err := datastore.User().UserByUsername(author)
+	if err != nil {
+		return nil, &StackAuthorMissingErr{int(stack.ID), author}
+	}
+
+	return getUserRegistries(datastore, user, stack.EndpointID)
+}
+
 func getUserRegistries(datastore dataservices.DataStore, user *portainer.User, endpointID portainer.EndpointID) ([]portainer.Registry, error) {
 	registries, err := datastore.Registry().Registries()
 	if err != nil {
@@ -2,12 +2,14 @@ package deployments
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
 	"github.com/pkg/errors"
 
 	portainer "github.com/portainer/portainer/api"
 	k "github.com/portainer/portainer/api/kubernetes"
+	"github.com/portainer/portainer/api/notifications"
 )
 
 type StackDeployer interface {
@@ -16,34 +18,65 @@ type StackDeployer interface {
 	DeployKubernetesStack(stack *portainer.Stack, endpoint *portainer.Endpoint, user *portainer.User) error
 }
 
+// ImageChecker checks the images of a stack before it is deployed, the images are pulled with the
+// credentials of the registries when they are missing or when pull is set
+type ImageChecker interface {
+	CheckStackImages(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, pull bool) error
+}
+
 type stackDeployer struct {
 	lock                *sync.Mutex
 	swarmStackManager   portainer.SwarmStackManager
 	composeStackManager portainer.ComposeStackManager
 	kubernetesDeployer  portainer.KubernetesDeployer
+	notificationService *notifications.Service
+	imageChecker        ImageChecker
 }
 
-// NewStackDeployer inits a stackDeployer struct with a SwarmStackManager, a ComposeStackManager and a KubernetesDeployer
-func NewStackDeployer(swarmStackManager portainer.SwarmStackManager, composeStackManager portainer.ComposeStackManager, kubernetesDeployer portainer.KubernetesDeployer) *stackDeployer {
+// NewStackDeployer inits a stackDeployer struct with a SwarmStackManager, a ComposeStackManager and a KubernetesDeployer.
+// Deployment failures are published on the notification service.
+// When imageChecker is not nil, the compose and swarm stacks are only deployed when their images pass the check.
+func NewStackDeployer(swarmStackManager portainer.SwarmStackManager, composeStackManager portainer.ComposeStackManager, kubernetesDeployer portainer.KubernetesDeployer, notificationService *notifications.Service, imageChecker ImageChecker) *stackDeployer {
 	return &stackDeployer{
 		lock:                &sync.Mutex{},
 		swarmStackManager:   swarmStackManager,
 		composeStackManager: composeStackManager,
 		kubernetesDeployer:  kubernetesDeployer,
+		notificationService: notificationService,
+		imageChecker:        imageChecker,
 	}
 }
 
 func (d *stackDeployer) DeploySwarmStack(stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, prune bool, pullImage bool) error {
+	// the scan can be slow, it must not hold back the other deployments
+	err := d.checkImages(stack, endpoint, registries, pullImage)
+	if err != nil {
+		d.publishFailure(stack, endpoint, err)
+		return err
+	}
+
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
 	d.swarmStackManager.Login(registries, endpoint)
 	defer d.swarmStackManager.Logout(endpoint)
 
-	return d.swarmStackManager.Deploy(stack, prune, pullImage, endpoint)
+	err = d.swarmStackManager.Deploy(stack, prune, pullImage, endpoint)
+	if err != nil {
+		d.publishFailure(stack, endpoint, err)
+	}
+
+	return err
 }
 
 func (d *stackDeployer) DeployComposeStack(stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, forcePullImage bool, forceRereate bool) error {
+	// the scan can be slow, it must not hold back the other deployments
+	err := d.checkImages(stack, endpoint, registries, forcePullImage)
+	if err != nil {
+		d.publishFailure(stack, endpoint, err)
+		return err
+	}
+
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
@@ -54,13 +87,15 @@ func (d *stackDeployer) DeployComposeStack(stack *portainer.Stack, endpoint *por
 	if forcePullImage {
 		err := d.composeStackManager.Pull(context.TODO(), stack, endpoint)
 		if err != nil {
+			d.publishFailure(stack, endpoint, err)
 			return err
 		}
 	}
 
-	err := d.composeStackManager.Up(context.TODO(), stack, endpoint, forceRereate)
+	err = d.composeStackManager.Up(context.TODO(), stack, endpoint, forceRereate)
 	if err != nil {
 		d.composeStackManager.Down(context.TODO(), stack, endpoint)
+		d.publishFailure(stack, endpoint, err)
 	}
 	return err
 }
@@ -88,8 +123,29 @@ func (d *stackDeployer) DeployKubernetesStack(stack *portainer.Stack, endpoint *
 
 	err = k8sDeploymentConfig.Deploy()
 	if err != nil {
+		d.publishFailure(stack, endpoint, err)
 		return errors.Wrap(err, "failed to deploy kubernetes application")
 	}
 
 	return nil
 }
+
+func (d *stackDeployer) checkImages(stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, pull bool) error {
+	if d.imageChecker == nil {
+		return nil
+	}
+
+	return d.imageChecker.CheckStackImages(context.TODO(), stack, endpoint, registries, pull)
+}
+
+func (d *stackDeployer) publishFailure(stack *portainer.Stack, endpoint *portainer.Endpoint, err error) {
+	d.notificationService.Publish(notifications.Event{
+		Type:       portainer.NotificationEventStackDeployFailed,
+		Message:    fmt.Sprintf("deployment of stack %s failed on environment %s", stack.Name, endpoint.Name),
+		EndpointID: endpoint.ID,
+		Details: map[string]string{
+			"stack": stack.Name,
+			"error": err.Error(),
+		},
+	})
+}
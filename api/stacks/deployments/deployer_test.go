@@ -0,0 +1,76 @@
+package deployments
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type noopSwarmStackManager struct{}
+
+func (manager *noopSwarmStackManager) Login(registries []portainer.Registry, endpoint *portainer.Endpoint) error {
+	return nil
+}
+
+func (manager *noopSwarmStackManager) Logout(endpoint *portainer.Endpoint) error {
+	return nil
+}
+
+func (manager *noopSwarmStackManager) Deploy(stack *portainer.Stack, prune bool, pullImage bool, endpoint *portainer.Endpoint) error {
+	return nil
+}
+
+func (manager *noopSwarmStackManager) Remove(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	return nil
+}
+
+func (manager *noopSwarmStackManager) NormalizeStackName(name string) string {
+	return name
+}
+
+type lockCheckingImageChecker struct {
+	deployer   *stackDeployer
+	err        error
+	lockHeld   bool
+	registries []portainer.Registry
+	pull       bool
+}
+
+func (checker *lockCheckingImageChecker) CheckStackImages(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, pull bool) error {
+	if checker.deployer.lock.TryLock() {
+		checker.deployer.lock.Unlock()
+	} else {
+		checker.lockHeld = true
+	}
+
+	checker.registries = registries
+	checker.pull = pull
+
+	return checker.err
+}
+
+func Test_DeployComposeStack_ChecksImagesOutsideOfTheLock(t *testing.T) {
+	is := assert.New(t)
+
+	checker := &lockCheckingImageChecker{err: errors.New("vulnerable")}
+	deployer := NewStackDeployer(&noopSwarmStackManager{}, testhelpers.NewComposeStackManager(), nil, nil, checker)
+	checker.deployer = deployer
+
+	registries := []portainer.Registry{{ID: 1}}
+	err := deployer.DeployComposeStack(&portainer.Stack{Name: "stack"}, &portainer.Endpoint{Name: "local"}, registries, true, false)
+
+	is.ErrorIs(err, checker.err, "the deployment should be blocked by the check")
+	is.False(checker.lockHeld, "the images should be checked before taking the deployment lock")
+	is.Equal(registries, checker.registries)
+	is.True(checker.pull)
+
+	checker.err = nil
+	is.NoError(deployer.DeploySwarmStack(&portainer.Stack{Name: "stack"}, &portainer.Endpoint{Name: "local"}, nil, false, false))
+	is.False(checker.lockHeld)
+	is.False(checker.pull)
+}
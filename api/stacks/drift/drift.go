@@ -0,0 +1,267 @@
+// Package drift compares the compose stacks files with the containers running on their environment.
+package drift
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+
+	"github.com/docker/cli/cli/compose/types"
+	"github.com/docker/distribution/reference"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/nat"
+)
+
+// Service drift statuses
+const (
+	// ServiceInSync means that the containers of the service match the stack file
+	ServiceInSync = "in_sync"
+	// ServiceDrifted means that at least one container of the service differs from the stack file
+	ServiceDrifted = "drifted"
+	// ServiceMissing means that the service has no container on the environment
+	ServiceMissing = "missing"
+	// ServiceUnexpected means that containers of the stack run a service which is not in the stack file
+	ServiceUnexpected = "unexpected"
+)
+
+// Compared fields
+const (
+	FieldState  = "state"
+	FieldImage  = "image"
+	FieldEnv    = "env"
+	FieldLabels = "labels"
+	FieldPorts  = "ports"
+)
+
+// Difference represents a setting of a container which differs from the stack file
+type Difference struct {
+	// Name of the container
+	Container string `json:"Container" example:"mystack-web-1"`
+	// Compared field, one of state, image, env, labels or ports
+	Field string `json:"Field" example:"env"`
+	// Environment variable, label or port the difference applies to, empty for the state and the image
+	Key string `json:"Key,omitempty" example:"LOG_LEVEL"`
+	// Value defined by the stack file, empty when the setting is not in the stack file
+	Expected string `json:"Expected" example:"info"`
+	// Value of the container, empty when the setting is missing from the container
+	Actual string `json:"Actual" example:"debug"`
+}
+
+// ServiceDrift represents the drift of a service of a stack
+type ServiceDrift struct {
+	// Name of the service
+	Service string `json:"Service" example:"web"`
+	// Drift status of the service, one of in_sync, drifted, missing or unexpected
+	Status string `json:"Status" example:"drifted"`
+	// Differences between the containers of the service and the stack file
+	Differences []Difference `json:"Differences"`
+}
+
+// Report represents the drift of a compose stack
+type Report struct {
+	// Stack identifier
+	StackID portainer.StackID `json:"StackId" example:"1"`
+	// Whether any service of the stack drifted
+	Drifted bool `json:"Drifted" example:"true"`
+	// Date of the check in unix time
+	CheckedAt int64 `json:"CheckedAt" example:"1587399600"`
+	// Drift of each service, sorted by name
+	Services []ServiceDrift `json:"Services"`
+}
+
+// compareStack builds the drift report of the services of a stack file given the containers of the stack, grouped by service
+func compareStack(services []types.ServiceConfig, containers map[string][]dockertypes.ContainerJSON) []ServiceDrift {
+	drifts := make([]ServiceDrift, 0, len(services))
+
+	defined := make(map[string]bool, len(services))
+	for _, service := range services {
+		defined[service.Name] = true
+		drifts = append(drifts, compareService(service, containers[service.Name]))
+	}
+
+	for name, serviceContainers := range containers {
+		if defined[name] {
+			continue
+		}
+
+		drift := ServiceDrift{Service: name, Status: ServiceUnexpected, Differences: []Difference{}}
+		for _, container := range serviceContainers {
+			drift.Differences = append(drift.Differences, Difference{
+				Container: containerName(container),
+				Field:     FieldState,
+				Actual:    container.State.Status,
+			})
+		}
+
+		drifts = append(drifts, drift)
+	}
+
+	sort.Slice(drifts, func(i, j int) bool {
+		return drifts[i].Service < drifts[j].Service
+	})
+
+	return drifts
+}
+
+// compareService compares the containers of a service with its definition
+func compareService(service types.ServiceConfig, containers []dockertypes.ContainerJSON) ServiceDrift {
+	drift := ServiceDrift{Service: service.Name, Status: ServiceInSync, Differences: []Difference{}}
+
+	if len(containers) == 0 {
+		drift.Status = ServiceMissing
+		return drift
+	}
+
+	for _, container := range containers {
+		drift.Differences = append(drift.Differences, compareContainer(service, container)...)
+	}
+
+	if len(drift.Differences) > 0 {
+		drift.Status = ServiceDrifted
+	}
+
+	return drift
+}
+
+func compareContainer(service types.ServiceConfig, container dockertypes.ContainerJSON) []Difference {
+	name := containerName(container)
+	differences := make([]Difference, 0)
+
+	if container.State != nil && !container.State.Running {
+		differences = append(differences, Difference{Container: name, Field: FieldState, Expected: "running", Actual: container.State.Status})
+	}
+
+	if container.Config == nil {
+		return differences
+	}
+
+	if service.Image != "" && normalizeImage(service.Image) != normalizeImage(container.Config.Image) {
+		differences = append(differences, Difference{Container: name, Field: FieldImage, Expected: service.Image, Actual: container.Config.Image})
+	}
+
+	env := parseEnv(container.Config.Env)
+	for _, key := range sortedKeys(service.Environment) {
+		expected := service.Environment[key]
+		if expected == nil {
+			// the value is taken from the environment of the deployment, it cannot be compared
+			continue
+		}
+
+		if actual, ok := env[key]; !ok || actual != *expected {
+			differences = append(differences, Difference{Container: name, Field: FieldEnv, Key: key, Expected: *expected, Actual: actual})
+		}
+	}
+
+	for _, key := range sortedKeys(service.Labels) {
+		if actual := container.Config.Labels[key]; actual != service.Labels[key] {
+			differences = append(differences, Difference{Container: name, Field: FieldLabels, Key: key, Expected: service.Labels[key], Actual: actual})
+		}
+	}
+
+	if container.HostConfig != nil {
+		differences = append(differences, comparePorts(name, service.Ports, container.HostConfig.PortBindings)...)
+	}
+
+	return differences
+}
+
+// comparePorts compares the published ports of the service with the port bindings of the container.
+// The ports published on a random host port cannot be compared and are ignored.
+func comparePorts(name string, ports []types.ServicePortConfig, bindings nat.PortMap) []Difference {
+	expected := make(map[string]string)
+	for _, port := range ports {
+		if port.Published == 0 {
+			continue
+		}
+
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = "tcp"
+		}
+
+		expected[fmt.Sprintf("%d/%s", port.Target, protocol)] = fmt.Sprintf("%d", port.Published)
+	}
+
+	actual := make(map[string]string)
+	for port, portBindings := range bindings {
+		hostPorts := make([]string, 0, len(portBindings))
+		for _, binding := range portBindings {
+			if binding.HostPort != "" {
+				hostPorts = append(hostPorts, binding.HostPort)
+			}
+		}
+
+		if len(hostPorts) > 0 {
+			actual[string(port)] = strings.Join(hostPorts, ",")
+		}
+	}
+
+	differences := make([]Difference, 0)
+	for _, key := range sortedKeys(expected) {
+		if !containsHostPort(actual[key], expected[key]) {
+			differences = append(differences, Difference{Container: name, Field: FieldPorts, Key: key, Expected: expected[key], Actual: actual[key]})
+		}
+	}
+
+	for _, key := range sortedKeys(actual) {
+		if _, ok := expected[key]; !ok {
+			differences = append(differences, Difference{Container: name, Field: FieldPorts, Key: key, Actual: actual[key]})
+		}
+	}
+
+	return differences
+}
+
+func containsHostPort(hostPorts string, port string) bool {
+	for _, hostPort := range strings.Split(hostPorts, ",") {
+		if hostPort == port {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeImage returns the fully qualified name of an image, nginx and docker.io/library/nginx:latest are the same image
+func normalizeImage(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+
+	return reference.TagNameOnly(named).String()
+}
+
+func parseEnv(env []string) map[string]string {
+	result := make(map[string]string, len(env))
+	for _, variable := range env {
+		parts := strings.SplitN(variable, "=", 2)
+		if len(parts) == 2 {
+			result[parts[0]] = parts[1]
+		} else {
+			result[parts[0]] = ""
+		}
+	}
+
+	return result
+}
+
+func containerName(container dockertypes.ContainerJSON) string {
+	if container.ContainerJSONBase == nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(container.Name, "/")
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,94 @@
+package drift
+
+import (
+	"testing"
+
+	"github.com/docker/cli/cli/compose/types"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+)
+
+func newContainer(name, image string, env []string, labels map[string]string, ports nat.PortMap) dockertypes.ContainerJSON {
+	return dockertypes.ContainerJSON{
+		ContainerJSONBase: &dockertypes.ContainerJSONBase{
+			Name:       "/" + name,
+			State:      &dockertypes.ContainerState{Status: "running", Running: true},
+			HostConfig: &container.HostConfig{PortBindings: ports},
+		},
+		Config: &container.Config{Image: image, Env: env, Labels: labels},
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestCompareService(t *testing.T) {
+	service := types.ServiceConfig{
+		Name:        "web",
+		Image:       "nginx",
+		Environment: types.MappingWithEquals{"LOG_LEVEL": strPtr("info"), "FROM_SHELL": nil},
+		Labels:      types.Labels{"tier": "front"},
+		Ports: []types.ServicePortConfig{
+			{Target: 80, Published: 8080, Protocol: "tcp"},
+			{Target: 443},
+		},
+	}
+
+	inSync := newContainer("stack-web-1", "docker.io/library/nginx:latest",
+		[]string{"LOG_LEVEL=info", "PATH=/usr/bin"},
+		map[string]string{"tier": "front", composeServiceLabel: "web"},
+		nat.PortMap{"80/tcp": {{HostPort: "8080"}}, "443/tcp": {{HostPort: ""}}},
+	)
+
+	t.Run("in sync", func(t *testing.T) {
+		drift := compareService(service, []dockertypes.ContainerJSON{inSync})
+		assert.Equal(t, ServiceInSync, drift.Status)
+		assert.Empty(t, drift.Differences)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		drift := compareService(service, nil)
+		assert.Equal(t, ServiceMissing, drift.Status)
+	})
+
+	t.Run("drifted", func(t *testing.T) {
+		drifted := newContainer("stack-web-1", "nginx:1.23",
+			[]string{"LOG_LEVEL=debug"},
+			map[string]string{},
+			nat.PortMap{"80/tcp": {{HostPort: "9090"}}, "22/tcp": {{HostPort: "2222"}}},
+		)
+		drifted.State = &dockertypes.ContainerState{Status: "exited"}
+
+		drift := compareService(service, []dockertypes.ContainerJSON{drifted})
+		assert.Equal(t, ServiceDrifted, drift.Status)
+		assert.Equal(t, []Difference{
+			{Container: "stack-web-1", Field: FieldState, Expected: "running", Actual: "exited"},
+			{Container: "stack-web-1", Field: FieldImage, Expected: "nginx", Actual: "nginx:1.23"},
+			{Container: "stack-web-1", Field: FieldEnv, Key: "LOG_LEVEL", Expected: "info", Actual: "debug"},
+			{Container: "stack-web-1", Field: FieldLabels, Key: "tier", Expected: "front", Actual: ""},
+			{Container: "stack-web-1", Field: FieldPorts, Key: "80/tcp", Expected: "8080", Actual: "9090"},
+			{Container: "stack-web-1", Field: FieldPorts, Key: "22/tcp", Expected: "", Actual: "2222"},
+		}, drift.Differences)
+	})
+}
+
+func TestCompareStack(t *testing.T) {
+	services := []types.ServiceConfig{{Name: "web", Image: "nginx"}, {Name: "db", Image: "postgres"}}
+	containers := map[string][]dockertypes.ContainerJSON{
+		"web":    {newContainer("stack-web-1", "nginx", nil, nil, nil)},
+		"worker": {newContainer("stack-worker-1", "busybox", nil, nil, nil)},
+	}
+
+	drifts := compareStack(services, containers)
+	assert.Len(t, drifts, 3)
+
+	statuses := make(map[string]string)
+	for _, drift := range drifts {
+		statuses[drift.Service] = drift.Status
+	}
+	assert.Equal(t, map[string]string{"db": ServiceMissing, "web": ServiceInSync, "worker": ServiceUnexpected}, statuses)
+	assert.Equal(t, "db", drifts[0].Service, "the services should be sorted by name")
+}
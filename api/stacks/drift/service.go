@@ -0,0 +1,322 @@
+package drift
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/internal/endpointutils"
+	"github.com/portainer/portainer/api/scheduler"
+	"github.com/portainer/portainer/api/stacks/deployments"
+
+	"github.com/docker/cli/cli/compose/loader"
+	"github.com/docker/cli/cli/compose/types"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/joho/godotenv"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+	composeOneoffLabel  = "com.docker.compose.oneoff"
+)
+
+// Service detects the drift of the compose stacks and optionally reconciles them on a schedule
+type Service struct {
+	dataStore           dataservices.DataStore
+	clientFactory       *docker.ClientFactory
+	fileService         portainer.FileService
+	composeStackManager portainer.ComposeStackManager
+	stackDeployer       deployments.StackDeployer
+	scheduler           *scheduler.Scheduler
+
+	mu    sync.Mutex
+	jobID string
+}
+
+// NewService initializes a new drift detection service, the drifted stacks are reconciled with stackDeployer
+func NewService(dataStore dataservices.DataStore, clientFactory *docker.ClientFactory, fileService portainer.FileService, composeStackManager portainer.ComposeStackManager, stackDeployer deployments.StackDeployer, scheduler *scheduler.Scheduler) *Service {
+	return &Service{
+		dataStore:           dataStore,
+		clientFactory:       clientFactory,
+		fileService:         fileService,
+		composeStackManager: composeStackManager,
+		stackDeployer:       stackDeployer,
+		scheduler:           scheduler,
+	}
+}
+
+// ValidateSettings returns an error when the drift settings cannot be applied
+func ValidateSettings(settings portainer.StackDriftSettings) error {
+	if settings.CheckInterval == "" {
+		if settings.AutoReconcile {
+			return errors.New("Invalid drift settings, the automatic reconciliation requires a check interval")
+		}
+
+		return nil
+	}
+
+	interval, err := time.ParseDuration(settings.CheckInterval)
+	if err != nil {
+		return errors.Wrap(err, "Invalid check interval")
+	}
+
+	if interval < time.Minute {
+		return errors.New("Invalid check interval, the stacks cannot be checked more than once a minute")
+	}
+
+	return nil
+}
+
+// Start schedules the drift checks with the interval stored in the settings
+func (service *Service) Start() error {
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	return service.Reschedule(settings.StackDriftSettings)
+}
+
+// Reschedule replaces the current check schedule with the one described by the given settings
+func (service *Service) Reschedule(settings portainer.StackDriftSettings) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if service.jobID != "" {
+		err := service.scheduler.StopJob(service.jobID)
+		if err != nil {
+			return err
+		}
+
+		service.jobID = ""
+	}
+
+	if settings.CheckInterval == "" {
+		return nil
+	}
+
+	interval, err := time.ParseDuration(settings.CheckInterval)
+	if err != nil {
+		return errors.Wrapf(err, "invalid check interval %q", settings.CheckInterval)
+	}
+
+	service.jobID = service.scheduler.StartJobEvery(interval, func() error {
+		service.checkStacks(settings.AutoReconcile)
+
+		// the job must keep running even if some stacks could not be checked
+		return nil
+	})
+
+	return nil
+}
+
+// checkStacks checks the drift of the active compose stacks deployed on a reachable environment
+// and redeploys the drifted ones when reconcile is enabled.
+// The Edge environments are skipped as they can only be reached while their tunnel is open.
+func (service *Service) checkStacks(reconcile bool) {
+	stacks, err := service.dataStore.Stack().Stacks()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to retrieve the stacks to check")
+		return
+	}
+
+	for i := range stacks {
+		stack := &stacks[i]
+		if stack.Type != portainer.DockerComposeStack || stack.Status != portainer.StackStatusActive {
+			continue
+		}
+
+		endpoint, err := service.dataStore.Endpoint().Endpoint(stack.EndpointID)
+		if err != nil {
+			log.Warn().Err(err).Int("stack_id", int(stack.ID)).Msg("unable to retrieve the environment of the stack")
+			continue
+		}
+
+		if !endpointutils.IsDockerEndpoint(endpoint) || endpointutils.IsEdgeEndpoint(endpoint) || endpoint.Status != portainer.EndpointStatusUp {
+			continue
+		}
+
+		report, err := service.Check(context.Background(), stack, endpoint)
+		if err != nil {
+			log.Warn().Err(err).Int("stack_id", int(stack.ID)).Msg("unable to check the drift of the stack")
+			continue
+		}
+
+		err = service.updateDriftStatus(stack.ID, func(status *portainer.StackDriftStatus) {
+			status.Drifted = report.Drifted
+			status.CheckedAt = report.CheckedAt
+		})
+		if err != nil {
+			log.Error().Err(err).Int("stack_id", int(stack.ID)).Msg("unable to persist the drift status of the stack")
+			continue
+		}
+
+		if !report.Drifted || !reconcile {
+			continue
+		}
+
+		err = service.reconcile(stack, endpoint)
+		if err != nil {
+			log.Error().Err(err).Int("stack_id", int(stack.ID)).Msg("unable to reconcile the drifted stack")
+		}
+	}
+}
+
+// reconcile redeploys a drifted stack from its stack files, with the registries of the stack author
+// like the automatic updates of the git stacks
+func (service *Service) reconcile(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	registries, err := deployments.GetStackRegistries(service.dataStore, stack)
+	if err != nil {
+		return err
+	}
+
+	err = service.stackDeployer.DeployComposeStack(stack, endpoint, registries, false, true)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Int("stack_id", int(stack.ID)).Str("stack", stack.Name).Msg("drifted stack reconciled")
+
+	return service.updateDriftStatus(stack.ID, func(status *portainer.StackDriftStatus) {
+		status.Drifted = false
+		status.ReconciledAt = time.Now().Unix()
+	})
+}
+
+// updateDriftStatus reloads the stack and updates only its drift status, so that the changes made
+// to the stack while its containers were being inspected are not overwritten
+func (service *Service) updateDriftStatus(stackID portainer.StackID, updateFunc func(status *portainer.StackDriftStatus)) error {
+	return service.dataStore.Stack().UpdateStackFunc(stackID, func(stack *portainer.Stack) {
+		status := portainer.StackDriftStatus{}
+		if stack.DriftStatus != nil {
+			status = *stack.DriftStatus
+		}
+
+		updateFunc(&status)
+		stack.DriftStatus = &status
+	})
+}
+
+// Check compares the stack files of a compose stack with the containers of the stack.
+// The drift status of the stack is only persisted by the scheduled checks.
+func (service *Service) Check(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint) (*Report, error) {
+	if stack.Type != portainer.DockerComposeStack {
+		return nil, errors.New("drift detection is only supported for compose stacks")
+	}
+
+	services, err := service.loadServices(stack)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse the stack files")
+	}
+
+	containers, err := service.stackContainers(ctx, stack, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		StackID:   stack.ID,
+		CheckedAt: time.Now().Unix(),
+		Services:  compareStack(services, containers),
+	}
+
+	for _, drift := range report.Services {
+		if drift.Status != ServiceInSync {
+			report.Drifted = true
+			break
+		}
+	}
+
+	return report, nil
+}
+
+// loadServices parses the stack files the same way they are deployed: the default .env file
+// next to the entry point is overridden by the environment variables of the stack
+func (service *Service) loadServices(stack *portainer.Stack) ([]types.ServiceConfig, error) {
+	workingDir := path.Join(stack.ProjectPath, path.Dir(stack.EntryPoint))
+
+	env := make(map[string]string)
+	defaultEnv, err := service.fileService.GetFileContent(stack.ProjectPath, path.Join(path.Dir(stack.EntryPoint), ".env"))
+	if err == nil {
+		env, err = godotenv.Unmarshal(string(defaultEnv))
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to parse the default .env file")
+		}
+	}
+
+	for _, pair := range stack.Env {
+		env[pair.Name] = pair.Value
+	}
+
+	configFiles := make([]types.ConfigFile, 0, len(stack.AdditionalFiles)+1)
+	for _, file := range append([]string{stack.EntryPoint}, stack.AdditionalFiles...) {
+		content, err := service.fileService.GetFileContent(stack.ProjectPath, file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read stack file %s", file)
+		}
+
+		config, err := loader.ParseYAML(content)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse stack file %s", file)
+		}
+
+		configFiles = append(configFiles, types.ConfigFile{Filename: file, Config: config})
+	}
+
+	composeConfig, err := loader.Load(types.ConfigDetails{
+		WorkingDir:  workingDir,
+		ConfigFiles: configFiles,
+		Environment: env,
+	}, func(options *loader.Options) {
+		options.SkipValidation = true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return composeConfig.Services, nil
+}
+
+// stackContainers returns the containers of the compose project of the stack, grouped by service.
+// The one-off containers created by "docker compose run" are not part of the stack.
+func (service *Service) stackContainers(ctx context.Context, stack *portainer.Stack, endpoint *portainer.Endpoint) (map[string][]dockertypes.ContainerJSON, error) {
+	cli, err := service.clientFactory.CreateClient(endpoint, "", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to connect to the Docker daemon")
+	}
+	defer cli.Close()
+
+	projectName := service.composeStackManager.NormalizeStackName(stack.Name)
+	containers, err := cli.ContainerList(ctx, dockertypes.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", composeProjectLabel+"="+projectName)),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list the containers of the stack")
+	}
+
+	result := make(map[string][]dockertypes.ContainerJSON)
+	for _, container := range containers {
+		if container.Labels[composeOneoffLabel] == "True" {
+			continue
+		}
+
+		inspect, err := cli.ContainerInspect(ctx, container.ID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to inspect container %s", container.ID)
+		}
+
+		serviceName := container.Labels[composeServiceLabel]
+		result[serviceName] = append(result[serviceName], inspect)
+	}
+
+	return result, nil
+}
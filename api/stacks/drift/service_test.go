@@ -0,0 +1,92 @@
+package drift
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/datastore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_updateDriftStatus(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, false)
+	defer teardown()
+
+	stack := &portainer.Stack{ID: 1, Name: "stack", DriftStatus: &portainer.StackDriftStatus{ReconciledAt: 10}}
+	require.NoError(t, store.Stack().Create(stack))
+
+	service := NewService(store, nil, nil, nil, nil, nil)
+
+	// the stack is updated while its containers are being inspected
+	updated := *stack
+	updated.Env = []portainer.Pair{{Name: "KEY", Value: "value"}}
+	require.NoError(t, store.Stack().UpdateStack(stack.ID, &updated))
+
+	err := service.updateDriftStatus(stack.ID, func(status *portainer.StackDriftStatus) {
+		status.Drifted = true
+		status.CheckedAt = 20
+	})
+	require.NoError(t, err)
+
+	stored, err := store.Stack().Stack(stack.ID)
+	require.NoError(t, err)
+	is.Equal(updated.Env, stored.Env, "the changes made to the stack should be kept")
+	is.Equal(&portainer.StackDriftStatus{Drifted: true, CheckedAt: 20, ReconciledAt: 10}, stored.DriftStatus)
+}
+
+type testStackDeployer struct {
+	registries    []portainer.Registry
+	forceRecreate bool
+}
+
+func (deployer *testStackDeployer) DeploySwarmStack(stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, prune bool, pullImage bool) error {
+	return nil
+}
+
+func (deployer *testStackDeployer) DeployComposeStack(stack *portainer.Stack, endpoint *portainer.Endpoint, registries []portainer.Registry, forcePullImage bool, forceRecreate bool) error {
+	deployer.registries = registries
+	deployer.forceRecreate = forceRecreate
+	return nil
+}
+
+func (deployer *testStackDeployer) DeployKubernetesStack(stack *portainer.Stack, endpoint *portainer.Endpoint, user *portainer.User) error {
+	return nil
+}
+
+func Test_reconcile(t *testing.T) {
+	is := assert.New(t)
+
+	_, store, teardown := datastore.MustNewTestStore(t, true, false)
+	defer teardown()
+
+	require.NoError(t, store.User().Create(&portainer.User{ID: 1, Username: "admin", Role: portainer.AdministratorRole}))
+	require.NoError(t, store.Registry().Create(&portainer.Registry{ID: 1, Name: "private", URL: "registry.example.com", Authentication: true}))
+
+	endpoint := &portainer.Endpoint{ID: 1, Name: "local", Type: portainer.DockerEnvironment}
+	stack := &portainer.Stack{ID: 1, Name: "stack", Type: portainer.DockerComposeStack, EndpointID: endpoint.ID, CreatedBy: "admin", DriftStatus: &portainer.StackDriftStatus{Drifted: true}}
+	require.NoError(t, store.Stack().Create(stack))
+
+	deployer := &testStackDeployer{}
+	service := NewService(store, nil, nil, nil, deployer, nil)
+
+	require.NoError(t, service.reconcile(stack, endpoint))
+	is.True(deployer.forceRecreate)
+	require.Len(t, deployer.registries, 1, "the stack should be deployed with the registries of its author")
+	is.Equal("private", deployer.registries[0].Name)
+
+	stored, err := store.Stack().Stack(stack.ID)
+	require.NoError(t, err)
+	is.False(stored.DriftStatus.Drifted)
+	is.NotZero(stored.DriftStatus.ReconciledAt)
+
+	t.Run("the stacks without author are not reconciled", func(t *testing.T) {
+		orphan := &portainer.Stack{ID: 2, Name: "orphan", Type: portainer.DockerComposeStack, EndpointID: endpoint.ID, CreatedBy: "removed"}
+		require.NoError(t, store.Stack().Create(orphan))
+
+		is.Error(service.reconcile(orphan, endpoint))
+	})
+}
@@ -0,0 +1,72 @@
+package stackutils
+
+import (
+	"sort"
+
+	"github.com/docker/cli/cli/compose/loader"
+	"github.com/docker/cli/cli/compose/types"
+	"github.com/pkg/errors"
+	portainer "github.com/portainer/portainer/api"
+)
+
+// GetStackImages returns the images used by the services of a compose or swarm stack,
+// the stack environment variables are interpolated in the image names
+func GetStackImages(stack *portainer.Stack, fileService portainer.FileService) ([]string, error) {
+	env := make(map[string]string, len(stack.Env))
+	for _, pair := range stack.Env {
+		env[pair.Name] = pair.Value
+	}
+
+	images := make(map[string]struct{})
+	for _, file := range GetStackFilePaths(stack, false) {
+		stackContent, err := fileService.GetFileContent(stack.ProjectPath, file)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get stack file content")
+		}
+
+		fileImages, err := StackFileImages(stackContent, env)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse stack file %s", file)
+		}
+
+		for _, image := range fileImages {
+			images[image] = struct{}{}
+		}
+	}
+
+	result := make([]string, 0, len(images))
+	for image := range images {
+		result = append(result, image)
+	}
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// StackFileImages returns the images used by the services of a stack file.
+// The services which are built from a Dockerfile are ignored, even when they name the image to build.
+func StackFileImages(stackFileContent []byte, env map[string]string) ([]string, error) {
+	composeConfigYAML, err := loader.ParseYAML(stackFileContent)
+	if err != nil {
+		return nil, err
+	}
+
+	composeConfig, err := loader.Load(types.ConfigDetails{
+		ConfigFiles: []types.ConfigFile{{Config: composeConfigYAML}},
+		Environment: env,
+	}, func(options *loader.Options) {
+		options.SkipValidation = true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var images []string
+	for _, service := range composeConfig.Services {
+		if service.Image != "" && service.Build.Context == "" {
+			images = append(images, service.Image)
+		}
+	}
+
+	return images, nil
+}
@@ -0,0 +1,30 @@
+package stackutils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_StackFileImages(t *testing.T) {
+	is := assert.New(t)
+
+	content := []byte(`
+version: "3"
+services:
+  web:
+    image: nginx:${TAG}
+  db:
+    image: postgres
+  app:
+    build: .
+  worker:
+    image: registry.example.com/worker:latest
+    build:
+      context: ./worker
+`)
+
+	images, err := StackFileImages(content, map[string]string{"TAG": "1.23"})
+	is.NoError(err)
+	is.ElementsMatch([]string{"nginx:1.23", "postgres"}, images)
+}
@@ -0,0 +1,169 @@
+package stackutils
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/dataservices"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// MaxStackVersions is the number of revisions kept for each stack, the oldest ones are removed first
+const MaxStackVersions = 20
+
+// envFileName is the name used to display the changes of the environment variables in a diff
+const envFileName = ".env"
+
+// RecordStackVersion persists a new revision of a stack built from the files currently stored in
+// its project path. No revision is created when nothing changed since the latest revision, in which
+// case the latest revision is returned.
+func RecordStackVersion(dataStore dataservices.DataStore, fileService portainer.FileService, stack *portainer.Stack) (*portainer.StackVersion, error) {
+	files := make(map[string]string)
+	for _, fileName := range append([]string{stack.EntryPoint}, stack.AdditionalFiles...) {
+		content, err := fileService.GetFileContent(stack.ProjectPath, fileName)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to read the file %s of the stack %v", fileName, stack.ID)
+		}
+
+		files[fileName] = string(content)
+	}
+
+	configHash := ""
+	if stack.GitConfig != nil {
+		configHash = stack.GitConfig.ConfigHash
+	}
+
+	versions, err := dataStore.StackVersion().StackVersions(stack.ID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to retrieve the versions of the stack %v", stack.ID)
+	}
+	SortStackVersions(versions)
+
+	nextVersion := 1
+	if len(versions) > 0 {
+		latest := versions[len(versions)-1]
+		if reflect.DeepEqual(latest.Files, files) && envEqual(latest.Env, stack.Env) && latest.ConfigHash == configHash &&
+			latest.EntryPoint == stack.EntryPoint && reflect.DeepEqual(latest.AdditionalFiles, stack.AdditionalFiles) {
+			return &latest, nil
+		}
+
+		nextVersion = latest.Version + 1
+	}
+
+	author := stack.UpdatedBy
+	if author == "" {
+		author = stack.CreatedBy
+	}
+
+	version := &portainer.StackVersion{
+		StackID:         stack.ID,
+		Version:         nextVersion,
+		Files:           files,
+		EntryPoint:      stack.EntryPoint,
+		AdditionalFiles: stack.AdditionalFiles,
+		Env:             stack.Env,
+		ConfigHash:      configHash,
+		Author:          author,
+		CreationDate:    time.Now().Unix(),
+	}
+
+	err = dataStore.StackVersion().Create(version)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to persist the version of the stack %v", stack.ID)
+	}
+
+	for i := 0; i < len(versions)+1-MaxStackVersions; i++ {
+		err = dataStore.StackVersion().DeleteStackVersion(versions[i].ID)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to remove the version %d of the stack %v", versions[i].Version, stack.ID)
+		}
+	}
+
+	return version, nil
+}
+
+// SortStackVersions sorts the versions of a stack from the oldest to the most recent one
+func SortStackVersions(versions []portainer.StackVersion) {
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+}
+
+// StackVersionDiff returns the unified diff of the files and of the environment variables
+// between two revisions of a stack. previous can be nil for the first revision.
+func StackVersionDiff(previous, current *portainer.StackVersion) (string, error) {
+	previousFiles := map[string]string{}
+	previousVersion := 0
+	if previous != nil {
+		previousFiles = withEnvFile(previous.Files, previous.Env)
+		previousVersion = previous.Version
+	}
+	currentFiles := withEnvFile(current.Files, current.Env)
+
+	names := make([]string, 0, len(currentFiles))
+	for name := range currentFiles {
+		names = append(names, name)
+	}
+	for name := range previousFiles {
+		if _, ok := currentFiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diff strings.Builder
+	for _, name := range names {
+		fileDiff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(previousFiles[name]),
+			B:        difflib.SplitLines(currentFiles[name]),
+			FromFile: fmt.Sprintf("%s (version %d)", name, previousVersion),
+			ToFile:   fmt.Sprintf("%s (version %d)", name, current.Version),
+			Context:  3,
+		})
+		if err != nil {
+			return "", errors.WithMessagef(err, "failed to compute the diff of %s", name)
+		}
+
+		diff.WriteString(fileDiff)
+	}
+
+	return diff.String(), nil
+}
+
+func withEnvFile(files map[string]string, env []portainer.Pair) map[string]string {
+	result := make(map[string]string, len(files)+1)
+	for name, content := range files {
+		result[name] = content
+	}
+
+	if len(env) > 0 {
+		var content strings.Builder
+		for _, pair := range env {
+			fmt.Fprintf(&content, "%s=%s\n", pair.Name, pair.Value)
+		}
+
+		result[envFileName] = content.String()
+	}
+
+	return result
+}
+
+func envEqual(a, b []portainer.Pair) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
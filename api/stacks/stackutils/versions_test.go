@@ -0,0 +1,45 @@
+package stackutils
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_StackVersionDiff(t *testing.T) {
+	is := assert.New(t)
+
+	first := &portainer.StackVersion{
+		Version: 1,
+		Files:   map[string]string{"docker-compose.yml": "services:\n  web:\n    image: nginx:1.23\n"},
+	}
+
+	t.Run("first version is diffed against an empty stack", func(t *testing.T) {
+		diff, err := StackVersionDiff(nil, first)
+		is.NoError(err)
+		is.Contains(diff, "--- docker-compose.yml (version 0)")
+		is.Contains(diff, "+    image: nginx:1.23")
+	})
+
+	t.Run("changed files and environment variables are diffed", func(t *testing.T) {
+		second := &portainer.StackVersion{
+			Version: 2,
+			Files:   map[string]string{"docker-compose.yml": "services:\n  web:\n    image: nginx:1.24\n"},
+			Env:     []portainer.Pair{{Name: "PORT", Value: "8080"}},
+		}
+
+		diff, err := StackVersionDiff(first, second)
+		is.NoError(err)
+		is.Contains(diff, "-    image: nginx:1.23")
+		is.Contains(diff, "+    image: nginx:1.24")
+		is.Contains(diff, "+++ .env (version 2)")
+		is.Contains(diff, "+PORT=8080")
+	})
+
+	t.Run("identical versions have an empty diff", func(t *testing.T) {
+		diff, err := StackVersionDiff(first, first)
+		is.NoError(err)
+		is.Empty(diff)
+	})
+}
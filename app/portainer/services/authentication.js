@@ -61,8 +61,8 @@ angular.module('portainer.app').factory('Authentication', [
       return $async(initAsync);
     }
 
-    async function OAuthLoginAsync(code) {
-      const response = await OAuth.validate({ code: code }).$promise;
+    async function OAuthLoginAsync(code, state) {
+      const response = await OAuth.validate({ code: code, state: state }).$promise;
       const jwt = setJWTFromResponse(response);
       await setUser(jwt);
     }
@@ -74,8 +74,8 @@ angular.module('portainer.app').factory('Authentication', [
       return response.jwt;
     }
 
-    function OAuthLogin(code) {
-      return $async(OAuthLoginAsync, code);
+    function OAuthLogin(code, state) {
+      return $async(OAuthLoginAsync, code, state);
     }
 
     async function loginAsync(username, password) {
@@ -110,11 +110,25 @@ class AuthenticationController {
     return '&state=' + uuid;
   }
 
+  // the authorization requests initiated by Portainer hold their own state, checked by the server
+  isServerInitiatedOAuthLogin() {
+    return !!this.state.OAuthLoginURI && this.state.OAuthLoginURI.startsWith('api/auth/oauth/login');
+  }
+
   generateOAuthLoginURI() {
+    if (this.isServerInitiatedOAuthLogin()) {
+      this.OAuthLoginURI = this.state.OAuthLoginURI;
+      return;
+    }
+
     this.OAuthLoginURI = this.state.OAuthLoginURI + this.generateState();
   }
 
   hasValidState(state) {
+    if (this.isServerInitiatedOAuthLogin()) {
+      return !!state;
+    }
+
     const savedUUID = this.LocalStorage.getLoginStateUUID();
     return savedUUID && state && savedUUID === state;
   }
@@ -162,9 +176,9 @@ class AuthenticationController {
    * LOGIN METHODS SECTION
    */
 
-  async oAuthLoginAsync(code) {
+  async oAuthLoginAsync(code, state) {
     try {
-      await this.Authentication.OAuthLogin(code);
+      await this.Authentication.OAuthLogin(code, state);
       this.URLHelper.cleanParameters();
     } catch (err) {
       this.error(err, 'Unable to login via OAuth');
@@ -209,7 +223,7 @@ class AuthenticationController {
    */
   async manageOauthCodeReturn(code, state) {
     if (this.hasValidState(state)) {
-      await this.oAuthLoginAsync(code);
+      await this.oAuthLoginAsync(code, state);
     } else {
       this.error(null, 'Invalid OAuth state, try again.');
     }
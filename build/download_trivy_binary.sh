@@ -0,0 +1,31 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+if [[ $# -ne 3 ]]; then
+    echo "Illegal number of parameters" >&2
+    exit 1
+fi
+
+PLATFORM=$1
+ARCH=$2
+TRIVY_VERSION=$3
+
+case "$ARCH" in
+  amd64) TRIVY_ARCH="64bit" ;;
+  arm64) TRIVY_ARCH="ARM64" ;;
+  arm) TRIVY_ARCH="ARM" ;;
+  ppc64le) TRIVY_ARCH="PPC64LE" ;;
+  s390x) TRIVY_ARCH="s390x" ;;
+  *) echo "Unsupported architecture: $ARCH" >&2; exit 1 ;;
+esac
+
+TRIVY_DIST="trivy_${TRIVY_VERSION#v}"
+TRIVY_URL="https://github.com/aquasecurity/trivy/releases/download/${TRIVY_VERSION}"
+
+if [[ ${PLATFORM} == "windows" ]]; then
+  wget -O tmp.zip "${TRIVY_URL}/${TRIVY_DIST}_windows-${TRIVY_ARCH}.zip" && unzip -o -j tmp.zip "trivy.exe" -d dist && rm -f tmp.zip
+else
+  wget -qO- "${TRIVY_URL}/${TRIVY_DIST}_Linux-${TRIVY_ARCH}.tar.gz" | tar -x -z "trivy"
+  mv "trivy" "dist/trivy"
+  chmod +x "dist/trivy"
+fi
@@ -0,0 +1,42 @@
+package binary
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/portainer/portainer/pkg/libhelm/options"
+	"github.com/portainer/portainer/pkg/libhelm/release"
+)
+
+var errRequiredHistoryOptions = errors.New("release name is required")
+
+// History runs `helm history <name> --output json --namespace <namespace> --max <max>` with specified history options.
+// The history options translate to CLI arguments which are passed in to the helm binary when executing history.
+func (hbpm *helmBinaryPackageManager) History(historyOpts options.HistoryOptions) ([]release.ReleaseHistoryElement, error) {
+	if historyOpts.Name == "" {
+		return nil, errRequiredHistoryOptions
+	}
+
+	args := []string{historyOpts.Name, "--output", "json"}
+
+	if historyOpts.Namespace != "" {
+		args = append(args, "--namespace", historyOpts.Namespace)
+	}
+	if historyOpts.Max > 0 {
+		args = append(args, "--max", strconv.Itoa(historyOpts.Max))
+	}
+
+	result, err := hbpm.runWithKubeConfig("history", args, historyOpts.KubernetesClusterAccess, historyOpts.Env)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to run helm history on specified args")
+	}
+
+	response := []release.ReleaseHistoryElement{}
+	err = json.Unmarshal(result, &response)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal helm history response to ReleaseHistoryElement list")
+	}
+
+	return response, nil
+}
@@ -0,0 +1,101 @@
+package binary
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// indexCacheMetadata holds the validators of a cached repository index
+type indexCacheMetadata struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+}
+
+// indexCache stores the parsed repository indexes on disk, along with their metadata, keyed by repository URL
+type indexCache struct {
+	dir string
+}
+
+func newIndexCache(dir string) *indexCache {
+	return &indexCache{dir: dir}
+}
+
+func (cache *indexCache) paths(url string) (string, string) {
+	sum := sha256.Sum256([]byte(url))
+	key := hex.EncodeToString(sum[:])
+
+	return filepath.Join(cache.dir, key+".json"), filepath.Join(cache.dir, key+".meta.json")
+}
+
+// load returns the cached index of the repository, ok is false when the index is not cached
+func (cache *indexCache) load(url string) (metadata indexCacheMetadata, index []byte, ok bool) {
+	indexPath, metadataPath := cache.paths(url)
+
+	data, err := os.ReadFile(metadataPath)
+	if err != nil || json.Unmarshal(data, &metadata) != nil || metadata.URL != url {
+		return indexCacheMetadata{}, nil, false
+	}
+
+	index, err = os.ReadFile(indexPath)
+	if err != nil {
+		return indexCacheMetadata{}, nil, false
+	}
+
+	return metadata, index, true
+}
+
+// store caches the index of the repository, the index is written before its metadata
+// so that a metadata file always describes a complete index
+func (cache *indexCache) store(metadata indexCacheMetadata, index []byte) error {
+	indexPath, metadataPath := cache.paths(metadata.URL)
+
+	err := os.MkdirAll(cache.dir, 0700)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the index cache directory")
+	}
+
+	if index != nil {
+		err = writeFileAtomic(indexPath, index)
+		if err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the index cache metadata")
+	}
+
+	return writeFileAtomic(metadataPath, data)
+}
+
+// writeFileAtomic writes the file through a temporary file renamed once complete,
+// concurrent searches of the same repository never read a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to create the index cache file")
+	}
+
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return errors.Wrap(err, "failed to write the index cache file")
+	}
+
+	return nil
+}
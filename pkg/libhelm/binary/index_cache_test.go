@@ -0,0 +1,112 @@
+package binary
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/portainer/portainer/pkg/libhelm/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testIndex = `apiVersion: v1
+entries:
+  nginx:
+  - name: nginx
+    version: 1.0.0
+generated: "2022-01-01T00:00:00Z"
+`
+
+func Test_SearchRepo_cache(t *testing.T) {
+	is := assert.New(t)
+
+	requests, fullResponses := 0, 0
+	online := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if !online {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		fullResponses++
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(testIndex))
+	}))
+	defer server.Close()
+
+	opts := options.SearchRepoOptions{Repo: server.URL, CacheDir: t.TempDir(), CacheTTL: time.Hour}
+
+	first, err := SearchRepo(opts)
+	require.NoError(t, err)
+	is.Contains(string(first), `"nginx"`)
+	is.Equal(1, requests)
+
+	t.Run("the cached index is used during the TTL", func(t *testing.T) {
+		result, err := SearchRepo(opts)
+		require.NoError(t, err)
+		is.Equal(first, result)
+		is.Equal(1, requests)
+	})
+
+	opts.CacheTTL = 0
+
+	t.Run("the cached index is revalidated with its ETag once expired", func(t *testing.T) {
+		result, err := SearchRepo(opts)
+		require.NoError(t, err)
+		is.Equal(first, result)
+		is.Equal(2, requests)
+		is.Equal(1, fullResponses, "the index should not be downloaded again")
+	})
+
+	t.Run("the cached index is used when the repository is unavailable", func(t *testing.T) {
+		online = false
+		defer func() { online = true }()
+
+		result, err := SearchRepo(opts)
+		require.NoError(t, err)
+		is.Equal(first, result)
+	})
+
+	t.Run("the index is not cached without cache directory", func(t *testing.T) {
+		_, err := SearchRepo(options.SearchRepoOptions{Repo: server.URL})
+		require.NoError(t, err)
+		is.Equal(2, fullResponses)
+	})
+
+	t.Run("OCI repositories cannot be searched", func(t *testing.T) {
+		_, err := SearchRepo(options.SearchRepoOptions{Repo: "oci://registry.example.com/charts"})
+		is.ErrorIs(err, errSearchOCIRepository)
+	})
+}
+
+func Test_chartArgs(t *testing.T) {
+	is := assert.New(t)
+
+	args, registryConfigFile, err := chartArgs("nginx", "https://charts.bitnami.com/bitnami", &options.RegistryAccess{Username: "user"})
+	require.NoError(t, err)
+	is.Equal([]string{"nginx", "--repo", "https://charts.bitnami.com/bitnami"}, args)
+	is.Empty(registryConfigFile, "no registry config is required for HTTP repositories")
+
+	args, registryConfigFile, err = chartArgs("nginx", "oci://registry.example.com/charts/", nil)
+	require.NoError(t, err)
+	is.Equal([]string{"oci://registry.example.com/charts/nginx"}, args)
+	is.Empty(registryConfigFile)
+
+	args, registryConfigFile, err = chartArgs("oci://registry.example.com/charts/nginx", "", &options.RegistryAccess{Username: "user", Password: "pass"})
+	require.NoError(t, err)
+	is.Equal([]string{"oci://registry.example.com/charts/nginx", "--registry-config", registryConfigFile}, args)
+
+	config, err := os.ReadFile(registryConfigFile)
+	require.NoError(t, err)
+	os.Remove(registryConfigFile)
+	is.JSONEq(`{"auths":{"registry.example.com":{"auth":"dXNlcjpwYXNz"}}}`, string(config))
+}
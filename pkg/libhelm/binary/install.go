@@ -2,6 +2,7 @@ package binary
 
 import (
 	"encoding/json"
+	"os"
 
 	"github.com/pkg/errors"
 	"github.com/portainer/portainer/pkg/libhelm/options"
@@ -14,15 +15,22 @@ func (hbpm *helmBinaryPackageManager) Install(installOpts options.InstallOptions
 	if installOpts.Name == "" {
 		installOpts.Name = "--generate-name"
 	}
-	args := []string{
-		installOpts.Name,
-		installOpts.Chart,
-		"--repo", installOpts.Repo,
-		"--output", "json",
+	refArgs, registryConfigFile, err := chartArgs(installOpts.Chart, installOpts.Repo, installOpts.Registry)
+	if err != nil {
+		return nil, err
+	}
+	if registryConfigFile != "" {
+		defer os.Remove(registryConfigFile)
 	}
+
+	args := append([]string{installOpts.Name}, refArgs...)
+	args = append(args, "--output", "json")
 	if installOpts.Namespace != "" {
 		args = append(args, "--namespace", installOpts.Namespace)
 	}
+	if installOpts.Version != "" {
+		args = append(args, "--version", installOpts.Version)
+	}
 	if installOpts.ValuesFile != "" {
 		args = append(args, "--values", installOpts.ValuesFile)
 	}
@@ -0,0 +1,84 @@
+package binary
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/portainer/portainer/pkg/libhelm/options"
+)
+
+type registryAuth struct {
+	Auth string `json:"auth"`
+}
+
+type registryConfig struct {
+	Auths map[string]registryAuth `json:"auths"`
+}
+
+// WriteRegistryConfig writes a registry config file holding the credentials of the OCI registry of the chart reference,
+// in the format of the docker config file used by helm. The file must be removed by the caller.
+// It is shared by the HelmPackageManager implementations.
+func WriteRegistryConfig(chartRef string, registry *options.RegistryAccess) (string, error) {
+	if !options.IsOCIReference(chartRef) {
+		return "", errors.Errorf("not an OCI chart reference: %s", chartRef)
+	}
+
+	host, _, _ := strings.Cut(chartRef[len(options.OCIScheme):], "/")
+	if host == "" {
+		return "", errors.Errorf("invalid OCI chart reference: %s", chartRef)
+	}
+
+	config := registryConfig{
+		Auths: map[string]registryAuth{
+			host: {Auth: base64.StdEncoding.EncodeToString([]byte(registry.Username + ":" + registry.Password))},
+		},
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal the registry config")
+	}
+
+	file, err := os.CreateTemp("", "helm-registry-config")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create the registry config file")
+	}
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Close()
+	} else {
+		file.Close()
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", errors.Wrap(err, "failed to write the registry config file")
+	}
+
+	return file.Name(), nil
+}
+
+// chartArgs returns the arguments referencing the chart of install, upgrade and show along with the registry config file
+// holding the credentials of the OCI registry of the chart, if any. The registry config file must be removed by the caller.
+func chartArgs(chart, repo string, registry *options.RegistryAccess) ([]string, string, error) {
+	chartRef, repo := options.ResolveChart(chart, repo)
+
+	args := []string{chartRef}
+	if repo != "" {
+		args = append(args, "--repo", repo)
+	}
+
+	if registry == nil || !options.IsOCIReference(chartRef) {
+		return args, "", nil
+	}
+
+	registryConfigFile, err := WriteRegistryConfig(chartRef, registry)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return append(args, "--registry-config", registryConfigFile), registryConfigFile, nil
+}
@@ -16,7 +16,10 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-var errRequiredSearchOptions = errors.New("repo is required")
+var (
+	errRequiredSearchOptions = errors.New("repo is required")
+	errSearchOCIRepository   = errors.New("OCI repositories do not have an index and cannot be searched")
+)
 
 type File struct {
 	APIVersion string             `yaml:"apiVersion" json:"apiVersion"`
@@ -46,10 +49,22 @@ type Entry struct {
 // The functionality is similar to that of what `helm search repo [chart] --repo <repo>` CLI runs;
 // this approach is used instead since the `helm search repo` requires a repo to be added to the global helm cache
 func (hbpm *helmBinaryPackageManager) SearchRepo(searchRepoOpts options.SearchRepoOptions) ([]byte, error) {
+	return SearchRepo(searchRepoOpts)
+}
+
+// SearchRepo downloads and parses the `index.yaml` file of a repo, it is shared by the HelmPackageManager implementations.
+// When a cache directory is specified, the parsed index is cached on disk: it is used as is during the cache TTL,
+// then revalidated against the repository with its ETag and Last-Modified validators.
+// The cached index is also used when the repository cannot be reached.
+func SearchRepo(searchRepoOpts options.SearchRepoOptions) ([]byte, error) {
 	if searchRepoOpts.Repo == "" {
 		return nil, errRequiredSearchOptions
 	}
 
+	if options.IsOCIReference(searchRepoOpts.Repo) {
+		return nil, errSearchOCIRepository
+	}
+
 	client := searchRepoOpts.Client
 	if searchRepoOpts.Client == nil {
 		// The current index.yaml is ~9MB on bitnami.
@@ -67,12 +82,56 @@ func (hbpm *helmBinaryPackageManager) SearchRepo(searchRepoOpts options.SearchRe
 	}
 
 	url.Path = path.Join(url.Path, "index.yaml")
-	resp, err := client.Get(url.String())
+	indexURL := url.String()
+
+	var cache *indexCache
+	var cached indexCacheMetadata
+	var cachedIndex []byte
+	isCached := false
+	if searchRepoOpts.CacheDir != "" {
+		cache = newIndexCache(searchRepoOpts.CacheDir)
+		cached, cachedIndex, isCached = cache.load(indexURL)
+		if isCached && time.Since(cached.FetchedAt) < searchRepoOpts.CacheTTL {
+			return cachedIndex, nil
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, indexURL, nil)
 	if err != nil {
+		return nil, errors.Wrap(err, "failed to create index file request")
+	}
+	if isCached {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if isCached {
+			return cachedIndex, nil
+		}
 		return nil, errors.Wrap(err, "failed to get index file")
 	}
 	defer resp.Body.Close()
 
+	if isCached && resp.StatusCode == http.StatusNotModified {
+		cached.FetchedAt = time.Now()
+		// a failing cache must not fail the search
+		_ = cache.store(cached, nil)
+		return cachedIndex, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if isCached {
+			return cachedIndex, nil
+		}
+		return nil, errors.Errorf("failed to get index file: %s", resp.Status)
+	}
+
 	var file File
 	err = yaml.NewDecoder(resp.Body).Decode(&file)
 	if err != nil {
@@ -84,5 +143,15 @@ func (hbpm *helmBinaryPackageManager) SearchRepo(searchRepoOpts options.SearchRe
 		return nil, errors.Wrap(err, "failed to marshal index file")
 	}
 
+	if cache != nil {
+		// a failing cache must not fail the search
+		_ = cache.store(indexCacheMetadata{
+			URL:          indexURL,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			FetchedAt:    time.Now(),
+		}, result)
+	}
+
 	return result, nil
 }
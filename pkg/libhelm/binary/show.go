@@ -1,6 +1,8 @@
 package binary
 
 import (
+	"os"
+
 	"github.com/pkg/errors"
 	"github.com/portainer/portainer/pkg/libhelm/options"
 )
@@ -10,14 +12,21 @@ var errRequiredShowOptions = errors.New("chart, repo and output format are requi
 // Show runs `helm show <command> <chart> --repo <repo>` with specified show options.
 // The show options translate to CLI arguments which are passed in to the helm binary when executing install.
 func (hbpm *helmBinaryPackageManager) Show(showOpts options.ShowOptions) ([]byte, error) {
-	if showOpts.Chart == "" || showOpts.Repo == "" || showOpts.OutputFormat == "" {
+	if showOpts.Chart == "" || (showOpts.Repo == "" && !options.IsOCIReference(showOpts.Chart)) || showOpts.OutputFormat == "" {
 		return nil, errRequiredShowOptions
 	}
 
-	args := []string{
-		string(showOpts.OutputFormat),
-		showOpts.Chart,
-		"--repo", showOpts.Repo,
+	refArgs, registryConfigFile, err := chartArgs(showOpts.Chart, showOpts.Repo, showOpts.Registry)
+	if err != nil {
+		return nil, err
+	}
+	if registryConfigFile != "" {
+		defer os.Remove(registryConfigFile)
+	}
+
+	args := append([]string{string(showOpts.OutputFormat)}, refArgs...)
+	if showOpts.Version != "" {
+		args = append(args, "--version", showOpts.Version)
 	}
 
 	result, err := hbpm.run("show", args, showOpts.Env)
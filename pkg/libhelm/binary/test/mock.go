@@ -2,6 +2,7 @@ package test
 
 import (
 	"encoding/json"
+	"fmt"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -37,6 +38,33 @@ func NewMockHelmBinaryPackageManager(binaryPath string) libhelm.HelmPackageManag
 
 var mockCharts = []release.ReleaseElement{}
 
+// mockHistories holds the revisions of the releases, keyed by namespace and name
+var mockHistories = map[string][]release.ReleaseHistoryElement{}
+
+func mockHistoryKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// addMockRevision records a new deployed revision of a release and returns its number (not thread safe)
+func addMockRevision(namespace, name, chart, description string) int {
+	key := mockHistoryKey(namespace, name)
+	history := mockHistories[key]
+	for i := range history {
+		history[i].Status = "superseded"
+	}
+
+	revision := len(history) + 1
+	mockHistories[key] = append(history, release.ReleaseHistoryElement{
+		Revision:    revision,
+		Status:      "deployed",
+		Chart:       chart,
+		AppVersion:  "1.2.3",
+		Description: description,
+	})
+
+	return revision
+}
+
 func newMockReleaseElement(installOpts options.InstallOptions) *release.ReleaseElement {
 	return &release.ReleaseElement{
 		Name:       installOpts.Name,
@@ -68,6 +96,9 @@ func (hpm *helmMockPackageManager) Install(installOpts options.InstallOptions) (
 		}
 	}
 
+	delete(mockHistories, mockHistoryKey(installOpts.Namespace, installOpts.Name))
+	releaseElement.Revision = fmt.Sprint(addMockRevision(installOpts.Namespace, installOpts.Name, installOpts.Chart, "Install complete"))
+
 	mockCharts = append(mockCharts, *releaseElement)
 	return newMockRelease(releaseElement), nil
 }
@@ -110,6 +141,70 @@ func (hpm *helmMockPackageManager) Uninstall(uninstallOpts options.UninstallOpti
 			mockCharts = append(mockCharts[:i], mockCharts[i+1:]...)
 		}
 	}
+	delete(mockHistories, mockHistoryKey(uninstallOpts.Namespace, uninstallOpts.Name))
+	return nil
+}
+
+// Upgrade a helm release, installing it when requested (not thread safe)
+func (hpm *helmMockPackageManager) Upgrade(upgradeOpts options.UpgradeOptions) (*release.Release, error) {
+	for i, rel := range mockCharts {
+		if rel.Name == upgradeOpts.Name && rel.Namespace == upgradeOpts.Namespace {
+			revision := addMockRevision(upgradeOpts.Namespace, upgradeOpts.Name, upgradeOpts.Chart, "Upgrade complete")
+			mockCharts[i].Chart = upgradeOpts.Chart
+			mockCharts[i].Revision = fmt.Sprint(revision)
+
+			upgraded := newMockRelease(&mockCharts[i])
+			upgraded.Version = revision
+			return upgraded, nil
+		}
+	}
+
+	if !upgradeOpts.Install {
+		return nil, fmt.Errorf("%q has no deployed releases", upgradeOpts.Name)
+	}
+
+	return hpm.Install(options.InstallOptions{Name: upgradeOpts.Name, Chart: upgradeOpts.Chart, Namespace: upgradeOpts.Namespace, Repo: upgradeOpts.Repo})
+}
+
+// History of a helm release (not thread safe)
+func (hpm *helmMockPackageManager) History(historyOpts options.HistoryOptions) ([]release.ReleaseHistoryElement, error) {
+	history, ok := mockHistories[mockHistoryKey(historyOpts.Namespace, historyOpts.Name)]
+	if !ok {
+		return nil, errors.New("release: not found")
+	}
+
+	if historyOpts.Max > 0 && len(history) > historyOpts.Max {
+		history = history[len(history)-historyOpts.Max:]
+	}
+
+	return history, nil
+}
+
+// Rollback a helm release to a previous revision (not thread safe)
+func (hpm *helmMockPackageManager) Rollback(rollbackOpts options.RollbackOptions) error {
+	history, ok := mockHistories[mockHistoryKey(rollbackOpts.Namespace, rollbackOpts.Name)]
+	if !ok {
+		return errors.New("release: not found")
+	}
+
+	target := rollbackOpts.Revision
+	if target == 0 {
+		target = len(history) - 1
+	}
+	if target < 1 || target > len(history) {
+		return fmt.Errorf("release has no %d version", target)
+	}
+
+	chart := history[target-1].Chart
+	revision := addMockRevision(rollbackOpts.Namespace, rollbackOpts.Name, chart, fmt.Sprintf("Rollback to %d", target))
+
+	for i, rel := range mockCharts {
+		if rel.Name == rollbackOpts.Name && rel.Namespace == rollbackOpts.Namespace {
+			mockCharts[i].Chart = chart
+			mockCharts[i].Revision = fmt.Sprint(revision)
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,65 @@
+package binary
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/portainer/portainer/pkg/libhelm/options"
+	"github.com/portainer/portainer/pkg/libhelm/release"
+)
+
+var errRequiredUpgradeOptions = errors.New("release name and chart are required")
+
+// Upgrade runs `helm upgrade` with specified upgrade options.
+// The upgrade options translate to CLI arguments which are passed in to the helm binary when executing upgrade.
+func (hbpm *helmBinaryPackageManager) Upgrade(upgradeOpts options.UpgradeOptions) (*release.Release, error) {
+	if upgradeOpts.Name == "" || upgradeOpts.Chart == "" {
+		return nil, errRequiredUpgradeOptions
+	}
+
+	refArgs, registryConfigFile, err := chartArgs(upgradeOpts.Chart, upgradeOpts.Repo, upgradeOpts.Registry)
+	if err != nil {
+		return nil, err
+	}
+	if registryConfigFile != "" {
+		defer os.Remove(registryConfigFile)
+	}
+
+	args := append([]string{upgradeOpts.Name}, refArgs...)
+	args = append(args, "--output", "json")
+	if upgradeOpts.Namespace != "" {
+		args = append(args, "--namespace", upgradeOpts.Namespace)
+	}
+	if upgradeOpts.Version != "" {
+		args = append(args, "--version", upgradeOpts.Version)
+	}
+	if upgradeOpts.ValuesFile != "" {
+		args = append(args, "--values", upgradeOpts.ValuesFile)
+	}
+	if upgradeOpts.ReuseValues {
+		args = append(args, "--reuse-values")
+	}
+	if upgradeOpts.Wait {
+		args = append(args, "--wait")
+	}
+	if upgradeOpts.PostRenderer != "" {
+		args = append(args, "--post-renderer", upgradeOpts.PostRenderer)
+	}
+	if upgradeOpts.Install {
+		args = append(args, "--install")
+	}
+
+	result, err := hbpm.runWithKubeConfig("upgrade", args, upgradeOpts.KubernetesClusterAccess, upgradeOpts.Env)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to run helm upgrade on specified args")
+	}
+
+	response := &release.Release{}
+	err = json.Unmarshal(result, &response)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal helm upgrade response to Release struct")
+	}
+
+	return response, nil
+}
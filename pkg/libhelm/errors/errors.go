@@ -0,0 +1,16 @@
+// Package errors defines the errors returned by the HelmPackageManager implementations,
+// they can be matched with errors.Is.
+package errors
+
+import "errors"
+
+var (
+	// ErrReleaseNotFound is returned when the release does not exist
+	ErrReleaseNotFound = errors.New("release not found")
+	// ErrReleaseAlreadyExists is returned when installing a release whose name is already in use
+	ErrReleaseAlreadyExists = errors.New("release already exists")
+	// ErrChartNotFound is returned when the chart cannot be located
+	ErrChartNotFound = errors.New("chart not found")
+	// ErrInvalidOptions is returned when required options are missing
+	ErrInvalidOptions = errors.New("invalid options")
+)
@@ -13,4 +13,7 @@ type HelmPackageManager interface {
 	List(listOpts options.ListOptions) ([]release.ReleaseElement, error)
 	Install(installOpts options.InstallOptions) (*release.Release, error)
 	Uninstall(uninstallOpts options.UninstallOptions) error
+	Upgrade(upgradeOpts options.UpgradeOptions) (*release.Release, error)
+	History(historyOpts options.HistoryOptions) ([]release.ReleaseHistoryElement, error)
+	Rollback(rollbackOpts options.RollbackOptions) error
 }
@@ -2,12 +2,23 @@ package libhelm
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/portainer/portainer/pkg/libhelm/binary"
 )
 
+// Supported Helm backends
+const (
+	// BackendBinary runs the helm operations with the helm binary
+	BackendBinary = "binary"
+	// BackendSDK runs the helm operations with the Helm Go SDK, it requires the helmsdk build tag
+	BackendSDK = "sdk"
+)
+
 // HelmConfig is a struct that holds the configuration for the Helm package manager
 type HelmConfig struct {
+	// Backend used to run the helm operations, defaults to binary
+	Backend    string `example:"binary"`
 	BinaryPath string `example:"/portainer/dist"`
 }
 
@@ -15,8 +26,15 @@ var errBinaryPathNotSpecified = errors.New("binary path not specified")
 
 // NewHelmPackageManager returns a new instance of HelmPackageManager based on HelmConfig
 func NewHelmPackageManager(config HelmConfig) (HelmPackageManager, error) {
-	if config.BinaryPath != "" {
-		return binary.NewHelmBinaryPackageManager(config.BinaryPath), nil
+	switch config.Backend {
+	case "", BackendBinary:
+		if config.BinaryPath != "" {
+			return binary.NewHelmBinaryPackageManager(config.BinaryPath), nil
+		}
+		return nil, errBinaryPathNotSpecified
+	case BackendSDK:
+		return newSDKPackageManager()
 	}
-	return nil, errBinaryPathNotSpecified
+
+	return nil, fmt.Errorf("unsupported helm backend %q", config.Backend)
 }
@@ -0,0 +1,11 @@
+//go:build !helmsdk
+
+package libhelm
+
+import "errors"
+
+var errSDKBackendNotBuilt = errors.New("the helm SDK backend is not included in this build, build with the helmsdk tag to enable it")
+
+func newSDKPackageManager() (HelmPackageManager, error) {
+	return nil, errSDKBackendNotBuilt
+}
@@ -0,0 +1,9 @@
+//go:build helmsdk
+
+package libhelm
+
+import "github.com/portainer/portainer/pkg/libhelm/sdk"
+
+func newSDKPackageManager() (HelmPackageManager, error) {
+	return sdk.NewHelmSDKPackageManager(), nil
+}
@@ -0,0 +1,12 @@
+package options
+
+// HistoryOptions are portainer supported options for `helm history`
+type HistoryOptions struct {
+	Name      string
+	Namespace string
+	// Maximum number of revisions to return, all the revisions are returned when 0
+	Max                     int
+	KubernetesClusterAccess *KubernetesClusterAccess
+
+	Env []string
+}
@@ -5,10 +5,13 @@ type InstallOptions struct {
 	Chart                   string
 	Namespace               string
 	Repo                    string
+	Version                 string
 	Wait                    bool
 	ValuesFile              string
 	PostRenderer            string
 	KubernetesClusterAccess *KubernetesClusterAccess
+	// Credentials of the OCI registry storing the chart
+	Registry *RegistryAccess
 
 	// Optional environment vars to pass when running helm
 	Env []string
@@ -0,0 +1,31 @@
+package options
+
+import "strings"
+
+// OCIScheme is the scheme of the chart references stored in an OCI registry
+const OCIScheme = "oci://"
+
+// RegistryAccess holds the credentials used to pull a chart from an OCI registry
+type RegistryAccess struct {
+	Username string
+	Password string
+}
+
+// IsOCIReference returns true when the chart reference or the repository is stored in an OCI registry
+func IsOCIReference(ref string) bool {
+	return strings.HasPrefix(strings.ToLower(ref), OCIScheme)
+}
+
+// ResolveChart returns the chart reference and the repository to pass to helm.
+// The charts of an OCI repository are referenced by their full OCI reference, without repository.
+func ResolveChart(chart, repo string) (string, string) {
+	if IsOCIReference(chart) {
+		return chart, ""
+	}
+
+	if IsOCIReference(repo) {
+		return strings.TrimSuffix(repo, "/") + "/" + chart, ""
+	}
+
+	return chart, repo
+}
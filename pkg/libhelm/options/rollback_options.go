@@ -0,0 +1,13 @@
+package options
+
+// RollbackOptions are portainer supported options for `helm rollback`
+type RollbackOptions struct {
+	Name      string
+	Namespace string
+	// Revision to roll back to, the release is rolled back to its previous revision when 0
+	Revision                int
+	Wait                    bool
+	KubernetesClusterAccess *KubernetesClusterAccess
+
+	Env []string
+}
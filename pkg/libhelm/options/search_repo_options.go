@@ -1,8 +1,15 @@
 package options
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 type SearchRepoOptions struct {
 	Repo   string       `example:"https://charts.gitlab.io/"`
 	Client *http.Client `example:"&http.Client{Timeout: time.Second * 10}"`
+	// Directory where the repository indexes are cached, the indexes are not cached when empty
+	CacheDir string
+	// Duration during which a cached index is used without being revalidated against the repository
+	CacheTTL time.Duration
 }
@@ -19,6 +19,10 @@ type ShowOptions struct {
 	OutputFormat ShowOutputFormat
 	Chart        string
 	Repo         string
+	// Version of the chart, the latest version is used when empty
+	Version string
+	// Credentials of the OCI registry storing the chart
+	Registry *RegistryAccess
 
 	Env []string
 }
@@ -0,0 +1,23 @@
+package options
+
+// UpgradeOptions are portainer supported options for `helm upgrade`
+type UpgradeOptions struct {
+	Name      string
+	Chart     string
+	Namespace string
+	Repo      string
+	// Version of the chart, the latest version is used when empty
+	Version      string
+	Wait         bool
+	ValuesFile   string
+	ReuseValues  bool
+	PostRenderer string
+	// Install the release when it does not exist yet
+	Install                 bool
+	KubernetesClusterAccess *KubernetesClusterAccess
+	// Credentials of the OCI registry storing the chart
+	Registry *RegistryAccess
+
+	// Optional environment vars to pass when running helm
+	Env []string
+}
@@ -0,0 +1,91 @@
+//go:build helmsdk
+
+package sdk
+
+import (
+	"bytes"
+	"fmt"
+
+	libhelmerrors "github.com/portainer/portainer/pkg/libhelm/errors"
+	"github.com/portainer/portainer/pkg/libhelm/options"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/action"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+)
+
+// Get returns the hooks, manifest, notes and/or values of a release, like `helm get` does.
+func (hspm *helmSDKPackageManager) Get(getOpts options.GetOptions) ([]byte, error) {
+	if getOpts.Name == "" || getOpts.ReleaseResource == "" {
+		return nil, fmt.Errorf("%w: release name and release resource are required", libhelmerrors.ErrInvalidOptions)
+	}
+
+	config, err := hspm.actionConfig(getOpts.Namespace, getOpts.KubernetesClusterAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	rel, err := action.NewGet(config).Run(getOpts.Name)
+	if err != nil {
+		return nil, errors.Wrap(translateError(err, getOpts.Name), "failed to get the release")
+	}
+
+	var out bytes.Buffer
+	switch getOpts.ReleaseResource {
+	case options.GetAll:
+		err = writeValues(&out, rel)
+		if err == nil {
+			out.WriteString("\nHOOKS:\n")
+			writeHooks(&out, rel)
+			out.WriteString("MANIFEST:\n")
+			out.WriteString(rel.Manifest)
+			out.WriteString("\nNOTES:\n")
+			writeNotes(&out, rel)
+		}
+	case options.GetHooks:
+		writeHooks(&out, rel)
+	case options.GetManifest:
+		out.WriteString(rel.Manifest)
+	case options.GetNotes:
+		writeNotes(&out, rel)
+	case options.GetValues:
+		err = writeValues(&out, rel)
+	default:
+		return nil, fmt.Errorf("%w: invalid release resource %q", libhelmerrors.ErrInvalidOptions, getOpts.ReleaseResource)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// writeValues writes the user supplied values of the release
+func writeValues(out *bytes.Buffer, rel *helmrelease.Release) error {
+	out.WriteString("USER-SUPPLIED VALUES:\n")
+	if len(rel.Config) == 0 {
+		out.WriteString("null\n")
+		return nil
+	}
+
+	err := yaml.NewEncoder(out).Encode(rel.Config)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the release values")
+	}
+
+	return nil
+}
+
+func writeHooks(out *bytes.Buffer, rel *helmrelease.Release) {
+	for _, hook := range rel.Hooks {
+		fmt.Fprintf(out, "---\n# Source: %s\n%s\n", hook.Path, hook.Manifest)
+	}
+}
+
+func writeNotes(out *bytes.Buffer, rel *helmrelease.Release) {
+	if rel.Info != nil && rel.Info.Notes != "" {
+		out.WriteString(rel.Info.Notes)
+		out.WriteString("\n")
+	}
+}
@@ -0,0 +1,175 @@
+//go:build helmsdk
+
+// Package sdk implements HelmPackageManager with the Helm Go SDK, no helm binary is required.
+//
+// The package is only built with the helmsdk build tag as it pulls the Helm SDK and its Kubernetes dependencies:
+//
+//	go get helm.sh/helm/v3@v3.11.2
+//	go build -tags helmsdk ./...
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/portainer/portainer/pkg/libhelm/binary"
+	libhelmerrors "github.com/portainer/portainer/pkg/libhelm/errors"
+	"github.com/portainer/portainer/pkg/libhelm/options"
+	"github.com/portainer/portainer/pkg/libhelm/release"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
+	helmregistry "helm.sh/helm/v3/pkg/registry"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// defaultTimeout is the default timeout of the helm operations, as for the helm CLI
+const defaultTimeout = 5 * time.Minute
+
+// helmStorageDriver is the storage driver of the releases, as for the helm CLI
+const helmStorageDriver = "secret"
+
+// actionConfigFunc returns the helm action configuration used to run the operations against a cluster
+type actionConfigFunc func(namespace string, kca *options.KubernetesClusterAccess) (*action.Configuration, error)
+
+// helmSDKPackageManager is a wrapper for the Helm Go SDK which implements HelmPackageManager
+type helmSDKPackageManager struct {
+	settings     *cli.EnvSettings
+	actionConfig actionConfigFunc
+}
+
+// NewHelmSDKPackageManager initializes a new HelmPackageManager service using the Helm Go SDK.
+func NewHelmSDKPackageManager() *helmSDKPackageManager {
+	return &helmSDKPackageManager{
+		settings:     cli.New(),
+		actionConfig: newActionConfig,
+	}
+}
+
+// newActionConfig builds the helm action configuration of the cluster described by the cluster access.
+// The kubeconfig of the environment is used when the cluster access is nil.
+func newActionConfig(namespace string, kca *options.KubernetesClusterAccess) (*action.Configuration, error) {
+	flags := genericclioptions.NewConfigFlags(false)
+	flags.Namespace = &namespace
+	if kca != nil {
+		flags.APIServer = &kca.ClusterServerURL
+		flags.BearerToken = &kca.AuthToken
+		flags.CAFile = &kca.CertificateAuthorityFile
+	}
+
+	config := new(action.Configuration)
+	err := config.Init(flags, namespace, helmStorageDriver, func(format string, v ...interface{}) {})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to initialize the helm configuration")
+	}
+
+	return config, nil
+}
+
+// loadChart locates the chart, downloading it from its repository when needed, and loads it
+func (hspm *helmSDKPackageManager) loadChart(chartPathOptions *action.ChartPathOptions, name string) (*chart.Chart, error) {
+	chartPath, err := chartPathOptions.LocateChart(name, hspm.settings)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", libhelmerrors.ErrChartNotFound, err)
+	}
+
+	loadedChart, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load chart %s", name)
+	}
+
+	return loadedChart, nil
+}
+
+// newRegistryClient returns the client pulling the OCI charts, authenticated with the credentials of the registry if any.
+// The returned function removes the credentials file and must be called once the chart is pulled.
+func newRegistryClient(chartRef string, registry *options.RegistryAccess) (*helmregistry.Client, func(), error) {
+	if registry == nil {
+		client, err := helmregistry.NewClient()
+		return client, func() {}, errors.Wrap(err, "failed to create the registry client")
+	}
+
+	registryConfigFile, err := binary.WriteRegistryConfig(chartRef, registry)
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.Remove(registryConfigFile) }
+
+	client, err := helmregistry.NewClient(helmregistry.ClientOptCredentialsFile(registryConfigFile))
+	if err != nil {
+		cleanup()
+		return nil, nil, errors.Wrap(err, "failed to create the registry client")
+	}
+
+	return client, cleanup, nil
+}
+
+// readValues reads the values file, no values are returned when the path is empty
+func readValues(valuesFile string) (map[string]interface{}, error) {
+	if valuesFile == "" {
+		return map[string]interface{}{}, nil
+	}
+
+	values, err := chartutil.ReadValuesFile(valuesFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the values file")
+	}
+
+	return values.AsMap(), nil
+}
+
+// convertRelease converts a release of the SDK to the release of libhelm, which share the same JSON representation
+func convertRelease(rel *helmrelease.Release) (*release.Release, error) {
+	data, err := json.Marshal(rel)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal the release")
+	}
+
+	response := &release.Release{}
+	err = json.Unmarshal(data, response)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the release to Release struct")
+	}
+
+	return response, nil
+}
+
+// chartName returns the name of the chart of a release as displayed by the helm CLI
+func chartName(rel *helmrelease.Release) string {
+	if rel.Chart == nil || rel.Chart.Metadata == nil {
+		return "MISSING"
+	}
+
+	return fmt.Sprintf("%s-%s", rel.Chart.Metadata.Name, rel.Chart.Metadata.Version)
+}
+
+func appVersion(rel *helmrelease.Release) string {
+	if rel.Chart == nil || rel.Chart.Metadata == nil {
+		return ""
+	}
+
+	return rel.Chart.Metadata.AppVersion
+}
+
+// translateError converts the errors of the SDK to the typed errors of libhelm
+func translateError(err error, name string) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, driver.ErrReleaseNotFound), strings.Contains(err.Error(), "has no deployed releases"):
+		return fmt.Errorf("%w: %s", libhelmerrors.ErrReleaseNotFound, name)
+	case strings.Contains(err.Error(), "cannot re-use a name that is still in use"):
+		return fmt.Errorf("%w: %s", libhelmerrors.ErrReleaseAlreadyExists, name)
+	}
+
+	return err
+}
@@ -0,0 +1,117 @@
+//go:build helmsdk
+
+package sdk
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	libhelmerrors "github.com/portainer/portainer/pkg/libhelm/errors"
+	"github.com/portainer/portainer/pkg/libhelm/options"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// newTestPackageManager returns a package manager which runs against a fake Kubernetes client
+// and stores the releases in memory
+func newTestPackageManager(t *testing.T) *helmSDKPackageManager {
+	registryClient, err := registry.NewClient()
+	require.NoError(t, err)
+
+	config := &action.Configuration{
+		Releases:       storage.Init(driver.NewMemory()),
+		KubeClient:     &kubefake.PrintingKubeClient{Out: io.Discard},
+		Capabilities:   chartutil.DefaultCapabilities,
+		RegistryClient: registryClient,
+		Log:            func(format string, v ...interface{}) {},
+	}
+
+	return &helmSDKPackageManager{
+		settings: cli.New(),
+		actionConfig: func(namespace string, kca *options.KubernetesClusterAccess) (*action.Configuration, error) {
+			return config, nil
+		},
+	}
+}
+
+// writeTestChart writes a minimal chart in a temporary directory and returns its path
+func writeTestChart(t *testing.T, version string) string {
+	dir := filepath.Join(t.TempDir(), "nginx")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "templates"), 0755))
+
+	files := map[string]string{
+		"Chart.yaml":               "apiVersion: v2\nname: nginx\nversion: " + version + "\nappVersion: \"1.23\"\n",
+		"values.yaml":              "replicaCount: 1\n",
+		"templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}\ndata:\n  replicas: \"{{ .Values.replicaCount }}\"\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	return dir
+}
+
+func Test_helmSDKPackageManager(t *testing.T) {
+	is := assert.New(t)
+
+	hspm := newTestPackageManager(t)
+
+	valuesFile := filepath.Join(t.TempDir(), "values.yaml")
+	require.NoError(t, os.WriteFile(valuesFile, []byte("replicaCount: 3\n"), 0644))
+
+	rel, err := hspm.Install(options.InstallOptions{Name: "nginx", Chart: writeTestChart(t, "1.0.0"), Namespace: "default", ValuesFile: valuesFile})
+	require.NoError(t, err)
+	is.Equal("nginx", rel.Name)
+	is.Equal(1, rel.Version)
+	is.Contains(rel.Manifest, `replicas: "3"`)
+
+	_, err = hspm.Install(options.InstallOptions{Name: "nginx", Chart: writeTestChart(t, "1.0.0"), Namespace: "default"})
+	is.ErrorIs(err, libhelmerrors.ErrReleaseAlreadyExists)
+
+	releases, err := hspm.List(options.ListOptions{Namespace: "default"})
+	require.NoError(t, err)
+	require.Len(t, releases, 1)
+	is.Equal("nginx-1.0.0", releases[0].Chart)
+	is.Equal("deployed", releases[0].Status)
+
+	values, err := hspm.Get(options.GetOptions{Name: "nginx", Namespace: "default", ReleaseResource: options.GetValues})
+	require.NoError(t, err)
+	is.Contains(string(values), "replicaCount: 3")
+
+	rel, err = hspm.Upgrade(options.UpgradeOptions{Name: "nginx", Chart: writeTestChart(t, "2.0.0"), Namespace: "default"})
+	require.NoError(t, err)
+	is.Equal(2, rel.Version)
+
+	is.NoError(hspm.Rollback(options.RollbackOptions{Name: "nginx", Namespace: "default", Revision: 1}))
+
+	history, err := hspm.History(options.HistoryOptions{Name: "nginx", Namespace: "default"})
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	is.Equal("superseded", history[0].Status)
+	is.Equal("nginx-1.0.0", history[2].Chart)
+	is.Equal("Rollback to 1", history[2].Description)
+
+	history, err = hspm.History(options.HistoryOptions{Name: "nginx", Namespace: "default", Max: 1})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	is.Equal(3, history[0].Revision)
+
+	is.NoError(hspm.Uninstall(options.UninstallOptions{Name: "nginx", Namespace: "default"}))
+
+	_, err = hspm.History(options.HistoryOptions{Name: "nginx", Namespace: "default"})
+	is.ErrorIs(err, libhelmerrors.ErrReleaseNotFound)
+
+	rel, err = hspm.Upgrade(options.UpgradeOptions{Name: "nginx-new", Chart: writeTestChart(t, "1.0.0"), Namespace: "default", Install: true})
+	require.NoError(t, err, "upgrade should install a missing release")
+	is.Equal(1, rel.Version)
+}
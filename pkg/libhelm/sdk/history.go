@@ -0,0 +1,60 @@
+//go:build helmsdk
+
+package sdk
+
+import (
+	"fmt"
+	"sort"
+
+	libhelmerrors "github.com/portainer/portainer/pkg/libhelm/errors"
+	"github.com/portainer/portainer/pkg/libhelm/options"
+	"github.com/portainer/portainer/pkg/libhelm/release"
+	"github.com/portainer/portainer/pkg/libhelm/time"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+// History returns the revisions of a release from the oldest to the most recent one, like `helm history` does.
+func (hspm *helmSDKPackageManager) History(historyOpts options.HistoryOptions) ([]release.ReleaseHistoryElement, error) {
+	if historyOpts.Name == "" {
+		return nil, fmt.Errorf("%w: release name is required", libhelmerrors.ErrInvalidOptions)
+	}
+
+	config, err := hspm.actionConfig(historyOpts.Namespace, historyOpts.KubernetesClusterAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	releases, err := action.NewHistory(config).Run(historyOpts.Name)
+	if err != nil {
+		return nil, errors.Wrap(translateError(err, historyOpts.Name), "failed to retrieve the release history")
+	}
+
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].Version < releases[j].Version
+	})
+
+	if historyOpts.Max > 0 && len(releases) > historyOpts.Max {
+		releases = releases[len(releases)-historyOpts.Max:]
+	}
+
+	response := make([]release.ReleaseHistoryElement, 0, len(releases))
+	for _, rel := range releases {
+		element := release.ReleaseHistoryElement{
+			Revision:   rel.Version,
+			Chart:      chartName(rel),
+			AppVersion: appVersion(rel),
+		}
+
+		if rel.Info != nil {
+			element.Status = rel.Info.Status.String()
+			element.Description = rel.Info.Description
+			element.Updated = time.Time{Time: rel.Info.LastDeployed.Time}
+		}
+
+		response = append(response, element)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,78 @@
+//go:build helmsdk
+
+package sdk
+
+import (
+	"fmt"
+
+	libhelmerrors "github.com/portainer/portainer/pkg/libhelm/errors"
+	"github.com/portainer/portainer/pkg/libhelm/options"
+	"github.com/portainer/portainer/pkg/libhelm/release"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/postrender"
+)
+
+// Install installs a chart with the specified install options, like `helm install` does.
+// A name is generated for the release when the name is empty.
+func (hspm *helmSDKPackageManager) Install(installOpts options.InstallOptions) (*release.Release, error) {
+	if installOpts.Chart == "" {
+		return nil, fmt.Errorf("%w: chart is required", libhelmerrors.ErrInvalidOptions)
+	}
+
+	config, err := hspm.actionConfig(installOpts.Namespace, installOpts.KubernetesClusterAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	chartRef, repo := options.ResolveChart(installOpts.Chart, installOpts.Repo)
+
+	if options.IsOCIReference(chartRef) {
+		registryClient, cleanup, err := newRegistryClient(chartRef, installOpts.Registry)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		config.RegistryClient = registryClient
+	}
+
+	client := action.NewInstall(config)
+	client.Namespace = installOpts.Namespace
+	client.RepoURL = repo
+	client.Version = installOpts.Version
+	client.Wait = installOpts.Wait
+	client.Timeout = defaultTimeout
+	client.ReleaseName = installOpts.Name
+	if installOpts.Name == "" {
+		client.GenerateName = true
+		client.ReleaseName, _, err = client.NameAndChart([]string{chartRef})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to generate a release name")
+		}
+	}
+
+	if installOpts.PostRenderer != "" {
+		client.PostRenderer, err = postrender.NewExec(installOpts.PostRenderer)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to initialize the post renderer")
+		}
+	}
+
+	chart, err := hspm.loadChart(&client.ChartPathOptions, chartRef)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := readValues(installOpts.ValuesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	rel, err := client.Run(chart, values)
+	if err != nil {
+		return nil, errors.Wrap(translateError(err, client.ReleaseName), "failed to install the chart")
+	}
+
+	return convertRelease(rel)
+}
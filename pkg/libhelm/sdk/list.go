@@ -0,0 +1,53 @@
+//go:build helmsdk
+
+package sdk
+
+import (
+	"strconv"
+
+	"github.com/portainer/portainer/pkg/libhelm/options"
+	"github.com/portainer/portainer/pkg/libhelm/release"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+// List lists the releases with the specified list options, like `helm list` does.
+func (hspm *helmSDKPackageManager) List(listOpts options.ListOptions) ([]release.ReleaseElement, error) {
+	config, err := hspm.actionConfig(listOpts.Namespace, listOpts.KubernetesClusterAccess)
+	if err != nil {
+		return []release.ReleaseElement{}, err
+	}
+
+	client := action.NewList(config)
+	client.Filter = listOpts.Filter
+	client.Selector = listOpts.Selector
+	client.SetStateMask()
+
+	releases, err := client.Run()
+	if err != nil {
+		return []release.ReleaseElement{}, errors.Wrap(err, "failed to list the releases")
+	}
+
+	response := make([]release.ReleaseElement, 0, len(releases))
+	for _, rel := range releases {
+		element := release.ReleaseElement{
+			Name:       rel.Name,
+			Namespace:  rel.Namespace,
+			Revision:   strconv.Itoa(rel.Version),
+			Chart:      chartName(rel),
+			AppVersion: appVersion(rel),
+		}
+
+		if rel.Info != nil {
+			element.Status = rel.Info.Status.String()
+			if !rel.Info.LastDeployed.IsZero() {
+				element.Updated = rel.Info.LastDeployed.String()
+			}
+		}
+
+		response = append(response, element)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,38 @@
+//go:build helmsdk
+
+package sdk
+
+import (
+	"fmt"
+
+	libhelmerrors "github.com/portainer/portainer/pkg/libhelm/errors"
+	"github.com/portainer/portainer/pkg/libhelm/options"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+// Rollback rolls a release back to a previous revision, like `helm rollback` does.
+// The release is rolled back to its previous revision when the revision is 0.
+func (hspm *helmSDKPackageManager) Rollback(rollbackOpts options.RollbackOptions) error {
+	if rollbackOpts.Name == "" {
+		return fmt.Errorf("%w: release name is required", libhelmerrors.ErrInvalidOptions)
+	}
+
+	config, err := hspm.actionConfig(rollbackOpts.Namespace, rollbackOpts.KubernetesClusterAccess)
+	if err != nil {
+		return err
+	}
+
+	client := action.NewRollback(config)
+	client.Version = rollbackOpts.Revision
+	client.Wait = rollbackOpts.Wait
+	client.Timeout = defaultTimeout
+
+	err = client.Run(rollbackOpts.Name)
+	if err != nil {
+		return errors.Wrap(translateError(err, rollbackOpts.Name), "failed to roll back the release")
+	}
+
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build helmsdk
+
+package sdk
+
+import (
+	"github.com/portainer/portainer/pkg/libhelm/binary"
+	"github.com/portainer/portainer/pkg/libhelm/options"
+)
+
+// SearchRepo downloads the `index.yaml` file of the repo, parses it and returns JSON to caller.
+// The repository index is downloaded directly, as for the binary implementation.
+func (hspm *helmSDKPackageManager) SearchRepo(searchRepoOpts options.SearchRepoOptions) ([]byte, error) {
+	return binary.SearchRepo(searchRepoOpts)
+}
@@ -0,0 +1,62 @@
+//go:build helmsdk
+
+package sdk
+
+import (
+	"fmt"
+
+	libhelmerrors "github.com/portainer/portainer/pkg/libhelm/errors"
+	"github.com/portainer/portainer/pkg/libhelm/options"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+var showOutputFormats = map[options.ShowOutputFormat]action.ShowOutputFormat{
+	options.ShowAll:    action.ShowAll,
+	options.ShowChart:  action.ShowChart,
+	options.ShowValues: action.ShowValues,
+	options.ShowReadme: action.ShowReadme,
+}
+
+// Show returns the definition, values and/or readme of a chart, like `helm show` does.
+func (hspm *helmSDKPackageManager) Show(showOpts options.ShowOptions) ([]byte, error) {
+	if showOpts.Chart == "" || (showOpts.Repo == "" && !options.IsOCIReference(showOpts.Chart)) || showOpts.OutputFormat == "" {
+		return nil, fmt.Errorf("%w: chart, repo and output format are required", libhelmerrors.ErrInvalidOptions)
+	}
+
+	outputFormat, ok := showOutputFormats[showOpts.OutputFormat]
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid output format %q", libhelmerrors.ErrInvalidOptions, showOpts.OutputFormat)
+	}
+
+	chartRef, repo := options.ResolveChart(showOpts.Chart, showOpts.Repo)
+
+	// the chart is only located, no cluster is needed
+	config := &action.Configuration{}
+
+	if options.IsOCIReference(chartRef) {
+		registryClient, cleanup, err := newRegistryClient(chartRef, showOpts.Registry)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		config.RegistryClient = registryClient
+	}
+
+	client := action.NewShowWithConfig(outputFormat, config)
+	client.RepoURL = repo
+	client.Version = showOpts.Version
+
+	chartPath, err := client.ChartPathOptions.LocateChart(chartRef, hspm.settings)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", libhelmerrors.ErrChartNotFound, err)
+	}
+
+	result, err := client.Run(chartPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to show the chart")
+	}
+
+	return []byte(result), nil
+}
@@ -0,0 +1,35 @@
+//go:build helmsdk
+
+package sdk
+
+import (
+	"fmt"
+
+	libhelmerrors "github.com/portainer/portainer/pkg/libhelm/errors"
+	"github.com/portainer/portainer/pkg/libhelm/options"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+// Uninstall uninstalls a release, like `helm uninstall` does.
+func (hspm *helmSDKPackageManager) Uninstall(uninstallOpts options.UninstallOptions) error {
+	if uninstallOpts.Name == "" {
+		return fmt.Errorf("%w: release name is required", libhelmerrors.ErrInvalidOptions)
+	}
+
+	config, err := hspm.actionConfig(uninstallOpts.Namespace, uninstallOpts.KubernetesClusterAccess)
+	if err != nil {
+		return err
+	}
+
+	client := action.NewUninstall(config)
+	client.Timeout = defaultTimeout
+
+	_, err = client.Run(uninstallOpts.Name)
+	if err != nil {
+		return errors.Wrap(translateError(err, uninstallOpts.Name), "failed to uninstall the release")
+	}
+
+	return nil
+}
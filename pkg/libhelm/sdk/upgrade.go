@@ -0,0 +1,92 @@
+//go:build helmsdk
+
+package sdk
+
+import (
+	"fmt"
+
+	libhelmerrors "github.com/portainer/portainer/pkg/libhelm/errors"
+	"github.com/portainer/portainer/pkg/libhelm/options"
+	"github.com/portainer/portainer/pkg/libhelm/release"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/postrender"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// Upgrade upgrades a release with the specified upgrade options, like `helm upgrade` does.
+// The release is installed when it does not exist and the Install option is set.
+func (hspm *helmSDKPackageManager) Upgrade(upgradeOpts options.UpgradeOptions) (*release.Release, error) {
+	if upgradeOpts.Name == "" || upgradeOpts.Chart == "" {
+		return nil, fmt.Errorf("%w: release name and chart are required", libhelmerrors.ErrInvalidOptions)
+	}
+
+	config, err := hspm.actionConfig(upgradeOpts.Namespace, upgradeOpts.KubernetesClusterAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	if upgradeOpts.Install {
+		history := action.NewHistory(config)
+		history.Max = 1
+		if _, err := history.Run(upgradeOpts.Name); errors.Is(err, driver.ErrReleaseNotFound) {
+			return hspm.Install(options.InstallOptions{
+				Name:                    upgradeOpts.Name,
+				Chart:                   upgradeOpts.Chart,
+				Namespace:               upgradeOpts.Namespace,
+				Repo:                    upgradeOpts.Repo,
+				Version:                 upgradeOpts.Version,
+				Wait:                    upgradeOpts.Wait,
+				ValuesFile:              upgradeOpts.ValuesFile,
+				PostRenderer:            upgradeOpts.PostRenderer,
+				KubernetesClusterAccess: upgradeOpts.KubernetesClusterAccess,
+				Registry:                upgradeOpts.Registry,
+				Env:                     upgradeOpts.Env,
+			})
+		}
+	}
+
+	chartRef, repo := options.ResolveChart(upgradeOpts.Chart, upgradeOpts.Repo)
+
+	if options.IsOCIReference(chartRef) {
+		registryClient, cleanup, err := newRegistryClient(chartRef, upgradeOpts.Registry)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		config.RegistryClient = registryClient
+	}
+
+	client := action.NewUpgrade(config)
+	client.Namespace = upgradeOpts.Namespace
+	client.RepoURL = repo
+	client.Version = upgradeOpts.Version
+	client.ReuseValues = upgradeOpts.ReuseValues
+	client.Wait = upgradeOpts.Wait
+	client.Timeout = defaultTimeout
+
+	if upgradeOpts.PostRenderer != "" {
+		client.PostRenderer, err = postrender.NewExec(upgradeOpts.PostRenderer)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to initialize the post renderer")
+		}
+	}
+
+	chart, err := hspm.loadChart(&client.ChartPathOptions, chartRef)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := readValues(upgradeOpts.ValuesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	rel, err := client.Run(upgradeOpts.Name, chart, values)
+	if err != nil {
+		return nil, errors.Wrap(translateError(err, upgradeOpts.Name), "failed to upgrade the release")
+	}
+
+	return convertRelease(rel)
+}
@@ -9,10 +9,13 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/portainer/portainer/pkg/libhelm/options"
 )
 
 const invalidChartRepo = "%q is not a valid chart repository or cannot be reached"
 
+// ValidateHelmRepositoryURL verifies that the URL is a chart repository which can be reached.
+// The OCI repositories (oci://) do not have an index and are only validated syntactically.
 func ValidateHelmRepositoryURL(repoUrl string, client *http.Client) error {
 	if repoUrl == "" {
 		return errors.New("URL is required")
@@ -23,6 +26,14 @@ func ValidateHelmRepositoryURL(repoUrl string, client *http.Client) error {
 		return errors.Wrap(err, fmt.Sprintf("invalid helm chart URL: %s", repoUrl))
 	}
 
+	if options.IsOCIReference(repoUrl) {
+		if url.Host == "" {
+			return errors.New(fmt.Sprintf("invalid helm chart URL: %s", repoUrl))
+		}
+
+		return nil
+	}
+
 	if !strings.EqualFold(url.Scheme, "http") && !strings.EqualFold(url.Scheme, "https") {
 		return errors.New(fmt.Sprintf("invalid helm chart URL: %s", repoUrl))
 	}
@@ -47,3 +47,10 @@ func Test_ValidateHelmRepositoryURL(t *testing.T) {
 		}(test)
 	}
 }
+
+func Test_ValidateHelmRepositoryURL_OCI(t *testing.T) {
+	is := assert.New(t)
+
+	is.NoError(ValidateHelmRepositoryURL("oci://registry.example.com/charts", nil))
+	is.Error(ValidateHelmRepositoryURL("oci:///charts", nil), "the registry host is required")
+}